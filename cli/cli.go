@@ -39,6 +39,7 @@ func query(db *sql.DB, query string) {
 		fmt.Printf("ERROR : Cannot query : %s\n", err)
 		return
 	}
+	defer rows.Close()
 
 	columns, err := rows.Columns()
 	if err != nil {
@@ -98,6 +99,9 @@ func prettyPrintRow(row []interface{}) {
 // Run start a command line interface reading on stdin and execute queries
 // on given sql.DB
 func Run(db *sql.DB) {
+	// statements share a single connection, so SET applies to following statements
+	db.SetMaxOpenConns(1)
+
 	// Readline
 	reader := bufio.NewReader(os.Stdin)
 
@@ -124,11 +128,12 @@ func Run(db *sql.DB) {
 		stmt = removeComments(stmt)
 
 		// Do things here
-		if strings.HasPrefix(stmt, "SELECT") {
+		keyword := strings.ToUpper(strings.TrimSpace(stmt))
+		if strings.HasPrefix(keyword, "SELECT") {
 			query(db, stmt)
-		} else if strings.HasPrefix(stmt, "SHOW") {
+		} else if strings.HasPrefix(keyword, "SHOW") {
 			query(db, stmt)
-		} else if strings.HasPrefix(stmt, "DESCRIBE") {
+		} else if strings.HasPrefix(keyword, "DESCRIBE") {
 			query(db, stmt)
 		} else {
 			exec(db, stmt)
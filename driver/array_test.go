@@ -0,0 +1,186 @@
+package ramsql
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+func setupArrayTable(t *testing.T, dbName string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("ramsql", dbName)
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+
+	batch := []string{
+		`CREATE TABLE post (id BIGSERIAL PRIMARY KEY, title TEXT, tags TEXT[], scores INT[])`,
+		`INSERT INTO post (title, tags, scores) VALUES ('intro', '{go,sql}', '{1,2,3}')`,
+		`INSERT INTO post (title, tags, scores) VALUES ('borrow', ARRAY['rust', 'sql'], ARRAY[4, 5])`,
+		`INSERT INTO post (title, tags, scores) VALUES ('hello', '{"hello world"}', '{}')`,
+	}
+	for _, b := range batch {
+		if _, err := db.Exec(b); err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+	_, err = db.Exec(`INSERT INTO post (title, tags, scores) VALUES ($1, $2, $3)`, "args", []string{"go"}, []int64{9})
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	return db
+}
+
+func queryTitles(t *testing.T, db *sql.DB, query string, args ...any) []string {
+	t.Helper()
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		t.Fatalf("sql.Query: %s: %s", query, err)
+	}
+	defer rows.Close()
+
+	var titles []string
+	for rows.Next() {
+		var title string
+		if err := rows.Scan(&title); err != nil {
+			t.Fatalf("Cannot scan row: %s", err)
+		}
+		titles = append(titles, title)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("rows.Err: %s", err)
+	}
+	return titles
+}
+
+func TestArrayPredicates(t *testing.T) {
+	db := setupArrayTable(t, "TestArrayPredicates")
+	defer db.Close()
+
+	tests := []struct {
+		query string
+		args  []any
+		want  []string
+	}{
+		{`SELECT title FROM post WHERE id = ANY($1)`, []any{[]int64{1, 3}}, []string{"intro", "hello"}},
+		{`SELECT title FROM post WHERE id = ANY('{2,3}')`, nil, []string{"borrow", "hello"}},
+		{`SELECT title FROM post WHERE id <> ALL(ARRAY[1, 2])`, nil, []string{"hello", "args"}},
+		{`SELECT title FROM post WHERE id = ANY(SELECT id FROM post WHERE title = 'borrow')`, nil, []string{"borrow"}},
+		{`SELECT p.title FROM post p WHERE p.id = ANY($1)`, []any{[]int64{4}}, []string{"args"}},
+		{`SELECT title FROM post WHERE 'sql' = ANY(tags)`, nil, []string{"intro", "borrow"}},
+		{`SELECT title FROM post WHERE $1 = ANY(scores)`, []any{5}, []string{"borrow"}},
+		{`SELECT title FROM post WHERE tags @> '{sql}'`, nil, []string{"intro", "borrow"}},
+		{`SELECT title FROM post WHERE tags <@ ARRAY['go', 'sql']`, nil, []string{"intro", "args"}},
+		{`SELECT title FROM post WHERE tags && $1`, []any{[]string{"rust", "hello world"}}, []string{"borrow", "hello"}},
+		{`SELECT title FROM post WHERE tags = '{go}'`, nil, []string{"args"}},
+		{`SELECT title FROM post WHERE id = ANY($1)`, []any{[]int64{}}, nil},
+		{`SELECT title FROM post WHERE id <> ALL($1)`, []any{[]int64{}}, []string{"intro", "borrow", "hello", "args"}},
+		// comparisons without attribute keep every row or none
+		{`SELECT title FROM post WHERE 5 = ANY($1)`, []any{[]int64{1}}, nil},
+		{`SELECT title FROM post WHERE 'x' = ANY(ARRAY['y'])`, nil, nil},
+		{`SELECT title FROM post WHERE 2 = ANY($1)`, []any{[]int64{}}, nil},
+		{`SELECT title FROM post WHERE 2 <> ALL($1)`, []any{[]int64{}}, []string{"intro", "borrow", "hello", "args"}},
+		{`SELECT title FROM post WHERE $1 = ANY($2) AND id < 3`, []any{1, []int64{1}}, []string{"intro", "borrow"}},
+		{`SELECT title FROM post WHERE $1 = ANY($2) OR id = 3`, []any{2, []int64{1}}, []string{"hello"}},
+	}
+
+	for _, tt := range tests {
+		got := queryTitles(t, db, tt.query, tt.args...)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("%s: expected %v, got %v", tt.query, tt.want, got)
+		}
+	}
+}
+
+func TestArrayFunctions(t *testing.T) {
+	db := setupArrayTable(t, "TestArrayFunctions")
+	defer db.Close()
+
+	// arrays are scanned in PostgreSQL text format
+	var tags, scores string
+	err := db.QueryRow(`SELECT tags, scores FROM post WHERE title = 'hello'`).Scan(&tags, &scores)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if tags != `{"hello world"}` || scores != "{}" {
+		t.Fatalf("expected {\"hello world\"} and {}, got %s and %s", tags, scores)
+	}
+
+	_, err = db.Exec(`UPDATE post SET scores = ARRAY[7] WHERE title = 'hello'`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	rows, err := db.Query(`SELECT title, array_length(scores, 1) FROM post`)
+	if err != nil {
+		t.Fatalf("sql.Query: %s", err)
+	}
+	lengths := make(map[string]sql.NullInt64)
+	for rows.Next() {
+		var title string
+		var n sql.NullInt64
+		if err := rows.Scan(&title, &n); err != nil {
+			t.Fatalf("Cannot scan row: %s", err)
+		}
+		lengths[title] = n
+	}
+	rows.Close()
+	if lengths["intro"].Int64 != 3 || lengths["borrow"].Int64 != 2 || lengths["hello"].Int64 != 1 {
+		t.Fatalf("unexpected array lengths %v", lengths)
+	}
+
+	var ids string
+	err = db.QueryRow(`SELECT array_agg(id) FROM post WHERE id > 1`).Scan(&ids)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if ids != "{2,3,4}" {
+		t.Fatalf("expected {2,3,4}, got %s", ids)
+	}
+
+	_, err = db.Exec(`INSERT INTO post (title, scores) VALUES ('null', '{1,NULL}')`)
+	if err == nil {
+		t.Fatalf("expected error on NULL array element")
+	}
+}
+
+func TestUnnest(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestUnnest")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT * FROM unnest($1)`, []int64{3, 1, 2})
+	if err != nil {
+		t.Fatalf("sql.Query: %s", err)
+	}
+	var got []int64
+	for rows.Next() {
+		var v int64
+		if err := rows.Scan(&v); err != nil {
+			t.Fatalf("Cannot scan row: %s", err)
+		}
+		got = append(got, v)
+	}
+	rows.Close()
+	if !reflect.DeepEqual(got, []int64{3, 1, 2}) {
+		t.Fatalf("expected [3 1 2], got %v", got)
+	}
+
+	titles := queryTitles(t, db, `SELECT t.x FROM unnest(ARRAY['a', 'b', 'c']) AS t(x) WHERE t.x <> 'b'`)
+	if !reflect.DeepEqual(titles, []string{"a", "c"}) {
+		t.Fatalf("expected [a c], got %v", titles)
+	}
+
+	// relations holding elements are dropped once rows are read
+	var n int
+	err = db.QueryRow(`SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = 'public'`).Scan(&n)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if n != 0 {
+		t.Fatalf("expected no relation, got %d", n)
+	}
+}
@@ -0,0 +1,122 @@
+package ramsql
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func TestBindPlaceholderStyles(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestBindPlaceholderStyles")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE account (id BIGINT PRIMARY KEY, email TEXT, age BIGINT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	// positional and named markers in VALUES
+	_, err = db.Exec(`INSERT INTO account (id, email, age) VALUES ($1, $2, @age_1)`, 1, "foo@bar.com", sql.Named("age_1", 20))
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	_, err = db.Exec(`INSERT INTO account (id, email, age) VALUES (?, ?, ?), (?, ?, ?)`, 2, "bar@bar.com", 30, 3, "baz@bar.com", 40)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	// ON CONFLICT DO UPDATE SET
+	_, err = db.Exec(`INSERT INTO account (id, email, age) VALUES (:id, :email, 0) ON CONFLICT (id) DO UPDATE SET age = :age`,
+		sql.Named("id", 1), sql.Named("email", "foo@bar.com"), sql.Named("age", 21))
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	// SET and WHERE with ? counted across the whole statement
+	_, err = db.Exec(`UPDATE account SET email = ?, age = ? WHERE id = ? AND age = ?`, "qux@bar.com", 31, 2, 30)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	var email string
+	var age int64
+	err = db.QueryRow(`SELECT email, age FROM account WHERE id = @id`, sql.Named("id", 2)).Scan(&email, &age)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if email != "qux@bar.com" || age != 31 {
+		t.Fatalf("expected qux@bar.com aged 31, got %s aged %d", email, age)
+	}
+	err = db.QueryRow(`SELECT age FROM account WHERE id = ?`, 1).Scan(&age)
+	if err != nil || age != 21 {
+		t.Fatalf("expected age 21 after ON CONFLICT, got %d (%v)", age, err)
+	}
+
+	// SELECT list, IN list, ORDER BY and LIMIT, a named parameter used twice
+	rows, err := db.Query(`SELECT :tag, id FROM account WHERE id IN (?, $2, :max) AND age > :min ORDER BY :tag, id DESC LIMIT @limit`,
+		1, 2, sql.Named("tag", "x"), sql.Named("max", 3), sql.Named("min", 20), sql.Named("limit", 2))
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var tag string
+		var id int64
+		if err := rows.Scan(&tag, &id); err != nil {
+			t.Fatalf("rows.Scan: Error: %s\n", err)
+		}
+		if tag != "x" {
+			t.Fatalf("expected tag x, got %s", tag)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("rows.Err: Error: %s\n", err)
+	}
+	if len(ids) != 2 || ids[0] != 3 || ids[1] != 2 {
+		t.Fatalf("expected ids [3 2], got %v", ids)
+	}
+
+	// CTE body
+	err = db.QueryRow(`WITH adults AS (SELECT id, age FROM account WHERE age >= $1) SELECT COUNT(*) FROM adults`, 31).Scan(&age)
+	if err != nil || age != 2 {
+		t.Fatalf("expected 2 accounts in CTE, got %d (%v)", age, err)
+	}
+}
+
+func TestBindArgumentErrors(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestBindArgumentErrors")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE account (id BIGINT PRIMARY KEY, email TEXT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	tests := []struct {
+		query string
+		args  []any
+		err   string
+	}{
+		{`SELECT id FROM account WHERE id = $2`, []any{1}, "missing argument for parameter $2"},
+		{`SELECT id FROM account WHERE id = ? AND email = ?`, []any{1}, "missing argument for parameter $2"},
+		{`SELECT id FROM account WHERE id = :id`, []any{sql.Named("ID", 1)}, "missing argument for parameter :id"},
+		{`SELECT id FROM account WHERE id = $1`, []any{1, 2}, "unexpected argument $2"},
+		{`SELECT id FROM account WHERE id = @id`, []any{sql.Named("id", 1), sql.Named("email", "x")}, "unexpected argument email"},
+	}
+
+	for _, tt := range tests {
+		_, err = db.Query(tt.query, tt.args...)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Fatalf("%s: expected error '%s', got %v", tt.query, tt.err, err)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package ramsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestSystemCatalog(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestSystemCatalog")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email VARCHAR(255) NOT NULL UNIQUE, name TEXT DEFAULT 'anonymous')`,
+		`CREATE TABLE post (id BIGSERIAL PRIMARY KEY, account_id BIGINT REFERENCES account(id) ON DELETE CASCADE, title TEXT)`,
+		`CREATE INDEX post_title_idx ON post (title)`,
+	}
+	for _, q := range batch {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("sql.Exec(%s): Error: %s\n", q, err)
+		}
+	}
+
+	var count int
+	err = db.QueryRow(`SELECT count(*) FROM pg_tables WHERE schemaname = 'public'`).Scan(&count)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 tables, got %d", count)
+	}
+
+	err = db.QueryRow(`SELECT count(*) FROM pg_catalog.pg_class c JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = 'public' AND c.relname = 'post' AND c.relkind = 'r'`).Scan(&count)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected relation post in pg_class, got %d", count)
+	}
+
+	var indexes []string
+	rows, err := db.Query(`SELECT indexname FROM pg_indexes WHERE tablename = 'post' ORDER BY indexname`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("rows.Scan: %s", err)
+		}
+		indexes = append(indexes, name)
+	}
+	rows.Close()
+	if len(indexes) != 2 || indexes[0] != "post_pkey" || indexes[1] != "post_title_idx" {
+		t.Fatalf("unexpected indexes %v", indexes)
+	}
+
+	type column struct {
+		name     string
+		dataType string
+		nullable string
+		dflt     sql.NullString
+		length   sql.NullInt64
+	}
+	var columns []column
+	rows, err = db.Query(`SELECT column_name, data_type, is_nullable, column_default, character_maximum_length FROM information_schema.columns WHERE table_schema = 'public' AND table_name = 'account' ORDER BY ordinal_position`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	for rows.Next() {
+		var c column
+		if err := rows.Scan(&c.name, &c.dataType, &c.nullable, &c.dflt, &c.length); err != nil {
+			t.Fatalf("rows.Scan: %s", err)
+		}
+		columns = append(columns, c)
+	}
+	rows.Close()
+	if len(columns) != 3 {
+		t.Fatalf("expected 3 columns, got %d", len(columns))
+	}
+	if c := columns[0]; c.name != "id" || c.dataType != "bigint" || c.nullable != "NO" || c.dflt.String != "nextval('account_id_seq'::regclass)" {
+		t.Fatalf("unexpected column %+v", c)
+	}
+	if c := columns[1]; c.name != "email" || c.dataType != "character varying" || c.nullable != "NO" || c.dflt.Valid || c.length.Int64 != 255 {
+		t.Fatalf("unexpected column %+v", c)
+	}
+	if c := columns[2]; c.name != "name" || c.dataType != "text" || c.nullable != "YES" || c.dflt.String != "'anonymous'::text" {
+		t.Fatalf("unexpected column %+v", c)
+	}
+
+	var constraints []string
+	rows, err = db.Query(`SELECT tc.constraint_type, kcu.column_name FROM information_schema.table_constraints tc JOIN information_schema.key_column_usage kcu ON kcu.constraint_name = tc.constraint_name WHERE tc.table_name = 'account' ORDER BY tc.constraint_type`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	for rows.Next() {
+		var typ, col string
+		if err := rows.Scan(&typ, &col); err != nil {
+			t.Fatalf("rows.Scan: %s", err)
+		}
+		constraints = append(constraints, typ+":"+col)
+	}
+	rows.Close()
+	if len(constraints) != 2 || constraints[0] != "PRIMARY KEY:id" || constraints[1] != "UNIQUE:email" {
+		t.Fatalf("unexpected constraints %v", constraints)
+	}
+
+	var name, unique, rule string
+	err = db.QueryRow(`SELECT constraint_name, unique_constraint_name, delete_rule FROM information_schema.referential_constraints WHERE constraint_schema = 'public'`).Scan(&name, &unique, &rule)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if name != "post_account_id_fkey" || unique != "account_pkey" || rule != "CASCADE" {
+		t.Fatalf("unexpected referential constraint (%s, %s, %s)", name, unique, rule)
+	}
+
+	var contype, confdeltype string
+	err = db.QueryRow(`SELECT contype, confdeltype FROM pg_constraint WHERE conname = 'post_account_id_fkey'`).Scan(&contype, &confdeltype)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if contype != "f" || confdeltype != "c" {
+		t.Fatalf("unexpected pg_constraint (%s, %s)", contype, confdeltype)
+	}
+
+	// views are always consistent with relations
+	if _, err := db.Exec(`DROP TABLE post`); err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	err = db.QueryRow(`SELECT count(*) FROM pg_attribute a JOIN pg_class c ON c.oid = a.attrelid WHERE c.relname = 'post'`).Scan(&count)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if count != 0 {
+		t.Fatalf("expected no attribute of dropped relation, got %d", count)
+	}
+
+	err = db.QueryRow(`SELECT count(*) FROM information_schema.schemata WHERE schema_name IN ('public', 'pg_catalog', 'information_schema')`).Scan(&count)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if count != 3 {
+		t.Fatalf("expected 3 schemas, got %d", count)
+	}
+}
+
+func TestCatalogQueryStatement(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestCatalogQueryStatement")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email VARCHAR(255))`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	// GORM postgres Migrator.ColumnTypes query
+	query := `SELECT a.attname as column_name, format_type(a.atttypid, a.atttypmod) AS data_type
+		FROM pg_attribute a JOIN pg_class b ON a.attrelid = b.oid AND relnamespace = (SELECT oid FROM pg_catalog.pg_namespace WHERE nspname = CURRENT_SCHEMA())
+		WHERE a.attnum > 0 -- hide internal columns
+		AND NOT a.attisdropped -- hide deleted columns
+		AND b.relname = $1`
+
+	var stmts []Statement
+	_, err = AddHook("TestCatalogQueryStatement", Hook{
+		Match: regexp.MustCompile(`format_type`),
+		After: func(s *Statement) error {
+			stmts = append(stmts, *s)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("cannot add hook: %s", err)
+	}
+
+	rows, err := db.Query(query, "account")
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	types := make(map[string]string)
+	for rows.Next() {
+		var name, dataType string
+		if err := rows.Scan(&name, &dataType); err != nil {
+			t.Fatalf("rows.Scan: %s", err)
+		}
+		types[name] = dataType
+	}
+	rows.Close()
+	if len(types) != 2 || types["email"] != "character varying(255)" {
+		t.Fatalf("unexpected column types %v", types)
+	}
+
+	// catalog queries run between hooks and are recorded like any other statement
+	if len(stmts) != 1 || stmts[0].Kind != "SELECT" || stmts[0].Rows != 2 || stmts[0].Err != nil {
+		t.Fatalf("unexpected statements %+v", stmts)
+	}
+	history, err := QueryHistory("TestCatalogQueryStatement")
+	if err != nil {
+		t.Fatalf("cannot get query history: %s", err)
+	}
+	last := history[len(history)-1]
+	if last.Query != query || last.Rows != 2 || last.Err != nil {
+		t.Fatalf("unexpected query history record %+v", last)
+	}
+
+	// and are subject to fault injection and statement timeout
+	var e *Error
+	err = SetFaultProfile("TestCatalogQueryStatement", FaultProfile{SerializationFailurePercent: 100})
+	if err != nil {
+		t.Fatalf("cannot set fault profile: %s", err)
+	}
+	_, err = db.Query(query, "account")
+	if !errors.As(err, &e) || e.SQLState() != "40001" {
+		t.Fatalf("expected serialization failure, got %v", err)
+	}
+
+	err = SetFaultProfile("TestCatalogQueryStatement", FaultProfile{Latency: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("cannot set fault profile: %s", err)
+	}
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("db.Conn: Error: %s\n", err)
+	}
+	_, err = conn.ExecContext(ctx, `SET statement_timeout = '10ms'`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	_, err = conn.QueryContext(ctx, query, "account")
+	if !errors.As(err, &e) || e.SQLState() != "57014" {
+		t.Fatalf("expected statement timeout, got %v", err)
+	}
+	conn.Close()
+	err = SetFaultProfile("TestCatalogQueryStatement", FaultProfile{})
+	if err != nil {
+		t.Fatalf("cannot set fault profile: %s", err)
+	}
+
+	// other queries on the same relations are not answered as the catalog query
+	_, err = db.Query(query+` AND a.attname = 'email'`, "account")
+	if err == nil {
+		t.Fatalf("expected unsupported query to fail")
+	}
+}
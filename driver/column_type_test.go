@@ -0,0 +1,119 @@
+package ramsql
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestColumnTypes(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestColumnTypes")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	init := []string{
+		`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email VARCHAR(255) NOT NULL, balance DECIMAL(10, 2), active BOOLEAN, created_at TIMESTAMP DEFAULT NOW())`,
+		`INSERT INTO account (email, balance, active) VALUES ('foo@bar.com', 12.5, true)`,
+	}
+	for _, q := range init {
+		_, err = db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	rows, err := db.Query(`SELECT id, email, balance, active, created_at FROM account`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatalf("cannot get column types: %s", err)
+	}
+	if len(types) != 5 {
+		t.Fatalf("expected 5 column types, got %d", len(types))
+	}
+
+	expected := []struct {
+		name     string
+		typeName string
+		scanType reflect.Type
+		nullable bool
+	}{
+		{"id", "BIGSERIAL", reflect.TypeOf(int64(0)), false},
+		{"email", "VARCHAR", reflect.TypeOf(""), false},
+		{"balance", "FLOAT", reflect.TypeOf(float64(0)), true},
+		{"active", "BOOLEAN", reflect.TypeOf(true), true},
+		{"created_at", "TIMESTAMP", reflect.TypeOf(time.Time{}), true},
+	}
+	for i, e := range expected {
+		ct := types[i]
+		if ct.Name() != e.name || ct.DatabaseTypeName() != e.typeName || ct.ScanType() != e.scanType {
+			t.Fatalf("column %d: expected %s %s (%s), got %s %s (%s)", i, e.name, e.typeName, e.scanType, ct.Name(), ct.DatabaseTypeName(), ct.ScanType())
+		}
+		nullable, ok := ct.Nullable()
+		if !ok || nullable != e.nullable {
+			t.Fatalf("column %s: expected nullable %v, got %v (known: %v)", e.name, e.nullable, nullable, ok)
+		}
+	}
+
+	if l, ok := types[1].Length(); !ok || l != 255 {
+		t.Fatalf("expected email length 255, got %d (%v)", l, ok)
+	}
+	if p, s, ok := types[2].DecimalSize(); !ok || p != 10 || s != 2 {
+		t.Fatalf("expected balance DECIMAL(10, 2), got (%d, %d) %v", p, s, ok)
+	}
+	if _, ok := types[0].Length(); ok {
+		t.Fatalf("expected no length for id")
+	}
+}
+
+func TestColumnTypesComputed(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestColumnTypesComputed")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	init := []string{
+		`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT)`,
+		`INSERT INTO account (email) VALUES ('foo@bar.com')`,
+	}
+	for _, q := range init {
+		_, err = db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	rows, err := db.Query(`SELECT COUNT(*) FROM account`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatalf("cannot get column types: %s", err)
+	}
+	rows.Close()
+	if len(types) != 1 || types[0].DatabaseTypeName() != "BIGINT" || types[0].ScanType() != reflect.TypeOf(int64(0)) {
+		t.Fatalf("unexpected COUNT column type %+v", types)
+	}
+
+	rows, err = db.Query(`SELECT * FROM account`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	types, err = rows.ColumnTypes()
+	if err != nil {
+		t.Fatalf("cannot get column types: %s", err)
+	}
+	rows.Close()
+	if len(types) != 2 || types[1].Name() != "email" || types[1].DatabaseTypeName() != "TEXT" {
+		t.Fatalf("unexpected star column types")
+	}
+}
@@ -0,0 +1,349 @@
+package ramsql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/proullon/ramsql/engine/agnostic"
+	"github.com/proullon/ramsql/engine/executor"
+	"github.com/proullon/ramsql/engine/log"
+)
+
+// SQL dialects accepted by Config.Dialect
+const (
+	// DialectPostgres is the PostgreSQL dialect, used by default.
+	DialectPostgres = "postgres"
+	// DialectMySQL also accepts MySQL syntax: AUTO_INCREMENT, LIMIT offset, count,
+	// ON DUPLICATE KEY UPDATE, table options, TINYINT(1) booleans and LAST_INSERT_ID().
+	DialectMySQL = "mysql"
+	// DialectSQLite also accepts SQLite syntax: INTEGER PRIMARY KEY rowid aliases, AUTOINCREMENT,
+	// INSERT OR REPLACE and OR IGNORE, sqlite_master and PRAGMA table_info. Values follow type affinity.
+	DialectSQLite = "sqlite"
+)
+
+// Config describes a RamSQL engine.
+//
+// Connectors sharing the same Name share the same engine. The configuration is
+// applied when the engine is created. Connecting to a running engine with another
+// Dialect or Schema, or with Strict when the engine is not strict, is an error.
+// Empty and other options are ignored if the engine already runs.
+type Config struct {
+	// Name identifies the engine, "default" if empty
+	Name string
+	// Schema is the schema used when statements do not specify one, "public" if empty
+	Schema string
+	// Dialect is the SQL dialect, DialectPostgres if empty
+	Dialect string
+	// Strict disables implicit conversions between incompatible types, for example integer to text
+	Strict bool
+
+	// File is the write-ahead log path, engine is in-memory only if empty
+	File string
+	// CompactEvery is the number of commits between write-ahead log compactions
+	CompactEvery int
+	// HistorySize is the number of statements kept in query history.
+	// Zero means executor.DefaultHistorySize, negative disables history.
+	HistorySize int
+
+	Faults FaultProfile
+	Limits Limits
+}
+
+func (c Config) validate() error {
+	switch c.Dialect {
+	case "", DialectPostgres, DialectMySQL, DialectSQLite:
+	default:
+		return fmt.Errorf("unsupported dialect '%s'", c.Dialect)
+	}
+	return nil
+}
+
+// compatible returns an error if c asks for another dialect, schema or strict mode
+// than the running engine configured with running.
+func (c Config) compatible(running Config) error {
+	dialect := func(d string) string {
+		if d == "" {
+			return DialectPostgres
+		}
+		return d
+	}
+	if c.Dialect != "" && dialect(c.Dialect) != dialect(running.Dialect) {
+		return fmt.Errorf("engine %s already runs with dialect %s", c.Name, dialect(running.Dialect))
+	}
+	if c.Schema != "" && c.Schema != running.Schema && (running.Schema != "" || c.Schema != agnostic.DefaultSchema) {
+		return fmt.Errorf("engine %s already runs with another default schema", c.Name)
+	}
+	if c.Strict && !running.Strict {
+		return fmt.Errorf("engine %s already runs without strict mode", c.Name)
+	}
+	return nil
+}
+
+// ParseDSN parses a data source name into a Config.
+//
+// The preferred form is an URL whose host is the engine name:
+//
+//	ramsql://name?schema=app&dialect=postgres&strict=true
+//
+// The scheme can be omitted, and for compatibility the legacy proto:addr*DBNAME/USER/PASSWD
+// form is accepted, only DBNAME is used. Without proto:addr*, slashes are part of the name,
+// so subtests can open their own engine with t.Name(), but DBNAME// is still DBNAME.
+//
+// Currently implemented options:
+//
+//	schema  - schema used when statements do not specify one
+//	dialect - SQL dialect, postgres, mysql or sqlite
+//	strict  - disable implicit conversions between incompatible types
+//	file    - write-ahead log path, committed transactions are persisted and replayed on open
+//	compact - number of commits between write-ahead log compactions
+//	history - number of statements kept in query history, 0 disables it
+//
+// Fault injection options, see FaultProfile. Percentages are between 0 and 100:
+//
+//	latency               - latency added to every statement, in format accepted by time.ParseDuration
+//	latency_jitter        - maximum random latency added to every statement
+//	bad_conn              - percentage of connection calls failing with driver.ErrBadConn
+//	serialization_failure - percentage of statements failing with SQLSTATE 40001
+//	disk_full             - percentage of write statements failing with SQLSTATE 53100
+//	fault_seed            - seed making random faults deterministic
+//
+// Size limit options, writes exceeding them fail with SQLSTATE 53100:
+//
+//	max_rows      - maximum number of rows in the database
+//	max_bytes     - maximum approximate size of the database, in bytes
+//	max_relations - maximum number of relations in the database
+func ParseDSN(dsn string) (Config, error) {
+	c := Config{}
+
+	name := strings.TrimPrefix(dsn, "ramsql://")
+	name, q, _ := strings.Cut(name, "?")
+
+	// legacy proto:addr*DBNAME/USER/PASSWD form, engines run in process so the address
+	// and credentials are ignored. Otherwise the whole path is the name, like a test name,
+	// without the trailing slashes of empty credentials.
+	if _, db, ok := strings.Cut(name, "*"); ok {
+		name, _, _ = strings.Cut(db, "/")
+	}
+	name = strings.TrimRight(name, "/")
+	if name == "" {
+		log.Info("Empty data source name, using 'default' engine")
+		name = "default"
+	}
+	c.Name = name
+
+	values, err := url.ParseQuery(q)
+	if err != nil {
+		return c, err
+	}
+	for k := range values {
+		v := values.Get(k)
+		switch k {
+		case "schema":
+			c.Schema = v
+		case "dialect":
+			c.Dialect = v
+		case "strict":
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return c, fmt.Errorf("wrong strict option: %w", err)
+			}
+			c.Strict = b
+		case "file":
+			c.File = v
+		case "compact":
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return c, fmt.Errorf("wrong compact option: %w", err)
+			}
+			c.CompactEvery = n
+		case "history":
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return c, fmt.Errorf("wrong history option: %w", err)
+			}
+			if n == 0 {
+				n = -1
+			}
+			c.HistorySize = n
+		case "latency", "latency_jitter":
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return c, fmt.Errorf("wrong %s option: %w", k, err)
+			}
+			if k == "latency" {
+				c.Faults.Latency = d
+			} else {
+				c.Faults.LatencyJitter = d
+			}
+		case "bad_conn", "serialization_failure", "disk_full":
+			pct, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return c, fmt.Errorf("wrong %s option: %w", k, err)
+			}
+			switch k {
+			case "bad_conn":
+				c.Faults.BadConnPercent = pct
+			case "serialization_failure":
+				c.Faults.SerializationFailurePercent = pct
+			case "disk_full":
+				c.Faults.DiskFullPercent = pct
+			}
+		case "fault_seed":
+			seed, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return c, fmt.Errorf("wrong fault_seed option: %w", err)
+			}
+			c.Faults.Seed = seed
+		case "max_rows", "max_bytes", "max_relations":
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return c, fmt.Errorf("wrong %s option: %w", k, err)
+			}
+			switch k {
+			case "max_rows":
+				c.Limits.MaxRows = n
+			case "max_bytes":
+				c.Limits.MaxBytes = n
+			case "max_relations":
+				c.Limits.MaxRelations = int(n)
+			}
+		default:
+			return c, errors.New("Unknown option: " + k)
+		}
+	}
+
+	return c, c.validate()
+}
+
+// Connector implements sql/driver Connector interface
+//
+// The engine is stopped when the last sql.DB using it is closed.
+//
+// https://pkg.go.dev/database/sql/driver#Connector
+type Connector struct {
+	d   *Driver
+	cfg Config
+	err error
+
+	closed bool
+	sync.Mutex
+}
+
+// NewConnector returns a connector to the engine described by cfg, to be used with sql.OpenDB:
+//
+//	db := sql.OpenDB(ramsql.NewConnector(ramsql.Config{Name: "TestFoo", Strict: true}))
+func NewConnector(cfg Config) *Connector {
+	return defaultDriver.NewConnector(cfg)
+}
+
+// NewConnector returns a connector to the engine described by cfg, starting it if needed,
+// so the engine can be configured before the first connection.
+// The connector holds a reference on the engine until closed.
+func (rs *Driver) NewConnector(cfg Config) *Connector {
+	if cfg.Name == "" {
+		cfg.Name = "default"
+	}
+
+	rs.Lock()
+	rs.refs[cfg.Name]++
+	rs.Unlock()
+
+	c := &Connector{d: rs, cfg: cfg, err: cfg.validate()}
+	if c.err == nil {
+		_, c.err = rs.start(cfg)
+	}
+	return c
+}
+
+// OpenConnector parses dsn, see ParseDSN.
+//
+// Implemented for DriverContext interface
+func (rs *Driver) OpenConnector(dsn string) (driver.Connector, error) {
+	cfg, err := ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return rs.NewConnector(cfg), nil
+}
+
+// Connect returns a connection to the engine, starting it if needed.
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	e, err := c.d.start(c.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return newConn(e), nil
+}
+
+// Close releases the engine, which is stopped if no other sql.DB uses it.
+//
+// Called by sql.DB.Close.
+func (c *Connector) Close() error {
+	c.Lock()
+	defer c.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		c.d.release(c.cfg.Name)
+	}
+	return nil
+}
+
+// Driver returns the underlying Driver of the Connector.
+func (c *Connector) Driver() driver.Driver {
+	return c.d
+}
+
+// start returns the engine described by cfg, creating it if needed
+func (rs *Driver) start(cfg Config) (*executor.Engine, error) {
+	rs.Lock()
+	defer rs.Unlock()
+
+	i, exist := rs.engines[cfg.Name]
+	if exist {
+		if err := cfg.compatible(i.cfg); err != nil {
+			return nil, err
+		}
+		return i.e, nil
+	}
+
+	e, err := executor.NewEngineWithName(cfg.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Schema != "" {
+		e.SetDefaultSchema(cfg.Schema)
+	}
+	e.SetStrict(cfg.Strict)
+	if err := e.SetDialect(cfg.Dialect); err != nil {
+		return nil, err
+	}
+	if cfg.HistorySize != 0 {
+		e.SetHistorySize(cfg.HistorySize)
+	}
+	e.SetFaultProfile(cfg.Faults)
+	e.SetLimits(cfg.Limits)
+
+	if cfg.File != "" {
+		err = e.Persist(cfg.File, cfg.CompactEvery)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rs.engines[cfg.Name] = &instance{e: e, cfg: cfg}
+	return e, nil
+}
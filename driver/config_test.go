@@ -0,0 +1,150 @@
+package ramsql
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestConnector(t *testing.T) {
+	db := sql.OpenDB(NewConnector(Config{Name: "TestConnector", Schema: "app"}))
+	defer db.Close()
+
+	init := []string{
+		`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT)`,
+		`INSERT INTO account (email) VALUES ('foo@bar.com')`,
+	}
+	for _, q := range init {
+		_, err := db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	// relation is created in configured default schema
+	var email string
+	err := db.QueryRow(`SELECT email FROM app.account WHERE id = 1`).Scan(&email)
+	if err != nil {
+		t.Fatalf("cannot query app schema: %s", err)
+	}
+	if email != "foo@bar.com" {
+		t.Fatalf("expected foo@bar.com, got %s", email)
+	}
+
+	var schema string
+	err = db.QueryRow(`SELECT current_schema()`).Scan(&schema)
+	if err != nil {
+		t.Fatalf("cannot query current schema: %s", err)
+	}
+	if schema != "app" {
+		t.Fatalf("expected current schema app, got %s", schema)
+	}
+
+	// a DSN with the same name shares the engine
+	db2, err := sql.Open("ramsql", "ramsql://TestConnector?schema=app")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db2.Close()
+	err = db2.QueryRow(`SELECT email FROM account WHERE id = 1`).Scan(&email)
+	if err != nil {
+		t.Fatalf("cannot query shared engine: %s", err)
+	}
+
+	// but cannot change its configuration
+	for _, dsn := range []string{"ramsql://TestConnector?schema=public", "ramsql://TestConnector?dialect=mysql", "ramsql://TestConnector?strict=true"} {
+		db3, err := sql.Open("ramsql", dsn)
+		if err != nil {
+			t.Fatalf("sql.Open : Error : %s\n", err)
+		}
+		if err := db3.Ping(); err == nil {
+			t.Fatalf("expected error opening %s", dsn)
+		}
+		db3.Close()
+	}
+}
+
+func TestConnectorStrict(t *testing.T) {
+	db, err := sql.Open("ramsql", "ramsql://TestConnectorStrict?dialect=postgres&strict=true")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT, age INT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	_, err = db.Exec(`INSERT INTO account (email, age) VALUES ($1, $2)`, "foo@bar.com", 42)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	_, err = db.Exec(`INSERT INTO account (email, age) VALUES ($1, $2)`, 42, 42)
+	if err == nil {
+		t.Fatalf("expected strict mode to reject integer in text attribute")
+	}
+
+	_, err = db.Exec(`UPDATE account SET email = $1 WHERE id = 1`, 12)
+	if err == nil {
+		t.Fatalf("expected strict mode to reject integer in text attribute")
+	}
+}
+
+func TestConnectorSubtests(t *testing.T) {
+	parent := t
+	for _, name := range []string{"a", "b"} {
+		t.Run(name, func(t *testing.T) {
+			db, err := sql.Open("ramsql", t.Name())
+			if err != nil {
+				t.Fatalf("sql.Open : Error : %s\n", err)
+			}
+			// keep engine running until other subtests are done
+			parent.Cleanup(func() { db.Close() })
+
+			// each subtest has its own engine
+			_, err = db.Exec(`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT)`)
+			if err != nil {
+				t.Fatalf("sql.Exec: Error: %s\n", err)
+			}
+		})
+	}
+}
+
+func TestParseDSN(t *testing.T) {
+	cfg, err := ParseDSN("ramsql://foo?schema=app&strict=true&max_rows=10&history=0")
+	if err != nil {
+		t.Fatalf("cannot parse DSN: %s", err)
+	}
+	if cfg.Name != "foo" || cfg.Schema != "app" || !cfg.Strict || cfg.Limits.MaxRows != 10 || cfg.HistorySize >= 0 {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+
+	cfg, err = ParseDSN("TestParseDSN/subtest")
+	if err != nil {
+		t.Fatalf("cannot parse DSN: %s", err)
+	}
+	if cfg.Name != "TestParseDSN/subtest" {
+		t.Fatalf("expected engine TestParseDSN/subtest, got %s", cfg.Name)
+	}
+
+	cfg, err = ParseDSN("tcp:127.0.0.1:3306*foo/user/password")
+	if err != nil {
+		t.Fatalf("cannot parse DSN: %s", err)
+	}
+	if cfg.Name != "foo" {
+		t.Fatalf("expected engine foo, got %s", cfg.Name)
+	}
+
+	for _, dsn := range []string{"ramsql://foo?dialect=oracle", "foo?strict=maybe"} {
+		if _, err := ParseDSN(dsn); err == nil {
+			t.Fatalf("expected error parsing %s", dsn)
+		}
+	}
+
+	db := sql.OpenDB(NewConnector(Config{Name: "TestParseDSN", Dialect: "oracle"}))
+	defer db.Close()
+	if err := db.Ping(); err == nil {
+		t.Fatalf("expected error with unsupported dialect")
+	}
+}
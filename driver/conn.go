@@ -30,12 +30,13 @@ import (
 // https://pkg.go.dev/database/sql/driver#ConnPrepareContext
 // https://pkg.go.dev/database/sql/driver#ConnBeginTx
 type Conn struct {
-	e  *executor.Engine
-	tx *executor.Tx
+	e       *executor.Engine
+	tx      *executor.Tx
+	session *executor.Session
 }
 
 func newConn(e *executor.Engine) *Conn {
-	return &Conn{e: e}
+	return &Conn{e: e, session: executor.NewSession()}
 }
 
 // Ping
@@ -44,6 +45,9 @@ func newConn(e *executor.Engine) *Conn {
 //
 // Implemented for Pinger interface
 func (c *Conn) Ping(ctx context.Context) error {
+	if c.e.Stopped() {
+		return driver.ErrBadConn
+	}
 	return nil
 }
 
@@ -53,6 +57,9 @@ func (c *Conn) Ping(ctx context.Context) error {
 //
 // Implemented for SessionResetter interface
 func (c *Conn) ResetSession(ctx context.Context) error {
+	if c.e.Stopped() {
+		return driver.ErrBadConn
+	}
 	return nil
 }
 
@@ -61,17 +68,14 @@ func (c *Conn) ResetSession(ctx context.Context) error {
 //
 // Implemented for Validator interface
 func (c *Conn) IsValid() bool {
-	return true
+	return !c.e.Stopped()
 }
 
 // Prepare returns a prepared statement, bound to this connection.
 //
 // Implemented for Conn interface
 func (c *Conn) Prepare(query string) (driver.Stmt, error) {
-
-	stmt := prepareStatement(c, query)
-
-	return stmt, nil
+	return prepareStatement(c, query)
 }
 
 // Close invalidates and potentially stops any current
@@ -89,6 +93,7 @@ func (c *Conn) Close() error {
 		_ = c.tx.Rollback()
 		c.tx = nil
 	}
+	c.e.ReleaseAdvisoryLocks(c.session)
 
 	return nil
 }
@@ -99,10 +104,14 @@ func (c *Conn) Close() error {
 //
 // Implemented for Conn interface
 func (c *Conn) Begin() (driver.Tx, error) {
+	if err := c.e.ConnFault(); err != nil {
+		return nil, err
+	}
 	tx, err := executor.NewTx(context.Background(), c.e, sql.TxOptions{})
 	if err != nil {
 		return nil, err
 	}
+	tx.SetSession(c.session)
 	c.tx = tx
 	log.Debug("%p BEGIN", c.tx)
 	return c, nil
@@ -116,10 +125,14 @@ func (c *Conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, e
 		Isolation: sql.IsolationLevel(opts.Isolation),
 		ReadOnly:  opts.ReadOnly,
 	}
+	if err := c.e.ConnFault(); err != nil {
+		return nil, err
+	}
 	tx, err := executor.NewTx(ctx, c.e, o)
 	if err != nil {
 		return nil, err
 	}
+	tx.SetSession(c.session)
 	c.tx = tx
 	log.Debug("%p BEGIN", c.tx)
 	return c, nil
@@ -149,10 +162,21 @@ func (c *Conn) Commit() error {
 //
 // Implemented for QueryerContext interface
 func (c *Conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	log.Debug("Conn.QueryContext: %s", query)
+
+	return c.query(args, func(tx *executor.Tx, a []executor.NamedValue) (*executor.Rows, error) {
+		return tx.QueryContext(ctx, query, a)
+	})
+}
+
+// query runs q in the current transaction, or in an autocommit transaction
+func (c *Conn) query(args []driver.NamedValue, q func(*executor.Tx, []executor.NamedValue) (*executor.Rows, error)) (driver.Rows, error) {
 	var err error
 	autocommit := false
 
-	log.Debug("Conn.QueryContext: %s", query)
+	if err := c.e.ConnFault(); err != nil {
+		return nil, err
+	}
 
 	tx := c.tx
 
@@ -162,38 +186,48 @@ func (c *Conn) QueryContext(ctx context.Context, query string, args []driver.Nam
 		if err != nil {
 			return nil, err
 		}
-		defer tx.Rollback()
-	}
-
-	a := make([]executor.NamedValue, len(args))
-	for i, arg := range args {
-		a[i].Name = arg.Name
-		a[i].Ordinal = arg.Ordinal
-		a[i].Value = arg.Value
+		tx.SetSession(c.session)
+		tx.Snapshot()
 	}
 
-	cols, tuples, err := tx.QueryContext(ctx, query, a)
+	rows, err := q(tx, namedValues(args))
 	if err != nil {
+		if autocommit {
+			tx.Rollback()
+		}
 		return nil, err
 	}
 
+	// autocommit transaction is committed once rows are read or closed
 	if autocommit {
-		err = tx.Commit()
-		if err != nil {
+		if err := rows.Autocommit(tx); err != nil {
+			rows.Close()
 			return nil, err
 		}
 	}
 
-	return newRows(cols, tuples), nil
+	return newRows(rows), nil
 }
 
 // ExecContext is the sql package prefered way to run Exec
 //
 // Implemented for ExecerContext interface
 func (c *Conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	log.Info("Conn.ExecContext: %s", query)
+
+	return c.exec(args, func(tx *executor.Tx, a []executor.NamedValue) (int64, int64, error) {
+		return tx.ExecContext(ctx, query, a)
+	})
+}
+
+// exec runs e in the current transaction, or in an autocommit transaction
+func (c *Conn) exec(args []driver.NamedValue, e func(*executor.Tx, []executor.NamedValue) (int64, int64, error)) (driver.Result, error) {
 	var err error
 	autocommit := false
-	log.Info("Conn.ExecContext: %s", query)
+
+	if err := c.e.ConnFault(); err != nil {
+		return nil, err
+	}
 
 	tx := c.tx
 
@@ -203,18 +237,12 @@ func (c *Conn) ExecContext(ctx context.Context, query string, args []driver.Name
 		if err != nil {
 			return nil, err
 		}
+		tx.SetSession(c.session)
 		defer tx.Rollback()
 	}
 
-	a := make([]executor.NamedValue, len(args))
-	for i, arg := range args {
-		a[i].Name = arg.Name
-		a[i].Ordinal = arg.Ordinal
-		a[i].Value = arg.Value
-	}
-
 	r := &Result{}
-	r.lastInsertedID, r.rowsAffected, r.err = tx.ExecContext(ctx, query, a)
+	r.lastInsertedID, r.rowsAffected, r.err = e(tx, namedValues(args))
 	if r.err != nil {
 		return r, r.err
 	}
@@ -228,3 +256,13 @@ func (c *Conn) ExecContext(ctx context.Context, query string, args []driver.Name
 
 	return r, r.err
 }
+
+func namedValues(args []driver.NamedValue) []executor.NamedValue {
+	a := make([]executor.NamedValue, len(args))
+	for i, arg := range args {
+		a[i].Name = arg.Name
+		a[i].Ordinal = arg.Ordinal
+		a[i].Value = arg.Value
+	}
+	return a
+}
@@ -1991,6 +1991,45 @@ func TestInsertWithMissingValue(t *testing.T) {
 	}
 }
 
+func TestInsertOmittedNullable(t *testing.T) {
+
+	db, err := sql.Open("ramsql", "TestInsertOmittedNullable")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`
+		CREATE TABLE user (
+			id BIGSERIAL PRIMARY KEY,
+			name TEXT NOT NULL,
+			email TEXT
+		)
+	`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	_, err = db.Exec("INSERT INTO user (name) VALUES ('Bob')")
+	if err != nil {
+		t.Fatalf("Cannot insert into table user: %s", err)
+	}
+
+	var email sql.NullString
+	err = db.QueryRow("SELECT email FROM user WHERE name = 'Bob'").Scan(&email)
+	if err != nil {
+		t.Fatalf("row.Scan: %s", err)
+	}
+	if email.Valid {
+		t.Fatalf("Expected NULL email, got '%v'", email.String)
+	}
+
+	_, err = db.Exec("INSERT INTO user (email) VALUES ('bob@example.com')")
+	if err == nil {
+		t.Fatalf("Expected error with NOT NULL name omitted")
+	}
+}
+
 func TestInsertMultiple(t *testing.T) {
 
 	db, err := sql.Open("ramsql", "TestInsertMultiple")
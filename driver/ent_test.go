@@ -0,0 +1,416 @@
+package ramsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"entgo.io/ent/dialect"
+	entsql "entgo.io/ent/dialect/sql"
+	"entgo.io/ent/dialect/sql/sqlgraph"
+	"entgo.io/ent/schema/field"
+)
+
+// Tables as created by ent migration for a User with O2M pets edge and M2M groups edge
+var entSchema = []string{
+	`CREATE TABLE IF NOT EXISTS "users" ("id" bigint GENERATED BY DEFAULT AS IDENTITY NOT NULL, "age" bigint NOT NULL, "name" character varying NOT NULL, "nickname" character varying NULL, PRIMARY KEY ("id"))`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS "users_name_key" ON "users" ("name")`,
+	`CREATE TABLE IF NOT EXISTS "pets" ("id" bigint GENERATED BY DEFAULT AS IDENTITY NOT NULL, "name" character varying NOT NULL, "user_pets" bigint NULL, PRIMARY KEY ("id"), CONSTRAINT "pets_users_pets" FOREIGN KEY ("user_pets") REFERENCES "users" ("id") ON DELETE SET NULL)`,
+	`CREATE TABLE IF NOT EXISTS "groups" ("id" bigint GENERATED BY DEFAULT AS IDENTITY NOT NULL, "name" character varying NOT NULL, PRIMARY KEY ("id"))`,
+	`CREATE TABLE IF NOT EXISTS "group_users" ("group_id" bigint NOT NULL, "user_id" bigint NOT NULL, PRIMARY KEY ("group_id", "user_id"), CONSTRAINT "group_users_group_id" FOREIGN KEY ("group_id") REFERENCES "groups" ("id") ON DELETE CASCADE, CONSTRAINT "group_users_user_id" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE)`,
+}
+
+var entUserColumns = []string{"id", "age", "name", "nickname"}
+
+type EntUser struct {
+	ID       int
+	Age      int
+	Name     string
+	Nickname *string
+}
+
+func openEnt(t *testing.T, name string) dialect.Driver {
+	t.Helper()
+
+	db, err := sql.Open("ramsql", name)
+	if err != nil {
+		t.Fatalf("cannot open db: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	drv := entsql.OpenDB(dialect.Postgres, db)
+	for _, q := range entSchema {
+		if err := drv.Exec(context.Background(), q, []any{}, nil); err != nil {
+			t.Fatalf("cannot create schema with %s: %s", q, err)
+		}
+	}
+	return drv
+}
+
+// entCreateUser creates a user as generated UserCreate.Save does
+func entCreateUser(ctx context.Context, drv dialect.Driver, name string, age int) (int, error) {
+	spec := sqlgraph.NewCreateSpec("users", sqlgraph.NewFieldSpec("id", field.TypeInt))
+	spec.SetField("age", field.TypeInt, age)
+	spec.SetField("name", field.TypeString, name)
+	if err := sqlgraph.CreateNode(ctx, drv, spec); err != nil {
+		return 0, err
+	}
+	return int(spec.ID.Value.(int64)), nil
+}
+
+// entQueryUsers queries users as generated UserQuery.All does
+func entQueryUsers(ctx context.Context, drv dialect.Driver, pred func(*entsql.Selector), limit int) ([]*EntUser, error) {
+	var users []*EntUser
+	spec := sqlgraph.NewQuerySpec("users", entUserColumns, sqlgraph.NewFieldSpec("id", field.TypeInt))
+	spec.Predicate = pred
+	spec.Limit = limit
+	spec.Order = func(s *entsql.Selector) {
+		s.OrderBy(entsql.Asc(s.C("id")))
+	}
+	spec.ScanValues = func(columns []string) ([]any, error) {
+		values := make([]any, len(columns))
+		for i, c := range columns {
+			switch c {
+			case "id", "age":
+				values[i] = new(sql.NullInt64)
+			default:
+				values[i] = new(sql.NullString)
+			}
+		}
+		return values, nil
+	}
+	spec.Assign = func(columns []string, values []any) error {
+		u := &EntUser{}
+		for i, c := range columns {
+			switch c {
+			case "id":
+				u.ID = int(values[i].(*sql.NullInt64).Int64)
+			case "age":
+				u.Age = int(values[i].(*sql.NullInt64).Int64)
+			case "name":
+				u.Name = values[i].(*sql.NullString).String
+			case "nickname":
+				if v := values[i].(*sql.NullString); v.Valid {
+					u.Nickname = &v.String
+				}
+			}
+		}
+		users = append(users, u)
+		return nil
+	}
+	if err := sqlgraph.QueryNodes(ctx, drv, spec); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func TestEntCRUD(t *testing.T) {
+	ctx := context.Background()
+	drv := openEnt(t, "TestEntCRUD")
+
+	a8m, err := entCreateUser(ctx, drv, "a8m", 30)
+	if err != nil {
+		t.Fatalf("cannot create user: %s", err)
+	}
+	if _, err := entCreateUser(ctx, drv, "nati", 28); err != nil {
+		t.Fatalf("cannot create user: %s", err)
+	}
+	if _, err := entCreateUser(ctx, drv, "a8m", 31); err == nil {
+		t.Fatalf("expected unique constraint error, got %v", err)
+	}
+
+	// CreateBulk
+	bulk := &sqlgraph.BatchCreateSpec{}
+	for _, name := range []string{"ariel", "rotem"} {
+		spec := sqlgraph.NewCreateSpec("users", sqlgraph.NewFieldSpec("id", field.TypeInt))
+		spec.SetField("age", field.TypeInt, 20)
+		spec.SetField("name", field.TypeString, name)
+		bulk.Nodes = append(bulk.Nodes, spec)
+	}
+	if err := sqlgraph.BatchCreate(ctx, drv, bulk); err != nil {
+		t.Fatalf("cannot create users in bulk: %s", err)
+	}
+	for _, n := range bulk.Nodes {
+		if n.ID.Value == nil {
+			t.Fatalf("expected bulk created user to have an id")
+		}
+	}
+
+	// Query().Where(user.NameHasPrefix("a"), user.AgeGT(25)).All()
+	users, err := entQueryUsers(ctx, drv, func(s *entsql.Selector) {
+		s.Where(entsql.And(entsql.HasPrefix(s.C("name"), "a"), entsql.GT(s.C("age"), 25)))
+	}, 0)
+	if err != nil {
+		t.Fatalf("cannot query users: %s", err)
+	}
+	if len(users) != 1 || users[0].ID != a8m || users[0].Nickname != nil {
+		t.Fatalf("unexpected users %+v", users)
+	}
+
+	// Query().Where(user.NameIn(...)).First()
+	users, err = entQueryUsers(ctx, drv, func(s *entsql.Selector) {
+		s.Where(entsql.In(s.C("name"), "nati", "rotem"))
+	}, 1)
+	if err != nil {
+		t.Fatalf("cannot query users: %s", err)
+	}
+	if len(users) != 1 || users[0].Name != "nati" {
+		t.Fatalf("unexpected users %+v", users)
+	}
+
+	// Query().Count()
+	count, err := sqlgraph.CountNodes(ctx, drv, sqlgraph.NewQuerySpec("users", nil, sqlgraph.NewFieldSpec("id", field.TypeInt)))
+	if err != nil {
+		t.Fatalf("cannot count users: %s", err)
+	}
+	if count != 4 {
+		t.Fatalf("expected 4 users, got %d", count)
+	}
+
+	// UpdateOneID(a8m).AddAge(1).SetNickname("ariel").Save()
+	update := sqlgraph.NewUpdateSpec("users", entUserColumns, sqlgraph.NewFieldSpec("id", field.TypeInt))
+	update.Node.ID.Value = a8m
+	update.AddField("age", field.TypeInt, 1)
+	update.SetField("nickname", field.TypeString, "ariel")
+	var updated EntUser
+	update.ScanValues = func(columns []string) ([]any, error) {
+		return []any{new(sql.NullInt64), new(sql.NullInt64), new(sql.NullString), new(sql.NullString)}, nil
+	}
+	update.Assign = func(columns []string, values []any) error {
+		updated.Age = int(values[1].(*sql.NullInt64).Int64)
+		updated.Nickname = &values[3].(*sql.NullString).String
+		return nil
+	}
+	if err := sqlgraph.UpdateNode(ctx, drv, update); err != nil {
+		t.Fatalf("cannot update user: %s", err)
+	}
+	if updated.Age != 31 || updated.Nickname == nil || *updated.Nickname != "ariel" {
+		t.Fatalf("unexpected updated user %+v", updated)
+	}
+
+	// Update().Where(user.AgeLT(25)).ClearNickname().SetAge(21).Save()
+	update = sqlgraph.NewUpdateSpec("users", entUserColumns, sqlgraph.NewFieldSpec("id", field.TypeInt))
+	update.Predicate = func(s *entsql.Selector) {
+		s.Where(entsql.LT(s.C("age"), 25))
+	}
+	update.SetField("age", field.TypeInt, 21)
+	update.ClearField("nickname", field.TypeString)
+	n, err := sqlgraph.UpdateNodes(ctx, drv, update)
+	if err != nil {
+		t.Fatalf("cannot update users: %s", err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 updated users, got %d", n)
+	}
+
+	// Delete().Where(user.Name("rotem")).Exec()
+	del := sqlgraph.NewDeleteSpec("users", sqlgraph.NewFieldSpec("id", field.TypeInt))
+	del.Predicate = func(s *entsql.Selector) {
+		s.Where(entsql.EQ(s.C("name"), "rotem"))
+	}
+	n, err = sqlgraph.DeleteNodes(ctx, drv, del)
+	if err != nil {
+		t.Fatalf("cannot delete users: %s", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 deleted user, got %d", n)
+	}
+}
+
+func TestEntEdges(t *testing.T) {
+	ctx := context.Background()
+	drv := openEnt(t, "TestEntEdges")
+
+	a8m, err := entCreateUser(ctx, drv, "a8m", 30)
+	if err != nil {
+		t.Fatalf("cannot create user: %s", err)
+	}
+	nati, err := entCreateUser(ctx, drv, "nati", 28)
+	if err != nil {
+		t.Fatalf("cannot create user: %s", err)
+	}
+
+	// CreatePet().SetName("pedro").SetOwnerID(a8m)
+	for _, name := range []string{"pedro", "xabi"} {
+		spec := sqlgraph.NewCreateSpec("pets", sqlgraph.NewFieldSpec("id", field.TypeInt))
+		spec.SetField("name", field.TypeString, name)
+		spec.Edges = append(spec.Edges, &sqlgraph.EdgeSpec{
+			Rel:     sqlgraph.M2O,
+			Inverse: true,
+			Table:   "pets",
+			Columns: []string{"user_pets"},
+			Bidi:    false,
+			Target: &sqlgraph.EdgeTarget{
+				Nodes:  []driver.Value{a8m},
+				IDSpec: sqlgraph.NewFieldSpec("id", field.TypeInt),
+			},
+		})
+		if err := sqlgraph.CreateNode(ctx, drv, spec); err != nil {
+			t.Fatalf("cannot create pet: %s", err)
+		}
+	}
+
+	// CreateGroup().SetName("GitHub").AddUserIDs(a8m, nati)
+	group := sqlgraph.NewCreateSpec("groups", sqlgraph.NewFieldSpec("id", field.TypeInt))
+	group.SetField("name", field.TypeString, "GitHub")
+	group.Edges = append(group.Edges, &sqlgraph.EdgeSpec{
+		Rel:     sqlgraph.M2M,
+		Inverse: false,
+		Table:   "group_users",
+		Columns: []string{"group_id", "user_id"},
+		Target: &sqlgraph.EdgeTarget{
+			Nodes:  []driver.Value{a8m, nati},
+			IDSpec: sqlgraph.NewFieldSpec("id", field.TypeInt),
+		},
+	})
+	if err := sqlgraph.CreateNode(ctx, drv, group); err != nil {
+		t.Fatalf("cannot create group: %s", err)
+	}
+
+	// Query().Where(user.HasPets()).All()
+	users, err := entQueryUsers(ctx, drv, func(s *entsql.Selector) {
+		sqlgraph.HasNeighbors(s, sqlgraph.NewStep(
+			sqlgraph.From("users", "id"),
+			sqlgraph.To("pets", "id"),
+			sqlgraph.Edge(sqlgraph.O2M, false, "pets", "user_pets"),
+		))
+	}, 0)
+	if err != nil {
+		t.Fatalf("cannot query users with pets: %s", err)
+	}
+	if len(users) != 1 || users[0].ID != a8m {
+		t.Fatalf("unexpected users %+v", users)
+	}
+
+	// Query().Where(user.HasGroupsWith(group.Name("GitHub"))).All()
+	users, err = entQueryUsers(ctx, drv, func(s *entsql.Selector) {
+		sqlgraph.HasNeighborsWith(s, sqlgraph.NewStep(
+			sqlgraph.From("users", "id"),
+			sqlgraph.To("groups", "id"),
+			sqlgraph.Edge(sqlgraph.M2M, true, "group_users", "group_id", "user_id"),
+		), func(s *entsql.Selector) {
+			s.Where(entsql.EQ(s.C("name"), "GitHub"))
+		})
+	}, 0)
+	if err != nil {
+		t.Fatalf("cannot query users of group: %s", err)
+	}
+	if len(users) != 2 {
+		t.Fatalf("expected 2 users, got %+v", users)
+	}
+
+	// QueryPets() of a8m, as the eager loading of WithPets does
+	rows := &entsql.Rows{}
+	query, args := entsql.Dialect(dialect.Postgres).
+		Select("id", "name", "user_pets").
+		From(entsql.Table("pets")).
+		Where(entsql.InValues("user_pets", a8m, nati)).
+		OrderBy("name").
+		Query()
+	if err := drv.Query(ctx, query, args, rows); err != nil {
+		t.Fatalf("cannot load pets: %s", err)
+	}
+	var names []string
+	for rows.Next() {
+		var id, owner int
+		var name string
+		if err := rows.Scan(&id, &name, &owner); err != nil {
+			t.Fatalf("cannot scan pet: %s", err)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if len(names) != 2 || names[0] != "pedro" {
+		t.Fatalf("unexpected pets %v", names)
+	}
+
+	// QueryUsers() of group, through the join table
+	neighbors := sqlgraph.Neighbors(dialect.Postgres, sqlgraph.NewStep(
+		sqlgraph.From("groups", "id", group.ID.Value),
+		sqlgraph.To("users", "id"),
+		sqlgraph.Edge(sqlgraph.M2M, false, "group_users", "group_id", "user_id"),
+	))
+	rows = &entsql.Rows{}
+	query, args = neighbors.Select(neighbors.C("name")).OrderBy(neighbors.C("name")).Query()
+	if err := drv.Query(ctx, query, args, rows); err != nil {
+		t.Fatalf("cannot query group users: %s", err)
+	}
+	names = nil
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("cannot scan user: %s", err)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if len(names) != 2 || names[0] != "a8m" || names[1] != "nati" {
+		t.Fatalf("unexpected group users %v", names)
+	}
+
+	// Deleting a user sets its pets owner to NULL
+	del := sqlgraph.NewDeleteSpec("users", sqlgraph.NewFieldSpec("id", field.TypeInt))
+	del.Predicate = func(s *entsql.Selector) {
+		s.Where(entsql.EQ(s.C("id"), a8m))
+	}
+	if _, err := sqlgraph.DeleteNodes(ctx, drv, del); err != nil {
+		t.Fatalf("cannot delete user: %s", err)
+	}
+	count, err := sqlgraph.CountNodes(ctx, drv, &sqlgraph.QuerySpec{
+		Node: &sqlgraph.NodeSpec{Table: "pets", ID: sqlgraph.NewFieldSpec("id", field.TypeInt)},
+		Predicate: func(s *entsql.Selector) {
+			s.Where(entsql.IsNull(s.C("user_pets")))
+		},
+	})
+	if err != nil {
+		t.Fatalf("cannot count pets: %s", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 pets without owner, got %d", count)
+	}
+}
+
+func TestEntUpsert(t *testing.T) {
+	ctx := context.Background()
+	drv := openEnt(t, "TestEntUpsert")
+
+	if _, err := entCreateUser(ctx, drv, "a8m", 30); err != nil {
+		t.Fatalf("cannot create user: %s", err)
+	}
+
+	// Create().SetName("a8m").SetAge(31).OnConflictColumns(user.FieldName).UpdateNewValues().ID()
+	spec := sqlgraph.NewCreateSpec("users", sqlgraph.NewFieldSpec("id", field.TypeInt))
+	spec.SetField("age", field.TypeInt, 31)
+	spec.SetField("name", field.TypeString, "a8m")
+	spec.OnConflict = []entsql.ConflictOption{
+		entsql.ConflictColumns("name"),
+		entsql.ResolveWithNewValues(),
+	}
+	if err := sqlgraph.CreateNode(ctx, drv, spec); err != nil {
+		t.Fatalf("cannot upsert user: %s", err)
+	}
+
+	users, err := entQueryUsers(ctx, drv, nil, 0)
+	if err != nil {
+		t.Fatalf("cannot query users: %s", err)
+	}
+	if len(users) != 1 || users[0].Age != 31 || int64(users[0].ID) != spec.ID.Value.(int64) {
+		t.Fatalf("unexpected users %+v", users)
+	}
+
+	// Query().Aggregate(ent.Sum(user.FieldAge)).Int()
+	rows := &entsql.Rows{}
+	s := entsql.Dialect(dialect.Postgres).Select().From(entsql.Table("users"))
+	query, args := s.Select(entsql.Sum(s.C("age"))).Query()
+	if err := drv.Query(ctx, query, args, rows); err != nil {
+		t.Fatalf("cannot aggregate: %s", err)
+	}
+	sum, err := entsql.ScanInt(rows)
+	if err != nil {
+		t.Fatalf("cannot scan sum: %s", err)
+	}
+	if sum != 31 {
+		t.Fatalf("expected sum 31, got %d", sum)
+	}
+}
@@ -0,0 +1,35 @@
+package ramsql
+
+import (
+	"github.com/proullon/ramsql/engine/agnostic"
+	"github.com/proullon/ramsql/engine/executor"
+)
+
+// FaultProfile describes failures an engine injects in connections and transactions.
+type FaultProfile = executor.FaultProfile
+
+// Error is an error carrying a SQLSTATE code.
+type Error = agnostic.Error
+
+var (
+	// ErrSerializationFailure matches errors with SQLSTATE 40001 using errors.Is
+	ErrSerializationFailure = &Error{Code: agnostic.SerializationFailure}
+	// ErrDiskFull matches errors with SQLSTATE 53100 using errors.Is
+	ErrDiskFull = &Error{Code: agnostic.DiskFull}
+)
+
+// SetFaultProfile replaces the fault profile of the engine opened with dsn.
+func SetFaultProfile(dsn string, p FaultProfile) error {
+	return defaultDriver.SetFaultProfile(dsn, p)
+}
+
+// SetFaultProfile replaces the fault profile of the engine opened with dsn.
+func (rs *Driver) SetFaultProfile(dsn string, p FaultProfile) error {
+	e, err := rs.engine(dsn)
+	if err != nil {
+		return err
+	}
+
+	e.SetFaultProfile(p)
+	return nil
+}
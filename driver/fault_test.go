@@ -0,0 +1,144 @@
+package ramsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFaultSerializationFailure(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestFaultSerializationFailure")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	err = SetFaultProfile("TestFaultSerializationFailure", FaultProfile{SerializationFailurePercent: 100})
+	if err != nil {
+		t.Fatalf("cannot set fault profile: %s", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("cannot begin: %s", err)
+	}
+	_, err = tx.Exec(`INSERT INTO account (email) VALUES ('foo@bar.com')`)
+	if !errors.Is(err, ErrSerializationFailure) {
+		t.Fatalf("expected serialization failure, got %v", err)
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.SQLState() != "40001" {
+		t.Fatalf("expected SQLSTATE 40001, got %v", err)
+	}
+	tx.Rollback()
+
+	err = SetFaultProfile("TestFaultSerializationFailure", FaultProfile{})
+	if err != nil {
+		t.Fatalf("cannot set fault profile: %s", err)
+	}
+	_, err = db.Exec(`INSERT INTO account (email) VALUES ('foo@bar.com')`)
+	if err != nil {
+		t.Fatalf("expected no fault, got %s", err)
+	}
+}
+
+func TestFaultDiskFull(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestFaultDiskFull?disk_full=100")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT)`)
+	if !errors.Is(err, ErrDiskFull) {
+		t.Fatalf("expected disk full error, got %v", err)
+	}
+
+	// reads are not affected
+	var n int
+	err = db.QueryRow(`SELECT 1`).Scan(&n)
+	if err != nil {
+		t.Fatalf("expected no fault on read, got %s", err)
+	}
+}
+
+func TestFaultBadConn(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestFaultBadConn?bad_conn=100")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT)`)
+	if !errors.Is(err, driver.ErrBadConn) {
+		t.Fatalf("expected bad connection error, got %v", err)
+	}
+}
+
+func TestFaultLatency(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestFaultLatency?latency=20ms")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	start := time.Now()
+	_, err = db.Exec(`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Fatalf("expected at least 20ms latency, got %s", d)
+	}
+
+	err = SetFaultProfile("TestFaultLatency?latency=20ms", FaultProfile{Latency: time.Minute})
+	if err != nil {
+		t.Fatalf("cannot set fault profile: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = db.ExecContext(ctx, `INSERT INTO account (email) VALUES ('foo@bar.com')`)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestFaultSeed(t *testing.T) {
+	run := func(dsn string) []bool {
+		db, err := sql.Open("ramsql", dsn)
+		if err != nil {
+			t.Fatalf("sql.Open : Error : %s\n", err)
+		}
+		defer db.Close()
+
+		var failures []bool
+		for i := 0; i < 20; i++ {
+			_, err := db.Exec(`SELECT 1`)
+			failures = append(failures, err != nil)
+		}
+		return failures
+	}
+
+	a := run("TestFaultSeedA?serialization_failure=50&fault_seed=42")
+	b := run("TestFaultSeedB?serialization_failure=50&fault_seed=42")
+	var count int
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("expected same faults with same seed, got %v and %v", a, b)
+		}
+		if a[i] {
+			count++
+		}
+	}
+	if count == 0 || count == len(a) {
+		t.Fatalf("expected some statements to fail, got %d failures", count)
+	}
+}
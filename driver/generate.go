@@ -0,0 +1,24 @@
+package ramsql
+
+// Generate inserts n synthetic rows in relation of the engine opened with dsn.
+// Empty schema means default schema.
+//
+// Values match attributes type and name, foreign keys reference existing rows
+// and unique attributes never collide. The same seed generates the same rows.
+// The equivalent statement is:
+//
+//	GENERATE 100 ROWS FOR schema.relation SEED 42
+func Generate(dsn string, schema, relation string, n int, seed int64) (int64, error) {
+	return defaultDriver.Generate(dsn, schema, relation, n, seed)
+}
+
+// Generate inserts n synthetic rows in relation of the engine opened with dsn.
+// Empty schema means default schema.
+func (rs *Driver) Generate(dsn string, schema, relation string, n int, seed int64) (int64, error) {
+	e, err := rs.engine(dsn)
+	if err != nil {
+		return 0, err
+	}
+
+	return e.Generate(schema, relation, n, seed)
+}
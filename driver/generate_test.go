@@ -0,0 +1,200 @@
+package ramsql
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestGenerate")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	init := []string{
+		`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT UNIQUE, name TEXT, age INT, created_at TIMESTAMP DEFAULT now())`,
+		`CREATE TABLE address (id BIGSERIAL PRIMARY KEY, account_id BIGINT REFERENCES account (id), city TEXT)`,
+	}
+	for _, q := range init {
+		_, err = db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	// children cannot be generated without parents
+	_, err = db.Exec(`GENERATE 10 ROWS FOR address`)
+	if err == nil {
+		t.Fatalf("expected error generating rows referencing an empty relation")
+	}
+
+	res, err := db.Exec(`GENERATE 50 ROWS FOR account SEED 42`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil || n != 50 {
+		t.Fatalf("expected 50 rows affected, got %d (%v)", n, err)
+	}
+
+	_, err = db.Exec(`GENERATE 200 ROWS FOR address SEED 42`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	emails := make(map[string]struct{})
+	rows, err := db.Query(`SELECT email FROM account`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			t.Fatalf("cannot scan: %s", err)
+		}
+		emails[email] = struct{}{}
+	}
+	rows.Close()
+	if len(emails) != 50 {
+		t.Fatalf("expected 50 distinct emails, got %d", len(emails))
+	}
+
+	rows, err = db.Query(`SELECT address.account_id, account.email FROM address JOIN account ON address.account_id = account.id`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	defer rows.Close()
+	count := 0
+	for rows.Next() {
+		var id int64
+		var email string
+		if err := rows.Scan(&id, &email); err != nil {
+			t.Fatalf("cannot scan: %s", err)
+		}
+		if !strings.Contains(email, "@") {
+			t.Fatalf("expected generated email, got %s", email)
+		}
+		count++
+	}
+	if count != 200 {
+		t.Fatalf("expected 200 addresses referencing accounts, got %d", count)
+	}
+}
+
+func TestGenerateSeed(t *testing.T) {
+	names := func(dsn string) []string {
+		db, err := sql.Open("ramsql", dsn)
+		if err != nil {
+			t.Fatalf("sql.Open : Error : %s\n", err)
+		}
+		defer db.Close()
+
+		_, err = db.Exec(`CREATE TABLE champion (id BIGSERIAL PRIMARY KEY, name TEXT UNIQUE, score INT)`)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+		_, err = Generate(dsn, "", "champion", 20, 7)
+		if err != nil {
+			t.Fatalf("cannot generate: %s", err)
+		}
+
+		rows, err := db.Query(`SELECT name FROM champion ORDER BY id`)
+		if err != nil {
+			t.Fatalf("sql.Query: Error: %s\n", err)
+		}
+		defer rows.Close()
+		var l []string
+		for rows.Next() {
+			var n string
+			if err := rows.Scan(&n); err != nil {
+				t.Fatalf("cannot scan: %s", err)
+			}
+			l = append(l, n)
+		}
+		return l
+	}
+
+	a := names("TestGenerateSeedA")
+	b := names("TestGenerateSeedB")
+	if len(a) != 20 || strings.Join(a, ",") != strings.Join(b, ",") {
+		t.Fatalf("expected same rows with same seed, got %v and %v", a, b)
+	}
+}
+
+func TestGenerateArrays(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestGenerateArrays")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE post (id BIGSERIAL PRIMARY KEY, tags TEXT[], scores INT[], codes TEXT[] UNIQUE)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	_, err = db.Exec(`GENERATE 20 ROWS FOR post SEED 3`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	rows, err := db.Query(`SELECT array_length(tags, 1), array_length(scores, 1), array_length(codes, 1) FROM post`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	defer rows.Close()
+	count := 0
+	for rows.Next() {
+		var tags, scores, codes int64
+		if err := rows.Scan(&tags, &scores, &codes); err != nil {
+			t.Fatalf("cannot scan: %s", err)
+		}
+		for _, l := range []int64{tags, scores, codes} {
+			if l < 1 || l > 3 {
+				t.Fatalf("expected 1 to 3 array elements, got %d", l)
+			}
+		}
+		count++
+	}
+	if count != 20 {
+		t.Fatalf("expected 20 rows, got %d", count)
+	}
+}
+
+func TestGenerateSmallDomain(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestGenerateSmallDomain")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	init := []string{
+		`CREATE TABLE flag (id BIGSERIAL PRIMARY KEY, enabled BOOLEAN UNIQUE)`,
+		`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT)`,
+		`CREATE TABLE profile (id BIGSERIAL PRIMARY KEY, account_id BIGINT UNIQUE REFERENCES account (id))`,
+		`GENERATE 2 ROWS FOR account`,
+	}
+	for _, q := range init {
+		_, err = db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	// a unique boolean has 2 values, a unique foreign key as many as parent rows
+	for _, relation := range []string{"flag", "profile"} {
+		_, err = db.Exec(`GENERATE 3 ROWS FOR ` + relation)
+		if err == nil || !strings.Contains(err.Error(), "must be unique but has only 2 distinct values") {
+			t.Fatalf("expected domain error generating 3 rows for %s, got %v", relation, err)
+		}
+		_, err = db.Exec(`GENERATE 2 ROWS FOR ` + relation)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+		_, err = db.Exec(`GENERATE 1 ROWS FOR ` + relation)
+		if err == nil {
+			t.Fatalf("expected domain error generating rows for full %s", relation)
+		}
+	}
+}
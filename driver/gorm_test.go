@@ -6,6 +6,7 @@ import (
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
@@ -311,3 +312,362 @@ func TestGormCompositeForeignKey(t *testing.T) {
 		t.Fatalf("cannot delete category after control removed: %s", err)
 	}
 }
+
+func TestGormReturning(t *testing.T) {
+	ramdb, err := sql.Open("ramsql", "TestGormReturning")
+	if err != nil {
+		t.Fatalf("cannot open db: %s", err)
+	}
+
+	db, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: ramdb,
+	}),
+		&gorm.Config{})
+	if err != nil {
+		t.Fatalf("cannot setup gorm: %s", err)
+	}
+
+	err = db.AutoMigrate(&Product{})
+	if err != nil {
+		t.Fatalf("cannot automigrate: %s", err)
+	}
+	for _, code := range []string{"D42", "F42"} {
+		err = db.Create(&Product{Code: code, Price: 100}).Error
+		if err != nil {
+			t.Fatalf("cannot create: %s", err)
+		}
+	}
+
+	var products []Product
+	err = db.Model(&products).Clauses(clause.Returning{}).Where("price = ?", 100).Update("price", 200).Error
+	if err != nil {
+		t.Fatalf("cannot update: %s", err)
+	}
+	if len(products) != 2 || products[0].Price != 200 || products[1].Price != 200 || products[0].Code == "" {
+		t.Fatalf("expected 2 updated products, got %+v", products)
+	}
+
+	var deleted []Product
+	err = db.Unscoped().Clauses(clause.Returning{Columns: []clause.Column{{Name: "code"}}}).Where("code = ?", "D42").Delete(&deleted).Error
+	if err != nil {
+		t.Fatalf("cannot delete: %s", err)
+	}
+	if len(deleted) != 1 || deleted[0].Code != "D42" {
+		t.Fatalf("expected D42 to be returned, got %+v", deleted)
+	}
+}
+
+type MigratorUser struct {
+	ID    uint `gorm:"primaryKey"`
+	Name  string
+	Email string  `gorm:"uniqueIndex"`
+	Code  string  `gorm:"index:idx_migrator_users_code"`
+	Login *string `gorm:"unique"`
+	Age   int32
+}
+
+func (MigratorUser) TableName() string { return "migrator_users" }
+
+// MigratorUserV2 is MigratorUser with added, resized and retyped fields
+type MigratorUserV2 struct {
+	ID       uint `gorm:"primaryKey"`
+	Name     string
+	Email    string  `gorm:"uniqueIndex"`
+	Code     string  `gorm:"index:idx_migrator_users_code"`
+	Login    *string `gorm:"unique"`
+	Age      int64
+	Nickname string `gorm:"size:32;not null;default:'anonymous'"`
+	Score    float64
+	Team     string `gorm:"index"`
+}
+
+func (MigratorUserV2) TableName() string { return "migrator_users" }
+
+type MigratorPet struct {
+	ID     uint `gorm:"primaryKey"`
+	Name   string
+	UserID uint
+	User   MigratorUser `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+func (MigratorPet) TableName() string { return "migrator_pets" }
+
+func openGormMigrator(t *testing.T, name string) *gorm.DB {
+	ramdb, err := sql.Open("ramsql", name)
+	if err != nil {
+		t.Fatalf("cannot open db: %s", err)
+	}
+	t.Cleanup(func() { ramdb.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: ramdb,
+	}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("cannot setup gorm: %s", err)
+	}
+
+	if err := db.AutoMigrate(&MigratorUser{}, &MigratorPet{}); err != nil {
+		t.Fatalf("cannot automigrate: %s", err)
+	}
+	return db
+}
+
+func TestGormMigratorGetTables(t *testing.T) {
+	db := openGormMigrator(t, "TestGormMigratorGetTables")
+
+	tables, err := db.Migrator().GetTables()
+	if err != nil {
+		t.Fatalf("cannot get tables: %s", err)
+	}
+	if len(tables) != 2 || tables[0] != "migrator_pets" || tables[1] != "migrator_users" {
+		t.Fatalf("unexpected tables %v", tables)
+	}
+}
+
+func TestGormMigratorHasColumn(t *testing.T) {
+	db := openGormMigrator(t, "TestGormMigratorHasColumn")
+
+	m := db.Migrator()
+	if !m.HasColumn(&MigratorUser{}, "Email") || !m.HasColumn(&MigratorUser{}, "age") {
+		t.Fatalf("expected columns email and age")
+	}
+	if m.HasColumn(&MigratorUser{}, "nickname") {
+		t.Fatalf("unexpected column nickname")
+	}
+}
+
+func TestGormMigratorHasIndex(t *testing.T) {
+	db := openGormMigrator(t, "TestGormMigratorHasIndex")
+
+	m := db.Migrator()
+	if !m.HasIndex(&MigratorUser{}, "idx_migrator_users_code") || !m.HasIndex(&MigratorUser{}, "Email") {
+		t.Fatalf("expected indexes on code and email")
+	}
+	if m.HasIndex(&MigratorUser{}, "idx_migrator_users_name") {
+		t.Fatalf("unexpected index on name")
+	}
+
+	if err := m.DropIndex(&MigratorUser{}, "idx_migrator_users_code"); err != nil {
+		t.Fatalf("cannot drop index: %s", err)
+	}
+	if m.HasIndex(&MigratorUser{}, "idx_migrator_users_code") {
+		t.Fatalf("expected index to be dropped")
+	}
+	if err := m.CreateIndex(&MigratorUser{}, "Code"); err != nil {
+		t.Fatalf("cannot create index: %s", err)
+	}
+	if !m.HasIndex(&MigratorUser{}, "idx_migrator_users_code") {
+		t.Fatalf("expected index to be created")
+	}
+}
+
+func TestGormMigratorRenameIndex(t *testing.T) {
+	db := openGormMigrator(t, "TestGormMigratorRenameIndex")
+
+	m := db.Migrator()
+	if err := m.RenameIndex(&MigratorUser{}, "idx_migrator_users_code", "idx_code"); err != nil {
+		t.Fatalf("cannot rename index: %s", err)
+	}
+	if m.HasIndex(&MigratorUser{}, "idx_migrator_users_code") || !m.HasIndex(&MigratorUser{}, "idx_code") {
+		t.Fatalf("expected index to be renamed")
+	}
+}
+
+func TestGormMigratorHasConstraint(t *testing.T) {
+	db := openGormMigrator(t, "TestGormMigratorHasConstraint")
+
+	m := db.Migrator()
+	if !m.HasConstraint(&MigratorPet{}, "User") || !m.HasConstraint(&MigratorPet{}, "fk_migrator_pets_user") {
+		t.Fatalf("expected foreign key constraint fk_migrator_pets_user")
+	}
+	if m.HasConstraint(&MigratorPet{}, "fk_unknown") {
+		t.Fatalf("unexpected constraint fk_unknown")
+	}
+
+	if err := m.DropConstraint(&MigratorPet{}, "User"); err != nil {
+		t.Fatalf("cannot drop constraint: %s", err)
+	}
+	if m.HasConstraint(&MigratorPet{}, "User") {
+		t.Fatalf("expected constraint to be dropped")
+	}
+	if err := m.CreateConstraint(&MigratorPet{}, "User"); err != nil {
+		t.Fatalf("cannot create constraint: %s", err)
+	}
+	if !m.HasConstraint(&MigratorPet{}, "User") {
+		t.Fatalf("expected constraint to be created")
+	}
+}
+
+func TestGormMigratorColumnTypes(t *testing.T) {
+	db := openGormMigrator(t, "TestGormMigratorColumnTypes")
+
+	columnTypes, err := db.Migrator().ColumnTypes(&MigratorUser{})
+	if err != nil {
+		t.Fatalf("cannot get column types: %s", err)
+	}
+	if len(columnTypes) != 6 {
+		t.Fatalf("expected 6 columns, got %d", len(columnTypes))
+	}
+
+	byName := make(map[string]gorm.ColumnType)
+	for _, c := range columnTypes {
+		byName[c.Name()] = c
+	}
+
+	id := byName["id"]
+	if pk, _ := id.PrimaryKey(); !pk {
+		t.Fatalf("expected id to be primary key")
+	}
+	if ai, _ := id.AutoIncrement(); !ai {
+		t.Fatalf("expected id to be auto incremented")
+	}
+	if id.DatabaseTypeName() != "int8" {
+		t.Fatalf("unexpected id type %s", id.DatabaseTypeName())
+	}
+	if unique, _ := byName["login"].Unique(); !unique {
+		t.Fatalf("expected login to be unique")
+	}
+	if unique, _ := byName["email"].Unique(); unique {
+		t.Fatalf("expected email to be indexed, not constrained")
+	}
+	if nullable, _ := byName["name"].Nullable(); !nullable {
+		t.Fatalf("expected name to be nullable")
+	}
+	if byName["age"].DatabaseTypeName() != "int4" {
+		t.Fatalf("unexpected age type %s", byName["age"].DatabaseTypeName())
+	}
+}
+
+func TestGormMigratorAlterColumn(t *testing.T) {
+	db := openGormMigrator(t, "TestGormMigratorAlterColumn")
+
+	if err := db.Create(&MigratorUser{Name: "Alice", Email: "alice@example.com", Age: 32}).Error; err != nil {
+		t.Fatalf("cannot create: %s", err)
+	}
+
+	if err := db.Migrator().AlterColumn(&MigratorUserV2{}, "Age"); err != nil {
+		t.Fatalf("cannot alter column: %s", err)
+	}
+	columnTypes, err := db.Migrator().ColumnTypes(&MigratorUserV2{})
+	if err != nil {
+		t.Fatalf("cannot get column types: %s", err)
+	}
+	for _, c := range columnTypes {
+		if c.Name() == "age" && c.DatabaseTypeName() != "int8" {
+			t.Fatalf("expected age to be altered to int8, got %s", c.DatabaseTypeName())
+		}
+	}
+
+	var user MigratorUserV2
+	if err := db.First(&user, "email = ?", "alice@example.com").Error; err != nil {
+		t.Fatalf("cannot read: %s", err)
+	}
+	if user.Age != 32 {
+		t.Fatalf("expected age to be kept, got %d", user.Age)
+	}
+}
+
+func TestGormMigratorRenameColumn(t *testing.T) {
+	db := openGormMigrator(t, "TestGormMigratorRenameColumn")
+
+	if err := db.Create(&MigratorUser{Name: "Alice", Email: "alice@example.com", Code: "A1"}).Error; err != nil {
+		t.Fatalf("cannot create: %s", err)
+	}
+
+	m := db.Migrator()
+	if err := m.RenameColumn(&MigratorUser{}, "code", "reference"); err != nil {
+		t.Fatalf("cannot rename column: %s", err)
+	}
+	if m.HasColumn(&MigratorUser{}, "code") || !m.HasColumn(&MigratorUser{}, "reference") {
+		t.Fatalf("expected column code to be renamed to reference")
+	}
+
+	var reference string
+	if err := db.Raw(`SELECT reference FROM migrator_users WHERE email = ?`, "alice@example.com").Scan(&reference).Error; err != nil {
+		t.Fatalf("cannot read: %s", err)
+	}
+	if reference != "A1" {
+		t.Fatalf("expected value to be kept, got '%s'", reference)
+	}
+}
+
+func TestGormMigratorDropColumn(t *testing.T) {
+	db := openGormMigrator(t, "TestGormMigratorDropColumn")
+
+	if err := db.Create(&MigratorUser{Name: "Alice", Email: "alice@example.com", Code: "A1"}).Error; err != nil {
+		t.Fatalf("cannot create: %s", err)
+	}
+
+	m := db.Migrator()
+	if err := m.DropColumn(&MigratorUser{}, "Code"); err != nil {
+		t.Fatalf("cannot drop column: %s", err)
+	}
+	if m.HasColumn(&MigratorUser{}, "code") {
+		t.Fatalf("expected column code to be dropped")
+	}
+	if m.HasIndex(&MigratorUser{}, "idx_migrator_users_code") {
+		t.Fatalf("expected index of dropped column to be dropped")
+	}
+
+	var name string
+	if err := db.Raw(`SELECT name FROM migrator_users WHERE email = ?`, "alice@example.com").Scan(&name).Error; err != nil {
+		t.Fatalf("cannot read: %s", err)
+	}
+	if name != "Alice" {
+		t.Fatalf("unexpected name '%s'", name)
+	}
+}
+
+func TestGormMigratorAutoMigrateChanges(t *testing.T) {
+	db := openGormMigrator(t, "TestGormMigratorAutoMigrateChanges")
+
+	if err := db.Create(&MigratorUser{Name: "Alice", Email: "alice@example.com", Age: 32}).Error; err != nil {
+		t.Fatalf("cannot create: %s", err)
+	}
+
+	// re-running an unchanged migration is a no-op
+	if err := db.AutoMigrate(&MigratorUser{}, &MigratorPet{}); err != nil {
+		t.Fatalf("cannot re-run automigrate: %s", err)
+	}
+
+	if err := db.AutoMigrate(&MigratorUserV2{}); err != nil {
+		t.Fatalf("cannot automigrate changed model: %s", err)
+	}
+
+	m := db.Migrator()
+	for _, column := range []string{"nickname", "score", "team"} {
+		if !m.HasColumn(&MigratorUserV2{}, column) {
+			t.Fatalf("expected column %s to be added", column)
+		}
+	}
+	if !m.HasIndex(&MigratorUserV2{}, "idx_migrator_users_team") {
+		t.Fatalf("expected index on team to be created")
+	}
+
+	var user MigratorUserV2
+	if err := db.First(&user, "email = ?", "alice@example.com").Error; err != nil {
+		t.Fatalf("cannot read: %s", err)
+	}
+	if user.Age != 32 || user.Nickname != "anonymous" || user.Score != 0 {
+		t.Fatalf("unexpected migrated row %+v", user)
+	}
+
+	user2 := MigratorUserV2{Name: "Bob", Email: "bob@example.com", Nickname: "bobby", Score: 4.5, Team: "blue", Age: 1 << 40}
+	if err := db.Create(&user2).Error; err != nil {
+		t.Fatalf("cannot create with new fields: %s", err)
+	}
+	var bob MigratorUserV2
+	if err := db.First(&bob, "team = ?", "blue").Error; err != nil {
+		t.Fatalf("cannot read with new fields: %s", err)
+	}
+	if bob.Nickname != "bobby" || bob.Age != 1<<40 {
+		t.Fatalf("unexpected row %+v", bob)
+	}
+
+	// and is idempotent
+	if err := db.AutoMigrate(&MigratorUserV2{}); err != nil {
+		t.Fatalf("cannot re-run automigrate on changed model: %s", err)
+	}
+}
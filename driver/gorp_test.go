@@ -0,0 +1,204 @@
+package ramsql
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/go-gorp/gorp"
+)
+
+type GorpInvoice struct {
+	ID       int64  `db:"id"`
+	Created  int64  `db:"created"`
+	Updated  int64  `db:"updated"`
+	Memo     string `db:"memo, size:200"`
+	PersonID int64  `db:"person_id"`
+	Version  int64  `db:"version"`
+	Ignored  string `db:"-"`
+}
+
+// PreInsert implements gorp.HasPreInsert
+func (i *GorpInvoice) PreInsert(s gorp.SqlExecutor) error {
+	i.Created = time.Now().UnixNano()
+	i.Updated = i.Created
+	return nil
+}
+
+// PreUpdate implements gorp.HasPreUpdate
+func (i *GorpInvoice) PreUpdate(s gorp.SqlExecutor) error {
+	i.Updated = time.Now().UnixNano()
+	return nil
+}
+
+type GorpPerson struct {
+	ID    int64          `db:"id"`
+	Name  string         `db:"name"`
+	Email sql.NullString `db:"email"`
+}
+
+func openGorp(t *testing.T, name string) *gorp.DbMap {
+	t.Helper()
+
+	db, err := sql.Open("ramsql", name)
+	if err != nil {
+		t.Fatalf("sql.Open failed: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	dbmap := &gorp.DbMap{Db: db, Dialect: gorp.PostgresDialect{}}
+	dbmap.AddTableWithName(GorpInvoice{}, "invoice").SetKeys(true, "ID").SetVersionCol("Version")
+	dbmap.AddTableWithName(GorpPerson{}, "person").SetKeys(true, "ID").ColMap("Name").SetUnique(true).SetNotNull(true)
+
+	if err := dbmap.CreateTablesIfNotExists(); err != nil {
+		t.Fatalf("cannot create tables: %s", err)
+	}
+	// and again, tables already exist
+	if err := dbmap.CreateTablesIfNotExists(); err != nil {
+		t.Fatalf("cannot create tables twice: %s", err)
+	}
+
+	return dbmap
+}
+
+func TestGorpCRUD(t *testing.T) {
+	dbmap := openGorp(t, "TestGorpCRUD")
+
+	p := &GorpPerson{Name: "Alice"}
+	if err := dbmap.Insert(p); err != nil {
+		t.Fatalf("cannot insert person: %s", err)
+	}
+	if p.ID == 0 {
+		t.Fatalf("expected person id to be set")
+	}
+
+	inv1 := &GorpInvoice{Memo: "first order", PersonID: p.ID}
+	inv2 := &GorpInvoice{Memo: "second order", PersonID: p.ID}
+	if err := dbmap.Insert(inv1, inv2); err != nil {
+		t.Fatalf("cannot insert invoices: %s", err)
+	}
+	if inv1.ID == 0 || inv2.ID == 0 || inv1.ID == inv2.ID {
+		t.Fatalf("unexpected invoice ids %d and %d", inv1.ID, inv2.ID)
+	}
+	if inv1.Version != 1 || inv1.Created == 0 {
+		t.Fatalf("unexpected invoice %+v", inv1)
+	}
+
+	obj, err := dbmap.Get(GorpInvoice{}, inv1.ID)
+	if err != nil {
+		t.Fatalf("cannot get invoice: %s", err)
+	}
+	got, ok := obj.(*GorpInvoice)
+	if !ok || got.Memo != "first order" || got.PersonID != p.ID {
+		t.Fatalf("unexpected invoice %+v", obj)
+	}
+
+	obj, err = dbmap.Get(GorpInvoice{}, int64(1000))
+	if err != nil || obj != nil {
+		t.Fatalf("expected no invoice, got %+v (%v)", obj, err)
+	}
+
+	got.Memo = "first order, updated"
+	n, err := dbmap.Update(got)
+	if err != nil {
+		t.Fatalf("cannot update invoice: %s", err)
+	}
+	if n != 1 || got.Version != 2 {
+		t.Fatalf("expected 1 updated invoice with version 2, got %d with version %d", n, got.Version)
+	}
+
+	// inv1 is stale
+	inv1.Memo = "stale"
+	_, err = dbmap.Update(inv1)
+	if _, ok := err.(gorp.OptimisticLockError); !ok {
+		t.Fatalf("expected optimistic lock error, got %v", err)
+	}
+
+	n, err = dbmap.Delete(inv2)
+	if err != nil {
+		t.Fatalf("cannot delete invoice: %s", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 deleted invoice, got %d", n)
+	}
+
+	count, err := dbmap.SelectInt(`SELECT COUNT(*) FROM invoice`)
+	if err != nil {
+		t.Fatalf("cannot count invoices: %s", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 invoice, got %d", count)
+	}
+
+	// unique name
+	if err := dbmap.Insert(&GorpPerson{Name: "Alice"}); err == nil {
+		t.Fatalf("expected unique constraint violation")
+	}
+}
+
+func TestGorpSelect(t *testing.T) {
+	dbmap := openGorp(t, "TestGorpSelect")
+
+	for _, name := range []string{"Alice", "Bob", "Carol"} {
+		if err := dbmap.Insert(&GorpPerson{Name: name}); err != nil {
+			t.Fatalf("cannot insert person: %s", err)
+		}
+	}
+
+	var people []GorpPerson
+	_, err := dbmap.Select(&people, `SELECT * FROM person WHERE name <> $1 ORDER BY name`, "Bob")
+	if err != nil {
+		t.Fatalf("cannot select people: %s", err)
+	}
+	if len(people) != 2 || people[0].Name != "Alice" || people[1].Name != "Carol" || people[0].Email.Valid {
+		t.Fatalf("unexpected people %+v", people)
+	}
+
+	var bob GorpPerson
+	if err := dbmap.SelectOne(&bob, `SELECT * FROM person WHERE name = :name`, map[string]any{"name": "Bob"}); err != nil {
+		t.Fatalf("cannot select one: %s", err)
+	}
+	if bob.ID == 0 {
+		t.Fatalf("unexpected person %+v", bob)
+	}
+
+	name, err := dbmap.SelectStr(`SELECT name FROM person WHERE id = $1`, bob.ID)
+	if err != nil {
+		t.Fatalf("cannot select str: %s", err)
+	}
+	if name != "Bob" {
+		t.Fatalf("expected Bob, got %s", name)
+	}
+
+	email, err := dbmap.SelectNullStr(`SELECT email FROM person WHERE id = $1`, bob.ID)
+	if err != nil {
+		t.Fatalf("cannot select null str: %s", err)
+	}
+	if email.Valid {
+		t.Fatalf("expected NULL email, got %v", email)
+	}
+
+	tx, err := dbmap.Begin()
+	if err != nil {
+		t.Fatalf("cannot begin: %s", err)
+	}
+	bob.Email = sql.NullString{String: "bob@example.com", Valid: true}
+	if _, err := tx.Update(&bob); err != nil {
+		t.Fatalf("cannot update in transaction: %s", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("cannot commit: %s", err)
+	}
+
+	email, err = dbmap.SelectNullStr(`SELECT email FROM person WHERE id = $1`, bob.ID)
+	if err != nil {
+		t.Fatalf("cannot select null str: %s", err)
+	}
+	if email.String != "bob@example.com" {
+		t.Fatalf("unexpected email %v", email)
+	}
+
+	if err := dbmap.DropTablesIfExists(); err != nil {
+		t.Fatalf("cannot drop tables: %s", err)
+	}
+}
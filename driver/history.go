@@ -0,0 +1,41 @@
+package ramsql
+
+import (
+	"github.com/proullon/ramsql/engine/executor"
+)
+
+// QueryRecord describes a statement executed by an engine.
+type QueryRecord = executor.QueryRecord
+
+// QueryHistory returns statements executed by the engine opened with dsn, from oldest to newest.
+//
+// History is also available with SQL in the ramsql.query_history table.
+func QueryHistory(dsn string) ([]QueryRecord, error) {
+	return defaultDriver.QueryHistory(dsn)
+}
+
+// ClearQueryHistory removes all statements recorded by the engine opened with dsn.
+func ClearQueryHistory(dsn string) error {
+	return defaultDriver.ClearQueryHistory(dsn)
+}
+
+// QueryHistory returns statements executed by the engine opened with dsn, from oldest to newest.
+func (rs *Driver) QueryHistory(dsn string) ([]QueryRecord, error) {
+	e, err := rs.engine(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.History(), nil
+}
+
+// ClearQueryHistory removes all statements recorded by the engine opened with dsn.
+func (rs *Driver) ClearQueryHistory(dsn string) error {
+	e, err := rs.engine(dsn)
+	if err != nil {
+		return err
+	}
+
+	e.ClearHistory()
+	return nil
+}
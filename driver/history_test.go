@@ -0,0 +1,155 @@
+package ramsql
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func TestQueryHistory(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestQueryHistory")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	init := []string{
+		`CREATE TABLE orders (id BIGSERIAL PRIMARY KEY, status TEXT)`,
+		`INSERT INTO orders (status) VALUES ('new')`,
+		`INSERT INTO orders (status) VALUES ('new')`,
+	}
+	for _, q := range init {
+		_, err = db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	_, err = db.Exec(`UPDATE orders SET status = $1 WHERE id = $2`, "paid", 2)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	_, err = db.Exec(`UPDATE unknown SET status = 'paid'`)
+	if err == nil {
+		t.Fatalf("expected an error updating unknown table")
+	}
+
+	history, err := QueryHistory("TestQueryHistory")
+	if err != nil {
+		t.Fatalf("cannot get query history: %s", err)
+	}
+
+	var updates []QueryRecord
+	for _, r := range history {
+		if strings.HasPrefix(r.Query, "UPDATE orders") {
+			updates = append(updates, r)
+		}
+	}
+	if len(updates) != 1 {
+		t.Fatalf("expected exactly 1 UPDATE on orders, got %d", len(updates))
+	}
+	u := updates[0]
+	if u.Rows != 1 {
+		t.Errorf("expected 1 row affected, got %d", u.Rows)
+	}
+	if u.Err != nil {
+		t.Errorf("unexpected error recorded: %s", u.Err)
+	}
+	if len(u.Args) != 2 || u.Args[0].Value != "paid" {
+		t.Errorf("unexpected args recorded: %v", u.Args)
+	}
+	if u.TxID == 0 {
+		t.Errorf("expected a transaction id")
+	}
+
+	last := history[len(history)-1]
+	if last.Err == nil {
+		t.Errorf("expected last statement to record an error")
+	}
+
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM ramsql.query_history WHERE error IS NOT NULL`).Scan(&count)
+	if err != nil {
+		t.Fatalf("cannot query history table: %s", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 failed statement, got %d", count)
+	}
+
+	var query string
+	var rows int64
+	err = db.QueryRow(`SELECT query, rows FROM ramsql.query_history WHERE query LIKE 'UPDATE orders%'`).Scan(&query, &rows)
+	if err != nil {
+		t.Fatalf("cannot query history table: %s", err)
+	}
+	if rows != 1 {
+		t.Fatalf("expected 1 row affected, got %d", rows)
+	}
+
+	_, err = db.Exec(`DELETE FROM ramsql.query_history`)
+	if err == nil {
+		t.Fatalf("expected query history to be read-only")
+	}
+
+	err = ClearQueryHistory("TestQueryHistory")
+	if err != nil {
+		t.Fatalf("cannot clear query history: %s", err)
+	}
+	history, err = QueryHistory("TestQueryHistory")
+	if err != nil {
+		t.Fatalf("cannot get query history: %s", err)
+	}
+	if len(history) != 0 {
+		t.Fatalf("expected empty history, got %d records", len(history))
+	}
+}
+
+func TestQueryHistorySize(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestQueryHistorySize?history=2")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	for _, q := range []string{`SELECT 1`, `SELECT 2`, `SELECT 3`} {
+		_, err = db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	history, err := QueryHistory("TestQueryHistorySize?history=2")
+	if err != nil {
+		t.Fatalf("cannot get query history: %s", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(history))
+	}
+	if history[0].Query != `SELECT 2` || history[1].Query != `SELECT 3` {
+		t.Fatalf("unexpected history: %v", history)
+	}
+}
+
+func TestQueryHistoryMultiStatement(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestQueryHistoryMultiStatement")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT 1; SELECT 2`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	for rows.Next() || rows.NextResultSet() {
+	}
+	rows.Close()
+
+	history, err := QueryHistory("TestQueryHistoryMultiStatement")
+	if err != nil {
+		t.Fatalf("cannot get query history: %s", err)
+	}
+	if len(history) != 2 || history[0].Query != `SELECT 1` || history[1].Query != `SELECT 2` {
+		t.Fatalf("expected a record per statement, got %v", history)
+	}
+}
@@ -0,0 +1,46 @@
+package ramsql
+
+import (
+	"github.com/proullon/ramsql/engine/executor"
+)
+
+// Statement describes a statement being executed, as seen by hooks.
+type Statement = executor.Statement
+
+// Hook runs callbacks before and after statements matching its Kind, Table and Match criteria.
+type Hook = executor.Hook
+
+// Breakpoint pauses statements matching a hook until released.
+type Breakpoint = executor.Breakpoint
+
+// AddHook registers h on the engine opened with dsn.
+// The returned function removes the hook.
+func AddHook(dsn string, h Hook) (remove func(), err error) {
+	return defaultDriver.AddHook(dsn, h)
+}
+
+// Break pauses statements matching h on the engine opened with dsn.
+func Break(dsn string, h Hook) (*Breakpoint, error) {
+	return defaultDriver.Break(dsn, h)
+}
+
+// AddHook registers h on the engine opened with dsn.
+// The returned function removes the hook.
+func (rs *Driver) AddHook(dsn string, h Hook) (remove func(), err error) {
+	e, err := rs.engine(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.AddHook(h), nil
+}
+
+// Break pauses statements matching h on the engine opened with dsn.
+func (rs *Driver) Break(dsn string, h Hook) (*Breakpoint, error) {
+	e, err := rs.engine(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.Break(h), nil
+}
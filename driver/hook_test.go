@@ -0,0 +1,180 @@
+package ramsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/proullon/ramsql/engine/parser"
+)
+
+func TestHookInjectError(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestHookInjectError")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	init := []string{
+		`CREATE TABLE orders (id BIGSERIAL PRIMARY KEY, status TEXT)`,
+		`INSERT INTO orders (status) VALUES ('new')`,
+	}
+	for _, q := range init {
+		_, err = db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	injected := errors.New("injected")
+	var after *Statement
+	remove, err := AddHook("TestHookInjectError", Hook{
+		Kind:  "update",
+		Table: "orders",
+		Before: func(s *Statement) error {
+			if s.Instruction.Decls[0].Token != parser.UpdateToken {
+				t.Errorf("expected UPDATE instruction")
+			}
+			return injected
+		},
+		After: func(s *Statement) error {
+			after = s
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("cannot add hook: %s", err)
+	}
+
+	_, err = db.Exec(`UPDATE orders SET status = 'paid' WHERE id = 1`)
+	if err == nil || err.Error() != injected.Error() {
+		t.Fatalf("expected injected error, got %v", err)
+	}
+	if after != nil {
+		t.Fatalf("After hook should not run on aborted statement")
+	}
+
+	// other statements are not matched
+	var status string
+	err = db.QueryRow(`SELECT status FROM orders WHERE id = 1`).Scan(&status)
+	if err != nil {
+		t.Fatalf("cannot select: %s", err)
+	}
+	if status != "new" {
+		t.Fatalf("expected status to be unchanged, got %s", status)
+	}
+
+	remove()
+	_, err = db.Exec(`UPDATE orders SET status = 'paid' WHERE id = 1`)
+	if err != nil {
+		t.Fatalf("expected hook to be removed, got %s", err)
+	}
+}
+
+func TestHookAfter(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestHookAfter")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	var stmts []Statement
+	_, err = AddHook("TestHookAfter", Hook{
+		Match: regexp.MustCompile(`^INSERT`),
+		After: func(s *Statement) error {
+			stmts = append(stmts, *s)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("cannot add hook: %s", err)
+	}
+
+	init := []string{
+		`CREATE TABLE orders (id BIGSERIAL PRIMARY KEY, status TEXT)`,
+		`INSERT INTO orders (status) VALUES ('new')`,
+		`INSERT INTO orders (status) VALUES ('new')`,
+	}
+	for _, q := range init {
+		_, err = db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	if len(stmts) != 2 {
+		t.Fatalf("expected 2 INSERT, got %d", len(stmts))
+	}
+	for _, s := range stmts {
+		if s.Kind != "INSERT" || len(s.Tables) != 1 || s.Tables[0] != "orders" || s.Rows != 1 || s.Err != nil {
+			t.Fatalf("unexpected statement: %+v", s)
+		}
+	}
+}
+
+func TestBreakpoint(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestBreakpoint")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	init := []string{
+		`CREATE TABLE orders (id BIGSERIAL PRIMARY KEY, status TEXT)`,
+		`INSERT INTO orders (status) VALUES ('new')`,
+	}
+	for _, q := range init {
+		_, err = db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	bp, err := Break("TestBreakpoint", Hook{Kind: "UPDATE", Table: "orders"})
+	if err != nil {
+		t.Fatalf("cannot add breakpoint: %s", err)
+	}
+	defer bp.Clear()
+
+	done := make(chan error)
+	go func() {
+		_, err := db.Exec(`UPDATE orders SET status = 'paid' WHERE id = 1`)
+		done <- err
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s, err := bp.Wait(ctx)
+	if err != nil {
+		t.Fatalf("breakpoint not hit: %s", err)
+	}
+	if s.Kind != "UPDATE" {
+		t.Fatalf("expected UPDATE statement, got %s", s.Kind)
+	}
+
+	// UPDATE is paused, concurrent statements still run
+	var status string
+	err = db.QueryRow(`SELECT status FROM orders WHERE id = 1`).Scan(&status)
+	if err != nil {
+		t.Fatalf("cannot select: %s", err)
+	}
+	if status != "new" {
+		t.Fatalf("expected status new while UPDATE is paused, got %s", status)
+	}
+
+	bp.Release()
+	if err := <-done; err != nil {
+		t.Fatalf("cannot update: %s", err)
+	}
+
+	err = db.QueryRow(`SELECT status FROM orders WHERE id = 1`).Scan(&status)
+	if err != nil {
+		t.Fatalf("cannot select: %s", err)
+	}
+	if status != "paid" {
+		t.Fatalf("expected status paid after release, got %s", status)
+	}
+}
@@ -0,0 +1,238 @@
+package ramsql
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func setupJSONTable(t *testing.T, dbName string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("ramsql", dbName)
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+
+	batch := []string{
+		`CREATE TABLE event (id BIGSERIAL PRIMARY KEY, name TEXT, payload JSONB)`,
+		`INSERT INTO event (name, payload) VALUES ('signup', '{"type": "signup", "user": {"id": 1, "plan": "pro"}, "tags": ["web", "mobile"]}')`,
+		`INSERT INTO event (name, payload) VALUES ('login', '{"type": "login", "user": {"id": 2}, "tags": ["web"]}')`,
+		`INSERT INTO event (name, payload) VALUES ('empty', '{}')`,
+		`INSERT INTO event (name) VALUES ('nopayload')`,
+	}
+	for _, b := range batch {
+		if _, err := db.Exec(b); err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+	_, err = db.Exec(`INSERT INTO event (name, payload) VALUES ($1, $2)`, "args", `{"user":{"plan":"free","id":3},"type":"signup"}`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	return db
+}
+
+func TestJSONPredicates(t *testing.T) {
+	db := setupJSONTable(t, "TestJSONPredicates")
+	defer db.Close()
+
+	tests := []struct {
+		query string
+		args  []any
+		want  []string
+	}{
+		{`SELECT name FROM event WHERE payload->>'type' = 'signup'`, nil, []string{"signup", "args"}},
+		{`SELECT name FROM event WHERE payload->>'type' = $1`, []any{"login"}, []string{"login"}},
+		{`SELECT name FROM event WHERE payload->'user'->>'plan' = 'pro'`, nil, []string{"signup"}},
+		{`SELECT name FROM event WHERE payload#>>'{user,id}' = '2'`, nil, []string{"login"}},
+		{`SELECT name FROM event WHERE payload->'user'->'id' = '3'`, nil, []string{"args"}},
+		{`SELECT name FROM event WHERE payload->'tags'->>0 = 'web'`, nil, []string{"signup", "login"}},
+		{`SELECT name FROM event WHERE payload->>'type' IN ('login', 'other')`, nil, []string{"login"}},
+		{`SELECT name FROM event WHERE payload->'user' IS NULL`, nil, []string{"empty", "nopayload"}},
+		{`SELECT e.name FROM event e WHERE e.payload->'user'->>'plan' = 'free'`, nil, []string{"args"}},
+		{`SELECT name FROM event WHERE payload @> '{"user": {"plan": "pro"}}'`, nil, []string{"signup"}},
+		{`SELECT name FROM event WHERE payload @> $1`, []any{`{"type": "signup"}`}, []string{"signup", "args"}},
+		{`SELECT name FROM event WHERE payload->'tags' @> '"mobile"'`, nil, []string{"signup"}},
+		{`SELECT name FROM event WHERE payload <@ '{"type": "login", "user": {"id": 2}, "tags": ["web"], "extra": 1}'`, nil, []string{"login", "empty"}},
+		{`SELECT name FROM event WHERE payload ? 'tags'`, nil, []string{"signup", "login"}},
+		{`SELECT name FROM event WHERE payload->'user' ? 'plan' AND name <> 'args'`, nil, []string{"signup"}},
+		// documents are equal whatever the order of their keys
+		{`SELECT name FROM event WHERE payload = '{"tags": ["web"], "user": {"id": 2}, "type": "login"}'`, nil, []string{"login"}},
+		{`SELECT name FROM event WHERE payload->'user' = '{"plan": "pro", "id": 1.0}'`, nil, []string{"signup"}},
+	}
+
+	for _, tt := range tests {
+		got := queryTitles(t, db, tt.query, tt.args...)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("%s: expected %v, got %v", tt.query, tt.want, got)
+		}
+	}
+
+	_, err := db.Query(`SELECT name FROM event WHERE payload = '{"type":'`)
+	if err == nil {
+		t.Fatalf("expected error on invalid document")
+	}
+	_, err = db.Exec(`INSERT INTO event (name, payload) VALUES ('invalid', '{"type":')`)
+	if err == nil {
+		t.Fatalf("expected error on invalid document")
+	}
+}
+
+func TestJSONOperators(t *testing.T) {
+	db := setupJSONTable(t, "TestJSONOperators")
+	defer db.Close()
+
+	// jsonb documents are normalized, keys being sorted
+	var payload string
+	err := db.QueryRow(`SELECT payload FROM event WHERE name = 'args'`).Scan(&payload)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if payload != `{"type": "signup", "user": {"id": 3, "plan": "free"}}` {
+		t.Fatalf("unexpected payload %s", payload)
+	}
+
+	var user, plan, tag string
+	var missing sql.NullString
+	err = db.QueryRow(`SELECT payload->'user', payload->'user'->>'plan', payload#>>'{tags,-1}', payload->>'missing' FROM event WHERE name = 'signup'`).Scan(&user, &plan, &tag, &missing)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if user != `{"id": 1, "plan": "pro"}` || plan != "pro" || tag != "mobile" || missing.Valid {
+		t.Fatalf("unexpected values %s, %s, %s and %v", user, plan, tag, missing)
+	}
+
+	var doc string
+	err = db.QueryRow(`SELECT jsonb_build_object('name', name, 'id', id, 'plan', payload->'user'->'plan') FROM event WHERE name = 'signup'`).Scan(&doc)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if doc != `{"id": 1, "name": "signup", "plan": "pro"}` {
+		t.Fatalf("unexpected document %s", doc)
+	}
+
+	_, err = db.Exec(`UPDATE event SET payload = jsonb_set(payload, '{user,plan}', '"team"') WHERE payload->>'type' = 'signup'`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	_, err = db.Exec(`UPDATE event SET payload = jsonb_set(payload, '{user,plan}', $1, false) WHERE name = 'login'`, `"pro"`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	titles := queryTitles(t, db, `SELECT name FROM event WHERE payload @> '{"user": {"plan": "team"}}'`)
+	if !reflect.DeepEqual(titles, []string{"signup", "args"}) {
+		t.Fatalf("expected [signup args], got %v", titles)
+	}
+	titles = queryTitles(t, db, `SELECT name FROM event WHERE payload->'user' ? 'plan'`)
+	if !reflect.DeepEqual(titles, []string{"signup", "args"}) {
+		t.Fatalf("expected [signup args], got %v", titles)
+	}
+
+	_, err = db.Exec(`INSERT INTO event (name, payload) VALUES ('built', jsonb_build_object('type', 'built', 'n', 1, 'ok', true))`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	err = db.QueryRow(`SELECT payload FROM event WHERE payload->>'type' = 'built'`).Scan(&doc)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if doc != `{"n": 1, "ok": true, "type": "built"}` {
+		t.Fatalf("unexpected document %s", doc)
+	}
+}
+
+func TestJSONAggregateAndElements(t *testing.T) {
+	db := setupJSONTable(t, "TestJSONAggregateAndElements")
+	defer db.Close()
+
+	var names string
+	err := db.QueryRow(`SELECT jsonb_agg(name) FROM event WHERE payload->>'type' = 'signup'`).Scan(&names)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if names != `["signup", "args"]` {
+		t.Fatalf(`expected ["signup", "args"], got %s`, names)
+	}
+
+	var payloads string
+	err = db.QueryRow(`SELECT jsonb_agg(payload) FROM event WHERE name IN ('empty', 'nopayload')`).Scan(&payloads)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if payloads != `[{}, null]` {
+		t.Fatalf(`expected [{}, null], got %s`, payloads)
+	}
+
+	rows, err := db.Query(`SELECT value FROM json_array_elements($1)`, `[1, "two", {"three": 3}]`)
+	if err != nil {
+		t.Fatalf("sql.Query: %s", err)
+	}
+	var got []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			t.Fatalf("Cannot scan row: %s", err)
+		}
+		got = append(got, v)
+	}
+	rows.Close()
+	if !reflect.DeepEqual(got, []string{"1", `"two"`, `{"three": 3}`}) {
+		t.Fatalf("unexpected elements %v", got)
+	}
+
+	titles := queryTitles(t, db, `SELECT e->>'name' FROM json_array_elements('[{"name": "a"}, {"name": "b"}]') AS t(e) WHERE e->>'name' <> 'a'`)
+	if !reflect.DeepEqual(titles, []string{"b"}) {
+		t.Fatalf("expected [b], got %v", titles)
+	}
+
+	titles = queryTitles(t, db, `SELECT t.value->>'type' FROM json_array_elements((SELECT jsonb_agg(payload) FROM event WHERE name = 'login')) t`)
+	if !reflect.DeepEqual(titles, []string{"login"}) {
+		t.Fatalf("expected [login], got %v", titles)
+	}
+
+	_, err = db.Query(`SELECT value FROM json_array_elements('{"a": 1}')`)
+	if err == nil {
+		t.Fatalf("expected error on object document")
+	}
+}
+
+func TestJSONText(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestJSONText")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE a (doc JSONB, raw JSON)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	_, err = db.Exec(`INSERT INTO a (doc, raw) VALUES ($1, $1)`, `{"b":1,"a":[1,2]}`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	// jsonb documents are normalized, json documents kept as written
+	var doc, raw string
+	err = db.QueryRow(`SELECT doc, raw FROM a`).Scan(&doc, &raw)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if doc != `{"a": [1, 2], "b": 1}` {
+		t.Fatalf("unexpected jsonb document %s", doc)
+	}
+	if raw != `{"b":1,"a":[1,2]}` {
+		t.Fatalf("unexpected json document %s", raw)
+	}
+
+	_, err = db.Exec(`INSERT INTO a (doc) VALUES ('{bad json')`)
+	if err == nil || !strings.Contains(err.Error(), "invalid input syntax for type json") {
+		t.Fatalf("expected invalid input syntax error, got %v", err)
+	}
+	_, err = db.Exec(`UPDATE a SET raw = $1`, `{bad json`)
+	if err == nil || !strings.Contains(err.Error(), "invalid input syntax for type json") {
+		t.Fatalf("expected invalid input syntax error, got %v", err)
+	}
+}
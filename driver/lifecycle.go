@@ -0,0 +1,91 @@
+package ramsql
+
+import (
+	"github.com/proullon/ramsql/engine/agnostic"
+)
+
+// Drop stops the engine opened with dsn and releases its data.
+// Open connections fail with driver.ErrBadConn, a new engine is started on next connection.
+//
+// The write-ahead log of a persisted engine is removed, see Config.File.
+func Drop(dsn string) error {
+	return defaultDriver.Drop(dsn)
+}
+
+// Reset replaces the engine opened with dsn by a new empty engine with the same configuration.
+// Open connections fail with driver.ErrBadConn and are replaced by database/sql.
+//
+// The write-ahead log of a persisted engine is removed, the new engine starts a new one. See Config.File.
+func Reset(dsn string) error {
+	return defaultDriver.Reset(dsn)
+}
+
+// Drop stops the engine opened with dsn and releases its data.
+func (rs *Driver) Drop(dsn string) error {
+	cfg, err := ParseDSN(dsn)
+	if err != nil {
+		return err
+	}
+
+	rs.Lock()
+	i, ok := rs.engines[cfg.Name]
+	delete(rs.engines, cfg.Name)
+	rs.Unlock()
+
+	if ok {
+		i.e.Stop()
+		cfg = i.cfg
+	}
+	if cfg.File != "" {
+		return agnostic.RemoveLog(cfg.File)
+	}
+	return nil
+}
+
+// Reset replaces the engine opened with dsn by a new empty engine with the same configuration.
+func (rs *Driver) Reset(dsn string) error {
+	cfg, err := ParseDSN(dsn)
+	if err != nil {
+		return err
+	}
+
+	rs.Lock()
+	i, ok := rs.engines[cfg.Name]
+	if ok {
+		delete(rs.engines, cfg.Name)
+		cfg = i.cfg
+	}
+	rs.Unlock()
+
+	if !ok {
+		return nil
+	}
+	i.e.Stop()
+
+	if cfg.File != "" {
+		if err := agnostic.RemoveLog(cfg.File); err != nil {
+			return err
+		}
+	}
+	_, err = rs.start(cfg)
+	return err
+}
+
+// release drops a reference on engine name, stopping it if unused
+func (rs *Driver) release(name string) {
+	rs.Lock()
+	defer rs.Unlock()
+
+	rs.refs[name]--
+	if rs.refs[name] > 0 {
+		return
+	}
+	delete(rs.refs, name)
+
+	i, ok := rs.engines[name]
+	if !ok {
+		return
+	}
+	delete(rs.engines, name)
+	i.e.Stop()
+}
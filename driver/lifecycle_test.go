@@ -0,0 +1,200 @@
+package ramsql
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLifecycleClose(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestLifecycleClose")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	db2, err := sql.Open("ramsql", "TestLifecycleClose")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+
+	_, err = db.Exec(`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	// engine is kept while a sql.DB uses it
+	db.Close()
+	_, err = db2.Exec(`INSERT INTO account (email) VALUES ('foo@bar.com')`)
+	if err != nil {
+		t.Fatalf("expected engine to be kept, got %s", err)
+	}
+
+	// last close stops the engine
+	db2.Close()
+	db, err = sql.Open("ramsql", "TestLifecycleClose")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+	_, err = db.Exec(`INSERT INTO account (email) VALUES ('foo@bar.com')`)
+	if err == nil {
+		t.Fatalf("expected a new engine without account relation")
+	}
+}
+
+func TestLifecycleUnopened(t *testing.T) {
+	dsn := "TestLifecycleUnopened"
+	if _, err := QueryHistory(dsn); err == nil {
+		t.Fatalf("expected an error on unopened engine")
+	}
+	if _, err := AddHook(dsn, Hook{}); err == nil {
+		t.Fatalf("expected an error on unopened engine")
+	}
+	if _, err := Generate(dsn, "", "account", 1, 0); err == nil {
+		t.Fatalf("expected an error on unopened engine")
+	}
+
+	// engine is started by sql.Open, so it can be configured before the first query
+	db, err := sql.Open("ramsql", dsn)
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	if err := ClearQueryHistory(dsn); err != nil {
+		t.Fatalf("expected engine to be opened, got %s", err)
+	}
+	db.Close()
+	if _, err := QueryHistory(dsn); err == nil {
+		t.Fatalf("expected an error on closed engine")
+	}
+}
+
+func TestLifecycleDrop(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestLifecycleDrop")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	conn, err := NewDriver().Open("TestLifecycleDropConn")
+	if err != nil {
+		t.Fatalf("cannot open conn: %s", err)
+	}
+	c := conn.(*Conn)
+
+	if err := Drop("TestLifecycleDrop"); err != nil {
+		t.Fatalf("cannot drop engine: %s", err)
+	}
+
+	// pooled connections are replaced by database/sql
+	_, err = db.Exec(`INSERT INTO account (email) VALUES ('foo@bar.com')`)
+	if err == nil {
+		t.Fatalf("expected dropped relation")
+	}
+	_, err = db.Exec(`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	c.e.Stop()
+	if c.IsValid() {
+		t.Fatalf("expected connection to a stopped engine to be invalid")
+	}
+	if _, err := c.Begin(); err == nil {
+		t.Fatalf("expected bad connection error")
+	}
+}
+
+func TestLifecycleReset(t *testing.T) {
+	db := sql.OpenDB(NewConnector(Config{Name: "TestLifecycleReset", Schema: "app"}))
+	defer db.Close()
+
+	_, err := db.Exec(`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	if err := Reset("TestLifecycleReset"); err != nil {
+		t.Fatalf("cannot reset engine: %s", err)
+	}
+
+	var n int
+	err = db.QueryRow(`SELECT COUNT(*) FROM app.account`).Scan(&n)
+	if err == nil {
+		t.Fatalf("expected relation to be reset")
+	}
+
+	// configuration is kept
+	_, err = db.Exec(`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	err = db.QueryRow(`SELECT COUNT(*) FROM app.account`).Scan(&n)
+	if err != nil {
+		t.Fatalf("expected relation in app schema after reset: %s", err)
+	}
+
+	// engine is still stopped on close
+	db.Close()
+	defaultDriver.Lock()
+	_, ok := defaultDriver.engines["TestLifecycleReset"]
+	defaultDriver.Unlock()
+	if ok {
+		t.Fatalf("expected engine to be stopped on close")
+	}
+}
+
+func TestLifecyclePersisted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lifecycle.wal")
+	dsn := "TestLifecyclePersisted?file=" + path
+
+	db, err := sql.Open("ramsql", dsn)
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT)`,
+		`INSERT INTO account (email) VALUES ('foo@bar.com')`,
+	}
+	for _, q := range batch {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	// reset engine does not replay the log
+	if err := Reset(dsn); err != nil {
+		t.Fatalf("cannot reset engine: %s", err)
+	}
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM account`).Scan(&n); err == nil {
+		t.Fatalf("expected relation to be reset, got %d rows", n)
+	}
+
+	// the new engine is persisted to the same file
+	for _, q := range batch {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected write-ahead log after reset: %s", err)
+	}
+
+	// dropped data does not come back on next open
+	if err := Drop(dsn); err != nil {
+		t.Fatalf("cannot drop engine: %s", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected write-ahead log to be removed, got %v", err)
+	}
+	if err := db.QueryRow(`SELECT COUNT(*) FROM account`).Scan(&n); err == nil {
+		t.Fatalf("expected relation to be dropped, got %d rows", n)
+	}
+}
@@ -0,0 +1,359 @@
+package ramsql
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/proullon/ramsql/engine/executor"
+)
+
+// Migrate applies migrations of directory dir to the engine opened with dsn,
+// and returns the number of migrations applied.
+//
+// Migrations are applied in order of version, the number prefixing file names:
+//   - golang-migrate files, such as 1_create_users.up.sql. Down files are ignored.
+//   - goose files, such as 00001_create_users.sql, with -- +goose Up and -- +goose Down sections.
+//   - plain SQL files, such as atlas migrations 20240101120000_create_users.sql.
+//
+// Applied versions are recorded as the migration tool does, in goose_db_version for goose
+// migrations and in schema_migrations otherwise. Migrations already applied are skipped,
+// and the tool can apply the following ones.
+func Migrate(dsn string, dir string) (int, error) {
+	return defaultDriver.Migrate(dsn, os.DirFS(dir))
+}
+
+// MigrateFS applies migrations found at the root of fsys to the engine opened with dsn, see Migrate.
+// Use fs.Sub to apply migrations of a sub directory of an embed.FS.
+func MigrateFS(dsn string, fsys fs.FS) (int, error) {
+	return defaultDriver.Migrate(dsn, fsys)
+}
+
+// Migrate applies migrations found at the root of fsys to the engine opened with dsn.
+func (rs *Driver) Migrate(dsn string, fsys fs.FS) (int, error) {
+	migrations, goose, err := readMigrations(fsys)
+	if err != nil {
+		return 0, err
+	}
+
+	e, err := rs.engine(dsn)
+	if err != nil {
+		return 0, err
+	}
+	db := sql.OpenDB(migrationConnector{d: rs, e: e})
+	defer db.Close()
+
+	if goose {
+		return applyGooseMigrations(db, migrations)
+	}
+	return applyMigrations(db, migrations)
+}
+
+// migrationConnector connects to an engine without holding a reference on it,
+// so the engine is not stopped once migrations are applied
+type migrationConnector struct {
+	d *Driver
+	e *executor.Engine
+}
+
+func (c migrationConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return newConn(c.e), nil
+}
+
+func (c migrationConnector) Driver() driver.Driver {
+	return c.d
+}
+
+type migration struct {
+	version int64
+	name    string
+	// statements of up migration
+	statements []string
+	// noTx is set for goose migrations annotated NO TRANSACTION
+	noTx bool
+}
+
+// readMigrations returns up migrations of fsys sorted by version,
+// and whether they are goose migrations
+func readMigrations(fsys fs.FS) ([]*migration, bool, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, false, err
+	}
+
+	var migrations []*migration
+	versions := make(map[int64]string)
+	goose := false
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".sql") || strings.HasSuffix(name, ".down.sql") {
+			continue
+		}
+
+		version, err := migrationVersion(name)
+		if err != nil {
+			return nil, false, err
+		}
+		if other, ok := versions[version]; ok {
+			return nil, false, fmt.Errorf("duplicate migration version %d: %s and %s", version, other, name)
+		}
+		versions[version] = name
+
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, false, err
+		}
+
+		m := &migration{version: version, name: name}
+		if isGooseMigration(string(content)) {
+			goose = true
+			m.statements, m.noTx, err = parseGooseMigration(string(content))
+			if err != nil {
+				return nil, false, fmt.Errorf("%s: %w", name, err)
+			}
+		} else {
+			m.statements = []string{string(content)}
+		}
+		migrations = append(migrations, m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return migrations, goose, nil
+}
+
+// migrationVersion returns the number prefixing a migration file name
+func migrationVersion(name string) (int64, error) {
+	i := 0
+	for i < len(name) && name[i] >= '0' && name[i] <= '9' {
+		i++
+	}
+	if i == 0 || (name[i] != '_' && name[i] != '.') {
+		return 0, fmt.Errorf("invalid migration file name %s, expected version prefix", name)
+	}
+
+	return strconv.ParseInt(name[:i], 10, 64)
+}
+
+// gooseDirective returns the goose annotation of line, such as "up" for -- +goose Up
+func gooseDirective(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "--") {
+		return "", false
+	}
+	fields := strings.Fields(strings.TrimPrefix(line, "--"))
+	if len(fields) < 2 || fields[0] != "+goose" {
+		return "", false
+	}
+	return strings.ToLower(strings.Join(fields[1:], " ")), true
+}
+
+func isGooseMigration(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		if d, ok := gooseDirective(line); ok && d == "up" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseGooseMigration returns the statements of the Up section of a goose migration.
+//
+// Statements end with a semicolon at end of line, unless enclosed
+// in -- +goose StatementBegin and -- +goose StatementEnd.
+func parseGooseMigration(content string) ([]string, bool, error) {
+	var statements []string
+	var buf strings.Builder
+	up, block, noTx := false, false, false
+
+	s := bufio.NewScanner(strings.NewReader(content))
+	for s.Scan() {
+		line := s.Text()
+
+		if d, ok := gooseDirective(line); ok {
+			switch d {
+			case "up":
+				up = true
+			case "down":
+				up = false
+			case "no transaction":
+				noTx = true
+			case "statementbegin":
+				block = true
+			case "statementend":
+				block = false
+				if up {
+					statements = append(statements, buf.String())
+				}
+				buf.Reset()
+			}
+			continue
+		}
+		if !up {
+			continue
+		}
+		// comments are ignored outside of statement blocks
+		if !block && strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+
+		buf.WriteString(line)
+		buf.WriteString("\n")
+		if !block && strings.HasSuffix(strings.TrimSpace(line), ";") {
+			statements = append(statements, buf.String())
+			buf.Reset()
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, false, err
+	}
+
+	if block {
+		return nil, false, fmt.Errorf("missing -- +goose StatementEnd")
+	}
+	if strings.TrimSpace(buf.String()) != "" {
+		return nil, false, fmt.Errorf("unfinished statement, expected semicolon at end of line")
+	}
+	return statements, noTx, nil
+}
+
+// applyMigrations applies migrations not recorded in golang-migrate schema_migrations relation
+func applyMigrations(db *sql.DB, migrations []*migration) (int, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version bigint not null primary key, dirty boolean not null)`)
+	if err != nil {
+		return 0, err
+	}
+
+	current := int64(-1)
+	var dirty bool
+	err = db.QueryRow(`SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&current, &dirty)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	if dirty {
+		return 0, fmt.Errorf("dirty database version %d, fix and force version", current)
+	}
+
+	n := 0
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		err := inTx(db, func(tx *sql.Tx) error {
+			if _, err := tx.Exec(m.statements[0]); err != nil {
+				return fmt.Errorf("migration %s failed: %w", m.name, err)
+			}
+			if _, err := tx.Exec(`TRUNCATE schema_migrations`); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)`, m.version, false)
+			return err
+		})
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+// applyGooseMigrations applies migrations not recorded in goose_db_version relation
+func applyGooseMigrations(db *sql.DB, migrations []*migration) (int, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS goose_db_version (id serial NOT NULL, version_id bigint NOT NULL, is_applied boolean NOT NULL, tstamp timestamp NULL default now(), PRIMARY KEY(id))`)
+	if err != nil {
+		return 0, err
+	}
+
+	// latest record of a version tells whether it is applied
+	rows, err := db.Query(`SELECT version_id, is_applied FROM goose_db_version ORDER BY id DESC`)
+	if err != nil {
+		return 0, err
+	}
+	seen := make(map[int64]bool)
+	applied := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		var isApplied bool
+		if err := rows.Scan(&version, &isApplied); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if !seen[version] {
+			seen[version] = true
+			applied[version] = isApplied
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	// goose records version 0 when creating its relation
+	if len(seen) == 0 {
+		if _, err := db.Exec(`INSERT INTO goose_db_version (version_id, is_applied) VALUES ($1, $2)`, 0, true); err != nil {
+			return 0, err
+		}
+	}
+
+	var current int64
+	for v, ok := range applied {
+		if ok && v > current {
+			current = v
+		}
+	}
+
+	n := 0
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+		if m.version < current {
+			return n, fmt.Errorf("migration %s is missing, current version is %d", m.name, current)
+		}
+
+		apply := func(e interface {
+			Exec(string, ...any) (sql.Result, error)
+		}) error {
+			for _, s := range m.statements {
+				if _, err := e.Exec(s); err != nil {
+					return fmt.Errorf("migration %s failed: %w", m.name, err)
+				}
+			}
+			_, err := e.Exec(`INSERT INTO goose_db_version (version_id, is_applied) VALUES ($1, $2)`, m.version, true)
+			return err
+		}
+
+		if m.noTx {
+			err = apply(db)
+		} else {
+			err = inTx(db, func(tx *sql.Tx) error { return apply(tx) })
+		}
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+// inTx runs f in a transaction, committed if f succeeds
+func inTx(db *sql.DB, f func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := f(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,283 @@
+package ramsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/pressly/goose/v3"
+)
+
+func checkMigratedSchema(t *testing.T, db *sql.DB) {
+	t.Helper()
+
+	var uid int64
+	err := db.QueryRow(`INSERT INTO users (email, name) VALUES ($1, $2) RETURNING id`, "rob@example.com", "Rob").Scan(&uid)
+	if err != nil {
+		t.Fatalf("cannot insert user: %s", err)
+	}
+	_, err = db.Exec(`INSERT INTO posts (user_id, title) VALUES ($1, $2)`, uid, "Hello")
+	if err != nil {
+		t.Fatalf("cannot insert post: %s", err)
+	}
+	_, err = db.Exec(`INSERT INTO posts (user_id, title) VALUES ($1, $2)`, uid+100, "Hello")
+	if err == nil {
+		t.Fatalf("expected foreign key violation")
+	}
+	_, err = db.Exec(`INSERT INTO users (email) VALUES ($1)`, "rob@example.com")
+	if err == nil {
+		t.Fatalf("expected unique violation")
+	}
+}
+
+func countTables(t *testing.T, db *sql.DB, names ...string) int {
+	t.Helper()
+
+	n := 0
+	for _, name := range names {
+		var c int64
+		err := db.QueryRow(`SELECT COUNT(*) FROM information_schema.tables WHERE table_name = $1`, name).Scan(&c)
+		if err != nil {
+			t.Fatalf("cannot count tables: %s", err)
+		}
+		n += int(c)
+	}
+	return n
+}
+
+func TestGolangMigrate(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestGolangMigrate")
+	if err != nil {
+		t.Fatalf("sql.Open: %s", err)
+	}
+	defer db.Close()
+
+	drv, err := postgres.WithInstance(db, &postgres.Config{})
+	if err != nil {
+		t.Fatalf("cannot create migrate driver: %s", err)
+	}
+	m, err := migrate.NewWithDatabaseInstance("file://testdata/migrations/migrate", "ramsql", drv)
+	if err != nil {
+		t.Fatalf("cannot create migrate instance: %s", err)
+	}
+
+	if err := m.Up(); err != nil {
+		t.Fatalf("cannot migrate up: %s", err)
+	}
+	version, dirty, err := m.Version()
+	if err != nil || version != 3 || dirty {
+		t.Fatalf("expected clean version 3, got %d %v (%v)", version, dirty, err)
+	}
+	checkMigratedSchema(t, db)
+
+	if err := m.Up(); !errors.Is(err, migrate.ErrNoChange) {
+		t.Fatalf("expected no change, got %v", err)
+	}
+
+	if err := m.Steps(-2); err != nil {
+		t.Fatalf("cannot migrate down 2 steps: %s", err)
+	}
+	version, _, err = m.Version()
+	if err != nil || version != 1 {
+		t.Fatalf("expected version 1, got %d (%v)", version, err)
+	}
+	if n := countTables(t, db, "users", "posts"); n != 1 {
+		t.Fatalf("expected only users table, got %d tables", n)
+	}
+
+	if err := m.Down(); err != nil {
+		t.Fatalf("cannot migrate down: %s", err)
+	}
+	if _, _, err := m.Version(); !errors.Is(err, migrate.ErrNilVersion) {
+		t.Fatalf("expected nil version, got %v", err)
+	}
+	if n := countTables(t, db, "users", "posts"); n != 0 {
+		t.Fatalf("expected no table, got %d", n)
+	}
+
+	if err := m.Up(); err != nil {
+		t.Fatalf("cannot migrate up again: %s", err)
+	}
+	if err := m.Drop(); err != nil {
+		t.Fatalf("cannot drop: %s", err)
+	}
+	if n := countTables(t, db, "users", "posts", "schema_migrations"); n != 0 {
+		t.Fatalf("expected no table after drop, got %d", n)
+	}
+}
+
+func TestGoose(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestGoose")
+	if err != nil {
+		t.Fatalf("sql.Open: %s", err)
+	}
+	defer db.Close()
+
+	goose.SetLogger(goose.NopLogger())
+	if err := goose.SetDialect("postgres"); err != nil {
+		t.Fatalf("cannot set dialect: %s", err)
+	}
+
+	if err := goose.Up(db, "testdata/migrations/goose"); err != nil {
+		t.Fatalf("cannot migrate up: %s", err)
+	}
+	version, err := goose.GetDBVersion(db)
+	if err != nil || version != 3 {
+		t.Fatalf("expected version 3, got %d (%v)", version, err)
+	}
+	checkMigratedSchema(t, db)
+
+	if err := goose.Down(db, "testdata/migrations/goose"); err != nil {
+		t.Fatalf("cannot migrate down: %s", err)
+	}
+	if err := goose.Down(db, "testdata/migrations/goose"); err != nil {
+		t.Fatalf("cannot migrate down: %s", err)
+	}
+	version, err = goose.GetDBVersion(db)
+	if err != nil || version != 1 {
+		t.Fatalf("expected version 1, got %d (%v)", version, err)
+	}
+	if n := countTables(t, db, "users", "posts"); n != 1 {
+		t.Fatalf("expected only users table, got %d tables", n)
+	}
+
+	if err := goose.Reset(db, "testdata/migrations/goose"); err != nil {
+		t.Fatalf("cannot reset: %s", err)
+	}
+	version, err = goose.GetDBVersion(db)
+	if err != nil || version != 0 {
+		t.Fatalf("expected version 0, got %d (%v)", version, err)
+	}
+
+	if err := goose.UpTo(db, "testdata/migrations/goose", 2); err != nil {
+		t.Fatalf("cannot migrate up to 2: %s", err)
+	}
+	var admins int64
+	if err := db.QueryRow(`SELECT COUNT(*) FROM users WHERE email = 'admin@example.com'`).Scan(&admins); err != nil || admins != 1 {
+		t.Fatalf("expected admin user, got %d (%v)", admins, err)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	dirs := []string{"migrate", "goose", "atlas"}
+
+	for _, dir := range dirs {
+		t.Run(dir, func(t *testing.T) {
+			dsn := "TestMigrate" + dir
+			db, err := sql.Open("ramsql", dsn)
+			if err != nil {
+				t.Fatalf("sql.Open: %s", err)
+			}
+			defer db.Close()
+
+			n, err := Migrate(dsn, "testdata/migrations/"+dir)
+			if err != nil {
+				t.Fatalf("cannot migrate: %s", err)
+			}
+			if n != 2 && dir == "atlas" || n != 3 && dir != "atlas" {
+				t.Fatalf("unexpected number of migrations applied: %d", n)
+			}
+			checkMigratedSchema(t, db)
+
+			n, err = Migrate(dsn, "testdata/migrations/"+dir)
+			if err != nil {
+				t.Fatalf("cannot migrate again: %s", err)
+			}
+			if n != 0 {
+				t.Fatalf("expected no migration applied, got %d", n)
+			}
+		})
+	}
+}
+
+func TestMigrateThenTools(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestMigrateThenTools")
+	if err != nil {
+		t.Fatalf("sql.Open: %s", err)
+	}
+	defer db.Close()
+
+	if _, err := Migrate("TestMigrateThenTools", "testdata/migrations/migrate"); err != nil {
+		t.Fatalf("cannot migrate: %s", err)
+	}
+	drv, err := postgres.WithInstance(db, &postgres.Config{})
+	if err != nil {
+		t.Fatalf("cannot create migrate driver: %s", err)
+	}
+	m, err := migrate.NewWithDatabaseInstance("file://testdata/migrations/migrate", "ramsql", drv)
+	if err != nil {
+		t.Fatalf("cannot create migrate instance: %s", err)
+	}
+	if err := m.Up(); !errors.Is(err, migrate.ErrNoChange) {
+		t.Fatalf("expected no change, got %v", err)
+	}
+	if err := m.Steps(-1); err != nil {
+		t.Fatalf("cannot migrate down: %s", err)
+	}
+
+	gdb, err := sql.Open("ramsql", "TestMigrateThenToolsGoose")
+	if err != nil {
+		t.Fatalf("sql.Open: %s", err)
+	}
+	defer gdb.Close()
+
+	if _, err := Migrate("TestMigrateThenToolsGoose", "testdata/migrations/goose"); err != nil {
+		t.Fatalf("cannot migrate: %s", err)
+	}
+	goose.SetLogger(goose.NopLogger())
+	if err := goose.SetDialect("postgres"); err != nil {
+		t.Fatalf("cannot set dialect: %s", err)
+	}
+	version, err := goose.GetDBVersion(gdb)
+	if err != nil || version != 3 {
+		t.Fatalf("expected version 3, got %d (%v)", version, err)
+	}
+	if err := goose.Down(gdb, "testdata/migrations/goose"); err != nil {
+		t.Fatalf("cannot migrate down: %s", err)
+	}
+}
+
+func TestAdvisoryLock(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestAdvisoryLock")
+	if err != nil {
+		t.Fatalf("sql.Open: %s", err)
+	}
+	defer db.Close()
+	// connections are closed instead of returning to the pool
+	db.SetMaxIdleConns(0)
+
+	ctx := context.Background()
+	c1, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("cannot get connection: %s", err)
+	}
+	c2, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("cannot get connection: %s", err)
+	}
+	defer c2.Close()
+
+	if _, err := c1.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, 42); err != nil {
+		t.Fatalf("cannot lock: %s", err)
+	}
+	var ok bool
+	if err := c2.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(42)`).Scan(&ok); err != nil || ok {
+		t.Fatalf("expected lock to be held, got %v (%v)", ok, err)
+	}
+	if err := c2.QueryRowContext(ctx, `SELECT pg_advisory_unlock(42)`).Scan(&ok); err != nil || ok {
+		t.Fatalf("expected unlock to fail, got %v (%v)", ok, err)
+	}
+
+	// lock is released when its connection closes
+	c1.Close()
+	if err := c2.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(42)`).Scan(&ok); err != nil || !ok {
+		t.Fatalf("expected lock to be taken, got %v (%v)", ok, err)
+	}
+	if err := c2.QueryRowContext(ctx, `SELECT pg_advisory_unlock(42)`).Scan(&ok); err != nil || !ok {
+		t.Fatalf("expected unlock, got %v (%v)", ok, err)
+	}
+}
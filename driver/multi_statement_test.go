@@ -0,0 +1,97 @@
+package ramsql
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestMultiStatementQuery(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestMultiStatementQuery")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	rows, err := db.Query(`INSERT INTO account (email) VALUES ('foo@bar.com'); SELECT id, email FROM account; SELECT COUNT(*) FROM account`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	defer rows.Close()
+
+	// INSERT returns no rows
+	if rows.Next() {
+		t.Fatalf("expected no row for INSERT")
+	}
+
+	if !rows.NextResultSet() {
+		t.Fatalf("expected a 2nd result set: %v", rows.Err())
+	}
+	cols, err := rows.Columns()
+	if err != nil || len(cols) != 2 || cols[1] != "email" {
+		t.Fatalf("unexpected columns %v (%v)", cols, err)
+	}
+	if !rows.Next() {
+		t.Fatalf("expected a row: %v", rows.Err())
+	}
+	var id int64
+	var email string
+	if err := rows.Scan(&id, &email); err != nil {
+		t.Fatalf("cannot scan: %s", err)
+	}
+	if id != 1 || email != "foo@bar.com" {
+		t.Fatalf("unexpected row (%d, %s)", id, email)
+	}
+
+	if !rows.NextResultSet() {
+		t.Fatalf("expected a 3rd result set: %v", rows.Err())
+	}
+	if !rows.Next() {
+		t.Fatalf("expected a row: %v", rows.Err())
+	}
+	var count int64
+	if err := rows.Scan(&count); err != nil {
+		t.Fatalf("cannot scan: %s", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 row, got %d", count)
+	}
+	if rows.Next() || rows.NextResultSet() {
+		t.Fatalf("expected no more rows")
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("rows.Err: %s", err)
+	}
+}
+
+func TestMultiStatementQueryError(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestMultiStatementQueryError")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	_, err = db.Query(`INSERT INTO account (email) VALUES ('foo@bar.com'); SELECT nope FROM account`)
+	if err == nil {
+		t.Fatalf("expected an error on 2nd statement")
+	}
+
+	// statements are executed in the same transaction
+	var count int64
+	err = db.QueryRow(`SELECT COUNT(*) FROM account`).Scan(&count)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if count != 0 {
+		t.Fatalf("expected insert to be rolled back, got %d rows", count)
+	}
+}
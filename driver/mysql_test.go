@@ -0,0 +1,349 @@
+package ramsql
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMySQLDialect(t *testing.T) {
+	db, err := sql.Open("ramsql", "ramsql://TestMySQLDialect?dialect=mysql")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE `user` (" +
+		"`id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY, " +
+		"`email` VARCHAR(255) NOT NULL UNIQUE, " +
+		"`active` TINYINT(1) NOT NULL DEFAULT 1, " +
+		"`hits` INT NOT NULL DEFAULT 0, " +
+		"`created_at` DATETIME NULL" +
+		") ENGINE=InnoDB AUTO_INCREMENT=10 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci")
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	created := time.Date(2024, time.March, 1, 12, 30, 0, 0, time.UTC)
+	res, err := db.Exec("INSERT INTO `user` (`email`, `active`, `created_at`) VALUES (?, ?, ?)", "foo@bar.com", true, created)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		t.Fatalf("LastInsertId: Error: %s\n", err)
+	}
+	if id != 10 {
+		t.Fatalf("expected first id 10, got %d", id)
+	}
+	for _, email := range []string{"bar@bar.com", "baz@bar.com"} {
+		_, err = db.Exec("INSERT INTO `user` (`email`, `active`, `created_at`) VALUES (?, false, NULL)", email)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	var active bool
+	var at time.Time
+	err = db.QueryRow("SELECT active, created_at FROM `user` WHERE email = ?", "foo@bar.com").Scan(&active, &at)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if !active || !at.Equal(created) {
+		t.Fatalf("unexpected row (%t, %s)", active, at)
+	}
+
+	rows, err := db.Query("SELECT email FROM `user` ORDER BY id LIMIT 1, 1")
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	var emails []string
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			t.Fatalf("rows.Scan: Error: %s\n", err)
+		}
+		emails = append(emails, email)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("rows.Err: Error: %s\n", err)
+	}
+	if len(emails) != 1 || emails[0] != "bar@bar.com" {
+		t.Fatalf("expected [bar@bar.com], got %v", emails)
+	}
+
+	res, err = db.Exec("UPDATE `user` SET hits = 3")
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	if n, _ := res.RowsAffected(); n != 3 {
+		t.Fatalf("expected 3 updated rows, got %d", n)
+	}
+}
+
+func TestMySQLTinyIntBoolean(t *testing.T) {
+	db, err := sql.Open("ramsql", "ramsql://TestMySQLTinyIntBoolean?dialect=mysql")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		"CREATE TABLE flag (id INT AUTO_INCREMENT PRIMARY KEY, name TEXT, active TINYINT(1) NOT NULL DEFAULT 0)",
+		"INSERT INTO flag (name, active) VALUES ('one', 1)",
+		"INSERT INTO flag (name, active) VALUES ('zero', 0)",
+		"INSERT INTO flag (name, active) VALUES ('literal', TRUE)",
+		"INSERT INTO flag (name) VALUES ('unset')",
+	}
+	for _, b := range batch {
+		if _, err := db.Exec(b); err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+	_, err = db.Exec("INSERT INTO flag (name, active) VALUES (?, ?)", "bound", 1)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	var active bool
+	err = db.QueryRow("SELECT active FROM flag WHERE name = ?", "one").Scan(&active)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if !active {
+		t.Fatalf("expected one to be active")
+	}
+
+	titles := queryTitles(t, db, "SELECT name FROM flag WHERE active = 1")
+	if !reflect.DeepEqual(titles, []string{"one", "literal", "bound"}) {
+		t.Fatalf("expected [one literal bound], got %v", titles)
+	}
+	titles = queryTitles(t, db, "SELECT name FROM flag WHERE active = 0")
+	if !reflect.DeepEqual(titles, []string{"zero", "unset"}) {
+		t.Fatalf("expected [zero unset], got %v", titles)
+	}
+	titles = queryTitles(t, db, "SELECT name FROM flag WHERE active = ?", 0)
+	if !reflect.DeepEqual(titles, []string{"zero", "unset"}) {
+		t.Fatalf("expected [zero unset], got %v", titles)
+	}
+
+	_, err = db.Exec("UPDATE flag SET active = 1 WHERE name = 'zero'")
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	titles = queryTitles(t, db, "SELECT name FROM flag WHERE active = false")
+	if !reflect.DeepEqual(titles, []string{"unset"}) {
+		t.Fatalf("expected [unset], got %v", titles)
+	}
+
+	_, err = db.Exec("INSERT INTO flag (name, active) VALUES ('two', 2)")
+	if err == nil {
+		t.Fatalf("expected 2 to be rejected for a TINYINT(1) column")
+	}
+}
+
+func TestMySQLOnDuplicateKeyUpdate(t *testing.T) {
+	db, err := sql.Open("ramsql", "ramsql://TestMySQLOnDuplicateKeyUpdate?dialect=mysql")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE counter (id INT AUTO_INCREMENT PRIMARY KEY, name VARCHAR(64) UNIQUE, hits INT, label TEXT)")
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	queries := []string{
+		"INSERT INTO counter (name, hits, label) VALUES ('home', 1, 'first')",
+		"INSERT INTO counter (name, hits, label) VALUES ('home', 5, 'second') ON DUPLICATE KEY UPDATE hits = VALUES(hits), label = 'updated'",
+		"INSERT INTO counter (name, hits, label) VALUES ('about', 1, 'first') ON DUPLICATE KEY UPDATE hits = 7",
+		"INSERT INTO counter (id, name, hits, label) VALUES (2, 'other', 3, 'third') ON DUPLICATE KEY UPDATE hits = 9",
+		"INSERT INTO counter (name, hits, label) VALUES ('home', 2, 'third') ON DUPLICATE KEY UPDATE hits = hits + VALUES(hits)",
+		"INSERT INTO counter (name, hits) VALUES ('about', 1) ON DUPLICATE KEY UPDATE hits = counter.hits + 1",
+	}
+	// an updated row counts as 2 affected rows
+	affected := []int64{1, 2, 1, 2, 2, 2}
+	for i, q := range queries {
+		res, err := db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			t.Fatalf("RowsAffected: Error: %s\n", err)
+		}
+		if n != affected[i] {
+			t.Fatalf("expected %d affected rows by %s, got %d", affected[i], q, n)
+		}
+	}
+
+	expected := map[string]struct {
+		hits  int64
+		label string
+	}{
+		"home":  {7, "updated"},
+		"about": {10, "first"},
+	}
+	rows, err := db.Query("SELECT name, hits, label FROM counter")
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	defer rows.Close()
+	n := 0
+	for rows.Next() {
+		var name, label string
+		var hits int64
+		if err := rows.Scan(&name, &hits, &label); err != nil {
+			t.Fatalf("rows.Scan: Error: %s\n", err)
+		}
+		e, ok := expected[name]
+		if !ok || e.hits != hits || e.label != label {
+			t.Fatalf("unexpected row (%s, %d, %s)", name, hits, label)
+		}
+		n++
+	}
+	if n != len(expected) {
+		t.Fatalf("expected %d rows, got %d", len(expected), n)
+	}
+}
+
+func TestMySQLKeys(t *testing.T) {
+	db, err := sql.Open("ramsql", "ramsql://TestMySQLKeys?dialect=mysql")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		"CREATE TABLE `member` (" +
+			"`id` INT NOT NULL AUTO_INCREMENT, " +
+			"`email` VARCHAR(255) NOT NULL, " +
+			"`team` VARCHAR(64), " +
+			"PRIMARY KEY (`id`), " +
+			"UNIQUE KEY `uniq_email` (`email`), " +
+			"KEY `idx_team` (`team`)" +
+			") ENGINE=InnoDB",
+		"CREATE TABLE `guest` (`id` INT NOT NULL AUTO_INCREMENT PRIMARY KEY, `team` VARCHAR(64), `code` TEXT, KEY `idx_team` (`team`), INDEX (`team`, `code`), UNIQUE (`code`))",
+		"INSERT INTO member (email, team) VALUES ('foo@bar.com', 'red')",
+	}
+	for _, q := range batch {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("sql.Exec(%s): Error: %s\n", q, err)
+		}
+	}
+
+	_, err = db.Exec("INSERT INTO member (email, team) VALUES ('foo@bar.com', 'blue')")
+	if err == nil {
+		t.Fatalf("expected unique key violation")
+	}
+
+	var name string
+	err = db.QueryRow("SELECT constraint_name FROM information_schema.table_constraints WHERE table_name = 'member' AND constraint_type = 'UNIQUE'").Scan(&name)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if name != "uniq_email" {
+		t.Fatalf("expected unique constraint uniq_email, got %s", name)
+	}
+
+	var indexes []string
+	rows, err := db.Query("SELECT tablename, indexname FROM pg_indexes WHERE indexname IN ('idx_team', 'team') ORDER BY tablename, indexname")
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	for rows.Next() {
+		var table, index string
+		if err := rows.Scan(&table, &index); err != nil {
+			t.Fatalf("rows.Scan: Error: %s\n", err)
+		}
+		indexes = append(indexes, table+"."+index)
+	}
+	rows.Close()
+	if !reflect.DeepEqual(indexes, []string{"guest.idx_team", "guest.team", "member.idx_team"}) {
+		t.Fatalf("unexpected indexes %v", indexes)
+	}
+}
+
+func TestMySQLLastInsertID(t *testing.T) {
+	db, err := sql.Open("ramsql", "ramsql://TestMySQLLastInsertID?dialect=mysql")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("db.Conn: Error: %s\n", err)
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, "CREATE TABLE item (id INT AUTO_INCREMENT PRIMARY KEY, name TEXT)")
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	var id int64
+	if err := conn.QueryRowContext(ctx, "SELECT LAST_INSERT_ID()").Scan(&id); err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if id != 0 {
+		t.Fatalf("expected 0 before any insert, got %d", id)
+	}
+
+	for _, name := range []string{"a", "b"} {
+		if _, err := conn.ExecContext(ctx, "INSERT INTO item (name) VALUES (?)", name); err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+	if err := conn.QueryRowContext(ctx, "SELECT LAST_INSERT_ID()").Scan(&id); err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if id != 2 {
+		t.Fatalf("expected 2, got %d", id)
+	}
+
+	// a multi-row INSERT records its first id
+	res, err := conn.ExecContext(ctx, "INSERT INTO item (name) VALUES ('c'), ('d'), ('e')")
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	if n, _ := res.RowsAffected(); n != 3 {
+		t.Fatalf("expected 3 inserted rows, got %d", n)
+	}
+	if err := conn.QueryRowContext(ctx, "SELECT LAST_INSERT_ID()").Scan(&id); err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if id != 3 {
+		t.Fatalf("expected first id 3, got %d", id)
+	}
+
+	// LAST_INSERT_ID() is per connection
+	other, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("db.Conn: Error: %s\n", err)
+	}
+	defer other.Close()
+	if err := other.QueryRowContext(ctx, "SELECT LAST_INSERT_ID()").Scan(&id); err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if id != 0 {
+		t.Fatalf("expected 0 on another connection, got %d", id)
+	}
+}
+
+func TestMySQLSyntaxRequiresDialect(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestMySQLSyntaxRequiresDialect")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE item (id INT AUTO_INCREMENT PRIMARY KEY, name TEXT) ENGINE=InnoDB")
+	if err == nil {
+		t.Fatalf("expected table options to be rejected by the postgres dialect")
+	}
+}
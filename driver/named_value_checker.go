@@ -3,17 +3,43 @@ package ramsql
 import (
 	"database/sql/driver"
 	"reflect"
+	"strings"
+
+	"github.com/proullon/ramsql/engine/agnostic"
 )
 
 // CheckNamedValue implements driver.NamedValueChecker.
-// It allows slice and array values to pass through without conversion so
+//
+// Values of a type registered with RegisterType are encoded. driver.Valuer
+// values, such as uuid.UUID or decimal.Decimal, and byte slices, such as
+// json.RawMessage, fall back to the default database/sql conversion.
+// Other slice and array values pass through without conversion so
 // they can be expanded inside inExecutor when the query is executed.
+// pgx query execution modes, which GORM postgres driver gives as first
+// argument, do not apply to ramsql and are removed.
 // All other value types fall back to the default database/sql conversion.
 func (c *Conn) CheckNamedValue(nv *driver.NamedValue) error {
 	if nv.Value == nil {
 		return driver.ErrSkip
 	}
+	if t := reflect.TypeOf(nv.Value); t.Name() == "QueryExecMode" && strings.HasPrefix(t.PkgPath(), "github.com/jackc/pgx/") {
+		return driver.ErrRemoveArgument
+	}
+	if t, ok := agnostic.LookupGoType(reflect.TypeOf(nv.Value)); ok {
+		v, err := t.Encode(nv.Value)
+		if err != nil {
+			return err
+		}
+		nv.Value = v
+		return nil
+	}
+	if _, ok := nv.Value.(driver.Valuer); ok {
+		return driver.ErrSkip
+	}
 	rv := reflect.ValueOf(nv.Value)
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+		return driver.ErrSkip
+	}
 	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
 		// Accept as-is; executor.inExecutor handles expansion via expandArrayValue.
 		return nil
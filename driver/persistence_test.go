@@ -0,0 +1,72 @@
+package ramsql
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestPersistence(t *testing.T) {
+	cfg := Config{Name: "TestPersistence", File: filepath.Join(t.TempDir(), "db.wal")}
+
+	init := []string{
+		`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT, created_at TIMESTAMP DEFAULT NOW())`,
+		`INSERT INTO account (email) VALUES ('foo@bar.com')`,
+		`INSERT INTO account (email) VALUES ('bar@bar.com')`,
+		`INSERT INTO account (email) VALUES ('baz@bar.com')`,
+		`UPDATE account SET email = 'new@bar.com' WHERE id = 2`,
+		`DELETE FROM account WHERE id = 3`,
+	}
+
+	db := sql.OpenDB(NewDriver().NewConnector(cfg))
+	for _, q := range init {
+		_, err := db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+	db.Close()
+
+	// a new driver simulates a process restart
+	db = sql.OpenDB(NewDriver().NewConnector(cfg))
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT id, email FROM account ORDER BY id`)
+	if err != nil {
+		t.Fatalf("cannot query: %s", err)
+	}
+	defer rows.Close()
+
+	expected := []string{"foo@bar.com", "new@bar.com"}
+	var i int
+	for rows.Next() {
+		var id int64
+		var email string
+		if err := rows.Scan(&id, &email); err != nil {
+			t.Fatalf("cannot scan: %s", err)
+		}
+		if i >= len(expected) || email != expected[i] || id != int64(i+1) {
+			t.Fatalf("unexpected row %d: %d %s", i, id, email)
+		}
+		i++
+	}
+	if i != len(expected) {
+		t.Fatalf("expected %d rows, got %d", len(expected), i)
+	}
+
+	var id int64
+	err = db.QueryRow(`INSERT INTO account (email) VALUES ('last@bar.com') RETURNING id`).Scan(&id)
+	if err != nil {
+		t.Fatalf("cannot insert after replay: %s", err)
+	}
+	if id != 4 {
+		t.Fatalf("expected id 4 after replay, got %d", id)
+	}
+}
+
+func TestPersistenceUnknownOption(t *testing.T) {
+	_, err := NewDriver().Open("TestPersistenceUnknownOption?fil=/tmp/db.wal")
+	if err == nil {
+		t.Fatalf("expected an error with unknown option")
+	}
+}
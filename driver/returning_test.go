@@ -0,0 +1,130 @@
+package ramsql
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestUpdateReturning(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestUpdateReturning")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	init := []string{
+		`CREATE TABLE product (id BIGSERIAL PRIMARY KEY, code TEXT, price BIGINT)`,
+		`INSERT INTO product (code, price) VALUES ('D42', 100)`,
+		`INSERT INTO product (code, price) VALUES ('F42', 300)`,
+	}
+	for _, q := range init {
+		_, err = db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	rows, err := db.Query(`UPDATE product SET price = $1 WHERE code = $2 RETURNING *`, 200, "D42")
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	cols, err := rows.Columns()
+	if err != nil || len(cols) != 3 {
+		t.Fatalf("expected 3 columns, got %v (%v)", cols, err)
+	}
+	if !rows.Next() {
+		t.Fatalf("expected a row: %v", rows.Err())
+	}
+	var id, price int64
+	var code string
+	if err := rows.Scan(&id, &code, &price); err != nil {
+		t.Fatalf("cannot scan: %s", err)
+	}
+	if id != 1 || code != "D42" || price != 200 {
+		t.Fatalf("expected new row image (1, D42, 200), got (%d, %s, %d)", id, code, price)
+	}
+	if rows.Next() {
+		t.Fatalf("expected a single row")
+	}
+	rows.Close()
+
+	rows, err = db.Query(`UPDATE product SET code = 'X' RETURNING id, price * 2 AS double_price, code AS label`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	defer rows.Close()
+	cols, err = rows.Columns()
+	if err != nil || len(cols) != 3 || cols[1] != "double_price" || cols[2] != "label" {
+		t.Fatalf("unexpected columns %v (%v)", cols, err)
+	}
+	var sum int64
+	n := 0
+	for rows.Next() {
+		var double int64
+		if err := rows.Scan(&id, &double, &code); err != nil {
+			t.Fatalf("cannot scan: %s", err)
+		}
+		if code != "X" {
+			t.Fatalf("expected new code X, got %s", code)
+		}
+		sum += double
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("rows.Err: %s", err)
+	}
+	if n != 2 || sum != 1000 {
+		t.Fatalf("expected 2 rows summing to 1000, got %d rows summing to %d", n, sum)
+	}
+
+	// without RETURNING, no row is returned
+	rows, err = db.Query(`UPDATE product SET price = 0 WHERE id > 0`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	defer rows.Close()
+	if rows.Next() {
+		t.Fatalf("expected no row without RETURNING")
+	}
+}
+
+func TestDeleteReturning(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestDeleteReturning")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	init := []string{
+		`CREATE TABLE product (id BIGSERIAL PRIMARY KEY, code TEXT, price BIGINT)`,
+		`INSERT INTO product (code, price) VALUES ('D42', 100)`,
+		`INSERT INTO product (code, price) VALUES ('F42', 300)`,
+	}
+	for _, q := range init {
+		_, err = db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	var id int64
+	var code string
+	err = db.QueryRow(`DELETE FROM product WHERE price > 200 RETURNING product.id AS deleted_id, code`).Scan(&id, &code)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if id != 2 || code != "F42" {
+		t.Fatalf("expected deleted row (2, F42), got (%d, %s)", id, code)
+	}
+
+	_, err = db.Query(`DELETE FROM product RETURNING nope`)
+	if err == nil {
+		t.Fatalf("expected an error returning unknown attribute")
+	}
+
+	var count int64
+	err = db.QueryRow(`SELECT COUNT(*) FROM product`).Scan(&count)
+	if err != nil || count != 1 {
+		t.Fatalf("expected 1 row left, got %d (%v)", count, err)
+	}
+}
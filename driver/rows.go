@@ -4,29 +4,50 @@ import (
 	"database/sql/driver"
 	"fmt"
 	"io"
+	"reflect"
 
 	"github.com/proullon/ramsql/engine/agnostic"
+	"github.com/proullon/ramsql/engine/executor"
 )
 
 // Rows implements the sql/driver Rows interface
+//
+// Rows also implements column type interfaces, so sql.Rows.ColumnTypes is supported.
+//
+// https://pkg.go.dev/database/sql/driver#RowsColumnTypeDatabaseTypeName
+// https://pkg.go.dev/database/sql/driver#RowsColumnTypeScanType
+// https://pkg.go.dev/database/sql/driver#RowsColumnTypeNullable
+// https://pkg.go.dev/database/sql/driver#RowsColumnTypeLength
+// https://pkg.go.dev/database/sql/driver#RowsColumnTypePrecisionScale
+//
+// Rows of a multi-statement query hold a result set per statement, see NextResultSet.
+//
+// https://pkg.go.dev/database/sql/driver#RowsNextResultSet
 type Rows struct {
-	columns []string
-	tuples  []*agnostic.Tuple
-	idx     int
-	end     int
+	columns  []string
+	types    []agnostic.ColumnType
+	decoders []func(any) (any, error)
+	rows     *executor.Rows
 }
 
-func newRows(cols []string, tuples []*agnostic.Tuple) *Rows {
+func newRows(rows *executor.Rows) *Rows {
 
-	r := &Rows{
-		tuples:  tuples,
-		columns: cols,
-		end:     len(tuples) - 1,
-	}
+	r := &Rows{}
+	r.set(rows)
 
 	return r
 }
 
+func (r *Rows) set(rows *executor.Rows) {
+	r.rows = rows
+	r.columns = agnostic.ColumnNames(rows.Columns())
+	r.types = rows.Columns()
+	r.decoders = make([]func(any) (any, error), len(r.types))
+	for i, t := range r.types {
+		r.decoders[i] = columnDecoder(t.TypeName)
+	}
+}
+
 // Columns returns the names of the columns. The number of
 // columns of the result is inferred from the length of the
 // slice.  If a particular column name isn't known, an empty
@@ -35,11 +56,81 @@ func (r *Rows) Columns() []string {
 	return r.columns
 }
 
-// Close closes the rows iterator.
-func (r *Rows) Close() error {
+// ColumnTypeDatabaseTypeName returns the database type name of column index, in upper case.
+func (r *Rows) ColumnTypeDatabaseTypeName(index int) string {
+	return r.types[index].TypeName
+}
+
+// ColumnTypeScanType returns the Go type of column index values.
+//
+// Columns of a type registered with RegisterType return values of its Go type,
+// JSON columns return []byte.
+func (r *Rows) ColumnTypeScanType(index int) reflect.Type {
+	switch r.types[index].TypeName {
+	case "JSON", "JSONB":
+		return reflect.TypeOf([]byte(nil))
+	}
+	if t, ok := agnostic.LookupType(r.types[index].TypeName); ok {
+		return t.GoType
+	}
+	if r.types[index].ScanType == nil {
+		return reflect.TypeOf(new(any)).Elem()
+	}
+	return r.types[index].ScanType
+}
+
+// ColumnTypeNullable reports whether column index may be NULL. ok is false if unknown.
+func (r *Rows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	return r.types[index].Nullable, r.types[index].NullableKnown
+}
+
+// ColumnTypeLength returns the declared length of variable length column index, such as VARCHAR(255).
+func (r *Rows) ColumnTypeLength(index int) (length int64, ok bool) {
+	return r.types[index].Length, r.types[index].HasLength
+}
+
+// ColumnTypePrecisionScale returns the precision and scale of decimal column index.
+func (r *Rows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
+	t := r.types[index]
+	return t.Precision, t.Scale, t.HasPrecision
+}
+
+// HasNextResultSet is called at the end of the current result set and
+// reports whether there is another result set after the current one.
+func (r *Rows) HasNextResultSet() bool {
+	return r.rows.NextResultSet() != nil
+}
+
+// NextResultSet advances the driver to the next result set even
+// if there are remaining rows in the current result set.
+//
+// NextResultSet should return io.EOF when there are no more result sets.
+func (r *Rows) NextResultSet() error {
+	next := r.rows.NextResultSet()
+	if next == nil {
+		return io.EOF
+	}
+
+	if err := r.rows.Close(); err != nil {
+		return err
+	}
+	r.set(next)
 	return nil
 }
 
+// Close closes the rows iterator, including remaining result sets.
+//
+// The implicit transaction of an autocommit query is committed.
+func (r *Rows) Close() error {
+	var err error
+	for rows := r.rows; rows != nil; rows = rows.NextResultSet() {
+		if cerr := rows.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
 // Next is called to populate the next row of data into
 // the provided slice. The provided slice will be the same
 // size as the Columns() are wide.
@@ -49,20 +140,33 @@ func (r *Rows) Close() error {
 // All string values must be converted to []byte.
 //
 // Next should return io.EOF when there are no more rows.
+//
+// Rows are produced on demand. Once all rows of the last result set
+// are read, the implicit transaction of an autocommit query is committed.
 func (r *Rows) Next(dest []driver.Value) (err error) {
-	if r.idx > r.end {
+	tuple, err := r.rows.Next()
+	if err != nil {
+		return err
+	}
+	if tuple == nil {
+		if err := r.rows.Close(); err != nil {
+			return err
+		}
 		return io.EOF
 	}
 
-	tuple := r.tuples[r.idx]
-	r.idx++
-
 	values := tuple.Values()
 	if len(dest) < len(values) {
 		return fmt.Errorf("slice too short (%d slots for %d values)", len(dest), len(values))
 	}
 
 	for i, v := range values {
+		if v != nil && i < len(r.decoders) && r.decoders[i] != nil {
+			v, err = r.decoders[i](v)
+			if err != nil {
+				return fmt.Errorf("cannot decode column %s: %s", r.columns[i], err)
+			}
+		}
 		dest[i] = v
 	}
 
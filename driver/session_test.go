@@ -0,0 +1,292 @@
+package ramsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSessionSetShow(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestSessionSetShow")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("db.Conn: Error: %s\n", err)
+	}
+	defer conn.Close()
+
+	show := func(name string) string {
+		var v string
+		if err := conn.QueryRowContext(context.Background(), `SHOW `+name).Scan(&v); err != nil {
+			t.Fatalf("SHOW %s: Error: %s\n", name, err)
+		}
+		return v
+	}
+
+	if v := show("application_name"); v != "" {
+		t.Fatalf("expected empty application_name, got %q", v)
+	}
+
+	_, err = conn.ExecContext(context.Background(), `SET application_name = 'billing'`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	_, err = conn.ExecContext(context.Background(), `SET TIME ZONE 'Europe/Paris'`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	_, err = conn.ExecContext(context.Background(), `set statement_timeout to 5000`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	if v := show("application_name"); v != "billing" {
+		t.Fatalf("expected application_name billing, got %q", v)
+	}
+	if v := show("TIME ZONE"); v != "Europe/Paris" {
+		t.Fatalf("expected timezone Europe/Paris, got %q", v)
+	}
+	if v := show("statement_timeout"); v != "5s" {
+		t.Fatalf("expected statement_timeout 5s, got %q", v)
+	}
+
+	// other connections have their own session
+	var v string
+	other, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("db.Conn: Error: %s\n", err)
+	}
+	defer other.Close()
+	if err := other.QueryRowContext(context.Background(), `SHOW application_name`).Scan(&v); err != nil || v != "" {
+		t.Fatalf("expected empty application_name on other connection, got %q (%v)", v, err)
+	}
+
+	// SET in a transaction is discarded on rollback, SET LOCAL at the end of the transaction
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("cannot begin: %s", err)
+	}
+	_, err = tx.Exec(`SET application_name = 'rolled back'`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	tx.Rollback()
+	if v := show("application_name"); v != "billing" {
+		t.Fatalf("expected application_name billing after rollback, got %q", v)
+	}
+
+	tx, err = conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("cannot begin: %s", err)
+	}
+	_, err = tx.Exec(`SET LOCAL application_name = 'local'`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	if err := tx.QueryRow(`SHOW application_name`).Scan(&v); err != nil || v != "local" {
+		t.Fatalf("expected local application_name in transaction, got %q (%v)", v, err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("cannot commit: %s", err)
+	}
+	if v := show("application_name"); v != "billing" {
+		t.Fatalf("expected application_name billing after SET LOCAL, got %q", v)
+	}
+
+	_, err = conn.ExecContext(context.Background(), `RESET ALL`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	if v := show("timezone"); v != "UTC" {
+		t.Fatalf("expected timezone UTC after reset, got %q", v)
+	}
+	if v := show("statement_timeout"); v != "0" {
+		t.Fatalf("expected statement_timeout 0 after reset, got %q", v)
+	}
+}
+
+func TestSessionInvalidSettings(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestSessionInvalidSettings")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	var e *Error
+	_, err = db.Exec(`SET work_mem = '64MB'`)
+	if !errors.As(err, &e) || e.SQLState() != "42704" {
+		t.Fatalf("expected unrecognized parameter error, got %v", err)
+	}
+	_, err = db.Query(`SHOW work_mem`)
+	if !errors.As(err, &e) || e.SQLState() != "42704" {
+		t.Fatalf("expected unrecognized parameter error, got %v", err)
+	}
+
+	queries := []string{
+		`SET timezone = 'Mars/Olympus_Mons'`,
+		`SET statement_timeout = 'soon'`,
+		`SET standard_conforming_strings = off`,
+		`SET application_name = 'a', 'b'`,
+	}
+	for _, q := range queries {
+		if _, err := db.Exec(q); err == nil {
+			t.Fatalf("expected error with %s", q)
+		}
+	}
+}
+
+func TestSessionSearchPath(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestSessionSearchPath")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	init := []string{
+		`CREATE SCHEMA tenant_a`,
+		`CREATE SCHEMA tenant_b`,
+		`CREATE TABLE tenant_a.account (id BIGSERIAL PRIMARY KEY, email TEXT)`,
+		`CREATE TABLE tenant_b.account (id BIGSERIAL PRIMARY KEY, email TEXT)`,
+		`INSERT INTO tenant_a.account (email) VALUES ('a@tenant.com')`,
+		`INSERT INTO tenant_b.account (email) VALUES ('b@tenant.com')`,
+	}
+	for _, q := range init {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("db.Conn: Error: %s\n", err)
+	}
+	defer conn.Close()
+
+	var path string
+	if err := conn.QueryRowContext(ctx, `SHOW search_path`).Scan(&path); err != nil || path != `"$user", public` {
+		t.Fatalf("expected default search_path, got %q (%v)", path, err)
+	}
+
+	for _, tenant := range []string{"tenant_a", "tenant_b"} {
+		_, err = conn.ExecContext(ctx, `SET search_path TO `+tenant+`, public`)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+
+		_, err = conn.ExecContext(ctx, `INSERT INTO account (email) VALUES ($1)`, "new@"+tenant)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+
+		var n int
+		if err := conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM account`).Scan(&n); err != nil || n != 2 {
+			t.Fatalf("expected 2 accounts in %s, got %d (%v)", tenant, n, err)
+		}
+
+		var schema string
+		if err := conn.QueryRowContext(ctx, `SELECT CURRENT_SCHEMA()`).Scan(&schema); err != nil || schema != tenant {
+			t.Fatalf("expected current schema %s, got %q (%v)", tenant, schema, err)
+		}
+	}
+
+	var email string
+	err = db.QueryRow(`SELECT email FROM tenant_a.account WHERE email = 'new@tenant_a'`).Scan(&email)
+	if err != nil {
+		t.Fatalf("expected account inserted in tenant_a: %s", err)
+	}
+
+	// other connections still use the public schema
+	_, err = db.Exec(`SELECT * FROM account`)
+	if err == nil {
+		t.Fatalf("expected no account relation in public schema")
+	}
+}
+
+func TestSessionStatementTimeout(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestSessionStatementTimeout")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	err = SetFaultProfile("TestSessionStatementTimeout", FaultProfile{Latency: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("cannot set fault profile: %s", err)
+	}
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("db.Conn: Error: %s\n", err)
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, `SET statement_timeout = '10ms'`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	var e *Error
+	_, err = conn.ExecContext(ctx, `INSERT INTO account (email) VALUES ('foo@bar.com')`)
+	if !errors.As(err, &e) || e.SQLState() != "57014" {
+		t.Fatalf("expected statement timeout, got %v", err)
+	}
+	_, err = conn.QueryContext(ctx, `SELECT * FROM account`)
+	if !errors.As(err, &e) || e.SQLState() != "57014" {
+		t.Fatalf("expected statement timeout, got %v", err)
+	}
+
+	_, err = conn.ExecContext(ctx, `RESET statement_timeout`)
+	if !errors.As(err, &e) || e.SQLState() != "57014" {
+		t.Fatalf("expected statement timeout, got %v", err)
+	}
+
+	err = SetFaultProfile("TestSessionStatementTimeout", FaultProfile{})
+	if err != nil {
+		t.Fatalf("cannot set fault profile: %s", err)
+	}
+	_, err = conn.ExecContext(ctx, `RESET statement_timeout`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	_, err = conn.ExecContext(ctx, `INSERT INTO account (email) VALUES ('foo@bar.com')`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	_, err = conn.ExecContext(ctx, `INSERT INTO account (email) VALUES ('bar@bar.com')`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	// statement timeout applies while rows are read
+	_, err = conn.ExecContext(ctx, `SET statement_timeout = '20ms'`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	rows, err := conn.QueryContext(ctx, `SELECT email FROM account`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		t.Fatalf("expected a row: %v", rows.Err())
+	}
+	time.Sleep(50 * time.Millisecond)
+	if rows.Next() {
+		t.Fatalf("expected statement timeout while reading rows")
+	}
+	if err := rows.Err(); !errors.As(err, &e) || e.SQLState() != "57014" {
+		t.Fatalf("expected statement timeout, got %v", err)
+	}
+}
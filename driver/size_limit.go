@@ -0,0 +1,41 @@
+package ramsql
+
+import (
+	"github.com/proullon/ramsql/engine/agnostic"
+)
+
+// Limits bounds the size of an engine or a relation. Zero values mean unlimited.
+type Limits = agnostic.Limits
+
+// SetLimits sets size limits of the engine opened with dsn.
+func SetLimits(dsn string, l Limits) error {
+	return defaultDriver.SetLimits(dsn, l)
+}
+
+// SetRelationLimits sets size limits of a relation in the engine opened with dsn.
+// Empty schema means default schema.
+func SetRelationLimits(dsn string, schema, relation string, l Limits) error {
+	return defaultDriver.SetRelationLimits(dsn, schema, relation, l)
+}
+
+// SetLimits sets size limits of the engine opened with dsn.
+func (rs *Driver) SetLimits(dsn string, l Limits) error {
+	e, err := rs.engine(dsn)
+	if err != nil {
+		return err
+	}
+
+	e.SetLimits(l)
+	return nil
+}
+
+// SetRelationLimits sets size limits of a relation in the engine opened with dsn.
+// Empty schema means default schema.
+func (rs *Driver) SetRelationLimits(dsn string, schema, relation string, l Limits) error {
+	e, err := rs.engine(dsn)
+	if err != nil {
+		return err
+	}
+
+	return e.SetRelationLimits(schema, relation, l)
+}
@@ -0,0 +1,132 @@
+package ramsql
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSizeLimitMaxRows(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestSizeLimitMaxRows?max_rows=3")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	init := []string{
+		`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT)`,
+		`CREATE TABLE champion (id BIGSERIAL PRIMARY KEY, name TEXT)`,
+		`INSERT INTO account (email) VALUES ('foo@bar.com')`,
+		`INSERT INTO account (email) VALUES ('bar@bar.com')`,
+		`INSERT INTO champion (name) VALUES ('zed')`,
+	}
+	for _, q := range init {
+		_, err = db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	_, err = db.Exec(`INSERT INTO champion (name) VALUES ('lulu')`)
+	if !errors.Is(err, ErrDiskFull) {
+		t.Fatalf("expected disk full error, got %v", err)
+	}
+
+	// deleting frees space
+	_, err = db.Exec(`DELETE FROM account WHERE id = 1`)
+	if err != nil {
+		t.Fatalf("cannot delete: %s", err)
+	}
+	_, err = db.Exec(`INSERT INTO champion (name) VALUES ('lulu')`)
+	if err != nil {
+		t.Fatalf("expected insert to succeed after delete, got %s", err)
+	}
+}
+
+func TestSizeLimitRelation(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestSizeLimitRelation")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	init := []string{
+		`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT)`,
+		`CREATE TABLE champion (id BIGSERIAL PRIMARY KEY, name TEXT)`,
+	}
+	for _, q := range init {
+		_, err = db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	err = SetRelationLimits("TestSizeLimitRelation", "", "account", Limits{MaxRows: 1, MaxBytes: 200})
+	if err != nil {
+		t.Fatalf("cannot set relation limits: %s", err)
+	}
+
+	_, err = db.Exec(`INSERT INTO account (email) VALUES ('foo@bar.com')`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	_, err = db.Exec(`INSERT INTO account (email) VALUES ('bar@bar.com')`)
+	if !errors.Is(err, ErrDiskFull) {
+		t.Fatalf("expected disk full error, got %v", err)
+	}
+
+	// other relations are not limited
+	for i := 0; i < 5; i++ {
+		_, err = db.Exec(`INSERT INTO champion (name) VALUES ('zed')`)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	// updates growing the row beyond maximum size fail too
+	_, err = db.Exec(`UPDATE account SET email = $1 WHERE id = 1`, strings.Repeat("a", 500))
+	if !errors.Is(err, ErrDiskFull) {
+		t.Fatalf("expected disk full error on update, got %v", err)
+	}
+}
+
+func TestSizeLimitMaxRelations(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestSizeLimitMaxRelations?max_relations=1")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	_, err = db.Exec(`CREATE TABLE champion (id BIGSERIAL PRIMARY KEY, name TEXT)`)
+	if !errors.Is(err, ErrDiskFull) {
+		t.Fatalf("expected disk full error, got %v", err)
+	}
+}
+
+func TestSizeLimitMaxBytes(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestSizeLimitMaxBytes?max_bytes=1024")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		_, err = db.Exec(`INSERT INTO account (email) VALUES ($1)`, strings.Repeat("a", 100))
+		if err != nil {
+			break
+		}
+	}
+	if !errors.Is(err, ErrDiskFull) {
+		t.Fatalf("expected disk full error, got %v", err)
+	}
+}
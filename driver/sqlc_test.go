@@ -0,0 +1,357 @@
+package ramsql
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+// Queries below are shaped like sqlc generated code for the postgresql engine with database/sql.
+
+type sqlcDBTX interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	PrepareContext(context.Context, string) (*sql.Stmt, error)
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
+}
+
+type sqlcQueries struct {
+	db sqlcDBTX
+}
+
+func (q *sqlcQueries) WithTx(tx *sql.Tx) *sqlcQueries {
+	return &sqlcQueries{db: tx}
+}
+
+type sqlcAuthor struct {
+	ID   int64
+	Name string
+	Bio  sql.NullString
+}
+
+const sqlcSchema = `CREATE TABLE authors (
+  id   BIGSERIAL PRIMARY KEY,
+  name text      NOT NULL,
+  bio  text
+)`
+
+const createAuthor = `-- name: CreateAuthor :one
+INSERT INTO authors (
+  name, bio
+) VALUES (
+  $1, $2
+)
+RETURNING id, name, bio
+`
+
+type createAuthorParams struct {
+	Name string
+	Bio  sql.NullString
+}
+
+func (q *sqlcQueries) CreateAuthor(ctx context.Context, arg createAuthorParams) (sqlcAuthor, error) {
+	row := q.db.QueryRowContext(ctx, createAuthor, arg.Name, arg.Bio)
+	var i sqlcAuthor
+	err := row.Scan(&i.ID, &i.Name, &i.Bio)
+	return i, err
+}
+
+const getAuthor = `-- name: GetAuthor :one
+SELECT id, name, bio FROM authors
+WHERE id = $1 LIMIT 1
+`
+
+func (q *sqlcQueries) GetAuthor(ctx context.Context, id int64) (sqlcAuthor, error) {
+	row := q.db.QueryRowContext(ctx, getAuthor, id)
+	var i sqlcAuthor
+	err := row.Scan(&i.ID, &i.Name, &i.Bio)
+	return i, err
+}
+
+const listAuthors = `-- name: ListAuthors :many
+SELECT id, name, bio FROM authors
+ORDER BY name
+`
+
+func (q *sqlcQueries) ListAuthors(ctx context.Context) ([]sqlcAuthor, error) {
+	rows, err := q.db.QueryContext(ctx, listAuthors)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []sqlcAuthor
+	for rows.Next() {
+		var i sqlcAuthor
+		if err := rows.Scan(&i.ID, &i.Name, &i.Bio); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchAuthors = `-- name: SearchAuthors :many
+SELECT id, name, bio FROM authors
+WHERE name ILIKE $1
+ORDER BY id
+LIMIT $2
+`
+
+type searchAuthorsParams struct {
+	Name  string
+	Limit int32
+}
+
+func (q *sqlcQueries) SearchAuthors(ctx context.Context, arg searchAuthorsParams) ([]sqlcAuthor, error) {
+	rows, err := q.db.QueryContext(ctx, searchAuthors, arg.Name, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []sqlcAuthor
+	for rows.Next() {
+		var i sqlcAuthor
+		if err := rows.Scan(&i.ID, &i.Name, &i.Bio); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countAuthors = `-- name: CountAuthors :one
+SELECT count(*) FROM authors
+`
+
+func (q *sqlcQueries) CountAuthors(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countAuthors)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const updateAuthor = `-- name: UpdateAuthor :one
+UPDATE authors
+  set name = $2,
+  bio = $3
+WHERE id = $1
+RETURNING id, name, bio
+`
+
+type updateAuthorParams struct {
+	ID   int64
+	Name string
+	Bio  sql.NullString
+}
+
+func (q *sqlcQueries) UpdateAuthor(ctx context.Context, arg updateAuthorParams) (sqlcAuthor, error) {
+	row := q.db.QueryRowContext(ctx, updateAuthor, arg.ID, arg.Name, arg.Bio)
+	var i sqlcAuthor
+	err := row.Scan(&i.ID, &i.Name, &i.Bio)
+	return i, err
+}
+
+// sqlc.narg('bio') is generated as a plain positional parameter
+const patchAuthorBio = `-- name: PatchAuthorBio :exec
+UPDATE authors
+  set bio = COALESCE($2, bio)
+WHERE id = $1
+`
+
+type patchAuthorBioParams struct {
+	ID  int64
+	Bio sql.NullString
+}
+
+func (q *sqlcQueries) PatchAuthorBio(ctx context.Context, arg patchAuthorBioParams) error {
+	_, err := q.db.ExecContext(ctx, patchAuthorBio, arg.ID, arg.Bio)
+	return err
+}
+
+const deleteAuthor = `-- name: DeleteAuthor :execrows
+DELETE FROM authors
+WHERE id = $1
+`
+
+func (q *sqlcQueries) DeleteAuthor(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteAuthor, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func openSqlc(t *testing.T, name string) (*sql.DB, *sqlcQueries) {
+	t.Helper()
+
+	db, err := sql.Open("ramsql", name)
+	if err != nil {
+		t.Fatalf("cannot open db: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(sqlcSchema); err != nil {
+		t.Fatalf("cannot create schema: %s", err)
+	}
+
+	return db, &sqlcQueries{db: db}
+}
+
+func TestSqlcQueries(t *testing.T) {
+	ctx := context.Background()
+	_, q := openSqlc(t, "TestSqlcQueries")
+
+	brian, err := q.CreateAuthor(ctx, createAuthorParams{
+		Name: "Brian Kernighan",
+		Bio:  sql.NullString{String: "Co-author of The C Programming Language", Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("cannot create author: %s", err)
+	}
+	if brian.ID != 1 || brian.Name != "Brian Kernighan" || !brian.Bio.Valid {
+		t.Fatalf("unexpected author: %+v", brian)
+	}
+
+	rob, err := q.CreateAuthor(ctx, createAuthorParams{Name: "Rob Pike"})
+	if err != nil {
+		t.Fatalf("cannot create author: %s", err)
+	}
+	if rob.ID != 2 || rob.Bio.Valid {
+		t.Fatalf("unexpected author: %+v", rob)
+	}
+
+	a, err := q.GetAuthor(ctx, rob.ID)
+	if err != nil {
+		t.Fatalf("cannot get author: %s", err)
+	}
+	if a != rob {
+		t.Fatalf("expected %+v, got %+v", rob, a)
+	}
+
+	_, err = q.GetAuthor(ctx, 42)
+	if err != sql.ErrNoRows {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+
+	authors, err := q.ListAuthors(ctx)
+	if err != nil {
+		t.Fatalf("cannot list authors: %s", err)
+	}
+	if len(authors) != 2 || authors[0].Name != "Brian Kernighan" || authors[1].Name != "Rob Pike" {
+		t.Fatalf("unexpected authors: %+v", authors)
+	}
+
+	authors, err = q.SearchAuthors(ctx, searchAuthorsParams{Name: "%PIKE%", Limit: 10})
+	if err != nil {
+		t.Fatalf("cannot search authors: %s", err)
+	}
+	if len(authors) != 1 || authors[0].ID != rob.ID {
+		t.Fatalf("unexpected authors: %+v", authors)
+	}
+
+	count, err := q.CountAuthors(ctx)
+	if err != nil {
+		t.Fatalf("cannot count authors: %s", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 authors, got %d", count)
+	}
+
+	rob, err = q.UpdateAuthor(ctx, updateAuthorParams{ID: rob.ID, Name: "Rob Pike", Bio: sql.NullString{String: "Go", Valid: true}})
+	if err != nil {
+		t.Fatalf("cannot update author: %s", err)
+	}
+	if rob.Bio.String != "Go" {
+		t.Fatalf("unexpected author: %+v", rob)
+	}
+
+	// NULL argument keeps current value
+	if err := q.PatchAuthorBio(ctx, patchAuthorBioParams{ID: rob.ID}); err != nil {
+		t.Fatalf("cannot patch author: %s", err)
+	}
+	a, err = q.GetAuthor(ctx, rob.ID)
+	if err != nil {
+		t.Fatalf("cannot get author: %s", err)
+	}
+	if a.Bio.String != "Go" {
+		t.Fatalf("expected bio to be kept, got %+v", a)
+	}
+
+	if err := q.PatchAuthorBio(ctx, patchAuthorBioParams{ID: rob.ID, Bio: sql.NullString{String: "Unix, Plan 9, Go", Valid: true}}); err != nil {
+		t.Fatalf("cannot patch author: %s", err)
+	}
+	a, err = q.GetAuthor(ctx, rob.ID)
+	if err != nil {
+		t.Fatalf("cannot get author: %s", err)
+	}
+	if a.Bio.String != "Unix, Plan 9, Go" {
+		t.Fatalf("expected bio to be updated, got %+v", a)
+	}
+
+	n, err := q.DeleteAuthor(ctx, brian.ID)
+	if err != nil {
+		t.Fatalf("cannot delete author: %s", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 row affected, got %d", n)
+	}
+	n, err = q.DeleteAuthor(ctx, brian.ID)
+	if err != nil {
+		t.Fatalf("cannot delete author: %s", err)
+	}
+	if n != 0 {
+		t.Fatalf("expected 0 row affected, got %d", n)
+	}
+}
+
+func TestSqlcWithTx(t *testing.T) {
+	ctx := context.Background()
+	db, q := openSqlc(t, "TestSqlcWithTx")
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("cannot begin: %s", err)
+	}
+	if _, err := q.WithTx(tx).CreateAuthor(ctx, createAuthorParams{Name: "Ken Thompson"}); err != nil {
+		t.Fatalf("cannot create author: %s", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("cannot rollback: %s", err)
+	}
+
+	count, err := q.CountAuthors(ctx)
+	if err != nil {
+		t.Fatalf("cannot count authors: %s", err)
+	}
+	if count != 0 {
+		t.Fatalf("expected no author after rollback, got %d", count)
+	}
+
+	tx, err = db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("cannot begin: %s", err)
+	}
+	a, err := q.WithTx(tx).CreateAuthor(ctx, createAuthorParams{Name: "Ken Thompson"})
+	if err != nil {
+		t.Fatalf("cannot create author: %s", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("cannot commit: %s", err)
+	}
+
+	if _, err := q.GetAuthor(ctx, a.ID); err != nil {
+		t.Fatalf("cannot get author after commit: %s", err)
+	}
+}
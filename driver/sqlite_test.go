@@ -0,0 +1,397 @@
+package ramsql
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+)
+
+func TestSQLiteDialect(t *testing.T) {
+	db, err := sql.Open("ramsql", "ramsql://TestSQLiteDialect?dialect=sqlite")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE user (
+		id INTEGER PRIMARY KEY,
+		email TEXT NOT NULL UNIQUE,
+		age INTEGER,
+		score REAL DEFAULT 0,
+		active BOOLEAN DEFAULT 1
+	)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	// rowid alias
+	res, err := db.Exec(`INSERT INTO user (email, age) VALUES ('foo@bar.com', 32)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	if id, err := res.LastInsertId(); err != nil || id != 1 {
+		t.Fatalf("expected first id 1, got %d (%v)", id, err)
+	}
+	_, err = db.Exec(`INSERT INTO user (id, email, age) VALUES (10, 'bar@bar.com', 40)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	res, err = db.Exec(`INSERT INTO user (id, email, age) VALUES (NULL, 'baz@bar.com', 50)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	if id, err := res.LastInsertId(); err != nil || id != 11 {
+		t.Fatalf("expected id following largest one, got %d (%v)", id, err)
+	}
+
+	// type affinity
+	_, err = db.Exec(`INSERT INTO user (email, age, score, active) VALUES ('qux@bar.com', '27', 3, ?)`, true)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	var age int64
+	var score float64
+	var active bool
+	err = db.QueryRow(`SELECT age, score, active FROM user WHERE email = 'qux@bar.com'`).Scan(&age, &score, &active)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if age != 27 || score != 3 || !active {
+		t.Fatalf("unexpected row (%d, %f, %t)", age, score, active)
+	}
+	_, err = db.Exec(`INSERT INTO user (email, age) VALUES ('unknown@bar.com', 'unknown')`)
+	if err != nil {
+		t.Fatalf("expected text to be stored in INTEGER attribute: %s", err)
+	}
+	var unknown string
+	err = db.QueryRow(`SELECT age FROM user WHERE email = 'unknown@bar.com'`).Scan(&unknown)
+	if err != nil || unknown != "unknown" {
+		t.Fatalf("expected 'unknown', got '%s' (%v)", unknown, err)
+	}
+
+	// conflict resolution
+	_, err = db.Exec(`INSERT OR IGNORE INTO user (email, age) VALUES ('foo@bar.com', 99)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	_, err = db.Exec(`INSERT OR REPLACE INTO user (id, email, age) VALUES (10, 'bar@bar.com', 41)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	_, err = db.Exec(`INSERT INTO user (email, age) VALUES ('foo@bar.com', 99)`)
+	if err == nil {
+		t.Fatalf("expected unicity violation")
+	}
+
+	rows, err := db.Query(`SELECT id, age FROM user WHERE email IN ('foo@bar.com', 'bar@bar.com') ORDER BY id`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	var got [][2]int64
+	for rows.Next() {
+		var id, age int64
+		if err := rows.Scan(&id, &age); err != nil {
+			t.Fatalf("rows.Scan: %s", err)
+		}
+		got = append(got, [2]int64{id, age})
+	}
+	rows.Close()
+	if len(got) != 2 || got[0] != [2]int64{1, 32} || got[1] != [2]int64{10, 41} {
+		t.Fatalf("unexpected rows %v", got)
+	}
+}
+
+func TestSQLiteAutoincrement(t *testing.T) {
+	db, err := sql.Open("ramsql", "ramsql://TestSQLiteAutoincrement?dialect=sqlite")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE tag (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	for _, name := range []string{"a", "b"} {
+		if _, err := db.Exec(`INSERT INTO tag (name) VALUES (?)`, name); err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	var id int64
+	if err := db.QueryRow(`SELECT id FROM tag WHERE name = 'b'`).Scan(&id); err != nil || id != 2 {
+		t.Fatalf("expected id 2, got %d (%v)", id, err)
+	}
+
+	// ids of deleted rows are not reused
+	if _, err := db.Exec(`DELETE FROM tag WHERE id = 2`); err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	res, err := db.Exec(`INSERT INTO tag (name) VALUES ('c')`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	if id, err := res.LastInsertId(); err != nil || id != 3 {
+		t.Fatalf("expected id 3, got %d (%v)", id, err)
+	}
+}
+
+func TestSQLiteRowID(t *testing.T) {
+	db, err := sql.Open("ramsql", "ramsql://TestSQLiteRowID?dialect=sqlite")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE t (id INTEGER PRIMARY KEY, x TEXT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	_, err = db.Exec(`INSERT INTO t (x) VALUES ('a'), ('b')`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	rows, err := db.Query(`SELECT rowid, x FROM t ORDER BY rowid DESC`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		var x string
+		if err := rows.Scan(&id, &x); err != nil {
+			t.Fatalf("rows.Scan: Error: %s\n", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if len(ids) != 2 || ids[0] != 2 || ids[1] != 1 {
+		t.Fatalf("expected rowids [2 1], got %v", ids)
+	}
+
+	_, err = db.Exec(`UPDATE t SET x = 'c' WHERE oid = 1`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	var id int64
+	var x string
+	if err := db.QueryRow(`SELECT t._rowid_, x FROM t WHERE _rowid_ = 1`).Scan(&id, &x); err != nil || id != 1 || x != "c" {
+		t.Fatalf("expected row (1, c), got (%d, %s) (%v)", id, x, err)
+	}
+
+	// without AUTOINCREMENT, the rowid of the deleted largest row is reused
+	if _, err := db.Exec(`DELETE FROM t WHERE id = 2`); err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	res, err := db.Exec(`INSERT INTO t (x) VALUES ('d')`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	if id, err := res.LastInsertId(); err != nil || id != 2 {
+		t.Fatalf("expected rowid 2, got %d (%v)", id, err)
+	}
+	if _, err := db.Exec(`UPDATE t SET id = 10 WHERE id = 1`); err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	res, err = db.Exec(`INSERT INTO t (x) VALUES ('e')`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	if id, err := res.LastInsertId(); err != nil || id != 11 {
+		t.Fatalf("expected rowid 11, got %d (%v)", id, err)
+	}
+}
+
+func TestSQLiteImplicitRowID(t *testing.T) {
+	db, err := sql.Open("ramsql", "ramsql://TestSQLiteImplicitRowID?dialect=sqlite")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE note (body TEXT)`,
+		`INSERT INTO note (body) VALUES ('a'), ('b'), ('c')`,
+		`DELETE FROM note WHERE rowid = 3`,
+	}
+	for _, q := range batch {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("sql.Exec(%s): Error: %s\n", q, err)
+		}
+	}
+	res, err := db.Exec(`INSERT INTO note (body) VALUES ('d')`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	if id, err := res.LastInsertId(); err != nil || id != 3 {
+		t.Fatalf("expected rowid 3, got %d (%v)", id, err)
+	}
+
+	rows, err := db.Query(`SELECT rowid, oid, _rowid_, body FROM note ORDER BY rowid`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	var got []string
+	for rows.Next() {
+		var rowid, oid, id int64
+		var body string
+		if err := rows.Scan(&rowid, &oid, &id, &body); err != nil {
+			t.Fatalf("rows.Scan: %s", err)
+		}
+		if oid != rowid || id != rowid {
+			t.Fatalf("expected oid and _rowid_ to be %d, got %d and %d", rowid, oid, id)
+		}
+		got = append(got, fmt.Sprintf("%d:%s", rowid, body))
+	}
+	rows.Close()
+	if len(got) != 3 || got[0] != "1:a" || got[1] != "2:b" || got[2] != "3:d" {
+		t.Fatalf("unexpected rows %v", got)
+	}
+
+	// rowid is not a column of the relation
+	rows, err = db.Query(`SELECT * FROM note`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	cols, err := rows.Columns()
+	rows.Close()
+	if err != nil || len(cols) != 1 || cols[0] != "body" {
+		t.Fatalf("expected only body column, got %v (%v)", cols, err)
+	}
+	var ddl string
+	err = db.QueryRow(`SELECT sql FROM sqlite_master WHERE name = 'note'`).Scan(&ddl)
+	if err != nil || ddl != "CREATE TABLE note (body TEXT)" {
+		t.Fatalf("unexpected sql '%s' (%v)", ddl, err)
+	}
+}
+
+func TestSQLiteTypelessColumns(t *testing.T) {
+	db, err := sql.Open("ramsql", "ramsql://TestSQLiteTypelessColumns?dialect=sqlite")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`CREATE TABLE kv (k PRIMARY KEY, v)`,
+		`INSERT INTO kv (k, v) VALUES ('a', 1), ('b', 'two')`,
+	}
+	for _, q := range batch {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("sql.Exec(%s): Error: %s\n", q, err)
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO kv (k, v) VALUES ('c', ?)`, "3"); err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	// values are stored as given
+	var n int64
+	if err := db.QueryRow(`SELECT v FROM kv WHERE k = 'a'`).Scan(&n); err != nil || n != 1 {
+		t.Fatalf("expected 1, got %d (%v)", n, err)
+	}
+	var v any
+	if err := db.QueryRow(`SELECT v FROM kv WHERE k = 'c'`).Scan(&v); err != nil || v != "3" {
+		t.Fatalf("expected text '3', got %#v (%v)", v, err)
+	}
+	var s string
+	if err := db.QueryRow(`SELECT v FROM kv WHERE k = 'b'`).Scan(&s); err != nil || s != "two" {
+		t.Fatalf("expected 'two', got '%s' (%v)", s, err)
+	}
+
+	var ddl string
+	err = db.QueryRow(`SELECT sql FROM sqlite_master WHERE name = 'kv'`).Scan(&ddl)
+	if err != nil || ddl != "CREATE TABLE kv (k PRIMARY KEY, v)" {
+		t.Fatalf("unexpected sql '%s' (%v)", ddl, err)
+	}
+}
+
+func TestSQLiteCatalog(t *testing.T) {
+	db, err := sql.Open("ramsql", "ramsql://TestSQLiteCatalog?dialect=sqlite")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	batch := []string{
+		`PRAGMA foreign_keys = ON`,
+		`PRAGMA journal_mode = WAL`,
+		`CREATE TABLE account (id INTEGER PRIMARY KEY, name VARCHAR(64) NOT NULL DEFAULT 'anonymous')`,
+		`CREATE TABLE session (account_id INTEGER REFERENCES account(id), token TEXT, PRIMARY KEY (account_id, token))`,
+		`CREATE INDEX session_token ON session (token)`,
+	}
+	for _, q := range batch {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("sql.Exec(%s): Error: %s\n", q, err)
+		}
+	}
+
+	rows, err := db.Query(`SELECT type, name, tbl_name FROM sqlite_master ORDER BY name`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	var objects []string
+	for rows.Next() {
+		var typ, name, table string
+		if err := rows.Scan(&typ, &name, &table); err != nil {
+			t.Fatalf("rows.Scan: %s", err)
+		}
+		objects = append(objects, typ+":"+name+":"+table)
+	}
+	rows.Close()
+	if len(objects) != 3 || objects[0] != "table:account:account" || objects[1] != "table:session:session" || objects[2] != "index:session_token:session" {
+		t.Fatalf("unexpected sqlite_master rows %v", objects)
+	}
+
+	var ddl string
+	err = db.QueryRow(`SELECT sql FROM sqlite_schema WHERE type = 'table' AND name = 'account'`).Scan(&ddl)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if ddl != "CREATE TABLE account (id INTEGER PRIMARY KEY, name VARCHAR(64) NOT NULL DEFAULT 'anonymous')" {
+		t.Fatalf("unexpected sql '%s'", ddl)
+	}
+
+	rows, err = db.Query(`PRAGMA table_info(session)`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	type column struct {
+		cid     int64
+		name    string
+		typ     string
+		notNull int64
+		dflt    sql.NullString
+		pk      int64
+	}
+	var columns []column
+	for rows.Next() {
+		var c column
+		if err := rows.Scan(&c.cid, &c.name, &c.typ, &c.notNull, &c.dflt, &c.pk); err != nil {
+			t.Fatalf("rows.Scan: %s", err)
+		}
+		columns = append(columns, c)
+	}
+	rows.Close()
+	if len(columns) != 2 {
+		t.Fatalf("expected 2 columns, got %d", len(columns))
+	}
+	if c := columns[1]; c.cid != 1 || c.name != "token" || c.typ != "TEXT" || c.notNull != 0 || c.dflt.Valid || c.pk != 2 {
+		t.Fatalf("unexpected column %+v", c)
+	}
+
+	rows, err = db.Query(`PRAGMA table_info(unknown)`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	if rows.Next() {
+		t.Fatalf("expected no row for unknown relation")
+	}
+	rows.Close()
+
+	if _, err := db.Exec(`PRAGMA foo`); err == nil {
+		t.Fatalf("expected error on unsupported pragma")
+	}
+}
@@ -0,0 +1,263 @@
+package ramsql
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type SqlxPerson struct {
+	ID        int64          `db:"id"`
+	FirstName string         `db:"first_name"`
+	LastName  string         `db:"last_name"`
+	Email     sql.NullString `db:"email"`
+	Age       int            `db:"age"`
+	CreatedAt time.Time      `db:"created_at"`
+}
+
+func openSqlx(t *testing.T, name string) *sqlx.DB {
+	t.Helper()
+
+	ramdb, err := sql.Open("ramsql", name)
+	if err != nil {
+		t.Fatalf("cannot open db: %s", err)
+	}
+	t.Cleanup(func() { ramdb.Close() })
+
+	db := sqlx.NewDb(ramdb, "postgres")
+	db.MustExec(`CREATE TABLE person (
+		id BIGSERIAL PRIMARY KEY,
+		first_name TEXT NOT NULL,
+		last_name TEXT NOT NULL,
+		email TEXT,
+		age INT NOT NULL DEFAULT 0,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`)
+
+	return db
+}
+
+func TestSqlxNamedExec(t *testing.T) {
+	db := openSqlx(t, "TestSqlxNamedExec")
+
+	p := SqlxPerson{FirstName: "Jason", LastName: "Moiron", Email: sql.NullString{String: "jmoiron@jmoiron.net", Valid: true}, Age: 32, CreatedAt: time.Now()}
+	res, err := db.NamedExec(`INSERT INTO person (first_name, last_name, email, age, created_at) VALUES (:first_name, :last_name, :email, :age, :created_at)`, p)
+	if err != nil {
+		t.Fatalf("cannot insert struct: %s", err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		t.Fatalf("expected 1 row affected, got %d", n)
+	}
+
+	_, err = db.NamedExec(`INSERT INTO person (first_name, last_name, email) VALUES (:first, :last, :email)`, map[string]any{
+		"first": "John",
+		"last":  "Doe",
+		"email": nil,
+	})
+	if err != nil {
+		t.Fatalf("cannot insert map: %s", err)
+	}
+
+	// batch insert
+	people := []SqlxPerson{
+		{FirstName: "Ardie", LastName: "Savea", Age: 30, CreatedAt: time.Now()},
+		{FirstName: "Sonny Bill", LastName: "Williams", Age: 38, CreatedAt: time.Now()},
+		{FirstName: "Ngani", LastName: "Laumape", Age: 30, CreatedAt: time.Now()},
+	}
+	res, err = db.NamedExec(`INSERT INTO person (first_name, last_name, email, age, created_at) VALUES (:first_name, :last_name, :email, :age, :created_at)`, people)
+	if err != nil {
+		t.Fatalf("cannot batch insert: %s", err)
+	}
+	if n, _ := res.RowsAffected(); n != 3 {
+		t.Fatalf("expected 3 rows affected, got %d", n)
+	}
+
+	_, err = db.NamedExec(`UPDATE person SET age = :age WHERE first_name = :first_name`, SqlxPerson{FirstName: "John", Age: 41})
+	if err != nil {
+		t.Fatalf("cannot update: %s", err)
+	}
+
+	var count int
+	if err := db.Get(&count, `SELECT COUNT(*) FROM person`); err != nil {
+		t.Fatalf("cannot count: %s", err)
+	}
+	if count != 5 {
+		t.Fatalf("expected 5 people, got %d", count)
+	}
+
+	var age int
+	if err := db.Get(&age, `SELECT age FROM person WHERE first_name = $1`, "John"); err != nil {
+		t.Fatalf("cannot get age: %s", err)
+	}
+	if age != 41 {
+		t.Fatalf("expected age 41, got %d", age)
+	}
+}
+
+func TestSqlxSelect(t *testing.T) {
+	db := openSqlx(t, "TestSqlxSelect")
+
+	db.MustExec(`INSERT INTO person (first_name, last_name, email, age) VALUES ($1, $2, $3, $4)`, "Jason", "Moiron", "jmoiron@jmoiron.net", 32)
+	db.MustExec(`INSERT INTO person (first_name, last_name, age) VALUES ($1, $2, $3)`, "John", "Doe", 41)
+
+	var people []SqlxPerson
+	if err := db.Select(&people, `SELECT * FROM person ORDER BY first_name ASC`); err != nil {
+		t.Fatalf("cannot select: %s", err)
+	}
+	if len(people) != 2 {
+		t.Fatalf("expected 2 people, got %d", len(people))
+	}
+	if people[0].FirstName != "Jason" || !people[0].Email.Valid || people[0].Email.String != "jmoiron@jmoiron.net" || people[0].ID == 0 {
+		t.Fatalf("unexpected person %+v", people[0])
+	}
+	if people[1].Email.Valid || people[1].CreatedAt.IsZero() {
+		t.Fatalf("unexpected person %+v", people[1])
+	}
+
+	var jason SqlxPerson
+	if err := db.Get(&jason, `SELECT * FROM person WHERE first_name = $1`, "Jason"); err != nil {
+		t.Fatalf("cannot get: %s", err)
+	}
+	if jason.Age != 32 {
+		t.Fatalf("unexpected person %+v", jason)
+	}
+
+	err := db.Get(&jason, `SELECT * FROM person WHERE first_name = $1`, "Nobody")
+	if err != sql.ErrNoRows {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+
+	// subset of columns, scanned into a struct
+	var names []struct {
+		First string `db:"first_name"`
+		Last  string `db:"last_name"`
+	}
+	if err := db.Select(&names, `SELECT first_name, last_name FROM person WHERE age > $1`, 35); err != nil {
+		t.Fatalf("cannot select names: %s", err)
+	}
+	if len(names) != 1 || names[0].Last != "Doe" {
+		t.Fatalf("unexpected names %+v", names)
+	}
+
+	rows, err := db.NamedQuery(`SELECT * FROM person WHERE first_name = :fn`, map[string]any{"fn": "Jason"})
+	if err != nil {
+		t.Fatalf("cannot named query: %s", err)
+	}
+	defer rows.Close()
+	var n int
+	for rows.Next() {
+		var p SqlxPerson
+		if err := rows.StructScan(&p); err != nil {
+			t.Fatalf("cannot struct scan: %s", err)
+		}
+		n++
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 row, got %d", n)
+	}
+
+	row := db.QueryRowx(`SELECT first_name, age FROM person WHERE last_name = $1`, "Doe")
+	m := make(map[string]any)
+	if err := row.MapScan(m); err != nil {
+		t.Fatalf("cannot map scan: %s", err)
+	}
+	if m["first_name"] != "John" {
+		t.Fatalf("unexpected row %+v", m)
+	}
+}
+
+func TestSqlxIn(t *testing.T) {
+	db := openSqlx(t, "TestSqlxIn")
+
+	for _, name := range []string{"Alice", "Bob", "Carol", "Dave"} {
+		db.MustExec(`INSERT INTO person (first_name, last_name, age) VALUES ($1, $2, $3)`, name, "Doe", len(name))
+	}
+
+	query, args, err := sqlx.In(`SELECT * FROM person WHERE first_name IN (?) AND age > ? ORDER BY first_name`, []string{"Alice", "Bob", "Dave"}, 3)
+	if err != nil {
+		t.Fatalf("cannot expand IN: %s", err)
+	}
+	query = db.Rebind(query)
+
+	var people []SqlxPerson
+	if err := db.Select(&people, query, args...); err != nil {
+		t.Fatalf("cannot select: %s", err)
+	}
+	if len(people) != 2 || people[0].FirstName != "Alice" || people[1].FirstName != "Dave" {
+		t.Fatalf("unexpected people %+v", people)
+	}
+
+	// named IN
+	query, args, err = sqlx.Named(`SELECT * FROM person WHERE last_name = :last AND age IN (:ages)`, map[string]any{"last": "Doe", "ages": []int{3, 5}})
+	if err != nil {
+		t.Fatalf("cannot bind named: %s", err)
+	}
+	query, args, err = sqlx.In(query, args...)
+	if err != nil {
+		t.Fatalf("cannot expand named IN: %s", err)
+	}
+	people = nil
+	if err := db.Select(&people, db.Rebind(query), args...); err != nil {
+		t.Fatalf("cannot select: %s", err)
+	}
+	if len(people) != 3 {
+		t.Fatalf("expected 3 people, got %d", len(people))
+	}
+
+	// and update
+	query, args, err = sqlx.In(`UPDATE person SET age = ? WHERE id IN (?)`, 99, []int64{people[0].ID, people[1].ID})
+	if err != nil {
+		t.Fatalf("cannot expand IN: %s", err)
+	}
+	res, err := db.Exec(db.Rebind(query), args...)
+	if err != nil {
+		t.Fatalf("cannot update: %s", err)
+	}
+	if n, _ := res.RowsAffected(); n != 2 {
+		t.Fatalf("expected 2 rows affected, got %d", n)
+	}
+}
+
+func TestSqlxTransaction(t *testing.T) {
+	db := openSqlx(t, "TestSqlxTransaction")
+
+	tx := db.MustBegin()
+	tx.MustExec(`INSERT INTO person (first_name, last_name) VALUES ($1, $2)`, "Jason", "Moiron")
+	if _, err := tx.NamedExec(`INSERT INTO person (first_name, last_name) VALUES (:first_name, :last_name)`, &SqlxPerson{FirstName: "John", LastName: "Doe"}); err != nil {
+		t.Fatalf("cannot insert: %s", err)
+	}
+	var people []SqlxPerson
+	if err := tx.Select(&people, `SELECT * FROM person`); err != nil {
+		t.Fatalf("cannot select in transaction: %s", err)
+	}
+	if len(people) != 2 {
+		t.Fatalf("expected 2 people in transaction, got %d", len(people))
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("cannot rollback: %s", err)
+	}
+
+	var count int
+	if err := db.Get(&count, `SELECT COUNT(*) FROM person`); err != nil {
+		t.Fatalf("cannot count: %s", err)
+	}
+	if count != 0 {
+		t.Fatalf("expected rollback to discard inserts, got %d rows", count)
+	}
+
+	stmt, err := db.PrepareNamed(`SELECT * FROM person WHERE first_name = :first_name`)
+	if err != nil {
+		t.Fatalf("cannot prepare named: %s", err)
+	}
+	defer stmt.Close()
+	db.MustExec(`INSERT INTO person (first_name, last_name) VALUES ($1, $2)`, "Jason", "Moiron")
+	var jason SqlxPerson
+	if err := stmt.Get(&jason, map[string]any{"first_name": "Jason"}); err != nil {
+		t.Fatalf("cannot get with prepared named statement: %s", err)
+	}
+	if jason.LastName != "Moiron" {
+		t.Fatalf("unexpected person %+v", jason)
+	}
+}
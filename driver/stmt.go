@@ -4,45 +4,36 @@ import (
 	"context"
 	"database/sql/driver"
 	"fmt"
-	"strings"
+
+	"github.com/proullon/ramsql/engine/executor"
 )
 
 // Stmt implements the Statement interface of sql/driver
+//
+// Query is parsed once when the statement is prepared. Arguments are checked
+// against the type of the attribute each parameter is assigned to.
+//
+// https://pkg.go.dev/database/sql/driver#StmtExecContext
+// https://pkg.go.dev/database/sql/driver#StmtQueryContext
+// https://pkg.go.dev/database/sql/driver#NamedValueChecker
+// https://pkg.go.dev/database/sql/driver#ColumnConverter
 type Stmt struct {
 	conn     *Conn
-	query    string
-	numInput int
+	prepared *executor.Prepared
 }
 
-func countArguments(query string) int {
-	for id := 1; id > 0; id++ {
-		sep := fmt.Sprintf("$%d", id)
-		if strings.Count(query, sep) == 0 {
-			return id - 1
-		}
+func prepareStatement(c *Conn, query string) (*Stmt, error) {
+	p, err := c.e.Prepare(query)
+	if err != nil {
+		return nil, err
 	}
 
-	return -1
-}
-
-func prepareStatement(c *Conn, query string) *Stmt {
-
-	// Parse number of arguments here
-	// Should handler either Postgres ($*) or ODBC (?) parameter markers
-	numInput := strings.Count(query, "?")
-	// if numInput == 0, maybe it's Postgres format
-	if numInput == 0 {
-		numInput = countArguments(query)
-	}
-
-	// Create statement
 	stmt := &Stmt{
 		conn:     c,
-		query:    query,
-		numInput: numInput,
+		prepared: p,
 	}
 
-	return stmt
+	return stmt, nil
 }
 
 // Close closes the statement.
@@ -63,12 +54,59 @@ func (s *Stmt) Close() error {
 // its number of placeholders. In that case, the sql package
 // will not sanity check Exec or Query argument counts.
 func (s *Stmt) NumInput() int {
-	return s.numInput
+	return s.prepared.NumInput()
+}
+
+// CheckNamedValue converts argument as database/sql would, then checks it
+// can be assigned to its parameter.
+//
+// Implemented for NamedValueChecker interface
+func (s *Stmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if err := s.conn.CheckNamedValue(nv); err != driver.ErrSkip {
+		return err
+	}
+
+	v, err := driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	if err != nil {
+		return err
+	}
+	nv.Value = v
+
+	return s.prepared.CheckArg(nv.Name, nv.Ordinal, v)
+}
+
+// ColumnConverter returns a ValueConverter for the parameter at index idx.
+//
+// Implemented for ColumnConverter interface
+func (s *Stmt) ColumnConverter(idx int) driver.ValueConverter {
+	return paramConverter{p: s.prepared, ordinal: idx + 1}
+}
+
+// paramConverter checks values of a positional parameter
+type paramConverter struct {
+	p       *executor.Prepared
+	ordinal int
+}
+
+func (c paramConverter) ConvertValue(v any) (driver.Value, error) {
+	v, err := driver.DefaultParameterConverter.ConvertValue(v)
+	if err != nil {
+		return nil, err
+	}
+	return v, c.p.CheckArg("", c.ordinal, v)
 }
 
 // Exec executes a query that doesn't return rows, such
 // as an INSERT or UPDATE.
-func (s *Stmt) Exec(args []driver.Value) (r driver.Result, err error) {
+func (s *Stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), ordinalValues(args))
+}
+
+// ExecContext executes a query that doesn't return rows, such
+// as an INSERT or UPDATE.
+//
+// Implemented for StmtExecContext interface
+func (s *Stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (r driver.Result, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("fatalf error: %s", r)
@@ -76,21 +114,22 @@ func (s *Stmt) Exec(args []driver.Value) (r driver.Result, err error) {
 		}
 	}()
 
-	if s.query == "" {
-		return nil, fmt.Errorf("empty statement")
-	}
-
-	var cargs []driver.NamedValue
-	for i, arg := range args {
-		cargs = append(cargs, driver.NamedValue{Name: fmt.Sprintf("%d", i+1), Ordinal: i + 1, Value: arg})
-	}
-
-	return s.conn.ExecContext(context.Background(), s.query, cargs)
+	return s.conn.exec(args, func(tx *executor.Tx, a []executor.NamedValue) (int64, int64, error) {
+		return tx.ExecPrepared(ctx, s.prepared, a)
+	})
 }
 
 // Query executes a query that may return rows, such as a
 // SELECT.
-func (s *Stmt) Query(args []driver.Value) (r driver.Rows, err error) {
+func (s *Stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), ordinalValues(args))
+}
+
+// QueryContext executes a query that may return rows, such as a
+// SELECT.
+//
+// Implemented for StmtQueryContext interface
+func (s *Stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (r driver.Rows, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("fatalf error: %s", r)
@@ -98,13 +137,15 @@ func (s *Stmt) Query(args []driver.Value) (r driver.Rows, err error) {
 		}
 	}()
 
-	if s.query == "" {
-		return nil, fmt.Errorf("empty statement")
-	}
-	var cargs []driver.NamedValue
+	return s.conn.query(args, func(tx *executor.Tx, a []executor.NamedValue) (*executor.Rows, error) {
+		return tx.QueryPrepared(ctx, s.prepared, a)
+	})
+}
+
+func ordinalValues(args []driver.Value) []driver.NamedValue {
+	nv := make([]driver.NamedValue, len(args))
 	for i, arg := range args {
-		cargs = append(cargs, driver.NamedValue{Name: fmt.Sprintf("%d", i+1), Ordinal: i + 1, Value: arg})
+		nv[i] = driver.NamedValue{Name: fmt.Sprintf("%d", i+1), Ordinal: i + 1, Value: arg}
 	}
-
-	return s.conn.QueryContext(context.Background(), s.query, cargs)
+	return nv
 }
@@ -0,0 +1,125 @@
+package ramsql
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func TestPreparedInsert(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestPreparedInsert")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT, age BIGINT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	stmt, err := db.Prepare(`INSERT INTO account (email, age) VALUES (?, ?)`)
+	if err != nil {
+		t.Fatalf("sql.Prepare: Error: %s\n", err)
+	}
+	defer stmt.Close()
+
+	for i := 0; i < 100; i++ {
+		_, err = stmt.Exec("foo@bar.com", i)
+		if err != nil {
+			t.Fatalf("stmt.Exec: Error: %s\n", err)
+		}
+	}
+
+	var count, sum int64
+	err = db.QueryRow(`SELECT COUNT(*) FROM account`).Scan(&count)
+	if err != nil || count != 100 {
+		t.Fatalf("expected 100 rows, got %d (%v)", count, err)
+	}
+	err = db.QueryRow(`SELECT age FROM account WHERE id = 100`).Scan(&sum)
+	if err != nil || sum != 99 {
+		t.Fatalf("expected age 99, got %d (%v)", sum, err)
+	}
+
+	_, err = stmt.Exec("foo@bar.com")
+	if err == nil {
+		t.Fatalf("expected an error with missing argument")
+	}
+}
+
+func TestPreparedMarkerInLiteral(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestPreparedMarkerInLiteral")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	init := []string{
+		`CREATE TABLE question (id BIGSERIAL PRIMARY KEY, text TEXT, author TEXT)`,
+		`INSERT INTO question (text, author) VALUES ('what?', 'foo')`,
+		`INSERT INTO question (text, author) VALUES ('why $1?', 'bar')`,
+	}
+	for _, q := range init {
+		_, err = db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	stmt, err := db.Prepare(`SELECT id FROM question WHERE text = 'what?' AND author = ?`)
+	if err != nil {
+		t.Fatalf("sql.Prepare: Error: %s\n", err)
+	}
+	defer stmt.Close()
+
+	var id int64
+	err = stmt.QueryRow("foo").Scan(&id)
+	if err != nil || id != 1 {
+		t.Fatalf("expected question 1, got %d (%v)", id, err)
+	}
+
+	stmt, err = db.Prepare(`SELECT id FROM question WHERE text = 'why $1?'`)
+	if err != nil {
+		t.Fatalf("sql.Prepare: Error: %s\n", err)
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow().Scan(&id)
+	if err != nil || id != 2 {
+		t.Fatalf("expected question 2, got %d (%v)", id, err)
+	}
+}
+
+func TestPreparedArgumentType(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestPreparedArgumentType")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT, age BIGINT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	_, err = db.Prepare(`SELEKT * FROM account`)
+	if err == nil {
+		t.Fatalf("expected a syntax error on prepare")
+	}
+
+	stmt, err := db.Prepare(`UPDATE account SET age = $2 WHERE email = $1`)
+	if err != nil {
+		t.Fatalf("sql.Prepare: Error: %s\n", err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec("foo@bar.com", "forty")
+	if err == nil || !strings.Contains(err.Error(), "$2") {
+		t.Fatalf("expected an error on parameter $2, got %v", err)
+	}
+
+	_, err = stmt.Exec("foo@bar.com", 42)
+	if err != nil {
+		t.Fatalf("stmt.Exec: Error: %s\n", err)
+	}
+}
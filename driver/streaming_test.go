@@ -0,0 +1,166 @@
+package ramsql
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestStreamingLimit(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestStreamingLimit")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE event (id BIGSERIAL PRIMARY KEY, name TEXT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+	_, err = db.Exec(`GENERATE 5000 ROWS FOR event SEED 1`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	rows, err := db.Query(`SELECT id, name FROM event LIMIT 3 OFFSET 10`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatalf("cannot scan: %s", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("rows.Err: %s", err)
+	}
+	if len(ids) != 3 || ids[0] != 11 || ids[2] != 13 {
+		t.Fatalf("expected ids 11 to 13, got %v", ids)
+	}
+
+	// rows read are recorded once closed
+	history, err := QueryHistory("TestStreamingLimit")
+	if err != nil {
+		t.Fatalf("cannot get query history: %s", err)
+	}
+	last := history[len(history)-1]
+	if last.Rows != 3 || last.Err != nil {
+		t.Fatalf("expected 3 rows recorded, got %d (%v)", last.Rows, last.Err)
+	}
+
+	var count int64
+	err = db.QueryRow(`SELECT COUNT(*) FROM event`).Scan(&count)
+	if err != nil || count != 5000 {
+		t.Fatalf("expected 5000 rows, got %d (%v)", count, err)
+	}
+}
+
+func TestStreamingUnreadRows(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestStreamingUnreadRows")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	init := []string{
+		`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT)`,
+		`INSERT INTO account (email) VALUES ('foo@bar.com')`,
+		`INSERT INTO account (email) VALUES ('bar@bar.com')`,
+		`INSERT INTO account (email) VALUES ('baz@bar.com')`,
+	}
+	for _, q := range init {
+		_, err = db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	rows, err := db.Query(`SELECT email FROM account`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		t.Fatalf("expected a row: %v", rows.Err())
+	}
+
+	// writing the relation from another connection must not wait for rows to be read
+	_, err = db.Exec(`INSERT INTO account (email) VALUES ('qux@bar.com')`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	// rows are read as they were when the query started
+	n := 1
+	for rows.Next() {
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("rows.Err: %s", err)
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 rows, got %d", n)
+	}
+}
+
+func TestStreamingSnapshot(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestStreamingSnapshot")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	init := []string{
+		`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT)`,
+		`INSERT INTO account (email) VALUES ('foo@bar.com')`,
+		`INSERT INTO account (email) VALUES ('bar@bar.com')`,
+		`INSERT INTO account (email) VALUES ('baz@bar.com')`,
+	}
+	for _, q := range init {
+		_, err = db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	rows, err := db.Query(`SELECT id, email FROM account`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	defer rows.Close()
+
+	// rows changed while reading are returned as they were
+	var emails []string
+	for rows.Next() {
+		var id int64
+		var email string
+		if err := rows.Scan(&id, &email); err != nil {
+			t.Fatalf("cannot scan: %s", err)
+		}
+		emails = append(emails, email)
+
+		_, err = db.Exec(`UPDATE account SET email = 'updated' WHERE id = $1`, id+1)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+		_, err = db.Exec(`DELETE FROM account WHERE id = $1`, id)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("rows.Err: %s", err)
+	}
+	if len(emails) != 3 || emails[0] != "foo@bar.com" || emails[1] != "bar@bar.com" || emails[2] != "baz@bar.com" {
+		t.Fatalf("expected emails as they were, got %v", emails)
+	}
+
+	var count int64
+	err = db.QueryRow(`SELECT COUNT(*) FROM account`).Scan(&count)
+	if err != nil || count != 0 {
+		t.Fatalf("expected all rows deleted, got %d (%v)", count, err)
+	}
+}
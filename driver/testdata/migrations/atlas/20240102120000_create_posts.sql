@@ -0,0 +1,4 @@
+-- Create "posts" table
+CREATE TABLE "posts" ("id" bigint NOT NULL GENERATED BY DEFAULT AS IDENTITY, "user_id" bigint NOT NULL, "title" character varying NOT NULL, "body" text NULL, PRIMARY KEY ("id"), CONSTRAINT "posts_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE);
+-- Modify "users" table
+ALTER TABLE "users" ADD COLUMN "name" character varying NULL;
@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE users (
+  id BIGSERIAL PRIMARY KEY,
+  email TEXT NOT NULL UNIQUE,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX users_created_at_idx ON users (created_at);
+
+-- +goose Down
+DROP INDEX users_created_at_idx;
+DROP TABLE users;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE posts (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  title TEXT NOT NULL,
+  body TEXT
+);
+-- +goose StatementEnd
+INSERT INTO users (email) VALUES ('admin@example.com');
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE posts;
+-- +goose StatementEnd
+DELETE FROM users WHERE email = 'admin@example.com';
@@ -0,0 +1,2 @@
+-- display name, optional
+ALTER TABLE users ADD COLUMN name TEXT;
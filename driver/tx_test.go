@@ -137,6 +137,90 @@ func TestTransactionRollback(t *testing.T) {
 	}
 }
 
+func TestTransactionRollbackUpdateDelete(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestTransactionRollbackUpdateDelete")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	init := []string{
+		`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, email TEXT)`,
+		`INSERT INTO account (email) VALUES ('foo@bar.com')`,
+		`INSERT INTO account (email) VALUES ('bar@bar.com')`,
+		`INSERT INTO account (email) VALUES ('baz@bar.com')`,
+	}
+	for _, q := range init {
+		_, err = db.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("cannot begin transaction: %s", err)
+	}
+	queries := []string{
+		`INSERT INTO account (email) VALUES ('new@bar.com')`,
+		`UPDATE account SET email = 'qux@bar.com' WHERE id = 2`,
+		`UPDATE account SET email = 'quux@bar.com' WHERE id = 2`,
+		`UPDATE account SET email = 'newer@bar.com' WHERE id = 4`,
+		`DELETE FROM account WHERE id = 4`,
+		`DELETE FROM account WHERE id = 1`,
+		`DELETE FROM account WHERE id = 2`,
+	}
+	for _, q := range queries {
+		_, err = tx.Exec(q)
+		if err != nil {
+			t.Fatalf("sql.Exec: Error: %s\n", err)
+		}
+	}
+	err = tx.Rollback()
+	if err != nil {
+		t.Fatalf("cannot rollback transaction: %s", err)
+	}
+
+	rows, err := db.Query(`SELECT id, email FROM account`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	defer rows.Close()
+
+	expected := []string{"foo@bar.com", "bar@bar.com", "baz@bar.com"}
+	var n int
+	for rows.Next() {
+		var id int64
+		var email string
+		if err := rows.Scan(&id, &email); err != nil {
+			t.Fatalf("rows.Scan: Error: %s\n", err)
+		}
+		if n >= len(expected) || email != expected[n] || id != int64(n+1) {
+			t.Fatalf("unexpected row %d: (%d, %s)", n, id, email)
+		}
+		n++
+	}
+	if n != len(expected) {
+		t.Fatalf("expected %d rows, got %d", len(expected), n)
+	}
+
+	// primary key index is restored
+	for i, e := range expected {
+		var email string
+		err = db.QueryRow(`SELECT email FROM account WHERE id = $1`, i+1).Scan(&email)
+		if err != nil {
+			t.Fatalf("sql.QueryRow: Error: %s\n", err)
+		}
+		if email != e {
+			t.Fatalf("expected %s for id %d, got %s", e, i+1, email)
+		}
+	}
+	err = db.QueryRow(`SELECT email FROM account WHERE id = 4`).Scan(new(string))
+	if err != sql.ErrNoRows {
+		t.Fatalf("expected no row for rolled back insert, got %v", err)
+	}
+}
+
 func TestCheckAttributes(t *testing.T) {
 
 	db, err := sql.Open("ramsql", "TestCheckAttribute")
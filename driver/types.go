@@ -0,0 +1,74 @@
+package ramsql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+
+	"github.com/proullon/ramsql/engine/agnostic"
+)
+
+// RegisterType maps the Go type of value to column type typeName, in every engine.
+//
+// Arguments of that type are stored as returned by encode. Values of typeName
+// columns are returned as decode returns them, so they can be scanned into a
+// value of that type, or into any.
+//
+//	ramsql.RegisterType(Money{}, "MONEY",
+//		func(v any) (driver.Value, error) { return v.(Money).Cents, nil },
+//		func(v driver.Value) (any, error) { return Money{Cents: v.(int64)}, nil },
+//	)
+//	db.Exec(`CREATE TABLE account (id BIGSERIAL PRIMARY KEY, balance MONEY)`)
+func RegisterType(value any, typeName string, encode func(any) (driver.Value, error), decode func(driver.Value) (any, error)) error {
+	if value == nil {
+		return fmt.Errorf("cannot register type %s of nil value", typeName)
+	}
+
+	return agnostic.RegisterType(agnostic.Type{
+		Name:   typeName,
+		GoType: reflect.TypeOf(value),
+		Encode: func(v any) (any, error) { return encode(v) },
+		Decode: func(v any) (any, error) { return decode(v) },
+	})
+}
+
+// columnDecoder returns the function converting values of a typeName column
+// before they are returned by Rows, or nil if they are returned as stored.
+func columnDecoder(typeName string) func(any) (any, error) {
+	switch typeName {
+	case "JSON", "JSONB":
+		return jsonDecoder
+	}
+
+	if agnostic.IsArrayType(typeName) {
+		return arrayDecoder
+	}
+
+	if t, ok := agnostic.LookupType(typeName); ok {
+		return t.Decode
+	}
+	return nil
+}
+
+// arrayDecoder returns arrays in PostgreSQL text format as []byte, so they can
+// be scanned with array types of PostgreSQL drivers, such as pq.Array
+func arrayDecoder(v any) (any, error) {
+	if s, ok := agnostic.FormatArray(v); ok {
+		return []byte(s), nil
+	}
+	return v, nil
+}
+
+// jsonDecoder returns JSON documents as []byte, so they can be scanned into
+// json.RawMessage as well as string
+func jsonDecoder(v any) (any, error) {
+	switch v := v.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return append([]byte(nil), v...), nil
+	case agnostic.JSON:
+		return []byte(v.Text()), nil
+	}
+	return v, nil
+}
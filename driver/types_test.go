@@ -0,0 +1,158 @@
+package ramsql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// amount is bound as text and scanned from a number, like decimal types
+type amount struct {
+	cents int64
+}
+
+func (a amount) Value() (driver.Value, error) {
+	return fmt.Sprintf("%d.%02d", a.cents/100, a.cents%100), nil
+}
+
+func (a *amount) Scan(src any) error {
+	switch v := src.(type) {
+	case float64:
+		a.cents = int64(v*100 + 0.5)
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+		a.cents = int64(f*100 + 0.5)
+	default:
+		return fmt.Errorf("cannot scan %T into amount", src)
+	}
+	return nil
+}
+
+func TestValuerScanner(t *testing.T) {
+	db, err := sql.Open("ramsql", "TestValuerScanner")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE item (id UUID PRIMARY KEY, price DECIMAL, doc JSONB)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	id := uuid.New()
+	doc := json.RawMessage(`{"color":"blue"}`)
+	_, err = db.Exec(`INSERT INTO item (id, price, doc) VALUES ($1, $2, $3)`, id, amount{cents: 1250}, doc)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	var gotID uuid.UUID
+	var gotPrice amount
+	var gotDoc json.RawMessage
+	err = db.QueryRow(`SELECT id, price, doc FROM item WHERE id = $1`, id).Scan(&gotID, &gotPrice, &gotDoc)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if gotID != id {
+		t.Fatalf("expected id %s, got %s", id, gotID)
+	}
+	if gotPrice.cents != 1250 {
+		t.Fatalf("expected 1250 cents, got %d", gotPrice.cents)
+	}
+	// jsonb documents are returned normalized
+	if string(gotDoc) != `{"color": "blue"}` {
+		t.Fatalf("expected document %s, got %s", doc, gotDoc)
+	}
+
+	var text string
+	err = db.QueryRow(`SELECT doc FROM item`).Scan(&text)
+	if err != nil || text != `{"color": "blue"}` {
+		t.Fatalf("expected document %s as string, got %s (%v)", doc, text, err)
+	}
+}
+
+type point struct {
+	X, Y int64
+}
+
+func TestRegisterType(t *testing.T) {
+	err := RegisterType(point{}, "POINT",
+		func(v any) (driver.Value, error) {
+			p := v.(point)
+			return fmt.Sprintf("(%d,%d)", p.X, p.Y), nil
+		},
+		func(v driver.Value) (any, error) {
+			var p point
+			_, err := fmt.Sscanf(v.(string), "(%d,%d)", &p.X, &p.Y)
+			return p, err
+		},
+	)
+	if err != nil {
+		t.Fatalf("RegisterType: Error: %s\n", err)
+	}
+
+	err = RegisterType(0, "BIGINT", nil, nil)
+	if err == nil {
+		t.Fatalf("expected an error registering an incomplete builtin type")
+	}
+
+	db, err := sql.Open("ramsql", "TestRegisterType")
+	if err != nil {
+		t.Fatalf("sql.Open : Error : %s\n", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE place (id BIGSERIAL PRIMARY KEY, location POINT)`)
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	_, err = db.Exec(`INSERT INTO place (location) VALUES ($1)`, point{X: 3, Y: 4})
+	if err != nil {
+		t.Fatalf("sql.Exec: Error: %s\n", err)
+	}
+
+	var p point
+	err = db.QueryRow(`SELECT location FROM place WHERE location = $1`, point{X: 3, Y: 4}).Scan(&p)
+	if err != nil {
+		t.Fatalf("sql.QueryRow: Error: %s\n", err)
+	}
+	if p.X != 3 || p.Y != 4 {
+		t.Fatalf("expected point (3,4), got %v", p)
+	}
+
+	rows, err := db.Query(`SELECT location FROM place`)
+	if err != nil {
+		t.Fatalf("sql.Query: Error: %s\n", err)
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatalf("rows.ColumnTypes: Error: %s\n", err)
+	}
+	if types[0].DatabaseTypeName() != "POINT" || types[0].ScanType() != reflect.TypeOf(point{}) {
+		t.Fatalf("expected POINT column of %s, got %s of %s", reflect.TypeOf(point{}), types[0].DatabaseTypeName(), types[0].ScanType())
+	}
+
+	var v any
+	if !rows.Next() {
+		t.Fatalf("expected a row")
+	}
+	if err := rows.Scan(&v); err != nil {
+		t.Fatalf("rows.Scan: Error: %s\n", err)
+	}
+	if v != (point{X: 3, Y: 4}) {
+		t.Fatalf("expected point (3,4), got %v (%T)", v, v)
+	}
+}
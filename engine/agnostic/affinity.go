@@ -0,0 +1,100 @@
+package agnostic
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// affinityType returns the storage type of a declared type name following
+// SQLite type affinity rules, see https://www.sqlite.org/datatype3.html.
+//
+// Dates and booleans have no storage class in SQLite, they are stored as
+// time.Time and integers. Values of an attribute without type are stored as given.
+func affinityType(typeName string) reflect.Type {
+	name := strings.ToUpper(typeName)
+
+	switch {
+	case name == "":
+		return reflect.TypeOf((*any)(nil)).Elem()
+	case strings.Contains(name, "INT"):
+		return reflect.TypeOf(int64(0))
+	case strings.Contains(name, "CHAR"), strings.Contains(name, "CLOB"), strings.Contains(name, "TEXT"),
+		strings.Contains(name, "BLOB"):
+		return reflect.TypeOf("")
+	case strings.Contains(name, "REAL"), strings.Contains(name, "FLOA"), strings.Contains(name, "DOUB"):
+		return reflect.TypeOf(float64(0))
+	case strings.Contains(name, "BOOL"):
+		return reflect.TypeOf(int64(0))
+	case strings.Contains(name, "DATE"), strings.Contains(name, "TIME"):
+		return reflect.TypeOf(time.Time{})
+	}
+
+	// NUMERIC affinity
+	return reflect.TypeOf(float64(0))
+}
+
+// affinityValue converts val to type to when it holds the same value,
+// for example text '42' to an integer or true to 1.
+// val is returned unchanged if it cannot be converted.
+func affinityValue(val any, to reflect.Type) any {
+	switch to.Kind() {
+	case reflect.Int64:
+		switch v := val.(type) {
+		case bool:
+			if v {
+				return int64(1)
+			}
+			return int64(0)
+		case string:
+			s := strings.TrimSpace(v)
+			if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return i
+			}
+			if f, err := strconv.ParseFloat(s, 64); err == nil && f == float64(int64(f)) {
+				return int64(f)
+			}
+		case float64:
+			if v == float64(int64(v)) {
+				return int64(v)
+			}
+		}
+	case reflect.Float64:
+		switch v := val.(type) {
+		case bool:
+			if v {
+				return float64(1)
+			}
+			return float64(0)
+		case string:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return f
+			}
+		}
+	case reflect.String:
+		switch v := val.(type) {
+		case bool:
+			if v {
+				return "1"
+			}
+			return "0"
+		case int, int8, int16, int32, int64:
+			return strconv.FormatInt(reflect.ValueOf(v).Int(), 10)
+		case uint, uint8, uint16, uint32, uint64:
+			return strconv.FormatUint(reflect.ValueOf(v).Uint(), 10)
+		case float32, float64:
+			return strconv.FormatFloat(reflect.ValueOf(v).Float(), 'g', -1, 64)
+		case time.Time:
+			return v.Format("2006-01-02 15:04:05.999999999-07:00")
+		}
+	case reflect.Struct:
+		if s, ok := val.(string); ok && to == reflect.TypeOf(time.Time{}) {
+			if t, err := parseDate(s); err == nil {
+				return t
+			}
+		}
+	}
+
+	return val
+}
@@ -0,0 +1,162 @@
+package agnostic
+
+import (
+	"container/list"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// AggregateSelector computes SUM, MIN, MAX, AVG, ARRAY_AGG or JSONB_AGG of an attribute over all rows.
+//
+// NULL values are ignored, except by JSONB_AGG, and the aggregate of no value is NULL.
+type AggregateSelector struct {
+	relation  string
+	attribute string
+	fn        string
+	distinct  bool
+	alias     string
+	cols      []string
+}
+
+// NewAggregateSelector creates an AggregateSelector applying fn, one of sum, min, max, avg, array_agg or jsonb_agg, to attr values
+func NewAggregateSelector(rname string, fn string, attr string, distinct bool) (*AggregateSelector, error) {
+	fn = strings.ToLower(fn)
+	switch fn {
+	case "sum", "min", "max", "avg", "array_agg", "jsonb_agg":
+	case "json_agg":
+		fn = "jsonb_agg"
+	default:
+		return nil, fmt.Errorf("unknown aggregate function %s", fn)
+	}
+
+	s := &AggregateSelector{
+		relation:  rname,
+		attribute: attr,
+		fn:        fn,
+		distinct:  distinct,
+	}
+	return s, nil
+}
+
+func (s *AggregateSelector) Attribute() []string {
+	if s.cols != nil {
+		return s.cols
+	}
+
+	return []string{s.attribute}
+}
+
+func (s *AggregateSelector) Relation() string {
+	return s.relation
+}
+
+func (s *AggregateSelector) Alias() string {
+	return s.alias
+}
+
+func (s *AggregateSelector) Select(cols []string, in []*list.Element) (out []*Tuple, err error) {
+	idx := -1
+	for i, c := range cols {
+		if c == s.attribute || c == s.relation+"."+s.attribute {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		return nil, fmt.Errorf("%s.%s: columns not found in left node", s.relation, s.attribute)
+	}
+
+	if s.distinct {
+		s.cols = []string{strings.ToUpper(s.fn) + "(DISTINCT " + s.attribute + ")"}
+	} else {
+		s.cols = []string{strings.ToUpper(s.fn) + "(" + s.attribute + ")"}
+	}
+
+	var values []any
+	seen := make(map[string]struct{})
+	for _, e := range in {
+		v := e.Value.(*Tuple).values[idx]
+		if v == nil && s.fn != "jsonb_agg" {
+			continue
+		}
+		if s.distinct {
+			k := fmt.Sprintf("%v", v)
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+		}
+		values = append(values, v)
+	}
+
+	v, err := s.aggregate(values)
+	if err != nil {
+		return nil, err
+	}
+	out = append(out, NewTuple(v))
+	return
+}
+
+func (s *AggregateSelector) aggregate(values []any) (any, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	switch s.fn {
+	case "array_agg":
+		// NULL values are skipped, arrays cannot hold them
+		return NewArray(values), nil
+	case "jsonb_agg":
+		a := make([]any, len(values))
+		for i, v := range values {
+			a[i] = jsonValue(v)
+		}
+		return NewJSON(a), nil
+	case "min", "max":
+		res := values[0]
+		for _, v := range values[1:] {
+			gt, err := greater(v, res)
+			if err != nil {
+				return nil, err
+			}
+			if gt == (s.fn == "max") {
+				res = v
+			}
+		}
+		return res, nil
+	}
+
+	// sum of integers is an integer, other sums and averages are floats
+	var isum int64
+	var fsum float64
+	integers := true
+	for _, v := range values {
+		rv := reflect.ValueOf(v)
+		f, ok := numericValue(rv)
+		if !ok {
+			return nil, fmt.Errorf("cannot compute %s of %v (%s)", s.fn, v, rv.Type())
+		}
+		if rv.CanInt() {
+			isum += rv.Int()
+		} else {
+			integers = false
+		}
+		fsum += f
+	}
+
+	if s.fn == "avg" {
+		return fsum / float64(len(values)), nil
+	}
+	if integers {
+		return isum, nil
+	}
+	return fsum, nil
+}
+
+func (s AggregateSelector) String() string {
+	if s.distinct {
+		return fmt.Sprintf("%s(DISTINCT %s.%s)", strings.ToUpper(s.fn), s.relation, s.attribute)
+	}
+	return fmt.Sprintf("%s(%s.%s)", strings.ToUpper(s.fn), s.relation, s.attribute)
+}
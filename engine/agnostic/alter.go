@@ -0,0 +1,638 @@
+package agnostic
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// relationDef holds the definition and the rows of a relation being altered.
+//
+// A relation is altered by building a new relation from its altered definition,
+// which replaces the existing relation. The replacement is recorded as a drop,
+// a creation and an insert of every row, so it is rolled back and written
+// to the write-ahead log as any other change. See Transaction.alter.
+type relationDef struct {
+	name       string
+	attributes []Attribute
+	pk         []string
+	// indexes created by CREATE INDEX. Indexes of PRIMARY KEY and UNIQUE
+	// constraints are created along with the new relation.
+	indexes []indexDef
+	rows    [][]any
+
+	// checkForeignKeys is set when rows must be validated against foreign keys
+	checkForeignKeys bool
+}
+
+type indexDef struct {
+	name   string
+	attrs  []string
+	unique bool
+}
+
+// isConstraintIndex reports whether index was created for a PRIMARY KEY or UNIQUE constraint
+func isConstraintIndex(name string) bool {
+	return strings.HasPrefix(name, "pk_") || strings.HasPrefix(name, "unique_")
+}
+
+// def returns the definition of r. Relation must be locked.
+func (r *Relation) def() *relationDef {
+	d := &relationDef{
+		name:       r.name,
+		attributes: make([]Attribute, len(r.attributes)),
+	}
+	copy(d.attributes, r.attributes)
+
+	for _, idx := range r.pk {
+		d.pk = append(d.pk, r.attributes[idx].name)
+	}
+
+	for _, i := range r.indexes {
+		hi, ok := i.(*HashIndex)
+		if !ok || isConstraintIndex(hi.name) {
+			continue
+		}
+		d.indexes = append(d.indexes, indexDef{name: hi.name, attrs: append([]string(nil), hi.attrsName...), unique: hi.unique})
+	}
+
+	for e := r.rows.Front(); e != nil; e = e.Next() {
+		d.rows = append(d.rows, append([]any(nil), e.Value.(*Tuple).values...))
+	}
+
+	return d
+}
+
+// attribute returns the position of attribute name
+func (d *relationDef) attribute(name string) (int, error) {
+	for i, a := range d.attributes {
+		if a.name == name {
+			return i, nil
+		}
+	}
+	return 0, NewError(UndefinedColumn, "attribute %s of relation %s does not exist", name, d.name)
+}
+
+// isPK reports whether attribute name is part of the primary key
+func (d *relationDef) isPK(name string) bool {
+	for _, k := range d.pk {
+		if k == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkUnique returns an error if two rows hold the same non NULL value for attribute at position idx
+func (d *relationDef) checkUnique(idx int) error {
+	seen := make(map[string]struct{})
+	for _, row := range d.rows {
+		if row[idx] == nil {
+			continue
+		}
+		k := fmt.Sprintf("%v", row[idx])
+		if _, ok := seen[k]; ok {
+			return NewError(UniqueViolation, "constraint violation: %s unicity, %v is duplicated", d.attributes[idx], row[idx])
+		}
+		seen[k] = struct{}{}
+	}
+	return nil
+}
+
+// checkNotNull returns an error if a row holds NULL for attribute at position idx
+func (d *relationDef) checkNotNull(idx int) error {
+	for _, row := range d.rows {
+		if row[idx] == nil {
+			return NewError(NotNullViolation, "attribute %s of relation %s contains null values", d.attributes[idx].name, d.name)
+		}
+	}
+	return nil
+}
+
+// alter replaces relation by the relation built from its definition, once altered by f
+func (t *Transaction) alter(schema, relation string, f func(d *relationDef) error) error {
+	if err := t.aborted(); err != nil {
+		return err
+	}
+
+	s, err := t.schema(schema, relation)
+	if err != nil {
+		return t.abort(err)
+	}
+	r, err := s.Relation(relation)
+	if err != nil {
+		return t.abort(err)
+	}
+	if r.virtual != nil {
+		return t.abort(fmt.Errorf("relation %s is read-only", r))
+	}
+
+	t.lock(r)
+
+	d := r.def()
+	if err := f(d); err != nil {
+		return t.abort(err)
+	}
+
+	nr, err := NewRelation(r.schema, d.name, d.attributes, d.pk)
+	if err != nil {
+		return t.abort(err)
+	}
+	nr.limits = r.limits
+	for _, idx := range d.indexes {
+		if err := nr.createIndex(idx.name, HashIndexType, idx.attrs, idx.unique); err != nil {
+			return t.abort(err)
+		}
+	}
+	t.lock(nr)
+
+	s.Remove(r.name)
+	s.Add(nr.name, nr)
+	t.changes.PushBack(RelationChange{schema: s, current: nil, old: r})
+	t.changes.PushBack(RelationChange{schema: s, current: nr, old: nil})
+	for _, idx := range d.indexes {
+		t.changes.PushBack(IndexChange{rel: nr, name: idx.name, t: HashIndexType, attrs: idx.attrs, unique: idx.unique})
+	}
+
+	for _, values := range d.rows {
+		tuple := NewTuple(values...)
+		if d.checkForeignKeys {
+			if err := t.validateForeignKeys(s.name, nr.name, nr, tuple); err != nil {
+				return err
+			}
+		}
+		e := nr.rows.PushBack(tuple)
+		nr.size.add(tuple)
+		for _, i := range nr.indexes {
+			i.Add(e)
+		}
+		t.changes.PushBack(ValueChange{current: e, old: nil, l: nr.rows, rel: nr})
+	}
+
+	return nil
+}
+
+// checkReferences returns an error if attributes of relation r are referenced by
+// a foreign key of another relation. If no attribute is given, any reference to r is an error.
+func (t *Transaction) checkReferences(r *Relation, d *relationDef, attrs ...string) error {
+	for _, s := range t.e.schemas {
+		s.RLock()
+		relations := make([]*Relation, 0, len(s.relations))
+		for _, rel := range s.relations {
+			relations = append(relations, rel)
+		}
+		s.RUnlock()
+
+		for _, rel := range relations {
+			if rel == r {
+				continue
+			}
+			for _, fk := range uniqueRelationFKs(rel) {
+				refSchema := fk.refSchema
+				if refSchema == "" {
+					refSchema = rel.schema
+				}
+				if refSchema != r.schema || fk.refRelation != r.name {
+					continue
+				}
+				refColumns := fk.refColumns
+				if len(refColumns) == 0 {
+					refColumns = d.pk
+				}
+				if len(attrs) == 0 || intersects(refColumns, attrs) {
+					return fmt.Errorf("relation %s is referenced by foreign key %s of relation %s", r, fk.constraintName(rel.name), rel)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func intersects(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// relation returns the relation, or an error if it does not exist
+func (t *Transaction) relation(schema, relation string) (*Relation, error) {
+	s, err := t.schema(schema, relation)
+	if err != nil {
+		return nil, err
+	}
+	return s.Relation(relation)
+}
+
+// AddAttribute adds attr to relation. Existing rows are given the attribute
+// default value, or NULL if it has none.
+func (t *Transaction) AddAttribute(schema, relation string, attr Attribute) error {
+	return t.alter(schema, relation, func(d *relationDef) error {
+		if _, err := d.attribute(attr.name); err == nil {
+			return fmt.Errorf("attribute %s of relation %s already exists", attr.name, d.name)
+		}
+		if t.e.affinity {
+			attr = attr.withAffinity()
+		}
+
+		d.attributes = append(d.attributes, attr)
+		idx := len(d.attributes) - 1
+		for i := range d.rows {
+			var v any
+			switch {
+			case attr.defaultValue != nil:
+				v = attr.defaultValue()
+			case attr.autoIncrement:
+				v = reflect.ValueOf(attr.nextValue).Convert(attr.typeInstance).Interface()
+				d.attributes[idx].nextValue++
+			}
+			d.rows[i] = append(d.rows[i], v)
+		}
+
+		if attr.notNull {
+			if err := d.checkNotNull(idx); err != nil {
+				return err
+			}
+		}
+		if attr.unique {
+			if err := d.checkUnique(idx); err != nil {
+				return err
+			}
+		}
+		d.checkForeignKeys = attr.fk != nil
+		return nil
+	})
+}
+
+// DropAttribute removes attribute name from relation, along with the indexes and
+// constraints using it. The primary key is dropped if it uses the attribute.
+func (t *Transaction) DropAttribute(schema, relation, name string) error {
+	r, err := t.relation(schema, relation)
+	if err != nil {
+		return t.abort(err)
+	}
+
+	return t.alter(schema, relation, func(d *relationDef) error {
+		idx, err := d.attribute(name)
+		if err != nil {
+			return err
+		}
+		if err := t.checkReferences(r, d, name); err != nil {
+			return err
+		}
+
+		d.attributes = append(d.attributes[:idx:idx], d.attributes[idx+1:]...)
+		for i, a := range d.attributes {
+			if a.fk != nil && (contains(a.fk.localColumns, name) || a.fk.refRelation == d.name && contains(a.fk.refColumns, name)) {
+				d.attributes[i].fk = nil
+			}
+		}
+		if d.isPK(name) {
+			d.pk = nil
+		}
+
+		var indexes []indexDef
+		for _, i := range d.indexes {
+			if !contains(i.attrs, name) {
+				indexes = append(indexes, i)
+			}
+		}
+		d.indexes = indexes
+
+		for i, row := range d.rows {
+			d.rows[i] = append(row[:idx:idx], row[idx+1:]...)
+		}
+		return nil
+	})
+}
+
+func contains(l []string, s string) bool {
+	for _, x := range l {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// RenameAttribute renames attribute name of relation to newName
+func (t *Transaction) RenameAttribute(schema, relation, name, newName string) error {
+	r, err := t.relation(schema, relation)
+	if err != nil {
+		return t.abort(err)
+	}
+
+	return t.alter(schema, relation, func(d *relationDef) error {
+		idx, err := d.attribute(name)
+		if err != nil {
+			return err
+		}
+		if _, err := d.attribute(newName); err == nil {
+			return fmt.Errorf("attribute %s of relation %s already exists", newName, d.name)
+		}
+		if err := t.checkReferences(r, d, name); err != nil {
+			return err
+		}
+
+		rename := func(l []string) []string {
+			renamed := make([]string, len(l))
+			for i, s := range l {
+				if s == name {
+					s = newName
+				}
+				renamed[i] = s
+			}
+			return renamed
+		}
+
+		d.attributes[idx].name = newName
+		for i, a := range d.attributes {
+			if a.fk == nil {
+				continue
+			}
+			fk := *a.fk
+			fk.localColumns = rename(fk.localColumns)
+			if fk.refRelation == d.name {
+				fk.refColumns = rename(fk.refColumns)
+			}
+			d.attributes[i].fk = &fk
+		}
+		d.pk = rename(d.pk)
+		for i := range d.indexes {
+			d.indexes[i].attrs = rename(d.indexes[i].attrs)
+		}
+		return nil
+	})
+}
+
+// AlterAttribute replaces the definition of attribute attr.Name() of relation by attr.
+// Values are converted to the attribute type, and must satisfy its constraints.
+func (t *Transaction) AlterAttribute(schema, relation string, attr Attribute) error {
+	return t.alter(schema, relation, func(d *relationDef) error {
+		idx, err := d.attribute(attr.name)
+		if err != nil {
+			return err
+		}
+		if t.e.affinity {
+			attr = attr.withAffinity()
+		}
+
+		if attr.defaultConst != nil {
+			v, err := t.cast(attr.defaultConst, attr)
+			if err != nil {
+				return fmt.Errorf("default value of %s cannot be converted: %w", attr.name, err)
+			}
+			attr = attr.WithDefaultConst(v)
+		}
+
+		if attr.typeInstance != d.attributes[idx].typeInstance {
+			for _, row := range d.rows {
+				if row[idx] == nil {
+					continue
+				}
+				v, err := t.cast(row[idx], attr)
+				if err != nil {
+					return err
+				}
+				row[idx] = v
+			}
+		}
+		d.attributes[idx] = attr
+
+		if attr.notNull {
+			if err := d.checkNotNull(idx); err != nil {
+				return err
+			}
+		}
+		if attr.unique {
+			if err := d.checkUnique(idx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// cast converts v to the type of attribute a
+func (t *Transaction) cast(v any, a Attribute) (any, error) {
+	if c, ok := t.e.convert(v, a.typeName, a.typeInstance); ok {
+		return c, nil
+	}
+	if a.typeInstance.Kind() == reflect.String {
+		return fmt.Sprint(v), nil
+	}
+	return nil, fmt.Errorf("cannot convert '%v' (type %s) to %s (type %s)", v, reflect.TypeOf(v), a.name, a.typeName)
+}
+
+// RenameRelation renames relation to newName
+func (t *Transaction) RenameRelation(schema, relation, newName string) error {
+	r, err := t.relation(schema, relation)
+	if err != nil {
+		return t.abort(err)
+	}
+	if t.CheckRelation(r.schema, newName) {
+		return t.abort(fmt.Errorf("relation %s already exists", newName))
+	}
+
+	return t.alter(schema, relation, func(d *relationDef) error {
+		if err := t.checkReferences(r, d); err != nil {
+			return err
+		}
+
+		for i, a := range d.attributes {
+			if a.fk == nil || a.fk.refRelation != d.name {
+				continue
+			}
+			fk := *a.fk
+			fk.refRelation = newName
+			d.attributes[i].fk = &fk
+		}
+		d.name = newName
+		return nil
+	})
+}
+
+// AddForeignKey adds foreign key constraint fk to relation. Existing rows must satisfy it.
+func (t *Transaction) AddForeignKey(schema, relation string, fk ForeignKey) error {
+	return t.alter(schema, relation, func(d *relationDef) error {
+		for _, c := range fk.localColumns {
+			idx, err := d.attribute(c)
+			if err != nil {
+				return err
+			}
+			if d.attributes[idx].fk != nil {
+				return fmt.Errorf("attribute %s of relation %s already has a foreign key", c, d.name)
+			}
+			d.attributes[idx] = d.attributes[idx].WithForeignKeyStruct(fk)
+		}
+		d.checkForeignKeys = true
+		return nil
+	})
+}
+
+// AddUnique adds UNIQUE constraint name on attrs of relation. Existing rows must satisfy it.
+func (t *Transaction) AddUnique(schema, relation, name string, attrs []string) error {
+	return t.alter(schema, relation, func(d *relationDef) error {
+		if len(attrs) != 1 {
+			return fmt.Errorf("UNIQUE constraint on several attributes is not supported")
+		}
+		idx, err := d.attribute(attrs[0])
+		if err != nil {
+			return err
+		}
+		d.attributes[idx] = d.attributes[idx].WithUniqueConstraint(name)
+		return d.checkUnique(idx)
+	})
+}
+
+// CheckConstraint returns true if relation has a PRIMARY KEY, UNIQUE or FOREIGN KEY constraint name
+func (t *Transaction) CheckConstraint(schema, relation, name string) bool {
+	if err := t.aborted(); err != nil {
+		return false
+	}
+
+	r, err := t.relation(schema, relation)
+	if err != nil {
+		return false
+	}
+	if len(r.pk) > 0 && name == primaryKeyName(r.name) {
+		return true
+	}
+	for _, a := range r.attributes {
+		if a.unique && a.uniqueConstraintName(r.name) == name {
+			return true
+		}
+		if a.fk != nil && a.fk.constraintName(r.name) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DropConstraint removes PRIMARY KEY, UNIQUE or FOREIGN KEY constraint name of relation
+func (t *Transaction) DropConstraint(schema, relation, name string) error {
+	r, err := t.relation(schema, relation)
+	if err != nil {
+		return t.abort(err)
+	}
+
+	return t.alter(schema, relation, func(d *relationDef) error {
+		if len(d.pk) > 0 && name == primaryKeyName(d.name) {
+			if err := t.checkReferences(r, d, d.pk...); err != nil {
+				return err
+			}
+			d.pk = nil
+			return nil
+		}
+
+		found := false
+		for i, a := range d.attributes {
+			if a.unique && a.uniqueConstraintName(d.name) == name {
+				d.attributes[i].unique = false
+				d.attributes[i].uniqueName = ""
+				found = true
+			}
+			if a.fk != nil && a.fk.constraintName(d.name) == name {
+				d.attributes[i].fk = nil
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("constraint %s of relation %s does not exist", name, d.name)
+		}
+		return nil
+	})
+}
+
+// indexRelation returns the relation holding index. If schema is empty, index is looked up in the search path.
+func (t *Transaction) indexRelation(schema, index string) (*Relation, error) {
+	path := []string{schema}
+	if schema == "" {
+		path = t.SearchPath()
+	}
+
+	for _, name := range path {
+		s, err := t.e.schema(name)
+		if err != nil {
+			continue
+		}
+		s.RLock()
+		for _, r := range s.relations {
+			for _, i := range r.indexes {
+				if i.Name() == index {
+					s.RUnlock()
+					return r, nil
+				}
+			}
+		}
+		s.RUnlock()
+	}
+
+	return nil, fmt.Errorf("index %s does not exist", index)
+}
+
+// CheckIndex returns true if index exists
+func (t *Transaction) CheckIndex(schema, index string) bool {
+	if err := t.aborted(); err != nil {
+		return false
+	}
+
+	_, err := t.indexRelation(schema, index)
+	return err == nil
+}
+
+// DropIndex removes index. Indexes of PRIMARY KEY and UNIQUE constraints are dropped with the constraint.
+func (t *Transaction) DropIndex(schema, index string) error {
+	if err := t.aborted(); err != nil {
+		return err
+	}
+
+	r, err := t.indexRelation(schema, index)
+	if err != nil {
+		return t.abort(err)
+	}
+	if isConstraintIndex(index) {
+		return t.abort(fmt.Errorf("cannot drop index %s, drop the constraint of relation %s instead", index, r))
+	}
+
+	return t.alter(r.schema, r.name, func(d *relationDef) error {
+		for i, idx := range d.indexes {
+			if idx.name == index {
+				d.indexes = append(d.indexes[:i:i], d.indexes[i+1:]...)
+				break
+			}
+		}
+		return nil
+	})
+}
+
+// RenameIndex renames index to newName
+func (t *Transaction) RenameIndex(schema, index, newName string) error {
+	if err := t.aborted(); err != nil {
+		return err
+	}
+
+	r, err := t.indexRelation(schema, index)
+	if err != nil {
+		return t.abort(err)
+	}
+	if isConstraintIndex(index) {
+		return t.abort(fmt.Errorf("cannot rename index %s of a constraint of relation %s", index, r))
+	}
+	if _, err := t.indexRelation(r.schema, newName); err == nil {
+		return t.abort(fmt.Errorf("index %s already exists", newName))
+	}
+
+	return t.alter(r.schema, r.name, func(d *relationDef) error {
+		for i := range d.indexes {
+			if d.indexes[i].name == index {
+				d.indexes[i].name = newName
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,514 @@
+package agnostic
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Arrays are stored as Go slices of their element type, such as []int64 for INT[] attributes.
+// NULL elements and multidimensional arrays are not supported.
+
+// IsArrayType reports whether typeName is an array type, such as int[]
+func IsArrayType(typeName string) bool {
+	return strings.HasSuffix(typeName, "[]")
+}
+
+// ArrayElemType returns the element type name of array type typeName
+func ArrayElemType(typeName string) string {
+	return strings.TrimSuffix(typeName, "[]")
+}
+
+// ArrayValues returns the elements of v if v is a slice, byte slices excepted
+func ArrayValues(v any) ([]any, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	if rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+
+	values := make([]any, rv.Len())
+	for i := range values {
+		values[i] = rv.Index(i).Interface()
+	}
+	return values, true
+}
+
+// NewArray returns values as a slice of their type, or as []any if their types differ
+func NewArray(values []any) any {
+	var t reflect.Type
+	for _, v := range values {
+		if v == nil {
+			return values
+		}
+		if t != nil && reflect.TypeOf(v) != t {
+			return values
+		}
+		t = reflect.TypeOf(v)
+	}
+	if t == nil {
+		return values
+	}
+
+	a := reflect.MakeSlice(reflect.SliceOf(t), len(values), len(values))
+	for i, v := range values {
+		a.Index(i).Set(reflect.ValueOf(v))
+	}
+	return a.Interface()
+}
+
+// ParseArray parses a PostgreSQL array literal such as {1,2} or {"a b",c}
+// into a slice of elemType values
+func ParseArray(literal string, elemType string) (any, error) {
+	s := strings.TrimSpace(literal)
+	if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' {
+		return nil, fmt.Errorf("malformed array literal: %s", literal)
+	}
+	s = s[1 : len(s)-1]
+
+	var elems []string
+	var quoted []bool
+	for i := 0; i < len(s); {
+		for i < len(s) && s[i] == ' ' {
+			i++
+		}
+		if i == len(s) {
+			break
+		}
+
+		var b strings.Builder
+		switch s[i] {
+		case '{':
+			return nil, fmt.Errorf("multidimensional arrays are not supported: %s", literal)
+		case '"':
+			i++
+			for i < len(s) && s[i] != '"' {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+				i++
+			}
+			if i == len(s) {
+				return nil, fmt.Errorf("malformed array literal: %s", literal)
+			}
+			i++
+			elems, quoted = append(elems, b.String()), append(quoted, true)
+		default:
+			for i < len(s) && s[i] != ',' {
+				b.WriteByte(s[i])
+				i++
+			}
+			elems, quoted = append(elems, strings.TrimSpace(b.String())), append(quoted, false)
+		}
+
+		for i < len(s) && s[i] == ' ' {
+			i++
+		}
+		if i < len(s) {
+			if s[i] != ',' {
+				return nil, fmt.Errorf("malformed array literal: %s", literal)
+			}
+			i++
+		}
+	}
+
+	t := typeInstanceFromName(elemType)
+	a := reflect.MakeSlice(reflect.SliceOf(t), len(elems), len(elems))
+	for i, e := range elems {
+		if !quoted[i] && strings.EqualFold(e, "null") {
+			return nil, fmt.Errorf("NULL array elements are not supported: %s", literal)
+		}
+		if t.Kind() == reflect.String {
+			a.Index(i).SetString(e)
+			continue
+		}
+		v, err := ToInstance(e, elemType)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s array element %s: %w", elemType, e, err)
+		}
+		rv := reflect.ValueOf(v)
+		if !rv.Type().ConvertibleTo(t) {
+			return nil, fmt.Errorf("invalid %s array element %s", elemType, e)
+		}
+		a.Index(i).Set(rv.Convert(t))
+	}
+	return a.Interface(), nil
+}
+
+// FormatArray returns the PostgreSQL text representation of array v, such as {1,2}
+func FormatArray(v any) (string, bool) {
+	values, ok := ArrayValues(v)
+	if !ok {
+		return "", false
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, e := range values {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		switch e := e.(type) {
+		case nil:
+			b.WriteString("NULL")
+		case string:
+			b.WriteString(quoteArrayElem(e))
+		case bool:
+			if e {
+				b.WriteByte('t')
+			} else {
+				b.WriteByte('f')
+			}
+		case float64:
+			b.WriteString(strconv.FormatFloat(e, 'f', -1, 64))
+		case time.Time:
+			b.WriteString(quoteArrayElem(e.Format("2006-01-02 15:04:05.999999999Z07:00")))
+		default:
+			b.WriteString(quoteArrayElem(fmt.Sprint(e)))
+		}
+	}
+	b.WriteByte('}')
+	return b.String(), true
+}
+
+// quoteArrayElem double quotes s if needed in an array literal
+func quoteArrayElem(s string) string {
+	if s != "" && !strings.EqualFold(s, "null") && !strings.ContainsAny(s, "{},\"\\ \t\n") {
+		return s
+	}
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// convertArray converts val, a slice or an array literal, to slice type to element by element
+func (e *Engine) convertArray(val any, typeName string, to reflect.Type) (any, bool) {
+	if s, ok := val.(string); ok {
+		v, err := ParseArray(s, ArrayElemType(typeName))
+		if err != nil {
+			return val, false
+		}
+		val = v
+	}
+
+	values, ok := ArrayValues(val)
+	if !ok {
+		return val, false
+	}
+	a := reflect.MakeSlice(to, len(values), len(values))
+	for i, v := range values {
+		if v == nil {
+			return val, false
+		}
+		c, ok := e.convert(v, ArrayElemType(typeName), to.Elem())
+		if !ok {
+			return val, false
+		}
+		a.Index(i).Set(reflect.ValueOf(c))
+	}
+	return a.Interface(), true
+}
+
+// arrayEqual reports whether arrays l and r hold equal elements in the same order
+func arrayEqual(l, r any) (bool, error) {
+	if lb, ok := l.([]byte); ok {
+		rb, ok := r.([]byte)
+		return ok && bytes.Equal(lb, rb), nil
+	}
+	lv, ok := ArrayValues(l)
+	if !ok {
+		return false, fmt.Errorf("%v (%v) and %v (%v) not comparable", l, reflect.TypeOf(l), r, reflect.TypeOf(r))
+	}
+	rv, ok := ArrayValues(r)
+	if !ok || len(lv) != len(rv) {
+		return false, nil
+	}
+	for i := range lv {
+		eq, err := equal(lv[i], rv[i])
+		if err != nil || !eq {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// arrayContains reports whether values holds an element equal to v
+func arrayContains(values []any, v any) (bool, error) {
+	for _, e := range values {
+		eq, err := equal(e, v)
+		if err != nil {
+			return false, err
+		}
+		if eq {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// compare reports whether vl op vr, op being a comparison PredicateType. NULL values never match.
+func compare(op PredicateType, vl, vr any) (bool, error) {
+	if vl == nil || vr == nil {
+		return false, nil
+	}
+
+	switch op {
+	case Eq:
+		return equal(vl, vr)
+	case Neq:
+		eq, err := equal(vl, vr)
+		return !eq, err
+	case Ge:
+		return greater(vl, vr)
+	case Le:
+		return greater(vr, vl)
+	case Geq:
+		lt, err := greater(vr, vl)
+		return !lt, err
+	case Leq:
+		gt, err := greater(vl, vr)
+		return !gt, err
+	case Like:
+		return likeMatch(fmt.Sprint(vl), fmt.Sprint(vr))
+	case ILike:
+		return likeMatch(strings.ToLower(fmt.Sprint(vl)), strings.ToLower(fmt.Sprint(vr)))
+	}
+	return false, fmt.Errorf("unsupported comparison %s", operatorString(op))
+}
+
+func operatorString(op PredicateType) string {
+	switch op {
+	case Eq:
+		return "="
+	case Neq:
+		return "<>"
+	case Ge:
+		return ">"
+	case Le:
+		return "<"
+	case Geq:
+		return ">="
+	case Leq:
+		return "<="
+	case Like:
+		return "LIKE"
+	case ILike:
+		return "ILIKE"
+	case Contains:
+		return "@>"
+	case ContainedBy:
+		return "<@"
+	case Overlap:
+		return "&&"
+	case KeyExists:
+		return "?"
+	}
+	return fmt.Sprint(int(op))
+}
+
+// AnyPredicate compares a value with each element of an array,
+// as in id = ANY($1) or status <> ALL('{done,failed}')
+type AnyPredicate struct {
+	left     ValueFunctor
+	right    ValueFunctor
+	op       PredicateType
+	all      bool
+	relation string
+}
+
+// NewAnyPredicate creates a predicate true if left op is true for an element of array right
+func NewAnyPredicate(left ValueFunctor, op PredicateType, right ValueFunctor) *AnyPredicate {
+	return &AnyPredicate{left: left, right: right, op: op}
+}
+
+// NewAllPredicate creates a predicate true if left op is true for every element of array right
+func NewAllPredicate(left ValueFunctor, op PredicateType, right ValueFunctor) *AnyPredicate {
+	return &AnyPredicate{left: left, right: right, op: op, all: true}
+}
+
+// WithRelation sets the relation filtered by p when none of its operands is an attribute,
+// as in 5 = ANY($1)
+func (p *AnyPredicate) WithRelation(relation string) *AnyPredicate {
+	p.relation = relation
+	return p
+}
+
+func (p *AnyPredicate) Type() PredicateType {
+	if p.all {
+		return All
+	}
+	return Any
+}
+
+func (p AnyPredicate) String() string {
+	if p.all {
+		return fmt.Sprintf("%s %s ALL(%s)", p.left, operatorString(p.op), p.right)
+	}
+	return fmt.Sprintf("%s %s ANY(%s)", p.left, operatorString(p.op), p.right)
+}
+
+func (p *AnyPredicate) Eval(cols []string, t *Tuple) (bool, error) {
+	vl := p.left.Value(cols, t)
+	vr := p.right.Value(cols, t)
+	if vl == nil || vr == nil {
+		return false, nil
+	}
+
+	values, ok := ArrayValues(vr)
+	if !ok {
+		return false, fmt.Errorf("%s: %v is not an array", p, vr)
+	}
+	for _, v := range values {
+		ok, err := compare(p.op, vl, v)
+		if err != nil {
+			return false, err
+		}
+		if ok != p.all {
+			return ok, nil
+		}
+	}
+	return p.all, nil
+}
+
+func (p *AnyPredicate) Left() (Predicate, bool) {
+	return nil, false
+}
+
+func (p *AnyPredicate) Right() (Predicate, bool) {
+	return nil, false
+}
+
+func (p *AnyPredicate) Relation() string {
+	if p.left.Relation() != "" {
+		return p.left.Relation()
+	}
+	if p.right.Relation() != "" {
+		return p.right.Relation()
+	}
+	return p.relation
+}
+
+func (p *AnyPredicate) Attribute() []string {
+	return append(p.left.Attribute(), p.right.Attribute()...)
+}
+
+// ArrayPredicate compares two arrays with @> (contains), <@ (is contained by) or && (overlaps)
+type ArrayPredicate struct {
+	left  ValueFunctor
+	right ValueFunctor
+	t     PredicateType
+}
+
+// NewArrayPredicate creates a predicate comparing arrays left and right, t being Contains, ContainedBy or Overlap
+func NewArrayPredicate(left ValueFunctor, t PredicateType, right ValueFunctor) *ArrayPredicate {
+	return &ArrayPredicate{left: left, right: right, t: t}
+}
+
+func (p *ArrayPredicate) Type() PredicateType {
+	return p.t
+}
+
+func (p ArrayPredicate) String() string {
+	return fmt.Sprintf("%s %s %s", p.left, operatorString(p.t), p.right)
+}
+
+func (p *ArrayPredicate) Eval(cols []string, t *Tuple) (bool, error) {
+	vl := p.left.Value(cols, t)
+	vr := p.right.Value(cols, t)
+	if vl == nil || vr == nil {
+		return false, nil
+	}
+
+	l, ok := ArrayValues(vl)
+	if !ok {
+		return false, fmt.Errorf("%s: %v is not an array", p, vl)
+	}
+	r, ok := ArrayValues(vr)
+	if !ok {
+		return false, fmt.Errorf("%s: %v is not an array", p, vr)
+	}
+
+	switch p.t {
+	case Contains:
+		return containsAll(l, r)
+	case ContainedBy:
+		return containsAll(r, l)
+	case Overlap:
+		for _, v := range r {
+			ok, err := arrayContains(l, v)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+	return false, fmt.Errorf("unknown array operator %s", operatorString(p.t))
+}
+
+// containsAll reports whether every element of sub is an element of values
+func containsAll(values, sub []any) (bool, error) {
+	for _, v := range sub {
+		ok, err := arrayContains(values, v)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (p *ArrayPredicate) Left() (Predicate, bool) {
+	return nil, false
+}
+
+func (p *ArrayPredicate) Right() (Predicate, bool) {
+	return nil, false
+}
+
+func (p *ArrayPredicate) Relation() string {
+	if p.left.Relation() != "" {
+		return p.left.Relation()
+	}
+
+	return p.right.Relation()
+}
+
+func (p *ArrayPredicate) Attribute() []string {
+	return append(p.left.Attribute(), p.right.Attribute()...)
+}
+
+// ArrayLengthValueFunctor returns the number of elements of an array,
+// NULL for an empty array as PostgreSQL array_length does
+type ArrayLengthValueFunctor struct {
+	array ValueFunctor
+}
+
+// NewArrayLengthValueFunctor creates a ValueFunctor computing array_length(array, 1)
+func NewArrayLengthValueFunctor(array ValueFunctor) ValueFunctor {
+	return &ArrayLengthValueFunctor{array: array}
+}
+
+func (f *ArrayLengthValueFunctor) Value(cols []string, t *Tuple) any {
+	values, ok := ArrayValues(f.array.Value(cols, t))
+	if !ok || len(values) == 0 {
+		return nil
+	}
+	return int64(len(values))
+}
+
+func (f *ArrayLengthValueFunctor) Relation() string {
+	return f.array.Relation()
+}
+
+func (f *ArrayLengthValueFunctor) Attribute() []string {
+	return f.array.Attribute()
+}
+
+func (f ArrayLengthValueFunctor) String() string {
+	return fmt.Sprintf("array_length(%s, 1)", f.array)
+}
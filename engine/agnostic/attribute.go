@@ -7,6 +7,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/proullon/ramsql/engine/parser"
 )
 
 type Defaulter func() any
@@ -19,15 +21,31 @@ type Domain struct {
 // AKA Field
 // AKA Column
 type Attribute struct {
-	name          string
-	typeName      string
-	typeInstance  reflect.Type
-	defaultValue  Defaulter
+	name         string
+	typeName     string
+	typeInstance reflect.Type
+	defaultValue Defaulter
+	// defaultConst and defaultNow keep track of how defaultValue was built,
+	// so the attribute definition can be persisted. See wal.go
+	defaultConst  any
+	defaultNow    bool
 	domain        Domain
 	autoIncrement bool
 	nextValue     uint64
-	unique        bool
-	fk            *ForeignKey
+	// rowID attributes alias SQLite rowid, see WithRowID
+	rowID bool
+	// reuseRowID is set for rowid attributes without AUTOINCREMENT
+	reuseRowID bool
+	// hidden attributes are not returned by SELECT *, see WithHidden
+	hidden bool
+	unique bool
+	// uniqueName is the name of the UNIQUE constraint, if declared
+	uniqueName string
+	notNull    bool
+	// size is the declared length, or precision of decimal attributes
+	size  int64
+	scale int64
+	fk    *ForeignKey
 }
 
 func NewAttribute(name, typeName string) Attribute {
@@ -46,11 +64,40 @@ func (a Attribute) WithAutoIncrement() Attribute {
 	return a
 }
 
+// WithNextValue sets the next value generated by an autoincrement attribute,
+// such as the MySQL AUTO_INCREMENT=n table option does
+func (a Attribute) WithNextValue(next uint64) Attribute {
+	a.nextValue = next
+	return a
+}
+
+// WithRowID makes the attribute an alias of SQLite rowid: an autoincrement attribute
+// also generating a value when NULL is inserted, and whose generated values follow
+// the largest inserted one. Without AUTOINCREMENT, the generated value follows the
+// largest one of the relation rows, so values of deleted rows may be reused.
+func (a Attribute) WithRowID() Attribute {
+	if !a.autoIncrement {
+		a = a.WithAutoIncrement()
+		a.reuseRowID = true
+	}
+	a.rowID = true
+	return a
+}
+
+// WithHidden hides the attribute from SELECT * and relation descriptions,
+// as the implicit rowid of SQLite tables. It can still be selected by name.
+func (a Attribute) WithHidden() Attribute {
+	a.hidden = true
+	return a
+}
+
 func (a Attribute) HasAutoIncrement() bool {
 	return a.autoIncrement
 }
 
 func (a Attribute) WithDefaultConst(defaultValue any) Attribute {
+	a.defaultConst = defaultValue
+	a.defaultNow = false
 	a.defaultValue = func() any {
 		if defaultValue == nil {
 			return nil
@@ -61,11 +108,15 @@ func (a Attribute) WithDefaultConst(defaultValue any) Attribute {
 }
 
 func (a Attribute) WithDefault(defaultValue Defaulter) Attribute {
+	a.defaultConst = nil
+	a.defaultNow = false
 	a.defaultValue = defaultValue
 	return a
 }
 
 func (a Attribute) WithDefaultNow() Attribute {
+	a.defaultConst = nil
+	a.defaultNow = true
 	a.defaultValue = func() any {
 		return time.Now()
 	}
@@ -77,6 +128,53 @@ func (a Attribute) WithUnique() Attribute {
 	return a
 }
 
+// WithUniqueConstraint makes the attribute unique, with a UNIQUE constraint named name
+func (a Attribute) WithUniqueConstraint(name string) Attribute {
+	a.unique = true
+	a.uniqueName = name
+	return a
+}
+
+// WithNotNull marks the attribute as declared NOT NULL
+func (a Attribute) WithNotNull() Attribute {
+	a.notNull = true
+	return a
+}
+
+// WithNullable drops the NOT NULL declaration of the attribute
+func (a Attribute) WithNullable() Attribute {
+	a.notNull = false
+	return a
+}
+
+// WithoutDefault drops the default value of the attribute.
+// Autoincrement attributes are no longer generated.
+func (a Attribute) WithoutDefault() Attribute {
+	a.defaultValue = nil
+	a.defaultConst = nil
+	a.defaultNow = false
+	a.autoIncrement = false
+	a.rowID = false
+	a.reuseRowID = false
+	return a
+}
+
+// WithType changes the type of the attribute, dropping its declared size.
+// A constant default value is kept as is, it must be converted to the new type.
+func (a Attribute) WithType(typeName string) Attribute {
+	a.typeName = typeName
+	a.typeInstance = typeInstanceFromName(typeName)
+	a.size, a.scale = 0, 0
+	return a
+}
+
+// WithSize sets the declared size of the attribute type, such as VARCHAR(size) or DECIMAL(size, scale)
+func (a Attribute) WithSize(size, scale int64) Attribute {
+	a.size = size
+	a.scale = scale
+	return a
+}
+
 func (a Attribute) WithForeignKey(schema, relation, attribute string) Attribute {
 	a.fk = &ForeignKey{
 		refSchema:    schema,
@@ -113,6 +211,11 @@ func (a Attribute) Name() string {
 	return a.name
 }
 
+// TypeName returns the declared type of the attribute
+func (a Attribute) TypeName() string {
+	return a.typeName
+}
+
 func (a Attribute) String() string {
 	s := a.name + " (" + a.typeName
 	if a.autoIncrement {
@@ -125,9 +228,30 @@ func (a Attribute) String() string {
 	return s
 }
 
+// withAffinity returns the attribute with the storage type given by SQLite type affinity rules.
+// The default value is converted to this type when possible.
+func (a Attribute) withAffinity() Attribute {
+	a.typeInstance = affinityType(a.typeName)
+	if a.defaultConst == nil {
+		return a
+	}
+
+	v := affinityValue(a.defaultConst, a.typeInstance)
+	if strictlyAssignable(reflect.TypeOf(v), a.typeInstance) {
+		v = reflect.ValueOf(v).Convert(a.typeInstance).Interface()
+	}
+	a = a.WithDefault(func() any { return v })
+	a.defaultConst = v
+	return a
+}
+
 func typeInstanceFromName(name string) reflect.Type {
+	if IsArrayType(name) {
+		return reflect.SliceOf(typeInstanceFromName(ArrayElemType(name)))
+	}
+
 	switch strings.ToLower(name) {
-	case "serial", "bigserial", "int", "integer", "bigint":
+	case "serial", "bigserial", "int", "integer", "bigint", "tinyint", "smallint", "mediumint":
 		var v int64
 		return reflect.TypeOf(v)
 	case "bool", "boolean":
@@ -136,15 +260,37 @@ func typeInstanceFromName(name string) reflect.Type {
 	case "decimal", "float":
 		var v float64
 		return reflect.TypeOf(v)
-	case "timestamp", "timestamptz", "date":
+	case "timestamp", "timestamptz", "date", "datetime":
 		var v time.Time
 		return reflect.TypeOf(v)
+	case "json", "jsonb":
+		return jsonType
 	default:
+		if t, ok := LookupType(name); ok {
+			return t.storage
+		}
 		var v string
 		return reflect.TypeOf(v)
 	}
 }
 
+// strictlyAssignable reports whether a value of type from can be assigned to
+// an attribute of type to without changing its meaning.
+// Integers are assignable to numeric attributes, other types must match.
+func strictlyAssignable(from, to reflect.Type) bool {
+	switch from.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return to.Kind() == reflect.Int64 || to.Kind() == reflect.Float64
+	case reflect.Float32, reflect.Float64:
+		return to.Kind() == reflect.Float64
+	case reflect.Slice:
+		// byte slices, such as json.RawMessage, hold text
+		return from.Elem().Kind() == reflect.Uint8 && to.Kind() == reflect.String
+	}
+	return from.Kind() == to.Kind() && from.Kind() != reflect.Struct || from == to
+}
+
 func ToInstance(value, typeName string) (any, error) {
 	if value == "now()" || value == "current_timestamp" {
 		return time.Now(), nil
@@ -152,6 +298,9 @@ func ToInstance(value, typeName string) (any, error) {
 	if value == "null" {
 		return nil, nil
 	}
+	if IsArrayType(typeName) {
+		return ParseArray(value, ArrayElemType(typeName))
+	}
 
 	switch strings.ToLower(typeName) {
 	case "serial", "bigserial":
@@ -167,7 +316,7 @@ func ToInstance(value, typeName string) (any, error) {
 			return nil, err
 		}
 		return v, nil
-	case "int", "integer", "bigint":
+	case "int", "integer", "bigint", "tinyint", "smallint", "mediumint":
 		v, err := strconv.ParseInt(value, 10, 64)
 		if err != nil {
 			return nil, err
@@ -179,13 +328,15 @@ func ToInstance(value, typeName string) (any, error) {
 			return nil, err
 		}
 		return v, nil
-	case "timestamp", "timestamptz", "date":
+	case "timestamp", "timestamptz", "date", "datetime":
 		v, err := parseDate(value)
 		if err != nil {
 			return nil, err
 		}
 		return v, nil
-	case "json", "jsonb", "varchar":
+	case "json", "jsonb":
+		return ParseJSON(value)
+	case "varchar":
 		return value, nil
 	default: // try everyting
 		if v, err := strconv.ParseUint(value, 10, 64); err == nil {
@@ -216,6 +367,13 @@ func parseDate(data string) (time.Time, error) {
 		return t, nil
 	}
 
+	for _, layout := range parser.DatePostgresFormats {
+		t, err = time.Parse(layout, data)
+		if err == nil {
+			return t, nil
+		}
+	}
+
 	t, err = time.Parse(DateShortFormat, data)
 	if err == nil {
 		return t, nil
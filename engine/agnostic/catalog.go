@@ -0,0 +1,227 @@
+package agnostic
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// RelationInfo describes a relation, as listed by catalog views such as sqlite_master
+type RelationInfo struct {
+	Schema string
+	Name   string
+	// Virtual relations are read-only views maintained by the engine
+	Virtual bool
+
+	Columns []ColumnInfo
+	// PrimaryKey holds the name of primary key attributes
+	PrimaryKey  []string
+	Indexes     []IndexInfo
+	Constraints []ConstraintInfo
+}
+
+// ColumnInfo describes an attribute of a relation
+type ColumnInfo struct {
+	ColumnType
+	// DeclaredType is the type name given when the relation was created
+	DeclaredType  string
+	AutoIncrement bool
+	// NotNull is set for attributes declared NOT NULL
+	NotNull bool
+	// RowID is set for attributes aliasing SQLite rowid
+	RowID  bool
+	Unique bool
+	// Default is the constant default value, DefaultNow is set for DEFAULT NOW()
+	Default    any
+	HasDefault bool
+	DefaultNow bool
+	ForeignKey *ForeignKey
+}
+
+// IndexInfo describes an index of a relation
+type IndexInfo struct {
+	Name    string
+	Columns []string
+	// Primary is set for indexes created by PRIMARY KEY constraints,
+	// Unique for those and indexes created by UNIQUE constraints or CREATE UNIQUE INDEX
+	Primary bool
+	Unique  bool
+}
+
+// ConstraintInfo describes a PRIMARY KEY, UNIQUE or FOREIGN KEY constraint of a relation.
+// Constraints are named as PostgreSQL does when the name is not declared.
+type ConstraintInfo struct {
+	Name string
+	// Type is PRIMARY KEY, UNIQUE or FOREIGN KEY
+	Type    string
+	Columns []string
+
+	// RefSchema, RefRelation and RefColumns are the attributes referenced by a foreign key.
+	// RefColumns is empty when the primary key is referenced.
+	RefSchema   string
+	RefRelation string
+	RefColumns  []string
+	// OnDelete is the ON DELETE action of a foreign key, NO ACTION if not declared
+	OnDelete string
+}
+
+// Catalog returns the description of every relation, sorted by schema and name.
+func (e *Engine) Catalog() []RelationInfo {
+	var infos []RelationInfo
+
+	for _, s := range e.schemas {
+		s.RLock()
+		for _, r := range s.relations {
+			infos = append(infos, r.info())
+		}
+		s.RUnlock()
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Schema != infos[j].Schema {
+			return infos[i].Schema < infos[j].Schema
+		}
+		return infos[i].Name < infos[j].Name
+	})
+
+	return infos
+}
+
+// RelationInfo returns the description of relation
+func (t *Transaction) RelationInfo(schema, relation string) (RelationInfo, error) {
+	if err := t.aborted(); err != nil {
+		return RelationInfo{}, err
+	}
+
+	s, err := t.schema(schema, relation)
+	if err != nil {
+		return RelationInfo{}, err
+	}
+	r, err := s.Relation(relation)
+	if err != nil {
+		return RelationInfo{}, err
+	}
+
+	return r.info(), nil
+}
+
+// info describes the relation. Fields are read one by one since the relation
+// may be locked by a running transaction.
+func (r *Relation) info() RelationInfo {
+	ri := RelationInfo{
+		Schema:  r.schema,
+		Name:    r.name,
+		Virtual: r.virtual != nil,
+	}
+
+	for i := range r.attributes {
+		a := &r.attributes[i]
+		if a.hidden {
+			continue
+		}
+		c := ColumnInfo{
+			ColumnType: ColumnType{
+				Name:          a.name,
+				TypeName:      strings.ToUpper(a.typeName),
+				ScanType:      a.typeInstance,
+				Nullable:      !a.notNull && !r.isPK(i),
+				NullableKnown: true,
+			},
+			DeclaredType:  a.typeName,
+			AutoIncrement: a.autoIncrement,
+			NotNull:       a.notNull,
+			RowID:         a.rowID,
+			Unique:        a.unique,
+			Default:       a.defaultConst,
+			HasDefault:    a.defaultConst != nil,
+			DefaultNow:    a.defaultNow,
+			ForeignKey:    a.fk,
+		}
+		if a.size > 0 && a.typeInstance != nil {
+			switch a.typeInstance.Kind() {
+			case reflect.String:
+				c.Length, c.HasLength = a.size, true
+			case reflect.Float64:
+				c.Precision, c.Scale, c.HasPrecision = a.size, a.scale, true
+			}
+		}
+		ri.Columns = append(ri.Columns, c)
+	}
+
+	for _, idx := range r.pk {
+		ri.PrimaryKey = append(ri.PrimaryKey, r.attributes[idx].name)
+	}
+
+	if len(ri.PrimaryKey) > 0 {
+		ri.Constraints = append(ri.Constraints, ConstraintInfo{
+			Name:    primaryKeyName(r.name),
+			Type:    "PRIMARY KEY",
+			Columns: ri.PrimaryKey,
+		})
+	}
+	for _, a := range r.attributes {
+		if a.unique {
+			ri.Constraints = append(ri.Constraints, ConstraintInfo{
+				Name:    a.uniqueConstraintName(r.name),
+				Type:    "UNIQUE",
+				Columns: []string{a.name},
+			})
+		}
+	}
+	for _, fk := range uniqueRelationFKs(r) {
+		c := ConstraintInfo{
+			Name:        fk.constraintName(r.name),
+			Type:        "FOREIGN KEY",
+			Columns:     fk.localColumns,
+			RefSchema:   fk.refSchema,
+			RefRelation: fk.refRelation,
+			RefColumns:  fk.refColumns,
+			OnDelete:    fk.onDelete,
+		}
+		if c.RefSchema == "" {
+			c.RefSchema = r.schema
+		}
+		if c.OnDelete == "" {
+			c.OnDelete = "NO ACTION"
+		}
+		ri.Constraints = append(ri.Constraints, c)
+	}
+
+	for _, index := range r.indexes {
+		ii := IndexInfo{Name: index.Name()}
+		if hi, ok := index.(*HashIndex); ok {
+			ii.Columns = hi.attrsName
+			ii.Unique = hi.unique
+		}
+		switch {
+		case strings.HasPrefix(ii.Name, "pk_"):
+			ii.Primary, ii.Unique = true, true
+		case strings.HasPrefix(ii.Name, "unique_"):
+			ii.Unique = true
+		}
+		ri.Indexes = append(ri.Indexes, ii)
+	}
+
+	return ri
+}
+
+// primaryKeyName returns the name of the PRIMARY KEY constraint of relation
+func primaryKeyName(relation string) string {
+	return relation + "_pkey"
+}
+
+// uniqueConstraintName returns the name of the UNIQUE constraint of attribute a of relation
+func (a Attribute) uniqueConstraintName(relation string) string {
+	if a.uniqueName != "" {
+		return a.uniqueName
+	}
+	return relation + "_" + a.name + "_key"
+}
+
+// constraintName returns the name of the foreign key constraint of relation
+func (fk ForeignKey) constraintName(relation string) string {
+	if fk.name != "" {
+		return fk.name
+	}
+	return relation + "_" + strings.Join(fk.localColumns, "_") + "_fkey"
+}
@@ -8,6 +8,9 @@ type ValueChange struct {
 	current *list.Element
 	old     *list.Element
 	l       *list.List
+	rel     *Relation
+	// next is the element following a deleted one, to restore it in place on rollback
+	next *list.Element
 }
 
 type RelationChange struct {
@@ -22,25 +25,85 @@ type SchemaChange struct {
 	e       *Engine
 }
 
-func (t *Transaction) rollbackValueChange(c ValueChange) {
+// TruncateChange records a TRUNCATE on a relation.
+//
+// Truncate is not transactional, so TruncateChange is only used to write the change in the write-ahead log.
+type TruncateChange struct {
+	rel *Relation
+}
+
+// IndexChange records an index creation.
+//
+// Like TruncateChange, it is only used to write the change in the write-ahead log.
+type IndexChange struct {
+	rel    *Relation
+	name   string
+	t      IndexType
+	attrs  []string
+	unique bool
+}
+
+// rollbackValueChange reverts c. Reverted deletes and updates put a new element
+// in the list, recorded in moved so that older changes on the same row find it.
+func (t *Transaction) rollbackValueChange(c ValueChange, moved map[*list.Element]*list.Element) {
+	resolve := func(e *list.Element) *list.Element {
+		for {
+			m, ok := moved[e]
+			if !ok {
+				return e
+			}
+			e = m
+		}
+	}
 
 	// revert insert
 	if c.current != nil && c.old == nil {
-		c.l.Remove(c.current)
+		cur := resolve(c.current)
+		c.l.Remove(cur)
+		if c.rel != nil {
+			c.rel.size.remove(cur.Value.(*Tuple))
+			for _, i := range c.rel.indexes {
+				i.Remove(cur)
+			}
+		}
 	}
 
 	// revert delete
 	if c.current == nil && c.old != nil {
 		old := c.old.Value.(*Tuple)
-		c.l.InsertAfter(old, c.old.Prev())
+		var e *list.Element
+		if c.next != nil {
+			e = c.l.InsertBefore(old, resolve(c.next))
+		}
+		if e == nil {
+			e = c.l.PushBack(old)
+		}
+		moved[c.old] = e
+		if c.rel != nil {
+			c.rel.size.add(old)
+			for _, i := range c.rel.indexes {
+				i.Add(e)
+			}
+		}
 	}
 
 	// revert update
 	if c.current != nil && c.old != nil {
-		cur := c.current.Value.(*Tuple)
+		cur := resolve(c.current)
 		old := c.old.Value.(*Tuple)
-		for i := range cur.values {
-			cur.values[i] = old.values[i]
+		e := c.l.InsertAfter(old, cur)
+		if e == nil {
+			e = c.l.PushBack(old)
+		}
+		c.l.Remove(cur)
+		moved[c.old] = e
+		if c.rel != nil {
+			c.rel.size.remove(cur.Value.(*Tuple))
+			c.rel.size.add(old)
+			for _, i := range c.rel.indexes {
+				i.Remove(cur)
+				i.Add(e)
+			}
 		}
 	}
 }
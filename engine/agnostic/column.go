@@ -0,0 +1,197 @@
+package agnostic
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ColumnType describes a column returned by a query
+type ColumnType struct {
+	Name string
+	// TypeName is the database type name in upper case, for example BIGINT or TEXT
+	TypeName string
+	// ScanType is the Go type of column values
+	ScanType reflect.Type
+
+	// Nullable is only meaningful if NullableKnown is true,
+	// which is the case for columns selected from an attribute
+	Nullable      bool
+	NullableKnown bool
+
+	// Length is the declared length of variable length types, such as VARCHAR(255)
+	Length    int64
+	HasLength bool
+
+	// Precision and Scale are declared by DECIMAL(precision, scale)
+	Precision    int64
+	Scale        int64
+	HasPrecision bool
+}
+
+// ColumnNames returns the name of each column
+func ColumnNames(types []ColumnType) []string {
+	if types == nil {
+		return nil
+	}
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// ArrayOf returns the type of arrays of t elements
+func (t ColumnType) ArrayOf() ColumnType {
+	a := ColumnType{Name: t.Name, TypeName: t.TypeName + "[]"}
+	if t.ScanType != nil {
+		a.ScanType = reflect.SliceOf(t.ScanType)
+	}
+	return a
+}
+
+// NewColumnTypes returns columns named cols, with types inferred from the first non-NULL value of each column
+func NewColumnTypes(cols []string, tuples []*Tuple) []ColumnType {
+	if cols == nil {
+		return nil
+	}
+	types := make([]ColumnType, len(cols))
+	for i, c := range cols {
+		var v any
+		for _, t := range tuples {
+			if i < len(t.values) && t.values[i] != nil {
+				v = t.values[i]
+				break
+			}
+		}
+		types[i] = valueColumnType(c, v)
+	}
+	return types
+}
+
+// ColumnType returns the type of values stored in attribute
+func (a Attribute) ColumnType() ColumnType {
+	c := ColumnType{
+		Name:          a.name,
+		TypeName:      strings.ToUpper(a.typeName),
+		ScanType:      a.typeInstance,
+		Nullable:      !a.notNull,
+		NullableKnown: true,
+	}
+
+	if a.size > 0 {
+		switch a.typeInstance.Kind() {
+		case reflect.String:
+			c.Length, c.HasLength = a.size, true
+		case reflect.Float64:
+			c.Precision, c.Scale, c.HasPrecision = a.size, a.scale, true
+		}
+	}
+
+	return c
+}
+
+// valueColumnType returns a column type matching v Go type
+func valueColumnType(name string, v any) ColumnType {
+	c := ColumnType{Name: name, TypeName: "TEXT", ScanType: reflect.TypeOf("")}
+
+	switch v.(type) {
+	case int, int32, int64:
+		c.TypeName = "BIGINT"
+	case uint64:
+		c.TypeName = "BIGINT"
+	case float32, float64:
+		c.TypeName = "FLOAT"
+	case bool:
+		c.TypeName = "BOOLEAN"
+	case time.Time:
+		c.TypeName = "TIMESTAMP"
+	case []byte:
+		c.TypeName = "BYTEA"
+	case JSON:
+		c.TypeName = "JSONB"
+	case nil:
+		return c
+	default:
+		// arrays are typed from their elements, such as BIGINT[]
+		if t := reflect.TypeOf(v); t.Kind() == reflect.Slice {
+			c.TypeName = valueColumnType(name, reflect.Zero(t.Elem()).Interface()).TypeName + "[]"
+		}
+	}
+	c.ScanType = reflect.TypeOf(v)
+
+	return c
+}
+
+// selectorColumnTypes returns types of the columns returned by s, values are used for columns not selected from an attribute
+func selectorColumnTypes(s Selector, relations map[string]*Relation, out []*Tuple) []ColumnType {
+	cols := s.Attribute()
+	types := make([]ColumnType, len(cols))
+
+	r := relations[s.Relation()]
+	if r == nil {
+		for _, rel := range relations {
+			if rel.name == s.Relation() {
+				r = rel
+				break
+			}
+		}
+	}
+
+	for i, c := range cols {
+		switch s := s.(type) {
+		case *CountSelector:
+			types[i] = ColumnType{Name: c, TypeName: "BIGINT", ScanType: reflect.TypeOf(int64(0)), NullableKnown: true}
+			continue
+		case *ConstSelector:
+			types[i] = valueColumnType(c, s.value)
+			continue
+		case *AggregateSelector:
+			if s.fn == "jsonb_agg" {
+				types[i] = ColumnType{Name: c, TypeName: "JSONB", ScanType: jsonType}
+				continue
+			}
+			// array_agg returns an array of attribute values
+			if r != nil && s.fn == "array_agg" {
+				if idx, ok := r.attrIndex[strings.ToLower(s.attribute)]; ok {
+					types[i] = r.attributes[idx].ColumnType().ArrayOf()
+					types[i].Name = c
+					continue
+				}
+			}
+		}
+
+		name := c
+		if idx := strings.LastIndex(c, "."); idx >= 0 {
+			name = c[idx+1:]
+		}
+		// expressions are typed from their values, unless they only return an attribute
+		if e, ok := s.(*ExprSelector); ok {
+			name = ""
+			if f, ok := e.f.(*AttributeValueFunctor); ok {
+				name = f.aname
+			}
+		}
+		if r != nil {
+			if idx, ok := r.attrIndex[strings.ToLower(name)]; ok {
+				types[i] = r.attributes[idx].ColumnType()
+				if r.isPK(idx) {
+					types[i].Nullable = false
+				}
+				types[i].Name = c
+				continue
+			}
+		}
+
+		var v any
+		for _, t := range out {
+			if i < len(t.values) && t.values[i] != nil {
+				v = t.values[i]
+				break
+			}
+		}
+		types[i] = valueColumnType(c, v)
+	}
+
+	return types
+}
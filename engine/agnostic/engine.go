@@ -2,7 +2,11 @@ package agnostic
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 	"sync"
+
+	"github.com/proullon/ramsql/engine/log"
 )
 
 const (
@@ -16,6 +20,21 @@ type Engine struct {
 	// This is used by CURRENT_SCHEMA() to return the first schema in the search path
 	searchPath []string
 
+	// wal is the write-ahead log of committed changes, nil if the engine is not persisted
+	wal *wal
+
+	limits Limits
+
+	// strict disables implicit conversions between incompatible types on write
+	strict bool
+	// affinity enables SQLite type affinity, see SetAffinity
+	affinity bool
+	// integerBooleans accepts integers 0 and 1 for booleans, see SetIntegerBooleans
+	integerBooleans bool
+
+	// catalogName is the database name reported by information_schema
+	catalogName string
+
 	sync.Mutex
 }
 
@@ -29,23 +48,34 @@ func NewEngine() *Engine {
 	// initialize search_path with default schema
 	e.searchPath = []string{DefaultSchema}
 
-	// create information_schema with a 'tables' relation used by clients (e.g. GORM)
-	info := NewSchema("information_schema")
-	// minimal columns used by GORM queries: table_schema, table_name, table_type
-	attrs := []Attribute{
-		NewAttribute("table_schema", "varchar"),
-		NewAttribute("table_name", "varchar"),
-		NewAttribute("table_type", "varchar"),
-	}
-	// create relation (no primary key)
-	if r, err := NewRelation("information_schema", "tables", attrs, nil); err == nil {
-		info.Add("tables", r)
+	// pg_catalog and information_schema relations, used by clients such as GORM
+	if err := e.createSystemRelations(); err != nil {
+		log.Warn("could not create system relations: %s", err)
 	}
-	e.schemas["information_schema"] = info
 
 	return e
 }
 
+// CreateVirtualRelation creates a read-only relation which rows are provided by the rows function.
+//
+// rows is called each time a transaction accesses the relation. Schema is created if needed.
+func (e *Engine) CreateVirtualRelation(schema, relation string, attributes []Attribute, rows func() [][]any) error {
+	e.Lock()
+	defer e.Unlock()
+
+	if _, ok := e.schemas[schema]; !ok {
+		e.schemas[schema] = NewSchema(schema)
+	}
+
+	_, r, err := e.createRelation(schema, relation, attributes, nil)
+	if err != nil {
+		return err
+	}
+	r.virtual = rows
+
+	return nil
+}
+
 func (e *Engine) Begin() (*Transaction, error) {
 	t, err := NewTransaction(e)
 	return t, err
@@ -58,6 +88,12 @@ func (e *Engine) createRelation(schema, relation string, attributes []Attribute,
 		return nil, nil, err
 	}
 
+	if e.affinity {
+		for i := range attributes {
+			attributes[i] = attributes[i].withAffinity()
+		}
+	}
+
 	r, err := NewRelation(schema, relation, attributes, pk)
 	if err != nil {
 		return nil, nil, err
@@ -85,10 +121,14 @@ func (e *Engine) dropRelation(schema, relation string) (*Schema, *Relation, erro
 
 func (e *Engine) schema(name string) (*Schema, error) {
 	if name == "" {
-		name = DefaultSchema
+		name = e.CurrentSchema()
 	}
 
 	s, ok := e.schemas[name]
+	if !ok {
+		// unquoted identifiers such as INFORMATION_SCHEMA are case insensitive
+		s, ok = e.schemas[strings.ToLower(name)]
+	}
 	if !ok {
 		return nil, fmt.Errorf("schema '%s' does not exist", name)
 	}
@@ -117,6 +157,127 @@ func (e *Engine) dropSchema(name string) (*Schema, error) {
 	return s, nil
 }
 
+// SetDefaultSchema sets the schema used when a statement does not specify one.
+// The schema is created if needed.
+func (e *Engine) SetDefaultSchema(name string) {
+	e.Lock()
+	defer e.Unlock()
+
+	if _, ok := e.schemas[name]; !ok {
+		e.schemas[name] = NewSchema(name)
+	}
+	e.searchPath = []string{name}
+}
+
+// SetStrict enables or disables strict typing. When enabled, values are only
+// assigned to attributes of a compatible type, for example an integer is not
+// converted to text.
+func (e *Engine) SetStrict(strict bool) {
+	e.Lock()
+	defer e.Unlock()
+
+	e.strict = strict
+}
+
+// SetAffinity enables or disables SQLite type affinity. When enabled, the type
+// of attributes created afterwards is derived from their declared type name as
+// SQLite does, and values are converted to it only when no information is lost.
+// Other values are stored as is, for example text in an INTEGER attribute.
+func (e *Engine) SetAffinity(affinity bool) {
+	e.Lock()
+	defer e.Unlock()
+
+	e.affinity = affinity
+}
+
+// SetIntegerBooleans enables or disables writing integers 0 and 1 to boolean
+// attributes, as MySQL does with TINYINT(1) columns.
+func (e *Engine) SetIntegerBooleans(enabled bool) {
+	e.Lock()
+	defer e.Unlock()
+
+	e.integerBooleans = enabled
+}
+
+// Assignable reports whether a value of type from can be written to an
+// attribute of type to, following strict typing if enabled.
+func (e *Engine) Assignable(from, to reflect.Type) bool {
+	// documents are written as text
+	if to == jsonType && (from.Kind() == reflect.String || from.Kind() == reflect.Slice && from.Elem().Kind() == reflect.Uint8) {
+		return true
+	}
+	// integers other than 0 and 1 are rejected when converted
+	if e.integerBooleans && to.Kind() == reflect.Bool {
+		switch from.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return true
+		}
+	}
+	return from.ConvertibleTo(to) && (!e.strict || strictlyAssignable(from, to))
+}
+
+// FromText parses val as a literal of type typeName if val is a string which
+// cannot be assigned to type to, as PostgreSQL does with text parameters.
+// driver.Valuer types such as decimals are bound as text.
+//
+// val is returned unchanged if it cannot be parsed, or if strict typing is enabled.
+func (e *Engine) FromText(val any, typeName string, to reflect.Type) any {
+	s, ok := val.(string)
+	if !ok || e.strict || to.Kind() == reflect.String {
+		return val
+	}
+	v, err := ToInstance(s, typeName)
+	if err != nil || v == nil {
+		return val
+	}
+	return v
+}
+
+// convert returns val converted to type to, for an attribute of type typeName.
+// ok is false if val cannot be assigned to such an attribute.
+func (e *Engine) convert(val any, typeName string, to reflect.Type) (v any, ok bool) {
+	if e.affinity {
+		val = affinityValue(val, to)
+		if strictlyAssignable(reflect.TypeOf(val), to) {
+			return reflect.ValueOf(val).Convert(to).Interface(), true
+		}
+		return val, true
+	}
+
+	if IsArrayType(typeName) {
+		return e.convertArray(val, typeName, to)
+	}
+	if e.integerBooleans && to.Kind() == reflect.Bool {
+		if b, ok := integerBool(val); ok {
+			return b, true
+		}
+		if v := reflect.ValueOf(val); v.CanInt() || v.CanUint() {
+			return val, false
+		}
+	}
+	if to == jsonType {
+		return convertJSON(val, typeName)
+	}
+
+	val = e.FromText(val, typeName, to)
+	if !e.Assignable(reflect.TypeOf(val), to) {
+		return val, false
+	}
+	return reflect.ValueOf(val).Convert(to).Interface(), true
+}
+
+// assignError returns the error of writing val to attribute attr of relation,
+// such as the syntax error of an invalid document.
+func assignError(val any, relation string, attr Attribute) error {
+	if attr.typeInstance == jsonType {
+		if _, err := ToJSON(val); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("cannot assign '%v' (type %s) to %s.%s (type %s)", val, reflect.TypeOf(val), relation, attr.name, attr.typeInstance)
+}
+
 // CurrentSchema returns the first schema in the search path
 // This implements the CURRENT_SCHEMA() function behavior
 func (e *Engine) CurrentSchema() string {
@@ -0,0 +1,49 @@
+package agnostic
+
+import (
+	"fmt"
+)
+
+// SQLSTATE codes returned by the engine
+const (
+	InvalidParameterValue = "22023"
+	NotNullViolation      = "23502"
+	ForeignKeyViolation   = "23503"
+	UniqueViolation       = "23505"
+	SerializationFailure  = "40001"
+	UndefinedColumn       = "42703"
+	UndefinedObject       = "42704"
+	UndefinedTable        = "42P01"
+	DiskFull              = "53100"
+	QueryCanceled         = "57014"
+)
+
+// Error is an error carrying a SQLSTATE code, so clients can handle it like a PostgreSQL error.
+type Error struct {
+	Code    string
+	Message string
+}
+
+// NewError returns an Error with given SQLSTATE code.
+func NewError(code string, format string, args ...any) *Error {
+	return &Error{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// Error returns the message of the error, the code is given by SQLState.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// SQLState returns the SQLSTATE code of the error.
+func (e *Error) SQLState() string {
+	return e.Code
+}
+
+// Is reports whether target is an Error with the same SQLSTATE code.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
@@ -0,0 +1,189 @@
+package agnostic
+
+import (
+	"container/list"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ArithmeticValueFunctor returns left op right, op being one of + - * /
+//
+// Integer operands give an integer, other numeric operands a float.
+// It returns nil if an operand is nil or not numeric, or on division by zero.
+type ArithmeticValueFunctor struct {
+	op    string
+	left  ValueFunctor
+	right ValueFunctor
+}
+
+// NewArithmeticValueFunctor creates a ValueFunctor computing left op right
+func NewArithmeticValueFunctor(op string, left, right ValueFunctor) ValueFunctor {
+	return &ArithmeticValueFunctor{
+		op:    op,
+		left:  left,
+		right: right,
+	}
+}
+
+func (f *ArithmeticValueFunctor) Value(cols []string, t *Tuple) any {
+	l := reflect.ValueOf(f.left.Value(cols, t))
+	r := reflect.ValueOf(f.right.Value(cols, t))
+
+	if l.CanInt() && r.CanInt() {
+		a, b := l.Int(), r.Int()
+		switch f.op {
+		case "+":
+			return a + b
+		case "-":
+			return a - b
+		case "*":
+			return a * b
+		case "/":
+			if b == 0 {
+				return nil
+			}
+			return a / b
+		}
+		return nil
+	}
+
+	a, ok := numericValue(l)
+	if !ok {
+		return nil
+	}
+	b, ok := numericValue(r)
+	if !ok {
+		return nil
+	}
+	switch f.op {
+	case "+":
+		return a + b
+	case "-":
+		return a - b
+	case "*":
+		return a * b
+	case "/":
+		if b == 0 {
+			return nil
+		}
+		return a / b
+	}
+	return nil
+}
+
+func (f *ArithmeticValueFunctor) Relation() string {
+	if f.left.Relation() != "" {
+		return f.left.Relation()
+	}
+	return f.right.Relation()
+}
+
+func (f *ArithmeticValueFunctor) Attribute() []string {
+	return append(f.left.Attribute(), f.right.Attribute()...)
+}
+
+func (f ArithmeticValueFunctor) String() string {
+	return fmt.Sprintf("%s %s %s", f.left, f.op, f.right)
+}
+
+// CoalesceValueFunctor returns the first non NULL value of its arguments
+type CoalesceValueFunctor struct {
+	args []ValueFunctor
+}
+
+// NewCoalesceValueFunctor creates a ValueFunctor computing COALESCE(args...)
+func NewCoalesceValueFunctor(args ...ValueFunctor) ValueFunctor {
+	return &CoalesceValueFunctor{
+		args: args,
+	}
+}
+
+func (f *CoalesceValueFunctor) Value(cols []string, t *Tuple) any {
+	for _, a := range f.args {
+		if v := a.Value(cols, t); v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+func (f *CoalesceValueFunctor) Relation() string {
+	for _, a := range f.args {
+		if r := a.Relation(); r != "" {
+			return r
+		}
+	}
+	return ""
+}
+
+func (f *CoalesceValueFunctor) Attribute() []string {
+	var attrs []string
+	for _, a := range f.args {
+		attrs = append(attrs, a.Attribute()...)
+	}
+	return attrs
+}
+
+func (f CoalesceValueFunctor) String() string {
+	args := make([]string, len(f.args))
+	for i, a := range f.args {
+		args[i] = fmt.Sprint(a)
+	}
+	return fmt.Sprintf("COALESCE(%s)", strings.Join(args, ", "))
+}
+
+func numericValue(v reflect.Value) (float64, bool) {
+	switch {
+	case v.CanInt():
+		return float64(v.Int()), true
+	case v.CanUint():
+		return float64(v.Uint()), true
+	case v.CanFloat():
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// ExprSelector returns the value of a ValueFunctor for each row, under given column name
+type ExprSelector struct {
+	relation string
+	name     string
+	f        ValueFunctor
+}
+
+// NewExprSelector creates a Selector returning f values in column name
+func NewExprSelector(relation, name string, f ValueFunctor) *ExprSelector {
+	return &ExprSelector{
+		relation: relation,
+		name:     name,
+		f:        f,
+	}
+}
+
+func (s *ExprSelector) Attribute() []string {
+	return []string{s.name}
+}
+
+func (s *ExprSelector) Relation() string {
+	return s.relation
+}
+
+func (s *ExprSelector) Alias() string {
+	return ""
+}
+
+func (s *ExprSelector) Select(cols []string, in []*list.Element) (out []*Tuple, err error) {
+	for _, e := range in {
+		t, ok := e.Value.(*Tuple)
+		if !ok || t == nil {
+			return nil, fmt.Errorf("provided tuple is nil")
+		}
+		out = append(out, NewTuple(s.f.Value(cols, t)))
+	}
+	return
+}
+
+func (s ExprSelector) String() string {
+	return fmt.Sprintf("%s AS %s", s.f, s.name)
+}
@@ -0,0 +1,423 @@
+package agnostic
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// generateRetries is the number of attempts to build a row satisfying
+// primary key and unique constraints before giving up.
+const generateRetries = 100
+
+var (
+	generatedFirstNames = []string{"Alice", "Bob", "Chloe", "David", "Emma", "Farid", "Grace", "Hugo", "Ines", "Jules", "Kenji", "Lea", "Malik", "Nora", "Oscar", "Priya"}
+	generatedLastNames  = []string{"Martin", "Smith", "Garcia", "Nguyen", "Muller", "Rossi", "Kowalski", "Dubois", "Silva", "Tanaka", "Okafor", "Jensen"}
+	generatedWords      = []string{"alpha", "bravo", "delta", "echo", "falcon", "harbor", "ivory", "jade", "lotus", "maple", "nova", "orbit", "pixel", "quartz", "river", "summit"}
+	generatedBaseDate   = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// generator builds synthetic rows for a relation
+type generator struct {
+	t      *Transaction
+	r      *Relation
+	rand   *rand.Rand
+	schema string
+
+	// parents holds referenced column values of each foreign key
+	fks     []ForeignKey
+	parents [][][]any
+
+	// keys holds already used values of each unique attribute and of the primary key
+	keys    [][]int
+	used    []map[string]struct{}
+	counter int64
+}
+
+// Generate inserts n synthetic rows in relation.
+//
+// Values are generated according to attributes type and name, using seed as
+// random source so the same seed produces the same rows. Autoincrement and
+// default values are left to the engine, foreign keys reference existing
+// parent rows and unique attributes never collide.
+func (t *Transaction) Generate(schema, relation string, n int, seed int64) (int64, error) {
+	if err := t.aborted(); err != nil {
+		return 0, err
+	}
+
+	s, err := t.schema(schema, relation)
+	if err != nil {
+		return 0, t.abort(err)
+	}
+	r, err := s.Relation(relation)
+	if err != nil {
+		return 0, t.abort(err)
+	}
+	if r.virtual != nil {
+		return 0, t.abort(fmt.Errorf("relation %s is read-only", r))
+	}
+
+	t.lock(r)
+
+	g := &generator{
+		t:      t,
+		r:      r,
+		rand:   rand.New(rand.NewSource(seed)),
+		schema: schema,
+	}
+	if err := g.init(); err != nil {
+		return 0, t.abort(err)
+	}
+	if err := g.checkDomains(n); err != nil {
+		return 0, t.abort(err)
+	}
+
+	var count int64
+	for i := 0; i < n; i++ {
+		values, err := g.row()
+		if err != nil {
+			return count, t.abort(err)
+		}
+		if _, err := t.Insert(schema, relation, values); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// init loads foreign key parent values and existing unique values
+func (g *generator) init() error {
+	r := g.r
+
+	for _, fk := range uniqueRelationFKs(r) {
+		values, err := g.parentValues(fk)
+		if err != nil {
+			return err
+		}
+		// self referencing foreign keys are set to NULL until the relation has rows
+		if len(values) == 0 && (fk.refRelation != r.name || fk.refSchema != "" && fk.refSchema != r.schema) {
+			return fmt.Errorf("cannot generate rows for %s: referenced relation %s is empty", r.name, fk.refRelation)
+		}
+		g.fks = append(g.fks, fk)
+		g.parents = append(g.parents, values)
+	}
+
+	// autoincrement attributes are unique by construction
+	if len(r.pk) > 0 && !g.autoIncrement(r.pk) {
+		g.keys = append(g.keys, r.pk)
+	}
+	for i, a := range r.attributes {
+		if a.unique && !a.autoIncrement {
+			g.keys = append(g.keys, []int{i})
+		}
+	}
+
+	g.used = make([]map[string]struct{}, len(g.keys))
+	for i := range g.used {
+		g.used[i] = make(map[string]struct{})
+	}
+	for e := r.rows.Front(); e != nil; e = e.Next() {
+		values := e.Value.(*Tuple).values
+		for i, key := range g.keys {
+			if k, ok := keyOf(key, values); ok {
+				g.used[i][k] = struct{}{}
+			}
+		}
+		for _, v := range values {
+			if n, ok := v.(int64); ok && n > g.counter {
+				g.counter = n
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkDomains returns an error if a unique attribute or the primary key cannot hold n more
+// distinct values, such as a unique boolean or a unique foreign key with too few parent rows.
+func (g *generator) checkDomains(n int) error {
+	for i, key := range g.keys {
+		size, bounded := g.domain(key)
+		if !bounded || len(g.used[i])+n <= size {
+			continue
+		}
+		var names []string
+		for _, idx := range key {
+			names = append(names, g.r.attributes[idx].name)
+		}
+		return fmt.Errorf("cannot generate %d rows for %s: %s must be unique but has only %d distinct values, %d already used",
+			n, g.r.name, strings.Join(names, ", "), size, len(g.used[i]))
+	}
+	return nil
+}
+
+// domain returns the number of distinct values generated for key, or false if it is not bounded.
+// Booleans have 2 values and foreign key columns take the values of parent rows, other values
+// embed the row counter.
+func (g *generator) domain(key []int) (int, bool) {
+	size := 1
+	fks := make(map[int]bool)
+	for _, idx := range key {
+		a := g.r.attributes[idx]
+
+		fk := -1
+		for i := range g.fks {
+			for _, c := range g.fks[i].localColumns {
+				if c == a.name {
+					fk = i
+				}
+			}
+		}
+		switch {
+		case fk >= 0 && len(g.parents[fk]) > 0:
+			// columns of a foreign key take values of the same parent row
+			if !fks[fk] {
+				fks[fk] = true
+				size *= len(g.parents[fk])
+			}
+		case fk < 0 && a.typeInstance.Kind() == reflect.Bool:
+			size *= 2
+		default:
+			return 0, false
+		}
+	}
+	return size, true
+}
+
+// parentValues returns referenced columns values of all rows of fk parent relation
+func (g *generator) parentValues(fk ForeignKey) ([][]any, error) {
+	refSchema := fk.refSchema
+	if refSchema == "" {
+		refSchema = g.schema
+	}
+	ps, err := g.t.e.schema(refSchema)
+	if err != nil {
+		return nil, err
+	}
+	pr, err := ps.Relation(fk.refRelation)
+	if err != nil {
+		return nil, err
+	}
+	g.t.lock(pr)
+
+	var idx []int
+	if len(fk.refColumns) == 0 {
+		idx = pr.pk
+	} else {
+		for _, c := range fk.refColumns {
+			i, ok := pr.attrIndex[c]
+			if !ok {
+				return nil, fmt.Errorf("attribute %s not found in relation %s", c, pr.name)
+			}
+			idx = append(idx, i)
+		}
+	}
+	if len(idx) != len(fk.localColumns) {
+		return nil, fmt.Errorf("foreign key on %s has %d columns but references %d columns on %s.%s", g.r.name, len(fk.localColumns), len(idx), refSchema, pr.name)
+	}
+
+	var values [][]any
+	for e := pr.rows.Front(); e != nil; e = e.Next() {
+		t := e.Value.(*Tuple)
+		v := make([]any, len(idx))
+		for i, j := range idx {
+			v[i] = t.values[j]
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// row builds the values of a new row, retrying until unique constraints are satisfied
+func (g *generator) row() (map[string]any, error) {
+	for try := 0; try < generateRetries; try++ {
+		values := g.candidate()
+
+		full := make([]any, len(g.r.attributes))
+		for i, a := range g.r.attributes {
+			full[i] = values[a.name]
+		}
+
+		keys := make([]string, len(g.keys))
+		ok := true
+		for i, key := range g.keys {
+			k, notNull := keyOf(key, full)
+			if !notNull {
+				continue
+			}
+			if _, exists := g.used[i][k]; exists {
+				ok = false
+				break
+			}
+			keys[i] = k
+		}
+		if !ok {
+			continue
+		}
+
+		for i, k := range keys {
+			if k != "" {
+				g.used[i][k] = struct{}{}
+			}
+		}
+		return values, nil
+	}
+
+	return nil, fmt.Errorf("cannot generate unique values for %s", g.r.name)
+}
+
+func (g *generator) candidate() map[string]any {
+	r := g.r
+	values := make(map[string]any)
+
+	for i, fk := range g.fks {
+		parents := g.parents[i]
+		var v []any
+		if len(parents) > 0 {
+			v = parents[g.rand.Intn(len(parents))]
+		}
+		for j, c := range fk.localColumns {
+			if v == nil {
+				values[c] = nil
+				continue
+			}
+			values[c] = v[j]
+		}
+	}
+
+	g.counter++
+	for i, a := range r.attributes {
+		if _, ok := values[a.name]; ok {
+			continue
+		}
+		if a.autoIncrement {
+			continue
+		}
+		if a.defaultValue != nil && !a.unique && !g.r.isPK(i) {
+			continue
+		}
+		values[a.name] = g.value(a, a.unique || g.r.isPK(i))
+	}
+
+	return values
+}
+
+func (g *generator) autoIncrement(idx []int) bool {
+	for _, i := range idx {
+		if g.r.attributes[i].autoIncrement {
+			return true
+		}
+	}
+	return false
+}
+
+// value returns a random value for attribute a. Unique values embed the row counter.
+func (g *generator) value(a Attribute, unique bool) any {
+	name := strings.ToLower(a.name)
+	typeName := strings.ToLower(a.typeName)
+	rnd := g.rand
+
+	switch a.typeInstance.Kind() {
+	case reflect.Slice:
+		if IsArrayType(a.typeName) {
+			return g.array(a, unique)
+		}
+	case reflect.Int64:
+		if unique {
+			return g.counter
+		}
+		switch {
+		case strings.Contains(name, "age"):
+			return int64(18 + rnd.Intn(72))
+		case strings.Contains(name, "year"):
+			return int64(1970 + rnd.Intn(56))
+		}
+		return int64(rnd.Intn(1000))
+	case reflect.Float64:
+		if unique {
+			return float64(g.counter)
+		}
+		return float64(rnd.Intn(100000)) / 100
+	case reflect.Bool:
+		return rnd.Intn(2) == 0
+	}
+
+	if a.typeInstance == reflect.TypeOf(generatedBaseDate) {
+		if unique {
+			return generatedBaseDate.Add(time.Duration(g.counter) * time.Second)
+		}
+		return generatedBaseDate.Add(time.Duration(rnd.Int63n(int64(5*365*24*time.Hour/time.Second))) * time.Second)
+	}
+
+	first := generatedFirstNames[rnd.Intn(len(generatedFirstNames))]
+	last := generatedLastNames[rnd.Intn(len(generatedLastNames))]
+	word := generatedWords[rnd.Intn(len(generatedWords))]
+	suffix := ""
+	if unique {
+		suffix = fmt.Sprintf("%d", g.counter)
+	}
+
+	switch {
+	case typeName == "uuid" || name == "uuid":
+		b := make([]byte, 16)
+		rnd.Read(b)
+		b[6] = b[6]&0x0f | 0x40
+		b[8] = b[8]&0x3f | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	case typeName == "json" || typeName == "jsonb":
+		return fmt.Sprintf(`{"id": %d, "tag": "%s"}`, g.counter, word)
+	case strings.Contains(name, "email"):
+		return fmt.Sprintf("%s.%s%s@example.com", strings.ToLower(first), strings.ToLower(last), suffix)
+	case strings.Contains(name, "first"):
+		return first + suffix
+	case strings.Contains(name, "last"):
+		return last + suffix
+	case strings.Contains(name, "user") || strings.Contains(name, "login"):
+		return strings.ToLower(first) + "_" + word + suffix
+	case strings.Contains(name, "name"):
+		return first + " " + last + suffix
+	case strings.Contains(name, "phone"):
+		return fmt.Sprintf("+1-555-%04d", rnd.Intn(10000)) + suffix
+	case strings.Contains(name, "url"):
+		return fmt.Sprintf("https://example.com/%s%s", word, suffix)
+	case strings.Contains(name, "city"):
+		return strings.ToUpper(word[:1]) + word[1:] + "ville" + suffix
+	}
+
+	sb := strings.Builder{}
+	for i, l := 0, 8+rnd.Intn(8); i < l; i++ {
+		sb.WriteByte(charset[rnd.Intn(len(charset))])
+	}
+	return sb.String() + suffix
+}
+
+// array returns 1 to 3 random elements for array attribute a.
+// The first element of unique values embeds the row counter.
+func (g *generator) array(a Attribute, unique bool) any {
+	elem := NewAttribute(a.name, ArrayElemType(a.typeName))
+	values := reflect.MakeSlice(a.typeInstance, 1+g.rand.Intn(3), 3)
+	for i := 0; i < values.Len(); i++ {
+		v := reflect.ValueOf(g.value(elem, unique && i == 0))
+		if !v.Type().ConvertibleTo(values.Type().Elem()) {
+			return nil
+		}
+		values.Index(i).Set(v.Convert(values.Type().Elem()))
+	}
+	return values.Interface()
+}
+
+// keyOf returns the key of values at idx, or false if one of them is NULL as NULL values never collide
+func keyOf(idx []int, values []any) (string, bool) {
+	var sb strings.Builder
+	for _, i := range idx {
+		if values[i] == nil {
+			return "", false
+		}
+		fmt.Fprintf(&sb, "%v\x00", values[i])
+	}
+	return sb.String(), true
+}
@@ -31,6 +31,8 @@ type HashIndex struct {
 	attrs     []int
 	attrsName []string
 	m         map[uint64][]uintptr
+	// unique indexes reject a row holding the same non NULL values as an existing row
+	unique bool
 
 	maphash.Hash
 }
@@ -134,6 +136,33 @@ func (h *HashIndex) GetAll(values []any) ([]*list.Element, error) {
 	return result, nil
 }
 
+// values returns the values of indexed attributes of t
+func (h *HashIndex) values(t *Tuple) []any {
+	vals := make([]any, len(h.attrs))
+	for i, idx := range h.attrs {
+		vals[i] = t.values[idx]
+	}
+	return vals
+}
+
+// conflicts reports whether h is unique and an existing row holds values.
+// Values containing NULL never conflict.
+func (h *HashIndex) conflicts(values []any) (bool, error) {
+	if !h.unique {
+		return false, nil
+	}
+	for _, v := range values {
+		if v == nil {
+			return false, nil
+		}
+	}
+	e, err := h.Get(values)
+	if err != nil {
+		return false, err
+	}
+	return e != nil, nil
+}
+
 func (h *HashIndex) Truncate() {
 	h.m = make(map[uint64][]uintptr)
 }
@@ -151,6 +180,13 @@ func (h *HashIndex) CanSourceWith(p Predicate) (bool, int64) {
 		return false, 0
 	}
 
+	// expressions such as payload->>'id' = 'x' cannot be looked up in the index
+	if eq, ok := p.(*EqPredicate); ok {
+		if _, ok := eq.left.(*AttributeValueFunctor); !ok {
+			return false, 0
+		}
+	}
+
 	var found bool
 	for _, l := range h.attrsName {
 		found = false
@@ -0,0 +1,279 @@
+package agnostic
+
+import (
+	"container/list"
+	"fmt"
+)
+
+// Iterator produces the rows of a node one at a time
+type Iterator interface {
+	// Columns returns the names of produced columns
+	Columns() []string
+	// Next returns the next row, or nil once all rows have been produced
+	Next() (*list.Element, error)
+}
+
+// Streamer is implemented by nodes able to produce rows lazily.
+//
+// Nodes needing all their input before producing the first row, like
+// OrderBySorter, GroupBySorter or joins, don't implement Streamer: Iter
+// executes them and iterates over the result.
+type Streamer interface {
+	Iter() (Iterator, error)
+}
+
+// Iter returns an iterator over rows produced by n
+func Iter(n Node) (Iterator, error) {
+	if s, ok := n.(Streamer); ok {
+		return s.Iter()
+	}
+
+	cols, res, err := n.Exec()
+	if err != nil {
+		return nil, err
+	}
+	return &sliceIterator{cols: cols, res: res}, nil
+}
+
+// drain consumes it and returns all rows, used by Exec of streaming nodes
+func drain(it Iterator) ([]string, []*list.Element, error) {
+	var res []*list.Element
+
+	for {
+		e, err := it.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+		if e == nil {
+			break
+		}
+		res = append(res, e)
+	}
+
+	return it.Columns(), res, nil
+}
+
+// sliceIterator iterates over an already computed result
+type sliceIterator struct {
+	cols []string
+	res  []*list.Element
+	pos  int
+}
+
+func (it *sliceIterator) Columns() []string {
+	return it.cols
+}
+
+func (it *sliceIterator) Next() (*list.Element, error) {
+	if it.pos >= len(it.res) {
+		return nil, nil
+	}
+	e := it.res[it.pos]
+	it.pos++
+	return e, nil
+}
+
+type scanIterator struct {
+	s    *RelationScanner
+	cols []string
+}
+
+func (s *RelationScanner) Iter() (Iterator, error) {
+	return &scanIterator{s: s, cols: s.src.Columns()}, nil
+}
+
+func (it *scanIterator) Columns() []string {
+	return it.cols
+}
+
+func (it *scanIterator) Next() (*list.Element, error) {
+	for it.s.src.HasNext() {
+		e := it.s.src.Next()
+		tup := e.Value.(*Tuple)
+		match := true
+		for _, p := range it.s.predicates {
+			ok, err := p.Eval(it.cols, tup)
+			if err != nil {
+				return nil, fmt.Errorf("RelationScanner.Exec: %s(%v) : %w", p, e, err)
+			}
+			if !ok {
+				match = false
+				break
+			}
+		}
+		if match {
+			return e, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (sn *SubqueryNode) Iter() (Iterator, error) {
+	return Iter(sn.src)
+}
+
+type limitIterator struct {
+	src   Iterator
+	limit int64
+	n     int64
+}
+
+// Iter stops pulling rows from child node once limit is reached
+func (d *LimitSorter) Iter() (Iterator, error) {
+	src, err := Iter(d.src)
+	if err != nil {
+		return nil, err
+	}
+	return &limitIterator{src: src, limit: d.limit}, nil
+}
+
+func (it *limitIterator) Columns() []string {
+	return it.src.Columns()
+}
+
+func (it *limitIterator) Next() (*list.Element, error) {
+	if it.n >= it.limit {
+		return nil, nil
+	}
+	e, err := it.src.Next()
+	if err != nil || e == nil {
+		return nil, err
+	}
+	it.n++
+	return e, nil
+}
+
+type offsetIterator struct {
+	src  Iterator
+	skip int
+}
+
+func (s *OffsetSorter) Iter() (Iterator, error) {
+	src, err := Iter(s.src)
+	if err != nil {
+		return nil, err
+	}
+	return &offsetIterator{src: src, skip: s.o}, nil
+}
+
+func (it *offsetIterator) Columns() []string {
+	return it.src.Columns()
+}
+
+func (it *offsetIterator) Next() (*list.Element, error) {
+	for ; it.skip > 0; it.skip-- {
+		e, err := it.src.Next()
+		if err != nil || e == nil {
+			return nil, err
+		}
+	}
+	return it.src.Next()
+}
+
+type selectorIterator struct {
+	sn      *SelectorNode
+	src     Iterator
+	srcCols []string
+	cols    []string
+	typed   bool
+}
+
+// Iter streams rows if all selectors work row by row. Aggregates like COUNT need
+// all rows, in which case the node is executed.
+func (sn *SelectorNode) Iter() (Iterator, error) {
+	for _, s := range sn.selectors {
+		switch s.(type) {
+		case *AttributeSelector, *StarSelector, *ConstSelector, *ExprSelector:
+		default:
+			cols, res, err := sn.Exec()
+			if err != nil {
+				return nil, err
+			}
+			return &sliceIterator{cols: cols, res: res}, nil
+		}
+	}
+
+	src, err := Iter(sn.child)
+	if err != nil {
+		return nil, err
+	}
+
+	it := &selectorIterator{sn: sn, src: src, srcCols: src.Columns()}
+	if len(sn.selectors) == 0 {
+		it.cols = it.srcCols
+	}
+	// selecting no rows resolves selected columns
+	for _, s := range sn.selectors {
+		if _, err := s.Select(it.srcCols, nil); err != nil {
+			return nil, err
+		}
+		it.cols = append(it.cols, s.Attribute()...)
+	}
+	sn.types = nil
+
+	return it, nil
+}
+
+func (it *selectorIterator) Columns() []string {
+	return it.cols
+}
+
+func (it *selectorIterator) Next() (*list.Element, error) {
+	e, err := it.src.Next()
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		it.setTypes(nil, nil)
+		return nil, nil
+	}
+
+	if len(it.sn.selectors) == 0 {
+		it.setTypes([]*list.Element{e}, nil)
+		return e, nil
+	}
+
+	in := []*list.Element{e}
+	outs := make([][]*Tuple, len(it.sn.selectors))
+	t := &Tuple{values: make([]any, 0, len(it.cols))}
+	for i, s := range it.sn.selectors {
+		out, err := s.Select(it.srcCols, in)
+		if err != nil {
+			return nil, err
+		}
+		if len(out) != 1 {
+			return nil, fmt.Errorf("selector %s returned %d rows for 1", s, len(out))
+		}
+		outs[i] = out
+		t.values = append(t.values, out[0].values...)
+	}
+	it.setTypes(nil, outs)
+
+	return &list.Element{Value: t}, nil
+}
+
+// setTypes types selected columns using the first row, if any
+func (it *selectorIterator) setTypes(in []*list.Element, outs [][]*Tuple) {
+	if it.typed {
+		return
+	}
+	it.typed = true
+
+	if len(it.sn.selectors) == 0 {
+		var tuples []*Tuple
+		for _, e := range in {
+			tuples = append(tuples, e.Value.(*Tuple))
+		}
+		it.sn.types = NewColumnTypes(it.cols, tuples)
+		return
+	}
+
+	for i, s := range it.sn.selectors {
+		var out []*Tuple
+		if outs != nil {
+			out = outs[i]
+		}
+		it.sn.types = append(it.sn.types, selectorColumnTypes(s, it.sn.relations, out)...)
+	}
+}
@@ -0,0 +1,66 @@
+package agnostic
+
+import (
+	"container/list"
+	"testing"
+)
+
+// countingNode streams n rows and counts pulled rows
+type countingNode struct {
+	n      int64
+	pulled int64
+}
+
+func (c *countingNode) Exec() ([]string, []*list.Element, error) {
+	it, _ := c.Iter()
+	return drain(it)
+}
+
+func (c *countingNode) EstimateCardinal() int64 { return c.n }
+func (c *countingNode) Children() []Node        { return nil }
+
+func (c *countingNode) Iter() (Iterator, error) {
+	return c, nil
+}
+
+func (c *countingNode) Columns() []string { return []string{"id"} }
+
+func (c *countingNode) Next() (*list.Element, error) {
+	if c.pulled >= c.n {
+		return nil, nil
+	}
+	c.pulled++
+	return &list.Element{Value: NewTuple(c.pulled)}, nil
+}
+
+func TestLimitIterator(t *testing.T) {
+	src := &countingNode{n: 1000}
+	offset := NewOffsetSorter(10)
+	offset.SetNode(src)
+	limit := NewLimitSorter(5)
+	limit.SetNode(offset)
+
+	it, err := Iter(limit)
+	if err != nil {
+		t.Fatalf("cannot iterate: %s", err)
+	}
+
+	var ids []int64
+	for {
+		e, err := it.Next()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if e == nil {
+			break
+		}
+		ids = append(ids, e.Value.(*Tuple).Values()[0].(int64))
+	}
+
+	if len(ids) != 5 || ids[0] != 11 || ids[4] != 15 {
+		t.Fatalf("expected rows 11 to 15, got %v", ids)
+	}
+	if src.pulled != 15 {
+		t.Fatalf("expected 15 rows pulled from source, got %d", src.pulled)
+	}
+}
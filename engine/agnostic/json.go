@@ -0,0 +1,781 @@
+package agnostic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSON and JSONB attributes hold documents parsed when written. Values of a document
+// are nil, bool, json.Number, string, []any and map[string]any.
+
+// JSON is a parsed JSON document
+type JSON struct {
+	v    any
+	text string
+}
+
+var jsonType = reflect.TypeOf(JSON{})
+
+// IsJSONType reports whether typeName is json or jsonb
+func IsJSONType(typeName string) bool {
+	switch strings.ToLower(typeName) {
+	case "json", "jsonb":
+		return true
+	}
+	return false
+}
+
+// ParseJSON parses document s, which is kept as written
+func ParseJSON(s string) (JSON, error) {
+	d := json.NewDecoder(strings.NewReader(s))
+	d.UseNumber()
+
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return JSON{}, fmt.Errorf("invalid input syntax for type json: %s", s)
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return JSON{}, fmt.Errorf("invalid input syntax for type json: %s", s)
+	}
+	return JSON{v: v, text: s}, nil
+}
+
+// NewJSON returns a document holding v, made of nil, bool, json.Number, string,
+// []any and map[string]any values
+func NewJSON(v any) JSON {
+	return JSON{v: v, text: formatJSON(v)}
+}
+
+// ToJSON returns v as a document. Text is parsed, other values are converted
+// to JSON values, as PostgreSQL to_jsonb does.
+func ToJSON(v any) (JSON, error) {
+	switch v := v.(type) {
+	case JSON:
+		return v, nil
+	case string:
+		return ParseJSON(v)
+	case []byte:
+		return ParseJSON(string(v))
+	}
+	return NewJSON(jsonValue(v)), nil
+}
+
+// Text returns json documents as written, jsonb documents and documents computed
+// by operators and functions being in PostgreSQL jsonb format
+func (j JSON) Text() string {
+	return j.text
+}
+
+// String returns the document in PostgreSQL jsonb format, where object keys are
+// sorted, so equal documents have the same representation in indexes
+func (j JSON) String() string {
+	return formatJSON(j.v)
+}
+
+// convertJSON returns val, a document or its text, as a JSON value of type typeName.
+// json documents are kept as written, jsonb documents are normalized as PostgreSQL does.
+func convertJSON(val any, typeName string) (any, bool) {
+	switch val.(type) {
+	case JSON, string, []byte:
+		j, err := ToJSON(val)
+		if err != nil {
+			return val, false
+		}
+		if strings.EqualFold(typeName, "jsonb") {
+			j = NewJSON(j.v)
+		}
+		return j, true
+	}
+	return val, false
+}
+
+// JSONArrayElements returns the elements of array document v
+func JSONArrayElements(v any) ([]any, error) {
+	j, err := ToJSON(v)
+	if err != nil {
+		return nil, err
+	}
+
+	switch a := j.v.(type) {
+	case []any:
+		values := make([]any, len(a))
+		for i, e := range a {
+			values[i] = NewJSON(e)
+		}
+		return values, nil
+	case map[string]any:
+		return nil, fmt.Errorf("cannot extract elements from an object")
+	}
+	return nil, fmt.Errorf("cannot extract elements from a scalar")
+}
+
+// jsonOperands returns vl and vr as documents if one of them is a document.
+// ok is false if none of them is.
+func jsonOperands(vl, vr any) (l JSON, r JSON, ok bool, err error) {
+	_, lok := vl.(JSON)
+	_, rok := vr.(JSON)
+	if !lok && !rok {
+		return l, r, false, nil
+	}
+
+	l, err = ToJSON(vl)
+	if err != nil {
+		return l, r, true, err
+	}
+	r, err = ToJSON(vr)
+	return l, r, true, err
+}
+
+// jsonValue converts v to a JSON value
+func jsonValue(v any) any {
+	switch v := v.(type) {
+	case nil, bool, json.Number, string:
+		return v
+	case JSON:
+		return v.v
+	case []byte:
+		return string(v)
+	case float32:
+		return json.Number(strconv.FormatFloat(float64(v), 'f', -1, 32))
+	case float64:
+		return json.Number(strconv.FormatFloat(v, 'f', -1, 64))
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch {
+	case rv.CanInt():
+		return json.Number(strconv.FormatInt(rv.Int(), 10))
+	case rv.CanUint():
+		return json.Number(strconv.FormatUint(rv.Uint(), 10))
+	}
+	if values, ok := ArrayValues(v); ok {
+		a := make([]any, len(values))
+		for i, e := range values {
+			a[i] = jsonValue(e)
+		}
+		return a
+	}
+	return fmt.Sprint(v)
+}
+
+// formatJSON returns v in PostgreSQL jsonb format, object keys being sorted by length, then bytewise
+func formatJSON(v any) string {
+	var b strings.Builder
+	writeJSON(&b, v)
+	return b.String()
+}
+
+func writeJSON(b *strings.Builder, v any) {
+	switch v := v.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case json.Number:
+		b.WriteString(string(v))
+	case string:
+		b.WriteString(quoteJSON(v))
+	case []any:
+		b.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeJSON(b, e)
+		}
+		b.WriteByte(']')
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if len(keys[i]) != len(keys[j]) {
+				return len(keys[i]) < len(keys[j])
+			}
+			return keys[i] < keys[j]
+		})
+		b.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(quoteJSON(k))
+			b.WriteString(": ")
+			writeJSON(b, v[k])
+		}
+		b.WriteByte('}')
+	}
+}
+
+func quoteJSON(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// jsonText returns v as text, as the ->> operator does: strings are unquoted and null is NULL
+func jsonText(v any) any {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return v
+	}
+	return formatJSON(v)
+}
+
+// compareNumbers compares JSON numbers a and b, which may have different representations such as 1 and 1.0
+func compareNumbers(a, b json.Number) int {
+	ra, aok := new(big.Rat).SetString(string(a))
+	rb, bok := new(big.Rat).SetString(string(b))
+	if !aok || !bok {
+		return strings.Compare(string(a), string(b))
+	}
+	return ra.Cmp(rb)
+}
+
+// jsonEqual reports whether JSON values a and b are equal, ignoring the order of object keys
+func jsonEqual(a, b any) bool {
+	switch av := a.(type) {
+	case json.Number:
+		bv, ok := b.(json.Number)
+		return ok && compareNumbers(av, bv) == 0
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !jsonEqual(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			w, ok := bv[k]
+			if !ok || !jsonEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// jsonRank orders JSON value types as PostgreSQL does: null < string < number < boolean < array < object
+func jsonRank(v any) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case string:
+		return 1
+	case json.Number:
+		return 2
+	case bool:
+		return 3
+	case []any:
+		return 4
+	}
+	return 5
+}
+
+// jsonCompare returns -1, 0 or 1 if JSON value a is lower than, equal to or greater than b.
+// Objects are compared by their text.
+func jsonCompare(a, b any) int {
+	ra, rb := jsonRank(a), jsonRank(b)
+	if ra != rb {
+		if ra < rb {
+			return -1
+		}
+		return 1
+	}
+
+	switch av := a.(type) {
+	case string:
+		return strings.Compare(av, b.(string))
+	case json.Number:
+		return compareNumbers(av, b.(json.Number))
+	case bool:
+		bv := b.(bool)
+		switch {
+		case av == bv:
+			return 0
+		case bv:
+			return -1
+		}
+		return 1
+	case []any:
+		bv := b.([]any)
+		for i := 0; i < len(av) && i < len(bv); i++ {
+			if c := jsonCompare(av[i], bv[i]); c != 0 {
+				return c
+			}
+		}
+		switch {
+		case len(av) < len(bv):
+			return -1
+		case len(av) > len(bv):
+			return 1
+		}
+		return 0
+	case map[string]any:
+		if jsonEqual(a, b) {
+			return 0
+		}
+		return strings.Compare(formatJSON(a), formatJSON(b))
+	}
+	return 0
+}
+
+// jsonContains reports whether document a contains document b, as the jsonb @> operator does.
+// An array contains a scalar if it is one of its elements.
+func jsonContains(a, b any) bool {
+	if av, ok := a.([]any); ok && jsonRank(b) < 4 {
+		for _, v := range av {
+			if jsonEqual(v, b) {
+				return true
+			}
+		}
+		return false
+	}
+	return jsonContainsValue(a, b)
+}
+
+func jsonContainsValue(a, b any) bool {
+	switch bv := b.(type) {
+	case map[string]any:
+		av, ok := a.(map[string]any)
+		if !ok {
+			return false
+		}
+		for k, w := range bv {
+			v, ok := av[k]
+			if !ok || !jsonContainsValue(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		av, ok := a.([]any)
+		if !ok {
+			return false
+		}
+		for _, w := range bv {
+			found := false
+			for _, v := range av {
+				if jsonContainsValue(v, w) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+	return jsonEqual(a, b)
+}
+
+// jsonKeyExists reports whether key is a key of object v, an element of array v or string v itself
+func jsonKeyExists(v any, key string) bool {
+	switch v := v.(type) {
+	case map[string]any:
+		_, ok := v[key]
+		return ok
+	case []any:
+		for _, e := range v {
+			if s, ok := e.(string); ok && s == key {
+				return true
+			}
+		}
+		return false
+	case string:
+		return v == key
+	}
+	return false
+}
+
+// jsonIndex returns the index of element i of array a, negative indexes counting from its end
+func jsonIndex(a []any, i int) (int, bool) {
+	if i < 0 {
+		i += len(a)
+	}
+	return i, i >= 0 && i < len(a)
+}
+
+// jsonGet returns member key of object v, or element key of array v if key is an integer
+func jsonGet(v any, key any) (any, bool) {
+	switch v := v.(type) {
+	case map[string]any:
+		k, ok := key.(string)
+		if !ok {
+			return nil, false
+		}
+		e, ok := v[k]
+		return e, ok
+	case []any:
+		rk := reflect.ValueOf(key)
+		if !rk.CanInt() {
+			return nil, false
+		}
+		i, ok := jsonIndex(v, int(rk.Int()))
+		if !ok {
+			return nil, false
+		}
+		return v[i], true
+	}
+	return nil, false
+}
+
+// jsonGetPath returns the value of v at path, made of object keys and array indexes
+func jsonGetPath(v any, path []string) (any, bool) {
+	for _, p := range path {
+		var key any = p
+		if _, ok := v.([]any); ok {
+			i, err := strconv.Atoi(p)
+			if err != nil {
+				return nil, false
+			}
+			key = i
+		}
+		e, ok := jsonGet(v, key)
+		if !ok {
+			return nil, false
+		}
+		v = e
+	}
+	return v, true
+}
+
+// jsonPath returns path elements of v, a text array or its literal such as {a,0}
+func jsonPath(v any) ([]string, bool) {
+	if s, ok := v.(string); ok {
+		a, err := ParseArray(s, "text")
+		if err != nil {
+			return nil, false
+		}
+		v = a
+	}
+	values, ok := ArrayValues(v)
+	if !ok {
+		return nil, false
+	}
+	path := make([]string, len(values))
+	for i, e := range values {
+		path[i] = fmt.Sprint(e)
+	}
+	return path, true
+}
+
+// jsonSet returns a copy of v in which the value at path is replaced by value, as jsonb_set does.
+// If create is true, a missing last path element is added.
+func jsonSet(v any, path []string, value any, create bool) any {
+	if len(path) == 0 {
+		return value
+	}
+
+	switch c := v.(type) {
+	case map[string]any:
+		e, ok := c[path[0]]
+		if !ok && (!create || len(path) > 1) {
+			return v
+		}
+		m := make(map[string]any, len(c)+1)
+		for k, e := range c {
+			m[k] = e
+		}
+		if ok {
+			m[path[0]] = jsonSet(e, path[1:], value, create)
+		} else {
+			m[path[0]] = value
+		}
+		return m
+	case []any:
+		n, err := strconv.Atoi(path[0])
+		if err != nil {
+			return v
+		}
+		a := append([]any(nil), c...)
+		if i, ok := jsonIndex(c, n); ok {
+			a[i] = jsonSet(c[i], path[1:], value, create)
+			return a
+		}
+		if !create || len(path) > 1 {
+			return v
+		}
+		if n < 0 {
+			return append([]any{value}, a...)
+		}
+		return append(a, value)
+	}
+	return v
+}
+
+// JSONOperatorValueFunctor returns a member of a document, as ->, ->>, #> and #>> operators do.
+//
+// -> and ->> take an object key or an array index, #> and #>> a path such as {a,0}.
+// ->> and #>> return text. Values are NULL if the member does not exist.
+type JSONOperatorValueFunctor struct {
+	op    string
+	left  ValueFunctor
+	right ValueFunctor
+}
+
+// NewJSONOperatorValueFunctor creates a ValueFunctor computing left op right, op being one of -> ->> #> #>>
+func NewJSONOperatorValueFunctor(op string, left, right ValueFunctor) ValueFunctor {
+	return &JSONOperatorValueFunctor{
+		op:    op,
+		left:  left,
+		right: right,
+	}
+}
+
+func (f *JSONOperatorValueFunctor) Value(cols []string, t *Tuple) any {
+	vl := f.left.Value(cols, t)
+	vr := f.right.Value(cols, t)
+	if vl == nil || vr == nil {
+		return nil
+	}
+	l, err := ToJSON(vl)
+	if err != nil {
+		return nil
+	}
+
+	var v any
+	var ok bool
+	switch f.op {
+	case "->", "->>":
+		v, ok = jsonGet(l.v, vr)
+	case "#>", "#>>":
+		path, valid := jsonPath(vr)
+		if !valid {
+			return nil
+		}
+		v, ok = jsonGetPath(l.v, path)
+	}
+	if !ok {
+		return nil
+	}
+
+	if f.op == "->>" || f.op == "#>>" {
+		return jsonText(v)
+	}
+	return NewJSON(v)
+}
+
+func (f *JSONOperatorValueFunctor) Relation() string {
+	if f.left.Relation() != "" {
+		return f.left.Relation()
+	}
+	return f.right.Relation()
+}
+
+func (f *JSONOperatorValueFunctor) Attribute() []string {
+	return append(f.left.Attribute(), f.right.Attribute()...)
+}
+
+func (f JSONOperatorValueFunctor) String() string {
+	return fmt.Sprintf("%s %s %s", f.left, f.op, f.right)
+}
+
+// JSONBuildObjectValueFunctor builds an object from alternating keys and values,
+// as jsonb_build_object does
+type JSONBuildObjectValueFunctor struct {
+	args []ValueFunctor
+}
+
+// NewJSONBuildObjectValueFunctor creates a ValueFunctor computing jsonb_build_object(args...)
+func NewJSONBuildObjectValueFunctor(args ...ValueFunctor) (ValueFunctor, error) {
+	if len(args)%2 != 0 {
+		return nil, fmt.Errorf("argument list must have even number of elements")
+	}
+	return &JSONBuildObjectValueFunctor{args: args}, nil
+}
+
+func (f *JSONBuildObjectValueFunctor) Value(cols []string, t *Tuple) any {
+	m := make(map[string]any, len(f.args)/2)
+	for i := 0; i < len(f.args); i += 2 {
+		k := jsonValue(f.args[i].Value(cols, t))
+		if k == nil {
+			return nil
+		}
+		key, ok := k.(string)
+		if !ok {
+			key = formatJSON(k)
+		}
+		m[key] = jsonValue(f.args[i+1].Value(cols, t))
+	}
+	return NewJSON(m)
+}
+
+func (f *JSONBuildObjectValueFunctor) Relation() string {
+	for _, a := range f.args {
+		if r := a.Relation(); r != "" {
+			return r
+		}
+	}
+	return ""
+}
+
+func (f *JSONBuildObjectValueFunctor) Attribute() []string {
+	var attrs []string
+	for _, a := range f.args {
+		attrs = append(attrs, a.Attribute()...)
+	}
+	return attrs
+}
+
+func (f JSONBuildObjectValueFunctor) String() string {
+	args := make([]string, len(f.args))
+	for i, a := range f.args {
+		args[i] = fmt.Sprint(a)
+	}
+	return fmt.Sprintf("jsonb_build_object(%s)", strings.Join(args, ", "))
+}
+
+// JSONSetValueFunctor replaces the value at a path of a document, as jsonb_set does
+type JSONSetValueFunctor struct {
+	target ValueFunctor
+	path   ValueFunctor
+	value  ValueFunctor
+	create ValueFunctor
+}
+
+// NewJSONSetValueFunctor creates a ValueFunctor computing jsonb_set(target, path, value, create).
+// create may be nil, missing keys are then added.
+func NewJSONSetValueFunctor(target, path, value, create ValueFunctor) ValueFunctor {
+	return &JSONSetValueFunctor{
+		target: target,
+		path:   path,
+		value:  value,
+		create: create,
+	}
+}
+
+func (f *JSONSetValueFunctor) Value(cols []string, t *Tuple) any {
+	vt := f.target.Value(cols, t)
+	vv := f.value.Value(cols, t)
+	if vt == nil || vv == nil {
+		return nil
+	}
+	target, err := ToJSON(vt)
+	if err != nil {
+		return nil
+	}
+	value, err := ToJSON(vv)
+	if err != nil {
+		return nil
+	}
+	path, ok := jsonPath(f.path.Value(cols, t))
+	if !ok {
+		return nil
+	}
+
+	create := true
+	if f.create != nil {
+		if b, ok := f.create.Value(cols, t).(bool); ok {
+			create = b
+		}
+	}
+	return NewJSON(jsonSet(target.v, path, value.v, create))
+}
+
+func (f *JSONSetValueFunctor) Relation() string {
+	return f.target.Relation()
+}
+
+func (f *JSONSetValueFunctor) Attribute() []string {
+	return f.target.Attribute()
+}
+
+func (f JSONSetValueFunctor) String() string {
+	return fmt.Sprintf("jsonb_set(%s, %s, %s)", f.target, f.path, f.value)
+}
+
+// JSONPredicate compares documents with @> (contains), <@ (is contained by), or tests
+// whether a key exists with ?
+type JSONPredicate struct {
+	left  ValueFunctor
+	right ValueFunctor
+	t     PredicateType
+}
+
+// NewJSONPredicate creates a predicate on document left, t being Contains, ContainedBy or KeyExists
+func NewJSONPredicate(left ValueFunctor, t PredicateType, right ValueFunctor) *JSONPredicate {
+	return &JSONPredicate{left: left, right: right, t: t}
+}
+
+func (p *JSONPredicate) Type() PredicateType {
+	return p.t
+}
+
+func (p JSONPredicate) String() string {
+	return fmt.Sprintf("%s %s %s", p.left, operatorString(p.t), p.right)
+}
+
+func (p *JSONPredicate) Eval(cols []string, t *Tuple) (bool, error) {
+	vl := p.left.Value(cols, t)
+	vr := p.right.Value(cols, t)
+	if vl == nil || vr == nil {
+		return false, nil
+	}
+
+	l, err := ToJSON(vl)
+	if err != nil {
+		return false, err
+	}
+	if p.t == KeyExists {
+		return jsonKeyExists(l.v, fmt.Sprint(vr)), nil
+	}
+
+	r, err := ToJSON(vr)
+	if err != nil {
+		return false, err
+	}
+	switch p.t {
+	case Contains:
+		return jsonContains(l.v, r.v), nil
+	case ContainedBy:
+		return jsonContains(r.v, l.v), nil
+	}
+	return false, fmt.Errorf("unknown json operator %s", operatorString(p.t))
+}
+
+func (p *JSONPredicate) Left() (Predicate, bool) {
+	return nil, false
+}
+
+func (p *JSONPredicate) Right() (Predicate, bool) {
+	return nil, false
+}
+
+func (p *JSONPredicate) Relation() string {
+	if p.left.Relation() != "" {
+		return p.left.Relation()
+	}
+
+	return p.right.Relation()
+}
+
+func (p *JSONPredicate) Attribute() []string {
+	return append(p.left.Attribute(), p.right.Attribute()...)
+}
@@ -27,10 +27,17 @@ const (
 	Ge
 	Neq
 	Like
+	ILike
 	In
 	Not
 	True
 	False
+	Any
+	All
+	Contains
+	ContainedBy
+	Overlap
+	KeyExists
 )
 
 var (
@@ -223,15 +230,11 @@ func (s OffsetSorter) String() string {
 }
 
 func (s *OffsetSorter) Exec() ([]string, []*list.Element, error) {
-	cols, res, err := s.src.Exec()
+	it, err := s.Iter()
 	if err != nil {
 		return nil, nil, err
 	}
-
-	if s.o >= len(res) {
-		return cols, nil, nil
-	}
-	return cols, res[s.o:], nil
+	return drain(it)
 }
 
 func (s *OffsetSorter) EstimateCardinal() int64 {
@@ -349,7 +352,7 @@ func (s *OrderBySorter) Exec() ([]string, []*list.Element, error) {
 			}
 		}
 		if !found {
-			return nil, nil, fmt.Errorf("column %s does not exist", a.attr)
+			return nil, nil, NewError(UndefinedColumn, "column %s does not exist", a.attr)
 		}
 	}
 
@@ -422,16 +425,11 @@ func (s LimitSorter) String() string {
 }
 
 func (d *LimitSorter) Exec() ([]string, []*list.Element, error) {
-
-	cols, res, err := d.src.Exec()
+	it, err := d.Iter()
 	if err != nil {
 		return nil, nil, err
 	}
-	if len(res) > int(d.limit) {
-		res = res[:d.limit]
-	}
-
-	return cols, res, nil
+	return drain(it)
 }
 
 func (d *LimitSorter) EstimateCardinal() int64 {
@@ -625,6 +623,7 @@ type CountSelector struct {
 	relation  string
 	attribute string
 	alias     string
+	distinct  bool
 	cols      []string
 }
 
@@ -636,6 +635,13 @@ func NewCountSelector(rname string, attr string) *CountSelector {
 	return s
 }
 
+// NewCountDistinctSelector creates a CountSelector counting distinct values of attr
+func NewCountDistinctSelector(rname string, attr string) *CountSelector {
+	s := NewCountSelector(rname, attr)
+	s.distinct = true
+	return s
+}
+
 func (s *CountSelector) Attribute() []string {
 	if s.cols != nil {
 		return s.cols
@@ -669,9 +675,35 @@ func (s *CountSelector) Select(cols []string, in []*list.Element) (out []*Tuple,
 		return nil, fmt.Errorf("%s.%s: columns not found in left node", s.relation, s.attribute)
 	}
 
-	s.cols = []string{"COUNT(" + s.attribute + ")"}
-	t := NewTuple(int64(len(in)))
-	out = append(out, t)
+	if s.distinct {
+		s.cols = []string{"COUNT(DISTINCT " + s.attribute + ")"}
+	} else {
+		s.cols = []string{"COUNT(" + s.attribute + ")"}
+	}
+
+	if s.attribute == "*" {
+		out = append(out, NewTuple(int64(len(in))))
+		return
+	}
+
+	// NULL values are not counted
+	var count int64
+	seen := make(map[string]struct{})
+	for _, e := range in {
+		v := e.Value.(*Tuple).values[idx]
+		if v == nil {
+			continue
+		}
+		if s.distinct {
+			k := fmt.Sprintf("%v", v)
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+		}
+		count++
+	}
+	out = append(out, NewTuple(count))
 	return
 }
 
@@ -721,6 +753,8 @@ type StarSelector struct {
 	relation string
 	alias    string
 	cols     []string
+	// hidden attributes are not selected, see Attribute.WithHidden
+	hidden map[string]struct{}
 }
 
 func NewStarSelector(rname string) *StarSelector {
@@ -745,8 +779,17 @@ func (s *StarSelector) Alias() string {
 func (s *StarSelector) Select(cols []string, in []*list.Element) (out []*Tuple, err error) {
 	var colIdx []int
 
+	s.cols = nil
+
 	// if only 1 relation, can return directly
 	for i, c := range cols {
+		if !strings.Contains(c, ".") && len(s.hidden) > 0 {
+			if _, ok := s.hidden[c]; !ok {
+				s.cols = append(s.cols, c)
+				colIdx = append(colIdx, i)
+			}
+			continue
+		}
 		if !strings.Contains(c, ".") {
 			out = make([]*Tuple, len(in))
 			for i, e := range in {
@@ -760,7 +803,11 @@ func (s *StarSelector) Select(cols []string, in []*list.Element) (out []*Tuple,
 			return
 		}
 		if strings.HasPrefix(c, s.relation) {
-			s.cols = append(s.cols, strings.Split(c, ".")[1])
+			name := strings.Split(c, ".")[1]
+			if _, ok := s.hidden[name]; ok {
+				continue
+			}
+			s.cols = append(s.cols, name)
 			colIdx = append(colIdx, i)
 		}
 	}
@@ -780,12 +827,6 @@ func (s StarSelector) String() string {
 	return s.relation + ".*"
 }
 
-type AvgSelector struct {
-}
-
-type MaxSelector struct {
-}
-
 func NewComparisonPredicate(left ValueFunctor, t PredicateType, right ValueFunctor) (Predicate, error) {
 
 	switch t {
@@ -803,6 +844,8 @@ func NewComparisonPredicate(left ValueFunctor, t PredicateType, right ValueFunct
 		return NewNeqPredicate(left, right), nil
 	case Like:
 		return NewLikePredicate(left, right), nil
+	case ILike:
+		return NewILikePredicate(left, right), nil
 	default:
 		return nil, fmt.Errorf("unknown predicate type %v", t)
 	}
@@ -1232,19 +1275,31 @@ func (p *EqPredicate) Attribute() []string {
 }
 
 type LikePredicate struct {
-	left  ValueFunctor
-	right ValueFunctor
+	left        ValueFunctor
+	right       ValueFunctor
+	insensitive bool
 }
 
 func NewLikePredicate(left, right ValueFunctor) *LikePredicate {
 	return &LikePredicate{left: left, right: right}
 }
 
+// NewILikePredicate creates a case insensitive LikePredicate
+func NewILikePredicate(left, right ValueFunctor) *LikePredicate {
+	return &LikePredicate{left: left, right: right, insensitive: true}
+}
+
 func (p *LikePredicate) Type() PredicateType {
+	if p.insensitive {
+		return ILike
+	}
 	return Like
 }
 
 func (p LikePredicate) String() string {
+	if p.insensitive {
+		return fmt.Sprintf("%s ILIKE %s", p.left, p.right)
+	}
 	return fmt.Sprintf("%s LIKE %s", p.left, p.right)
 }
 
@@ -1254,6 +1309,9 @@ func (p *LikePredicate) Eval(cols []string, t *Tuple) (bool, error) {
 	if vl == nil || vr == nil {
 		return false, nil
 	}
+	if p.insensitive {
+		return likeMatch(strings.ToLower(fmt.Sprint(vl)), strings.ToLower(fmt.Sprint(vr)))
+	}
 	return likeMatch(fmt.Sprint(vl), fmt.Sprint(vr))
 }
 
@@ -1314,6 +1372,10 @@ type SelectorNode struct {
 	selectors []Selector
 	child     Node
 	columns   []string
+
+	// relations are used to type selected columns
+	relations map[string]*Relation
+	types     []ColumnType
 }
 
 func NewSelectorNode(selectors []Selector, n Node) *SelectorNode {
@@ -1339,11 +1401,13 @@ func (sn *SelectorNode) Exec() ([]string, []*list.Element, error) {
 		return nil, nil, err
 	}
 	if len(sn.selectors) == 0 {
+		sn.types = NewColumnTypes(cols, nil)
 		return cols, srcs, nil
 	}
 
 	outs := make([][]*Tuple, len(sn.selectors))
 	var resc []string
+	sn.types = nil
 
 	var prevLen int
 	for i, selector := range sn.selectors {
@@ -1357,6 +1421,7 @@ func (sn *SelectorNode) Exec() ([]string, []*list.Element, error) {
 		}
 		prevLen = len(out)
 		resc = append(resc, selector.Attribute()...)
+		sn.types = append(sn.types, selectorColumnTypes(selector, sn.relations, out)...)
 	}
 
 	// We have prevLen rows with l columns to return
@@ -1387,6 +1452,11 @@ func (sn *SelectorNode) Columns() []string {
 	return sn.columns
 }
 
+// ColumnTypes returns the types of the columns returned by the last Exec
+func (sn *SelectorNode) ColumnTypes() []ColumnType {
+	return sn.types
+}
+
 func (sn *SelectorNode) EstimateCardinal() int64 {
 	return sn.child.EstimateCardinal()
 }
@@ -1633,6 +1703,13 @@ func (p *GeqPredicate) Eval(cols []string, t *Tuple) (bool, error) {
 		return false, nil
 	}
 
+	if jl, jr, ok, err := jsonOperands(vl, vr); ok {
+		if err != nil {
+			return false, err
+		}
+		return jsonCompare(jl.v, jr.v) >= 0, nil
+	}
+
 	switch l.Kind() {
 	default:
 		return false, fmt.Errorf("%s not comparable", l)
@@ -1723,6 +1800,13 @@ func (p *LeqPredicate) Eval(cols []string, t *Tuple) (bool, error) {
 		return false, nil
 	}
 
+	if jl, jr, ok, err := jsonOperands(vl, vr); ok {
+		if err != nil {
+			return false, err
+		}
+		return jsonCompare(jl.v, jr.v) <= 0, nil
+	}
+
 	switch l.Kind() {
 	default:
 		return false, fmt.Errorf("%s not comparable", l)
@@ -1813,6 +1897,13 @@ func (p *LePredicate) Eval(cols []string, t *Tuple) (bool, error) {
 		return false, nil
 	}
 
+	if jl, jr, ok, err := jsonOperands(vl, vr); ok {
+		if err != nil {
+			return false, err
+		}
+		return jsonCompare(jl.v, jr.v) < 0, nil
+	}
+
 	switch l.Kind() {
 	default:
 		return false, fmt.Errorf("%s not comparable", l)
@@ -1900,6 +1991,13 @@ func (p *GePredicate) Eval(cols []string, t *Tuple) (bool, error) {
 }
 
 /*
+	if jl, jr, ok, err := jsonOperands(vl, vr); ok {
+		if err != nil {
+			return false, err
+		}
+		return jsonCompare(jl.v, jr.v) > 0, nil
+	}
+
 	switch l.Kind() {
 	default:
 		return false, fmt.Errorf("%s not comparable", p)
@@ -1991,6 +2089,16 @@ func (p *NeqPredicate) Eval(cols []string, t *Tuple) (bool, error) {
 		return true, nil
 	}
 
+	if l.Kind() == reflect.Slice && r.Kind() == reflect.Slice {
+		eq, err := arrayEqual(vl, vr)
+		return !eq, err
+	}
+	if jl, jr, ok, err := jsonOperands(vl, vr); ok {
+		if err != nil {
+			return false, err
+		}
+		return !jsonEqual(jl.v, jr.v), nil
+	}
 	if l.Kind() == r.Kind() {
 		return !l.Equal(r), nil
 	}
@@ -2073,10 +2181,27 @@ func equal(vl, vr any) (bool, error) {
 		return false, nil
 	}
 
+	if l.Kind() == reflect.Slice && r.Kind() == reflect.Slice {
+		return arrayEqual(vl, vr)
+	}
+	if jl, jr, ok, err := jsonOperands(vl, vr); ok {
+		if err != nil {
+			return false, err
+		}
+		return jsonEqual(jl.v, jr.v), nil
+	}
 	if l.Kind() == r.Kind() {
 		return l.Equal(r), nil
 	}
 
+	// booleans equal integers 0 and 1, as MySQL TINYINT(1) booleans do
+	if b, ok := integerBool(vr); ok && l.Kind() == reflect.Bool {
+		return l.Bool() == b, nil
+	}
+	if b, ok := integerBool(vl); ok && r.Kind() == reflect.Bool {
+		return r.Bool() == b, nil
+	}
+
 	switch l.Kind() {
 	case reflect.Bool:
 		if r.Kind() == reflect.Bool {
@@ -2116,10 +2241,40 @@ func equal(vl, vr any) (bool, error) {
 	return false, fmt.Errorf("%v (%v) and %v (%v) not comparable", vl, reflect.TypeOf(vl), vr, reflect.TypeOf(vr))
 }
 
+// integerBool returns integer v as a boolean. ok is false if v is not 0 or 1.
+func integerBool(v any) (b bool, ok bool) {
+	r := reflect.ValueOf(v)
+	switch {
+	case r.CanInt() && (r.Int() == 0 || r.Int() == 1):
+		return r.Int() == 1, true
+	case r.CanUint() && r.Uint() <= 1:
+		return r.Uint() == 1, true
+	}
+	return false, false
+}
+
+// likeMatch reports whether value matches pattern, % and _ being escaped with a backslash
 func likeMatch(value, pattern string) (bool, error) {
-	regex := "^" + regexp.QuoteMeta(pattern) + "$"
-	regex = strings.ReplaceAll(regex, "%", ".*")
-	regex = strings.ReplaceAll(regex, "_", ".")
+	var b strings.Builder
+	b.WriteString("^")
+	escaped := false
+	for _, c := range pattern {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '%':
+			b.WriteString(".*")
+		case c == '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	regex := b.String()
 
 	candidates := []string{value}
 	if strings.Contains(pattern, ",") && !strings.Contains(value, ",") {
@@ -2152,6 +2307,12 @@ func greater(vl, vr any) (bool, error) {
 	if vr == nil {
 		return true, nil
 	}
+	if jl, jr, ok, err := jsonOperands(vl, vr); ok {
+		if err != nil {
+			return false, err
+		}
+		return jsonCompare(jl.v, jr.v) > 0, nil
+	}
 
 	switch l.Kind() {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
@@ -2193,12 +2354,11 @@ type Updater struct {
 	attributes []Attribute
 	indexes    []Index
 	txn        *Transaction
+	relation   *Relation
 }
 
 func NewUpdaterNode(schema string, relation *Relation, txn *Transaction, changes *list.List, values map[string]any) *Updater {
-	if schema == "" {
-		schema = DefaultSchema
-	}
+	schema = txn.resolve(schema, relation.name)
 	u := &Updater{
 		rel:        relation.name,
 		schema:     schema,
@@ -2208,6 +2368,7 @@ func NewUpdaterNode(schema string, relation *Relation, txn *Transaction, changes
 		attributes: relation.attributes,
 		indexes:    relation.indexes,
 		txn:        txn,
+		relation:   relation,
 	}
 
 	for k := range values {
@@ -2235,8 +2396,7 @@ type fieldChange struct {
 }
 
 // buildNewTupleAndChanges constructs the new tuple for an update and records per-attribute changes.
-// It also performs type conversion and mutates u.values by deleting consumed keys to keep
-// the unknown-attribute check at the end of Exec intact.
+// It also performs type conversion. u.values is left untouched so every row gets the same values.
 func (u *Updater) buildNewTupleAndChanges(src *Tuple, cols []string) (*Tuple, map[string]fieldChange, error) {
 	newt := &Tuple{values: make([]any, len(src.values))}
 	changed := make(map[string]fieldChange)
@@ -2245,25 +2405,31 @@ func (u *Updater) buildNewTupleAndChanges(src *Tuple, cols []string) (*Tuple, ma
 		nv := v
 		attr := u.attributes[i]
 		if val, ok := u.values[cols[i]]; ok {
+			// expressions are computed from the updated row
+			if f, ok := val.(ValueFunctor); ok {
+				val = f.Value(cols, src)
+			}
 			if val == nil {
 				newt.values[i] = nil
-				delete(u.values, cols[i])
 				// record change if old wasn't nil
 				if v != nil {
 					changed[attr.name] = fieldChange{old: v, new: nil}
 				}
 				continue
 			}
-			tof := reflect.TypeOf(val)
-			if !tof.ConvertibleTo(attr.typeInstance) {
-				return nil, nil, fmt.Errorf("cannot assign '%v' (type %s) to %s.%s (type %s)", val, tof, u.rel, attr.name, attr.typeInstance)
+			val, ok := u.txn.e.convert(val, attr.typeName, attr.typeInstance)
+			if !ok {
+				return nil, nil, assignError(val, u.rel, attr)
+			}
+			nv = val
+			// generated rowids follow the largest one, see Relation.nextRowID
+			if id, ok := val.(int64); ok && attr.rowID && id >= 0 && uint64(id) >= u.relation.attributes[i].nextValue {
+				u.relation.attributes[i].nextValue = uint64(id) + 1
 			}
-			nv = reflect.ValueOf(val).Convert(attr.typeInstance).Interface()
 			log.Debug("Updating %s to %v", attr.name, nv)
 		}
 
 		newt.values[i] = nv
-		delete(u.values, cols[i])
 
 		if !reflect.DeepEqual(nv, v) {
 			changed[attr.name] = fieldChange{old: v, new: nv}
@@ -2273,6 +2439,33 @@ func (u *Updater) buildNewTupleAndChanges(src *Tuple, cols []string) (*Tuple, ma
 	return newt, changed, nil
 }
 
+// checkUniqueIndexes rejects newt if a unique index attribute changed and another row holds the same values
+func (u *Updater) checkUniqueIndexes(newt *Tuple, changed map[string]fieldChange) error {
+	for _, index := range u.indexes {
+		hi, ok := index.(*HashIndex)
+		if !ok || !hi.unique {
+			continue
+		}
+		modified := false
+		for _, name := range hi.attrsName {
+			if _, ok := changed[name]; ok {
+				modified = true
+			}
+		}
+		if !modified {
+			continue
+		}
+		conflict, err := hi.conflicts(hi.values(newt))
+		if err != nil {
+			return err
+		}
+		if conflict {
+			return NewError(UniqueViolation, "constraint violation: unique index %s", hi.name)
+		}
+	}
+	return nil
+}
+
 // enforceParentRestrictOnUpdate prevents updates that would modify parent key columns
 // referenced by child rows (RESTRICT behavior). It scans all relations for references
 // to u.schema.u.rel and checks both column-level and relation-level FKs.
@@ -2365,7 +2558,7 @@ func (u *Updater) enforceParentRestrictOnUpdate(changed map[string]fieldChange,
 						// Found a child row that references the old parent values
 						localColsStr := strings.Join(fk.LocalColumns(), ", ")
 						refColsStr := strings.Join(refCols, ", ")
-						return fmt.Errorf("update violates foreign key: %s.%s(%s) is referenced by %s.%s(%s)", u.schema, u.rel, refColsStr, schName, childName, localColsStr)
+						return NewError(ForeignKeyViolation, "update violates foreign key: %s.%s(%s) is referenced by %s.%s(%s)", u.schema, u.rel, refColsStr, schName, childName, localColsStr)
 					}
 				}
 			}
@@ -2422,7 +2615,7 @@ func (u *Updater) enforceChildFKs(newt *Tuple, cols []string) error {
 		refCols := fk.RefColumns()
 		if len(refCols) == 0 {
 			// Reference parent PK
-			ps, err := u.txn.e.schema(refSchema)
+			ps, err := u.txn.schema(refSchema, refRel)
 			if err != nil {
 				return err
 			}
@@ -2480,7 +2673,7 @@ func (u *Updater) enforceChildFKs(newt *Tuple, cols []string) error {
 			// Build a readable error message
 			localColsStr := strings.Join(fk.LocalColumns(), ", ")
 			refColsStr := strings.Join(refCols, ", ")
-			return fmt.Errorf("update violates foreign key: %s.%s(%s) references %s.%s(%s)", u.schema, u.rel, localColsStr, refSchema, refRel, refColsStr)
+			return NewError(ForeignKeyViolation, "update violates foreign key: %s.%s(%s) references %s.%s(%s)", u.schema, u.rel, localColsStr, refSchema, refRel, refColsStr)
 		}
 	}
 
@@ -2503,6 +2696,10 @@ func (u *Updater) Exec() (cols []string, out []*list.Element, err error) {
 			return nil, nil, err
 		}
 
+		if err := u.checkUniqueIndexes(newt, changed); err != nil {
+			return nil, nil, err
+		}
+
 		if err := u.enforceParentRestrictOnUpdate(changed, t); err != nil {
 			return nil, nil, err
 		}
@@ -2511,11 +2708,17 @@ func (u *Updater) Exec() (cols []string, out []*list.Element, err error) {
 			return nil, nil, err
 		}
 
+		if err := u.txn.checkSizeLimits(u.relation, 0, tupleSize(newt)-tupleSize(t)); err != nil {
+			return nil, nil, err
+		}
+
 		newe := u.rows.InsertAfter(newt, e)
 		if newe == nil {
 			return nil, nil, fmt.Errorf("cannot update rows %v with %v, element not in rows", e, newe)
 		}
 		u.rows.Remove(e)
+		u.relation.size.remove(t)
+		u.relation.size.add(newt)
 		for _, i := range u.indexes {
 			i.Remove(e)
 		}
@@ -2528,14 +2731,26 @@ func (u *Updater) Exec() (cols []string, out []*list.Element, err error) {
 			current: newe,
 			old:     e,
 			l:       u.rows,
+			rel:     u.relation,
 		}
 		u.changes.PushBack(c)
 	}
 
 	// Only check for non-existent attributes if we actually processed rows.
-	// If no rows matched the WHERE clause, u.values won't be consumed, but that's OK.
-	if len(in) > 0 && len(u.values) > 0 {
-		return nil, nil, fmt.Errorf("attribute %s not existing in relation %s, %s", u.values, u.rel, u.attributes)
+	// If no rows matched the WHERE clause, that's OK.
+	if len(in) > 0 {
+		for k := range u.values {
+			found := false
+			for _, c := range cols {
+				if c == k {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, nil, fmt.Errorf("attribute %s not existing in relation %s, %s", u.values, u.rel, u.attributes)
+			}
+		}
 	}
 	return cols, out, nil
 }
@@ -2555,6 +2770,7 @@ type Deleter struct {
 	child      Node
 	attributes []Attribute
 	indexes    []Index
+	relation   *Relation
 }
 
 func NewDeleterNode(relation *Relation, changes *list.List) *Deleter {
@@ -2564,6 +2780,7 @@ func NewDeleterNode(relation *Relation, changes *list.List) *Deleter {
 		changes:    changes,
 		attributes: relation.attributes,
 		indexes:    relation.indexes,
+		relation:   relation,
 	}
 
 	return u
@@ -2591,7 +2808,9 @@ func (u *Deleter) Exec() (cols []string, out []*list.Element, err error) {
 
 	for _, t := range in {
 
+		next := t.Next()
 		u.rows.Remove(t)
+		u.relation.size.remove(t.Value.(*Tuple))
 		for _, i := range u.indexes {
 			i.Remove(t)
 		}
@@ -2602,6 +2821,8 @@ func (u *Deleter) Exec() (cols []string, out []*list.Element, err error) {
 			current: nil,
 			old:     t,
 			l:       u.rows,
+			rel:     u.relation,
+			next:    next,
 		}
 		u.changes.PushBack(c)
 	}
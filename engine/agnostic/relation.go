@@ -21,9 +21,26 @@ type Relation struct {
 
 	indexes []Index
 
+	// virtual relations are read-only, rows are rebuilt by calling virtual
+	// each time a transaction locks the relation
+	virtual func() [][]any
+
+	limits Limits
+	size   relationSize
+
 	sync.RWMutex
 }
 
+// isPK returns true if attribute at index idx is part of the primary key
+func (r *Relation) isPK(idx int) bool {
+	for _, i := range r.pk {
+		if i == idx {
+			return true
+		}
+	}
+	return false
+}
+
 func NewRelation(schema, name string, attributes []Attribute, pk []string) (*Relation, error) {
 	r := &Relation{
 		name:       name,
@@ -98,12 +115,69 @@ func (r *Relation) Attribute(name string) (int, Attribute, error) {
 	name = strings.ToLower(name)
 	index, ok := r.attrIndex[name]
 	if !ok {
-		return 0, Attribute{}, fmt.Errorf("attribute not defined: %s.%s", r.name, name)
+		index, ok = r.rowIDIndex(name)
+	}
+	if !ok {
+		return 0, Attribute{}, NewError(UndefinedColumn, "attribute not defined: %s.%s", r.name, name)
 	}
 	return index, r.attributes[index], nil
 }
 
-func (r *Relation) createIndex(name string, t IndexType, attrs []string) error {
+// rowIDIndex returns the index of the attribute aliasing rowid if name is
+// rowid, oid or _rowid_, as SQLite resolves them when no column has such name.
+func (r *Relation) rowIDIndex(name string) (int, bool) {
+	switch name {
+	case "rowid", "oid", "_rowid_":
+	default:
+		return 0, false
+	}
+	for i := range r.attributes {
+		if r.attributes[i].rowID {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// nextRowID returns the value generated for rowid attribute i without AUTOINCREMENT:
+// one more than the largest rowid of the relation rows, as SQLite does.
+// Rows are appended, so the last row holds the largest rowid unless it was deleted,
+// in which case rows are scanned.
+func (r *Relation) nextRowID(i int) uint64 {
+	last := r.rows.Back()
+	if last == nil {
+		return 1
+	}
+	next := r.attributes[i].nextValue
+	if id, ok := last.Value.(*Tuple).values[i].(int64); ok && id >= 0 && uint64(id)+1 == next {
+		return next
+	}
+
+	var max int64
+	for e := r.rows.Front(); e != nil; e = e.Next() {
+		if id, ok := e.Value.(*Tuple).values[i].(int64); ok && id > max {
+			max = id
+		}
+	}
+	return uint64(max) + 1
+}
+
+// hiddenAttributes returns the name of hidden attributes, see Attribute.WithHidden
+func (r *Relation) hiddenAttributes() map[string]struct{} {
+	var hidden map[string]struct{}
+	for _, a := range r.attributes {
+		if !a.hidden {
+			continue
+		}
+		if hidden == nil {
+			hidden = make(map[string]struct{})
+		}
+		hidden[a.name] = struct{}{}
+	}
+	return hidden
+}
+
+func (r *Relation) createIndex(name string, t IndexType, attrs []string, unique bool) error {
 
 	switch t {
 	case HashIndexType:
@@ -117,6 +191,20 @@ func (r *Relation) createIndex(name string, t IndexType, attrs []string) error {
 			}
 		}
 		i := NewHashIndex(name, r.name, r.attributes, attrs, attrsIdx)
+		i.unique = unique
+		for e := r.rows.Front(); e != nil; e = e.Next() {
+			if unique {
+				vals := i.values(e.Value.(*Tuple))
+				ok, err := i.conflicts(vals)
+				if err != nil {
+					return err
+				}
+				if ok {
+					return NewError(UniqueViolation, "cannot create unique index %s, %v is duplicated", name, vals)
+				}
+			}
+			i.Add(e)
+		}
 		r.indexes = append(r.indexes, i)
 		return nil
 	case BTreeIndexType:
@@ -171,10 +259,8 @@ func (r *Relation) hasIndexOn(attrs []string) bool {
 	return false
 }
 
+// Truncate removes all rows. Relation must be locked.
 func (r *Relation) Truncate() int64 {
-	r.Lock()
-	defer r.Unlock()
-
 	l := r.rows.Len()
 
 	for _, i := range r.indexes {
@@ -182,10 +268,29 @@ func (r *Relation) Truncate() int64 {
 	}
 
 	r.rows = list.New()
+	r.size.reset()
 
 	return int64(l)
 }
 
+// refresh rebuilds rows of a virtual relation. Relation must be locked.
+func (r *Relation) refresh() {
+	for _, i := range r.indexes {
+		i.Truncate()
+	}
+	r.rows = list.New()
+	r.size.reset()
+
+	for _, values := range r.virtual() {
+		t := NewTuple(values...)
+		e := r.rows.PushBack(t)
+		r.size.add(t)
+		for _, i := range r.indexes {
+			i.Add(e)
+		}
+	}
+}
+
 func (r *Relation) String() string {
 	if r.schema != "" {
 		return r.schema + "." + r.name
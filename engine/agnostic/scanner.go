@@ -28,32 +28,11 @@ func (s *RelationScanner) Append(p Predicate) {
 }
 
 func (s *RelationScanner) Exec() ([]string, []*list.Element, error) {
-	var ok bool
-	var err error
-	var res []*list.Element
-	var canAppend bool
-
-	cols := s.src.Columns()
-	for s.src.HasNext() {
-		t := s.src.Next()
-		tup := t.Value.(*Tuple)
-		canAppend = true
-		for _, p := range s.predicates {
-			ok, err = p.Eval(cols, tup)
-			if err != nil {
-				return nil, nil, fmt.Errorf("RelationScanner.Exec: %s(%v) : %w", p, t, err)
-			}
-			if !ok {
-				canAppend = false
-				break
-			}
-		}
-		if canAppend {
-			res = append(res, t)
-		}
+	it, err := s.Iter()
+	if err != nil {
+		return nil, nil, err
 	}
-
-	return cols, res, nil
+	return drain(it)
 }
 
 // No idea on how to estimate cardinal of scanner given predicates
@@ -1,7 +1,6 @@
 package agnostic
 
 import (
-	"fmt"
 	"sync"
 )
 
@@ -28,7 +27,7 @@ func (s *Schema) Relation(name string) (*Relation, error) {
 	r, ok := s.relations[name]
 	if !ok {
 		//	panic("lol")
-		return nil, fmt.Errorf("relation '%s'.'%s' does not exist", s.name, name)
+		return nil, NewError(UndefinedTable, "relation '%s'.'%s' does not exist", s.name, name)
 	}
 
 	return r, nil
@@ -48,7 +47,7 @@ func (s *Schema) Remove(name string) (*Relation, error) {
 	r, ok := s.relations[name]
 	if !ok {
 		//		panic("remove")
-		return nil, fmt.Errorf("relation '%s'.'%s' does not exist", s.name, name)
+		return nil, NewError(UndefinedTable, "relation '%s'.'%s' does not exist", s.name, name)
 	}
 
 	delete(s.relations, name)
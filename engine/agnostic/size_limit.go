@@ -0,0 +1,182 @@
+package agnostic
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Limits bounds the size of an engine or a relation. Zero values mean unlimited.
+//
+// Writes exceeding a limit fail with a disk full error (SQLSTATE 53100).
+type Limits struct {
+	// MaxRows is the maximum number of rows
+	MaxRows int64
+	// MaxBytes is the maximum approximate size of rows, in bytes
+	MaxBytes int64
+	// MaxRelations is the maximum number of relations, only relevant at engine level
+	MaxRelations int
+}
+
+// relationSize keeps track of a relation size, so engine totals can be computed without locking relations.
+type relationSize struct {
+	rows  atomic.Int64
+	bytes atomic.Int64
+}
+
+func (s *relationSize) add(t *Tuple) {
+	s.rows.Add(1)
+	s.bytes.Add(tupleSize(t))
+}
+
+func (s *relationSize) remove(t *Tuple) {
+	s.rows.Add(-1)
+	s.bytes.Add(-tupleSize(t))
+}
+
+func (s *relationSize) reset() {
+	s.rows.Store(0)
+	s.bytes.Store(0)
+}
+
+// tupleSize returns the approximate memory footprint of a tuple
+func tupleSize(t *Tuple) int64 {
+	// list element and slice headers
+	size := int64(64)
+
+	for _, v := range t.values {
+		size += valueSize(v)
+	}
+
+	return size
+}
+
+func valueSize(v any) int64 {
+	switch val := v.(type) {
+	case string:
+		return 16 + int64(len(val))
+	case []byte:
+		return 24 + int64(len(val))
+	case time.Time:
+		return 24
+	case bool:
+		return 1
+	case JSON:
+		return 16 + int64(len(val.text))
+	}
+
+	// slice header and elements of arrays
+	if values, ok := ArrayValues(v); ok {
+		size := int64(24)
+		for _, e := range values {
+			size += valueSize(e)
+		}
+		return size
+	}
+	return 8
+}
+
+// SetLimits sets engine wide limits.
+func (e *Engine) SetLimits(l Limits) {
+	e.Lock()
+	defer e.Unlock()
+
+	e.limits = l
+}
+
+// Limits returns engine wide limits.
+func (e *Engine) Limits() Limits {
+	e.Lock()
+	defer e.Unlock()
+
+	return e.limits
+}
+
+// SetRelationLimits sets limits of given relation. MaxRelations is ignored.
+func (e *Engine) SetRelationLimits(schema, relation string, l Limits) error {
+	s, err := e.schema(schema)
+	if err != nil {
+		return err
+	}
+	r, err := s.Relation(relation)
+	if err != nil {
+		return err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.limits = l
+	return nil
+}
+
+// usage returns the number of relations, rows and bytes used by user relations.
+//
+// information_schema and virtual relations are not accounted.
+func (e *Engine) usage() (relations int, rows int64, bytes int64) {
+	for name, s := range e.schemas {
+		if name == "information_schema" {
+			continue
+		}
+		s.RLock()
+		for _, r := range s.relations {
+			if r.virtual != nil {
+				continue
+			}
+			relations++
+			rows += r.size.rows.Load()
+			bytes += r.size.bytes.Load()
+		}
+		s.RUnlock()
+	}
+
+	return
+}
+
+// checkRelationLimit returns an error if engine cannot hold another relation.
+func (t *Transaction) checkRelationLimit(schema string) error {
+	if schema == "information_schema" {
+		return nil
+	}
+
+	l := t.e.Limits()
+	if l.MaxRelations <= 0 {
+		return nil
+	}
+
+	relations, _, _ := t.e.usage()
+	if relations >= l.MaxRelations {
+		return NewError(DiskFull, "could not create relation: maximum number of relations (%d) reached", l.MaxRelations)
+	}
+
+	return nil
+}
+
+// checkSizeLimits returns an error if writing rows and bytes more in r would exceed relation or engine limits.
+// Relation must be locked.
+func (t *Transaction) checkSizeLimits(r *Relation, rows int64, bytes int64) error {
+	if r.schema == "information_schema" {
+		return nil
+	}
+
+	rl := r.limits
+	if rl.MaxRows > 0 && rows > 0 && r.size.rows.Load()+rows > rl.MaxRows {
+		return NewError(DiskFull, "could not extend relation %s: maximum rows (%d) reached", r, rl.MaxRows)
+	}
+	if rl.MaxBytes > 0 && bytes > 0 && r.size.bytes.Load()+bytes > rl.MaxBytes {
+		return NewError(DiskFull, "could not extend relation %s: maximum size (%d bytes) reached", r, rl.MaxBytes)
+	}
+
+	el := t.e.Limits()
+	if el.MaxRows <= 0 && el.MaxBytes <= 0 {
+		return nil
+	}
+	_, totalRows, totalBytes := t.e.usage()
+	if el.MaxRows > 0 && rows > 0 && totalRows+rows > el.MaxRows {
+		return NewError(DiskFull, "could not extend relation %s: database maximum rows (%d) reached", r, el.MaxRows)
+	}
+	if el.MaxBytes > 0 && bytes > 0 && totalBytes+bytes > el.MaxBytes {
+		return NewError(DiskFull, "could not extend relation %s: database maximum size (%d bytes) reached", r, el.MaxBytes)
+	}
+
+	return nil
+}
@@ -70,6 +70,10 @@ type SeqScanSrc struct {
 	card  int64
 	rname string
 	cols  []string
+
+	// rows present when the scan was frozen, see freeze
+	rows   []*list.Element
+	frozen bool
 }
 
 func NewSeqScan(r *Relation, alias string) *SeqScanSrc {
@@ -92,10 +96,21 @@ func (s SeqScanSrc) String() string {
 }
 
 func (s *SeqScanSrc) HasNext() bool {
+	if s.frozen {
+		return len(s.rows) > 0
+	}
     return s.e != nil
 }
 
 func (s *SeqScanSrc) Next() *list.Element {
+	if s.frozen {
+		if len(s.rows) == 0 {
+			return nil
+		}
+		t := s.rows[0]
+		s.rows = s.rows[1:]
+		return t
+	}
 	if s.e == nil {
 		return nil
 	}
@@ -104,6 +119,16 @@ func (s *SeqScanSrc) Next() *list.Element {
 	return t
 }
 
+// freeze makes the scan return rows of the relation as they are now. Tuples are
+// never modified in place, so frozen rows can be read once the relation is unlocked.
+func (s *SeqScanSrc) freeze() {
+	for e := s.e; e != nil; e = e.Next() {
+		s.rows = append(s.rows, e)
+	}
+	s.e = nil
+	s.frozen = true
+}
+
 func (s *SeqScanSrc) EstimateCardinal() int64 {
 	return s.card
 }
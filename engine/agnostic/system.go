@@ -0,0 +1,527 @@
+package agnostic
+
+import (
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// systemOwner owns every object listed in system relations
+const systemOwner = "ramsql"
+
+// systemRelation is a virtual relation describing engine metadata, as PostgreSQL
+// pg_catalog and information_schema do. Rows are built from the catalog each
+// time the relation is accessed, so they are always consistent with the engine.
+type systemRelation struct {
+	schema     string
+	name       string
+	attributes []Attribute
+	rows       func(c *systemCatalog) [][]any
+}
+
+var systemRelations = []systemRelation{
+	{"pg_catalog", "pg_namespace", attributes("oid bigint", "nspname text", "nspowner bigint"), pgNamespace},
+	{"pg_catalog", "pg_class", attributes("oid bigint", "relname text", "relnamespace bigint", "relkind text", "relnatts bigint", "relhasindex boolean", "relpersistence text"), pgClass},
+	{"pg_catalog", "pg_tables", attributes("schemaname text", "tablename text", "tableowner text", "tablespace text", "hasindexes boolean", "hasrules boolean", "hastriggers boolean"), pgTables},
+	{"pg_catalog", "pg_attribute", attributes("attrelid bigint", "attname text", "atttypid bigint", "attnum bigint", "attnotnull boolean", "atthasdef boolean", "attisdropped boolean"), pgAttribute},
+	{"pg_catalog", "pg_index", attributes("indexrelid bigint", "indrelid bigint", "indnatts bigint", "indisunique boolean", "indisprimary boolean", "indkey text"), pgIndex},
+	{"pg_catalog", "pg_indexes", attributes("schemaname text", "tablename text", "indexname text", "tablespace text", "indexdef text"), pgIndexes},
+	{"pg_catalog", "pg_type", attributes("oid bigint", "typname text", "typnamespace bigint", "typlen bigint", "typtype text"), pgTypeRows},
+	{"pg_catalog", "pg_constraint", attributes("oid bigint", "conname text", "connamespace bigint", "contype text", "conrelid bigint", "confrelid bigint", "conkey text", "confkey text", "confdeltype text"), pgConstraint},
+	{"information_schema", "schemata", attributes("catalog_name text", "schema_name text", "schema_owner text"), infoSchemata},
+	{"information_schema", "tables", attributes("table_catalog text", "table_schema text", "table_name text", "table_type text"), infoTables},
+	{"information_schema", "columns", attributes("table_catalog text", "table_schema text", "table_name text", "column_name text", "ordinal_position bigint", "column_default text", "is_nullable text", "data_type text", "character_maximum_length bigint", "numeric_precision bigint", "numeric_scale bigint", "udt_name text", "numeric_precision_radix bigint", "datetime_precision bigint", "identity_increment text"), infoColumns},
+	{"information_schema", "table_constraints", attributes("constraint_catalog text", "constraint_schema text", "constraint_name text", "table_catalog text", "table_schema text", "table_name text", "constraint_type text"), infoTableConstraints},
+	{"information_schema", "key_column_usage", attributes("constraint_catalog text", "constraint_schema text", "constraint_name text", "table_catalog text", "table_schema text", "table_name text", "column_name text", "ordinal_position bigint", "position_in_unique_constraint bigint"), infoKeyColumnUsage},
+	{"information_schema", "referential_constraints", attributes("constraint_catalog text", "constraint_schema text", "constraint_name text", "unique_constraint_catalog text", "unique_constraint_schema text", "unique_constraint_name text", "match_option text", "update_rule text", "delete_rule text"), infoReferentialConstraints},
+}
+
+// attributes returns attributes declared as "name type"
+func attributes(defs ...string) []Attribute {
+	attrs := make([]Attribute, len(defs))
+	for i, def := range defs {
+		name, typeName, _ := strings.Cut(def, " ")
+		attrs[i] = NewAttribute(name, typeName)
+	}
+	return attrs
+}
+
+// createSystemRelations creates pg_catalog and information_schema virtual relations
+func (e *Engine) createSystemRelations() error {
+	for _, sr := range systemRelations {
+		rows := sr.rows
+		err := e.CreateVirtualRelation(sr.schema, sr.name, sr.attributes, func() [][]any {
+			return rows(e.systemCatalog())
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetCatalogName sets the database name reported by information_schema relations.
+func (e *Engine) SetCatalogName(name string) {
+	e.Lock()
+	defer e.Unlock()
+
+	e.catalogName = name
+}
+
+// systemCatalog is a snapshot of engine metadata
+type systemCatalog struct {
+	name      string
+	schemas   []string
+	relations []RelationInfo
+	byName    map[string]*RelationInfo
+}
+
+func (e *Engine) systemCatalog() *systemCatalog {
+	c := &systemCatalog{
+		name:      e.catalogName,
+		relations: e.Catalog(),
+		byName:    make(map[string]*RelationInfo),
+	}
+
+	for name := range e.schemas {
+		c.schemas = append(c.schemas, name)
+	}
+	sort.Strings(c.schemas)
+
+	for i := range c.relations {
+		ri := &c.relations[i]
+		c.byName[ri.Schema+"."+ri.Name] = ri
+	}
+
+	return c
+}
+
+// oid returns a stable object identifier for the object named by names
+func oid(names ...string) int64 {
+	h := fnv.New32a()
+	h.Write([]byte(strings.Join(names, ".")))
+	return int64(h.Sum32())
+}
+
+// attnum returns the position of attribute name in ri, starting at 1, or 0 if not found
+func attnum(ri *RelationInfo, name string) int64 {
+	for i, c := range ri.Columns {
+		if c.Name == name {
+			return int64(i + 1)
+		}
+	}
+	return 0
+}
+
+// referenced returns the relation and the attributes referenced by foreign key constraint con
+func (c *systemCatalog) referenced(con ConstraintInfo) (*RelationInfo, []string) {
+	ref := c.byName[con.RefSchema+"."+con.RefRelation]
+	if len(con.RefColumns) > 0 || ref == nil {
+		return ref, con.RefColumns
+	}
+	return ref, ref.PrimaryKey
+}
+
+// indexName returns the name of index ii of ri as PostgreSQL names it:
+// the indexes of PRIMARY KEY and UNIQUE constraints are named after the constraint.
+func indexName(ri *RelationInfo, ii IndexInfo) string {
+	switch {
+	case ii.Primary:
+		return ri.Name + "_pkey"
+	case ii.Unique:
+		for _, con := range ri.Constraints {
+			if con.Type == "UNIQUE" && strings.Join(con.Columns, ",") == strings.Join(ii.Columns, ",") {
+				return con.Name
+			}
+		}
+	}
+	return ii.Name
+}
+
+func pgNamespace(c *systemCatalog) [][]any {
+	var rows [][]any
+	for _, s := range c.schemas {
+		rows = append(rows, []any{oid(s), s, oid(systemOwner)})
+	}
+	return rows
+}
+
+func pgClass(c *systemCatalog) [][]any {
+	var rows [][]any
+	for i := range c.relations {
+		ri := &c.relations[i]
+		kind := "r"
+		if ri.Virtual {
+			kind = "v"
+		}
+		rows = append(rows, []any{oid(ri.Schema, ri.Name), ri.Name, oid(ri.Schema), kind, int64(len(ri.Columns)), len(ri.Indexes) > 0, "p"})
+		for _, ii := range ri.Indexes {
+			rows = append(rows, []any{oid(ri.Schema, ri.Name, ii.Name), indexName(ri, ii), oid(ri.Schema), "i", int64(len(ii.Columns)), false, "p"})
+		}
+	}
+	return rows
+}
+
+func pgTables(c *systemCatalog) [][]any {
+	var rows [][]any
+	for _, ri := range c.relations {
+		if ri.Virtual {
+			continue
+		}
+		rows = append(rows, []any{ri.Schema, ri.Name, systemOwner, nil, len(ri.Indexes) > 0, false, false})
+	}
+	return rows
+}
+
+func pgAttribute(c *systemCatalog) [][]any {
+	var rows [][]any
+	for _, ri := range c.relations {
+		for i, col := range ri.Columns {
+			typid, _, _ := pgType(col)
+			rows = append(rows, []any{oid(ri.Schema, ri.Name), col.Name, typid, int64(i + 1), !col.Nullable, col.HasDefault || col.DefaultNow || col.AutoIncrement, false})
+		}
+	}
+	return rows
+}
+
+func pgIndex(c *systemCatalog) [][]any {
+	var rows [][]any
+	for i := range c.relations {
+		ri := &c.relations[i]
+		for _, ii := range ri.Indexes {
+			var keys []string
+			for _, col := range ii.Columns {
+				keys = append(keys, fmt.Sprint(attnum(ri, col)))
+			}
+			rows = append(rows, []any{oid(ri.Schema, ri.Name, ii.Name), oid(ri.Schema, ri.Name), int64(len(ii.Columns)), ii.Unique, ii.Primary, strings.Join(keys, " ")})
+		}
+	}
+	return rows
+}
+
+func pgIndexes(c *systemCatalog) [][]any {
+	var rows [][]any
+	for i := range c.relations {
+		ri := &c.relations[i]
+		for _, ii := range ri.Indexes {
+			name := indexName(ri, ii)
+			def := "CREATE INDEX "
+			if ii.Unique {
+				def = "CREATE UNIQUE INDEX "
+			}
+			def += fmt.Sprintf("%s ON %s.%s USING hash (%s)", name, ri.Schema, ri.Name, strings.Join(ii.Columns, ", "))
+			rows = append(rows, []any{ri.Schema, ri.Name, name, nil, def})
+		}
+	}
+	return rows
+}
+
+func pgConstraint(c *systemCatalog) [][]any {
+	var rows [][]any
+	for i := range c.relations {
+		ri := &c.relations[i]
+		for _, con := range ri.Constraints {
+			var keys []string
+			for _, col := range con.Columns {
+				keys = append(keys, fmt.Sprint(attnum(ri, col)))
+			}
+			row := []any{oid(ri.Schema, ri.Name, con.Name), con.Name, oid(ri.Schema), "", oid(ri.Schema, ri.Name), int64(0), "{" + strings.Join(keys, ",") + "}", nil, " "}
+			switch con.Type {
+			case "PRIMARY KEY":
+				row[3] = "p"
+			case "UNIQUE":
+				row[3] = "u"
+			case "FOREIGN KEY":
+				row[3] = "f"
+				row[5] = oid(con.RefSchema, con.RefRelation)
+				row[8] = deleteType(con.OnDelete)
+				if ref, cols := c.referenced(con); ref != nil {
+					var fkeys []string
+					for _, col := range cols {
+						fkeys = append(fkeys, fmt.Sprint(attnum(ref, col)))
+					}
+					row[7] = "{" + strings.Join(fkeys, ",") + "}"
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// deleteType returns the pg_constraint code of ON DELETE action
+func deleteType(action string) string {
+	switch action {
+	case "CASCADE":
+		return "c"
+	case "RESTRICT":
+		return "r"
+	case "SET NULL":
+		return "n"
+	case "SET DEFAULT":
+		return "d"
+	}
+	return "a"
+}
+
+func infoSchemata(c *systemCatalog) [][]any {
+	var rows [][]any
+	for _, s := range c.schemas {
+		rows = append(rows, []any{c.name, s, systemOwner})
+	}
+	return rows
+}
+
+func infoTables(c *systemCatalog) [][]any {
+	var rows [][]any
+	for _, ri := range c.relations {
+		kind := "BASE TABLE"
+		if ri.Virtual {
+			kind = "VIEW"
+		}
+		rows = append(rows, []any{c.name, ri.Schema, ri.Name, kind})
+	}
+	return rows
+}
+
+func infoColumns(c *systemCatalog) [][]any {
+	var rows [][]any
+	for _, ri := range c.relations {
+		for i, col := range ri.Columns {
+			_, udt, dataType := pgType(col)
+			nullable := "YES"
+			if !col.Nullable {
+				nullable = "NO"
+			}
+			var length, precision, scale any
+			if col.HasLength {
+				length = col.Length
+			}
+			if col.HasPrecision {
+				precision, scale = col.Precision, col.Scale
+			}
+			var radix, datetimePrecision any
+			switch udt {
+			case "int8":
+				precision, scale, radix = int64(64), int64(0), int64(2)
+			case "int4":
+				precision, scale, radix = int64(32), int64(0), int64(2)
+			case "int2":
+				precision, scale, radix = int64(16), int64(0), int64(2)
+			case "float8":
+				precision, radix = int64(53), int64(2)
+			case "float4":
+				precision, radix = int64(24), int64(2)
+			case "numeric":
+				if precision != nil {
+					radix = int64(10)
+				}
+			case "timestamp", "timestamptz":
+				datetimePrecision = int64(6)
+			case "date":
+				datetimePrecision = int64(0)
+			}
+			rows = append(rows, []any{c.name, ri.Schema, ri.Name, col.Name, int64(i + 1), columnDefault(ri, col), nullable, dataType, length, precision, scale, udt, radix, datetimePrecision, nil})
+		}
+	}
+	return rows
+}
+
+func infoTableConstraints(c *systemCatalog) [][]any {
+	var rows [][]any
+	for _, ri := range c.relations {
+		for _, con := range ri.Constraints {
+			rows = append(rows, []any{c.name, ri.Schema, con.Name, c.name, ri.Schema, ri.Name, con.Type})
+		}
+	}
+	return rows
+}
+
+func infoKeyColumnUsage(c *systemCatalog) [][]any {
+	var rows [][]any
+	for _, ri := range c.relations {
+		for _, con := range ri.Constraints {
+			for i, col := range con.Columns {
+				var position any
+				if con.Type == "FOREIGN KEY" {
+					position = int64(i + 1)
+				}
+				rows = append(rows, []any{c.name, ri.Schema, con.Name, c.name, ri.Schema, ri.Name, col, int64(i + 1), position})
+			}
+		}
+	}
+	return rows
+}
+
+func infoReferentialConstraints(c *systemCatalog) [][]any {
+	var rows [][]any
+	for _, ri := range c.relations {
+		for _, con := range ri.Constraints {
+			if con.Type != "FOREIGN KEY" {
+				continue
+			}
+			var unique any
+			if ref, cols := c.referenced(con); ref != nil {
+				for _, rc := range ref.Constraints {
+					if (rc.Type == "PRIMARY KEY" || rc.Type == "UNIQUE") && strings.Join(rc.Columns, ",") == strings.Join(cols, ",") {
+						unique = rc.Name
+						break
+					}
+				}
+			}
+			rows = append(rows, []any{c.name, ri.Schema, con.Name, c.name, con.RefSchema, unique, "NONE", "NO ACTION", con.OnDelete})
+		}
+	}
+	return rows
+}
+
+// pgType returns the PostgreSQL type OID, type name and SQL standard type name of col,
+// from its declared type or its storage type if not a PostgreSQL type.
+// pgTypes are the types listed in pg_type, with their length in bytes, or -1 for variable length types
+var pgTypes = []struct {
+	oid  int64
+	name string
+	len  int64
+}{
+	{16, "bool", 1},
+	{17, "bytea", -1},
+	{20, "int8", 8},
+	{21, "int2", 2},
+	{23, "int4", 4},
+	{25, "text", -1},
+	{114, "json", -1},
+	{700, "float4", 4},
+	{701, "float8", 8},
+	{1042, "bpchar", -1},
+	{1043, "varchar", -1},
+	{1082, "date", 4},
+	{1114, "timestamp", 8},
+	{1184, "timestamptz", 8},
+	{1700, "numeric", -1},
+	{2950, "uuid", 16},
+	{3802, "jsonb", -1},
+}
+
+// pgArrayTypes maps oids of pgTypes to the oid of their array type
+var pgArrayTypes = map[int64]int64{
+	16:   1000,
+	17:   1001,
+	20:   1016,
+	21:   1005,
+	23:   1007,
+	25:   1009,
+	114:  199,
+	700:  1021,
+	701:  1022,
+	1042: 1014,
+	1043: 1015,
+	1082: 1182,
+	1114: 1115,
+	1184: 1185,
+	1700: 1231,
+	2950: 2951,
+	3802: 3807,
+}
+
+func pgTypeRows(c *systemCatalog) [][]any {
+	var rows [][]any
+	for _, t := range pgTypes {
+		rows = append(rows, []any{t.oid, t.name, oid("pg_catalog"), t.len, "b"})
+	}
+	for _, t := range pgTypes {
+		rows = append(rows, []any{pgArrayTypes[t.oid], "_" + t.name, oid("pg_catalog"), int64(-1), "b"})
+	}
+	return rows
+}
+
+func pgType(col ColumnInfo) (int64, string, string) {
+	if IsArrayType(col.DeclaredType) {
+		elem := col
+		elem.DeclaredType = ArrayElemType(col.DeclaredType)
+		if col.ScanType != nil && col.ScanType.Kind() == reflect.Slice {
+			elem.ScanType = col.ScanType.Elem()
+		}
+		oid, name, _ := pgType(elem)
+		return pgArrayTypes[oid], "_" + name, "ARRAY"
+	}
+
+	switch strings.ToLower(col.DeclaredType) {
+	case "smallint", "int2":
+		return 21, "int2", "smallint"
+	case "int", "integer", "int4", "serial", "mediumint":
+		return 23, "int4", "integer"
+	case "bigint", "int8", "bigserial":
+		return 20, "int8", "bigint"
+	case "bool", "boolean":
+		return 16, "bool", "boolean"
+	case "decimal", "numeric":
+		return 1700, "numeric", "numeric"
+	case "float", "float8", "double":
+		return 701, "float8", "double precision"
+	case "real", "float4":
+		return 700, "float4", "real"
+	case "varchar":
+		return 1043, "varchar", "character varying"
+	case "char", "character", "bpchar":
+		return 1042, "bpchar", "character"
+	case "text":
+		return 25, "text", "text"
+	case "timestamp", "datetime":
+		return 1114, "timestamp", "timestamp without time zone"
+	case "timestamptz":
+		return 1184, "timestamptz", "timestamp with time zone"
+	case "date":
+		return 1082, "date", "date"
+	case "json":
+		return 114, "json", "json"
+	case "jsonb":
+		return 3802, "jsonb", "jsonb"
+	case "uuid":
+		return 2950, "uuid", "uuid"
+	case "bytea":
+		return 17, "bytea", "bytea"
+	}
+
+	if col.ScanType != nil {
+		switch col.ScanType.Kind() {
+		case reflect.Int64:
+			return 20, "int8", "bigint"
+		case reflect.Float64:
+			return 701, "float8", "double precision"
+		case reflect.Bool:
+			return 16, "bool", "boolean"
+		}
+		if col.ScanType == reflect.TypeOf(time.Time{}) {
+			return 1114, "timestamp", "timestamp without time zone"
+		}
+	}
+	return 25, "text", "text"
+}
+
+// columnDefault returns the default value expression of col, as PostgreSQL formats it, or nil
+func columnDefault(ri RelationInfo, col ColumnInfo) any {
+	switch {
+	case col.DefaultNow:
+		return "now()"
+	case col.AutoIncrement:
+		return fmt.Sprintf("nextval('%s_%s_seq'::regclass)", ri.Name, col.Name)
+	case !col.HasDefault:
+		return nil
+	}
+
+	switch v := col.Default.(type) {
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'::" + strings.ToLower(col.DeclaredType)
+	case time.Time:
+		return "'" + v.Format("2006-01-02 15:04:05.999999") + "'::" + strings.ToLower(col.DeclaredType)
+	case JSON:
+		return "'" + strings.ReplaceAll(v.Text(), "'", "''") + "'::" + strings.ToLower(col.DeclaredType)
+	}
+	if a, ok := FormatArray(col.Default); ok {
+		return "'" + strings.ReplaceAll(a, "'", "''") + "'::" + strings.ToLower(col.DeclaredType)
+	}
+	return fmt.Sprint(col.Default)
+}
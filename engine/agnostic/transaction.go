@@ -12,19 +12,27 @@ import (
 )
 
 type Transaction struct {
-	e     *Engine
-	locks map[string]*Relation
+	e *Engine
+	// relations locked by the transaction. Relations are replaced when altered,
+	// so they are identified by pointer rather than by name
+	locks map[*Relation]struct{}
 
 	// list of Change
 	changes *list.List
 
 	err error
+
+	// searchPath overrides the engine search path, see SetSearchPath
+	searchPath []string
+
+	// snapshot is set by Snapshot
+	snapshot bool
 }
 
 func NewTransaction(e *Engine) (*Transaction, error) {
 	t := Transaction{
 		e:       e,
-		locks:   make(map[string]*Relation),
+		locks:   make(map[*Relation]struct{}),
 		changes: list.New(),
 	}
 
@@ -38,6 +46,14 @@ func (t *Transaction) Commit() (int, error) {
 
 	changed := t.changes.Len()
 
+	// Write changes to the write-ahead log before releasing locks,
+	// so concurrent transactions are logged in commit order
+	if err := t.e.logChanges(t.changes); err != nil {
+		t.Rollback()
+		t.err = err
+		return 0, err
+	}
+
 	// Remove links to be GC'd faster
 	for {
 		b := t.changes.Back()
@@ -57,6 +73,7 @@ func (t *Transaction) Rollback() {
 		return
 	}
 
+	moved := make(map[*list.Element]*list.Element)
 	for {
 		b := t.changes.Back()
 		if b == nil {
@@ -65,7 +82,10 @@ func (t *Transaction) Rollback() {
 		switch b.Value.(type) {
 		case ValueChange:
 			c := b.Value.(ValueChange)
-			t.rollbackValueChange(c)
+			t.rollbackValueChange(c, moved)
+		case *ValueChange:
+			c := b.Value.(*ValueChange)
+			t.rollbackValueChange(*c, moved)
 		case RelationChange:
 			c := b.Value.(RelationChange)
 			t.rollbackRelationChange(c)
@@ -74,9 +94,16 @@ func (t *Transaction) Rollback() {
 	}
 
 	t.unlock()
+	t.err = fmt.Errorf("transaction rolled back")
+}
+
+// Snapshot makes queries read rows as they are when planned. Cursors of a
+// transaction without changes then don't keep queried relations locked.
+func (t *Transaction) Snapshot() {
+	t.snapshot = true
 }
 
-func (t Transaction) Error() error {
+func (t *Transaction) Error() error {
 	return t.err
 }
 
@@ -90,7 +117,7 @@ func (t *Transaction) Truncate(schema, relation string) (int64, error) {
 		return 0, err
 	}
 
-	s, err := t.e.schema(schema)
+	s, err := t.schema(schema, relation)
 	if err != nil {
 		return 0, err
 	}
@@ -99,8 +126,13 @@ func (t *Transaction) Truncate(schema, relation string) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
+	if r.virtual != nil {
+		return 0, fmt.Errorf("relation %s is read-only", r)
+	}
 
+	t.lock(r)
 	c := r.Truncate()
+	t.changes.PushBack(TruncateChange{rel: r})
 
 	return c, nil
 }
@@ -110,7 +142,7 @@ func (t *Transaction) RelationAttribute(schName, relName, attrName string) (int,
 		return 0, Attribute{}, err
 	}
 
-	s, err := t.e.schema(schName)
+	s, err := t.schema(schName, relName)
 	if err != nil {
 		return 0, Attribute{}, err
 	}
@@ -123,9 +155,54 @@ func (t *Transaction) RelationAttribute(schName, relName, attrName string) (int,
 	return r.Attribute(attrName)
 }
 
-// CurrentSchema returns the first schema in the search path
+// SetSearchPath sets the schemas where unqualified relations are looked up, in order.
+// The engine search path is used if path is empty.
+func (t *Transaction) SetSearchPath(path []string) {
+	t.searchPath = path
+}
+
+// SearchPath returns the schemas where unqualified relations are looked up, in order.
+func (t *Transaction) SearchPath() []string {
+	if len(t.searchPath) == 0 {
+		return []string{t.e.CurrentSchema()}
+	}
+	return t.searchPath
+}
+
+// CurrentSchema returns the first existing schema in the search path,
+// where unqualified relations are created.
 func (t *Transaction) CurrentSchema() string {
-	return t.e.CurrentSchema()
+	path := t.SearchPath()
+	for _, name := range path {
+		if _, ok := t.e.schemas[name]; ok {
+			return name
+		}
+	}
+	return path[0]
+}
+
+// resolve returns schema, or if empty the first schema of the search path
+// holding relation, then pg_catalog, defaulting to the current schema.
+func (t *Transaction) resolve(schema, relation string) string {
+	if schema != "" {
+		return schema
+	}
+	path := t.SearchPath()
+	for _, name := range append(path[:len(path):len(path)], "pg_catalog") {
+		s, ok := t.e.schemas[name]
+		if !ok {
+			continue
+		}
+		if _, err := s.Relation(relation); err == nil {
+			return name
+		}
+	}
+	return t.CurrentSchema()
+}
+
+// schema returns the schema named name, resolved from the search path if empty, see resolve.
+func (t *Transaction) schema(name, relation string) (*Schema, error) {
+	return t.e.schema(t.resolve(name, relation))
 }
 
 func (t *Transaction) CheckRelation(schemaName, relName string) bool {
@@ -133,7 +210,7 @@ func (t *Transaction) CheckRelation(schemaName, relName string) bool {
 		return false
 	}
 
-	s, err := t.e.schema(schemaName)
+	s, err := t.schema(schemaName, relName)
 	if err != nil {
 		return false
 	}
@@ -147,6 +224,14 @@ func (t *Transaction) CreateRelation(schemaName, relName string, attributes []At
 		return err
 	}
 
+	if schemaName == "" {
+		schemaName = t.CurrentSchema()
+	}
+
+	if err := t.checkRelationLimit(schemaName); err != nil {
+		return t.abort(err)
+	}
+
 	s, r, err := t.e.createRelation(schemaName, relName, attributes, pk)
 	if err != nil {
 		return t.abort(err)
@@ -161,27 +246,6 @@ func (t *Transaction) CreateRelation(schemaName, relName string, attributes []At
 	log.Debug("CreateRelation(%s,%s,%s,%s)", schemaName, relName, attributes, pk)
 
 	t.lock(r)
-
-	// maintain information_schema.tables so external tools (eg. GORM) can query table existence
-	// insert a row into information_schema.tables: table_schema, table_name, table_type
-	// Use default schema if empty
-	sch := schemaName
-	if sch == "" {
-		sch = DefaultSchema
-	}
-	// best-effort: if information_schema exists, insert a metadata row via Transaction.Insert
-	if t.CheckSchema("information_schema") {
-		vals := map[string]any{
-			"table_schema": sch,
-			"table_name":   relName,
-			"table_type":   "BASE TABLE",
-		}
-		_, err := t.Insert("information_schema", "tables", vals)
-		if err != nil {
-			// do not fail relation creation because of metadata insertion; just log
-			log.Warn("could not update information_schema.tables: %s", err)
-		}
-	}
 	return nil
 }
 
@@ -190,6 +254,7 @@ func (t *Transaction) DropRelation(schemaName, relName string) error {
 		return err
 	}
 
+	schemaName = t.resolve(schemaName, relName)
 	s, r, err := t.e.dropRelation(schemaName, relName)
 	if err != nil {
 		return t.abort(err)
@@ -202,23 +267,6 @@ func (t *Transaction) DropRelation(schemaName, relName string) error {
 	}
 	t.changes.PushBack(c)
 
-	// remove metadata from information_schema.tables if present
-	sch := schemaName
-	if sch == "" {
-		sch = DefaultSchema
-	}
-	if t.CheckSchema("information_schema") {
-		// build predicate: table_schema = sch AND table_name = relName
-		left := NewEqPredicate(NewAttributeValueFunctor("tables", "table_schema"), NewConstValueFunctor(sch))
-		right := NewEqPredicate(NewAttributeValueFunctor("tables", "table_name"), NewConstValueFunctor(relName))
-		p := NewAndPredicate(left, right)
-		// selectors can be nil for Delete
-		_, _, err := t.Delete("information_schema", "tables", nil, p)
-		if err != nil {
-			log.Warn("could not remove information_schema.tables entry for %s.%s: %s", sch, relName, err)
-		}
-	}
-
 	return nil
 }
 
@@ -273,11 +321,20 @@ func (t *Transaction) DropSchema(schemaName string) error {
 }
 
 func (t *Transaction) CreateIndex(schema, relation, index string, it IndexType, attrs []string) error {
+	return t.createIndex(schema, relation, index, it, attrs, false)
+}
+
+// CreateUniqueIndex creates an index rejecting rows holding the same non NULL values as an existing row
+func (t *Transaction) CreateUniqueIndex(schema, relation, index string, it IndexType, attrs []string) error {
+	return t.createIndex(schema, relation, index, it, attrs, true)
+}
+
+func (t *Transaction) createIndex(schema, relation, index string, it IndexType, attrs []string, unique bool) error {
 	if err := t.aborted(); err != nil {
 		return err
 	}
 
-	s, err := t.e.schema(schema)
+	s, err := t.schema(schema, relation)
 	if err != nil {
 		return err
 	}
@@ -289,10 +346,11 @@ func (t *Transaction) CreateIndex(schema, relation, index string, it IndexType,
 
 	t.lock(r)
 
-	err = r.createIndex(index, it, attrs)
+	err = r.createIndex(index, it, attrs, unique)
 	if err != nil {
 		return err
 	}
+	t.changes.PushBack(IndexChange{rel: r, name: index, t: it, attrs: attrs, unique: unique})
 	log.Debug("CreateIndex(%s, %s, %s, %s)", schema, relation, index, attrs)
 
 	return nil
@@ -302,11 +360,21 @@ func (t *Transaction) CreateIndex(schema, relation, index string, it IndexType,
 //
 // Delete node needs to be inserted right as child of selector node.
 func (t *Transaction) Delete(schema, relation string, selectors []Selector, p Predicate) ([]string, []*Tuple, error) {
+	types, res, err := t.DeleteColumns(schema, relation, selectors, p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ColumnNames(types), res, nil
+}
+
+// DeleteColumns deletes rows like Delete and returns the type of each selected column
+func (t *Transaction) DeleteColumns(schema, relation string, selectors []Selector, p Predicate) ([]ColumnType, []*Tuple, error) {
 	if err := t.aborted(); err != nil {
 		return nil, nil, err
 	}
 
-	s, err := t.e.schema(schema)
+	s, err := t.schema(schema, relation)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -315,6 +383,9 @@ func (t *Transaction) Delete(schema, relation string, selectors []Selector, p Pr
 	if err != nil {
 		return nil, nil, err
 	}
+	if r.virtual != nil {
+		return nil, nil, fmt.Errorf("relation %s is read-only", r)
+	}
 
 	n, err := t.Plan(schema, selectors, p, nil, nil)
 	if err != nil {
@@ -436,11 +507,20 @@ func (t *Transaction) Delete(schema, relation string, selectors []Selector, p Pr
 							if _, _, err := t.Delete(schName, childName, nil, pred); err != nil {
 								return nil, nil, t.abort(err)
 							}
+						case "SET NULL":
+							// Set null: detach the referencing child rows
+							values := make(map[string]any)
+							for _, localCol := range fk.LocalColumns() {
+								values[localCol] = nil
+							}
+							if _, _, err := t.Update(schName, childName, values, nil, pred); err != nil {
+								return nil, nil, t.abort(err)
+							}
 						default:
 							// RESTRICT, NO ACTION, or unspecified: error
 							localColsStr := strings.Join(fk.LocalColumns(), ", ")
 							refColsStr := strings.Join(refCols, ", ")
-							return nil, nil, t.abort(fmt.Errorf("delete violates foreign key: %s.%s(%s) is referenced by %s.%s(%s)", s.name, relation, refColsStr, schName, childName, localColsStr))
+							return nil, nil, t.abort(NewError(ForeignKeyViolation, "delete violates foreign key: %s.%s(%s) is referenced by %s.%s(%s)", s.name, relation, refColsStr, schName, childName, localColsStr))
 						}
 					}
 				}
@@ -456,7 +536,7 @@ func (t *Transaction) Delete(schema, relation string, selectors []Selector, p Pr
 	PrintQueryPlan(n, 0, nil)
 
 	// (4), (5), (6)
-	cols, eres, err := n.Exec()
+	_, eres, err := n.Exec()
 	if err != nil {
 		return nil, nil, t.abort(err)
 	}
@@ -466,18 +546,28 @@ func (t *Transaction) Delete(schema, relation string, selectors []Selector, p Pr
 		res[i] = e.Value.(*Tuple)
 	}
 
-	return cols, res, nil
+	return snode.ColumnTypes(), res, nil
 }
 
 // Update relation with given values.
 //
 // Update node needs to be inserted right as child of selector node.
 func (t *Transaction) Update(schema, relation string, values map[string]any, selectors []Selector, p Predicate) ([]string, []*Tuple, error) {
+	types, res, err := t.UpdateColumns(schema, relation, values, selectors, p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ColumnNames(types), res, nil
+}
+
+// UpdateColumns updates rows like Update and returns the type of each selected column
+func (t *Transaction) UpdateColumns(schema, relation string, values map[string]any, selectors []Selector, p Predicate) ([]ColumnType, []*Tuple, error) {
 	if err := t.aborted(); err != nil {
 		return nil, nil, err
 	}
 
-	s, err := t.e.schema(schema)
+	s, err := t.schema(schema, relation)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -486,6 +576,14 @@ func (t *Transaction) Update(schema, relation string, values map[string]any, sel
 	if err != nil {
 		return nil, nil, err
 	}
+	if r.virtual != nil {
+		return nil, nil, fmt.Errorf("relation %s is read-only", r)
+	}
+
+	// without selectors, scanned relation would be unknown to a predicate such as TRUE
+	if len(selectors) == 0 {
+		selectors = []Selector{NewStarSelector(relation)}
+	}
 
 	n, err := t.Plan(schema, selectors, p, nil, nil)
 	if err != nil {
@@ -505,7 +603,7 @@ func (t *Transaction) Update(schema, relation string, values map[string]any, sel
 	PrintQueryPlan(n, 0, nil)
 
 	// (4), (5), (6)
-	cols, eres, err := n.Exec()
+	_, eres, err := n.Exec()
 	if err != nil {
 		return nil, nil, t.abort(err)
 	}
@@ -515,7 +613,7 @@ func (t *Transaction) Update(schema, relation string, values map[string]any, sel
 		res[i] = e.Value.(*Tuple)
 	}
 
-	return cols, res, nil
+	return snode.ColumnTypes(), res, nil
 }
 
 // CheckPrimaryKeyConflict checks if inserting the given values would cause a primary key conflict
@@ -525,7 +623,7 @@ func (t *Transaction) CheckPrimaryKeyConflict(schema, relation string, values ma
 		return false, err
 	}
 
-	s, err := t.e.schema(schema)
+	s, err := t.schema(schema, relation)
 	if err != nil {
 		return false, err
 	}
@@ -540,11 +638,10 @@ func (t *Transaction) CheckPrimaryKeyConflict(schema, relation string, values ma
 	for _, attr := range r.attributes {
 		val, specified := values[attr.name]
 		if specified {
-			if val != nil && reflect.TypeOf(val).ConvertibleTo(attr.typeInstance) {
-				tuple.Append(reflect.ValueOf(val).Convert(attr.typeInstance).Interface())
-			} else {
-				tuple.Append(val)
+			if val != nil {
+				val, _ = t.e.convert(val, attr.typeName, attr.typeInstance)
 			}
+			tuple.Append(val)
 		} else if attr.defaultValue != nil {
 			tuple.Append(attr.defaultValue())
 		} else {
@@ -562,6 +659,101 @@ func (t *Transaction) CheckPrimaryKeyConflict(schema, relation string, values ma
 	return !ok, nil
 }
 
+// ConflictingKeys returns the primary key, unique attributes and unique indexes of relation
+// for which an existing row has the same values as given ones, primary key first.
+//
+// It returns nil if values can be inserted without violating a key.
+func (t *Transaction) ConflictingKeys(schema, relation string, values map[string]any) ([][]string, error) {
+	if err := t.aborted(); err != nil {
+		return nil, err
+	}
+
+	s, err := t.schema(schema, relation)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.Relation(relation)
+	if err != nil {
+		return nil, err
+	}
+
+	t.lock(r)
+
+	conflict, err := t.CheckPrimaryKeyConflict(schema, relation, values)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys [][]string
+	if conflict {
+		var pk []string
+		for _, idx := range r.pk {
+			pk = append(pk, r.attributes[idx].name)
+		}
+		keys = append(keys, pk)
+	}
+
+	for _, attr := range r.attributes {
+		val, specified := values[attr.name]
+		if !attr.unique || !specified || val == nil {
+			continue
+		}
+		val, ok := t.e.convert(val, attr.typeName, attr.typeInstance)
+		if !ok {
+			return nil, assignError(val, relation, attr)
+		}
+
+		f := NewAttributeValueFunctor(r.name, attr.name)
+		p := NewEqPredicate(f, f)
+		for _, index := range r.indexes {
+			if ok, _ := index.CanSourceWith(p); !ok {
+				continue
+			}
+			idx, ok := index.(*HashIndex)
+			if !ok {
+				continue
+			}
+			e, err := idx.Get([]any{val})
+			if err != nil {
+				return nil, err
+			}
+			if e != nil {
+				keys = append(keys, []string{attr.name})
+			}
+			break
+		}
+	}
+
+	for _, index := range r.indexes {
+		hi, ok := index.(*HashIndex)
+		if !ok || !hi.unique {
+			continue
+		}
+		vals := make([]any, len(hi.attrs))
+		for i, idx := range hi.attrs {
+			attr := r.attributes[idx]
+			v := values[attr.name]
+			if v == nil {
+				continue
+			}
+			c, ok := t.e.convert(v, attr.typeName, attr.typeInstance)
+			if !ok {
+				return nil, assignError(v, relation, attr)
+			}
+			vals[i] = c
+		}
+		conflict, err := hi.conflicts(vals)
+		if err != nil {
+			return nil, err
+		}
+		if conflict {
+			keys = append(keys, append([]string(nil), hi.attrsName...))
+		}
+	}
+
+	return keys, nil
+}
+
 // ConvertValuesForRelation converts values to match the types of the relation's attributes.
 // This ensures type consistency when building predicates or performing lookups.
 func (t *Transaction) ConvertValuesForRelation(schema, relation string, values map[string]any) (map[string]any, error) {
@@ -569,7 +761,7 @@ func (t *Transaction) ConvertValuesForRelation(schema, relation string, values m
 		return nil, err
 	}
 
-	s, err := t.e.schema(schema)
+	s, err := t.schema(schema, relation)
 	if err != nil {
 		return nil, err
 	}
@@ -584,9 +776,7 @@ func (t *Transaction) ConvertValuesForRelation(schema, relation string, values m
 	}
 	for _, attr := range r.attributes {
 		if val, ok := result[attr.name]; ok && val != nil {
-			if reflect.TypeOf(val).ConvertibleTo(attr.typeInstance) {
-				result[attr.name] = reflect.ValueOf(val).Convert(attr.typeInstance).Interface()
-			}
+			result[attr.name], _ = t.e.convert(val, attr.typeName, attr.typeInstance)
 		}
 	}
 	return result, nil
@@ -609,7 +799,7 @@ func (t *Transaction) Insert(schema, relation string, values map[string]any) (*T
 		return nil, err
 	}
 
-	s, err := t.e.schema(schema)
+	s, err := t.schema(schema, relation)
 	if err != nil {
 		return nil, t.abort(err)
 	}
@@ -617,6 +807,9 @@ func (t *Transaction) Insert(schema, relation string, values map[string]any) (*T
 	if err != nil {
 		return nil, t.abort(err)
 	}
+	if r.virtual != nil {
+		return nil, t.abort(fmt.Errorf("relation %s is read-only", r))
+	}
 
 	t.lock(r)
 
@@ -625,26 +818,41 @@ func (t *Transaction) Insert(schema, relation string, values map[string]any) (*T
 	tuple := &Tuple{}
 	for i, attr := range r.attributes {
 		val, specified := values[attr.name]
+		if specified && val == nil && attr.rowID {
+			// NULL rowid is generated
+			delete(values, attr.name)
+			specified = false
+		}
 		if !specified {
 			if attr.defaultValue != nil {
 				tuple.Append(attr.defaultValue())
 				continue
 			}
 			if attr.autoIncrement {
-				tuple.Append(reflect.ValueOf(attr.nextValue).Convert(attr.typeInstance).Interface())
-				r.attributes[i].nextValue++
+				next := attr.nextValue
+				if attr.reuseRowID {
+					next = r.nextRowID(i)
+				}
+				tuple.Append(reflect.ValueOf(next).Convert(attr.typeInstance).Interface())
+				r.attributes[i].nextValue = next + 1
 				continue
 			}
 		}
 		if specified {
+			if val == nil && attr.notNull {
+				return nil, t.abort(NewError(NotNullViolation, "null value in %s.%s violates not-null constraint", relation, attr.name))
+			}
 			if val == nil {
 				tuple.Append(val)
 				delete(values, attr.name)
 				continue
 			}
-			tof := reflect.TypeOf(val)
-			if !tof.ConvertibleTo(attr.typeInstance) {
-				return nil, t.abort(fmt.Errorf("cannot assign '%v' (type %s) to %s.%s (type %s)", val, tof, relation, attr.name, attr.typeInstance))
+			val, ok := t.e.convert(val, attr.typeName, attr.typeInstance)
+			if !ok {
+				return nil, t.abort(assignError(val, relation, attr))
+			}
+			if id, ok := val.(int64); ok && attr.rowID && id >= 0 && uint64(id) >= attr.nextValue {
+				r.attributes[i].nextValue = uint64(id) + 1
 			}
 			if attr.unique {
 				f := NewAttributeValueFunctor(r.name, attr.name)
@@ -662,21 +870,21 @@ func (t *Transaction) Insert(schema, relation string, values map[string]any) (*T
 						return nil, t.abort(fmt.Errorf("cannot check unicity of %s", attr))
 					}
 					if tuple != nil {
-						return nil, t.abort(fmt.Errorf("constraint violation: %s unicity", attr))
+						return nil, t.abort(NewError(UniqueViolation, "constraint violation: %s unicity", attr))
 					}
 				}
 			}
 			// FK validation is now done after tuple is built (see below)
-			tuple.Append(reflect.ValueOf(val).Convert(attr.typeInstance).Interface())
+			tuple.Append(val)
 			delete(values, attr.name)
 			continue
 		}
-		return nil, t.abort(fmt.Errorf("no value for %s.%s", relation, attr.name))
+		return nil, t.abort(NewError(NotNullViolation, "no value for %s.%s", relation, attr.name))
 	}
 
 	// if values map is not empty, then an non existing attribute was specified
 	for k := range values {
-		return nil, t.abort(fmt.Errorf("attribute %s does not exist in relation %s", k, relation))
+		return nil, t.abort(NewError(UndefinedColumn, "attribute %s does not exist in relation %s", k, relation))
 	}
 
 	// Validate foreign keys after tuple is complete
@@ -690,12 +898,32 @@ func (t *Transaction) Insert(schema, relation string, values map[string]any) (*T
 		return nil, t.abort(err)
 	}
 	if !ok {
-		return nil, t.abort(fmt.Errorf("primary key violation"))
+		return nil, t.abort(NewError(UniqueViolation, "primary key violation"))
+	}
+
+	// check unique indexes violation
+	for _, index := range r.indexes {
+		hi, ok := index.(*HashIndex)
+		if !ok {
+			continue
+		}
+		conflict, err := hi.conflicts(hi.values(tuple))
+		if err != nil {
+			return nil, t.abort(err)
+		}
+		if conflict {
+			return nil, t.abort(NewError(UniqueViolation, "constraint violation: unique index %s", hi.name))
+		}
+	}
+
+	if err := t.checkSizeLimits(r, 1, tupleSize(tuple)); err != nil {
+		return nil, t.abort(err)
 	}
 
 	// insert into row list
 	log.Debug("Inserting %v", tuple.values)
 	e := r.rows.PushBack(tuple)
+	r.size.add(tuple)
 
 	// update indexes
 	for _, index := range r.indexes {
@@ -707,6 +935,7 @@ func (t *Transaction) Insert(schema, relation string, values map[string]any) (*T
 		current: e,
 		old:     nil,
 		l:       r.rows,
+		rel:     r,
 	}
 	t.changes.PushBack(c)
 
@@ -751,7 +980,7 @@ func (t *Transaction) validateForeignKeys(schema, relation string, r *Relation,
 		refCols := fk.RefColumns()
 		if len(refCols) == 0 {
 			// Reference parent PK
-			ps, err := t.e.schema(refSchema)
+			ps, err := t.schema(refSchema, refRel)
 			if err != nil {
 				return t.abort(err)
 			}
@@ -809,7 +1038,7 @@ func (t *Transaction) validateForeignKeys(schema, relation string, r *Relation,
 			// Build a readable error message
 			localColsStr := strings.Join(fk.LocalColumns(), ", ")
 			refColsStr := strings.Join(refCols, ", ")
-			return t.abort(fmt.Errorf("insert violates foreign key: %s.%s(%s) references %s.%s(%s)", schema, relation, localColsStr, refSchema, refRel, refColsStr))
+			return t.abort(NewError(ForeignKeyViolation, "insert violates foreign key: %s.%s(%s) references %s.%s(%s)", schema, relation, localColsStr, refSchema, refRel, refColsStr))
 		}
 	}
 	return nil
@@ -830,32 +1059,153 @@ func (t *Transaction) validateForeignKeys(schema, relation string, r *Relation,
 //
 // TODO: foreign keys should have hashmap index
 func (t *Transaction) Query(schema string, selectors []Selector, p Predicate, joiners []Joiner, sorters []Sorter) ([]string, []*Tuple, error) {
-	if err := t.aborted(); err != nil {
+	types, res, err := t.QueryColumns(schema, selectors, p, joiners, sorters)
+	if err != nil {
 		return nil, nil, err
 	}
 
-	n, err := t.Plan(schema, selectors, p, joiners, sorters)
+	return ColumnNames(types), res, nil
+}
+
+// QueryColumns runs a query like Query and returns the type of each returned column
+func (t *Transaction) QueryColumns(schema string, selectors []Selector, p Predicate, joiners []Joiner, sorters []Sorter) ([]ColumnType, []*Tuple, error) {
+	c, err := t.QueryCursor(schema, selectors, p, joiners, sorters)
 	if err != nil {
 		return nil, nil, err
 	}
+
+	res, err := c.All()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return c.ColumnTypes(), res, nil
+}
+
+// Cursor iterates over rows returned by a query
+type Cursor struct {
+	t     *Transaction
+	it    Iterator
+	types []ColumnType
+
+	// first row is fetched by QueryCursor to type columns
+	first   *Tuple
+	fetched bool
+}
+
+// QueryCursor plans a query like Query, rows are produced on demand by Cursor.Next.
+//
+// Queried relations stay locked until the transaction ends, rows must be read before.
+// In a Snapshot transaction without changes, relations are unlocked once the query is planned.
+func (t *Transaction) QueryCursor(schema string, selectors []Selector, p Predicate, joiners []Joiner, sorters []Sorter) (*Cursor, error) {
+	if err := t.aborted(); err != nil {
+		return nil, err
+	}
+
+	n, err := t.Plan(schema, selectors, p, joiners, sorters)
+	if err != nil {
+		return nil, err
+	}
 	PrintQueryPlan(n, 0, nil)
 
 	// (4), (5), (6)
-	columns, eres, err := n.Exec()
+	it, err := Iter(n)
 	if err != nil {
-		return nil, nil, t.abort(err)
+		return nil, t.abort(err)
 	}
+	c := &Cursor{t: t, it: it}
 
-	res := make([]*Tuple, len(eres))
-	for i, e := range eres {
-		res[i] = e.Value.(*Tuple)
+	c.first, err = c.next()
+	if err != nil {
+		return nil, err
+	}
+	c.fetched = true
+
+	if t.snapshot && t.changes.Len() == 0 {
+		t.unlock()
+	}
+
+	columns := it.Columns()
+	if sn, ok := n.(*SelectorNode); ok {
+		c.types = sn.ColumnTypes()
+	}
+	if len(c.types) != len(columns) {
+		var tuples []*Tuple
+		if c.first != nil {
+			tuples = append(tuples, c.first)
+		}
+		c.types = NewColumnTypes(columns, tuples)
+	}
+	for i := range c.types {
+		c.types[i].Name = columns[i]
+	}
+
+	return c, nil
+}
+
+// ColumnTypes returns the type of each returned column
+func (c *Cursor) ColumnTypes() []ColumnType {
+	return c.types
+}
+
+// Next returns the next row, or nil once all rows have been returned
+func (c *Cursor) Next() (*Tuple, error) {
+	if c.fetched {
+		c.fetched = false
+		return c.first, nil
+	}
+	return c.next()
+}
+
+// All returns all remaining rows
+func (c *Cursor) All() ([]*Tuple, error) {
+	var res []*Tuple
+	for {
+		tup, err := c.Next()
+		if err != nil {
+			return nil, err
+		}
+		if tup == nil {
+			return res, nil
+		}
+		res = append(res, tup)
+	}
+}
+
+// Materialize reads all remaining rows, so that the cursor no longer depends on queried relations
+func (c *Cursor) Materialize() error {
+	res, err := c.All()
+	if err != nil {
+		return err
 	}
 
-	return columns, res, nil
+	l := list.New()
+	it := &sliceIterator{cols: ColumnNames(c.types)}
+	for _, t := range res {
+		it.res = append(it.res, l.PushBack(t))
+	}
+	c.it = it
+	return nil
+}
+
+func (c *Cursor) next() (*Tuple, error) {
+	if err := c.t.aborted(); err != nil {
+		return nil, err
+	}
+
+	e, err := c.it.Next()
+	if err != nil {
+		return nil, c.t.abort(err)
+	}
+	if e == nil {
+		return nil, nil
+	}
+	return e.Value.(*Tuple), nil
 }
 
 func recAppendPredicates(rname string, sc Scanner, p Predicate) {
-	if p.Relation() == rname {
+	// FALSE filters out rows of every relation
+	if p.Relation() == rname || p.Type() == False {
 		sc.Append(p)
 		return
 	}
@@ -873,11 +1223,6 @@ func (t *Transaction) Plan(schema string, selectors []Selector, p Predicate, joi
 		return nil, err
 	}
 
-	s, err := t.e.schema(schema)
-	if err != nil {
-		return nil, t.abort(err)
-	}
-
 	if p == nil {
 		return nil, t.abort(errors.New("query requires 1 predicate"))
 	}
@@ -886,7 +1231,7 @@ func (t *Transaction) Plan(schema string, selectors []Selector, p Predicate, joi
 
 	// (1)
 	relations := make(map[string]*Relation)
-	err = t.recLock(schema, relations, p)
+	err := t.recLock(schema, relations, p)
 	if err != nil {
 		return nil, t.abort(err)
 	}
@@ -896,6 +1241,10 @@ func (t *Transaction) Plan(schema string, selectors []Selector, p Predicate, joi
 			// Skip selectors with no relation (constants in SELECT without FROM)
 			continue
 		}
+		s, err := t.schema(schema, rel)
+		if err != nil {
+			return nil, t.abort(err)
+		}
 		r, err := s.Relation(rel)
 		if err != nil {
 			return nil, t.abort(err)
@@ -903,9 +1252,30 @@ func (t *Transaction) Plan(schema string, selectors []Selector, p Predicate, joi
 		if a := sel.Alias(); a != "" {
 			aliases[rel] = a
 		}
+		if ss, ok := sel.(*StarSelector); ok {
+			ss.hidden = r.hiddenAttributes()
+		}
 		t.lock(r)
 		relations[rel] = r
 	}
+	// joined relations may not be referenced by selectors nor predicate
+	for _, j := range joiners {
+		for _, rel := range []string{j.Left(), j.Right()} {
+			if _, ok := relations[rel]; ok {
+				continue
+			}
+			s, err := t.schema(schema, rel)
+			if err != nil {
+				return nil, t.abort(err)
+			}
+			r, err := s.Relation(rel)
+			if err != nil {
+				return nil, t.abort(err)
+			}
+			t.lock(r)
+			relations[rel] = r
+		}
+	}
 
 	// Handle SELECT without FROM (no relations to query)
 	if len(relations) == 0 {
@@ -934,7 +1304,11 @@ func (t *Transaction) Plan(schema string, selectors []Selector, p Predicate, joi
 		}
 		if _, ok := sources[r.name]; !ok {
 			log.Debug("could not find suitable index for relation %s, using seq scan", r)
-			sources[r.name] = NewSeqScan(r, getAlias(r.name, aliases))
+			src := NewSeqScan(r, getAlias(r.name, aliases))
+			if t.snapshot {
+				src.freeze()
+			}
+			sources[r.name] = src
 		}
 	}
 
@@ -991,6 +1365,7 @@ func (t *Transaction) Plan(schema string, selectors []Selector, p Predicate, joi
 
 	// append selectors
 	n := NewSelectorNode(selectors, headJoin)
+	n.relations = relations
 
 	// append sorters
 	// GroupBy must contains both selector node and last join to compute arithmetic on all groups
@@ -1019,12 +1394,13 @@ func (t *Transaction) Plan(schema string, selectors []Selector, p Predicate, joi
 }
 
 func (t *Transaction) recLock(schema string, relations map[string]*Relation, p Predicate) error {
+	var err error
 
-	s, err := t.e.schema(schema)
-	if err != nil {
-		return err
-	}
 	if rel := p.Relation(); rel != "" {
+		s, err := t.schema(schema, rel)
+		if err != nil {
+			return err
+		}
 		r, err := s.Relation(rel)
 		if err != nil {
 			return err
@@ -1074,21 +1450,25 @@ func recCanUseIndex(relName string, index Index, p Predicate) (int64, bool, Pred
 
 // Lock relations if not already done
 func (t *Transaction) lock(r *Relation) {
-	_, done := t.locks[r.name]
+	_, done := t.locks[r]
 	if done {
 		return
 	}
 
 	r.Lock()
-	t.locks[r.name] = r
+	t.locks[r] = struct{}{}
+
+	if r.virtual != nil {
+		r.refresh()
+	}
 }
 
 // Unlock all touched relations
 func (t *Transaction) unlock() {
-	for _, r := range t.locks {
+	for r := range t.locks {
 		r.Unlock()
 	}
-	t.locks = make(map[string]*Relation)
+	t.locks = make(map[*Relation]struct{})
 }
 
 func (t *Transaction) aborted() error {
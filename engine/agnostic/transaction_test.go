@@ -1,6 +1,7 @@
 package agnostic
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 	"time"
@@ -70,9 +71,8 @@ func TestCreateRelation(t *testing.T) {
 	if err != nil {
 		t.Fatalf("cannot commit tx: %s", err)
 	}
-	// 2 changes: 1 for relation creation + 1 for information_schema.tables insert
-	if changed != 2 {
-		t.Fatalf("expected 2 changes, got %d", changed)
+	if changed != 1 {
+		t.Fatalf("expected 1 change, got %d", changed)
 	}
 
 	if len(e.schemas[DefaultSchema].relations) != 1 {
@@ -120,9 +120,9 @@ func TestDropRelation(t *testing.T) {
 	if err != nil {
 		t.Fatalf("cannot commit tx: %s", err)
 	}
-	// 4 changes: 1 for relation creation + 1 for information_schema insert + 1 for drop + 1 for information_schema delete
-	if changed != 4 {
-		t.Fatalf("expected 4 changes, got %d", changed)
+	// 2 changes: 1 for relation creation + 1 for drop
+	if changed != 2 {
+		t.Fatalf("expected 2 changes, got %d", changed)
 	}
 
 	if len(e.schemas[DefaultSchema].relations) != 0 {
@@ -455,9 +455,9 @@ func TestIndexCreation(t *testing.T) {
 	if err != nil {
 		t.Fatalf("cannot commit tx: %s", err)
 	}
-	// 5 changes: 1 for relation creation + 1 for information_schema insert + 3 for row inserts
-	if changed != 5 {
-		t.Fatalf("expected 5 changes, got %d", changed)
+	// 4 changes: 1 for relation creation + 3 for row inserts
+	if changed != 4 {
+		t.Fatalf("expected 4 changes, got %d", changed)
 	}
 
 	l := e.schemas[schema].relations[relation].rows.Len()
@@ -482,6 +482,57 @@ func TestIndexCreation(t *testing.T) {
 	}
 }
 
+func TestUniqueIndexViolation(t *testing.T) {
+	e := NewEngine()
+
+	tx, err := e.Begin()
+	if err != nil {
+		t.Fatalf("cannot begin tx: %s", err)
+	}
+	defer tx.Rollback()
+
+	attrs := []Attribute{
+		NewAttribute("id", "BIGINT").WithAutoIncrement(),
+		NewAttribute("email", "TEXT"),
+		NewAttribute("age", "INT"),
+	}
+
+	schema := DefaultSchema
+	relation := "myrel"
+
+	err = tx.CreateRelation(schema, relation, attrs, []string{"id"})
+	if err != nil {
+		t.Fatalf("cannot create relation: %s", err)
+	}
+	err = tx.CreateUniqueIndex(schema, relation, "myrel_email_age_idx", HashIndexType, []string{"email", "age"})
+	if err != nil {
+		t.Fatalf("cannot create unique index: %s", err)
+	}
+
+	_, err = tx.Insert(schema, relation, map[string]any{"email": "foo@bar.com", "age": 42})
+	if err != nil {
+		t.Fatalf("cannot insert values: %s", err)
+	}
+
+	_, err = tx.ConflictingKeys(schema, relation, map[string]any{"email": "foo@bar.com", "age": []int{42}})
+	if err == nil {
+		t.Fatalf("expected conversion error")
+	}
+
+	keys, err := tx.ConflictingKeys(schema, relation, map[string]any{"email": "foo@bar.com", "age": 42})
+	if err != nil {
+		t.Fatalf("cannot get conflicting keys: %s", err)
+	}
+	if len(keys) != 1 || !reflect.DeepEqual(keys[0], []string{"email", "age"}) {
+		t.Fatalf("expected unique index conflict, got %v", keys)
+	}
+
+	_, err = tx.Insert(schema, relation, map[string]any{"email": "foo@bar.com", "age": 42})
+	if !errors.Is(err, &Error{Code: UniqueViolation}) {
+		t.Fatalf("expected unique violation, got %v", err)
+	}
+}
+
 func TestQuery(t *testing.T) {
 	e := NewEngine()
 	log.SetLevel(log.WarningLevel)
@@ -1225,6 +1276,50 @@ func TestDelete(t *testing.T) {
 
 }
 
+func TestDeleteFalse(t *testing.T) {
+	e := NewEngine()
+
+	tx, err := e.Begin()
+	if err != nil {
+		t.Fatalf("cannot begin tx: %s", err)
+	}
+	defer tx.Rollback()
+
+	schema := DefaultSchema
+	relation := "foo"
+	attrs := []Attribute{
+		NewAttribute("id", "BIGINT").WithAutoIncrement(),
+		NewAttribute("name", "TEXT"),
+	}
+	err = tx.CreateRelation(schema, relation, attrs, []string{"id"})
+	if err != nil {
+		t.Fatalf("cannot create relation: %s", err)
+	}
+	for _, name := range []string{"a", "b"} {
+		_, err = tx.Insert(schema, relation, map[string]any{"name": name})
+		if err != nil {
+			t.Fatalf("cannot insert: %s", err)
+		}
+	}
+
+	// FALSE has no relation, it must still filter out rows of the deleted relation
+	_, res, err := tx.Delete(schema, relation, []Selector{NewStarSelector(relation)}, NewFalsePredicate())
+	if err != nil {
+		t.Fatalf("cannot delete: %s", err)
+	}
+	if len(res) != 0 {
+		t.Fatalf("expected no row deleted, got %d", len(res))
+	}
+
+	_, res, err = tx.Query(schema, []Selector{NewCountSelector(relation, "*")}, NewTruePredicate(), nil, nil)
+	if err != nil {
+		t.Fatalf("cannot query: %s", err)
+	}
+	if l := res[0].values[0].(int64); l != 2 {
+		t.Fatalf("expected count to be 2, got %d", l)
+	}
+}
+
 func TestAlias(t *testing.T) {
 	e := NewEngine()
 
@@ -0,0 +1,80 @@
+package agnostic
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Type maps a Go type to a column type, see RegisterType.
+type Type struct {
+	// Name is the column type name used in CREATE TABLE, such as MONEY
+	Name string
+	// GoType is the type of values encoded in, and decoded from, the column
+	GoType reflect.Type
+	// Encode converts a GoType value to the value stored in relations
+	Encode func(any) (any, error)
+	// Decode converts a stored value back to a GoType value
+	Decode func(any) (any, error)
+
+	// storage is the type of encoded values
+	storage reflect.Type
+}
+
+var registeredTypes = struct {
+	sync.RWMutex
+	byName   map[string]*Type
+	byGoType map[reflect.Type]*Type
+}{
+	byName:   make(map[string]*Type),
+	byGoType: make(map[reflect.Type]*Type),
+}
+
+// RegisterType makes t available as a column type to every engine.
+//
+// The type of stored values is the type returned by Encode for the zero value
+// of GoType, which must not be nil. Registering a name or Go type again
+// replaces the previous registration.
+func RegisterType(t Type) error {
+	if t.Name == "" || t.GoType == nil || t.Encode == nil || t.Decode == nil {
+		return fmt.Errorf("type registration requires a name, a Go type, an encoder and a decoder")
+	}
+	switch strings.ToLower(t.Name) {
+	case "serial", "bigserial", "int", "integer", "bigint", "tinyint", "smallint", "mediumint",
+		"bool", "boolean", "decimal", "float", "timestamp", "timestamptz", "date", "datetime",
+		"text", "varchar", "json", "jsonb":
+		return fmt.Errorf("cannot register builtin type %s", t.Name)
+	}
+
+	zero, err := t.Encode(reflect.Zero(t.GoType).Interface())
+	if err != nil {
+		return fmt.Errorf("cannot encode zero value of %s: %s", t.GoType, err)
+	}
+	if zero == nil {
+		return fmt.Errorf("cannot register %s, zero value of %s is encoded as NULL", t.Name, t.GoType)
+	}
+	t.storage = reflect.TypeOf(zero)
+
+	registeredTypes.Lock()
+	defer registeredTypes.Unlock()
+	registeredTypes.byName[strings.ToLower(t.Name)] = &t
+	registeredTypes.byGoType[t.GoType] = &t
+	return nil
+}
+
+// LookupType returns the registered type named name, case insensitive.
+func LookupType(name string) (*Type, bool) {
+	registeredTypes.RLock()
+	defer registeredTypes.RUnlock()
+	t, ok := registeredTypes.byName[strings.ToLower(name)]
+	return t, ok
+}
+
+// LookupGoType returns the registered type encoding values of Go type gt.
+func LookupGoType(gt reflect.Type) (*Type, bool) {
+	registeredTypes.RLock()
+	defer registeredTypes.RUnlock()
+	t, ok := registeredTypes.byGoType[gt]
+	return t, ok
+}
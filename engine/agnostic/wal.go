@@ -0,0 +1,859 @@
+package agnostic
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/proullon/ramsql/engine/log"
+)
+
+// DefaultCompactEvery is the number of committed transactions after which
+// the write-ahead log is compacted into the snapshot file.
+const DefaultCompactEvery = 1000
+
+// snapshotSuffix is appended to the write-ahead log path to get the snapshot path.
+const snapshotSuffix = ".snapshot"
+
+// maxFrameSize protects replay from allocating garbage sizes read in a corrupted frame header.
+const maxFrameSize = 1 << 30
+
+type walOpKind uint8
+
+const (
+	walCreateSchema walOpKind = iota + 1
+	walDropSchema
+	walCreateRelation
+	walDropRelation
+	walInsert
+	walDelete
+	walUpdate
+	walTruncate
+	walCreateIndex
+	walSequence
+)
+
+type walValueKind uint8
+
+const (
+	walNull walValueKind = iota
+	walInt
+	walUint
+	walFloat
+	walString
+	walBool
+	walTime
+	walBytes
+	walArray
+	walJSON
+)
+
+// walValue is a tagged union of every type a Tuple can hold.
+//
+// Using a concrete type instead of `any` avoids registering types to gob.
+type walValue struct {
+	Kind walValueKind
+	I    int64
+	U    uint64
+	F    float64
+	S    string
+	B    bool
+	T    time.Time
+	Raw  []byte
+	// A holds elements of arrays, S being the Go type of elements
+	A []walValue
+}
+
+type walForeignKey struct {
+	Name         string
+	LocalColumns []string
+	RefSchema    string
+	RefRelation  string
+	RefColumns   []string
+	OnDelete     string
+}
+
+type walAttribute struct {
+	Name          string
+	Type          string
+	AutoIncrement bool
+	NextValue     uint64
+	RowID         bool
+	ReuseRowID    bool
+	Hidden        bool
+	Unique        bool
+	UniqueName    string
+	HasDefault    bool
+	Default       walValue
+	DefaultNow    bool
+	NotNull       bool
+	Size          int64
+	Scale         int64
+	FK            *walForeignKey
+}
+
+type walRelation struct {
+	Attributes []walAttribute
+	PK         []string
+}
+
+type walIndex struct {
+	Name   string
+	Type   IndexType
+	Attrs  []string
+	Unique bool
+}
+
+type walOp struct {
+	Kind     walOpKind
+	Schema   string
+	Relation string
+
+	Def   *walRelation
+	Index *walIndex
+	Old   []walValue
+	New   []walValue
+	Next  []uint64
+}
+
+// walRecord is a frame of the log. Each committed transaction is written as one record.
+type walRecord struct {
+	Ops []walOp
+	// Generation identifies a log, it is set in the first frame of the log.
+	// In a snapshot, it is the generation of the last log compacted into the snapshot.
+	Generation uint64
+}
+
+// wal is an append-only log of committed changes.
+//
+// Frames are written as a 4 bytes length, a 4 bytes CRC32 checksum and a gob encoded walRecord.
+// A truncated or corrupted frame at the end of the file is ignored when replaying,
+// since it can only be a transaction which commit did not complete.
+//
+// The log is started again with a new generation after each compaction. A log whose generation
+// is already compacted into the snapshot is not replayed, in case compaction did not complete.
+type wal struct {
+	path         string
+	f            *os.File
+	generation   uint64
+	commits      int
+	compactEvery int
+
+	sync.Mutex
+}
+
+// Persist makes the engine durable by writing every committed transaction
+// to the write-ahead log at path.
+//
+// Existing snapshot and log are replayed first, so Persist must be called before the engine is used.
+// The log is compacted into a snapshot (path + ".snapshot") every compactEvery commits.
+// If compactEvery is 0, DefaultCompactEvery is used. If it is negative, automatic compaction is disabled.
+func (e *Engine) Persist(path string, compactEvery int) error {
+	e.Lock()
+	defer e.Unlock()
+
+	if e.wal != nil {
+		return fmt.Errorf("engine already persisted to %s", e.wal.path)
+	}
+
+	if compactEvery == 0 {
+		compactEvery = DefaultCompactEvery
+	}
+
+	_, compacted, err := e.replayFile(path+snapshotSuffix, 0)
+	if err != nil {
+		return fmt.Errorf("cannot load snapshot: %w", err)
+	}
+	valid, generation, err := e.replayFile(path, compacted)
+	if err != nil {
+		return fmt.Errorf("cannot replay write-ahead log: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	// drop incomplete trailing frame, if any
+	if err := f.Truncate(valid); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Seek(valid, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+
+	w := &wal{
+		path:         path,
+		f:            f,
+		generation:   generation,
+		compactEvery: compactEvery,
+	}
+	// start a new log if empty, or already compacted into the snapshot
+	if valid == 0 {
+		if err := w.start(compacted + 1); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	e.wal = w
+	log.Info("Persisting engine to %s", path)
+	return nil
+}
+
+// Compact writes current state in the snapshot file and truncates the write-ahead log.
+func (e *Engine) Compact() error {
+	w := e.wal
+	if w == nil {
+		return fmt.Errorf("engine is not persisted")
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	return w.compact()
+}
+
+// Close flushes and closes the write-ahead log, if any.
+func (e *Engine) Close() error {
+	e.Lock()
+	w := e.wal
+	e.wal = nil
+	e.Unlock()
+
+	if w == nil {
+		return nil
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	if err := w.f.Sync(); err != nil {
+		w.f.Close()
+		return err
+	}
+	return w.f.Close()
+}
+
+// RemoveLog removes the write-ahead log at path and its snapshot, so their data is not replayed again.
+// The engine persisted to path must be closed first. Missing files are ignored.
+func RemoveLog(path string) error {
+	for _, p := range []string{path, path + snapshotSuffix, path + snapshotSuffix + ".tmp"} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// logChanges appends given committed changes to the write-ahead log.
+func (e *Engine) logChanges(changes *list.List) error {
+	w := e.wal
+	if w == nil || changes.Len() == 0 {
+		return nil
+	}
+
+	rec := walRecord{}
+	inserted := make(map[*Relation]struct{})
+	for el := changes.Front(); el != nil; el = el.Next() {
+		switch c := el.Value.(type) {
+		case ValueChange:
+			rec.Ops = append(rec.Ops, valueChangeOp(&c))
+			if c.old == nil && c.rel != nil {
+				inserted[c.rel] = struct{}{}
+			}
+		case *ValueChange:
+			rec.Ops = append(rec.Ops, valueChangeOp(c))
+		case RelationChange:
+			if c.current != nil {
+				rec.Ops = append(rec.Ops, walOp{Kind: walCreateRelation, Schema: c.current.schema, Relation: c.current.name, Def: encodeRelation(c.current)})
+			} else if c.old != nil {
+				rec.Ops = append(rec.Ops, walOp{Kind: walDropRelation, Schema: c.old.schema, Relation: c.old.name})
+			}
+		case SchemaChange:
+			if c.current != nil {
+				rec.Ops = append(rec.Ops, walOp{Kind: walCreateSchema, Schema: c.current.name})
+			} else if c.old != nil {
+				rec.Ops = append(rec.Ops, walOp{Kind: walDropSchema, Schema: c.old.name})
+			}
+		case TruncateChange:
+			rec.Ops = append(rec.Ops, walOp{Kind: walTruncate, Schema: c.rel.schema, Relation: c.rel.name})
+		case IndexChange:
+			rec.Ops = append(rec.Ops, walOp{Kind: walCreateIndex, Schema: c.rel.schema, Relation: c.rel.name, Index: &walIndex{Name: c.name, Type: c.t, Attrs: c.attrs, Unique: c.unique}})
+		}
+	}
+
+	// autoincrement counters are not rolled back, save their value at commit time
+	for r := range inserted {
+		if !e.isCurrent(r) {
+			continue
+		}
+		rec.Ops = append(rec.Ops, sequenceOp(r))
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	offset, err := w.f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("cannot write to write-ahead log: %w", err)
+	}
+	if err := writeFrame(w.f, &rec); err != nil {
+		// don't leave a partial frame behind, following commits would not be replayed
+		w.f.Truncate(offset)
+		w.f.Seek(offset, io.SeekStart)
+		return fmt.Errorf("cannot write to write-ahead log: %w", err)
+	}
+	if err := w.f.Sync(); err != nil {
+		return fmt.Errorf("cannot sync write-ahead log: %w", err)
+	}
+
+	w.commits++
+	if w.compactEvery > 0 && w.commits >= w.compactEvery {
+		if err := w.compact(); err != nil {
+			// changes are safely written in the log, compaction will be retried later
+			log.Warn("cannot compact write-ahead log: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// isCurrent reports whether r is still the relation registered under its name.
+func (e *Engine) isCurrent(r *Relation) bool {
+	s, err := e.schema(r.schema)
+	if err != nil {
+		return false
+	}
+	cur, err := s.Relation(r.name)
+	return err == nil && cur == r
+}
+
+// compact rebuilds state from snapshot and log in a scratch engine,
+// so live relations don't need to be locked, then writes it as the new snapshot.
+//
+// The snapshot records the generation of the log, so the log is not replayed
+// on top of the snapshot if it cannot be started again.
+func (w *wal) compact() error {
+	scratch := NewEngine()
+	_, compacted, err := scratch.replayFile(w.path+snapshotSuffix, 0)
+	if err != nil {
+		return err
+	}
+	if _, _, err := scratch.replayFile(w.path, compacted); err != nil {
+		return err
+	}
+
+	tmp := w.path + snapshotSuffix + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	rec := scratch.dump()
+	rec.Generation = w.generation
+	if err := writeFrame(f, rec); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, w.path+snapshotSuffix); err != nil {
+		return err
+	}
+
+	if err := w.start(w.generation + 1); err != nil {
+		return err
+	}
+	w.commits = 0
+	log.Debug("Compacted write-ahead log %s", w.path)
+	return nil
+}
+
+// start truncates the log and writes its first frame, holding given generation.
+func (w *wal) start(generation uint64) error {
+	if err := w.f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := w.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := writeFrame(w.f, &walRecord{Generation: generation}); err != nil {
+		return err
+	}
+	if err := w.f.Sync(); err != nil {
+		return err
+	}
+	w.generation = generation
+	return nil
+}
+
+// dump returns a record recreating the whole engine state.
+func (e *Engine) dump() *walRecord {
+	rec := &walRecord{}
+
+	for _, s := range e.schemas {
+		rec.Ops = append(rec.Ops, walOp{Kind: walCreateSchema, Schema: s.name})
+		for _, r := range s.relations {
+			if r.virtual != nil {
+				continue
+			}
+			rec.Ops = append(rec.Ops, walOp{Kind: walCreateRelation, Schema: s.name, Relation: r.name, Def: encodeRelation(r)})
+			for el := r.rows.Front(); el != nil; el = el.Next() {
+				rec.Ops = append(rec.Ops, walOp{Kind: walInsert, Schema: s.name, Relation: r.name, New: encodeTuple(el.Value.(*Tuple))})
+			}
+			for _, i := range r.indexes {
+				hi, ok := i.(*HashIndex)
+				if !ok {
+					continue
+				}
+				rec.Ops = append(rec.Ops, walOp{Kind: walCreateIndex, Schema: s.name, Relation: r.name, Index: &walIndex{Name: hi.name, Type: HashIndexType, Attrs: hi.attrsName, Unique: hi.unique}})
+			}
+			rec.Ops = append(rec.Ops, sequenceOp(r))
+		}
+	}
+
+	return rec
+}
+
+// replayFile applies every valid frame of given file to the engine, unless the file
+// is a log of a generation up to compacted, which changes are already in the snapshot.
+// It returns the offset of the end of the last valid frame, 0 if the file is skipped,
+// and the generation of the file.
+func (e *Engine) replayFile(path string, compacted uint64) (int64, uint64, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	var offset int64
+	var generation uint64
+	rd := bufio.NewReader(f)
+	for {
+		rec, n, err := readFrame(rd)
+		if err == io.EOF {
+			return offset, generation, nil
+		}
+		if err != nil {
+			log.Warn("ignoring incomplete write-ahead log frame at %s:%d: %s", path, offset, err)
+			return offset, generation, nil
+		}
+		if offset == 0 {
+			generation = rec.Generation
+			if generation != 0 && generation <= compacted {
+				log.Info("skipping write-ahead log %s, generation %d is already in the snapshot", path, generation)
+				return 0, generation, nil
+			}
+		}
+		for _, op := range rec.Ops {
+			if err := e.apply(op); err != nil {
+				return offset, generation, err
+			}
+		}
+		offset += n
+	}
+}
+
+func (e *Engine) apply(op walOp) error {
+	switch op.Kind {
+	case walCreateSchema:
+		if _, ok := e.schemas[op.Schema]; ok {
+			return nil
+		}
+		_, err := e.createSchema(op.Schema)
+		return err
+	case walDropSchema:
+		_, err := e.dropSchema(op.Schema)
+		return err
+	case walCreateRelation:
+		attrs, pk := decodeRelation(op.Def)
+		_, _, err := e.createRelation(op.Schema, op.Relation, attrs, pk)
+		return err
+	case walDropRelation:
+		_, _, err := e.dropRelation(op.Schema, op.Relation)
+		return err
+	}
+
+	s, err := e.schema(op.Schema)
+	if err != nil {
+		return err
+	}
+	r, err := s.Relation(op.Relation)
+	if err != nil {
+		return err
+	}
+
+	switch op.Kind {
+	case walInsert:
+		t := decodeTuple(op.New)
+		el := r.rows.PushBack(t)
+		r.size.add(t)
+		for _, i := range r.indexes {
+			i.Add(el)
+		}
+	case walDelete:
+		el := r.find(decodeTuple(op.Old))
+		if el == nil {
+			return fmt.Errorf("cannot find row %v to delete in %s", decodeTuple(op.Old).values, r)
+		}
+		r.rows.Remove(el)
+		r.size.remove(el.Value.(*Tuple))
+		for _, i := range r.indexes {
+			i.Remove(el)
+		}
+	case walUpdate:
+		el := r.find(decodeTuple(op.Old))
+		if el == nil {
+			return fmt.Errorf("cannot find row %v to update in %s", decodeTuple(op.Old).values, r)
+		}
+		t := decodeTuple(op.New)
+		newe := r.rows.InsertAfter(t, el)
+		r.rows.Remove(el)
+		r.size.remove(el.Value.(*Tuple))
+		r.size.add(t)
+		for _, i := range r.indexes {
+			i.Remove(el)
+			i.Add(newe)
+		}
+	case walTruncate:
+		r.Truncate()
+	case walCreateIndex:
+		for _, i := range r.indexes {
+			if i.Name() == op.Index.Name {
+				return nil
+			}
+		}
+		return r.createIndex(op.Index.Name, op.Index.Type, op.Index.Attrs, op.Index.Unique)
+	case walSequence:
+		for i := range r.attributes {
+			if i < len(op.Next) && r.attributes[i].autoIncrement {
+				r.attributes[i].nextValue = op.Next[i]
+			}
+		}
+	default:
+		return fmt.Errorf("unknown write-ahead log operation %d", op.Kind)
+	}
+
+	return nil
+}
+
+// find returns the first row equal to given tuple.
+func (r *Relation) find(t *Tuple) *list.Element {
+	for el := r.rows.Front(); el != nil; el = el.Next() {
+		rt := el.Value.(*Tuple)
+		if len(rt.values) != len(t.values) {
+			continue
+		}
+		match := true
+		for i := range rt.values {
+			if !walEqual(rt.values[i], t.values[i]) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return el
+		}
+	}
+	return nil
+}
+
+func walEqual(a, b any) bool {
+	if ta, ok := a.(time.Time); ok {
+		tb, ok := b.(time.Time)
+		return ok && ta.Equal(tb)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func valueChangeOp(c *ValueChange) walOp {
+	op := walOp{Schema: c.rel.schema, Relation: c.rel.name}
+	switch {
+	case c.old == nil:
+		op.Kind = walInsert
+		op.New = encodeTuple(c.current.Value.(*Tuple))
+	case c.current == nil:
+		op.Kind = walDelete
+		op.Old = encodeTuple(c.old.Value.(*Tuple))
+	default:
+		op.Kind = walUpdate
+		op.Old = encodeTuple(c.old.Value.(*Tuple))
+		op.New = encodeTuple(c.current.Value.(*Tuple))
+	}
+	return op
+}
+
+func sequenceOp(r *Relation) walOp {
+	op := walOp{Kind: walSequence, Schema: r.schema, Relation: r.name}
+	for _, a := range r.attributes {
+		op.Next = append(op.Next, a.nextValue)
+	}
+	return op
+}
+
+func encodeRelation(r *Relation) *walRelation {
+	def := &walRelation{}
+
+	for _, a := range r.attributes {
+		wa := walAttribute{
+			Name:          a.name,
+			Type:          a.typeName,
+			AutoIncrement: a.autoIncrement,
+			NextValue:     a.nextValue,
+			RowID:         a.rowID,
+			ReuseRowID:    a.reuseRowID,
+			Hidden:        a.hidden,
+			Unique:        a.unique,
+			UniqueName:    a.uniqueName,
+			DefaultNow:    a.defaultNow,
+			NotNull:       a.notNull,
+			Size:          a.size,
+			Scale:         a.scale,
+		}
+		if a.defaultConst != nil {
+			wa.HasDefault = true
+			wa.Default = encodeValue(a.defaultConst)
+		} else if a.defaultValue != nil && !a.defaultNow {
+			log.Warn("default value of %s.%s cannot be persisted", r, a.name)
+		}
+		if a.fk != nil {
+			wa.FK = &walForeignKey{
+				Name:         a.fk.name,
+				LocalColumns: a.fk.localColumns,
+				RefSchema:    a.fk.refSchema,
+				RefRelation:  a.fk.refRelation,
+				RefColumns:   a.fk.refColumns,
+				OnDelete:     a.fk.onDelete,
+			}
+		}
+		def.Attributes = append(def.Attributes, wa)
+	}
+
+	for _, idx := range r.pk {
+		def.PK = append(def.PK, r.attributes[idx].name)
+	}
+
+	return def
+}
+
+func decodeRelation(def *walRelation) ([]Attribute, []string) {
+	var attrs []Attribute
+
+	for _, wa := range def.Attributes {
+		a := NewAttribute(wa.Name, wa.Type)
+		if wa.AutoIncrement {
+			a = a.WithAutoIncrement()
+			a.nextValue = wa.NextValue
+		}
+		if wa.RowID {
+			a = a.WithRowID()
+			a.reuseRowID = wa.ReuseRowID
+		}
+		if wa.Hidden {
+			a = a.WithHidden()
+		}
+		if wa.Unique {
+			a = a.WithUniqueConstraint(wa.UniqueName)
+		}
+		if wa.HasDefault {
+			a = a.WithDefaultConst(decodeValue(wa.Default))
+		}
+		if wa.DefaultNow {
+			a = a.WithDefaultNow()
+		}
+		if wa.NotNull {
+			a = a.WithNotNull()
+		}
+		a = a.WithSize(wa.Size, wa.Scale)
+		if wa.FK != nil {
+			a = a.WithForeignKeyStruct(ForeignKey{
+				name:         wa.FK.Name,
+				localColumns: wa.FK.LocalColumns,
+				refSchema:    wa.FK.RefSchema,
+				refRelation:  wa.FK.RefRelation,
+				refColumns:   wa.FK.RefColumns,
+				onDelete:     wa.FK.OnDelete,
+			})
+		}
+		attrs = append(attrs, a)
+	}
+
+	return attrs, def.PK
+}
+
+func encodeTuple(t *Tuple) []walValue {
+	vals := make([]walValue, len(t.values))
+	for i, v := range t.values {
+		vals[i] = encodeValue(v)
+	}
+	return vals
+}
+
+func decodeTuple(vals []walValue) *Tuple {
+	t := &Tuple{values: make([]any, len(vals))}
+	for i, v := range vals {
+		t.values[i] = decodeValue(v)
+	}
+	return t
+}
+
+func encodeValue(v any) walValue {
+	switch t := v.(type) {
+	case nil:
+		return walValue{Kind: walNull}
+	case int64:
+		return walValue{Kind: walInt, I: t}
+	case int:
+		return walValue{Kind: walInt, I: int64(t)}
+	case uint64:
+		return walValue{Kind: walUint, U: t}
+	case float64:
+		return walValue{Kind: walFloat, F: t}
+	case string:
+		return walValue{Kind: walString, S: t}
+	case bool:
+		return walValue{Kind: walBool, B: t}
+	case time.Time:
+		return walValue{Kind: walTime, T: t}
+	case []byte:
+		return walValue{Kind: walBytes, Raw: t}
+	case JSON:
+		return walValue{Kind: walJSON, S: t.Text()}
+	default:
+		if values, ok := ArrayValues(v); ok {
+			a := walValue{Kind: walArray, S: reflect.TypeOf(v).Elem().String()}
+			for _, e := range values {
+				a.A = append(a.A, encodeValue(e))
+			}
+			return a
+		}
+		return walValue{Kind: walString, S: fmt.Sprintf("%v", v)}
+	}
+}
+
+func decodeValue(v walValue) any {
+	switch v.Kind {
+	case walInt:
+		return v.I
+	case walUint:
+		return v.U
+	case walFloat:
+		return v.F
+	case walString:
+		return v.S
+	case walBool:
+		return v.B
+	case walTime:
+		return v.T
+	case walBytes:
+		return v.Raw
+	case walArray:
+		return decodeArray(v)
+	case walJSON:
+		j, err := ParseJSON(v.S)
+		if err != nil {
+			return v.S
+		}
+		return j
+	default:
+		return nil
+	}
+}
+
+// walArrayElemTypes are the Go types of array elements, by name
+var walArrayElemTypes = map[string]reflect.Type{
+	"int64":     reflect.TypeOf(int64(0)),
+	"uint64":    reflect.TypeOf(uint64(0)),
+	"float64":   reflect.TypeOf(float64(0)),
+	"string":    reflect.TypeOf(""),
+	"bool":      reflect.TypeOf(false),
+	"time.Time": reflect.TypeOf(time.Time{}),
+}
+
+func decodeArray(v walValue) any {
+	values := make([]any, len(v.A))
+	for i, e := range v.A {
+		values[i] = decodeValue(e)
+	}
+
+	t, ok := walArrayElemTypes[v.S]
+	if !ok {
+		return values
+	}
+	a := reflect.MakeSlice(reflect.SliceOf(t), len(values), len(values))
+	for i, e := range values {
+		if e != nil {
+			a.Index(i).Set(reflect.ValueOf(e).Convert(t))
+		}
+	}
+	return a.Interface()
+}
+
+func writeFrame(w io.Writer, rec *walRecord) error {
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(rec); err != nil {
+		return err
+	}
+
+	frame := make([]byte, 8+payload.Len())
+	binary.BigEndian.PutUint32(frame[0:4], uint32(payload.Len()))
+	binary.BigEndian.PutUint32(frame[4:8], crc32.ChecksumIEEE(payload.Bytes()))
+	copy(frame[8:], payload.Bytes())
+
+	_, err := w.Write(frame)
+	return err
+}
+
+// readFrame returns the next record and the size of its frame.
+// It returns io.EOF only if there is no more data.
+func readFrame(r io.Reader) (*walRecord, int64, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.EOF {
+			return nil, 0, io.EOF
+		}
+		return nil, 0, err
+	}
+
+	size := binary.BigEndian.Uint32(header[0:4])
+	sum := binary.BigEndian.Uint32(header[4:8])
+	if size > maxFrameSize {
+		return nil, 0, fmt.Errorf("frame too large (%d bytes)", size)
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, 0, err
+	}
+	if crc32.ChecksumIEEE(payload) != sum {
+		return nil, 0, fmt.Errorf("checksum mismatch")
+	}
+
+	rec := &walRecord{}
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(rec); err != nil {
+		return nil, 0, err
+	}
+
+	return rec, int64(8 + len(payload)), nil
+}
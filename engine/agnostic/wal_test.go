@@ -0,0 +1,249 @@
+package agnostic
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWALReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.wal")
+
+	e := NewEngine()
+	if err := e.Persist(path, -1); err != nil {
+		t.Fatalf("cannot persist engine: %s", err)
+	}
+
+	tx, err := e.Begin()
+	if err != nil {
+		t.Fatalf("cannot begin tx: %s", err)
+	}
+	attrs := []Attribute{
+		NewAttribute("id", "bigserial").WithAutoIncrement(),
+		NewAttribute("name", "text"),
+		NewAttribute("score", "int").WithDefaultConst(int64(7)),
+	}
+	if err := tx.CreateRelation("", "players", attrs, []string{"id"}); err != nil {
+		t.Fatalf("cannot create relation: %s", err)
+	}
+	for _, n := range []string{"zed", "lulu", "thresh"} {
+		if _, err := tx.Insert("", "players", map[string]any{"name": n}); err != nil {
+			t.Fatalf("cannot insert: %s", err)
+		}
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("cannot commit: %s", err)
+	}
+
+	tx, err = e.Begin()
+	if err != nil {
+		t.Fatalf("cannot begin tx: %s", err)
+	}
+	p := NewEqPredicate(NewAttributeValueFunctor("players", "name"), NewConstValueFunctor("lulu"))
+	if _, _, err := tx.Update("", "players", map[string]any{"score": int64(42)}, nil, p); err != nil {
+		t.Fatalf("cannot update: %s", err)
+	}
+	p = NewEqPredicate(NewAttributeValueFunctor("players", "name"), NewConstValueFunctor("zed"))
+	if _, _, err := tx.Delete("", "players", nil, p); err != nil {
+		t.Fatalf("cannot delete: %s", err)
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("cannot commit: %s", err)
+	}
+
+	// rolled back changes must not be persisted
+	tx, err = e.Begin()
+	if err != nil {
+		t.Fatalf("cannot begin tx: %s", err)
+	}
+	if _, err := tx.Insert("", "players", map[string]any{"name": "lux"}); err != nil {
+		t.Fatalf("cannot insert: %s", err)
+	}
+	tx.Rollback()
+
+	if err := e.Close(); err != nil {
+		t.Fatalf("cannot close engine: %s", err)
+	}
+
+	// restart
+	e = NewEngine()
+	if err := e.Persist(path, -1); err != nil {
+		t.Fatalf("cannot replay engine: %s", err)
+	}
+	defer e.Close()
+
+	checkPlayers(t, e, map[string]int64{"lulu": 42, "thresh": 7})
+
+	// autoincrement must resume after replay
+	tx, err = e.Begin()
+	if err != nil {
+		t.Fatalf("cannot begin tx: %s", err)
+	}
+	tuple, err := tx.Insert("", "players", map[string]any{"name": "ahri"})
+	if err != nil {
+		t.Fatalf("cannot insert: %s", err)
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("cannot commit: %s", err)
+	}
+	if id := tuple.values[0].(int64); id != 4 {
+		t.Fatalf("expected id 4 after replay, got %d", id)
+	}
+}
+
+func TestWALCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.wal")
+
+	e := NewEngine()
+	if err := e.Persist(path, 3); err != nil {
+		t.Fatalf("cannot persist engine: %s", err)
+	}
+
+	tx, err := e.Begin()
+	if err != nil {
+		t.Fatalf("cannot begin tx: %s", err)
+	}
+	attrs := []Attribute{
+		NewAttribute("name", "text"),
+		NewAttribute("score", "int"),
+	}
+	if err := tx.CreateRelation("", "players", attrs, nil); err != nil {
+		t.Fatalf("cannot create relation: %s", err)
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("cannot commit: %s", err)
+	}
+
+	for i, n := range []string{"zed", "lulu", "thresh", "lux"} {
+		tx, err := e.Begin()
+		if err != nil {
+			t.Fatalf("cannot begin tx: %s", err)
+		}
+		if _, err := tx.Insert("", "players", map[string]any{"name": n, "score": int64(i)}); err != nil {
+			t.Fatalf("cannot insert: %s", err)
+		}
+		if _, err := tx.Commit(); err != nil {
+			t.Fatalf("cannot commit: %s", err)
+		}
+	}
+
+	if _, err := os.Stat(path + snapshotSuffix); err != nil {
+		t.Fatalf("expected snapshot to be written: %s", err)
+	}
+	e.Close()
+
+	// simulate a crash in the middle of a commit
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("cannot open log: %s", err)
+	}
+	f.Write([]byte{0, 0, 1, 0, 42})
+	f.Close()
+
+	e = NewEngine()
+	if err := e.Persist(path, 3); err != nil {
+		t.Fatalf("cannot replay engine: %s", err)
+	}
+	defer e.Close()
+
+	checkPlayers(t, e, map[string]int64{"zed": 0, "lulu": 1, "thresh": 2, "lux": 3})
+}
+
+func TestWALCompactionCrash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.wal")
+
+	e := NewEngine()
+	if err := e.Persist(path, -1); err != nil {
+		t.Fatalf("cannot persist engine: %s", err)
+	}
+
+	tx, err := e.Begin()
+	if err != nil {
+		t.Fatalf("cannot begin tx: %s", err)
+	}
+	attrs := []Attribute{
+		NewAttribute("name", "text"),
+		NewAttribute("score", "int"),
+	}
+	if err := tx.CreateRelation("", "players", attrs, nil); err != nil {
+		t.Fatalf("cannot create relation: %s", err)
+	}
+	if _, err := tx.Insert("", "players", map[string]any{"name": "zed", "score": int64(1)}); err != nil {
+		t.Fatalf("cannot insert: %s", err)
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("cannot commit: %s", err)
+	}
+
+	compacted, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("cannot read log: %s", err)
+	}
+	if err := e.Compact(); err != nil {
+		t.Fatalf("cannot compact: %s", err)
+	}
+	e.Close()
+
+	// simulate a crash after the snapshot is written, before the log is started again
+	if err := os.WriteFile(path, compacted, 0o644); err != nil {
+		t.Fatalf("cannot write log: %s", err)
+	}
+
+	e = NewEngine()
+	if err := e.Persist(path, -1); err != nil {
+		t.Fatalf("cannot replay engine: %s", err)
+	}
+	checkPlayers(t, e, map[string]int64{"zed": 1})
+
+	// following commits are replayed after the snapshot
+	tx, err = e.Begin()
+	if err != nil {
+		t.Fatalf("cannot begin tx: %s", err)
+	}
+	if _, err := tx.Insert("", "players", map[string]any{"name": "lux", "score": int64(2)}); err != nil {
+		t.Fatalf("cannot insert: %s", err)
+	}
+	if _, err := tx.Commit(); err != nil {
+		t.Fatalf("cannot commit: %s", err)
+	}
+	e.Close()
+
+	e = NewEngine()
+	if err := e.Persist(path, -1); err != nil {
+		t.Fatalf("cannot replay engine: %s", err)
+	}
+	defer e.Close()
+	checkPlayers(t, e, map[string]int64{"zed": 1, "lux": 2})
+}
+
+func checkPlayers(t *testing.T, e *Engine, expected map[string]int64) {
+	t.Helper()
+
+	tx, err := e.Begin()
+	if err != nil {
+		t.Fatalf("cannot begin tx: %s", err)
+	}
+	defer tx.Rollback()
+
+	s := []Selector{NewStarSelector("players")}
+	_, res, err := tx.Query("", s, NewTruePredicate(), nil, nil)
+	if err != nil {
+		t.Fatalf("cannot query: %s", err)
+	}
+	if len(res) != len(expected) {
+		t.Fatalf("expected %d rows, got %d", len(expected), len(res))
+	}
+
+	name, _, _ := tx.RelationAttribute("", "players", "name")
+	score, _, _ := tx.RelationAttribute("", "players", "score")
+	for _, r := range res {
+		n := r.values[name].(string)
+		v, ok := expected[n]
+		if !ok {
+			t.Fatalf("unexpected row %v", r.values)
+		}
+		if r.values[score].(int64) != v {
+			t.Fatalf("expected score %d for %s, got %v", v, n, r.values[score])
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/proullon/ramsql/engine/parser"
+)
+
+// advisoryLocks holds PostgreSQL advisory locks of an engine.
+//
+// Locks are held by sessions and are reentrant: a session must unlock a key
+// as many times as it locked it. Transaction level locks are released when
+// the transaction ends.
+type advisoryLocks struct {
+	held map[int64]*advisoryLock
+
+	sync.Mutex
+}
+
+type advisoryLock struct {
+	owner *Session
+	count int
+	// released is closed once the lock is released by its owner
+	released chan struct{}
+}
+
+// lock takes key for owner. If wait is set, it blocks until other sessions release key or ctx is done.
+func (l *advisoryLocks) lock(ctx context.Context, owner *Session, key int64, wait bool) (bool, error) {
+	for {
+		l.Lock()
+		if l.held == nil {
+			l.held = make(map[int64]*advisoryLock)
+		}
+		a, ok := l.held[key]
+		if !ok {
+			l.held[key] = &advisoryLock{owner: owner, count: 1, released: make(chan struct{})}
+			l.Unlock()
+			return true, nil
+		}
+		if a.owner == owner {
+			a.count++
+			l.Unlock()
+			return true, nil
+		}
+		l.Unlock()
+
+		if !wait {
+			return false, nil
+		}
+		select {
+		case <-a.released:
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+}
+
+// unlock releases key once, returning false if owner does not hold it
+func (l *advisoryLocks) unlock(owner *Session, key int64) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	a, ok := l.held[key]
+	if !ok || a.owner != owner {
+		return false
+	}
+	a.count--
+	if a.count == 0 {
+		delete(l.held, key)
+		close(a.released)
+	}
+	return true
+}
+
+// unlockAll releases all locks held by owner
+func (l *advisoryLocks) unlockAll(owner *Session) {
+	l.Lock()
+	defer l.Unlock()
+
+	for key, a := range l.held {
+		if a.owner == owner {
+			delete(l.held, key)
+			close(a.released)
+		}
+	}
+}
+
+// ReleaseAdvisoryLocks releases advisory locks held by session s, to be called when its connection closes.
+func (e *Engine) ReleaseAdvisoryLocks(s *Session) {
+	e.locks.unlockAll(s)
+}
+
+// advisoryLock executes an advisory lock function and returns its result
+//
+// pg_advisory_lock, pg_advisory_xact_lock and pg_advisory_unlock_all return NULL (void),
+// other functions return whether the lock was taken or released.
+func (t *Tx) advisoryLock(decl *parser.Decl, args []NamedValue) (any, error) {
+	if decl.Lexeme == "pg_advisory_unlock_all" {
+		t.e.locks.unlockAll(t.session)
+		return nil, nil
+	}
+
+	key, err := advisoryKey(decl, args)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := t.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	switch decl.Lexeme {
+	case "pg_advisory_lock":
+		_, err = t.e.locks.lock(ctx, t.session, key, true)
+		return nil, err
+	case "pg_try_advisory_lock":
+		return t.e.locks.lock(ctx, t.session, key, false)
+	case "pg_advisory_xact_lock":
+		if _, err = t.e.locks.lock(ctx, t.session, key, true); err != nil {
+			return nil, err
+		}
+		t.xactLocks = append(t.xactLocks, key)
+		return nil, nil
+	case "pg_try_advisory_xact_lock":
+		ok, err := t.e.locks.lock(ctx, t.session, key, false)
+		if ok {
+			t.xactLocks = append(t.xactLocks, key)
+		}
+		return ok, err
+	case "pg_advisory_unlock":
+		return t.e.locks.unlock(t.session, key), nil
+	}
+
+	return nil, fmt.Errorf("function %s does not exist", decl.Lexeme)
+}
+
+// releaseXactLocks releases advisory locks taken with pg_advisory_xact_lock during the transaction
+func (t *Tx) releaseXactLocks() {
+	for _, key := range t.xactLocks {
+		t.e.locks.unlock(t.session, key)
+	}
+	t.xactLocks = nil
+}
+
+// advisoryKey returns the bigint key of an advisory lock, or combines two integer keys
+func advisoryKey(decl *parser.Decl, args []NamedValue) (int64, error) {
+	keys := make([]int64, len(decl.Decl))
+	for i, d := range decl.Decl {
+		var v any = d.Lexeme
+		if d.Token == parser.ArgToken {
+			var err error
+			if v, err = argValue(d, args); err != nil {
+				return 0, err
+			}
+		}
+
+		var k int64
+		switch rv := reflect.ValueOf(v); {
+		case rv.CanInt():
+			k = rv.Int()
+		case rv.CanUint():
+			k = int64(rv.Uint())
+		case rv.Kind() == reflect.String:
+			if _, err := fmt.Sscan(rv.String(), &k); err != nil {
+				return 0, fmt.Errorf("invalid advisory lock key %v", v)
+			}
+		default:
+			return 0, fmt.Errorf("invalid advisory lock key %v", v)
+		}
+		keys[i] = k
+	}
+
+	if len(keys) == 2 {
+		return keys[0]<<32 | int64(uint32(keys[1])), nil
+	}
+	return keys[0], nil
+}
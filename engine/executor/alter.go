@@ -0,0 +1,225 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/proullon/ramsql/engine/agnostic"
+	"github.com/proullon/ramsql/engine/parser"
+)
+
+/*
+|-> ALTER
+
+	|-> TABLE
+		|-> IF EXISTS, optional
+		|-> account
+	|-> ADD
+		|-> nickname
+			|-> varchar
+	|-> ALTER
+		|-> age
+			|-> TYPE
+				|-> bigint
+*/
+func alterExecutor(t *Tx, alterDecl *parser.Decl, args []NamedValue) (int64, int64, []agnostic.ColumnType, []*agnostic.Tuple, error) {
+	if len(alterDecl.Decl) < 2 || len(alterDecl.Decl[0].Decl) == 0 {
+		return 0, 0, nil, nil, ParsingError
+	}
+
+	objectDecl := alterDecl.Decl[0]
+	ifExists := hasIfExists(objectDecl)
+	nameDecl := objectDecl.Decl[len(objectDecl.Decl)-1]
+
+	var schema string
+	if d, ok := nameDecl.Has(parser.SchemaToken); ok {
+		schema = d.Lexeme
+	}
+	name := nameDecl.Lexeme
+
+	if objectDecl.Token == parser.IndexToken {
+		if !t.tx.CheckIndex(schema, name) {
+			if ifExists {
+				return 0, 0, nil, nil, nil
+			}
+			return 0, 0, nil, nil, fmt.Errorf("index %s does not exist", name)
+		}
+		renameDecl := alterDecl.Decl[1]
+		if len(renameDecl.Decl) != 1 {
+			return 0, 0, nil, nil, ParsingError
+		}
+		return 0, 0, nil, nil, t.tx.RenameIndex(schema, name, renameDecl.Decl[0].Lexeme)
+	}
+
+	if !t.tx.CheckRelation(schema, name) {
+		if ifExists {
+			return 0, 0, nil, nil, nil
+		}
+		return 0, 0, nil, nil, agnostic.NewError(agnostic.UndefinedTable, "relation %s does not exist", name)
+	}
+
+	for _, d := range alterDecl.Decl[1:] {
+		var err error
+		switch d.Token {
+		case parser.AddToken:
+			err = alterAdd(t, schema, name, d)
+		case parser.DropToken:
+			err = alterDrop(t, schema, name, d)
+		case parser.RenameToken:
+			err = alterRename(t, schema, name, d)
+		case parser.AlterToken:
+			err = alterColumn(t, schema, name, d)
+		default:
+			err = ParsingError
+		}
+		if err != nil {
+			return 0, 0, nil, nil, err
+		}
+	}
+
+	return 0, 0, nil, nil, nil
+}
+
+// alterAdd adds an attribute or a constraint to relation
+func alterAdd(t *Tx, schema, relation string, addDecl *parser.Decl) error {
+	if len(addDecl.Decl) == 0 {
+		return ParsingError
+	}
+	d := addDecl.Decl[len(addDecl.Decl)-1]
+
+	switch d.Token {
+	case parser.StringToken:
+		attr, isPk, err := parseAttribute(d)
+		if err != nil {
+			return err
+		}
+		if isPk {
+			return fmt.Errorf("cannot add PRIMARY KEY attribute %s to relation %s", attr.Name(), relation)
+		}
+		if _, _, err := t.tx.RelationAttribute(schema, relation, attr.Name()); err == nil && hasIfNotExists(addDecl) {
+			return nil
+		}
+		return t.tx.AddAttribute(schema, relation, attr)
+	case parser.ConstraintToken:
+		if len(d.Decl) < 2 {
+			return ParsingError
+		}
+		return alterAddConstraint(t, schema, relation, d.Decl[1], d.Decl[0].Lexeme)
+	default:
+		return alterAddConstraint(t, schema, relation, d, "")
+	}
+}
+
+// alterAddConstraint adds FOREIGN KEY or UNIQUE constraint cDecl to relation
+func alterAddConstraint(t *Tx, schema, relation string, cDecl *parser.Decl, name string) error {
+	switch cDecl.Token {
+	case parser.ForeignToken:
+		fk, err := parseTableForeignKey(cDecl, name)
+		if err != nil {
+			return err
+		}
+		return t.tx.AddForeignKey(schema, relation, fk)
+	case parser.UniqueToken:
+		var attrs []string
+		for _, d := range cDecl.Decl {
+			attrs = append(attrs, strings.ToLower(d.Lexeme))
+		}
+		return t.tx.AddUnique(schema, relation, name, attrs)
+	default:
+		return ParsingError
+	}
+}
+
+// alterDrop removes an attribute or a constraint from relation
+func alterDrop(t *Tx, schema, relation string, dropDecl *parser.Decl) error {
+	if len(dropDecl.Decl) == 0 || len(dropDecl.Decl[0].Decl) == 0 {
+		return ParsingError
+	}
+	d := dropDecl.Decl[0]
+	ifExists := hasIfExists(d)
+	name := d.Decl[len(d.Decl)-1].Lexeme
+
+	if d.Token == parser.ConstraintToken {
+		if ifExists && !t.tx.CheckConstraint(schema, relation, name) {
+			return nil
+		}
+		return t.tx.DropConstraint(schema, relation, name)
+	}
+
+	name = strings.ToLower(name)
+	if _, _, err := t.tx.RelationAttribute(schema, relation, name); err != nil && ifExists {
+		return nil
+	}
+	return t.tx.DropAttribute(schema, relation, name)
+}
+
+// alterRename renames relation, or one of its attributes
+func alterRename(t *Tx, schema, relation string, renameDecl *parser.Decl) error {
+	if len(renameDecl.Decl) == 0 {
+		return ParsingError
+	}
+	newName := renameDecl.Decl[len(renameDecl.Decl)-1].Lexeme
+
+	if renameDecl.Decl[0].Token == parser.ColumnToken {
+		if len(renameDecl.Decl[0].Decl) == 0 {
+			return ParsingError
+		}
+		name := strings.ToLower(renameDecl.Decl[0].Decl[0].Lexeme)
+		return t.tx.RenameAttribute(schema, relation, name, strings.ToLower(newName))
+	}
+
+	return t.tx.RenameRelation(schema, relation, newName)
+}
+
+// alterColumn changes the type, nullability or default value of an attribute of relation
+func alterColumn(t *Tx, schema, relation string, alterDecl *parser.Decl) error {
+	if len(alterDecl.Decl) == 0 || len(alterDecl.Decl[0].Decl) == 0 {
+		return ParsingError
+	}
+	columnDecl := alterDecl.Decl[0]
+	name := strings.ToLower(columnDecl.Lexeme)
+
+	_, attr, err := t.tx.RelationAttribute(schema, relation, name)
+	if err != nil {
+		return err
+	}
+
+	actionDecl := columnDecl.Decl[0]
+	if len(actionDecl.Decl) == 0 {
+		return ParsingError
+	}
+	d := actionDecl.Decl[0]
+
+	switch {
+	case actionDecl.Token == parser.TypeToken:
+		typeName, size, scale, err := parseAttributeType(name, d)
+		if err != nil {
+			return err
+		}
+		attr = attr.WithType(typeName)
+		if size > 0 {
+			attr = attr.WithSize(size, scale)
+		}
+	case actionDecl.Token == parser.SetToken && d.Token == parser.NotToken:
+		attr = attr.WithNotNull()
+	case actionDecl.Token == parser.DropToken && d.Token == parser.NotToken:
+		attr = attr.WithNullable()
+	case actionDecl.Token == parser.SetToken && d.Token == parser.DefaultToken:
+		if len(d.Decl) == 0 {
+			return ParsingError
+		}
+		attr = attr.WithoutDefault()
+		if d.Decl[0].Token != parser.NullToken {
+			attr, err = withDefault(attr, d.Decl[0], attr.TypeName())
+			if err != nil {
+				return err
+			}
+		}
+	case actionDecl.Token == parser.DropToken && d.Token == parser.DefaultToken:
+		attr = attr.WithoutDefault()
+	default:
+		return ParsingError
+	}
+
+	return t.tx.AlterAttribute(schema, relation, attr)
+}
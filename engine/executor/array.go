@@ -0,0 +1,313 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/proullon/ramsql/engine/agnostic"
+	"github.com/proullon/ramsql/engine/log"
+	"github.com/proullon/ramsql/engine/parser"
+)
+
+// unnestCount numbers relations holding unnest(...) and json_array_elements(...) elements
+var unnestCount atomic.Int64
+
+// arrayValue returns elements of an ARRAY[...] constructor
+func arrayValue(arrayDecl *parser.Decl, args []NamedValue) (any, error) {
+	values := make([]any, 0, len(arrayDecl.Decl))
+	for _, d := range arrayDecl.Decl {
+		var v any
+		var err error
+		switch d.Token {
+		case parser.ArgToken:
+			v, err = argValue(d, args)
+		case parser.NullToken:
+			return nil, fmt.Errorf("NULL array elements are not supported")
+		case parser.ArrayToken:
+			return nil, fmt.Errorf("multidimensional arrays are not supported")
+		case parser.NumberToken:
+			v, err = agnostic.ToInstance(d.Lexeme, "bigint")
+		case parser.FloatToken:
+			v, err = agnostic.ToInstance(d.Lexeme, "float")
+		case parser.TrueToken, parser.FalseToken:
+			v, err = agnostic.ToInstance(d.Lexeme, "bool")
+		case parser.DateToken:
+			v, err = agnostic.ToInstance(d.Lexeme, "timestamp")
+		default:
+			v = d.Lexeme
+		}
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return agnostic.NewArray(values), nil
+}
+
+// arrayOperand returns the array operand of ANY, ALL or an array operator: a parameter,
+// ARRAY[...], a subquery, a '{...}' literal or an attribute. Literals and parameters
+// sent as text are parsed as arrays of elemType.
+func (t *Tx) arrayOperand(d *parser.Decl, schema, fromTableName, elemType string, args []NamedValue, aliases map[string]string) (agnostic.ValueFunctor, error) {
+	switch d.Token {
+	case parser.ArgToken:
+		v, err := argValue(d, args)
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := v.(string); ok {
+			v, err = agnostic.ParseArray(s, elemType)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return agnostic.NewConstValueFunctor(v), nil
+	case parser.ArrayToken:
+		v, err := arrayValue(d, args)
+		if err != nil {
+			return nil, err
+		}
+		return agnostic.NewConstValueFunctor(v), nil
+	case parser.SelectToken:
+		values, err := subqueryValues(t, d, args, "")
+		if err != nil {
+			return nil, err
+		}
+		return agnostic.NewConstValueFunctor(values), nil
+	}
+
+	// identifiers cannot start with a brace
+	if len(d.Decl) == 0 && strings.HasPrefix(d.Lexeme, "{") {
+		v, err := agnostic.ParseArray(d.Lexeme, elemType)
+		if err != nil {
+			return nil, err
+		}
+		return agnostic.NewConstValueFunctor(v), nil
+	}
+
+	relation := fromTableName
+	if len(d.Decl) > 0 {
+		relation = getAlias(d.Decl[0].Lexeme, aliases)
+	}
+	attribute := strings.ToLower(d.Lexeme)
+	if _, _, err := t.tx.RelationAttribute(schema, relation, attribute); err != nil {
+		return nil, err
+	}
+	return agnostic.NewAttributeValueFunctor(relation, attribute), nil
+}
+
+// isArrayComparison reports whether cond compares a value with ANY or ALL array elements
+func isArrayComparison(cond *parser.Decl) bool {
+	n := len(cond.Decl)
+	return n >= 2 && (cond.Decl[n-1].Token == parser.AnyToken || cond.Decl[n-1].Token == parser.AllToken)
+}
+
+// arrayComparisonExecutor builds a predicate for value op ANY(array) or value op ALL(array),
+// value being an attribute, optionally qualified, or a literal as in 'go' = ANY(tags)
+func (t *Tx) arrayComparisonExecutor(cond *parser.Decl, schema, fromTableName string, args []NamedValue, aliases map[string]string) (agnostic.Predicate, error) {
+	n := len(cond.Decl)
+	op, arrayDecl := cond.Decl[n-2], cond.Decl[n-1]
+	if len(arrayDecl.Decl) == 0 {
+		return nil, ParsingError
+	}
+
+	var left agnostic.ValueFunctor
+	var elemType string
+	switch cond.Token {
+	case parser.ArgToken:
+		v, err := argValue(cond, args)
+		if err != nil {
+			return nil, err
+		}
+		left, elemType = agnostic.NewConstValueFunctor(v), inferType(v)
+	case parser.SimpleQuoteToken:
+		left, elemType = agnostic.NewConstValueFunctor(cond.Lexeme), "text"
+	case parser.NumberToken, parser.FloatToken, parser.TrueToken, parser.FalseToken:
+		elemType = "bigint"
+		switch cond.Token {
+		case parser.FloatToken:
+			elemType = "float"
+		case parser.TrueToken, parser.FalseToken:
+			elemType = "bool"
+		}
+		v, err := agnostic.ToInstance(cond.Lexeme, elemType)
+		if err != nil {
+			return nil, err
+		}
+		left = agnostic.NewConstValueFunctor(v)
+	default:
+		if n == 3 {
+			fromTableName = cond.Decl[0].Lexeme
+		}
+		fromTableName = getAlias(fromTableName, aliases)
+		attribute := strings.ToLower(cond.Lexeme)
+		_, attr, err := t.tx.RelationAttribute(schema, fromTableName, attribute)
+		if err != nil {
+			return nil, err
+		}
+		left, elemType = agnostic.NewAttributeValueFunctor(fromTableName, attribute), attr.TypeName()
+	}
+
+	right, err := t.arrayOperand(arrayDecl.Decl[0], schema, fromTableName, elemType, args, aliases)
+	if err != nil {
+		return nil, err
+	}
+
+	ptype, err := comparisonType(op)
+	if err != nil {
+		return nil, err
+	}
+	p := agnostic.NewAnyPredicate(left, ptype, right)
+	if arrayDecl.Token == parser.AllToken {
+		p = agnostic.NewAllPredicate(left, ptype, right)
+	}
+	// without attribute, as in 5 = ANY($1), the comparison filters rows of the FROM relation
+	return p.WithRelation(getAlias(fromTableName, aliases)), nil
+}
+
+// arrayOperatorExecutor builds a predicate for attribute @> array, attribute <@ array
+// or attribute && array
+func (t *Tx) arrayOperatorExecutor(cond *parser.Decl, schema, fromTableName string, args []NamedValue, aliases map[string]string) (agnostic.Predicate, error) {
+	attribute := strings.ToLower(cond.Lexeme)
+	_, attr, err := t.tx.RelationAttribute(schema, fromTableName, attribute)
+	if err != nil {
+		return nil, err
+	}
+	left := agnostic.NewAttributeValueFunctor(fromTableName, attribute)
+
+	right, err := t.arrayOperand(cond.Decl[1], schema, fromTableName, agnostic.ArrayElemType(attr.TypeName()), args, aliases)
+	if err != nil {
+		return nil, err
+	}
+
+	var ptype agnostic.PredicateType
+	switch cond.Decl[0].Token {
+	case parser.ContainsToken:
+		ptype = agnostic.Contains
+	case parser.ContainedByToken:
+		ptype = agnostic.ContainedBy
+	case parser.OverlapToken:
+		ptype = agnostic.Overlap
+	default:
+		return nil, fmt.Errorf("unknown array operator %s", cond.Decl[0].Lexeme)
+	}
+	return agnostic.NewArrayPredicate(left, ptype, right), nil
+}
+
+// arrayLengthSelector selects array_length(attribute, 1)
+func (t *Tx) arrayLengthSelector(attr *parser.Decl, schema string, tables []string, aliases map[string]string) (agnostic.Selector, error) {
+	if len(attr.Decl) == 0 {
+		return nil, ParsingError
+	}
+	attrDecl := attr.Decl[0]
+	attribute := strings.ToLower(attrDecl.Lexeme)
+
+	candidates := tables
+	if len(attrDecl.Decl) > 0 {
+		candidates = []string{attrDecl.Decl[0].Lexeme}
+	}
+	err := fmt.Errorf("column \"%s\" does not exist", attribute)
+	for _, table := range candidates {
+		relation := getAlias(table, aliases)
+		if _, _, err = t.tx.RelationAttribute(schema, relation, attribute); err == nil {
+			f := agnostic.NewArrayLengthValueFunctor(agnostic.NewAttributeValueFunctor(relation, attribute))
+			return agnostic.NewExprSelector(relation, "array_length", f), nil
+		}
+	}
+	return nil, err
+}
+
+// unnestRelations replaces unnest(...) and json_array_elements(...) sources of fromDecl with
+// relations holding array elements. Returned relations must be dropped once query rows are read.
+func (t *Tx) unnestRelations(fromDecl *parser.Decl, args []NamedValue) (*parser.Decl, []string, error) {
+	var relations []string
+	from := fromDecl
+	for i, d := range fromDecl.Decl {
+		if d.Token != parser.UnnestToken && d.Token != parser.JSONArrayElementsToken {
+			continue
+		}
+		// copy declaration so parsed statements can be executed again
+		if from == fromDecl {
+			from = &parser.Decl{Token: fromDecl.Token, Lexeme: fromDecl.Lexeme, Decl: append([]*parser.Decl(nil), fromDecl.Decl...)}
+		}
+		tableDecl, err := t.unnestRelation(d, args)
+		if err != nil {
+			t.dropRelations(relations)
+			return nil, nil, err
+		}
+		relations = append(relations, tableDecl.Lexeme)
+		from.Decl[i] = tableDecl
+	}
+	return from, relations, nil
+}
+
+// unnestRelation creates a relation holding a row per element of unnest(array) or
+// json_array_elements(document).
+//
+// Its only attribute is named after the column alias, else after the relation alias or unnest
+// for unnest(...), and value for json_array_elements(...).
+func (t *Tx) unnestRelation(unnestDecl *parser.Decl, args []NamedValue) (*parser.Decl, error) {
+	if len(unnestDecl.Decl) == 0 {
+		return nil, ParsingError
+	}
+
+	var values []any
+	column, typeName := "unnest", "text"
+	if unnestDecl.Token == parser.JSONArrayElementsToken {
+		var err error
+		values, err = t.jsonArrayElementsValues(unnestDecl, args)
+		if err != nil {
+			return nil, err
+		}
+		column, typeName = "value", "jsonb"
+	} else {
+		f, err := t.arrayOperand(unnestDecl.Decl[0], "", "", "text", args, nil)
+		if err != nil {
+			return nil, err
+		}
+		v := f.Value(nil, nil)
+		var ok bool
+		values, ok = agnostic.ArrayValues(v)
+		if !ok {
+			return nil, fmt.Errorf("cannot unnest %v, not an array", v)
+		}
+	}
+
+	name := fmt.Sprintf("%s_%d", unnestDecl.Lexeme, unnestCount.Add(1))
+	tableDecl := &parser.Decl{Token: parser.StringToken, Lexeme: name}
+	if len(unnestDecl.Decl) > 1 {
+		alias := unnestDecl.Decl[1]
+		tableDecl.Add(&parser.Decl{Token: parser.StringToken, Lexeme: alias.Lexeme})
+		// json_array_elements column is named value
+		if unnestDecl.Token == parser.UnnestToken {
+			column = alias.Lexeme
+		}
+		if len(alias.Decl) > 0 {
+			column = alias.Decl[0].Lexeme
+		}
+	}
+	column = strings.ToLower(column)
+
+	if len(values) > 0 {
+		typeName = inferType(values[0])
+	}
+	if err := t.tx.CreateRelation("", name, []agnostic.Attribute{agnostic.NewAttribute(column, typeName)}, nil); err != nil {
+		return nil, err
+	}
+	for _, e := range values {
+		if _, err := t.tx.Insert("", name, map[string]any{column: e}); err != nil {
+			t.dropRelations([]string{name})
+			return nil, err
+		}
+	}
+	return tableDecl, nil
+}
+
+// dropRelations drops relations created while executing a query
+func (t *Tx) dropRelations(relations []string) {
+	for _, r := range relations {
+		if err := t.tx.DropRelation("", r); err != nil {
+			log.Warn("cannot drop relation %s: %s", r, err)
+		}
+	}
+}
@@ -2,15 +2,15 @@ package executor
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
-	"time"
 
 	"github.com/proullon/ramsql/engine/agnostic"
 	"github.com/proullon/ramsql/engine/parser"
 )
 
 func parseAttribute(decl *parser.Decl) (attr agnostic.Attribute, isPk bool, err error) {
-	var name, typeName string
+	var name string
 
 	// Attribute name
 	if decl.Token != parser.StringToken {
@@ -22,20 +22,15 @@ func parseAttribute(decl *parser.Decl) (attr agnostic.Attribute, isPk bool, err
 	if len(decl.Decl) < 1 {
 		return attr, false, fmt.Errorf("Attribute %s has no type", decl.Lexeme)
 	}
-	switch decl.Decl[0].Token {
-	case parser.DecimalToken:
-		typeName = "float"
-	case parser.NumberToken:
-		typeName = "int"
-	case parser.DateToken:
-		typeName = "date"
-	case parser.StringToken:
-		typeName = decl.Decl[0].Lexeme
-	default:
-		return agnostic.Attribute{}, false, fmt.Errorf("engine: expected attribute type, got %v:%v", decl.Decl[0].Token, decl.Decl[0].Lexeme)
+	typeName, size, scale, err := parseAttributeType(name, decl.Decl[0])
+	if err != nil {
+		return agnostic.Attribute{}, false, err
 	}
 
 	attr = agnostic.NewAttribute(name, typeName)
+	if size > 0 {
+		attr = attr.WithSize(size, scale)
+	}
 
 	// Maybe domain and special thing like primary key
 	typeDecl := decl.Decl[1:]
@@ -45,15 +40,9 @@ func parseAttribute(decl *parser.Decl) (attr agnostic.Attribute, isPk bool, err
 		}
 
 		if typeDecl[i].Token == parser.DefaultToken {
-			switch typeDecl[i].Decl[0].Token {
-			case parser.LocalTimestampToken, parser.NowToken:
-				attr = attr.WithDefault(func() any { return time.Now() })
-			default:
-				v, err := agnostic.ToInstance(typeDecl[i].Decl[0].Lexeme, typeName)
-				if err != nil {
-					return agnostic.Attribute{}, false, err
-				}
-				attr = attr.WithDefaultConst(v)
+			attr, err = withDefault(attr, typeDecl[i].Decl[0], typeName)
+			if err != nil {
+				return agnostic.Attribute{}, false, err
 			}
 		}
 
@@ -61,6 +50,9 @@ func parseAttribute(decl *parser.Decl) (attr agnostic.Attribute, isPk bool, err
 		if typeDecl[i].Token == parser.UniqueToken {
 			attr = attr.WithUnique()
 		}
+		if typeDecl[i].Token == parser.NotToken {
+			attr = attr.WithNotNull()
+		}
 		if typeDecl[i].Token == parser.PrimaryToken {
 			if len(typeDecl[i].Decl) > 0 && typeDecl[i].Decl[0].Token == parser.KeyToken {
 				isPk = true
@@ -97,13 +89,69 @@ func parseAttribute(decl *parser.Decl) (attr agnostic.Attribute, isPk bool, err
 
 	}
 
-	if strings.ToLower(typeName) == "bigserial" {
+	switch strings.ToLower(typeName) {
+	case "serial", "bigserial":
 		attr = attr.WithAutoIncrement()
 	}
 
 	return attr, isPk, nil
 }
 
+// parseAttributeType returns the type name of attribute name, and its declared size,
+// such as VARCHAR(255) or DECIMAL(10, 2). Array type names end with [], such as int[]
+func parseAttributeType(name string, typeDecl *parser.Decl) (typeName string, size, scale int64, err error) {
+	switch typeDecl.Token {
+	case parser.DecimalToken:
+		typeName = "float"
+	case parser.NumberToken:
+		typeName = "int"
+	case parser.DateToken:
+		typeName = "date"
+	case parser.StringToken:
+		typeName = typeDecl.Lexeme
+	default:
+		return "", 0, 0, fmt.Errorf("engine: expected attribute type, got %v:%v", typeDecl.Token, typeDecl.Lexeme)
+	}
+	if _, ok := typeDecl.Has(parser.SquareBracketOpeningToken); ok {
+		typeName += "[]"
+	}
+
+	var sizeDecl []*parser.Decl
+	for _, d := range typeDecl.Decl {
+		if d.Token == parser.NumberToken {
+			sizeDecl = append(sizeDecl, d)
+		}
+	}
+	if len(sizeDecl) > 0 {
+		size, err = strconv.ParseInt(sizeDecl[0].Lexeme, 10, 64)
+		if err != nil {
+			return "", 0, 0, fmt.Errorf("wrong size for attribute %s: %w", name, err)
+		}
+		if len(sizeDecl) > 1 {
+			scale, err = strconv.ParseInt(sizeDecl[1].Lexeme, 10, 64)
+			if err != nil {
+				return "", 0, 0, fmt.Errorf("wrong scale for attribute %s: %w", name, err)
+			}
+		}
+	}
+
+	return typeName, size, scale, nil
+}
+
+// withDefault sets the default value of attr from the value of a DEFAULT clause
+func withDefault(attr agnostic.Attribute, valueDecl *parser.Decl, typeName string) (agnostic.Attribute, error) {
+	switch valueDecl.Token {
+	case parser.LocalTimestampToken, parser.NowToken:
+		return attr.WithDefaultNow(), nil
+	default:
+		v, err := agnostic.ToInstance(valueDecl.Lexeme, typeName)
+		if err != nil {
+			return agnostic.Attribute{}, err
+		}
+		return attr.WithDefaultConst(v), nil
+	}
+}
+
 // parseReferencesDecl extracts schema, table, column, and ON DELETE action from a REFERENCES decl node.
 // Returns (refSchema, refTable, refCol, onDelete, error).
 // If schema is not specified, refSchema is empty (meaning same schema as referencing table).
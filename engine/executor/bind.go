@@ -0,0 +1,171 @@
+package executor
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/proullon/ramsql/engine/parser"
+)
+
+// bind resolves parameter markers of instructions against args.
+//
+// ODBC markers (?) are numbered in order of appearance across all instructions,
+// $n markers reference the n-th argument and :name or @name markers reference
+// the argument of the same name, as provided by sql.Named.
+//
+// Every marker is replaced by an ArgToken holding the index of its value in the
+// returned arguments, starting at 1, so executors resolve all of them with argValue.
+// Declarations holding markers are copied, instructions are left untouched so a
+// prepared statement can be bound several times.
+//
+// bind returns an error if a marker has no matching argument, or if an argument
+// is not referenced by any marker.
+func bind(instructions []parser.Instruction, args []NamedValue) ([]parser.Instruction, []NamedValue, error) {
+	b := &binder{
+		args:  args,
+		used:  make([]bool, len(args)),
+		index: make(map[int]int),
+	}
+
+	bound := make([]parser.Instruction, len(instructions))
+	for i, inst := range instructions {
+		bound[i] = inst
+		decls, _, err := b.decls(inst.Decls)
+		if err != nil {
+			return nil, nil, err
+		}
+		bound[i].Decls = decls
+	}
+
+	for i, used := range b.used {
+		if used {
+			continue
+		}
+		if args[i].Name != "" && !isOrdinalName(args[i].Name) {
+			return nil, nil, fmt.Errorf("unexpected argument %s, query has no parameter :%s", args[i].Name, args[i].Name)
+		}
+		return nil, nil, fmt.Errorf("unexpected argument $%d, query has %d positional parameters", i+1, b.positional)
+	}
+
+	return bound, b.values, nil
+}
+
+// argValue returns the value of a marker bound by bind
+func argValue(d *parser.Decl, args []NamedValue) (any, error) {
+	idx, err := strconv.Atoi(d.Lexeme)
+	if err != nil || idx < 1 || idx > len(args) {
+		return nil, fmt.Errorf("unbound parameter %s", d.Lexeme)
+	}
+	return args[idx-1].Value, nil
+}
+
+type binder struct {
+	args []NamedValue
+	used []bool
+	// index maps position in args to position in values
+	index map[int]int
+	// values holds bound arguments, in order of first reference
+	values []NamedValue
+	// odbc counts ? markers
+	odbc int
+	// positional is the highest position referenced by ? or $n
+	positional int
+}
+
+// decls binds markers of decls, returning a copy if any of them changed
+func (b *binder) decls(decls []*parser.Decl) ([]*parser.Decl, bool, error) {
+	var bound []*parser.Decl
+	for i, d := range decls {
+		nd, err := b.decl(d)
+		if err != nil {
+			return nil, false, err
+		}
+		if nd != d && bound == nil {
+			bound = append([]*parser.Decl(nil), decls...)
+		}
+		if bound != nil {
+			bound[i] = nd
+		}
+	}
+	if bound == nil {
+		return decls, false, nil
+	}
+	return bound, true, nil
+}
+
+func (b *binder) decl(d *parser.Decl) (*parser.Decl, error) {
+	if d.Token == parser.ArgToken || d.Token == parser.NamedArgToken {
+		idx, err := b.marker(d)
+		if err != nil {
+			return nil, err
+		}
+		// a marker compared with ANY or ALL elements holds the comparison, as in $1 = ANY($2)
+		children, _, err := b.decls(d.Decl)
+		if err != nil {
+			return nil, err
+		}
+		return &parser.Decl{Token: parser.ArgToken, Lexeme: strconv.Itoa(idx), Decl: children}, nil
+	}
+
+	children, changed, err := b.decls(d.Decl)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return d, nil
+	}
+
+	nd := *d
+	nd.Decl = children
+	return &nd, nil
+}
+
+// marker returns the index in bound values of the argument referenced by d
+func (b *binder) marker(d *parser.Decl) (int, error) {
+	pos := -1
+
+	switch {
+	case d.Token == parser.NamedArgToken:
+		for i, arg := range b.args {
+			if arg.Name == d.Lexeme {
+				pos = i
+				break
+			}
+		}
+		if pos == -1 {
+			return 0, fmt.Errorf("missing argument for parameter :%s", d.Lexeme)
+		}
+	case d.Lexeme == "?":
+		b.odbc++
+		pos = b.odbc - 1
+	default:
+		n, err := strconv.Atoi(d.Lexeme)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("invalid parameter $%s", d.Lexeme)
+		}
+		pos = n - 1
+	}
+
+	if d.Token == parser.ArgToken {
+		if pos+1 > b.positional {
+			b.positional = pos + 1
+		}
+		if pos >= len(b.args) {
+			return 0, fmt.Errorf("missing argument for parameter $%d, %d provided", pos+1, len(b.args))
+		}
+	}
+
+	if idx, ok := b.index[pos]; ok {
+		return idx, nil
+	}
+	b.used[pos] = true
+	b.values = append(b.values, b.args[pos])
+	b.index[pos] = len(b.values)
+	return len(b.values), nil
+}
+
+// isOrdinalName returns true for names database/sql drivers give positional arguments
+func isOrdinalName(name string) bool {
+	_, err := strconv.Atoi(name)
+	return err == nil
+}
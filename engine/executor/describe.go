@@ -0,0 +1,178 @@
+package executor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/proullon/ramsql/engine/agnostic"
+	"github.com/proullon/ramsql/engine/parser"
+)
+
+// Command returns the command of the first statement of p in upper case, as
+// reported by PostgreSQL command tags: SELECT, INSERT, CREATE TABLE, DROP INDEX...
+func (p *Prepared) Command() string {
+	if len(p.instructions) == 0 || len(p.instructions[0].Decls) == 0 {
+		return ""
+	}
+
+	d := p.instructions[0].Decls[0]
+	switch d.Token {
+	case parser.CreateToken, parser.DropToken:
+		if len(d.Decl) > 0 {
+			return strings.ToUpper(d.Lexeme + " " + d.Decl[0].Lexeme)
+		}
+	case parser.WithToken:
+		return "SELECT"
+	case parser.TruncateToken:
+		return "TRUNCATE TABLE"
+	}
+	return strings.ToUpper(d.Lexeme)
+}
+
+// NumStatement returns the number of statements of p.
+func (p *Prepared) NumStatement() int {
+	return len(p.instructions)
+}
+
+// ReturnsRows reports whether the first statement of p returns rows: SELECT,
+// SHOW, PRAGMA, or a statement with a RETURNING clause.
+func (p *Prepared) ReturnsRows() bool {
+	if len(p.instructions) == 0 || len(p.instructions[0].Decls) == 0 {
+		return false
+	}
+
+	d := p.instructions[0].Decls[0]
+	switch d.Token {
+	case parser.SelectToken, parser.WithToken, parser.ShowToken, parser.PragmaToken:
+		return true
+	case parser.InsertToken, parser.UpdateToken, parser.DeleteToken:
+		_, ok := d.Has(parser.ReturningToken)
+		return ok
+	}
+	return false
+}
+
+// Describe returns the columns of rows returned by the first statement of p,
+// without changing any relation. Statements not returning rows have no columns.
+//
+// Parameters are bound to the zero value of their type, so columns typed from
+// their values may differ from the ones returned once executed.
+func (t *Tx) Describe(p *Prepared) ([]agnostic.ColumnType, error) {
+	if !p.ReturnsRows() {
+		return nil, nil
+	}
+
+	instructions, args, err := bind(p.instructions[:1], p.zeroArgs())
+	if err != nil {
+		return nil, err
+	}
+	inst := instructions[0]
+	decl := inst.Decls[0]
+
+	switch decl.Token {
+	case parser.SelectToken:
+		c, err := selectCursor(t, decl, args)
+		if err != nil {
+			return nil, err
+		}
+		return c.ColumnTypes(), nil
+	case parser.WithToken:
+		// common table expressions are stored in relations, which are dropped with a throwaway transaction
+		tx, err := NewTx(context.Background(), t.e, sql.TxOptions{})
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback()
+		tx.SetSession(t.session)
+		rows, err := tx.query(inst, args)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		return rows.Columns(), nil
+	case parser.ShowToken:
+		_, _, cols, _, err := showExecutor(t, decl, args)
+		return cols, err
+	case parser.PragmaToken:
+		_, _, cols, _, err := pragmaExecutor(t, decl, args)
+		return cols, err
+	case parser.InsertToken:
+		return t.describeInsert(decl)
+	case parser.UpdateToken:
+		return t.describeReturning(decl, decl.Decl[0], t.tx.UpdateColumns)
+	case parser.DeleteToken:
+		return t.describeReturning(decl, decl.Decl[0].Decl[0], func(schema, relation string, _ map[string]any, selectors []agnostic.Selector, p agnostic.Predicate) ([]agnostic.ColumnType, []*agnostic.Tuple, error) {
+			return t.tx.DeleteColumns(schema, relation, selectors, p)
+		})
+	}
+
+	return nil, nil
+}
+
+// describeInsert returns the columns of an INSERT RETURNING clause
+func (t *Tx) describeInsert(insertDecl *parser.Decl) ([]agnostic.ColumnType, error) {
+	var schema string
+	relationDecl := insertDecl.Decl[0].Decl[0]
+	if d, ok := relationDecl.Has(parser.SchemaToken); ok {
+		schema = d.Lexeme
+	}
+
+	var cols []agnostic.ColumnType
+	for _, d := range insertDecl.Decl {
+		if d.Token != parser.ReturningToken {
+			continue
+		}
+		_, attr, err := t.tx.RelationAttribute(schema, relationDecl.Lexeme, d.Decl[0].Lexeme)
+		if err != nil {
+			return nil, fmt.Errorf("cannot return %s, doesn't exist in relation %s", d.Decl[0].Lexeme, relationDecl.Lexeme)
+		}
+		c := attr.ColumnType()
+		c.Name = d.Decl[0].Lexeme
+		cols = append(cols, c)
+	}
+	return cols, nil
+}
+
+// describeReturning returns the columns of an UPDATE or DELETE RETURNING clause, applying it on no row
+func (t *Tx) describeReturning(decl, relationDecl *parser.Decl, apply func(string, string, map[string]any, []agnostic.Selector, agnostic.Predicate) ([]agnostic.ColumnType, []*agnostic.Tuple, error)) ([]agnostic.ColumnType, error) {
+	var schema string
+	if d, ok := relationDecl.Has(parser.SchemaToken); ok {
+		schema = d.Lexeme
+	}
+
+	d, _ := decl.Has(parser.ReturningToken)
+	selectors, err := t.getReturning(d, schema, relationDecl.Lexeme)
+	if err != nil {
+		return nil, err
+	}
+
+	cols, _, err := apply(schema, relationDecl.Lexeme, map[string]any{}, selectors, agnostic.NewFalsePredicate())
+	return cols, err
+}
+
+// zeroArgs returns an argument for each parameter of p, with the zero value of its type
+func (p *Prepared) zeroArgs() []NamedValue {
+	var positional, named []NamedValue
+	seen := make(map[string]bool)
+	for _, param := range p.Params {
+		var v any
+		if param.Type.ScanType != nil {
+			v = reflect.Zero(param.Type.ScanType).Interface()
+		}
+		if param.Name != "" {
+			if !seen[param.Name] {
+				seen[param.Name] = true
+				named = append(named, NamedValue{Name: param.Name, Value: v})
+			}
+			continue
+		}
+		for len(positional) < param.Ordinal {
+			positional = append(positional, NamedValue{Ordinal: len(positional) + 1})
+		}
+		positional[param.Ordinal-1].Value = v
+	}
+	return append(positional, named...)
+}
@@ -8,6 +8,8 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/proullon/ramsql/engine/agnostic"
 	"github.com/proullon/ramsql/engine/log"
@@ -18,6 +20,13 @@ import (
 type Engine struct {
 	memstore *agnostic.Engine
 	dbName   string
+	dialect  string
+	history  *history
+	hooks    hooks
+	faults   faults
+	locks    advisoryLocks
+	txID     atomic.Int64
+	stopped  atomic.Bool
 }
 
 // New initialize a new RamSQL server
@@ -31,6 +40,13 @@ func NewEngineWithName(dbName string) (e *Engine, err error) {
 	e = &Engine{
 		memstore: agnostic.NewEngine(),
 		dbName:   dbName,
+		history:  newHistory(DefaultHistorySize),
+	}
+	e.memstore.SetCatalogName(dbName)
+
+	err = e.memstore.CreateVirtualRelation("ramsql", "query_history", historyAttributes(), e.history.rows)
+	if err != nil {
+		return nil, err
 	}
 
 	return
@@ -45,33 +61,107 @@ func (e *Engine) Begin() (*Tx, error) {
 	return tx, nil
 }
 
+// Stop closes the engine. Connections to a stopped engine fail with driver.ErrBadConn, see ConnFault.
 func (e *Engine) Stop() {
+	if !e.stopped.CompareAndSwap(false, true) {
+		return
+	}
+	if err := e.memstore.Close(); err != nil {
+		log.Warn("cannot close write-ahead log: %s", err)
+	}
+}
+
+// Stopped returns true once Stop is called.
+func (e *Engine) Stopped() bool {
+	return e.stopped.Load()
+}
+
+// Persist replays the write-ahead log found at path, then appends every committed transaction to it.
+//
+// The log is compacted into a snapshot every compactEvery commits. See agnostic.Engine.Persist.
+func (e *Engine) Persist(path string, compactEvery int) error {
+	return e.memstore.Persist(path, compactEvery)
+}
+
+// Generate inserts n synthetic rows in relation, in its own transaction. See agnostic.Transaction.Generate.
+func (e *Engine) Generate(schema, relation string, n int, seed int64) (int64, error) {
+	tx, err := e.memstore.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	c, err := tx.Generate(schema, relation, n, seed)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return c, nil
+}
+
+// SetDefaultSchema sets the schema used when a statement does not specify one, creating it if needed.
+func (e *Engine) SetDefaultSchema(name string) {
+	e.memstore.SetDefaultSchema(name)
+}
+
+// SetDialect makes the engine accept the syntax of given SQL dialect, see parser.ParseInstructionWithDialect.
+//
+// The MySQL dialect also accepts integers 0 and 1 for booleans, see agnostic.Engine.SetIntegerBooleans.
+// The SQLite dialect also enables type affinity, see agnostic.Engine.SetAffinity,
+// and creates the sqlite_master and sqlite_schema relations in the default schema.
+func (e *Engine) SetDialect(dialect string) error {
+	e.dialect = dialect
+	e.memstore.SetIntegerBooleans(dialect == parser.DialectMySQL)
+	e.memstore.SetAffinity(dialect == parser.DialectSQLite)
+
+	if dialect != parser.DialectSQLite {
+		return nil
+	}
+	schema := e.memstore.CurrentSchema()
+	for _, name := range []string{"sqlite_master", "sqlite_schema"} {
+		err := e.memstore.CreateVirtualRelation(schema, name, sqliteMasterAttributes(), e.sqliteMaster(schema))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetStrict enables or disables strict typing, see agnostic.Engine.SetStrict.
+func (e *Engine) SetStrict(strict bool) {
+	e.memstore.SetStrict(strict)
+}
+
+// SetLimits sets engine wide size limits.
+func (e *Engine) SetLimits(l agnostic.Limits) {
+	e.memstore.SetLimits(l)
+}
+
+// SetRelationLimits sets size limits of given relation.
+func (e *Engine) SetRelationLimits(schema, relation string, l agnostic.Limits) error {
+	return e.memstore.SetRelationLimits(schema, relation, l)
+}
+
+// Compact writes the current state into the snapshot file and truncates the write-ahead log.
+func (e *Engine) Compact() error {
+	return e.memstore.Compact()
 }
 
 // resolveIntParameter resolves a parameter token to an integer value.
-// It supports PostgreSQL-style ($1, $2, ...) and named parameters (:name).
+// Parameters are bound beforehand, see bind.
 // Returns the resolved integer value or an error.
 func resolveIntParameter(decl *parser.Decl, args []NamedValue, clauseName string) (int64, error) {
 	switch decl.Token {
 	case parser.ArgToken:
-		// Handle PostgreSQL-style parameter like $1, $2, etc.
-		idx, err := strconv.ParseInt(decl.Lexeme, 10, 64)
+		v, err := argValue(decl, args)
 		if err != nil {
 			return 0, fmt.Errorf("wrong %s parameter: %s", clauseName, err)
 		}
-		// PostgreSQL parameters are 1-indexed ($1, $2, ...)
-		if idx < 1 {
-			return 0, fmt.Errorf("%s parameter index must be >= 1, got $%d", clauseName, idx)
-		}
-		if idx > int64(len(args)) {
-			argWord := "argument"
-			if len(args) != 1 {
-				argWord = "arguments"
-			}
-			return 0, fmt.Errorf("reference to $%s in %s, but only %d %s provided", decl.Lexeme, clauseName, len(args), argWord)
-		}
 		// Convert the argument value to int64
-		switch v := args[idx-1].Value.(type) {
+		switch v := v.(type) {
 		case int:
 			return int64(v), nil
 		case int64:
@@ -81,30 +171,13 @@ func resolveIntParameter(decl *parser.Decl, args []NamedValue, clauseName string
 		default:
 			return 0, fmt.Errorf("%s parameter must be an integer, got %T", clauseName, v)
 		}
-	case parser.NamedArgToken:
-		// Handle named parameter like :name
-		for _, arg := range args {
-			if arg.Name == decl.Lexeme {
-				switch v := arg.Value.(type) {
-				case int:
-					return int64(v), nil
-				case int64:
-					return v, nil
-				case int32:
-					return int64(v), nil
-				default:
-					return 0, fmt.Errorf("%s parameter must be an integer, got %T", clauseName, v)
-				}
-			}
-		}
-		return 0, fmt.Errorf("named parameter %s not found in arguments", decl.Lexeme)
 	default:
 		// Handle direct number
 		return strconv.ParseInt(decl.Lexeme, 10, 64)
 	}
 }
 
-func createExecutor(t *Tx, decl *parser.Decl, args []NamedValue) (int64, int64, []string, []*agnostic.Tuple, error) {
+func createExecutor(t *Tx, decl *parser.Decl, args []NamedValue) (int64, int64, []agnostic.ColumnType, []*agnostic.Tuple, error) {
 
 	if len(decl.Decl) == 0 {
 		return 0, 0, nil, nil, ParsingError
@@ -117,7 +190,7 @@ func createExecutor(t *Tx, decl *parser.Decl, args []NamedValue) (int64, int64,
 	return 0, 0, nil, nil, NotImplemented
 }
 
-func dropExecutor(t *Tx, decl *parser.Decl, args []NamedValue) (int64, int64, []string, []*agnostic.Tuple, error) {
+func dropExecutor(t *Tx, decl *parser.Decl, args []NamedValue) (int64, int64, []agnostic.ColumnType, []*agnostic.Tuple, error) {
 
 	if len(decl.Decl) == 0 {
 		return 0, 0, nil, nil, ParsingError
@@ -126,6 +199,9 @@ func dropExecutor(t *Tx, decl *parser.Decl, args []NamedValue) (int64, int64, []
 	if _, ok := decl.Has(parser.TableToken); ok {
 		return dropTable(t, decl.Decl[0], args)
 	}
+	if _, ok := decl.Has(parser.IndexToken); ok {
+		return dropIndex(t, decl.Decl[0], args)
+	}
 	if _, ok := decl.Has(parser.SchemaToken); ok {
 		return dropSchema(t, decl.Decl[0], args)
 	}
@@ -133,7 +209,7 @@ func dropExecutor(t *Tx, decl *parser.Decl, args []NamedValue) (int64, int64, []
 	return 0, 0, nil, nil, NotImplemented
 }
 
-func dropTable(t *Tx, decl *parser.Decl, args []NamedValue) (int64, int64, []string, []*agnostic.Tuple, error) {
+func dropTable(t *Tx, decl *parser.Decl, args []NamedValue) (int64, int64, []agnostic.ColumnType, []*agnostic.Tuple, error) {
 	if len(decl.Decl) == 0 {
 		return 0, 1, nil, nil, ParsingError
 	}
@@ -146,7 +222,8 @@ func dropTable(t *Tx, decl *parser.Decl, args []NamedValue) (int64, int64, []str
 		rDecl = decl.Decl[1]
 	}
 
-	schema := agnostic.DefaultSchema
+	// unqualified relation is looked up in the search path
+	var schema string
 	if d, ok := rDecl.Has(parser.SchemaToken); ok {
 		schema = d.Lexeme
 	}
@@ -156,8 +233,11 @@ func dropTable(t *Tx, decl *parser.Decl, args []NamedValue) (int64, int64, []str
 	if !exists && ifExists {
 		return 0, 0, nil, nil, nil
 	}
+	if !exists && schema == "" {
+		return 0, 0, nil, nil, agnostic.NewError(agnostic.UndefinedTable, "relation %s does not exist", relation)
+	}
 	if !exists {
-		return 0, 0, nil, nil, fmt.Errorf("relation %s.%s does not exist", schema, relation)
+		return 0, 0, nil, nil, agnostic.NewError(agnostic.UndefinedTable, "relation %s.%s does not exist", schema, relation)
 	}
 
 	err := t.tx.DropRelation(schema, relation)
@@ -168,7 +248,42 @@ func dropTable(t *Tx, decl *parser.Decl, args []NamedValue) (int64, int64, []str
 	return 0, 1, nil, nil, nil
 }
 
-func dropSchema(t *Tx, decl *parser.Decl, args []NamedValue) (int64, int64, []string, []*agnostic.Tuple, error) {
+func dropIndex(t *Tx, decl *parser.Decl, args []NamedValue) (int64, int64, []agnostic.ColumnType, []*agnostic.Tuple, error) {
+	if len(decl.Decl) == 0 {
+		return 0, 1, nil, nil, ParsingError
+	}
+
+	// Check if 'IF EXISTS' is present
+	ifExists := hasIfExists(decl)
+
+	iDecl := decl.Decl[0]
+	if ifExists {
+		iDecl = decl.Decl[1]
+	}
+
+	// unqualified index is looked up in the search path
+	var schema string
+	if d, ok := iDecl.Has(parser.SchemaToken); ok {
+		schema = d.Lexeme
+	}
+	index := iDecl.Lexeme
+
+	if !t.tx.CheckIndex(schema, index) {
+		if ifExists {
+			return 0, 0, nil, nil, nil
+		}
+		return 0, 0, nil, nil, fmt.Errorf("index %s does not exist", index)
+	}
+
+	err := t.tx.DropIndex(schema, index)
+	if err != nil {
+		return 0, 0, nil, nil, err
+	}
+
+	return 0, 1, nil, nil, nil
+}
+
+func dropSchema(t *Tx, decl *parser.Decl, args []NamedValue) (int64, int64, []agnostic.ColumnType, []*agnostic.Tuple, error) {
 	if len(decl.Decl) == 0 {
 		return 0, 1, nil, nil, ParsingError
 	}
@@ -194,11 +309,11 @@ func dropSchema(t *Tx, decl *parser.Decl, args []NamedValue) (int64, int64, []st
 	return 0, 1, nil, nil, nil
 }
 
-func grantExecutor(*Tx, *parser.Decl, []NamedValue) (int64, int64, []string, []*agnostic.Tuple, error) {
+func grantExecutor(*Tx, *parser.Decl, []NamedValue) (int64, int64, []agnostic.ColumnType, []*agnostic.Tuple, error) {
 	return 0, 1, nil, nil, nil
 }
 
-func createSchemaExecutor(t *Tx, tableDecl *parser.Decl, args []NamedValue) (int64, int64, []string, []*agnostic.Tuple, error) {
+func createSchemaExecutor(t *Tx, tableDecl *parser.Decl, args []NamedValue) (int64, int64, []agnostic.ColumnType, []*agnostic.Tuple, error) {
 	if len(tableDecl.Decl) == 0 {
 		return 0, 0, nil, nil, ParsingError
 	}
@@ -220,7 +335,7 @@ func createSchemaExecutor(t *Tx, tableDecl *parser.Decl, args []NamedValue) (int
 	return 0, 0, nil, nil, nil
 }
 
-func createTableExecutor(t *Tx, tableDecl *parser.Decl, args []NamedValue) (int64, int64, []string, []*agnostic.Tuple, error) {
+func createTableExecutor(t *Tx, tableDecl *parser.Decl, args []NamedValue) (int64, int64, []agnostic.ColumnType, []*agnostic.Tuple, error) {
 	var i int
 	var schemaName string
 
@@ -293,6 +408,7 @@ func createTableExecutor(t *Tx, tableDecl *parser.Decl, args []NamedValue) (int6
 	}
 
 	// Fetch table-level FOREIGN KEY constraints and distribute them to attributes directly
+	var indexes []*parser.Decl
 	for i < len(tableDecl.Decl) {
 		if tableDecl.Decl[i].Token == parser.ForeignToken {
 			fk, err := parseTableForeignKey(tableDecl.Decl[i], "")
@@ -311,12 +427,25 @@ func createTableExecutor(t *Tx, tableDecl *parser.Decl, args []NamedValue) (int6
 			i++
 			continue
 		}
+		if tableDecl.Decl[i].Token == parser.UniqueToken {
+			if err := uniqueConstraint(attributes, tableDecl.Decl[i], ""); err != nil {
+				return 0, 0, nil, nil, err
+			}
+			i++
+			continue
+		}
 		if tableDecl.Decl[i].Token == parser.ConstraintToken {
 			// CONSTRAINT name FOREIGN KEY (...)
+			// CONSTRAINT name UNIQUE (...)
 			constraintName := ""
 			if len(tableDecl.Decl[i].Decl) > 0 {
 				constraintName = tableDecl.Decl[i].Decl[0].Lexeme
 			}
+			if len(tableDecl.Decl[i].Decl) > 1 && tableDecl.Decl[i].Decl[1].Token == parser.UniqueToken {
+				if err := uniqueConstraint(attributes, tableDecl.Decl[i].Decl[1], constraintName); err != nil {
+					return 0, 0, nil, nil, err
+				}
+			}
 			if len(tableDecl.Decl[i].Decl) > 1 && tableDecl.Decl[i].Decl[1].Token == parser.ForeignToken {
 				fk, err := parseTableForeignKey(tableDecl.Decl[i].Decl[1], constraintName)
 				if err != nil {
@@ -334,17 +463,63 @@ func createTableExecutor(t *Tx, tableDecl *parser.Decl, args []NamedValue) (int6
 			i++
 			continue
 		}
+		if tableDecl.Decl[i].Token == parser.IndexToken {
+			// MySQL KEY name (...), created with the relation
+			indexes = append(indexes, tableDecl.Decl[i])
+			i++
+			continue
+		}
+		if tableDecl.Decl[i].Token == parser.AutoincrementToken && len(tableDecl.Decl[i].Decl) > 0 {
+			// MySQL AUTO_INCREMENT=n table option
+			next, err := strconv.ParseUint(tableDecl.Decl[i].Decl[0].Lexeme, 10, 64)
+			if err != nil {
+				return 0, 0, nil, nil, fmt.Errorf("wrong AUTO_INCREMENT value: %w", err)
+			}
+			for ai := range attributes {
+				if attributes[ai].HasAutoIncrement() {
+					attributes[ai] = attributes[ai].WithNextValue(next)
+				}
+			}
+			i++
+			continue
+		}
 		// Any other token (should be covered by prior loops or parser)
 		break
 	}
 
+	if t.e.dialect == parser.DialectSQLite {
+		attributes = withRowID(attributes, pk)
+	}
+
 	err := t.tx.CreateRelation(schemaName, relationName, attributes, pk)
 	if err != nil {
 		return 0, 0, nil, nil, err
 	}
+	for _, indexDecl := range indexes {
+		if _, _, _, _, err := createIndexExecutor(t, indexDecl, args); err != nil {
+			return 0, 0, nil, nil, err
+		}
+	}
 	return 0, 1, nil, nil, nil
 }
 
+// uniqueConstraint applies UNIQUE constraint uDecl, named name if not empty, to its attribute.
+// uDecl structure: UNIQUE -> (list of columns)
+func uniqueConstraint(attributes []agnostic.Attribute, uDecl *parser.Decl, name string) error {
+	if len(uDecl.Decl) != 1 {
+		return fmt.Errorf("UNIQUE constraint on several attributes is not supported")
+	}
+
+	col := strings.ToLower(uDecl.Decl[0].Lexeme)
+	for ai := range attributes {
+		if attributes[ai].Name() == col {
+			attributes[ai] = attributes[ai].WithUniqueConstraint(name)
+			return nil
+		}
+	}
+	return fmt.Errorf("attribute %s does not exist", col)
+}
+
 // parseTableForeignKey extracts a ForeignKey from a FOREIGN KEY decl node.
 // fkDecl structure: FOREIGN -> KEY -> (list of columns) -> REFERENCES -> ...
 func parseTableForeignKey(fkDecl *parser.Decl, constraintName string) (agnostic.ForeignKey, error) {
@@ -426,18 +601,22 @@ func parseTableForeignKey(fkDecl *parser.Decl, constraintName string) (agnostic.
 	|-> RETURNING
 	        |-> email
 */
-func insertIntoTableExecutor(t *Tx, insertDecl *parser.Decl, args []NamedValue) (int64, int64, []string, []*agnostic.Tuple, error) {
+func insertIntoTableExecutor(t *Tx, insertDecl *parser.Decl, args []NamedValue) (int64, int64, []agnostic.ColumnType, []*agnostic.Tuple, error) {
 
 	var lastInsertedID int64
 	var schemaName string
-	var returningAttrs []string
+	var returningAttrs []agnostic.ColumnType
 	var returningIdx []int
 	relationName := insertDecl.Decl[0].Decl[0].Lexeme
 
-	// Check for ON CONFLICT clause
+	// Check for ON CONFLICT clause, or SQLite OR REPLACE
 	var onConflictDecl *parser.Decl
 	var doUpdateDecl *parser.Decl
+	var replace bool
 	for i := range insertDecl.Decl {
+		if insertDecl.Decl[i].Token == parser.ReplaceToken {
+			replace = true
+		}
 		if insertDecl.Decl[i].Token == parser.OnToken {
 			onConflictDecl = insertDecl.Decl[i]
 			// Find the DO clause
@@ -456,13 +635,29 @@ func insertIntoTableExecutor(t *Tx, insertDecl *parser.Decl, args []NamedValue)
 	if len(insertDecl.Decl) > 2 {
 		for i := range insertDecl.Decl {
 			if insertDecl.Decl[i].Token == parser.ReturningToken {
-				returningDecl := insertDecl.Decl[i]
-				returningAttrs = append(returningAttrs, returningDecl.Decl[0].Lexeme)
-				idx, _, err := t.tx.RelationAttribute(schemaName, relationName, returningDecl.Decl[0].Lexeme)
-				if err != nil {
-					return 0, 0, nil, nil, fmt.Errorf("cannot return %s, doesn't exist in relation %s", returningDecl.Decl[0].Lexeme, relationName)
+				for _, d := range insertDecl.Decl[i].Decl {
+					// RETURNING * returns all attributes
+					if d.Token == parser.StarToken {
+						info, err := t.tx.RelationInfo(schemaName, relationName)
+						if err != nil {
+							return 0, 0, nil, nil, err
+						}
+						for idx, c := range info.Columns {
+							returningAttrs = append(returningAttrs, c.ColumnType)
+							returningIdx = append(returningIdx, idx)
+						}
+						continue
+					}
+
+					idx, attr, err := t.tx.RelationAttribute(schemaName, relationName, d.Lexeme)
+					if err != nil {
+						return 0, 0, nil, nil, fmt.Errorf("cannot return %s, doesn't exist in relation %s", d.Lexeme, relationName)
+					}
+					c := attr.ColumnType()
+					c.Name = d.Lexeme
+					returningAttrs = append(returningAttrs, c)
+					returningIdx = append(returningIdx, idx)
 				}
-				returningIdx = append(returningIdx, idx)
 			}
 		}
 	}
@@ -476,19 +671,35 @@ func insertIntoTableExecutor(t *Tx, insertDecl *parser.Decl, args []NamedValue)
 		specifiedAttrs = append(specifiedAttrs, d.Lexeme)
 	}
 
+	info, err := t.tx.RelationInfo(schemaName, relationName)
+	if err != nil {
+		return 0, 0, nil, nil, err
+	}
+
+	// inserted id is the first attribute, or the rowid in SQLite dialect
+	var idIdx int
+	if t.e.dialect == parser.DialectSQLite {
+		if idx, _, err := t.tx.RelationAttribute(schemaName, relationName, "rowid"); err == nil {
+			idIdx = idx
+		}
+	}
+
 	var tuples []*agnostic.Tuple
+	var firstInsertedID, affected int64
 	valuesDecl := insertDecl.Decl[1]
 	for _, valueListDecl := range valuesDecl.Decl {
-		values, err := getValues(specifiedAttrs, valueListDecl, args)
+		values, err := getValues(t, specifiedAttrs, valueListDecl, args)
 		if err != nil {
 			return 0, 0, nil, nil, err
 		}
+		nullOmitted(info, values)
 
 		var tuple *agnostic.Tuple
 
 		// If ON CONFLICT is present, check for conflict first
 		if onConflictDecl != nil {
-			tuple, err = handleOnConflict(t, schemaName, relationName, values, specifiedAttrs, onConflictDecl, doUpdateDecl)
+			var updated bool
+			tuple, updated, err = handleOnConflict(t, schemaName, relationName, values, specifiedAttrs, onConflictDecl, doUpdateDecl, args)
 			if err != nil {
 				return 0, 0, nil, nil, err
 			}
@@ -496,6 +707,15 @@ func insertIntoTableExecutor(t *Tx, insertDecl *parser.Decl, args []NamedValue)
 				// DO NOTHING case - skip this row
 				continue
 			}
+			// MySQL ON DUPLICATE KEY UPDATE counts an updated row twice
+			if updated && t.e.dialect == parser.DialectMySQL {
+				affected++
+			}
+		} else if replace {
+			tuple, err = replaceRow(t, schemaName, relationName, values)
+			if err != nil {
+				return 0, 0, nil, nil, err
+			}
 		} else {
 			// No ON CONFLICT clause, do normal insert
 			tuple, err = t.tx.Insert(schemaName, relationName, values)
@@ -505,6 +725,7 @@ func insertIntoTableExecutor(t *Tx, insertDecl *parser.Decl, args []NamedValue)
 		}
 
 		if tuple != nil {
+			affected++
 			returningTuple := agnostic.NewTuple()
 			for _, idx := range returningIdx {
 				returningTuple.Append(tuple.Values()[idx])
@@ -512,90 +733,133 @@ func insertIntoTableExecutor(t *Tx, insertDecl *parser.Decl, args []NamedValue)
 			tuples = append(tuples, returningTuple)
 
 			// guess lastInsertedID
-			if v := tuple.Values(); len(v) > 0 {
-				if reflect.TypeOf(v[0]).ConvertibleTo(reflect.TypeOf(lastInsertedID)) {
-					lastInsertedID = reflect.ValueOf(v[0]).Convert(reflect.TypeOf(lastInsertedID)).Int()
+			if v := tuple.Values(); len(v) > idIdx {
+				if reflect.TypeOf(v[idIdx]).ConvertibleTo(reflect.TypeOf(lastInsertedID)) {
+					lastInsertedID = reflect.ValueOf(v[idIdx]).Convert(reflect.TypeOf(lastInsertedID)).Int()
 				}
 			}
+			if len(tuples) == 1 {
+				firstInsertedID = lastInsertedID
+			}
 		}
 	}
 
+	// MySQL LAST_INSERT_ID() returns the first id generated by a multi-row INSERT
+	if len(tuples) > 0 {
+		t.session.setLastInsertID(firstInsertedID)
+	}
+
 	if len(returningAttrs) == 0 {
-		return lastInsertedID, int64(len(tuples)), nil, nil, nil
+		return lastInsertedID, affected, nil, nil, nil
 	}
 
-	return lastInsertedID, int64(len(tuples)), returningAttrs, tuples, nil
+	return lastInsertedID, affected, returningAttrs, tuples, nil
 }
 
 // handleOnConflict handles the ON CONFLICT clause for INSERT statements.
-// It returns the resulting tuple (from insert or update), or nil if DO NOTHING was specified.
-func handleOnConflict(t *Tx, schemaName, relationName string, values map[string]any, specifiedAttrs []string, onConflictDecl, doUpdateDecl *parser.Decl) (*agnostic.Tuple, error) {
-	hasConflict, err := t.tx.CheckPrimaryKeyConflict(schemaName, relationName, values)
+// It returns the resulting tuple (from insert or update) and whether it was updated, or nil if DO NOTHING was specified.
+func handleOnConflict(t *Tx, schemaName, relationName string, values map[string]any, specifiedAttrs []string, onConflictDecl, doUpdateDecl *parser.Decl, args []NamedValue) (*agnostic.Tuple, bool, error) {
+	// Get the conflict target columns
+	conflictDecl := onConflictDecl.Decl[0] // ConflictToken
+	if conflictDecl.Token != parser.ConflictToken {
+		return nil, false, fmt.Errorf("invalid ON CONFLICT clause structure: missing CONFLICT token")
+	}
+	var conflictCols []string
+	for _, colDecl := range conflictDecl.Decl {
+		conflictCols = append(conflictCols, strings.ToLower(colDecl.Lexeme))
+	}
+
+	keys, err := t.tx.ConflictingKeys(schemaName, relationName, values)
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+
+	// Without conflict target, any primary key or unique attribute conflict is handled
+	hasConflict := len(keys) > 0 && len(conflictCols) == 0
+	if hasConflict {
+		conflictCols = keys[0]
+	}
+	for _, key := range keys {
+		if sameColumns(key, conflictCols) {
+			hasConflict = true
+		}
 	}
 
 	if !hasConflict {
-		// No conflict, do normal insert
-		return t.tx.Insert(schemaName, relationName, values)
+		// No conflict, do normal insert, which reports violations of other keys
+		tuple, err := t.tx.Insert(schemaName, relationName, values)
+		return tuple, false, err
 	}
 
 	// Validate ON CONFLICT clause structure
 	if len(onConflictDecl.Decl) < 2 {
-		return nil, fmt.Errorf("invalid ON CONFLICT clause structure")
+		return nil, false, fmt.Errorf("invalid ON CONFLICT clause structure")
 	}
 
 	// Check if it's DO NOTHING
 	doDecl := onConflictDecl.Decl[1] // DoToken
 	if doDecl.Token != parser.DoToken {
-		return nil, fmt.Errorf("unexpected ON CONFLICT clause structure: missing DO token")
+		return nil, false, fmt.Errorf("unexpected ON CONFLICT clause structure: missing DO token")
 	}
 	if len(doDecl.Decl) > 0 && doDecl.Decl[0].Token == parser.NothingToken {
 		// DO NOTHING - return nil to signal skipping this row
-		return nil, nil
+		return nil, false, nil
 	}
 
 	if doUpdateDecl == nil {
-		return nil, fmt.Errorf("ON CONFLICT DO UPDATE specified but UPDATE clause not found")
-	}
-
-	// Get the conflict target columns
-	conflictDecl := onConflictDecl.Decl[0] // ConflictToken
-	if conflictDecl.Token != parser.ConflictToken {
-		return nil, fmt.Errorf("invalid ON CONFLICT clause structure: missing CONFLICT token")
-	}
-	var conflictCols []string
-	for _, colDecl := range conflictDecl.Decl {
-		conflictCols = append(conflictCols, strings.ToLower(colDecl.Lexeme))
+		return nil, false, fmt.Errorf("ON CONFLICT DO UPDATE specified but UPDATE clause not found")
 	}
 
 	// Convert values to match column types for consistent predicate comparison
 	convertedValues, err := t.tx.ConvertValuesForRelation(schemaName, relationName, values)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	// Build predicate for the conflicting row
 	predicate := buildConflictPredicate(relationName, conflictCols, convertedValues)
 	if predicate == nil {
-		return nil, fmt.Errorf("conflict target columns must have values in the INSERT statement")
+		return nil, false, fmt.Errorf("conflict target columns must have values in the INSERT statement")
 	}
 
 	// Get the SET clause values (use converted values for excluded.* references)
-	updateValues := extractUpdateValues(doUpdateDecl, convertedValues)
+	updateValues, err := extractUpdateValues(t, doUpdateDecl, schemaName, relationName, convertedValues, args)
+	if err != nil {
+		return nil, false, err
+	}
 
 	// Perform the update - use star selector to include all columns for RETURNING and DO UPDATE SET
 	selectors := []agnostic.Selector{agnostic.NewStarSelector(relationName)}
 	_, updatedTuples, err := t.tx.Update(schemaName, relationName, updateValues, selectors, predicate)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	if len(updatedTuples) > 0 {
-		return updatedTuples[0], nil
+		return updatedTuples[0], true, nil
 	}
 
-	return nil, fmt.Errorf("internal error: conflict detected but no matching rows found for update")
+	return nil, false, fmt.Errorf("internal error: conflict detected but no matching rows found for update")
+}
+
+// sameColumns reports whether a and b hold the same column names, in any order.
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, x := range a {
+		found := false
+		for _, y := range b {
+			if x == y {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
 }
 
 // buildConflictPredicate builds a predicate to match the conflicting row based on conflict columns.
@@ -627,9 +891,10 @@ func buildConflictPredicate(relationName string, conflictCols []string, values m
 
 // extractUpdateValues extracts the column values to update from the DO UPDATE SET clause.
 // It handles "excluded"."column" references by looking up values from the INSERT values.
-func extractUpdateValues(doUpdateDecl *parser.Decl, insertValues map[string]any) map[string]any {
+// Expressions, as in hits = counter.hits + excluded.hits, are computed on the conflicting row.
+func extractUpdateValues(t *Tx, doUpdateDecl *parser.Decl, schema, relation string, insertValues map[string]any, args []NamedValue) (map[string]any, error) {
 	if doUpdateDecl == nil || len(doUpdateDecl.Decl) == 0 {
-		return make(map[string]any)
+		return make(map[string]any), nil
 	}
 	setDecl := doUpdateDecl.Decl[0] // SetToken
 	updateValues := make(map[string]any)
@@ -641,6 +906,16 @@ func extractUpdateValues(doUpdateDecl *parser.Decl, insertValues map[string]any)
 		}
 
 		valueAttrDecl := attrDecl.Decl[0]
+		excludedRef := valueAttrDecl.Token == parser.StringToken && len(valueAttrDecl.Decl) == 1 && strings.ToLower(valueAttrDecl.Decl[0].Lexeme) == "excluded"
+		if isSetExpression(valueAttrDecl) && !excludedRef {
+			f, err := t.getSetValue(valueAttrDecl, schema, relation, insertValues, args)
+			if err != nil {
+				return nil, err
+			}
+			updateValues[colName] = f
+			continue
+		}
+
 		// Check if this is "excluded"."column" reference
 		if len(valueAttrDecl.Decl) > 0 {
 			// This is a qualified name like excluded.column
@@ -652,6 +927,18 @@ func extractUpdateValues(doUpdateDecl *parser.Decl, insertValues map[string]any)
 					updateValues[colName] = val
 				}
 			}
+		} else if valueAttrDecl.Token == parser.ArgToken {
+			v, err := argValue(valueAttrDecl, args)
+			if err != nil {
+				return nil, err
+			}
+			updateValues[colName] = v
+		} else if valueAttrDecl.Token == parser.ArrayToken {
+			v, err := arrayValue(valueAttrDecl, args)
+			if err != nil {
+				return nil, err
+			}
+			updateValues[colName] = v
 		} else {
 			// Direct value reference
 			var typeName string
@@ -664,6 +951,11 @@ func extractUpdateValues(doUpdateDecl *parser.Decl, insertValues map[string]any)
 				typeName = "text"
 			case parser.FloatToken:
 				typeName = "float"
+			case parser.TrueToken, parser.FalseToken:
+				typeName = "bool"
+			case parser.NullToken:
+				updateValues[colName] = nil
+				continue
 			default:
 				typeName = "text"
 				if _, err := agnostic.ToInstance(valueAttrDecl.Lexeme, "timestamp"); err == nil {
@@ -680,14 +972,23 @@ func extractUpdateValues(doUpdateDecl *parser.Decl, insertValues map[string]any)
 		}
 	}
 
-	return updateValues
+	return updateValues, nil
+}
+
+// nullOmitted sets nullable attributes without default omitted from values to NULL
+func nullOmitted(info agnostic.RelationInfo, values map[string]any) {
+	for _, c := range info.Columns {
+		if _, ok := values[c.Name]; ok || !c.Nullable || c.HasDefault || c.DefaultNow || c.AutoIncrement || c.RowID {
+			continue
+		}
+		values[c.Name] = nil
+	}
 }
 
-func getValues(specifiedAttrs []string, valuesDecl *parser.Decl, args []NamedValue) (map[string]any, error) {
+func getValues(t *Tx, specifiedAttrs []string, valuesDecl *parser.Decl, args []NamedValue) (map[string]any, error) {
 	var typeName string
 	var err error
 	values := make(map[string]any)
-	var odbcIdx int64 = 1
 
 	for i, d := range valuesDecl.Decl {
 		if d.Lexeme == "default" || d.Lexeme == "DEFAULT" {
@@ -714,26 +1015,21 @@ func getValues(specifiedAttrs []string, valuesDecl *parser.Decl, args []NamedVal
 
 		switch d.Token {
 		case parser.ArgToken:
-			var idx int64
-			if d.Lexeme == "?" {
-				idx = odbcIdx
-				odbcIdx++
-			} else {
-				idx, err = strconv.ParseInt(d.Lexeme, 10, 64)
-				if err != nil {
-					return nil, err
-				}
+			v, err = argValue(d, args)
+			if err != nil {
+				return nil, err
 			}
-			if len(args) <= int(idx)-1 {
-				return nil, fmt.Errorf("reference to $%s, but only %d argument provided", d.Lexeme, len(args))
+		case parser.ArrayToken:
+			v, err = arrayValue(d, args)
+			if err != nil {
+				return nil, err
 			}
-			v = args[idx-1].Value
-		case parser.NamedArgToken:
-			for _, arg := range args {
-				if arg.Name == d.Lexeme {
-					v = arg.Value
-				}
+		case parser.JSONBuildObjectToken, parser.JSONSetToken:
+			f, err := t.jsonValue(d, "", nil, nil, args)
+			if err != nil {
+				return nil, err
 			}
+			v = f.Value(nil, nil)
 		default:
 			v, err = agnostic.ToInstance(d.Lexeme, typeName)
 			if err != nil {
@@ -749,7 +1045,6 @@ func getValues(specifiedAttrs []string, valuesDecl *parser.Decl, args []NamedVal
 func getSet(specifiedAttrs []string, values map[string]any, valuesDecl *parser.Decl, args []NamedValue) (map[string]any, error) {
 	var typeName string
 	var err error
-	var odbcIdx int64 = 1
 
 	nameDecl := valuesDecl
 	valueDecl := nameDecl.Decl[1]
@@ -774,20 +1069,15 @@ func getSet(specifiedAttrs []string, values map[string]any, valuesDecl *parser.D
 
 	switch valueDecl.Token {
 	case parser.ArgToken:
-		var idx int64
-		if valueDecl.Lexeme == "?" {
-			idx = odbcIdx
-			odbcIdx++
-		} else {
-			idx, err = strconv.ParseInt(valueDecl.Lexeme, 10, 64)
-			if err != nil {
-				return nil, err
-			}
+		v, err = argValue(valueDecl, args)
+		if err != nil {
+			return nil, err
 		}
-		if len(args) <= int(idx)-1 {
-			return nil, fmt.Errorf("reference to $%s, but only %d argument provided", valueDecl.Lexeme, len(args))
+	case parser.ArrayToken:
+		v, err = arrayValue(valueDecl, args)
+		if err != nil {
+			return nil, err
 		}
-		v = args[idx-1].Value
 	default:
 		v, err = agnostic.ToInstance(valueDecl.Lexeme, typeName)
 		if err != nil {
@@ -799,6 +1089,104 @@ func getSet(specifiedAttrs []string, values map[string]any, valuesDecl *parser.D
 	return values, nil
 }
 
+// isSetExpression reports whether an UPDATE SET value references an attribute, as in
+// SET age = COALESCE(users.age, 0) + 1
+func isSetExpression(decl *parser.Decl) bool {
+	switch decl.Token {
+	case parser.CoalesceToken, parser.JSONBuildObjectToken, parser.JSONSetToken:
+		return true
+	case parser.StringToken, parser.NumberToken, parser.ArgToken:
+		return len(decl.Decl) > 0
+	}
+	return false
+}
+
+// getSetValue returns a ValueFunctor computing an UPDATE SET expression on relation rows.
+// excluded holds the values proposed for insertion by INSERT ... ON CONFLICT DO UPDATE,
+// referenced as excluded.col, and is nil otherwise.
+func (t *Tx) getSetValue(decl *parser.Decl, schema, relation string, excluded map[string]any, args []NamedValue) (agnostic.ValueFunctor, error) {
+	var f agnostic.ValueFunctor
+	operands := decl.Decl
+
+	switch decl.Token {
+	case parser.CoalesceToken:
+		var coalesced []agnostic.ValueFunctor
+		for len(operands) > 0 && !isArithmeticOperator(operands[0]) {
+			a, err := t.getSetValue(operands[0], schema, relation, excluded, args)
+			if err != nil {
+				return nil, err
+			}
+			coalesced = append(coalesced, a)
+			operands = operands[1:]
+		}
+		f = agnostic.NewCoalesceValueFunctor(coalesced...)
+	case parser.JSONBuildObjectToken, parser.JSONSetToken:
+		return t.jsonValue(decl, schema, []string{relation}, nil, args)
+	case parser.ArgToken, parser.NamedArgToken:
+		v, err := argValue(decl, args)
+		if err != nil {
+			return nil, err
+		}
+		f = agnostic.NewConstValueFunctor(v)
+	case parser.NumberToken:
+		v, err := agnostic.ToInstance(decl.Lexeme, "bigint")
+		if err != nil {
+			v, err = agnostic.ToInstance(decl.Lexeme, "float")
+		}
+		if err != nil {
+			return nil, err
+		}
+		f = agnostic.NewConstValueFunctor(v)
+	case parser.SimpleQuoteToken:
+		f = agnostic.NewConstValueFunctor(decl.Lexeme)
+	case parser.NullToken:
+		f = agnostic.NewConstValueFunctor(nil)
+	case parser.StringToken:
+		if hasJSONOperator(decl) {
+			return t.jsonValue(decl, schema, []string{relation}, nil, args)
+		}
+		if len(operands) > 0 && operands[0].Token == parser.StringToken && excluded != nil && strings.ToLower(operands[0].Lexeme) == "excluded" {
+			f = agnostic.NewConstValueFunctor(excluded[strings.ToLower(decl.Lexeme)])
+			operands = operands[1:]
+			break
+		}
+		if len(operands) > 0 && operands[0].Token == parser.StringToken {
+			if operands[0].Lexeme != relation {
+				return nil, fmt.Errorf("cannot use %s.%s, unknown relation %s", operands[0].Lexeme, decl.Lexeme, operands[0].Lexeme)
+			}
+			operands = operands[1:]
+		}
+		if _, _, err := t.tx.RelationAttribute(schema, relation, decl.Lexeme); err != nil {
+			return nil, err
+		}
+		f = agnostic.NewAttributeValueFunctor(relation, strings.ToLower(decl.Lexeme))
+	default:
+		return nil, fmt.Errorf("cannot use %s in SET expression", decl.Lexeme)
+	}
+
+	// arithmetic operator is followed by right operand
+	if len(operands) > 0 {
+		if len(operands) != 2 || !isArithmeticOperator(operands[0]) {
+			return nil, ParsingError
+		}
+		right, err := t.getSetValue(operands[1], schema, relation, excluded, args)
+		if err != nil {
+			return nil, err
+		}
+		f = agnostic.NewArithmeticValueFunctor(operands[0].Lexeme, f, right)
+	}
+
+	return f, nil
+}
+
+func isArithmeticOperator(decl *parser.Decl) bool {
+	switch decl.Token {
+	case parser.StarToken, parser.PlusToken, parser.MinusToken, parser.DivideToken:
+		return true
+	}
+	return false
+}
+
 func hasIfNotExists(tableDecl *parser.Decl) bool {
 	for _, d := range tableDecl.Decl {
 		if d.Token == parser.IfToken {
@@ -837,7 +1225,22 @@ func hasIfExists(tableDecl *parser.Decl) bool {
 			|-> =
 			|-> foo@bar.com
 */
-func selectExecutor(t *Tx, selectDecl *parser.Decl, args []NamedValue) (int64, int64, []string, []*agnostic.Tuple, error) {
+func selectExecutor(t *Tx, selectDecl *parser.Decl, args []NamedValue) (int64, int64, []agnostic.ColumnType, []*agnostic.Tuple, error) {
+	c, err := selectCursor(t, selectDecl, args)
+	if err != nil {
+		return 0, 0, nil, nil, err
+	}
+
+	res, err := c.All()
+	if err != nil {
+		return 0, 0, nil, nil, err
+	}
+
+	return 0, 0, c.ColumnTypes(), res, nil
+}
+
+// selectCursor plans selectDecl, rows are produced on demand by the returned cursor
+func selectCursor(t *Tx, selectDecl *parser.Decl, args []NamedValue) (*agnostic.Cursor, error) {
 
 	var schema string
 	var selectors []agnostic.Selector
@@ -847,17 +1250,34 @@ func selectExecutor(t *Tx, selectDecl *parser.Decl, args []NamedValue) (int64, i
 	var tables []string
 	var err error
 	var aliases map[string]string
+	var derived []agnostic.Predicate
+	var unnested []string
 
 	for i := range selectDecl.Decl {
 		switch selectDecl.Decl[i].Token {
 		case parser.FromToken:
-			schema, tables, aliases = getSelectedTables(selectDecl.Decl[i])
+			fromDecl, relations, err := t.unnestRelations(selectDecl.Decl[i], args)
+			if err != nil {
+				return nil, err
+			}
+			unnested = relations
+			defer t.dropRelations(unnested)
+			schema, tables, aliases = getSelectedTables(fromDecl)
 		case parser.WhereToken:
 			predicate, err = t.getPredicates(selectDecl.Decl[i].Decl, schema, tables[0], args, aliases)
 			if err != nil {
-				return 0, 0, nil, nil, err
+				return nil, err
 			}
 		case parser.JoinToken:
+			// JOIN (SELECT ...) AS alias filters rows instead of joining a relation
+			if len(selectDecl.Decl[i].Decl) > 0 && selectDecl.Decl[i].Decl[0].Token == parser.SelectToken {
+				p, err := t.derivedJoinExecutor(selectDecl.Decl[i], tables[0], aliases, args)
+				if err != nil {
+					return nil, err
+				}
+				derived = append(derived, p)
+				continue
+			}
 			// Capture alias mapping for joined table, if any
 			if len(selectDecl.Decl[i].Decl) > 0 {
 				if tbl, al, ok := extractAliasFromTableDecl(selectDecl.Decl[i].Decl[0]); ok {
@@ -869,34 +1289,34 @@ func selectExecutor(t *Tx, selectDecl *parser.Decl, args []NamedValue) (int64, i
 			}
 			j, err := t.getJoin(selectDecl.Decl[i], tables[0], aliases)
 			if err != nil {
-				return 0, 0, nil, nil, err
+				return nil, err
 			}
 			joiners = append(joiners, j)
 		case parser.OffsetToken:
 			offsetDecl := selectDecl.Decl[i].Decl[0]
 			offsetValue, err := resolveIntParameter(offsetDecl, args, "OFFSET")
 			if err != nil {
-				return 0, 0, nil, nil, err
+				return nil, err
 			}
 			s := agnostic.NewOffsetSorter(int(offsetValue))
 			sorters = append(sorters, s)
 		case parser.DistinctToken:
 			s, err := t.getDistinctSorter("", selectDecl.Decl[i], selectDecl.Decl[i+1].Lexeme)
 			if err != nil {
-				return 0, 0, nil, nil, err
+				return nil, err
 			}
 			sorters = append(sorters, s)
 		case parser.OrderToken:
-			s, err := orderbyExecutor(selectDecl.Decl[i], tables)
+			s, err := orderbyExecutor(t, selectDecl.Decl[i], schema, tables, aliases)
 			if err != nil {
-				return 0, 0, nil, nil, err
+				return nil, err
 			}
 			sorters = append(sorters, s)
 		case parser.LimitToken:
 			limitDecl := selectDecl.Decl[i].Decl[0]
 			limit, err := resolveIntParameter(limitDecl, args, "LIMIT")
 			if err != nil {
-				return 0, 0, nil, nil, err
+				return nil, err
 			}
 			s := agnostic.NewLimitSorter(limit)
 			sorters = append(sorters, s)
@@ -906,38 +1326,48 @@ func selectExecutor(t *Tx, selectDecl *parser.Decl, args []NamedValue) (int64, i
 	if predicate == nil {
 		predicate = agnostic.NewTruePredicate()
 	}
+	for _, p := range derived {
+		predicate = agnostic.NewAndPredicate(predicate, p)
+	}
 
 	for i := 0; i < len(selectDecl.Decl); i++ {
 		if selectDecl.Decl[i].Token != parser.StringToken &&
 			selectDecl.Decl[i].Token != parser.StarToken &&
 			selectDecl.Decl[i].Token != parser.CountToken &&
+			selectDecl.Decl[i].Token != parser.AggregateToken &&
 			selectDecl.Decl[i].Token != parser.NumberToken &&
 			selectDecl.Decl[i].Token != parser.SimpleQuoteToken &&
+			selectDecl.Decl[i].Token != parser.ArgToken &&
 			selectDecl.Decl[i].Token != parser.TrueToken &&
 			selectDecl.Decl[i].Token != parser.FalseToken &&
 			selectDecl.Decl[i].Token != parser.CurrentSchemaToken &&
-			selectDecl.Decl[i].Token != parser.CurrentDatabaseToken {
+			selectDecl.Decl[i].Token != parser.CurrentDatabaseToken &&
+			selectDecl.Decl[i].Token != parser.LastInsertIDToken &&
+			selectDecl.Decl[i].Token != parser.AdvisoryLockToken &&
+			selectDecl.Decl[i].Token != parser.ArrayLengthToken &&
+			selectDecl.Decl[i].Token != parser.JSONBuildObjectToken &&
+			selectDecl.Decl[i].Token != parser.JSONSetToken {
 			continue
 		}
 		// get attribute to select
-		selector, err := t.getSelector(selectDecl.Decl[i], schema, tables, aliases)
+		selector, err := t.getSelector(selectDecl.Decl[i], schema, tables, aliases, args)
 		if err != nil {
-			return 0, 0, nil, nil, err
+			return nil, err
 		}
 		selectors = append(selectors, selector)
 	}
 
 	// Query handles both cases: with and without FROM clause
 	log.Debug("executing '%s' with %s, joining with %s and sorting with %s", selectors, predicate, joiners, sorters)
-	cols, res, err := t.tx.Query(schema, selectors, predicate, joiners, sorters)
-	if err != nil {
-		return 0, 0, nil, nil, err
+	c, err := t.tx.QueryCursor(schema, selectors, predicate, joiners, sorters)
+	if err != nil || len(unnested) == 0 {
+		return c, err
 	}
-
-	return 0, 0, cols, res, nil
+	// rows must be read before unnest relations are dropped
+	return c, c.Materialize()
 }
 
-func createIndexExecutor(t *Tx, indexDecl *parser.Decl, args []NamedValue) (int64, int64, []string, []*agnostic.Tuple, error) {
+func createIndexExecutor(t *Tx, indexDecl *parser.Decl, args []NamedValue) (int64, int64, []agnostic.ColumnType, []*agnostic.Tuple, error) {
 	var i int
 	var schema, relation, index string
 
@@ -965,13 +1395,29 @@ func createIndexExecutor(t *Tx, indexDecl *parser.Decl, args []NamedValue) (int6
 		schema = d.Lexeme
 	}
 
+	if ifNotExists && t.tx.CheckIndex(schema, index) {
+		return 0, 0, nil, nil, nil
+	}
+
+	// indexed attributes, UNIQUE is the last child of unique indexes
 	var attrs []string
+	var unique bool
 	for i < len(indexDecl.Decl) {
-		attrs = append(attrs, indexDecl.Decl[i].Lexeme)
+		switch indexDecl.Decl[i].Token {
+		case parser.StringToken:
+			attrs = append(attrs, indexDecl.Decl[i].Lexeme)
+		case parser.UniqueToken:
+			unique = true
+		}
 		i++
 	}
 
-	err := t.tx.CreateIndex(schema, relation, index, agnostic.HashIndexType, attrs)
+	var err error
+	if unique {
+		err = t.tx.CreateUniqueIndex(schema, relation, index, agnostic.HashIndexType, attrs)
+	} else {
+		err = t.tx.CreateIndex(schema, relation, index, agnostic.HashIndexType, attrs)
+	}
 	if err != nil {
 		return 0, 0, nil, nil, err
 	}
@@ -979,7 +1425,7 @@ func createIndexExecutor(t *Tx, indexDecl *parser.Decl, args []NamedValue) (int6
 	return 0, 0, nil, nil, nil
 }
 
-func updateExecutor(t *Tx, updateDecl *parser.Decl, args []NamedValue) (int64, int64, []string, []*agnostic.Tuple, error) {
+func updateExecutor(t *Tx, updateDecl *parser.Decl, args []NamedValue) (int64, int64, []agnostic.ColumnType, []*agnostic.Tuple, error) {
 
 	var schema string
 	var selectors []agnostic.Selector
@@ -999,16 +1445,11 @@ func updateExecutor(t *Tx, updateDecl *parser.Decl, args []NamedValue) (int64, i
 		schema = d.Lexeme
 	}
 
-	// Check for RETURNING clause
-	if len(updateDecl.Decl) > 3 {
-		for i := range updateDecl.Decl {
-			if updateDecl.Decl[i].Token == parser.ReturningToken {
-				returningDecl := updateDecl.Decl[i]
-				_, _, err := t.tx.RelationAttribute(schema, relation, returningDecl.Decl[0].Lexeme)
-				if err != nil {
-					return 0, 0, nil, nil, fmt.Errorf("cannot return %s, doesn't exist in relation %s", returningDecl.Decl[0].Lexeme, relation)
-				}
-			}
+	// RETURNING clause selects the new row images
+	if d, ok := updateDecl.Has(parser.ReturningToken); ok {
+		selectors, err = t.getReturning(d, schema, relation)
+		if err != nil {
+			return 0, 0, nil, nil, err
 		}
 	}
 
@@ -1029,29 +1470,42 @@ func updateExecutor(t *Tx, updateDecl *parser.Decl, args []NamedValue) (int64, i
 	//	var tuples []*agnostic.Tuple
 	values := make(map[string]any)
 	for _, s := range setDecl.Decl {
-		_, err = getSet(specifiedAttrs, values, s, args)
+		// expressions are given to the updater as ValueFunctor computed for each row
+		if len(s.Decl) > 1 && isSetExpression(s.Decl[1]) {
+			values[s.Lexeme], err = t.getSetValue(s.Decl[1], schema, relation, nil, args)
+		} else {
+			_, err = getSet(specifiedAttrs, values, s, args)
+		}
 		if err != nil {
 			return 0, 0, nil, nil, err
 		}
 	}
 
 	log.Debug("executing update '%s' with values %v and predicate %s", selectors, values, predicate)
-	cols, res, err := t.tx.Update(schema, relation, values, selectors, predicate)
+	types, res, err := t.tx.UpdateColumns(schema, relation, values, selectors, predicate)
 	if err != nil {
 		return 0, 0, nil, nil, err
 	}
+	if selectors == nil {
+		return 0, int64(len(res)), nil, nil, nil
+	}
 
-	return 0, int64(len(res)), cols, res, nil
+	return 0, int64(len(res)), types, res, nil
 }
 
-func deleteExecutor(t *Tx, decl *parser.Decl, args []NamedValue) (int64, int64, []string, []*agnostic.Tuple, error) {
+func deleteExecutor(t *Tx, decl *parser.Decl, args []NamedValue) (int64, int64, []agnostic.ColumnType, []*agnostic.Tuple, error) {
 	var schema string
 	var selectors []agnostic.Selector
 	var predicate agnostic.Predicate
 	var err error
 
+	if len(decl.Decl) < 1 {
+		return 0, 0, nil, nil, ParsingError
+	}
+
+	// without WHERE clause, truncate the relation found in FROM clause
 	if len(decl.Decl) < 2 {
-		return truncateExecutor(t, decl, args)
+		return truncateExecutor(t, decl.Decl[0], args)
 	}
 
 	fromDecl := decl.Decl[0]
@@ -1063,16 +1517,11 @@ func deleteExecutor(t *Tx, decl *parser.Decl, args []NamedValue) (int64, int64,
 		schema = d.Lexeme
 	}
 
-	// Check for RETURNING clause
-	if len(decl.Decl) > 3 {
-		for i := range decl.Decl {
-			if decl.Decl[i].Token == parser.ReturningToken {
-				returningDecl := decl.Decl[i]
-				_, _, err := t.tx.RelationAttribute(schema, relation, returningDecl.Decl[0].Lexeme)
-				if err != nil {
-					return 0, 0, nil, nil, fmt.Errorf("cannot return %s, doesn't exist in relation %s", returningDecl.Decl[0].Lexeme, relation)
-				}
-			}
+	// RETURNING clause selects the deleted rows
+	if d, ok := decl.Has(parser.ReturningToken); ok {
+		selectors, err = t.getReturning(d, schema, relation)
+		if err != nil {
+			return 0, 0, nil, nil, err
 		}
 	}
 
@@ -1085,27 +1534,142 @@ func deleteExecutor(t *Tx, decl *parser.Decl, args []NamedValue) (int64, int64,
 		predicate = agnostic.NewTruePredicate()
 	}
 
-	_, res, err := t.tx.Delete(schema, relation, selectors, predicate)
+	types, res, err := t.tx.DeleteColumns(schema, relation, selectors, predicate)
 	if err != nil {
 		return 0, 0, nil, nil, err
 	}
+	if selectors == nil {
+		return 0, int64(len(res)), nil, nil, nil
+	}
+
+	return 0, int64(len(res)), types, res, nil
+}
+
+// getReturning returns selectors of a RETURNING clause on relation
+func (t *Tx) getReturning(decl *parser.Decl, schema, relation string) ([]agnostic.Selector, error) {
+	var selectors []agnostic.Selector
+
+	for _, attr := range decl.Decl {
+		if attr.Token == parser.StarToken {
+			selectors = append(selectors, agnostic.NewStarSelector(relation))
+			continue
+		}
+
+		f, name, err := t.getReturningValue(attr, schema, relation)
+		if err != nil {
+			return nil, err
+		}
+
+		as, aliased := attr.Has(parser.AsToken)
+		switch {
+		case aliased && len(as.Decl) > 0:
+			selectors = append(selectors, agnostic.NewExprSelector(relation, as.Decl[0].Lexeme, f))
+		case name != "":
+			selectors = append(selectors, agnostic.NewAttributeSelector(relation, []string{name}))
+		default:
+			selectors = append(selectors, agnostic.NewExprSelector(relation, "?column?", f))
+		}
+	}
 
-	return 0, int64(len(res)), nil, nil, nil
+	return selectors, nil
 }
 
-func truncateExecutor(t *Tx, trDecl *parser.Decl, args []NamedValue) (int64, int64, []string, []*agnostic.Tuple, error) {
+// getReturningValue returns a ValueFunctor computing decl, and the attribute name if decl is a naked attribute
+func (t *Tx) getReturningValue(decl *parser.Decl, schema, relation string) (agnostic.ValueFunctor, string, error) {
+	var f agnostic.ValueFunctor
+	var name string
+
+	switch decl.Token {
+	case parser.NumberToken:
+		v, err := agnostic.ToInstance(decl.Lexeme, "bigint")
+		if err != nil {
+			v, err = agnostic.ToInstance(decl.Lexeme, "float")
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		f = agnostic.NewConstValueFunctor(v)
+	case parser.StringToken:
+		if len(decl.Decl) > 0 && decl.Decl[0].Token == parser.StringToken && decl.Decl[0].Lexeme != relation {
+			return nil, "", fmt.Errorf("cannot return %s.%s, unknown relation %s", decl.Decl[0].Lexeme, decl.Lexeme, decl.Decl[0].Lexeme)
+		}
+		_, _, err := t.tx.RelationAttribute(schema, relation, decl.Lexeme)
+		if err != nil {
+			return nil, "", fmt.Errorf("cannot return %s, doesn't exist in relation %s", decl.Lexeme, relation)
+		}
+		f, name = agnostic.NewAttributeValueFunctor(relation, decl.Lexeme), decl.Lexeme
+	default:
+		return nil, "", fmt.Errorf("cannot return %s", decl.Lexeme)
+	}
+
+	// arithmetic operator is followed by right operand
+	for i, d := range decl.Decl {
+		switch d.Token {
+		case parser.StarToken, parser.PlusToken, parser.MinusToken, parser.DivideToken:
+			if i+1 >= len(decl.Decl) {
+				return nil, "", ParsingError
+			}
+			right, _, err := t.getReturningValue(decl.Decl[i+1], schema, relation)
+			if err != nil {
+				return nil, "", err
+			}
+			return agnostic.NewArithmeticValueFunctor(d.Lexeme, f, right), "", nil
+		}
+	}
+
+	return f, name, nil
+}
+
+func truncateExecutor(t *Tx, trDecl *parser.Decl, args []NamedValue) (int64, int64, []agnostic.ColumnType, []*agnostic.Tuple, error) {
 	var schema string
 
 	if len(trDecl.Decl) < 1 {
 		return 0, 0, nil, nil, ParsingError
 	}
 
-	if d, ok := trDecl.Decl[0].Has(parser.SchemaToken); ok {
+	var count int64
+	for _, d := range trDecl.Decl {
+		schema = ""
+		if s, ok := d.Has(parser.SchemaToken); ok {
+			schema = s.Lexeme
+		}
+
+		c, err := t.tx.Truncate(schema, d.Lexeme)
+		if err != nil {
+			return 0, 0, nil, nil, err
+		}
+		count += c
+	}
+
+	return 0, count, nil, nil, nil
+}
+
+func generateExecutor(t *Tx, genDecl *parser.Decl, args []NamedValue) (int64, int64, []agnostic.ColumnType, []*agnostic.Tuple, error) {
+	var schema string
+
+	if len(genDecl.Decl) < 2 {
+		return 0, 0, nil, nil, ParsingError
+	}
+
+	n, err := strconv.Atoi(genDecl.Decl[0].Lexeme)
+	if err != nil {
+		return 0, 0, nil, nil, fmt.Errorf("wrong row count: %w", err)
+	}
+
+	if d, ok := genDecl.Decl[1].Has(parser.SchemaToken); ok {
 		schema = d.Lexeme
 	}
-	relation := trDecl.Decl[0].Decl[0].Lexeme
+	relation := genDecl.Decl[1].Lexeme
 
-	c, err := t.tx.Truncate(schema, relation)
+	seed := time.Now().UnixNano()
+	if len(genDecl.Decl) > 2 && len(genDecl.Decl[2].Decl) > 0 {
+		seed, err = strconv.ParseInt(genDecl.Decl[2].Decl[0].Lexeme, 10, 64)
+		if err != nil {
+			return 0, 0, nil, nil, fmt.Errorf("wrong seed: %w", err)
+		}
+	}
+
+	c, err := t.tx.Generate(schema, relation, n, seed)
 	if err != nil {
 		return 0, 0, nil, nil, err
 	}
@@ -1113,7 +1677,7 @@ func truncateExecutor(t *Tx, trDecl *parser.Decl, args []NamedValue) (int64, int
 	return 0, c, nil, nil, nil
 }
 
-func orderbyExecutor(decl *parser.Decl, tables []string) (agnostic.Sorter, error) {
+func orderbyExecutor(t *Tx, decl *parser.Decl, schema string, tables []string, aliases map[string]string) (agnostic.Sorter, error) {
 	var orderingTk int
 	var valDecl *parser.Decl
 	var attrs []agnostic.SortExpression
@@ -1125,6 +1689,10 @@ func orderbyExecutor(decl *parser.Decl, tables []string) (agnostic.Sorter, error
 	for i := 0; i < len(valDecl.Decl); i++ {
 		attr := valDecl.Decl[i].Lexeme
 		attrDecl := valDecl.Decl[i]
+		// a parameter is a constant sort key, it doesn't change ordering
+		if attrDecl.Token == parser.ArgToken {
+			continue
+		}
 		if len(attrDecl.Decl) == 2 {
 			relationDecl := attrDecl.Decl[0]
 			orderingDecl := attrDecl.Decl[1]
@@ -1142,6 +1710,10 @@ func orderbyExecutor(decl *parser.Decl, tables []string) (agnostic.Sorter, error
 		} else {
 			orderingTk = parser.AscToken
 		}
+		// rowid, oid and _rowid_ sort by the attribute aliasing rowid, output column names are kept
+		if _, ra, err := t.tx.RelationAttribute(schema, getAlias(relation, aliases), attr); err == nil {
+			attr = selectedName(attr, ra)
+		}
 
 		switch orderingTk {
 		case parser.AscToken:
@@ -1154,13 +1726,16 @@ func orderbyExecutor(decl *parser.Decl, tables []string) (agnostic.Sorter, error
 
 	}
 
+	if tbl, ok := aliases[relation]; ok {
+		relation = tbl
+	}
 	sorter := agnostic.NewOrderBySorter(relation, attrs)
 	return sorter, nil
 }
 
 // withExecutor handles WITH (CTE) clauses
 // Structure: WITH decl contains CTE definitions, followed by main SELECT
-func withExecutor(t *Tx, withDecl *parser.Decl, args []NamedValue) (int64, int64, []string, []*agnostic.Tuple, error) {
+func withExecutor(t *Tx, withDecl *parser.Decl, args []NamedValue) (int64, int64, []agnostic.ColumnType, []*agnostic.Tuple, error) {
 	// Find the main SELECT statement (should be the last decl in parent instruction)
 	// The withDecl is the WITH token, and we need to find the SELECT in the instruction
 	
@@ -1200,12 +1775,12 @@ func withExecutor(t *Tx, withDecl *parser.Decl, args []NamedValue) (int64, int64
 				// Infer type from first value
 				val := tuples[0].Values()[i]
 				typeName := inferType(val)
-				attributes = append(attributes, agnostic.NewAttribute(colName, typeName))
+				attributes = append(attributes, agnostic.NewAttribute(colName.Name, typeName))
 			}
 		} else {
 			// No data, create attributes with text type
 			for _, colName := range cols {
-				attributes = append(attributes, agnostic.NewAttribute(colName, "text"))
+				attributes = append(attributes, agnostic.NewAttribute(colName.Name, "text"))
 			}
 		}
 		
@@ -1220,7 +1795,7 @@ func withExecutor(t *Tx, withDecl *parser.Decl, args []NamedValue) (int64, int64
 		for _, tuple := range tuples {
 			values := make(map[string]any)
 			for i, col := range cols {
-				values[strings.ToLower(col)] = tuple.Values()[i]
+				values[strings.ToLower(col.Name)] = tuple.Values()[i]
 			}
 			_, err = t.tx.Insert("", cteName, values)
 			if err != nil {
@@ -1253,6 +1828,10 @@ func inferType(val any) string {
 		return "bool"
 	case string:
 		return "text"
+	case time.Time:
+		return "timestamp"
+	case agnostic.JSON:
+		return "jsonb"
 	default:
 		return "text"
 	}
@@ -0,0 +1,121 @@
+package executor
+
+import (
+	"context"
+	"database/sql/driver"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/proullon/ramsql/engine/agnostic"
+	"github.com/proullon/ramsql/engine/parser"
+)
+
+// FaultProfile describes failures an engine injects in connections and transactions.
+//
+// Percentages are expressed between 0 and 100.
+type FaultProfile struct {
+	// Latency is added to every statement
+	Latency time.Duration
+	// LatencyJitter adds a random duration between 0 and LatencyJitter to every statement
+	LatencyJitter time.Duration
+	// BadConnPercent is the probability for a connection call to fail with driver.ErrBadConn
+	BadConnPercent float64
+	// SerializationFailurePercent is the probability for a statement to fail with SQLSTATE 40001
+	SerializationFailurePercent float64
+	// DiskFullPercent is the probability for a write statement to fail with a disk full error
+	DiskFullPercent float64
+	// Seed makes random faults deterministic if not 0
+	Seed int64
+}
+
+type faults struct {
+	profile FaultProfile
+	rand    *rand.Rand
+
+	sync.Mutex
+}
+
+// SetFaultProfile replaces the engine fault profile. Use a zero FaultProfile to disable faults.
+func (e *Engine) SetFaultProfile(p FaultProfile) {
+	e.faults.Lock()
+	defer e.faults.Unlock()
+
+	seed := p.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	e.faults.profile = p
+	e.faults.rand = rand.New(rand.NewSource(seed))
+}
+
+// FaultProfile returns the engine current fault profile.
+func (e *Engine) FaultProfile() FaultProfile {
+	e.faults.Lock()
+	defer e.faults.Unlock()
+
+	return e.faults.profile
+}
+
+// ConnFault returns driver.ErrBadConn if the engine is stopped, or according to fault profile.
+func (e *Engine) ConnFault() error {
+	if e.Stopped() {
+		return driver.ErrBadConn
+	}
+	if e.faults.draw(func(p FaultProfile) float64 { return p.BadConnPercent }) {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+// draw returns true with the probability returned by percent
+func (f *faults) draw(percent func(FaultProfile) float64) bool {
+	f.Lock()
+	defer f.Unlock()
+
+	pct := percent(f.profile)
+	if pct <= 0 || f.rand == nil {
+		return false
+	}
+	return f.rand.Float64()*100 < pct
+}
+
+func (f *faults) latency() time.Duration {
+	f.Lock()
+	defer f.Unlock()
+
+	d := f.profile.Latency
+	if f.profile.LatencyJitter > 0 && f.rand != nil {
+		d += time.Duration(f.rand.Int63n(int64(f.profile.LatencyJitter)))
+	}
+	return d
+}
+
+// statementFault applies latency, then returns the error injected for given instruction, if any.
+func (t *Tx) statementFault(ctx context.Context, inst *parser.Instruction) error {
+	if d := t.e.faults.latency(); d > 0 {
+		timer := time.NewTimer(d)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+
+	if t.e.faults.draw(func(p FaultProfile) float64 { return p.SerializationFailurePercent }) {
+		return agnostic.NewError(agnostic.SerializationFailure, "could not serialize access due to concurrent update")
+	}
+
+	if len(inst.Decls) == 0 {
+		return nil
+	}
+	switch inst.Decls[0].Token {
+	case parser.InsertToken, parser.UpdateToken, parser.CreateToken:
+		if t.e.faults.draw(func(p FaultProfile) float64 { return p.DiskFullPercent }) {
+			return agnostic.NewError(agnostic.DiskFull, "could not extend relation: no space left on device")
+		}
+	}
+
+	return nil
+}
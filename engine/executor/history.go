@@ -0,0 +1,167 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/proullon/ramsql/engine/agnostic"
+)
+
+// DefaultHistorySize is the number of statements kept in an engine query history.
+const DefaultHistorySize = 1000
+
+// QueryRecord describes a statement executed by an engine.
+type QueryRecord struct {
+	// ID is the position of the statement since engine start, starting at 1
+	ID int64
+	// TxID identifies the transaction the statement ran in
+	TxID     int64
+	Query    string
+	Args     []NamedValue
+	Start    time.Time
+	Duration time.Duration
+	// Rows is the number of rows affected or returned
+	Rows int64
+	Err  error
+}
+
+// history is a bounded ring buffer of QueryRecord.
+type history struct {
+	records []QueryRecord
+	next    int
+	size    int
+	count   int64
+
+	sync.Mutex
+}
+
+func newHistory(size int) *history {
+	return &history{size: size}
+}
+
+func (h *history) add(r QueryRecord) {
+	h.Lock()
+	defer h.Unlock()
+
+	h.count++
+	r.ID = h.count
+
+	if h.size <= 0 {
+		return
+	}
+
+	if len(h.records) < h.size {
+		h.records = append(h.records, r)
+		return
+	}
+
+	h.records[h.next] = r
+	h.next = (h.next + 1) % h.size
+}
+
+// list returns records from oldest to newest
+func (h *history) list() []QueryRecord {
+	h.Lock()
+	defer h.Unlock()
+
+	l := make([]QueryRecord, 0, len(h.records))
+	l = append(l, h.records[h.next:]...)
+	l = append(l, h.records[:h.next]...)
+	return l
+}
+
+func (h *history) resize(size int) {
+	if size < 0 {
+		size = 0
+	}
+	l := h.list()
+
+	h.Lock()
+	defer h.Unlock()
+
+	if size < len(l) {
+		l = l[len(l)-size:]
+	}
+	h.records = l
+	h.next = 0
+	h.size = size
+}
+
+func (h *history) clear() {
+	h.Lock()
+	defer h.Unlock()
+
+	h.records = nil
+	h.next = 0
+}
+
+// rows returns the content of ramsql.query_history relation
+func (h *history) rows() [][]any {
+	var rows [][]any
+
+	for _, r := range h.list() {
+		var args []string
+		for _, a := range r.Args {
+			args = append(args, fmt.Sprintf("%v", a.Value))
+		}
+		var err any
+		if r.Err != nil {
+			err = r.Err.Error()
+		}
+		rows = append(rows, []any{
+			r.ID,
+			r.TxID,
+			r.Query,
+			strings.Join(args, ", "),
+			r.Start,
+			r.Duration.Microseconds(),
+			r.Rows,
+			err,
+		})
+	}
+
+	return rows
+}
+
+func historyAttributes() []agnostic.Attribute {
+	return []agnostic.Attribute{
+		agnostic.NewAttribute("id", "bigint"),
+		agnostic.NewAttribute("tx_id", "bigint"),
+		agnostic.NewAttribute("query", "text"),
+		agnostic.NewAttribute("args", "text"),
+		agnostic.NewAttribute("started_at", "timestamp"),
+		agnostic.NewAttribute("duration_us", "bigint"),
+		agnostic.NewAttribute("rows", "bigint"),
+		agnostic.NewAttribute("error", "text"),
+	}
+}
+
+// History returns executed statements, from oldest to newest.
+func (e *Engine) History() []QueryRecord {
+	return e.history.list()
+}
+
+// SetHistorySize changes the number of statements kept in history.
+// A size of 0 disables recording.
+func (e *Engine) SetHistorySize(size int) {
+	e.history.resize(size)
+}
+
+// ClearHistory removes all recorded statements.
+func (e *Engine) ClearHistory() {
+	e.history.clear()
+}
+
+func (t *Tx) record(query string, args []NamedValue, start time.Time, rows int64, err error) {
+	t.e.history.add(QueryRecord{
+		TxID:     t.id,
+		Query:    query,
+		Args:     args,
+		Start:    start,
+		Duration: time.Since(start),
+		Rows:     rows,
+		Err:      err,
+	})
+}
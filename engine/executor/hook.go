@@ -0,0 +1,243 @@
+package executor
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/proullon/ramsql/engine/parser"
+)
+
+// Statement describes a statement being executed, as seen by hooks.
+type Statement struct {
+	Query       string
+	Args        []NamedValue
+	Instruction *parser.Instruction
+	// Kind is the statement keyword in upper case: SELECT, INSERT, UPDATE, DELETE, CREATE, DROP, TRUNCATE...
+	Kind string
+	// Tables holds the relations referenced by the statement
+	Tables []string
+	TxID   int64
+
+	// Rows and Err are set once the statement is executed, they are only relevant in After hooks
+	Rows int64
+	Err  error
+}
+
+// Hook runs callbacks around statements matching all its non-empty criteria.
+//
+// An error returned by Before aborts the statement before execution. An error
+// returned by After replaces the statement result.
+type Hook struct {
+	// Kind matches the statement keyword, case insensitive
+	Kind string
+	// Table matches any relation referenced by the statement
+	Table string
+	// Match is applied on the query text
+	Match *regexp.Regexp
+
+	Before func(*Statement) error
+	After  func(*Statement) error
+}
+
+func (h *Hook) match(s *Statement) bool {
+	if h.Kind != "" && !strings.EqualFold(h.Kind, s.Kind) {
+		return false
+	}
+	if h.Table != "" {
+		found := false
+		for _, t := range s.Tables {
+			if strings.EqualFold(h.Table, t) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if h.Match != nil && !h.Match.MatchString(s.Query) {
+		return false
+	}
+	return true
+}
+
+type hooks struct {
+	l []*Hook
+
+	sync.RWMutex
+}
+
+// AddHook registers h on every transaction of the engine.
+// The returned function removes the hook.
+func (e *Engine) AddHook(h Hook) (remove func()) {
+	hp := &h
+
+	e.hooks.Lock()
+	e.hooks.l = append(e.hooks.l, hp)
+	e.hooks.Unlock()
+
+	return func() {
+		e.hooks.Lock()
+		defer e.hooks.Unlock()
+		for i, v := range e.hooks.l {
+			if v == hp {
+				e.hooks.l = append(e.hooks.l[:i:i], e.hooks.l[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Breakpoint pauses statements matching a hook until released.
+type Breakpoint struct {
+	hits    chan *Statement
+	release chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	remove  func()
+}
+
+// Break pauses every statement matching h before its execution, until Release is called.
+//
+// Before and After callbacks of h are called once the statement is released.
+func (e *Engine) Break(h Hook) *Breakpoint {
+	b := &Breakpoint{
+		hits:    make(chan *Statement),
+		release: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	before := h.Before
+	h.Before = func(s *Statement) error {
+		select {
+		case b.hits <- s:
+		case <-b.done:
+			return nil
+		}
+		select {
+		case <-b.release:
+		case <-b.done:
+		}
+		if before != nil {
+			return before(s)
+		}
+		return nil
+	}
+	b.remove = e.AddHook(h)
+
+	return b
+}
+
+// Wait blocks until a statement hits the breakpoint and returns it. The statement stays paused until Release.
+func (b *Breakpoint) Wait(ctx context.Context) (*Statement, error) {
+	select {
+	case s := <-b.hits:
+		return s, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Release resumes the statement returned by Wait.
+func (b *Breakpoint) Release() {
+	select {
+	case b.release <- struct{}{}:
+	case <-b.done:
+	}
+}
+
+// Clear removes the breakpoint and resumes all paused statements.
+func (b *Breakpoint) Clear() {
+	b.once.Do(func() {
+		b.remove()
+		close(b.done)
+	})
+}
+
+// withHooks runs matching engine hooks around exec.
+func (t *Tx) withHooks(query string, args []NamedValue, inst *parser.Instruction, exec func() (int64, error)) error {
+	after, err := t.beforeHooks(query, args, inst)
+	if err != nil {
+		return err
+	}
+
+	return after(exec())
+}
+
+// beforeHooks runs Before callbacks of matching engine hooks, and returns a function
+// running their After callbacks once the statement is executed.
+func (t *Tx) beforeHooks(query string, args []NamedValue, inst *parser.Instruction) (func(int64, error) error, error) {
+	t.e.hooks.RLock()
+	l := t.e.hooks.l
+	t.e.hooks.RUnlock()
+
+	if len(l) == 0 {
+		return func(_ int64, err error) error { return err }, nil
+	}
+
+	s := &Statement{
+		Query:       query,
+		Args:        args,
+		Instruction: inst,
+		TxID:        t.id,
+	}
+	if len(inst.Decls) > 0 {
+		s.Kind = strings.ToUpper(inst.Decls[0].Lexeme)
+		s.Tables = statementTables(inst.Decls[0], nil)
+	}
+
+	var matching []*Hook
+	for _, h := range l {
+		if h.match(s) {
+			matching = append(matching, h)
+		}
+	}
+
+	for _, h := range matching {
+		if h.Before == nil {
+			continue
+		}
+		if err := h.Before(s); err != nil {
+			return nil, err
+		}
+	}
+
+	after := func(rows int64, err error) error {
+		s.Rows, s.Err = rows, err
+
+		for _, h := range matching {
+			if h.After == nil {
+				continue
+			}
+			if err := h.After(s); err != nil {
+				s.Err = err
+			}
+		}
+
+		return s.Err
+	}
+	return after, nil
+}
+
+// statementTables returns names of relations referenced in decl tree
+func statementTables(decl *parser.Decl, tables []string) []string {
+	switch decl.Token {
+	case parser.UpdateToken, parser.FromToken, parser.IntoToken, parser.JoinToken, parser.TableToken:
+		for _, d := range decl.Decl {
+			if d.Token == parser.StringToken {
+				tables = append(tables, d.Lexeme)
+			}
+			if decl.Token != parser.FromToken {
+				break
+			}
+		}
+	}
+
+	for _, d := range decl.Decl {
+		tables = statementTables(d, tables)
+	}
+
+	return tables
+}
@@ -0,0 +1,230 @@
+package executor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/proullon/ramsql/engine/agnostic"
+	"github.com/proullon/ramsql/engine/parser"
+)
+
+// catalogQuery is a catalog query issued by an ORM to introspect relations.
+//
+// These queries rely on PostgreSQL features ramsql does not implement, such as
+// joins with USING, correlated subqueries or format_type(). They are recognized
+// by their normalized text, see normalizeCatalogQuery, and answered from system
+// relations. They run as any other statement, between hooks, faults and timeout.
+type catalogQuery struct {
+	query  string
+	answer func(t *Tx, args []NamedValue) ([]string, [][]any, error)
+}
+
+var catalogQueries = []catalogQuery{
+	// GORM postgres Migrator.ColumnTypes
+	{"select c.column_name, c.is_nullable = 'yes', c.udt_name, c.character_maximum_length, c.numeric_precision, c.numeric_precision_radix, c.numeric_scale, c.datetime_precision, 8 * typlen, c.column_default, pd.description, c.identity_increment from information_schema.columns as c join pg_type as pgt on c.udt_name = pgt.typname left join pg_catalog.pg_description as pd on pd.objsubid = c.ordinal_position and pd.objoid = (select oid from pg_catalog.pg_class where relname = c.table_name and relnamespace = (select oid from pg_catalog.pg_namespace where nspname = c.table_schema)) where table_catalog = ? and table_schema = ? and table_name = ?", gormColumns},
+	{"select constraint_name from information_schema.table_constraints tc join information_schema.constraint_column_usage as ccu using (constraint_schema, constraint_catalog, table_name, constraint_name) join information_schema.columns as c on c.table_schema = tc.constraint_schema and tc.table_name = c.table_name and ccu.column_name = c.column_name where constraint_type in ('primary key', 'unique') and c.table_catalog = ? and c.table_schema = ? and c.table_name = ? and constraint_type = ?", gormUniqueConstraints},
+	{"select c.column_name, constraint_name, constraint_type from information_schema.table_constraints tc join information_schema.constraint_column_usage as ccu using (constraint_schema, constraint_catalog, table_name, constraint_name) join information_schema.columns as c on c.table_schema = tc.constraint_schema and tc.table_name = c.table_name and ccu.column_name = c.column_name where constraint_type in ('primary key', 'unique') and c.table_catalog = ? and c.table_schema = ? and c.table_name = ?", gormKeyConstraints},
+	{"select a.attname as column_name, format_type(a.atttypid, a.atttypmod) as data_type from pg_attribute a join pg_class b on a.attrelid = b.oid and relnamespace = (select oid from pg_catalog.pg_namespace where nspname = ?) where a.attnum > 0 and not a.attisdropped and b.relname = ?", gormColumnFormatTypes},
+	// GORM postgres Migrator.MigrateColumn, comments are not supported
+	{"select description from pg_catalog.pg_description where objsubid = (select ordinal_position from information_schema.columns where table_schema = ? and table_name = ? and column_name = ?) and objoid = (select oid from pg_catalog.pg_class where relname = ? and relnamespace = (select oid from pg_catalog.pg_namespace where nspname = ?))", noDescription},
+}
+
+var (
+	catalogComment     = regexp.MustCompile(`--[^\n]*`)
+	catalogPlaceholder = regexp.MustCompile(`\$[0-9]+|current_schema\(\)`)
+)
+
+// normalizeCatalogQuery lowercases query, removes comments and extra spaces, and replaces
+// parameters and CURRENT_SCHEMA(), which GORM uses in place of a schema argument, with ?
+func normalizeCatalogQuery(query string) string {
+	query = catalogComment.ReplaceAllString(strings.ToLower(query), "")
+	query = strings.Join(strings.Fields(query), " ")
+	return catalogPlaceholder.ReplaceAllString(query, "?")
+}
+
+// findCatalogQuery returns the known catalog query matching query
+func findCatalogQuery(query string) (*catalogQuery, bool) {
+	normalized := normalizeCatalogQuery(query)
+
+	for i := range catalogQueries {
+		if catalogQueries[i].query == normalized {
+			return &catalogQueries[i], true
+		}
+	}
+	return nil, false
+}
+
+// rows answers the catalog query
+func (cq *catalogQuery) rows(t *Tx, args []NamedValue) (*Rows, error) {
+	cols, values, err := cq.answer(t, args)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*agnostic.Tuple, len(values))
+	for i, v := range values {
+		res[i] = agnostic.NewTuple(v...)
+	}
+	return newRows(agnostic.NewColumnTypes(cols, res), res), nil
+}
+
+// catalogRows runs SELECT query on system relations and returns the values of each row
+func (t *Tx) catalogRows(query string, args ...any) ([][]any, error) {
+	instructions, err := parser.ParseInstruction(query)
+	if err != nil {
+		return nil, err
+	}
+
+	named := make([]NamedValue, len(args))
+	for i, a := range args {
+		named[i] = NamedValue{Ordinal: i + 1, Value: a}
+	}
+	instructions, bound, err := bind(instructions, named)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := selectCursor(t, instructions[0].Decls[0], bound)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.All()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([][]any, len(res))
+	for i, r := range res {
+		rows[i] = r.Values()
+	}
+	return rows, nil
+}
+
+// catalogArgs returns the schema and the other arguments of a catalog query taking n arguments
+// without schema. If given, schema is argument i, otherwise it is the current schema.
+func catalogArgs(t *Tx, args []NamedValue, n, i int) (string, []any) {
+	var rest []any
+	schema := t.tx.CurrentSchema()
+	for j, a := range args {
+		if len(args) == n+1 && j == i {
+			schema = fmt.Sprint(a.Value)
+			continue
+		}
+		rest = append(rest, a.Value)
+	}
+	return schema, rest
+}
+
+// gormColumns describes attributes of a relation. Arguments are database, [schema,] relation.
+func gormColumns(t *Tx, args []NamedValue) ([]string, [][]any, error) {
+	schema, rest := catalogArgs(t, args, 2, 1)
+	if len(rest) != 2 {
+		return nil, nil, fmt.Errorf("expected database, schema and relation arguments")
+	}
+
+	types, err := t.catalogRows(`SELECT typname, typlen FROM pg_catalog.pg_type`)
+	if err != nil {
+		return nil, nil, err
+	}
+	typlen := make(map[any]any)
+	for _, row := range types {
+		typlen[row[0]] = 8 * row[1].(int64)
+	}
+
+	columns, err := t.catalogRows(`SELECT column_name, is_nullable, udt_name, character_maximum_length, numeric_precision, numeric_precision_radix, numeric_scale, datetime_precision, column_default, identity_increment FROM information_schema.columns WHERE table_schema = $1 AND table_name = $2 ORDER BY ordinal_position`, schema, rest[1])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows [][]any
+	for _, c := range columns {
+		rows = append(rows, []any{c[0], c[1] == "YES", c[2], c[3], c[4], c[5], c[6], c[7], typlen[c[2]], c[8], nil, c[9]})
+	}
+
+	cols := []string{"column_name", "?column?", "udt_name", "character_maximum_length", "numeric_precision", "numeric_precision_radix", "numeric_scale", "datetime_precision", "?column?", "column_default", "description", "identity_increment"}
+	return cols, rows, nil
+}
+
+// keyConstraints returns column name, constraint name and type of PRIMARY KEY and UNIQUE constraints of relation
+func (t *Tx) keyConstraints(schema string, relation any) ([][]any, error) {
+	rows, err := t.catalogRows(`SELECT kcu.column_name, tc.constraint_name, tc.constraint_type FROM information_schema.table_constraints tc JOIN information_schema.key_column_usage kcu ON kcu.constraint_name = tc.constraint_name WHERE tc.table_schema = $1 AND tc.table_name = $2 AND kcu.table_schema = $1 AND kcu.table_name = $2`, schema, relation)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys [][]any
+	for _, row := range rows {
+		if row[2] == "PRIMARY KEY" || row[2] == "UNIQUE" {
+			keys = append(keys, row)
+		}
+	}
+	return keys, nil
+}
+
+// gormUniqueConstraints lists UNIQUE constraints of a relation, once per attribute.
+// Arguments are database, [schema,] relation, constraint type.
+func gormUniqueConstraints(t *Tx, args []NamedValue) ([]string, [][]any, error) {
+	schema, rest := catalogArgs(t, args, 3, 1)
+	if len(rest) != 3 {
+		return nil, nil, fmt.Errorf("expected database, schema, relation and constraint type arguments")
+	}
+
+	keys, err := t.keyConstraints(schema, rest[1])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows [][]any
+	for _, k := range keys {
+		if k[2] == rest[2] {
+			rows = append(rows, []any{k[1]})
+		}
+	}
+	return []string{"constraint_name"}, rows, nil
+}
+
+// gormKeyConstraints lists PRIMARY KEY and UNIQUE constraints of a relation attributes.
+// Arguments are database, [schema,] relation.
+func gormKeyConstraints(t *Tx, args []NamedValue) ([]string, [][]any, error) {
+	schema, rest := catalogArgs(t, args, 2, 1)
+	if len(rest) != 2 {
+		return nil, nil, fmt.Errorf("expected database, schema and relation arguments")
+	}
+
+	keys, err := t.keyConstraints(schema, rest[1])
+	if err != nil {
+		return nil, nil, err
+	}
+	return []string{"column_name", "constraint_name", "constraint_type"}, keys, nil
+}
+
+// gormColumnFormatTypes returns the type of each attribute of a relation, as format_type() formats it.
+// Arguments are [schema,] relation.
+func gormColumnFormatTypes(t *Tx, args []NamedValue) ([]string, [][]any, error) {
+	schema, rest := catalogArgs(t, args, 1, 0)
+	if len(rest) != 1 {
+		return nil, nil, fmt.Errorf("expected schema and relation arguments")
+	}
+
+	columns, err := t.catalogRows(`SELECT column_name, data_type, udt_name, character_maximum_length, numeric_precision, numeric_scale FROM information_schema.columns WHERE table_schema = $1 AND table_name = $2 ORDER BY ordinal_position`, schema, rest[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows [][]any
+	for _, c := range columns {
+		dataType := c[1]
+		switch {
+		case (c[2] == "varchar" || c[2] == "bpchar") && c[3] != nil:
+			dataType = fmt.Sprintf("%s(%d)", c[1], c[3])
+		case c[2] == "numeric" && c[4] != nil:
+			dataType = fmt.Sprintf("numeric(%d,%d)", c[4], c[5])
+		}
+		rows = append(rows, []any{c[0], dataType})
+	}
+	return []string{"column_name", "data_type"}, rows, nil
+}
+
+func noDescription(*Tx, []NamedValue) ([]string, [][]any, error) {
+	return []string{"description"}, nil, nil
+}
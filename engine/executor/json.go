@@ -0,0 +1,284 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/proullon/ramsql/engine/agnostic"
+	"github.com/proullon/ramsql/engine/parser"
+)
+
+// isJSONOperator reports whether d is one of the ->, ->>, #> and #>> JSON operators
+func isJSONOperator(d *parser.Decl) bool {
+	switch d.Token {
+	case parser.JSONFieldToken, parser.JSONFieldTextToken, parser.JSONPathToken, parser.JSONPathTextToken:
+		return true
+	}
+	return false
+}
+
+// hasJSONOperator reports whether attribute d is followed by JSON operators
+func hasJSONOperator(d *parser.Decl) bool {
+	for _, c := range d.Decl {
+		if isJSONOperator(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// isJSONCondition reports whether cond applies to a document, either a JSON attribute
+// or a member selected with JSON operators
+func isJSONCondition(cond *parser.Decl, attr agnostic.Attribute) bool {
+	if len(cond.Decl) == 0 {
+		return false
+	}
+	return agnostic.IsJSONType(attr.TypeName()) || isJSONOperator(cond.Decl[0]) || cond.Decl[0].Token == parser.KeyExistsToken
+}
+
+// jsonOperators applies JSON operators ops on document f. document reports whether
+// the resulting value is a document, which is not the case after ->> and #>>.
+func jsonOperators(f agnostic.ValueFunctor, ops []*parser.Decl, args []NamedValue) (res agnostic.ValueFunctor, document bool, err error) {
+	document = true
+	for _, op := range ops {
+		if len(op.Decl) == 0 {
+			return nil, false, ParsingError
+		}
+
+		var v any
+		d := op.Decl[0]
+		switch d.Token {
+		case parser.ArgToken:
+			v, err = argValue(d, args)
+		case parser.NumberToken:
+			v, err = agnostic.ToInstance(d.Lexeme, "bigint")
+		default:
+			v = d.Lexeme
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		f = agnostic.NewJSONOperatorValueFunctor(op.Lexeme, f, agnostic.NewConstValueFunctor(v))
+		document = op.Token == parser.JSONFieldToken || op.Token == parser.JSONPathToken
+	}
+	return f, document, nil
+}
+
+// jsonValue returns a ValueFunctor computing decl, an argument of a JSON function:
+// a value, a parameter, ARRAY[...], a scalar subquery, a JSON function or an
+// attribute of one of tables, optionally followed by JSON operators
+func (t *Tx) jsonValue(decl *parser.Decl, schema string, tables []string, aliases map[string]string, args []NamedValue) (agnostic.ValueFunctor, error) {
+	switch decl.Token {
+	case parser.JSONBuildObjectToken:
+		var values []agnostic.ValueFunctor
+		for _, d := range decl.Decl {
+			f, err := t.jsonValue(d, schema, tables, aliases, args)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, f)
+		}
+		return agnostic.NewJSONBuildObjectValueFunctor(values...)
+	case parser.JSONSetToken:
+		if len(decl.Decl) != 3 && len(decl.Decl) != 4 {
+			return nil, fmt.Errorf("function jsonb_set takes 3 or 4 arguments, got %d", len(decl.Decl))
+		}
+		var values []agnostic.ValueFunctor
+		for _, d := range decl.Decl {
+			f, err := t.jsonValue(d, schema, tables, aliases, args)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, f)
+		}
+		var create agnostic.ValueFunctor
+		if len(values) == 4 {
+			create = values[3]
+		}
+		return agnostic.NewJSONSetValueFunctor(values[0], values[1], values[2], create), nil
+	case parser.ArgToken:
+		v, err := argValue(decl, args)
+		if err != nil {
+			return nil, err
+		}
+		return agnostic.NewConstValueFunctor(v), nil
+	case parser.ArrayToken:
+		v, err := arrayValue(decl, args)
+		if err != nil {
+			return nil, err
+		}
+		return agnostic.NewConstValueFunctor(v), nil
+	case parser.SelectToken:
+		v, err := scalarSubqueryValue(t, decl, args)
+		if err != nil {
+			return nil, err
+		}
+		return agnostic.NewConstValueFunctor(v), nil
+	case parser.SimpleQuoteToken:
+		return agnostic.NewConstValueFunctor(decl.Lexeme), nil
+	case parser.NumberToken:
+		v, err := agnostic.ToInstance(decl.Lexeme, "bigint")
+		if err != nil {
+			v, err = agnostic.ToInstance(decl.Lexeme, "float")
+		}
+		if err != nil {
+			return nil, err
+		}
+		return agnostic.NewConstValueFunctor(v), nil
+	case parser.FloatToken:
+		v, err := agnostic.ToInstance(decl.Lexeme, "float")
+		if err != nil {
+			return nil, err
+		}
+		return agnostic.NewConstValueFunctor(v), nil
+	case parser.TrueToken, parser.FalseToken:
+		v, err := agnostic.ToInstance(decl.Lexeme, "bool")
+		if err != nil {
+			return nil, err
+		}
+		return agnostic.NewConstValueFunctor(v), nil
+	case parser.NullToken:
+		return agnostic.NewConstValueFunctor(nil), nil
+	case parser.StringToken:
+	default:
+		return nil, fmt.Errorf("cannot use %s in JSON expression", decl.Lexeme)
+	}
+
+	attribute := strings.ToLower(decl.Lexeme)
+	ops := decl.Decl
+	candidates := tables
+	if len(ops) > 0 && ops[0].Token == parser.StringToken {
+		candidates = []string{ops[0].Lexeme}
+		ops = ops[1:]
+	}
+	err := fmt.Errorf("column \"%s\" does not exist", attribute)
+	for _, table := range candidates {
+		relation := getAlias(table, aliases)
+		if _, _, err = t.tx.RelationAttribute(schema, relation, attribute); err == nil {
+			f, _, err := jsonOperators(agnostic.NewAttributeValueFunctor(relation, attribute), ops, args)
+			return f, err
+		}
+	}
+	return nil, err
+}
+
+// jsonSelector selects a JSON function or an attribute followed by JSON operators
+func (t *Tx) jsonSelector(attr *parser.Decl, schema string, tables []string, aliases map[string]string, args []NamedValue) (agnostic.Selector, error) {
+	f, err := t.jsonValue(attr, schema, tables, aliases, args)
+	if err != nil {
+		return nil, err
+	}
+
+	relation := f.Relation()
+	if relation == "" && len(tables) > 0 {
+		relation = getAlias(tables[0], aliases)
+	}
+	name := "?column?"
+	if attr.Token != parser.StringToken {
+		name = attr.Lexeme
+	}
+	return agnostic.NewExprSelector(relation, name, f), nil
+}
+
+// jsonConditionExecutor builds a predicate on document attribute cond of relation, optionally followed
+// by JSON operators, as in payload->>'type' = 'signup', payload @> '{"plan": "pro"}' or payload ? 'plan'.
+// Literals compared with a document are parsed as documents.
+func (t *Tx) jsonConditionExecutor(cond *parser.Decl, relation string, args []NamedValue, aliases map[string]string) (agnostic.Predicate, error) {
+	attribute := strings.ToLower(cond.Lexeme)
+	n := 0
+	for n < len(cond.Decl) && isJSONOperator(cond.Decl[n]) {
+		n++
+	}
+	left, document, err := jsonOperators(agnostic.NewAttributeValueFunctor(relation, attribute), cond.Decl[:n], args)
+	if err != nil {
+		return nil, err
+	}
+	if n == len(cond.Decl) {
+		return nil, fmt.Errorf("Malformed predicate \"%s\"", cond.Lexeme)
+	}
+
+	op := cond.Decl[n]
+	switch {
+	case op.Token == parser.IsToken:
+		return isExecutor(left, op)
+	case op.Token == parser.InToken:
+		return inExecutor(t, left, op, args)
+	case op.Token == parser.NotToken && len(op.Decl) > 0 && op.Decl[0].Token == parser.InToken:
+		return notInExecutor(t, left, op, args)
+	}
+
+	if n+1 >= len(cond.Decl) {
+		return nil, fmt.Errorf("Malformed predicate \"%s\"", cond.Lexeme)
+	}
+	rightS := cond.Decl[n+1]
+
+	var right agnostic.ValueFunctor
+	switch rightS.Token {
+	case parser.ArgToken:
+		v, err := argValue(rightS, args)
+		if err != nil {
+			return nil, err
+		}
+		right = agnostic.NewConstValueFunctor(v)
+	case parser.SelectToken:
+		v, err := scalarSubqueryValue(t, rightS, args)
+		if err != nil {
+			return nil, err
+		}
+		right = agnostic.NewConstValueFunctor(v)
+	case parser.StringToken:
+		// qualified attribute of the same relation, other relations are compared with JOIN ON
+		if len(rightS.Decl) > 0 {
+			rightTableName := getAlias(rightS.Decl[0].Lexeme, aliases)
+			if rightTableName != relation {
+				return nil, fmt.Errorf("cannot compare %s.%s with %s.%s, use JOIN ... ON", relation, attribute, rightTableName, rightS.Lexeme)
+			}
+			right = agnostic.NewAttributeValueFunctor(rightTableName, strings.ToLower(rightS.Lexeme))
+			break
+		}
+		fallthrough
+	default:
+		// ->> and #>> return text, as well as keys of ?
+		if !document || op.Token == parser.KeyExistsToken {
+			right = agnostic.NewConstValueFunctor(rightS.Lexeme)
+			break
+		}
+		v, err := agnostic.ParseJSON(rightS.Lexeme)
+		if err != nil {
+			return nil, err
+		}
+		right = agnostic.NewConstValueFunctor(v)
+	}
+
+	switch op.Token {
+	case parser.ContainsToken:
+		return agnostic.NewJSONPredicate(left, agnostic.Contains, right), nil
+	case parser.ContainedByToken:
+		return agnostic.NewJSONPredicate(left, agnostic.ContainedBy, right), nil
+	case parser.KeyExistsToken:
+		return agnostic.NewJSONPredicate(left, agnostic.KeyExists, right), nil
+	}
+
+	ptype, err := comparisonType(op)
+	if err != nil {
+		return nil, err
+	}
+	return agnostic.NewComparisonPredicate(left, ptype, right)
+}
+
+// jsonArrayElementsValues returns elements of the array document of json_array_elements(...)
+func (t *Tx) jsonArrayElementsValues(d *parser.Decl, args []NamedValue) ([]any, error) {
+	if len(d.Decl) == 0 {
+		return nil, ParsingError
+	}
+	f, err := t.jsonValue(d.Decl[0], "", nil, nil, args)
+	if err != nil {
+		return nil, err
+	}
+	v := f.Value(nil, nil)
+	if v == nil {
+		return nil, nil
+	}
+	return agnostic.JSONArrayElements(v)
+}
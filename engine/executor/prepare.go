@@ -0,0 +1,325 @@
+package executor
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/proullon/ramsql/engine/agnostic"
+	"github.com/proullon/ramsql/engine/parser"
+)
+
+// Prepared is a query parsed once, which can be executed several times with
+// Tx.ExecPrepared and Tx.QueryPrepared.
+type Prepared struct {
+	Query string
+	// Params holds parameter markers of the query, in order of first appearance
+	Params []Param
+
+	instructions []parser.Instruction
+	e            *Engine
+}
+
+// Param describes a parameter marker of a prepared query.
+type Param struct {
+	// Name is set for named parameters, such as :name
+	Name string
+	// Ordinal is set for positional parameters, such as ? or $1, starting at 1
+	Ordinal int
+	// Type is the type of the attribute the parameter is compared or assigned to.
+	// ScanType is nil if unknown.
+	Type agnostic.ColumnType
+	// Checked is true if values must be assignable to Type, which is the case
+	// for INSERT and UPDATE values and LIMIT and OFFSET clauses
+	Checked bool
+
+	schema   string
+	relation string
+	attr     string
+	// array is true for parameters holding elements compared with attr, as in attr = ANY($1)
+	array bool
+}
+
+func (p Param) String() string {
+	if p.Name != "" {
+		return ":" + p.Name
+	}
+	return "$" + strconv.Itoa(p.Ordinal)
+}
+
+// Prepare parses query and describes its parameters.
+//
+// Parameter types are resolved from the schema at prepare time, parameters
+// referencing an unknown relation or attribute have no type.
+func (e *Engine) Prepare(query string) (*Prepared, error) {
+	instructions, err := parser.ParseInstructionWithDialect(query, e.dialect)
+	if err != nil {
+		return nil, err
+	}
+	if len(instructions) == 0 {
+		return nil, fmt.Errorf("empty statement")
+	}
+
+	c := &paramCollector{}
+	for _, inst := range instructions {
+		for _, d := range inst.Decls {
+			if err := c.walk(d, &paramScope{}, paramContext{}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	tx, err := e.memstore.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	for i, p := range c.params {
+		if p.attr == "" {
+			continue
+		}
+		if _, attr, err := tx.RelationAttribute(p.schema, p.relation, p.attr); err == nil {
+			c.params[i].Type = attr.ColumnType()
+			if p.array {
+				c.params[i].Type = c.params[i].Type.ArrayOf()
+			}
+		}
+	}
+
+	return &Prepared{Query: query, Params: c.params, instructions: instructions, e: e}, nil
+}
+
+// NumInput returns the number of arguments expected by the query: the highest
+// ordinal for positional parameters, or the number of distinct names for named
+// parameters. It returns -1 if both are used.
+func (p *Prepared) NumInput() int {
+	var positional, named int
+	for _, param := range p.Params {
+		if param.Name != "" {
+			named++
+			continue
+		}
+		if param.Ordinal > positional {
+			positional = param.Ordinal
+		}
+	}
+	if positional > 0 && named > 0 {
+		return -1
+	}
+	return positional + named
+}
+
+// Param returns the parameter matching name, or ordinal if name is empty.
+func (p *Prepared) Param(name string, ordinal int) (Param, bool) {
+	for _, param := range p.Params {
+		if name != "" && param.Name == name || name == "" && param.Name == "" && param.Ordinal == ordinal {
+			return param, true
+		}
+	}
+	return Param{}, false
+}
+
+// CheckArg returns an error if v cannot be bound to the parameter matching name, or ordinal.
+func (p *Prepared) CheckArg(name string, ordinal int, v any) error {
+	param, ok := p.Param(name, ordinal)
+	if !ok || !param.Checked || param.Type.ScanType == nil || v == nil {
+		return nil
+	}
+
+	v = p.e.memstore.FromText(v, param.Type.TypeName, param.Type.ScanType)
+	tof := reflect.TypeOf(v)
+	if !p.e.memstore.Assignable(tof, param.Type.ScanType) {
+		return fmt.Errorf("cannot use '%v' (type %s) as parameter %s (type %s)", v, tof, param, param.Type.TypeName)
+	}
+	return nil
+}
+
+// paramScope holds relations referenced by the statement being walked
+type paramScope struct {
+	schema   string
+	relation string
+	aliases  map[string]string
+}
+
+// paramContext describes where a parameter marker is found
+type paramContext struct {
+	attr      string
+	qualifier string
+	checked   bool
+	integer   bool
+	array     bool
+}
+
+type paramCollector struct {
+	params []Param
+	odbc   int
+}
+
+func (c *paramCollector) walk(d *parser.Decl, s *paramScope, ctx paramContext) error {
+	switch d.Token {
+	case parser.ArgToken, parser.NamedArgToken:
+		if err := c.add(d, s, ctx); err != nil {
+			return err
+		}
+		// a parameter compared with ANY or ALL elements holds the comparison, as in $1 = ANY($2)
+		ctx = paramContext{}
+	case parser.InsertToken:
+		return c.insert(d)
+	case parser.SelectToken, parser.DeleteToken:
+		s = &paramScope{}
+	case parser.UpdateToken:
+		s = &paramScope{}
+		if len(d.Decl) > 0 {
+			s.relation = d.Decl[0].Lexeme
+			if schema, ok := d.Decl[0].Has(parser.SchemaToken); ok {
+				s.schema = schema.Lexeme
+			}
+		}
+	case parser.FromToken:
+		c.from(d, s)
+	case parser.SetToken:
+		for _, attr := range d.Decl {
+			for _, v := range attr.Decl {
+				if err := c.walk(v, s, paramContext{attr: attr.Lexeme, checked: true}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case parser.LimitToken, parser.OffsetToken:
+		ctx = paramContext{checked: true, integer: true}
+	case parser.AnyToken, parser.AllToken:
+		ctx.array = true
+	case parser.JSONBuildObjectToken, parser.JSONSetToken, parser.JSONArrayElementsToken:
+		ctx = paramContext{}
+	case parser.StringToken:
+		// attribute compared to its children, optionally qualified by relation name or alias
+		if len(d.Decl) > 0 {
+			ctx = paramContext{attr: d.Lexeme}
+			if len(d.Decl) > 1 && d.Decl[0].Token == parser.StringToken {
+				ctx.qualifier = d.Decl[0].Lexeme
+			}
+		}
+		// members of documents and keys have no attribute type
+		if _, ok := d.Has(parser.KeyExistsToken); ok || hasJSONOperator(d) {
+			ctx = paramContext{}
+		}
+	}
+
+	for _, child := range d.Decl {
+		if err := c.walk(child, s, ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insert assigns VALUES parameters to specified attributes, by position
+func (c *paramCollector) insert(d *parser.Decl) error {
+	if len(d.Decl) == 0 || len(d.Decl[0].Decl) == 0 {
+		return nil
+	}
+
+	table := d.Decl[0].Decl[0]
+	s := &paramScope{relation: table.Lexeme}
+	var attrs []string
+	for _, a := range table.Decl {
+		if a.Token == parser.SchemaToken {
+			s.schema = a.Lexeme
+			continue
+		}
+		attrs = append(attrs, a.Lexeme)
+	}
+
+	for _, child := range d.Decl[1:] {
+		if child.Token != parser.ValuesToken {
+			if err := c.walk(child, s, paramContext{}); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, values := range child.Decl {
+			for i, v := range values.Decl {
+				ctx := paramContext{checked: true}
+				if i < len(attrs) {
+					ctx.attr = attrs[i]
+				}
+				if err := c.walk(v, s, ctx); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// from registers relations and aliases of a FROM clause, the first one being the default relation
+func (c *paramCollector) from(d *parser.Decl, s *paramScope) {
+	for _, t := range d.Decl {
+		if t.Token != parser.StringToken {
+			continue
+		}
+		if s.relation == "" {
+			s.relation = t.Lexeme
+			if schema, ok := t.Has(parser.SchemaToken); ok {
+				s.schema = schema.Lexeme
+			}
+		}
+		for _, alias := range t.Decl {
+			if alias.Token != parser.StringToken {
+				continue
+			}
+			if s.aliases == nil {
+				s.aliases = make(map[string]string)
+			}
+			s.aliases[alias.Lexeme] = t.Lexeme
+		}
+	}
+}
+
+func (c *paramCollector) add(d *parser.Decl, s *paramScope, ctx paramContext) error {
+	p := Param{Checked: ctx.checked}
+	switch {
+	case d.Token == parser.NamedArgToken:
+		p.Name = d.Lexeme
+	case d.Lexeme == "?":
+		c.odbc++
+		p.Ordinal = c.odbc
+	default:
+		n, err := strconv.Atoi(d.Lexeme)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid parameter $%s", d.Lexeme)
+		}
+		p.Ordinal = n
+	}
+
+	if ctx.integer {
+		p.Type = agnostic.ColumnType{TypeName: "BIGINT", ScanType: reflect.TypeOf(int64(0))}
+	}
+	if ctx.attr != "" {
+		p.schema, p.relation, p.attr, p.array = s.schema, s.relation, ctx.attr, ctx.array
+		if ctx.qualifier != "" {
+			p.relation = ctx.qualifier
+			if r, ok := s.aliases[ctx.qualifier]; ok {
+				p.relation = r
+			}
+		}
+	}
+
+	// a parameter used several times is described by its first typed occurrence
+	for i, prev := range c.params {
+		if prev.Name != p.Name || prev.Ordinal != p.Ordinal {
+			continue
+		}
+		if prev.attr == "" && prev.Type.ScanType == nil {
+			p.Checked = p.Checked || prev.Checked
+			c.params[i] = p
+		} else {
+			c.params[i].Checked = prev.Checked || p.Checked
+		}
+		return nil
+	}
+
+	c.params = append(c.params, p)
+	return nil
+}
@@ -0,0 +1,178 @@
+package executor
+
+import (
+	"context"
+	"sync"
+
+	"github.com/proullon/ramsql/engine/agnostic"
+)
+
+// Rows iterates over the result of a query.
+//
+// Rows of a SELECT are produced on demand, so they must be read before the
+// transaction ends. Hooks After callbacks and query history see the statement
+// once Rows is closed, with the number of rows read. Other statements are
+// executed before rows are returned.
+type Rows struct {
+	types []agnostic.ColumnType
+
+	cursor *agnostic.Cursor
+	tuples []*agnostic.Tuple
+	pos    int
+	stream bool
+
+	// tx is ended with Rows, see Autocommit
+	tx *Tx
+
+	// next holds rows of the following statement of a multi-statement query
+	next *Rows
+
+	// ctx is the statement context, see timeout
+	ctx    context.Context
+	parent context.Context
+	cancel context.CancelFunc
+
+	count  int64
+	err    error
+	done   func(int64, error) error
+	closed bool
+
+	sync.Mutex
+}
+
+func newRows(types []agnostic.ColumnType, tuples []*agnostic.Tuple) *Rows {
+	return &Rows{types: types, tuples: tuples, count: int64(len(tuples))}
+}
+
+func newCursorRows(c *agnostic.Cursor) *Rows {
+	return &Rows{types: c.ColumnTypes(), cursor: c, stream: true}
+}
+
+// Columns returns the type of each returned column
+func (r *Rows) Columns() []agnostic.ColumnType {
+	return r.types
+}
+
+// NextResultSet returns rows of the next statement of a multi-statement query, or nil
+func (r *Rows) NextResultSet() *Rows {
+	return r.next
+}
+
+// Autocommit makes Rows of the last statement end tx, committed or rolled back
+// on error once all rows are read or Rows is closed. Rows not produced on demand
+// end tx before Autocommit returns.
+//
+// tx should be a Snapshot transaction, so unread rows don't keep queried
+// relations locked.
+func (r *Rows) Autocommit(tx *Tx) error {
+	for r.next != nil {
+		r = r.next
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.tx = tx
+	if r.cursor == nil {
+		r.end()
+	}
+	return r.err
+}
+
+// timeout makes rows produced on demand fail once statement context ctx, derived
+// from parent, is done. cancel is called when Rows is read or closed.
+func (r *Rows) timeout(parent, ctx context.Context, cancel context.CancelFunc) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.cursor == nil {
+		cancel()
+		return
+	}
+	r.ctx, r.parent, r.cancel = ctx, parent, cancel
+}
+
+// Next returns the next row, or nil once all rows have been returned
+func (r *Rows) Next() (*agnostic.Tuple, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.closed || r.err != nil {
+		return nil, r.err
+	}
+
+	var tup *agnostic.Tuple
+	if r.cursor != nil {
+		if r.ctx != nil && r.ctx.Err() != nil {
+			r.err = timeoutError(r.parent, r.ctx, r.ctx.Err())
+		} else {
+			tup, r.err = r.cursor.Next()
+		}
+		if tup == nil {
+			r.cursor = nil
+			r.end()
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+	} else if r.pos < len(r.tuples) {
+		tup = r.tuples[r.pos]
+		r.pos++
+	}
+
+	if tup != nil && r.stream {
+		r.count++
+	}
+	return tup, nil
+}
+
+// Close ends the statement. The returned error is the query error, possibly replaced by a hook.
+func (r *Rows) Close() error {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.closed {
+		return r.err
+	}
+	r.closed = true
+
+	if r.done != nil {
+		r.err = r.done(r.count, r.err)
+	}
+	r.end()
+	return r.err
+}
+
+// buffer reads remaining rows, so the transaction can execute other statements
+func (r *Rows) buffer() error {
+	r.Lock()
+	defer r.Unlock()
+
+	r.drain()
+	return r.err
+}
+
+func (r *Rows) drain() {
+	if r.cursor != nil && r.err == nil {
+		r.tuples, r.err = r.cursor.All()
+		r.pos = 0
+	}
+	r.cursor = nil
+}
+
+func (r *Rows) end() {
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+	if r.tx == nil {
+		return
+	}
+
+	if r.err == nil {
+		r.err = r.tx.Commit()
+	} else {
+		r.tx.Rollback()
+	}
+	r.tx = nil
+}
@@ -0,0 +1,347 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/proullon/ramsql/engine/agnostic"
+	"github.com/proullon/ramsql/engine/parser"
+)
+
+// setting describes a session configuration parameter
+type setting struct {
+	// def is the value of the parameter until it is SET, see Tx.defaultSetting
+	def string
+	// normalize validates values given to SET and returns the value SHOW returns
+	normalize func(name string, values []string) (string, error)
+}
+
+var settings = map[string]setting{
+	"application_name":            {def: "", normalize: single(nil)},
+	"search_path":                 {def: `"$user", public`, normalize: normalizeSearchPath},
+	"standard_conforming_strings": {def: "on", normalize: single(normalizeConformingStrings)},
+	"statement_timeout":           {def: "0", normalize: single(normalizeTimeout)},
+	"timezone":                    {def: "UTC", normalize: single(normalizeTimezone)},
+}
+
+// Session holds the configuration parameters of a connection, shared by its transactions.
+//
+// Parameters SET in a transaction are visible to the session once it commits,
+// parameters SET LOCAL only last until the transaction ends.
+type Session struct {
+	values map[string]string
+	// lastInsertID is the value of MySQL LAST_INSERT_ID()
+	lastInsertID int64
+
+	sync.Mutex
+}
+
+// NewSession returns a Session with all parameters to their default.
+func NewSession() *Session {
+	return &Session{values: make(map[string]string)}
+}
+
+// Get returns the value of parameter name in the session, or its default if never SET.
+func (s *Session) Get(name string) (string, error) {
+	name = strings.ToLower(name)
+	st, ok := settings[name]
+	if !ok {
+		return "", unknownSetting(name)
+	}
+	if v, ok := s.value(name); ok {
+		return v, nil
+	}
+	return st.def, nil
+}
+
+// Set changes parameter name in the session, as a committed SET would.
+// Values of search_path are separated by commas.
+func (s *Session) Set(name, value string) error {
+	name = strings.ToLower(name)
+	st, ok := settings[name]
+	if !ok {
+		return unknownSetting(name)
+	}
+
+	values := []string{value}
+	if name == "search_path" {
+		values = strings.Split(value, ",")
+		for i := range values {
+			values[i] = strings.Trim(strings.TrimSpace(values[i]), `"`)
+		}
+	}
+	v, err := st.normalize(name, values)
+	if err != nil {
+		return err
+	}
+	s.merge(map[string]*string{name: &v})
+	return nil
+}
+
+// LastInsertID returns the id of the last row inserted in the session, as MySQL LAST_INSERT_ID() does.
+func (s *Session) LastInsertID() int64 {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.lastInsertID
+}
+
+func (s *Session) setLastInsertID(id int64) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.lastInsertID = id
+}
+
+// value returns the value of parameter name, if SET in the session
+func (s *Session) value(name string) (string, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	v, ok := s.values[name]
+	return v, ok
+}
+
+// merge applies values SET in a committed transaction. Nil values reset parameters.
+func (s *Session) merge(values map[string]*string) {
+	s.Lock()
+	defer s.Unlock()
+
+	for name, v := range values {
+		if v == nil {
+			delete(s.values, name)
+			continue
+		}
+		s.values[name] = *v
+	}
+}
+
+// SetSession makes transaction statements use and change session parameters.
+func (t *Tx) SetSession(s *Session) {
+	t.session = s
+	t.applySearchPath()
+}
+
+// setting returns the value of parameter name as seen by the transaction
+func (t *Tx) setting(name string) string {
+	if v, ok := t.local[name]; ok {
+		return v
+	}
+	if v, ok := t.pending[name]; ok {
+		if v == nil {
+			return t.defaultSetting(name)
+		}
+		return *v
+	}
+	if v, ok := t.session.value(name); ok {
+		return v
+	}
+	return t.defaultSetting(name)
+}
+
+// defaultSetting returns the value of parameter name until it is SET.
+// search_path defaults to the engine search path.
+func (t *Tx) defaultSetting(name string) string {
+	if name == "search_path" {
+		return `"$user", ` + t.e.memstore.CurrentSchema()
+	}
+	return settings[name].def
+}
+
+// applySearchPath makes unqualified relations resolve through search_path
+func (t *Tx) applySearchPath() {
+	var path []string
+	for _, s := range strings.Split(t.setting("search_path"), ",") {
+		s = strings.Trim(strings.TrimSpace(s), `"`)
+		if s == "" || s == "$user" {
+			continue
+		}
+		path = append(path, s)
+	}
+	if len(path) == 0 {
+		path = []string{agnostic.DefaultSchema}
+	}
+	t.tx.SetSearchPath(path)
+}
+
+// statementTimeout returns ctx with statement_timeout deadline, if any
+func (t *Tx) statementTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	d, _ := parseTimeout(t.setting("statement_timeout"))
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// timeoutError returns a query canceled error if statement ran past statement_timeout
+func timeoutError(parent, ctx context.Context, err error) error {
+	if parent.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return agnostic.NewError(agnostic.QueryCanceled, "canceling statement due to statement timeout")
+	}
+	return err
+}
+
+func setExecutor(t *Tx, setDecl *parser.Decl, args []NamedValue) (int64, int64, []agnostic.ColumnType, []*agnostic.Tuple, error) {
+	local := false
+	nameDecl := setDecl.Decl[0]
+	if nameDecl.Token == parser.LocalToken {
+		local = true
+		nameDecl = setDecl.Decl[1]
+	}
+
+	name := nameDecl.Lexeme
+	st, ok := settings[name]
+	if !ok {
+		return 0, 0, nil, nil, unknownSetting(name)
+	}
+
+	value := t.defaultSetting(name)
+	if len(nameDecl.Decl) != 1 || nameDecl.Decl[0].Token != parser.DefaultToken {
+		var values []string
+		for _, d := range nameDecl.Decl {
+			values = append(values, d.Lexeme)
+		}
+		v, err := st.normalize(name, values)
+		if err != nil {
+			return 0, 0, nil, nil, err
+		}
+		value = v
+	}
+
+	if local {
+		t.local[name] = value
+	} else {
+		delete(t.local, name)
+		t.pending[name] = &value
+	}
+	t.applySearchPath()
+
+	return 0, 0, nil, nil, nil
+}
+
+func resetExecutor(t *Tx, resetDecl *parser.Decl, args []NamedValue) (int64, int64, []agnostic.ColumnType, []*agnostic.Tuple, error) {
+	name := resetDecl.Decl[0].Lexeme
+	names := []string{name}
+	if name == "all" {
+		names = settingNames()
+	} else if _, ok := settings[name]; !ok {
+		return 0, 0, nil, nil, unknownSetting(name)
+	}
+
+	for _, name := range names {
+		delete(t.local, name)
+		t.pending[name] = nil
+	}
+	t.applySearchPath()
+
+	return 0, 0, nil, nil, nil
+}
+
+func showExecutor(t *Tx, showDecl *parser.Decl, args []NamedValue) (int64, int64, []agnostic.ColumnType, []*agnostic.Tuple, error) {
+	name := showDecl.Decl[0].Lexeme
+
+	if name == "all" {
+		var res []*agnostic.Tuple
+		for _, name := range settingNames() {
+			res = append(res, agnostic.NewTuple(name, t.setting(name)))
+		}
+		return 0, int64(len(res)), agnostic.NewColumnTypes([]string{"name", "setting"}, res), res, nil
+	}
+
+	if _, ok := settings[name]; !ok {
+		return 0, 0, nil, nil, unknownSetting(name)
+	}
+
+	res := []*agnostic.Tuple{agnostic.NewTuple(t.setting(name))}
+	return 0, 1, agnostic.NewColumnTypes([]string{name}, res), res, nil
+}
+
+func settingNames() []string {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func unknownSetting(name string) error {
+	return agnostic.NewError(agnostic.UndefinedObject, "unrecognized configuration parameter \"%s\"", name)
+}
+
+func invalidValue(name, value string) error {
+	return agnostic.NewError(agnostic.InvalidParameterValue, "invalid value for parameter \"%s\": \"%s\"", name, value)
+}
+
+// single returns a normalize function for parameters taking one value
+func single(normalize func(name, value string) (string, error)) func(string, []string) (string, error) {
+	return func(name string, values []string) (string, error) {
+		if len(values) != 1 {
+			return "", fmt.Errorf("SET %s takes only one argument", name)
+		}
+		if normalize == nil {
+			return values[0], nil
+		}
+		return normalize(name, values[0])
+	}
+}
+
+func normalizeSearchPath(name string, values []string) (string, error) {
+	for i, v := range values {
+		if v == "$user" {
+			values[i] = `"$user"`
+		}
+	}
+	return strings.Join(values, ", "), nil
+}
+
+func normalizeConformingStrings(name, value string) (string, error) {
+	if strings.EqualFold(value, "on") || strings.EqualFold(value, "true") {
+		return "on", nil
+	}
+	return "", invalidValue(name, value)
+}
+
+func normalizeTimezone(name, value string) (string, error) {
+	if _, err := time.LoadLocation(value); err != nil {
+		return "", invalidValue(name, value)
+	}
+	return value, nil
+}
+
+func normalizeTimeout(name, value string) (string, error) {
+	d, err := parseTimeout(value)
+	if err != nil || d < 0 {
+		return "", invalidValue(name, value)
+	}
+
+	switch {
+	case d == 0:
+		return "0", nil
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour), nil
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dmin", d/time.Minute), nil
+	case d%time.Second == 0:
+		return fmt.Sprintf("%ds", d/time.Second), nil
+	}
+	return fmt.Sprintf("%dms", d/time.Millisecond), nil
+}
+
+// parseTimeout parses a duration in milliseconds, or with ms, s, min or h unit
+func parseTimeout(value string) (time.Duration, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+	if strings.HasSuffix(value, "min") {
+		value = strings.TrimSuffix(value, "in")
+	}
+	return time.ParseDuration(value)
+}
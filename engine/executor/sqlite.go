@@ -0,0 +1,240 @@
+package executor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/proullon/ramsql/engine/agnostic"
+	"github.com/proullon/ramsql/engine/parser"
+)
+
+// sqliteMasterAttributes are the attributes of SQLite sqlite_master relation
+func sqliteMasterAttributes() []agnostic.Attribute {
+	return []agnostic.Attribute{
+		agnostic.NewAttribute("type", "text"),
+		agnostic.NewAttribute("name", "text"),
+		agnostic.NewAttribute("tbl_name", "text"),
+		agnostic.NewAttribute("rootpage", "integer"),
+		agnostic.NewAttribute("sql", "text"),
+	}
+}
+
+// sqliteMaster returns the rows of sqlite_master, describing relations of schema
+// and their indexes. Indexes created by PRIMARY KEY and UNIQUE constraints are not listed.
+func (e *Engine) sqliteMaster(schema string) func() [][]any {
+	return func() [][]any {
+		var rows [][]any
+		for _, ri := range e.memstore.Catalog() {
+			if ri.Schema != schema || ri.Virtual {
+				continue
+			}
+			rows = append(rows, []any{"table", ri.Name, ri.Name, int64(len(rows) + 2), createTableSQL(ri)})
+			for _, ii := range ri.Indexes {
+				if ii.Primary || ii.Unique {
+					continue
+				}
+				sql := fmt.Sprintf("CREATE INDEX %s ON %s (%s)", ii.Name, ri.Name, strings.Join(ii.Columns, ", "))
+				rows = append(rows, []any{"index", ii.Name, ri.Name, int64(len(rows) + 2), sql})
+			}
+		}
+		return rows
+	}
+}
+
+// createTableSQL returns the CREATE TABLE statement of relation, as stored in sqlite_master
+func createTableSQL(ri agnostic.RelationInfo) string {
+	var defs []string
+
+	for _, c := range ri.Columns {
+		def := c.Name
+		if t := declaredType(c); t != "" {
+			def += " " + t
+		}
+		if len(ri.PrimaryKey) == 1 && ri.PrimaryKey[0] == c.Name {
+			def += " PRIMARY KEY"
+		}
+		if c.AutoIncrement && !c.RowID {
+			def += " AUTOINCREMENT"
+		}
+		if c.NotNull {
+			def += " NOT NULL"
+		}
+		if c.Unique {
+			def += " UNIQUE"
+		}
+		if v := defaultSQL(c); v != nil {
+			def += " DEFAULT " + v.(string)
+		}
+		defs = append(defs, def)
+	}
+
+	if len(ri.PrimaryKey) > 1 {
+		defs = append(defs, "PRIMARY KEY ("+strings.Join(ri.PrimaryKey, ", ")+")")
+	}
+
+	// foreign keys are shared by their local attributes, declared once
+	for _, c := range ri.Columns {
+		fk := c.ForeignKey
+		if fk == nil || len(fk.LocalColumns()) == 0 || fk.LocalColumns()[0] != c.Name {
+			continue
+		}
+		def := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)",
+			strings.Join(fk.LocalColumns(), ", "), fk.RefRelation(), strings.Join(fk.RefColumns(), ", "))
+		if action := fk.OnDeleteAction(); action != "" {
+			def += " ON DELETE " + strings.ToUpper(action)
+		}
+		defs = append(defs, def)
+	}
+
+	return fmt.Sprintf("CREATE TABLE %s (%s)", ri.Name, strings.Join(defs, ", "))
+}
+
+// declaredType returns the type of c as declared, with its size
+func declaredType(c agnostic.ColumnInfo) string {
+	t := strings.ToUpper(c.DeclaredType)
+
+	switch {
+	case c.HasLength:
+		return fmt.Sprintf("%s(%d)", t, c.Length)
+	case c.HasPrecision && c.Scale > 0:
+		return fmt.Sprintf("%s(%d,%d)", t, c.Precision, c.Scale)
+	case c.HasPrecision:
+		return fmt.Sprintf("%s(%d)", t, c.Precision)
+	}
+	return t
+}
+
+// defaultSQL returns the default value of c as an SQL literal, or nil without default.
+func defaultSQL(c agnostic.ColumnInfo) any {
+	if c.DefaultNow {
+		return "CURRENT_TIMESTAMP"
+	}
+	if !c.HasDefault {
+		return nil
+	}
+
+	switch v := c.Default.(type) {
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case time.Time:
+		return "'" + v.Format("2006-01-02 15:04:05") + "'"
+	case agnostic.JSON:
+		return "'" + strings.ReplaceAll(v.Text(), "'", "''") + "'"
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// withRowID gives a relation the rowid of SQLite tables. The INTEGER PRIMARY KEY attribute
+// is an alias of rowid, as SQLite does: it is given the next integer when inserted without
+// value or with NULL, and rowid, oid and _rowid_ refer to it. Without such attribute, a hidden
+// rowid attribute is added, unless an attribute is already named rowid, oid or _rowid_.
+func withRowID(attributes []agnostic.Attribute, pk []string) []agnostic.Attribute {
+	if len(pk) == 1 {
+		for i := range attributes {
+			if strings.EqualFold(attributes[i].Name(), pk[0]) && attributes[i].ColumnType().TypeName == "INTEGER" {
+				attributes[i] = attributes[i].WithRowID()
+				return attributes
+			}
+		}
+	}
+
+	for _, a := range attributes {
+		switch a.Name() {
+		case "rowid", "oid", "_rowid_":
+			return attributes
+		}
+	}
+	return append(attributes, agnostic.NewAttribute("rowid", "integer").WithRowID().WithHidden())
+}
+
+// replaceRow deletes rows having the same primary key or unique attribute values than values,
+// then inserts values, as SQLite INSERT OR REPLACE does.
+func replaceRow(t *Tx, schemaName, relationName string, values map[string]any) (*agnostic.Tuple, error) {
+	keys, err := t.tx.ConflictingKeys(schemaName, relationName, values)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) > 0 {
+		convertedValues, err := t.tx.ConvertValuesForRelation(schemaName, relationName, values)
+		if err != nil {
+			return nil, err
+		}
+
+		selectors := []agnostic.Selector{agnostic.NewStarSelector(relationName)}
+		for _, key := range keys {
+			predicate := buildConflictPredicate(relationName, key, convertedValues)
+			if predicate == nil {
+				continue
+			}
+			if _, _, err := t.tx.Delete(schemaName, relationName, selectors, predicate); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return t.tx.Insert(schemaName, relationName, values)
+}
+
+/*
+|-> PRAGMA
+
+	|-> table_info
+	    |-> user
+*/
+func pragmaExecutor(t *Tx, pragmaDecl *parser.Decl, args []NamedValue) (int64, int64, []agnostic.ColumnType, []*agnostic.Tuple, error) {
+	nameDecl := pragmaDecl.Decl[0]
+
+	if nameDecl.Lexeme == "table_info" {
+		if len(nameDecl.Decl) == 0 {
+			return 0, 0, nil, nil, fmt.Errorf("PRAGMA table_info requires a relation name")
+		}
+		return tableInfo(t, nameDecl.Decl[0].Lexeme)
+	}
+
+	// settings such as foreign_keys or journal_mode do not apply to an in-memory engine
+	if len(nameDecl.Decl) > 0 {
+		return 0, 0, nil, nil, nil
+	}
+
+	return 0, 0, nil, nil, fmt.Errorf("unsupported pragma %s", nameDecl.Lexeme)
+}
+
+// tableInfo returns a row per attribute of relation, as SQLite PRAGMA table_info does.
+// No row is returned if relation does not exist.
+func tableInfo(t *Tx, relation string) (int64, int64, []agnostic.ColumnType, []*agnostic.Tuple, error) {
+	cols := []string{"cid", "name", "type", "notnull", "dflt_value", "pk"}
+
+	var res []*agnostic.Tuple
+	if t.tx.CheckRelation("", relation) {
+		ri, err := t.tx.RelationInfo("", relation)
+		if err != nil {
+			return 0, 0, nil, nil, err
+		}
+
+		for cid, c := range ri.Columns {
+			var notNull, pk int64
+			if c.NotNull {
+				notNull = 1
+			}
+			for i, name := range ri.PrimaryKey {
+				if name == c.Name {
+					pk = int64(i + 1)
+				}
+			}
+			res = append(res, agnostic.NewTuple(int64(cid), c.Name, declaredType(c), notNull, defaultSQL(c), pk))
+		}
+	}
+
+	return 0, int64(len(res)), agnostic.NewColumnTypes(cols, res), res, nil
+}
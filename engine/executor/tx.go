@@ -5,15 +5,15 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"strconv"
 	"strings"
+	"time"
 
 	"github.com/proullon/ramsql/engine/agnostic"
 	"github.com/proullon/ramsql/engine/log"
 	"github.com/proullon/ramsql/engine/parser"
 )
 
-type executorFunc func(*Tx, *parser.Decl, []NamedValue) (int64, int64, []string, []*agnostic.Tuple, error)
+type executorFunc func(*Tx, *parser.Decl, []NamedValue) (int64, int64, []agnostic.ColumnType, []*agnostic.Tuple, error)
 
 var (
 	NotImplemented = errors.New("not implemented")
@@ -29,7 +29,18 @@ type NamedValue struct {
 type Tx struct {
 	e            *Engine
 	tx           *agnostic.Transaction
+	id           int64
 	opsExecutors map[int]executorFunc
+
+	// session parameters, with values SET and SET LOCAL in the transaction
+	session *Session
+	pending map[string]*string
+	local   map[string]string
+
+	// ctx is the context of the running statement
+	ctx context.Context
+	// xactLocks are advisory locks released when the transaction ends
+	xactLocks []int64
 }
 
 func NewTx(ctx context.Context, e *Engine, opts sql.TxOptions) (*Tx, error) {
@@ -39,8 +50,12 @@ func NewTx(ctx context.Context, e *Engine, opts sql.TxOptions) (*Tx, error) {
 	}
 
 	t := &Tx{
-		e:  e,
-		tx: tx,
+		e:       e,
+		tx:      tx,
+		id:      e.txID.Add(1),
+		session: NewSession(),
+		pending: make(map[string]*string),
+		local:   make(map[string]string),
 	}
 
 	t.opsExecutors = map[int]executorFunc{
@@ -56,85 +71,277 @@ func NewTx(ctx context.Context, e *Engine, opts sql.TxOptions) (*Tx, error) {
 		parser.DropToken:     dropExecutor,
 		parser.GrantToken:    grantExecutor,
 		parser.WithToken:     withExecutor,
+		parser.GenerateToken: generateExecutor,
+		parser.SetToken:      setExecutor,
+		parser.ResetToken:    resetExecutor,
+		parser.ShowToken:     showExecutor,
+		parser.PragmaToken:   pragmaExecutor,
+		parser.AlterToken:    alterExecutor,
 	}
 
 	return t, nil
 }
 
-func (t *Tx) QueryContext(ctx context.Context, query string, args []NamedValue) ([]string, []*agnostic.Tuple, error) {
+// QueryContext runs query and returns its rows, which must be closed before the transaction ends.
+//
+// If query holds several statements, they are executed in order and the rows of
+// each statement are returned by Rows.NextResultSet. Rows of all statements but
+// the last are read before the next statement is executed.
+func (t *Tx) QueryContext(ctx context.Context, query string, args []NamedValue) (*Rows, error) {
+	start := time.Now()
+	rows, err := t.queryContext(ctx, query, args, start)
+	if err != nil {
+		t.record(query, args, start, 0, err)
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// QueryPrepared runs p and returns its rows, as QueryContext does.
+func (t *Tx) QueryPrepared(ctx context.Context, p *Prepared, args []NamedValue) (*Rows, error) {
+	start := time.Now()
+	rows, err := t.queryInstructions(ctx, p.Query, p.instructions, args, start)
+	if err != nil {
+		t.record(p.Query, args, start, 0, err)
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+func (t *Tx) queryContext(ctx context.Context, query string, args []NamedValue, start time.Time) (*Rows, error) {
+
+	if cq, ok := findCatalogQuery(query); ok {
+		inst := parser.Instruction{Decls: []*parser.Decl{{Token: parser.SelectToken, Lexeme: "select"}}, Text: query}
+		rows, err := t.queryStatement(ctx, query, args, &inst, func() (*Rows, error) {
+			return cq.rows(t, args)
+		})
+		if err != nil {
+			return nil, err
+		}
+		rows.done = recordedBy(t, query, args, start, rows.done)
+		return rows, nil
+	}
+
+	instructions, err := parser.ParseInstructionWithDialect(query, t.e.dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.queryInstructions(ctx, query, instructions, args, start)
+}
+
+func (t *Tx) queryInstructions(ctx context.Context, query string, instructions []parser.Instruction, args []NamedValue, start time.Time) (*Rows, error) {
+	var err error
+
+	if len(instructions) == 0 {
+		return nil, fmt.Errorf("expected 1 query")
+	}
+
+	instructions, bound, err := bind(instructions, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var first, last *Rows
+	for i := range instructions {
+		inst := instructions[i]
+		if len(inst.Decls) == 0 {
+			err = fmt.Errorf("expected 1 query")
+		}
+
+		var rows *Rows
+		if err == nil {
+			rows, err = t.queryStatement(ctx, query, args, &inst, func() (*Rows, error) {
+				return t.query(inst, bound)
+			})
+		}
+		if err == nil && i < len(instructions)-1 {
+			err = rows.buffer()
+		}
+		if err != nil {
+			for r := first; r != nil; r = r.next {
+				r.Close()
+			}
+			if rows != nil {
+				rows.Close()
+			}
+			return nil, err
+		}
 
-	instructions, err := parser.ParseInstruction(query)
+		text := query
+		if len(instructions) > 1 && inst.Text != "" {
+			text = inst.Text
+		}
+		rows.done = recordedBy(t, text, args, start, rows.done)
+		if first == nil {
+			first = rows
+		} else {
+			last.next = rows
+		}
+		last = rows
+	}
+
+	return first, nil
+}
+
+// queryStatement runs inst with run between engine hooks, which are given arguments as provided
+func (t *Tx) queryStatement(ctx context.Context, query string, args []NamedValue, inst *parser.Instruction, run func() (*Rows, error)) (*Rows, error) {
+	after, err := t.beforeHooks(query, args, inst)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	if len(instructions) != 1 {
-		return nil, nil, fmt.Errorf("expected 1 query, got %d", len(instructions))
+	sctx, cancel := t.statementTimeout(ctx)
+	if err := t.statementFault(sctx, inst); err != nil {
+		cancel()
+		return nil, after(0, timeoutError(ctx, sctx, err))
 	}
 
-	inst := instructions[0]
-	if len(inst.Decls) == 0 {
-		return nil, nil, fmt.Errorf("expected 1 query")
+	t.ctx = sctx
+	rows, err := run()
+	t.ctx = nil
+	if err == nil && sctx.Err() != nil {
+		rows.Close()
+		err = sctx.Err()
 	}
+	if err != nil {
+		cancel()
+		return nil, after(0, timeoutError(ctx, sctx, err))
+	}
+	rows.done = after
+	rows.timeout(ctx, sctx, cancel)
+
+	return rows, nil
+}
 
+// recordedBy records statement in query history once done
+func recordedBy(t *Tx, query string, args []NamedValue, start time.Time, done func(int64, error) error) func(int64, error) error {
+	return func(n int64, err error) error {
+		err = done(n, err)
+		t.record(query, args, start, n, err)
+		return err
+	}
+}
+
+func (t *Tx) query(inst parser.Instruction, args []NamedValue) (*Rows, error) {
 	// Handle WITH clause specially
 	if inst.Decls[0].Token == parser.WithToken {
 		// Execute WITH to create temporary tables
 		if _, _, _, _, err := t.opsExecutors[parser.WithToken](t, inst.Decls[0], args); err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 		
 		// Now execute the main SELECT (should be second decl)
 		if len(inst.Decls) < 2 {
-			return nil, nil, fmt.Errorf("WITH clause must be followed by SELECT")
+			return nil, fmt.Errorf("WITH clause must be followed by SELECT")
 		}
 		
 		if inst.Decls[1].Token != parser.SelectToken {
-			return nil, nil, fmt.Errorf("WITH clause must be followed by SELECT, got %d", inst.Decls[1].Token)
+			return nil, fmt.Errorf("WITH clause must be followed by SELECT, got %d", inst.Decls[1].Token)
 		}
 		
 		_, _, cols, res, err := t.opsExecutors[parser.SelectToken](t, inst.Decls[1], args)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 		
-		return cols, res, nil
+		return newRows(cols, res), nil
+	}
+
+	// SELECT rows are produced on demand
+	if inst.Decls[0].Token == parser.SelectToken {
+		c, err := selectCursor(t, inst.Decls[0], args)
+		if err != nil {
+			return nil, err
+		}
+		return newCursorRows(c), nil
 	}
 
 	if t.opsExecutors[inst.Decls[0].Token] == nil {
-		return nil, nil, NotImplemented
+		return nil, NotImplemented
 	}
 
 	_, _, cols, res, err := t.opsExecutors[inst.Decls[0].Token](t, inst.Decls[0], args)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	return cols, res, nil
+	return newRows(cols, res), nil
 }
 
 // Commit the transaction on server
 func (t *Tx) Commit() error {
+	defer t.releaseXactLocks()
 	_, err := t.tx.Commit()
+	if err == nil {
+		t.session.merge(t.pending)
+	}
 	return err
 }
 
+// Snapshot makes queries read rows as they are when planned, see agnostic.Transaction.Snapshot
+func (t *Tx) Snapshot() {
+	t.tx.Snapshot()
+}
+
 // Rollback all changes
 func (t *Tx) Rollback() error {
+	defer t.releaseXactLocks()
 	t.tx.Rollback()
 	return nil
 }
 
 func (t *Tx) ExecContext(ctx context.Context, query string, args []NamedValue) (int64, int64, error) {
+	start := time.Now()
+	lastInsertedID, rowsAffected, err := t.execContext(ctx, query, args)
+	t.record(query, args, start, rowsAffected, err)
+	return lastInsertedID, rowsAffected, err
+}
+
+// ExecPrepared runs p, as ExecContext does.
+func (t *Tx) ExecPrepared(ctx context.Context, p *Prepared, args []NamedValue) (int64, int64, error) {
+	start := time.Now()
+	lastInsertedID, rowsAffected, err := t.execInstructions(ctx, p.Query, p.instructions, args)
+	t.record(p.Query, args, start, rowsAffected, err)
+	return lastInsertedID, rowsAffected, err
+}
+
+func (t *Tx) execContext(ctx context.Context, query string, args []NamedValue) (int64, int64, error) {
 	log.Info("ExecContext(%p, %s)", t.tx, query)
 
-	instructions, err := parser.ParseInstruction(query)
+	instructions, err := parser.ParseInstructionWithDialect(query, t.e.dialect)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return t.execInstructions(ctx, query, instructions, args)
+}
+
+func (t *Tx) execInstructions(ctx context.Context, query string, instructions []parser.Instruction, args []NamedValue) (int64, int64, error) {
+	var err error
+
+	instructions, bound, err := bind(instructions, args)
 	if err != nil {
 		return 0, 0, err
 	}
 
 	var lastInsertedID, rowsAffected, aff int64
 	for _, instruct := range instructions {
-		lastInsertedID, aff, err = t.executeQuery(instruct, args)
+		err = t.withHooks(query, args, &instruct, func() (int64, error) {
+			sctx, cancel := t.statementTimeout(ctx)
+			defer cancel()
+			if err := t.statementFault(sctx, &instruct); err != nil {
+				return 0, timeoutError(ctx, sctx, err)
+			}
+			t.ctx = sctx
+			lastInsertedID, aff, err = t.executeQuery(instruct, bound)
+			t.ctx = nil
+			if err == nil && sctx.Err() != nil {
+				err = sctx.Err()
+			}
+			return aff, timeoutError(ctx, sctx, err)
+		})
 		if err != nil {
 			return 0, 0, err
 		}
@@ -158,7 +365,7 @@ func (t *Tx) executeQuery(i parser.Instruction, args []NamedValue) (int64, int64
 	return l, r, nil
 }
 
-func (t *Tx) getSelector(attr *parser.Decl, schema string, tables []string, aliases map[string]string) (agnostic.Selector, error) {
+func (t *Tx) getSelector(attr *parser.Decl, schema string, tables []string, aliases map[string]string, args []NamedValue) (agnostic.Selector, error) {
 	var err error
 
 	switch attr.Token {
@@ -168,7 +375,7 @@ func (t *Tx) getSelector(attr *parser.Decl, schema string, tables []string, alia
 		if len(tables) > 0 {
 			relation = tables[0]
 		}
-		return agnostic.NewConstSelector(relation, t.tx.Engine().CurrentSchema()), nil
+		return agnostic.NewConstSelector(relation, t.tx.CurrentSchema()), nil
 	case parser.CurrentDatabaseToken:
 		// Handle CURRENT_DATABASE() function
 		relation := ""
@@ -176,6 +383,23 @@ func (t *Tx) getSelector(attr *parser.Decl, schema string, tables []string, alia
 			relation = tables[0]
 		}
 		return agnostic.NewConstSelector(relation, t.e.dbName), nil
+	case parser.AdvisoryLockToken:
+		relation := ""
+		if len(tables) > 0 {
+			relation = tables[0]
+		}
+		v, err := t.advisoryLock(attr, args)
+		if err != nil {
+			return nil, err
+		}
+		return agnostic.NewConstSelector(relation, v), nil
+	case parser.LastInsertIDToken:
+		// Handle MySQL LAST_INSERT_ID() function
+		relation := ""
+		if len(tables) > 0 {
+			relation = tables[0]
+		}
+		return agnostic.NewConstSelector(relation, t.session.LastInsertID()), nil
 	case parser.NumberToken:
 		// Handle literal numbers (e.g., SELECT 1)
 		relation := ""
@@ -183,6 +407,17 @@ func (t *Tx) getSelector(attr *parser.Decl, schema string, tables []string, alia
 			relation = tables[0]
 		}
 		return agnostic.NewConstSelector(relation, attr.Lexeme), nil
+	case parser.ArgToken:
+		// Handle parameters (e.g., SELECT $1)
+		v, err := argValue(attr, args)
+		if err != nil {
+			return nil, err
+		}
+		relation := ""
+		if len(tables) > 0 {
+			relation = tables[0]
+		}
+		return agnostic.NewConstSelector(relation, v), nil
 	case parser.SimpleQuoteToken:
 		// Handle literal strings (e.g., SELECT 'hello')
 		relation := ""
@@ -206,29 +441,49 @@ func (t *Tx) getSelector(attr *parser.Decl, schema string, tables []string, alia
 		return agnostic.NewConstSelector(relation, "false"), nil
 	case parser.StarToken:
 		return agnostic.NewStarSelector(tables[0]), nil
+	case parser.ArrayLengthToken:
+		return t.arrayLengthSelector(attr, schema, tables, aliases)
+	case parser.JSONBuildObjectToken, parser.JSONSetToken:
+		return t.jsonSelector(attr, schema, tables, aliases, args)
 	case parser.CountToken:
+		_, distinct := attr.Has(parser.DistinctToken)
 		for _, table := range tables {
 			if attr.Decl[0].Lexeme == "*" {
 				return agnostic.NewCountSelector(table, "*"), nil
 			}
 			_, _, err = t.tx.RelationAttribute(schema, getAlias(table, aliases), attr.Decl[0].Lexeme)
+			if err == nil && distinct {
+				return agnostic.NewCountDistinctSelector(table, attr.Decl[0].Lexeme), nil
+			}
 			if err == nil {
 				return agnostic.NewCountSelector(table, attr.Decl[0].Lexeme), nil
 			}
 		}
 		return nil, err
+	case parser.AggregateToken:
+		_, distinct := attr.Has(parser.DistinctToken)
+		for _, table := range tables {
+			_, _, err = t.tx.RelationAttribute(schema, getAlias(table, aliases), attr.Decl[0].Lexeme)
+			if err == nil {
+				return agnostic.NewAggregateSelector(table, attr.Lexeme, attr.Decl[0].Lexeme, distinct)
+			}
+		}
+		return nil, err
 	case parser.StringToken:
+		if hasJSONOperator(attr) {
+			return t.jsonSelector(attr, schema, tables, aliases, args)
+		}
 		attribute := attr.Lexeme
 		if len(attr.Decl) > 0 {
 			a := getAlias(attr.Decl[0].Lexeme, aliases)
-			_, _, err = t.tx.RelationAttribute(schema, a, attribute)
+			_, ra, err := t.tx.RelationAttribute(schema, a, attribute)
 			if err != nil {
 				return nil, err
 			}
 
 			// Always select using the resolved relation name (no alias),
 			// to keep internal resolution consistent across joins.
-			return agnostic.NewAttributeSelector(a, []string{attribute}), nil
+			return agnostic.NewAttributeSelector(a, []string{selectedName(attribute, ra)}), nil
 		}
 
 		// If no tables provided (SELECT without FROM), column doesn't exist
@@ -237,9 +492,10 @@ func (t *Tx) getSelector(attr *parser.Decl, schema string, tables []string, alia
 		}
 
 		for _, table := range tables {
-			_, _, err = t.tx.RelationAttribute(schema, getAlias(table, aliases), attribute)
+			var ra agnostic.Attribute
+			_, ra, err = t.tx.RelationAttribute(schema, getAlias(table, aliases), attribute)
 			if err == nil {
-				return agnostic.NewAttributeSelector(table, []string{attribute}), nil
+				return agnostic.NewAttributeSelector(table, []string{selectedName(attribute, ra)}), nil
 			}
 		}
 		return nil, err
@@ -248,6 +504,15 @@ func (t *Tx) getSelector(attr *parser.Decl, schema string, tables []string, alia
 	return nil, fmt.Errorf("cannot handle %s", attr.Lexeme)
 }
 
+// selectedName returns the name attribute is selected with. SQLite rowid, oid and _rowid_
+// resolve to the attribute aliasing rowid, which names the column as SQLite does.
+func selectedName(attribute string, attr agnostic.Attribute) string {
+	if strings.EqualFold(attribute, attr.Name()) {
+		return attribute
+	}
+	return attr.Name()
+}
+
 func getSelectedTables(fromDecl *parser.Decl) (string, []string, map[string]string) {
 	var tables []string
 	var schema string
@@ -305,11 +570,6 @@ func extractAliasFromTableDecl(t *parser.Decl) (table string, alias string, ok b
 }
 
 func (t *Tx) getPredicates(decl []*parser.Decl, schema, fromTableName string, args []NamedValue, aliases map[string]string) (agnostic.Predicate, error) {
-	var odbcIdx int64 = 1
-	return t.getPredicatesWithODBCIdx(decl, schema, fromTableName, args, aliases, &odbcIdx)
-}
-
-func (t *Tx) getPredicatesWithODBCIdx(decl []*parser.Decl, schema, fromTableName string, args []NamedValue, aliases map[string]string, odbcIdx *int64) (agnostic.Predicate, error) {
 
 	for i, cond := range decl {
 
@@ -334,6 +594,11 @@ func (t *Tx) getPredicatesWithODBCIdx(decl []*parser.Decl, schema, fromTableName
 	var err error
 	cond := decl[0]
 
+	// value compared with ANY or ALL array elements
+	if isArrayComparison(cond) {
+		return t.arrayComparisonExecutor(cond, schema, fromTableName, args, aliases)
+	}
+
 	// 1 PREDICATE
 	if cond.Lexeme == "1" {
 		log.Debug("Cond is %+v, returning TruePredicate", cond)
@@ -382,26 +647,48 @@ func (t *Tx) getPredicatesWithODBCIdx(decl []*parser.Decl, schema, fromTableName
 		}
 	}
 
+	// [NOT] EXISTS (SELECT ...)
+	if cond.Token == parser.ExistsToken {
+		return t.existsExecutor(cond, schema, args, aliases)
+	}
+	if cond.Token == parser.NotToken && len(cond.Decl) > 0 && cond.Decl[0].Token == parser.ExistsToken {
+		p, err := t.existsExecutor(cond.Decl[0], schema, args, aliases)
+		if err != nil {
+			return nil, err
+		}
+		return agnostic.NewNotPredicate(p), nil
+	}
+
 	switch cond.Decl[0].Token {
-	case parser.IsToken, parser.InToken, parser.EqualityToken, parser.DistinctnessToken, parser.LeftDipleToken, parser.RightDipleToken, parser.LessOrEqualToken, parser.GreaterOrEqualToken, parser.LikeToken:
+	case parser.IsToken, parser.InToken, parser.EqualityToken, parser.DistinctnessToken, parser.LeftDipleToken, parser.RightDipleToken, parser.LessOrEqualToken, parser.GreaterOrEqualToken, parser.LikeToken, parser.IlikeToken, parser.ContainsToken, parser.ContainedByToken, parser.OverlapToken,
+		parser.KeyExistsToken, parser.JSONFieldToken, parser.JSONFieldTextToken, parser.JSONPathToken, parser.JSONPathTextToken:
 		break
 	default:
 		fromTableName = cond.Decl[0].Lexeme
-		cond.Decl = cond.Decl[1:]
+		// copy declaration so parsed statements can be executed again
+		unqualified := *cond
+		unqualified.Decl = cond.Decl[1:]
+		cond = &unqualified
 	}
 
 	pLeftValue := strings.ToLower(cond.Lexeme)
 
 	fromTableName = getAlias(fromTableName, aliases)
 
-	_, _, err = t.tx.RelationAttribute(schema, fromTableName, pLeftValue)
+	_, leftAttr, err := t.tx.RelationAttribute(schema, fromTableName, pLeftValue)
 	if err != nil {
 		return nil, err
 	}
+	pLeftValue = selectedName(pLeftValue, leftAttr)
+
+	// documents and their members selected with ->, ->>, #> and #>>
+	if isJSONCondition(cond, leftAttr) {
+		return t.jsonConditionExecutor(cond, fromTableName, args, aliases)
+	}
 
 	// Handle IN keyword
 	if cond.Decl[0].Token == parser.InToken {
-		p, err := inExecutor(fromTableName, pLeftValue, cond.Decl[0], args)
+		p, err := inExecutor(t, agnostic.NewAttributeValueFunctor(fromTableName, pLeftValue), cond.Decl[0], args)
 		if err != nil {
 			return nil, err
 		}
@@ -410,7 +697,7 @@ func (t *Tx) getPredicatesWithODBCIdx(decl []*parser.Decl, schema, fromTableName
 
 	// Handle NOT IN keywords
 	if cond.Decl[0].Token == parser.NotToken && cond.Decl[0].Decl[0].Token == parser.InToken {
-		p, err := notInExecutor(fromTableName, pLeftValue, cond.Decl[0], args)
+		p, err := notInExecutor(t, agnostic.NewAttributeValueFunctor(fromTableName, pLeftValue), cond.Decl[0], args)
 		if err != nil {
 			return nil, err
 		}
@@ -419,7 +706,7 @@ func (t *Tx) getPredicatesWithODBCIdx(decl []*parser.Decl, schema, fromTableName
 
 	// Handle IS NULL and IS NOT NULL
 	if cond.Decl[0].Token == parser.IsToken {
-		p, err := isExecutor(fromTableName, pLeftValue, cond.Decl[0])
+		p, err := isExecutor(agnostic.NewAttributeValueFunctor(fromTableName, pLeftValue), cond.Decl[0])
 		if err != nil {
 			return nil, err
 		}
@@ -430,6 +717,12 @@ func (t *Tx) getPredicatesWithODBCIdx(decl []*parser.Decl, schema, fromTableName
 		return nil, fmt.Errorf("Malformed predicate \"%s\"", cond.Lexeme)
 	}
 
+	// Handle array operators @>, <@ and &&
+	switch cond.Decl[0].Token {
+	case parser.ContainsToken, parser.ContainedByToken, parser.OverlapToken:
+		return t.arrayOperatorExecutor(cond, schema, fromTableName, args, aliases)
+	}
+
 	leftS := cond
 	op := cond.Decl[0]
 	rightS := cond.Decl[1]
@@ -438,66 +731,54 @@ func (t *Tx) getPredicatesWithODBCIdx(decl []*parser.Decl, schema, fromTableName
 
 	switch leftS.Token {
 	case parser.CurrentSchemaToken:
-		// Use the builtin function functor for CURRENT_SCHEMA()
-		left = agnostic.NewCurrentSchemaFunctor(t.tx.Engine())
-	case parser.NamedArgToken:
-		for _, arg := range args {
-			if leftS.Lexeme == arg.Name {
-				left = agnostic.NewConstValueFunctor(arg.Value)
-				break
-			}
-		}
-		if left == nil {
-			return nil, fmt.Errorf("no named argument found for '%s'", leftS.Lexeme)
-		}
+		// CURRENT_SCHEMA() is the first schema of the transaction search path
+		left = agnostic.NewConstValueFunctor(t.tx.CurrentSchema())
 	case parser.ArgToken:
-		var idx int64
-		if leftS.Lexeme == "?" {
-			idx = *odbcIdx
-			*odbcIdx++
-		} else {
-			idx, err = strconv.ParseInt(leftS.Lexeme, 10, 64)
-			if err != nil {
-				return nil, err
-			}
-		}
-		if len(args) <= int(idx)-1 {
-			return nil, fmt.Errorf("reference to $%s, but only %d argument provided", leftS.Lexeme, len(args))
+		v, err := argValue(leftS, args)
+		if err != nil {
+			return nil, err
 		}
-		left = agnostic.NewConstValueFunctor(args[idx-1].Value)
+		left = agnostic.NewConstValueFunctor(v)
 	default:
 		left = agnostic.NewAttributeValueFunctor(fromTableName, pLeftValue)
 	}
 
 	switch rightS.Token {
 	case parser.CurrentSchemaToken:
-		// Use the builtin function functor for CURRENT_SCHEMA()
-		right = agnostic.NewCurrentSchemaFunctor(t.tx.Engine())
-	case parser.NamedArgToken:
-		for _, arg := range args {
-			if rightS.Lexeme == arg.Name {
-				right = agnostic.NewConstValueFunctor(arg.Value)
-				break
-			}
+		// CURRENT_SCHEMA() is the first schema of the transaction search path
+		right = agnostic.NewConstValueFunctor(t.tx.CurrentSchema())
+	case parser.ArgToken:
+		v, err := argValue(rightS, args)
+		if err != nil {
+			return nil, err
 		}
-		if right == nil {
-			return nil, fmt.Errorf("no named argument found for '%s'", rightS.Lexeme)
+		right = agnostic.NewConstValueFunctor(v)
+	case parser.SelectToken:
+		v, err := scalarSubqueryValue(t, rightS, args)
+		if err != nil {
+			return nil, err
 		}
-	case parser.ArgToken:
-		var idx int64
-		if rightS.Lexeme == "?" {
-			idx = *odbcIdx
-			*odbcIdx++
-		} else {
-			idx, err = strconv.ParseInt(rightS.Lexeme, 10, 64)
-			if err != nil {
-				return nil, err
+		right = agnostic.NewConstValueFunctor(v)
+	case parser.StringToken:
+		// qualified attribute of the same relation, other relations are compared with JOIN ON
+		if len(rightS.Decl) > 0 {
+			rightTableName := getAlias(rightS.Decl[0].Lexeme, aliases)
+			if rightTableName != fromTableName {
+				return nil, fmt.Errorf("cannot compare %s.%s with %s.%s, use JOIN ... ON", fromTableName, pLeftValue, rightTableName, rightS.Lexeme)
 			}
+			right = agnostic.NewAttributeValueFunctor(rightTableName, strings.ToLower(rightS.Lexeme))
+			break
 		}
-		if len(args) <= int(idx)-1 {
-			return nil, fmt.Errorf("reference to $%s, but only %d argument provided", rightS.Lexeme, len(args))
+		typeName := parser.TypeNameFromToken(rightS.Token)
+		// array literal, as in tags = '{a,b}'
+		if agnostic.IsArrayType(leftAttr.TypeName()) {
+			typeName = leftAttr.TypeName()
 		}
-		right = agnostic.NewConstValueFunctor(args[idx-1].Value)
+		v, err := agnostic.ToInstance(rightS.Lexeme, typeName)
+		if err != nil {
+			return nil, err
+		}
+		right = agnostic.NewConstValueFunctor(v)
 	default:
 		v, err := agnostic.ToInstance(rightS.Lexeme, parser.TypeNameFromToken(rightS.Token))
 		if err != nil {
@@ -506,27 +787,35 @@ func (t *Tx) getPredicatesWithODBCIdx(decl []*parser.Decl, schema, fromTableName
 		right = agnostic.NewConstValueFunctor(v)
 	}
 
-	var ptype agnostic.PredicateType
+	ptype, err := comparisonType(op)
+	if err != nil {
+		return nil, err
+	}
+
+	return agnostic.NewComparisonPredicate(left, ptype, right)
+}
+
+// comparisonType returns the PredicateType of comparison operator op
+func comparisonType(op *parser.Decl) (agnostic.PredicateType, error) {
 	switch op.Token {
 	case parser.EqualityToken:
-		ptype = agnostic.Eq
+		return agnostic.Eq, nil
 	case parser.LessOrEqualToken:
-		ptype = agnostic.Leq
+		return agnostic.Leq, nil
 	case parser.GreaterOrEqualToken:
-		ptype = agnostic.Geq
+		return agnostic.Geq, nil
 	case parser.DistinctnessToken:
-		ptype = agnostic.Neq
+		return agnostic.Neq, nil
 	case parser.LeftDipleToken:
-		ptype = agnostic.Le
+		return agnostic.Le, nil
 	case parser.RightDipleToken:
-		ptype = agnostic.Ge
+		return agnostic.Ge, nil
 	case parser.LikeToken:
-		ptype = agnostic.Like
-	default:
-		return nil, fmt.Errorf("unknown comparison token %s", op.Lexeme)
+		return agnostic.Like, nil
+	case parser.IlikeToken:
+		return agnostic.ILike, nil
 	}
-
-	return agnostic.NewComparisonPredicate(left, ptype, right)
+	return 0, fmt.Errorf("unknown comparison token %s", op.Lexeme)
 }
 
 func (t *Tx) and(left []*parser.Decl, right []*parser.Decl, schema, tableName string, args []NamedValue, aliases map[string]string) (agnostic.Predicate, error) {
@@ -632,8 +921,8 @@ func (t *Tx) getDistinctSorter(rel string, decl *parser.Decl, nextAttr string) (
 	return agnostic.NewDistinctSorter(rel, dattrs), nil
 }
 
-func notInExecutor(rname string, aname string, inDecl *parser.Decl, args []NamedValue) (agnostic.Predicate, error) {
-	in, err := inExecutor(rname, aname, inDecl.Decl[0], args)
+func notInExecutor(t *Tx, v agnostic.ValueFunctor, inDecl *parser.Decl, args []NamedValue) (agnostic.Predicate, error) {
+	in, err := inExecutor(t, v, inDecl.Decl[0], args)
 	if err != nil {
 		return nil, err
 	}
@@ -641,37 +930,35 @@ func notInExecutor(rname string, aname string, inDecl *parser.Decl, args []Named
 	return agnostic.NewNotPredicate(in), nil
 }
 
-func inExecutor(rname string, aname string, inDecl *parser.Decl, args []NamedValue) (agnostic.Predicate, error) {
+func inExecutor(t *Tx, v agnostic.ValueFunctor, inDecl *parser.Decl, args []NamedValue) (agnostic.Predicate, error) {
 
 	if len(inDecl.Decl) == 0 {
 		return nil, ParsingError
 	}
 
-	v := agnostic.NewAttributeValueFunctor(rname, aname)
-
 	var n agnostic.Node
 	switch inDecl.Decl[0].Token {
 	case parser.SelectToken:
-		return nil, fmt.Errorf("IN subquery not implemented")
+		values, err := subqueryValues(t, inDecl.Decl[0], args, "")
+		if err != nil {
+			return nil, err
+		}
+		n = agnostic.NewListNode(values...)
 	default:
 		var values []any
 		for _, d := range inDecl.Decl {
-			// Handle bound parameters (PostgreSQL-style: $1, $2, etc.)
+			// Handle bound parameters
 			if d.Token == parser.ArgToken {
-				idx, err := strconv.ParseInt(d.Lexeme, 10, 64)
+				v, err := argValue(d, args)
 				if err != nil {
-					return nil, fmt.Errorf("cannot parse arg index %s: %w", d.Lexeme, err)
-				}
-				if int(idx) > len(args) || idx < 1 {
-					return nil, fmt.Errorf("arg index %d out of range (have %d args)", idx, len(args))
+					return nil, err
 				}
-				argValue := args[idx-1].Value
-				
+
 				// Check if the argument is an array/slice and expand it
-				if expandValues, ok := expandArrayValue(argValue); ok {
+				if expandValues, ok := expandArrayValue(v); ok {
 					values = append(values, expandValues...)
 				} else {
-					values = append(values, argValue)
+					values = append(values, v)
 				}
 			} else {
 				values = append(values, d.Lexeme)
@@ -684,6 +971,212 @@ func inExecutor(rname string, aname string, inDecl *parser.Decl, args []NamedVal
 	return p, nil
 }
 
+// scalarSubqueryValue executes a subquery used as an expression, NULL without row
+func scalarSubqueryValue(t *Tx, selectDecl *parser.Decl, args []NamedValue) (any, error) {
+	values, err := subqueryValues(t, selectDecl, args, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(values) > 1 {
+		return nil, fmt.Errorf("more than one row returned by a subquery used as an expression")
+	}
+	if len(values) == 1 {
+		return values[0], nil
+	}
+	return nil, nil
+}
+
+// subqueryValues executes an uncorrelated subquery once and returns the non NULL values of
+// its column, or of column name if specified.
+func subqueryValues(t *Tx, selectDecl *parser.Decl, args []NamedValue, column string) ([]any, error) {
+	_, _, cols, res, err := selectExecutor(t, selectDecl, args)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := -1
+	for i, c := range cols {
+		if column == "" || c.Name == column || strings.HasSuffix(c.Name, "."+column) {
+			idx = i
+			break
+		}
+	}
+	switch {
+	case column != "" && idx == -1:
+		return nil, fmt.Errorf("column %s does not exist in subquery", column)
+	case column == "" && len(cols) != 1:
+		return nil, fmt.Errorf("subquery has %d columns, expected 1", len(cols))
+	}
+
+	values := make([]any, 0, len(res))
+	for _, tuple := range res {
+		if v := tuple.Values()[idx]; v != nil {
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
+
+// existsExecutor builds a predicate for EXISTS (SELECT ... WHERE outer.a = inner.b AND ...).
+//
+// A correlated subquery must reference the outer query with an equality ANDed to other
+// conditions. It is then evaluated once, as outer.a IN (SELECT inner.b FROM ... WHERE ...).
+func (t *Tx) existsExecutor(existsDecl *parser.Decl, schema string, args []NamedValue, aliases map[string]string) (agnostic.Predicate, error) {
+	if len(existsDecl.Decl) == 0 || existsDecl.Decl[0].Token != parser.SelectToken {
+		return nil, ParsingError
+	}
+	selectDecl := existsDecl.Decl[0]
+
+	// relations of the subquery, any other is a reference to the outer query
+	inner := make(map[string]bool)
+	var whereDecl *parser.Decl
+	for _, d := range selectDecl.Decl {
+		switch d.Token {
+		case parser.FromToken:
+			_, tables, as := getSelectedTables(d)
+			for _, tbl := range tables {
+				inner[tbl] = true
+			}
+			for a := range as {
+				inner[a] = true
+			}
+		case parser.JoinToken:
+			inner[d.Decl[0].Lexeme] = true
+			if _, a, ok := extractAliasFromTableDecl(d.Decl[0]); ok {
+				inner[a] = true
+			}
+		case parser.WhereToken:
+			whereDecl = d
+		}
+	}
+
+	var outerRel, outerAttr string
+	var innerAttr *parser.Decl
+	conds := []*parser.Decl{{Token: parser.NumberToken, Lexeme: "1"}}
+	if whereDecl != nil {
+		conds = whereDecl.Decl
+	}
+	for i, cond := range conds {
+		if cond.Token == parser.OrToken {
+			break
+		}
+		if cond.Token != parser.StringToken || len(cond.Decl) != 3 || cond.Decl[1].Token != parser.EqualityToken || len(cond.Decl[2].Decl) == 0 {
+			continue
+		}
+		left, right := cond.Decl[0].Lexeme, cond.Decl[2].Decl[0].Lexeme
+		switch {
+		case inner[left] && !inner[right]:
+			outerRel, outerAttr = right, cond.Decl[2].Lexeme
+			innerAttr = &parser.Decl{Token: parser.StringToken, Lexeme: cond.Lexeme, Decl: []*parser.Decl{cond.Decl[0]}}
+		case !inner[left] && inner[right]:
+			outerRel, outerAttr = left, cond.Lexeme
+			innerAttr = cond.Decl[2]
+		default:
+			continue
+		}
+
+		// remove correlation and its AND link from conditions
+		remaining := make([]*parser.Decl, 0, len(conds))
+		for j, c := range conds {
+			if j == i || (i > 0 && j == i-1) || (i == 0 && j == 1) {
+				continue
+			}
+			remaining = append(remaining, c)
+		}
+		if len(remaining) == 0 {
+			remaining = append(remaining, &parser.Decl{Token: parser.NumberToken, Lexeme: "1"})
+		}
+		conds = remaining
+		break
+	}
+
+	// subquery selecting correlated attribute, or all attributes
+	subDecl := &parser.Decl{Token: parser.SelectToken, Lexeme: selectDecl.Lexeme}
+	if innerAttr != nil {
+		subDecl.Add(innerAttr)
+	}
+	from := false
+	for _, d := range selectDecl.Decl {
+		switch {
+		case d.Token == parser.FromToken:
+			from = true
+		case !from:
+			if innerAttr == nil {
+				subDecl.Add(d)
+			}
+			continue
+		case d.Token == parser.WhereToken:
+			d = &parser.Decl{Token: parser.WhereToken, Lexeme: d.Lexeme, Decl: conds}
+		}
+		subDecl.Add(d)
+	}
+
+	// uncorrelated subquery is either true or false for all rows
+	if innerAttr == nil {
+		_, _, _, res, err := selectExecutor(t, subDecl, args)
+		if err != nil {
+			return nil, err
+		}
+		if len(res) > 0 {
+			return agnostic.NewTruePredicate(), nil
+		}
+		return agnostic.NewFalsePredicate(), nil
+	}
+
+	values, err := subqueryValues(t, subDecl, args, "")
+	if err != nil {
+		return nil, err
+	}
+	v := agnostic.NewAttributeValueFunctor(getAlias(outerRel, aliases), strings.ToLower(outerAttr))
+	return agnostic.NewInPredicate(v, agnostic.NewListNode(values...)), nil
+}
+
+// derivedJoinExecutor builds a predicate for JOIN (SELECT ...) AS alias ON attr = alias.column.
+//
+// Columns of the derived table cannot be selected, the join only keeps rows matching
+// one of its rows. Column values must be unique so no row is duplicated.
+func (t *Tx) derivedJoinExecutor(joinDecl *parser.Decl, fromTableName string, aliases map[string]string, args []NamedValue) (agnostic.Predicate, error) {
+	subDecl := joinDecl.Decl[0]
+	last := subDecl.Decl[len(subDecl.Decl)-1]
+	if last.Token != parser.StringToken {
+		return nil, fmt.Errorf("subquery in JOIN must have an alias")
+	}
+	alias := last.Lexeme
+	// alias is not part of the subquery
+	subDecl = &parser.Decl{Token: subDecl.Token, Lexeme: subDecl.Lexeme, Decl: subDecl.Decl[:len(subDecl.Decl)-1]}
+
+	if len(joinDecl.Decl) < 2 || joinDecl.Decl[1].Token != parser.OnToken || len(joinDecl.Decl[1].Decl) != 3 {
+		return nil, fmt.Errorf("expected JOIN ON to have pivot")
+	}
+	on := joinDecl.Decl[1]
+	left, right := on.Decl[0], on.Decl[2]
+	if len(left.Decl) > 0 && left.Decl[0].Lexeme == alias {
+		left, right = right, left
+	}
+	if len(right.Decl) == 0 || right.Decl[0].Lexeme != alias {
+		return nil, fmt.Errorf("expected JOIN ON to reference %s", alias)
+	}
+	if len(left.Decl) > 0 {
+		fromTableName = left.Decl[0].Lexeme
+	}
+
+	values, err := subqueryValues(t, subDecl, args, right.Lexeme)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]struct{})
+	for _, v := range values {
+		k := fmt.Sprintf("%v", v)
+		if _, ok := seen[k]; ok {
+			return nil, fmt.Errorf("cannot join %s, %s.%s value %v is not unique", alias, alias, right.Lexeme, v)
+		}
+		seen[k] = struct{}{}
+	}
+
+	v := agnostic.NewAttributeValueFunctor(getAlias(fromTableName, aliases), strings.ToLower(left.Lexeme))
+	return agnostic.NewInPredicate(v, agnostic.NewListNode(values...)), nil
+}
+
 // expandArrayValue checks if a value is an array or slice and expands it.
 // Returns the expanded values and true if expansion occurred, or nil and false otherwise.
 func expandArrayValue(value any) ([]any, bool) {
@@ -754,15 +1247,12 @@ func tupleInExecutor(fromTableName string, attrs []*parser.Decl, inDecl *parser.
 			for _, valDecl := range tupleDecl.Decl {
 				switch valDecl.Token {
 				case parser.ArgToken:
-					// Handle prepared statement placeholder ($1, $2, etc.)
-					idx, err := strconv.ParseInt(valDecl.Lexeme, 10, 64)
+					// Handle prepared statement placeholder
+					v, err := argValue(valDecl, args)
 					if err != nil {
-						return nil, fmt.Errorf("cannot parse arg index %s: %w", valDecl.Lexeme, err)
-					}
-					if int(idx) > len(args) || idx < 1 {
-						return nil, fmt.Errorf("arg index %d out of range (have %d args)", idx, len(args))
+						return nil, err
 					}
-					values = append(values, args[idx-1].Value)
+					values = append(values, v)
 				default:
 					values = append(values, valDecl.Lexeme)
 				}
@@ -778,15 +1268,15 @@ func tupleInExecutor(fromTableName string, attrs []*parser.Decl, inDecl *parser.
 	return p, nil
 }
 
-func isExecutor(rname string, aname string, isDecl *parser.Decl) (agnostic.Predicate, error) {
+func isExecutor(v agnostic.ValueFunctor, isDecl *parser.Decl) (agnostic.Predicate, error) {
 
 	if isDecl.Decl[0].Token == parser.NullToken {
-		p := agnostic.NewEqPredicate(agnostic.NewAttributeValueFunctor(rname, aname), agnostic.NewConstValueFunctor(nil))
+		p := agnostic.NewEqPredicate(v, agnostic.NewConstValueFunctor(nil))
 		return p, nil
 	}
 
 	if isDecl.Decl[0].Token == parser.NotToken && isDecl.Decl[1].Token == parser.NullToken {
-		p := agnostic.NewEqPredicate(agnostic.NewAttributeValueFunctor(rname, aname), agnostic.NewConstValueFunctor(nil))
+		p := agnostic.NewEqPredicate(v, agnostic.NewConstValueFunctor(nil))
 		return agnostic.NewNotPredicate(p), nil
 	}
 
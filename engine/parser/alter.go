@@ -0,0 +1,372 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseAlter parses an ALTER TABLE or ALTER INDEX statement
+//
+//	ALTER TABLE [ IF EXISTS ] [ ONLY ] name action [, ...]
+//	ALTER TABLE [ IF EXISTS ] [ ONLY ] name RENAME [ COLUMN ] column TO new_column
+//	ALTER TABLE [ IF EXISTS ] [ ONLY ] name RENAME TO new_name
+//	ALTER INDEX [ IF EXISTS ] name RENAME TO new_name
+//
+// where action is one of
+//
+//	ADD [ COLUMN ] [ IF NOT EXISTS ] column type [ constraint [...] ]
+//	ADD [ CONSTRAINT name ] { FOREIGN KEY (...) REFERENCES ... | UNIQUE (...) }
+//	DROP [ COLUMN ] [ IF EXISTS ] column [ CASCADE | RESTRICT ]
+//	DROP CONSTRAINT [ IF EXISTS ] name [ CASCADE | RESTRICT ]
+//	ALTER [ COLUMN ] column [ SET DATA ] TYPE type [ USING expression ]
+//	ALTER [ COLUMN ] column { SET | DROP } NOT NULL
+//	ALTER [ COLUMN ] column SET DEFAULT value
+//	ALTER [ COLUMN ] column DROP DEFAULT
+//
+// |-> "ALTER" (AlterToken)
+//
+//	|-> TABLE (TableToken) or INDEX (IndexToken)
+//	    |-> IF (IfToken), optional
+//	        |-> EXISTS (ExistsToken)
+//	    |-> name (StringToken)
+//	|-> action (AddToken, DropToken, RenameToken or AlterToken)
+//	    |-> ...
+func (p *parser) parseAlter() (*Instruction, error) {
+	// actions are parsed on the statement tokens only, terminated by a semicolon,
+	// so clauses can always look at the following token
+	start := p.index
+	end := start
+	for end < p.tokenLen && p.tokens[end].Token != SemicolonToken {
+		end++
+	}
+	tokens := append([]Token{}, p.tokens[start:end]...)
+	tokens = append(tokens, Token{Token: SemicolonToken, Lexeme: ";"})
+	sub := &parser{tokens: tokens, tokenLen: len(tokens), dialect: p.dialect}
+
+	i, err := sub.parseAlterStatement()
+	if err != nil {
+		return nil, err
+	}
+	if sub.index != len(tokens)-1 {
+		return nil, fmt.Errorf("Syntax error near %s", sub.cur().Lexeme)
+	}
+	p.index = end
+
+	return i, nil
+}
+
+func (p *parser) parseAlterStatement() (*Instruction, error) {
+	i := &Instruction{}
+
+	alterDecl := &Decl{Token: AlterToken, Lexeme: "alter"}
+	i.Decls = append(i.Decls, alterDecl)
+	p.index++
+
+	objectDecl, err := p.consumeToken(TableToken, IndexToken)
+	if err != nil {
+		return nil, fmt.Errorf("ALTER must be followed by TABLE or INDEX")
+	}
+	alterDecl.Add(objectDecl)
+
+	if err := p.parseAlterIfExists(objectDecl); err != nil {
+		return nil, err
+	}
+	if objectDecl.Token == TableToken && p.isWord("only") {
+		p.index++
+	}
+
+	nameDecl, err := p.parseTableName()
+	if err != nil {
+		return nil, err
+	}
+	objectDecl.Add(nameDecl)
+
+	if objectDecl.Token == IndexToken {
+		if !p.isWord("rename") {
+			return nil, fmt.Errorf("Syntax error near %s, expected RENAME", p.cur().Lexeme)
+		}
+		d, err := p.parseAlterRename()
+		if err != nil {
+			return nil, err
+		}
+		alterDecl.Add(d)
+		return i, nil
+	}
+
+	for {
+		var d *Decl
+		switch {
+		case p.isWord("add"):
+			d, err = p.parseAlterAdd()
+		case p.is(DropToken):
+			d, err = p.parseAlterDrop()
+		case p.isWord("rename"):
+			d, err = p.parseAlterRename()
+		case p.isWord("alter"):
+			d, err = p.parseAlterColumn()
+		default:
+			return nil, fmt.Errorf("Syntax error near %s, expected ADD, DROP, RENAME or ALTER", p.cur().Lexeme)
+		}
+		if err != nil {
+			return nil, err
+		}
+		alterDecl.Add(d)
+
+		if !p.is(CommaToken) {
+			break
+		}
+		p.index++
+	}
+
+	return i, nil
+}
+
+// parseAlterIfExists parses an optional IF EXISTS clause
+func (p *parser) parseAlterIfExists(decl *Decl) error {
+	if !p.is(IfToken) {
+		return nil
+	}
+	ifDecl, err := p.consumeToken(IfToken)
+	if err != nil {
+		return err
+	}
+	existsDecl, err := p.consumeToken(ExistsToken)
+	if err != nil {
+		return err
+	}
+	ifDecl.Add(existsDecl)
+	decl.Add(ifDecl)
+	return nil
+}
+
+// parseAlterAdd parses an ADD action
+//
+// |-> "ADD" (AddToken)
+//
+//	|-> IF (IfToken), optional
+//	    |-> NOT (NotToken)
+//	        |-> EXISTS (ExistsToken)
+//	|-> column definition (StringToken), CONSTRAINT (ConstraintToken),
+//	    FOREIGN (ForeignToken) or UNIQUE (UniqueToken)
+func (p *parser) parseAlterAdd() (*Decl, error) {
+	addDecl := &Decl{Token: AddToken, Lexeme: "add"}
+	p.index++
+
+	var d *Decl
+	var err error
+	switch {
+	case p.is(ConstraintToken):
+		d, err = p.parseTableConstraint()
+	case p.is(ForeignToken):
+		d, err = p.parseTableForeignKey()
+	case p.is(UniqueToken):
+		d, err = p.parseUniqueConstraint()
+	default:
+		if p.isWord("column") {
+			p.index++
+		}
+		if p.is(IfToken) {
+			ifDecl, err := p.consumeToken(IfToken)
+			if err != nil {
+				return nil, err
+			}
+			notDecl, err := p.consumeToken(NotToken)
+			if err != nil {
+				return nil, err
+			}
+			existsDecl, err := p.consumeToken(ExistsToken)
+			if err != nil {
+				return nil, err
+			}
+			ifDecl.Add(notDecl)
+			notDecl.Add(existsDecl)
+			addDecl.Add(ifDecl)
+		}
+		d, err = p.parseColumnDefinition()
+	}
+	if err != nil {
+		return nil, err
+	}
+	addDecl.Add(d)
+
+	return addDecl, nil
+}
+
+// parseAlterDrop parses a DROP action
+//
+// |-> "DROP" (DropToken)
+//
+//	|-> COLUMN (ColumnToken) or CONSTRAINT (ConstraintToken)
+//	    |-> IF (IfToken), optional
+//	        |-> EXISTS (ExistsToken)
+//	    |-> name (StringToken)
+func (p *parser) parseAlterDrop() (*Decl, error) {
+	dropDecl, err := p.consumeToken(DropToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var d *Decl
+	switch {
+	case p.is(ConstraintToken):
+		d, err = p.consumeToken(ConstraintToken)
+		if err != nil {
+			return nil, err
+		}
+	case p.isWord("column"):
+		d = &Decl{Token: ColumnToken, Lexeme: "column"}
+		p.index++
+	default:
+		d = &Decl{Token: ColumnToken, Lexeme: "column"}
+	}
+	dropDecl.Add(d)
+
+	if err := p.parseAlterIfExists(d); err != nil {
+		return nil, err
+	}
+
+	nameDecl, err := p.parseQuotedToken()
+	if err != nil {
+		return nil, err
+	}
+	d.Add(nameDecl)
+
+	if p.is(CascadeToken, RestrictToken) {
+		p.index++
+	}
+
+	return dropDecl, nil
+}
+
+// parseAlterRename parses a RENAME action
+//
+// |-> "RENAME" (RenameToken)
+//
+//	|-> COLUMN (ColumnToken), for RENAME [ COLUMN ] column TO new_column
+//	    |-> column (StringToken)
+//	|-> new name (StringToken)
+func (p *parser) parseAlterRename() (*Decl, error) {
+	renameDecl := &Decl{Token: RenameToken, Lexeme: "rename"}
+	p.index++
+
+	if !p.isWord("to") {
+		if p.isWord("column") {
+			p.index++
+		}
+		nameDecl, err := p.parseQuotedToken()
+		if err != nil {
+			return nil, err
+		}
+		columnDecl := &Decl{Token: ColumnToken, Lexeme: "column"}
+		columnDecl.Add(nameDecl)
+		renameDecl.Add(columnDecl)
+	}
+
+	if !p.isWord("to") {
+		return nil, fmt.Errorf("Syntax error near %s, expected TO", p.cur().Lexeme)
+	}
+	p.index++
+
+	nameDecl, err := p.parseQuotedToken()
+	if err != nil {
+		return nil, err
+	}
+	renameDecl.Add(nameDecl)
+
+	return renameDecl, nil
+}
+
+// parseAlterColumn parses an ALTER COLUMN action
+//
+// |-> "ALTER" (AlterToken)
+//
+//	|-> column (StringToken)
+//	    |-> TYPE (TypeToken)
+//	        |-> type
+//	    or SET (SetToken) or DROP (DropToken)
+//	        |-> NOT (NotToken)
+//	            |-> NULL (NullToken)
+//	        or DEFAULT (DefaultToken)
+//	            |-> value, for SET DEFAULT
+func (p *parser) parseAlterColumn() (*Decl, error) {
+	alterDecl := &Decl{Token: AlterToken, Lexeme: "alter"}
+	p.index++
+
+	if p.isWord("column") {
+		p.index++
+	}
+	columnDecl, err := p.parseQuotedToken()
+	if err != nil {
+		return nil, err
+	}
+	alterDecl.Add(columnDecl)
+
+	// SET DATA TYPE is TYPE
+	if p.is(SetToken) && p.tokens[p.index+1].Token == StringToken && strings.EqualFold(p.tokens[p.index+1].Lexeme, "data") {
+		p.index += 2
+	}
+
+	switch {
+	case p.isWord("type"):
+		typeDecl := &Decl{Token: TypeToken, Lexeme: "type"}
+		p.index++
+		t, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		typeDecl.Add(t)
+		columnDecl.Add(typeDecl)
+
+		// values are converted to the new type, the expression is not evaluated
+		if p.isWord("using") {
+			depth := 0
+			for p.isNot(SemicolonToken) && (depth > 0 || p.isNot(CommaToken)) {
+				switch p.cur().Token {
+				case BracketOpeningToken:
+					depth++
+				case BracketClosingToken:
+					depth--
+				}
+				p.index++
+			}
+		}
+	case p.is(SetToken, DropToken):
+		actionDecl, err := p.consumeToken(SetToken, DropToken)
+		if err != nil {
+			return nil, err
+		}
+		columnDecl.Add(actionDecl)
+
+		switch {
+		case p.is(NotToken):
+			notDecl, err := p.consumeToken(NotToken)
+			if err != nil {
+				return nil, err
+			}
+			nullDecl, err := p.consumeToken(NullToken)
+			if err != nil {
+				return nil, err
+			}
+			notDecl.Add(nullDecl)
+			actionDecl.Add(notDecl)
+		case p.is(DefaultToken) && actionDecl.Token == SetToken:
+			dDecl, err := p.parseDefaultClause()
+			if err != nil {
+				return nil, err
+			}
+			actionDecl.Add(dDecl)
+		case p.is(DefaultToken):
+			dDecl, err := p.consumeToken(DefaultToken)
+			if err != nil {
+				return nil, err
+			}
+			actionDecl.Add(dDecl)
+		default:
+			return nil, fmt.Errorf("Syntax error near %s, expected NOT NULL or DEFAULT", p.cur().Lexeme)
+		}
+	default:
+		return nil, fmt.Errorf("Syntax error near %s, expected TYPE, SET or DROP", p.cur().Lexeme)
+	}
+
+	return alterDecl, nil
+}
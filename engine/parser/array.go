@@ -0,0 +1,268 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// isArrayConstructor reports whether current token starts an ARRAY[...] constructor
+func (p *parser) isArrayConstructor() bool {
+	if !p.isWord("array") {
+		return false
+	}
+	_, err := p.isNext(SquareBracketOpeningToken)
+	return err == nil
+}
+
+// parseArrayConstructor parses ARRAY[value, ...]
+//
+//	ArrayToken
+//	    |-> value
+//	    |-> value
+func (p *parser) parseArrayConstructor() (*Decl, error) {
+	arrayDecl := &Decl{Token: ArrayToken, Lexeme: "array"}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if _, err := p.consumeToken(SquareBracketOpeningToken); err != nil {
+		return nil, err
+	}
+
+	for !p.is(SquareBracketClosingToken) {
+		valueDecl, err := p.parseListElement()
+		if err != nil {
+			return nil, err
+		}
+		arrayDecl.Add(valueDecl)
+		if !p.is(CommaToken) {
+			break
+		}
+		if _, err := p.consumeToken(CommaToken); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.consumeToken(SquareBracketClosingToken); err != nil {
+		return nil, err
+	}
+	return arrayDecl, nil
+}
+
+// isArrayComparison reports whether current token starts ANY(...) or ALL(...)
+func (p *parser) isArrayComparison() bool {
+	if !p.isWord("any") && !p.isWord("all") {
+		return false
+	}
+	_, err := p.isNext(BracketOpeningToken)
+	return err == nil
+}
+
+// parseArrayComparison parses the right operand of a comparison with ANY or ALL array elements
+//
+//	AnyToken or AllToken
+//	    |-> SelectToken, ArrayToken, parameter, '{...}' literal or attribute
+func (p *parser) parseArrayComparison() (*Decl, error) {
+	d := &Decl{Token: AnyToken, Lexeme: "any"}
+	if p.isWord("all") {
+		d = &Decl{Token: AllToken, Lexeme: "all"}
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	if p.isSubquery() {
+		subDecl, err := p.parseSubquery()
+		if err != nil {
+			return nil, err
+		}
+		d.Add(subDecl)
+		return d, nil
+	}
+
+	if _, err := p.consumeToken(BracketOpeningToken); err != nil {
+		return nil, err
+	}
+	operandDecl, err := p.parseArrayOperand()
+	if err != nil {
+		return nil, err
+	}
+	d.Add(operandDecl)
+	if _, err := p.consumeToken(BracketClosingToken); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// parseArrayOperand parses an array value, either ARRAY[...], a parameter,
+// a '{...}' literal or an attribute
+func (p *parser) parseArrayOperand() (*Decl, error) {
+	switch {
+	case p.isArrayConstructor():
+		return p.parseArrayConstructor()
+	case p.is(SimpleQuoteToken, ArgToken, NamedArgToken):
+		return p.parseValue()
+	}
+	return p.parseAttribute()
+}
+
+// isValueArrayComparison reports whether current tokens start a value compared
+// with array elements, as in 'go' = ANY(tags)
+func (p *parser) isValueArrayComparison() bool {
+	op := p.index + 1
+	switch {
+	case p.is(SimpleQuoteToken):
+		op = p.index + 3
+	case p.is(ArgToken, NamedArgToken, NumberToken, FloatToken, TrueToken, FalseToken):
+	default:
+		return false
+	}
+	if op+1 >= p.tokenLen {
+		return false
+	}
+
+	switch p.tokens[op].Token {
+	case EqualityToken, DistinctnessToken, LeftDipleToken, RightDipleToken, LessOrEqualToken, GreaterOrEqualToken:
+	default:
+		return false
+	}
+	t := p.tokens[op+1]
+	return t.Token == StringToken && (strings.EqualFold(t.Lexeme, "any") || strings.EqualFold(t.Lexeme, "all"))
+}
+
+// parseValueArrayComparison parses a value compared with array elements
+//
+//	value ('literal' as SimpleQuoteToken, parameter, number or boolean)
+//	    |-> comparison operator
+//	    |-> AnyToken or AllToken
+func (p *parser) parseValueArrayComparison() (*Decl, error) {
+	var valueDecl *Decl
+	var err error
+	if p.is(SimpleQuoteToken) {
+		valueDecl, err = p.parseStringLiteral()
+		if err == nil {
+			valueDecl.Token = SimpleQuoteToken
+		}
+	} else {
+		valueDecl, err = p.consumeToken(ArgToken, NamedArgToken, NumberToken, FloatToken, TrueToken, FalseToken)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	opDecl, err := p.consumeToken(p.cur().Token)
+	if err != nil {
+		return nil, err
+	}
+	valueDecl.Add(opDecl)
+
+	arrayDecl, err := p.parseArrayComparison()
+	if err != nil {
+		return nil, err
+	}
+	valueDecl.Add(arrayDecl)
+	return valueDecl, nil
+}
+
+// parseArrayLength parses array_length(attribute, 1)
+//
+//	ArrayLengthToken
+//	    |-> attribute
+//	    |-> dimension (NumberToken)
+func (p *parser) parseArrayLength() (*Decl, error) {
+	d := &Decl{Token: ArrayLengthToken, Lexeme: "array_length"}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if _, err := p.consumeToken(BracketOpeningToken); err != nil {
+		return nil, err
+	}
+	attrDecl, err := p.parseAttribute()
+	if err != nil {
+		return nil, err
+	}
+	d.Add(attrDecl)
+	if _, err := p.consumeToken(CommaToken); err != nil {
+		return nil, err
+	}
+	dimDecl, err := p.consumeToken(NumberToken)
+	if err != nil {
+		return nil, err
+	}
+	if dimDecl.Lexeme != "1" {
+		return nil, fmt.Errorf("multidimensional arrays are not supported")
+	}
+	d.Add(dimDecl)
+	if _, err := p.consumeToken(BracketClosingToken); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// isUnnest reports whether current token starts an unnest(...) set-returning function
+func (p *parser) isUnnest() bool {
+	if !p.isWord("unnest") {
+		return false
+	}
+	_, err := p.isNext(BracketOpeningToken)
+	return err == nil
+}
+
+// parseUnnest parses unnest(array) [[AS] alias [(column)]] in a FROM clause
+//
+//	UnnestToken
+//	    |-> ArrayToken, parameter, '{...}' literal or attribute
+//	    |-> alias (StringToken), optional
+//	        |-> column (StringToken), optional
+func (p *parser) parseUnnest() (*Decl, error) {
+	d := &Decl{Token: UnnestToken, Lexeme: "unnest"}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if _, err := p.consumeToken(BracketOpeningToken); err != nil {
+		return nil, err
+	}
+	operandDecl, err := p.parseArrayOperand()
+	if err != nil {
+		return nil, err
+	}
+	d.Add(operandDecl)
+	if _, err := p.consumeToken(BracketClosingToken); err != nil {
+		return nil, err
+	}
+	return p.parseFunctionAlias(d)
+}
+
+// parseFunctionAlias adds the optional [AS] alias [(column)] of set-returning function d in a FROM clause
+func (p *parser) parseFunctionAlias(d *Decl) (*Decl, error) {
+	if p.is(AsToken) {
+		if _, err := p.consumeToken(AsToken); err != nil {
+			return nil, err
+		}
+		if !p.is(StringToken) {
+			return nil, fmt.Errorf("Expected alias name after AS")
+		}
+	}
+	if !p.is(StringToken) {
+		return d, nil
+	}
+	aliasDecl, err := p.consumeToken(StringToken)
+	if err != nil {
+		return nil, err
+	}
+	d.Add(aliasDecl)
+
+	if p.is(BracketOpeningToken) {
+		if _, err := p.consumeToken(BracketOpeningToken); err != nil {
+			return nil, err
+		}
+		columnDecl, err := p.consumeToken(StringToken)
+		if err != nil {
+			return nil, err
+		}
+		aliasDecl.Add(columnDecl)
+		if _, err := p.consumeToken(BracketClosingToken); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
@@ -235,26 +235,38 @@ func (p *parser) parseTable(tokens []Token) (*Decl, error) {
 			}
 			tableDecl.Add(fkDecl)
 			continue
-		case ConstraintToken:
-			// CONSTRAINT name FOREIGN KEY ... REFERENCES ...
-			cDecl, err := p.consumeToken(ConstraintToken)
+		case UniqueToken:
+			if p.dialect == DialectMySQL {
+				d, err := p.parseIndexDefinition(nameTable)
+				if err != nil {
+					return nil, err
+				}
+				tableDecl.Add(d)
+				continue
+			}
+			uDecl, err := p.parseUniqueConstraint()
 			if err != nil {
 				return nil, err
 			}
-			nameDecl, err := p.parseQuotedToken()
+			tableDecl.Add(uDecl)
+			continue
+		case KeyToken, IndexToken:
+			if p.dialect != DialectMySQL {
+				break
+			}
+			d, err := p.parseIndexDefinition(nameTable)
 			if err != nil {
 				return nil, err
 			}
-			cDecl.Add(nameDecl)
-			// require a FOREIGN KEY clause next
-			if !p.is(ForeignToken) {
-				return nil, p.syntaxError()
-			}
-			fkDecl, err := p.parseTableForeignKey()
+			tableDecl.Add(d)
+			continue
+		case ConstraintToken:
+			// CONSTRAINT name FOREIGN KEY ... REFERENCES ...
+			// CONSTRAINT name UNIQUE (...)
+			cDecl, err := p.parseTableConstraint()
 			if err != nil {
 				return nil, err
 			}
-			cDecl.Add(fkDecl)
 			tableDecl.Add(cDecl)
 			continue
 		case CommaToken:
@@ -264,146 +276,244 @@ func (p *parser) parseTable(tokens []Token) (*Decl, error) {
 		default:
 		}
 
-		// Closing bracket ?
+		// Closing bracket ? consumed even if last token, so table options start after it
 		if tokens[p.index].Token == BracketClosingToken {
-			_, err = p.consumeToken(BracketClosingToken)
-			if err != nil {
-				return nil, err
-			}
+			p.index++
 			break
 		}
 
-		// New attribute name
-		newAttribute, err := p.parseQuotedToken()
+		newAttribute, err := p.parseColumnDefinition()
 		if err != nil {
 			return nil, err
 		}
 		tableDecl.Add(newAttribute)
 
-		newAttributeType, err := p.parseType()
+		if p.is(SemicolonToken) {
+			return nil, p.syntaxError()
+		}
+
+		// The current token is either closing bracked or comma.
+
+		// Closing bracket means table parsing stops.
+		if tokens[p.index].Token == BracketClosingToken {
+			p.index++
+			break
+		}
+
+		// Comma means continue on next table column.
+		p.index++
+	}
+
+	if p.dialect == DialectMySQL {
+		if err := p.parseTableOptions(tableDecl); err != nil {
+			return nil, err
+		}
+	}
+
+	return tableDecl, nil
+}
+
+// parseColumnDefinition parses a column name, its type and its column constraints,
+// up to the closing bracket or comma of a table definition
+//
+//	name type [ constraint [...] ]
+//
+// The type is optional in SQLite dialect, a column without type is given an empty type.
+func (p *parser) parseColumnDefinition() (*Decl, error) {
+	// New attribute name
+	newAttribute, err := p.parseQuotedToken()
+	if err != nil {
+		return nil, err
+	}
+
+	newAttributeType := &Decl{Token: StringToken}
+	if p.dialect != DialectSQLite || p.is(FloatToken, DateToken, DecimalToken, NumberToken, StringToken) {
+		newAttributeType, err = p.parseType()
 		if err != nil {
 			return nil, err
 		}
-		newAttribute.Add(newAttributeType)
+	}
+	newAttribute.Add(newAttributeType)
 
-		// All the following tokens until bracket or comma are column constraints.
-		// Column constraints can be listed in any order.
-		for p.isNot(BracketClosingToken, CommaToken) {
-			switch p.cur().Token {
-			case UnsignedToken:
-				_, err = p.consumeToken(UnsignedToken)
+	// All the following tokens until bracket, comma or end of statement are column constraints.
+	// Column constraints can be listed in any order.
+	for p.isNot(BracketClosingToken, CommaToken, SemicolonToken) {
+		switch p.cur().Token {
+		case UnsignedToken:
+			_, err = p.consumeToken(UnsignedToken)
+			if err != nil {
+				return nil, err
+			}
+		case UniqueToken: // UNIQUE
+			uniqueDecl, err := p.consumeToken(UniqueToken)
+			if err != nil {
+				return nil, err
+			}
+			newAttribute.Add(uniqueDecl)
+		case NotToken: // NOT NULL
+			if _, err = p.isNext(NullToken); err == nil {
+				notDecl, err := p.consumeToken(NotToken)
 				if err != nil {
 					return nil, err
 				}
-			case UniqueToken: // UNIQUE
-				uniqueDecl, err := p.consumeToken(UniqueToken)
+				newAttribute.Add(notDecl)
+				nullDecl, err := p.consumeToken(NullToken)
 				if err != nil {
 					return nil, err
 				}
-				newAttribute.Add(uniqueDecl)
-			case NotToken: // NOT NULL
-				if _, err = p.isNext(NullToken); err == nil {
-					notDecl, err := p.consumeToken(NotToken)
-					if err != nil {
-						return nil, err
-					}
-					newAttribute.Add(notDecl)
-					nullDecl, err := p.consumeToken(NullToken)
-					if err != nil {
-						return nil, err
-					}
-					notDecl.Add(nullDecl)
-				}
-			case PrimaryToken: // PRIMARY KEY
-				if _, err = p.isNext(KeyToken); err == nil {
-					newPrimary := NewDecl(tokens[p.index])
-					newAttribute.Add(newPrimary)
+				notDecl.Add(nullDecl)
+			}
+		case PrimaryToken: // PRIMARY KEY
+			if _, err = p.isNext(KeyToken); err == nil {
+				newPrimary := NewDecl(p.cur())
+				newAttribute.Add(newPrimary)
 
-					if err = p.next(); err != nil {
-						return nil, fmt.Errorf("Unexpected end")
-					}
+				if err = p.next(); err != nil {
+					return nil, fmt.Errorf("Unexpected end")
+				}
 
-					newKey := NewDecl(tokens[p.index])
-					newPrimary.Add(newKey)
+				newKey := NewDecl(p.cur())
+				newPrimary.Add(newKey)
 
-					if err = p.next(); err != nil {
-						return nil, fmt.Errorf("Unexpected end")
-					}
-				}
-			case AutoincrementToken:
-				autoincDecl, err := p.consumeToken(AutoincrementToken)
-				if err != nil {
-					return nil, err
+				if err = p.next(); err != nil {
+					return nil, fmt.Errorf("Unexpected end")
 				}
-				newAttribute.Add(autoincDecl)
-			case WithToken: // WITH TIME ZONE
-				if strings.ToLower(newAttributeType.Lexeme) == "timestamp" {
-					withDecl, err := p.consumeToken(WithToken)
-					if err != nil {
-						return nil, err
-					}
-					timeDecl, err := p.consumeToken(TimeToken)
-					if err != nil {
-						return nil, err
-					}
-					zoneDecl, err := p.consumeToken(ZoneToken)
-					if err != nil {
-						return nil, err
-					}
-					newAttributeType.Add(withDecl)
-					withDecl.Add(timeDecl)
-					timeDecl.Add(zoneDecl)
-				}
-			case DefaultToken: // DEFAULT
-				dDecl, err := p.parseDefaultClause()
-				if err != nil {
-					return nil, err
-				}
-				newAttribute.Add(dDecl)
-			case ReferencesToken: // REFERENCES table(col)
-				rDecl, err := p.parseReferencesClause()
-				if err != nil {
-					return nil, err
-				}
-				newAttribute.Add(rDecl)
-			case ConstraintToken: // CONSTRAINT name REFERENCES ...
-				cDecl, err := p.consumeToken(ConstraintToken)
+			}
+		case NullToken: // NULL, attributes are nullable unless NOT NULL
+			if err = p.next(); err != nil {
+				return nil, err
+			}
+		case AutoincrementToken:
+			autoincDecl, err := p.consumeToken(AutoincrementToken)
+			if err != nil {
+				return nil, err
+			}
+			newAttribute.Add(autoincDecl)
+		case StringToken: // GENERATED { ALWAYS | BY DEFAULT } AS IDENTITY
+			if !p.isWord("generated") {
+				return nil, p.syntaxError()
+			}
+			autoincDecl, err := p.parseIdentityClause()
+			if err != nil {
+				return nil, err
+			}
+			newAttribute.Add(autoincDecl)
+		case WithToken: // WITH TIME ZONE
+			if strings.ToLower(newAttributeType.Lexeme) == "timestamp" {
+				withDecl, err := p.consumeToken(WithToken)
 				if err != nil {
 					return nil, err
 				}
-				name, err := p.parseQuotedToken()
+				timeDecl, err := p.consumeToken(TimeToken)
 				if err != nil {
 					return nil, err
 				}
-				cDecl.Add(name)
-				if !p.is(ReferencesToken) {
-					return nil, p.syntaxError()
-				}
-				rDecl, err := p.parseReferencesClause()
+				zoneDecl, err := p.consumeToken(ZoneToken)
 				if err != nil {
 					return nil, err
 				}
-				cDecl.Add(rDecl)
-				newAttribute.Add(cDecl)
-			default:
-				// Unknown column constraint
+				newAttributeType.Add(withDecl)
+				withDecl.Add(timeDecl)
+				timeDecl.Add(zoneDecl)
+			}
+		case DefaultToken: // DEFAULT
+			dDecl, err := p.parseDefaultClause()
+			if err != nil {
+				return nil, err
+			}
+			newAttribute.Add(dDecl)
+		case ReferencesToken: // REFERENCES table(col)
+			rDecl, err := p.parseReferencesClause()
+			if err != nil {
+				return nil, err
+			}
+			newAttribute.Add(rDecl)
+		case ConstraintToken: // CONSTRAINT name REFERENCES ...
+			cDecl, err := p.consumeToken(ConstraintToken)
+			if err != nil {
+				return nil, err
+			}
+			name, err := p.parseQuotedToken()
+			if err != nil {
+				return nil, err
+			}
+			cDecl.Add(name)
+			if !p.is(ReferencesToken) {
 				return nil, p.syntaxError()
 			}
+			rDecl, err := p.parseReferencesClause()
+			if err != nil {
+				return nil, err
+			}
+			cDecl.Add(rDecl)
+			newAttribute.Add(cDecl)
+		default:
+			// Unknown column constraint
+			return nil, p.syntaxError()
 		}
+	}
 
-		// The current token is either closing bracked or comma.
+	return newAttribute, nil
+}
 
-		// Closing bracket means table parsing stops.
-		if tokens[p.index].Token == BracketClosingToken {
-			p.index++
+// parseTableConstraint parses a named table-level constraint
+//
+//	CONSTRAINT name { FOREIGN KEY (col1, col2) REFERENCES ... | UNIQUE (col1, col2) }
+func (p *parser) parseTableConstraint() (*Decl, error) {
+	cDecl, err := p.consumeToken(ConstraintToken)
+	if err != nil {
+		return nil, err
+	}
+	nameDecl, err := p.parseQuotedToken()
+	if err != nil {
+		return nil, err
+	}
+	cDecl.Add(nameDecl)
+
+	var d *Decl
+	switch p.cur().Token {
+	case ForeignToken:
+		d, err = p.parseTableForeignKey()
+	case UniqueToken:
+		d, err = p.parseUniqueConstraint()
+	default:
+		return nil, p.syntaxError()
+	}
+	if err != nil {
+		return nil, err
+	}
+	cDecl.Add(d)
+
+	return cDecl, nil
+}
+
+// parseUniqueConstraint parses a table-level UNIQUE constraint
+// UNIQUE (col1, col2)
+func (p *parser) parseUniqueConstraint() (*Decl, error) {
+	uDecl, err := p.consumeToken(UniqueToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consumeToken(BracketOpeningToken); err != nil {
+		return nil, err
+	}
+	for {
+		d, err := p.parseQuotedToken()
+		if err != nil {
+			return nil, err
+		}
+		uDecl.Add(d)
+		d, err = p.consumeToken(CommaToken, BracketClosingToken)
+		if err != nil {
+			return nil, err
+		}
+		if d.Token == BracketClosingToken {
 			break
 		}
-
-		// Comma means continue on next table column.
-		p.index++
 	}
 
-	return tableDecl, nil
+	return uDecl, nil
 }
 
 // parseTableForeignKey parses a table-level FOREIGN KEY constraint
@@ -564,7 +674,7 @@ func (p *parser) parseDefaultClause() (*Decl, error) {
 	if p.is(SimpleQuoteToken) || p.is(DoubleQuoteToken) {
 		vDecl, err = p.parseStringLiteral()
 	} else {
-		vDecl, err = p.consumeToken(NullToken, FloatToken, FalseToken, NumberToken, LocalTimestampToken, NowToken, ArgToken, NamedArgToken)
+		vDecl, err = p.consumeToken(NullToken, FloatToken, TrueToken, FalseToken, NumberToken, LocalTimestampToken, NowToken, ArgToken, NamedArgToken)
 	}
 
 	if err != nil {
@@ -574,6 +684,53 @@ func (p *parser) parseDefaultClause() (*Decl, error) {
 	return dDecl, nil
 }
 
+// parseIdentityClause parses an identity column clause as an AUTOINCREMENT (AutoincrementToken) decl
+//
+//	GENERATED { ALWAYS | BY DEFAULT } AS IDENTITY [ ( sequence_options ) ]
+//
+// Sequence options are ignored.
+func (p *parser) parseIdentityClause() (*Decl, error) {
+	p.index++
+
+	switch {
+	case p.isWord("always"):
+		p.index++
+	case p.is(ByToken):
+		p.index++
+		if _, err := p.consumeToken(DefaultToken); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, p.syntaxError()
+	}
+
+	if _, err := p.consumeToken(AsToken); err != nil {
+		return nil, err
+	}
+	if !p.isWord("identity") {
+		return nil, p.syntaxError()
+	}
+	p.index++
+
+	if p.is(BracketOpeningToken) {
+		depth := 0
+		for p.index < p.tokenLen {
+			switch p.cur().Token {
+			case BracketOpeningToken:
+				depth++
+			case BracketClosingToken:
+				depth--
+			}
+			p.index++
+			if depth == 0 {
+				break
+			}
+		}
+	}
+
+	return &Decl{Token: AutoincrementToken, Lexeme: "autoincrement"}, nil
+}
+
 func (p *parser) parsePrimaryKey() (*Decl, error) {
 	primaryDecl, err := p.consumeToken(PrimaryToken)
 	if err != nil {
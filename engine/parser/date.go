@@ -13,6 +13,14 @@ const DateShortFormat = "2006-Jan-02"
 
 const DateNumberFormat = "2006-01-02"
 
+// DatePostgresFormats are timestamp text formats of PostgreSQL
+var DatePostgresFormats = []string{
+	"2006-01-02 15:04:05.999999999Z07:00:00",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+}
+
 // ParseDate intends to parse all SQL date format
 func ParseDate(data string) (*time.Time, error) {
 	t, err := time.Parse(DateLongFormat, data)
@@ -25,6 +33,13 @@ func ParseDate(data string) (*time.Time, error) {
 		return &t, nil
 	}
 
+	for _, layout := range DatePostgresFormats {
+		t, err = time.Parse(layout, data)
+		if err == nil {
+			return &t, nil
+		}
+	}
+
 	t, err = time.Parse(DateShortFormat, data)
 	if err == nil {
 		return &t, nil
@@ -33,10 +33,17 @@ func (p *parser) parseDelete() (*Instruction, error) {
 		return i, nil
 	}
 
-	err = p.parseWhere(deleteDecl)
-	if err != nil {
+	if p.is(ReturningToken) {
+		addImplicitWhereAll(deleteDecl)
+	} else if err = p.parseWhere(deleteDecl); err != nil {
 		return nil, err
 	}
 
+	if p.is(ReturningToken) {
+		if err = p.parseReturning(deleteDecl); err != nil {
+			return nil, err
+		}
+	}
+
 	return i, nil
 }
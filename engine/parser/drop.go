@@ -1,5 +1,15 @@
 package parser
 
+// parseDrop parses a DROP statement
+//
+//	DROP { TABLE | SCHEMA | INDEX } [ IF EXISTS ] name [ CASCADE | RESTRICT ]
+//
+// |-> "DROP" (DropToken)
+//
+//	|-> TABLE (TableToken), SCHEMA (SchemaToken) or INDEX (IndexToken)
+//	    |-> IF (IfToken), optional
+//	        |-> EXISTS (ExistsToken)
+//	    |-> name (StringToken)
 func (p *parser) parseDrop(tokens []Token) (*Instruction, error) {
 	var err error
 	i := &Instruction{}
@@ -10,25 +20,25 @@ func (p *parser) parseDrop(tokens []Token) (*Instruction, error) {
 	}
 	i.Decls = append(i.Decls, trDecl)
 
-	var d *Decl
-	switch tokens[p.index].Token {
-	case TableToken:
-		d, err = p.consumeToken(TableToken)
-		if err != nil {
-			return nil, err
-		}
-	case IndexToken:
-		d, err = p.consumeToken(SchemaToken)
+	d, err := p.consumeToken(TableToken, SchemaToken, IndexToken)
+	if err != nil {
+		return nil, err
+	}
+	trDecl.Add(d)
+
+	// Maybe have "IF EXISTS" here
+	if p.is(IfToken) {
+		ifDecl, err := p.consumeToken(IfToken)
 		if err != nil {
 			return nil, err
 		}
-	case SchemaToken:
-		d, err = p.consumeToken(SchemaToken)
+		existsDecl, err := p.consumeToken(ExistsToken)
 		if err != nil {
 			return nil, err
 		}
+		ifDecl.Add(existsDecl)
+		d.Add(ifDecl)
 	}
-	trDecl.Add(d)
 
 	// Should be a name attribute
 	nameDecl, err := p.parseAttribute()
@@ -37,5 +47,10 @@ func (p *parser) parseDrop(tokens []Token) (*Instruction, error) {
 	}
 	d.Add(nameDecl)
 
+	// dependent objects are dropped with their relation
+	if p.is(CascadeToken, RestrictToken) {
+		p.index++
+	}
+
 	return i, nil
 }
@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"strings"
+)
+
+// parseGenerate parses a synthetic data generation statement:
+//
+//	GENERATE 100 ROWS FOR [schema.]table [SEED 42]
+//
+// The generated AST is as follows:
+//
+//	GenerateToken
+//	|-> NumberToken (row count)
+//	|-> StringToken (table name)
+//	|   |-> SchemaToken (optional)
+//	|-> StringToken (seed, optional)
+//	    |-> NumberToken
+//
+// ROWS and SEED are not reserved keywords, so they are matched as string tokens.
+func (p *parser) parseGenerate() (*Instruction, error) {
+	i := &Instruction{}
+
+	genDecl, err := p.consumeToken(GenerateToken)
+	if err != nil {
+		return nil, err
+	}
+	i.Decls = append(i.Decls, genDecl)
+
+	countDecl, err := p.consumeToken(NumberToken)
+	if err != nil {
+		return nil, err
+	}
+	genDecl.Add(countDecl)
+
+	if !p.is(StringToken) || !strings.EqualFold(p.cur().Lexeme, "rows") {
+		return nil, p.syntaxError()
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consumeToken(ForToken); err != nil {
+		return nil, err
+	}
+
+	tableDecl, err := p.parseTableName()
+	if err != nil {
+		return nil, err
+	}
+	genDecl.Add(tableDecl)
+
+	// optional SEED clause
+	if p.is(StringToken) && strings.EqualFold(p.cur().Lexeme, "seed") && p.hasNext() && p.tokens[p.index+1].Token == NumberToken {
+		seedDecl := NewDecl(p.cur())
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		numDecl, err := p.consumeToken(NumberToken)
+		if err != nil {
+			return nil, err
+		}
+		seedDecl.Add(numDecl)
+		genDecl.Add(seedDecl)
+	}
+
+	return i, nil
+}
@@ -24,8 +24,11 @@ import "fmt"
 //	            |-> "UPDATE" (UpdateToken) or "NOTHING" (NothingToken)
 //	                |-> "SET" (SetToken) (if UPDATE)
 //	                    |-> column = value assignments
+//	    |-> "REPLACE" (ReplaceToken) (optional, for SQLite INSERT OR REPLACE)
 //	    |-> "RETURNING" (ReturningToken) (optional)
 //	        |-> column name
+//
+// SQLite INSERT OR IGNORE is returned as ON CONFLICT DO NOTHING.
 func (p *parser) parseInsert() (*Instruction, error) {
 	i := &Instruction{}
 
@@ -36,6 +39,15 @@ func (p *parser) parseInsert() (*Instruction, error) {
 	}
 	i.Decls = append(i.Decls, insertDecl)
 
+	// SQLite conflict resolution, such as OR REPLACE
+	var resolutionDecl *Decl
+	if p.dialect == DialectSQLite && p.is(OrToken) {
+		resolutionDecl, err = p.parseConflictResolution()
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// should be INTO
 	intoDecl, err := p.consumeToken(IntoToken)
 	if err != nil {
@@ -118,8 +130,14 @@ func (p *parser) parseInsert() (*Instruction, error) {
 		break
 	}
 
-	// we may have ON CONFLICT clause here
-	if p.is(OnToken) {
+	// we may have ON CONFLICT clause here, or ON DUPLICATE KEY UPDATE in MySQL dialect
+	if p.is(OnToken) && p.dialect == DialectMySQL && p.hasNext() && p.tokens[p.index+1].Token != ConflictToken {
+		onDecl, err := p.parseOnDuplicateKey()
+		if err != nil {
+			return nil, err
+		}
+		insertDecl.Add(onDecl)
+	} else if p.is(OnToken) {
 		onDecl, err := p.consumeToken(OnToken)
 		if err != nil {
 			return nil, err
@@ -190,53 +208,77 @@ func (p *parser) parseInsert() (*Instruction, error) {
 			updateDecl.Add(setDecl)
 
 			// Parse column = value assignments
-			for {
-				attrDecl, err := p.parseAttribute()
-				if err != nil {
-					return nil, err
-				}
-				setDecl.Add(attrDecl)
-
-				_, err = p.consumeToken(EqualityToken)
-				if err != nil {
-					return nil, err
-				}
-
-				// Parse the value (can be "excluded"."column" or a regular value)
-				valueDecl, err := p.parseAttribute()
-				if err != nil {
-					return nil, err
-				}
-				attrDecl.Add(valueDecl)
-
-				if !p.is(CommaToken) {
-					break
-				}
-				_, err = p.consumeToken(CommaToken)
-				if err != nil {
-					return nil, err
-				}
+			if err := p.parseConflictAssignments(setDecl); err != nil {
+				return nil, err
 			}
 		} else {
 			return nil, fmt.Errorf("expected UPDATE or NOTHING after DO")
 		}
+	} else if resolutionDecl != nil {
+		// an upsert clause takes precedence over the conflict resolution
+		insertDecl.Add(resolutionDecl)
 	}
 
 	// we may have `returning "something"` here
-	if retDecl, err := p.consumeToken(ReturningToken); err == nil {
-		insertDecl.Add(retDecl)
-
-		// returned attribute
-		attrDecl, err := p.parseAttribute()
-		if err != nil {
+	if p.is(ReturningToken) {
+		if err := p.parseReturning(insertDecl); err != nil {
 			return nil, err
 		}
-		retDecl.Add(attrDecl)
 	}
 
 	return i, nil
 }
 
+// parseConflictAssignments parses column = value assignments of ON CONFLICT DO UPDATE SET clause.
+// Values can be "excluded"."column", a parameter or a literal.
+func (p *parser) parseConflictAssignments(setDecl *Decl) error {
+	for {
+		attrDecl, err := p.parseAttribute()
+		if err != nil {
+			return err
+		}
+		setDecl.Add(attrDecl)
+
+		_, err = p.consumeToken(EqualityToken)
+		if err != nil {
+			return err
+		}
+
+		var valueDecl *Decl
+		switch {
+		case p.is(ArgToken, NamedArgToken):
+			valueDecl, err = p.consumeToken(ArgToken, NamedArgToken)
+		case p.is(SimpleQuoteToken, NumberToken, FloatToken, NullToken, TrueToken, FalseToken, NowToken):
+			valueDecl, err = p.parseListElement()
+		case p.is(ValuesToken) && p.dialect == DialectMySQL:
+			valueDecl, err = p.parseInsertedValue()
+		case p.isArrayConstructor():
+			valueDecl, err = p.parseArrayConstructor()
+		case p.isWord("coalesce"):
+			valueDecl, err = p.parseOperand()
+		default:
+			valueDecl, err = p.parseAttribute()
+		}
+		if err != nil {
+			return err
+		}
+		// counters, as in hits = counter.hits + excluded.hits
+		valueDecl, err = p.parseArithmetic(valueDecl)
+		if err != nil {
+			return err
+		}
+		attrDecl.Add(valueDecl)
+
+		if !p.is(CommaToken) {
+			return nil
+		}
+		_, err = p.consumeToken(CommaToken)
+		if err != nil {
+			return err
+		}
+	}
+}
+
 func (p *parser) parseListElement() (*Decl, error) {
 	quoted := false
 
@@ -249,7 +291,15 @@ func (p *parser) parseListElement() (*Decl, error) {
 		return v, nil
 	}
 
-	if p.is(SimpleQuoteToken) || p.is(DoubleQuoteToken) {
+	if p.isArrayConstructor() {
+		return p.parseArrayConstructor()
+	}
+
+	if p.isJSONFunction() {
+		return p.parseJSONFunction()
+	}
+
+	if p.is(SimpleQuoteToken) || p.is(DoubleQuoteToken) || p.is(BacktickToken) {
 		quoted = true
 		p.next()
 	}
@@ -261,7 +311,7 @@ func (p *parser) parseListElement() (*Decl, error) {
 	}
 
 	if quoted {
-		if _, err := p.consumeToken(SimpleQuoteToken, DoubleQuoteToken); err != nil {
+		if _, err := p.consumeToken(SimpleQuoteToken, DoubleQuoteToken, BacktickToken); err != nil {
 			return nil, err
 		}
 	}
@@ -2,10 +2,33 @@ package parser
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+)
+
+// Dialects accepted by ParseInstructionWithDialect
+const (
+	// DialectPostgres is the default syntax
+	DialectPostgres = "postgres"
+	// DialectMySQL also accepts MySQL syntax, such as LIMIT offset, count or ON DUPLICATE KEY UPDATE
+	DialectMySQL = "mysql"
+	// DialectSQLite also accepts SQLite syntax, such as INSERT OR REPLACE or PRAGMA
+	DialectSQLite = "sqlite"
 )
 
 // ParseInstruction calls lexer and parser, then return Decl tree for each instruction
 func ParseInstruction(instruction string) ([]Instruction, error) {
+	return ParseInstructionWithDialect(instruction, DialectPostgres)
+}
+
+// ParseInstructionWithDialect is like ParseInstruction, also accepting the syntax
+// specific to given dialect. An empty dialect is DialectPostgres.
+func ParseInstructionWithDialect(instruction string, dialect string) ([]Instruction, error) {
+	switch dialect {
+	case "", DialectPostgres, DialectMySQL, DialectSQLite:
+	default:
+		return nil, fmt.Errorf("unknown dialect %s", dialect)
+	}
 
 	l := lexer{}
 	tokens, err := l.lex([]byte(instruction))
@@ -13,7 +36,7 @@ func ParseInstruction(instruction string) ([]Instruction, error) {
 		return nil, err
 	}
 
-	p := parser{}
+	p := parser{dialect: dialect}
 	instructions, err := p.parse(tokens)
 	if err != nil {
 		return nil, err
@@ -23,5 +46,28 @@ func ParseInstruction(instruction string) ([]Instruction, error) {
 		return nil, errors.New("Error in syntax near " + instruction)
 	}
 
+	texts := statementTexts(instruction, l.ends)
+	if len(texts) == len(instructions) {
+		for i := range instructions {
+			instructions[i].Text = texts[i]
+		}
+	}
+
 	return instructions, nil
 }
+
+// statementTexts splits query at semicolons ending statements, given by ends, ignoring empty statements
+func statementTexts(query string, ends []int) []string {
+	var texts []string
+	start := 0
+	for _, end := range append(ends, len(query)+1) {
+		if end > len(query) {
+			end = len(query) + 1
+		}
+		if t := strings.TrimSpace(query[start : end-1]); t != "" {
+			texts = append(texts, t)
+		}
+		start = end
+	}
+	return texts
+}
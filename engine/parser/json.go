@@ -0,0 +1,147 @@
+package parser
+
+// isJSONOperator reports whether current token is one of the ->, ->>, #> and #>> JSON operators
+func (p *parser) isJSONOperator() bool {
+	return p.is(JSONFieldToken, JSONFieldTextToken, JSONPathToken, JSONPathTextToken)
+}
+
+// parseJSONOperators adds JSON operators following attribute attrDecl, if any
+//
+//	attribute
+//	    |-> JSONFieldToken, JSONFieldTextToken, JSONPathToken or JSONPathTextToken
+//	        |-> key, index or path ('literal' as SimpleQuoteToken, number or parameter)
+func (p *parser) parseJSONOperators(attrDecl *Decl) (*Decl, error) {
+	for p.isJSONOperator() {
+		opDecl, err := p.consumeToken(p.cur().Token)
+		if err != nil {
+			return nil, err
+		}
+
+		var operandDecl *Decl
+		if p.is(SimpleQuoteToken) {
+			operandDecl, err = p.parseStringLiteral()
+			if err == nil {
+				operandDecl.Token = SimpleQuoteToken
+			}
+		} else {
+			operandDecl, err = p.consumeToken(NumberToken, ArgToken, NamedArgToken)
+		}
+		if err != nil {
+			return nil, err
+		}
+		opDecl.Add(operandDecl)
+		attrDecl.Add(opDecl)
+	}
+
+	return attrDecl, nil
+}
+
+// isJSONFunction reports whether current token starts jsonb_build_object(...) or jsonb_set(...)
+func (p *parser) isJSONFunction() bool {
+	if !p.isWord("jsonb_build_object") && !p.isWord("json_build_object") && !p.isWord("jsonb_set") {
+		return false
+	}
+	_, err := p.isNext(BracketOpeningToken)
+	return err == nil
+}
+
+// parseJSONFunction parses jsonb_build_object(key, value, ...) or
+// jsonb_set(target, path, value [, create_missing])
+//
+//	JSONBuildObjectToken or JSONSetToken
+//	    |-> argument
+//	    |-> argument
+func (p *parser) parseJSONFunction() (*Decl, error) {
+	d := &Decl{Token: JSONBuildObjectToken, Lexeme: "jsonb_build_object"}
+	if p.isWord("jsonb_set") {
+		d = &Decl{Token: JSONSetToken, Lexeme: "jsonb_set"}
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if _, err := p.consumeToken(BracketOpeningToken); err != nil {
+		return nil, err
+	}
+
+	for !p.is(BracketClosingToken) {
+		argDecl, err := p.parseJSONOperand()
+		if err != nil {
+			return nil, err
+		}
+		d.Add(argDecl)
+		if !p.is(CommaToken) {
+			break
+		}
+		if _, err := p.consumeToken(CommaToken); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.consumeToken(BracketClosingToken); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// parseJSONOperand parses an argument of a JSON function, either a JSON function,
+// ARRAY[...], a subquery, a value or an attribute followed by JSON operators
+func (p *parser) parseJSONOperand() (*Decl, error) {
+	switch {
+	case p.isJSONFunction():
+		return p.parseJSONFunction()
+	case p.isArrayConstructor():
+		return p.parseArrayConstructor()
+	case p.isSubquery():
+		return p.parseSubquery()
+	case p.is(SimpleQuoteToken):
+		valueDecl, err := p.parseStringLiteral()
+		if err != nil {
+			return nil, err
+		}
+		valueDecl.Token = SimpleQuoteToken
+		return valueDecl, nil
+	case p.is(ArgToken, NamedArgToken, NumberToken, FloatToken, TrueToken, FalseToken, NullToken):
+		return p.consumeToken(ArgToken, NamedArgToken, NumberToken, FloatToken, TrueToken, FalseToken, NullToken)
+	}
+
+	attrDecl, err := p.parseAttribute()
+	if err != nil {
+		return nil, err
+	}
+	return p.parseJSONOperators(attrDecl)
+}
+
+// isJSONArrayElements reports whether current token starts a json_array_elements(...)
+// or jsonb_array_elements(...) set-returning function
+func (p *parser) isJSONArrayElements() bool {
+	if !p.isWord("json_array_elements") && !p.isWord("jsonb_array_elements") {
+		return false
+	}
+	_, err := p.isNext(BracketOpeningToken)
+	return err == nil
+}
+
+// parseJSONArrayElements parses json_array_elements(document) [[AS] alias [(column)]] in a FROM clause
+//
+//	JSONArrayElementsToken
+//	    |-> document: parameter, literal, subquery or JSON function
+//	    |-> alias (StringToken), optional
+//	        |-> column (StringToken), optional
+func (p *parser) parseJSONArrayElements() (*Decl, error) {
+	d := &Decl{Token: JSONArrayElementsToken, Lexeme: "json_array_elements"}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if _, err := p.consumeToken(BracketOpeningToken); err != nil {
+		return nil, err
+	}
+	operandDecl, err := p.parseJSONOperand()
+	if err != nil {
+		return nil, err
+	}
+	d.Add(operandDecl)
+	if _, err := p.consumeToken(BracketClosingToken); err != nil {
+		return nil, err
+	}
+	return p.parseFunctionAlias(d)
+}
@@ -33,6 +33,7 @@ const (
 	PlusToken
 	MinusToken
 	DivideToken
+	CastToken
 
 	// First order Token
 
@@ -46,6 +47,7 @@ const (
 	DropToken
 	GrantToken
 	DistinctToken
+	GenerateToken
 
 	// Second order Token
 
@@ -119,6 +121,66 @@ const (
 
 	ArgToken
 	NamedArgToken
+
+	// Session Token, not reserved by the lexer
+
+	ShowToken
+	ResetToken
+	LocalToken
+
+	// MySQL Token, not reserved by the lexer
+
+	LastInsertIDToken
+
+	// SQLite Token, not reserved by the lexer
+
+	ReplaceToken
+	PragmaToken
+
+	// ALTER Token, not reserved by the lexer
+
+	AlterToken
+	AddToken
+	RenameToken
+	ColumnToken
+	TypeToken
+
+	// Function and operator Token, not reserved by the lexer
+
+	AggregateToken
+	CoalesceToken
+	IlikeToken
+	AdvisoryLockToken
+
+	// Array Token
+
+	SquareBracketOpeningToken
+	SquareBracketClosingToken
+	ContainsToken
+	ContainedByToken
+	OverlapToken
+
+	// Array function Token, not reserved by the lexer
+
+	ArrayToken
+	AnyToken
+	AllToken
+	ArrayLengthToken
+	UnnestToken
+
+	// JSON Token
+
+	JSONFieldToken
+	JSONFieldTextToken
+	JSONPathToken
+	JSONPathTextToken
+	KeyExistsToken
+
+	// JSON function Token, not reserved by the lexer
+
+	JSONBuildObjectToken
+	JSONSetToken
+	JSONArrayElementsToken
 )
 
 // Token struct holds token id and it's lexeme
@@ -132,6 +194,8 @@ type lexer struct {
 	instruction    []byte
 	instructionLen int
 	pos            int
+	// ends holds the position following each semicolon ending a statement
+	ends []int
 }
 
 // Matcher tries to match given string to an SQL token
@@ -145,7 +209,11 @@ func (l *lexer) lex(instruction []byte) ([]Token, error) {
 	securityPos := 0
 
 	var matchers []Matcher
+	matchers = append(matchers, l.MatchCommentToken)
 	matchers = append(matchers, l.MatchArgTokenODBC)
+	matchers = append(matchers, l.MatchCastToken)
+	matchers = append(matchers, l.MatchArrayOperatorToken)
+	matchers = append(matchers, l.MatchJSONOperatorToken)
 	matchers = append(matchers, l.MatchNamedArgToken)
 	matchers = append(matchers, l.MatchArgToken)
 	matchers = append(matchers, l.MatchFloatToken)
@@ -155,6 +223,8 @@ func (l *lexer) lex(instruction []byte) ([]Token, error) {
 	matchers = append(matchers, l.genericByteMatcher(',', CommaToken))
 	matchers = append(matchers, l.genericByteMatcher('(', BracketOpeningToken))
 	matchers = append(matchers, l.genericByteMatcher(')', BracketClosingToken))
+	matchers = append(matchers, l.genericByteMatcher('[', SquareBracketOpeningToken))
+	matchers = append(matchers, l.genericByteMatcher(']', SquareBracketClosingToken))
 	matchers = append(matchers, l.genericByteMatcher('*', StarToken))
 	matchers = append(matchers, l.MatchSimpleQuoteToken)
 	matchers = append(matchers, l.genericByteMatcher('=', EqualityToken))
@@ -177,6 +247,7 @@ func (l *lexer) lex(instruction []byte) ([]Token, error) {
 	matchers = append(matchers, l.genericStringMatcher("drop", DropToken))
 	matchers = append(matchers, l.genericStringMatcher("grant", GrantToken))
 	matchers = append(matchers, l.genericStringMatcher("distinct", DistinctToken))
+	matchers = append(matchers, l.genericStringMatcher("generate", GenerateToken))
 	// Second order Matcher
 	matchers = append(matchers, l.genericStringMatcher("table", TableToken))
 	matchers = append(matchers, l.genericStringMatcher("current_database()", CurrentDatabaseToken))
@@ -250,14 +321,19 @@ func (l *lexer) lex(instruction []byte) ([]Token, error) {
 	matchers = append(matchers, l.MatchStringToken)
 
 	var r bool
+	l.ends = nil
 	for l.pos < l.instructionLen {
 		r = false
+		n := len(l.tokens)
 		for _, m := range matchers {
 			if r = m(); r {
 				securityPos = l.pos
 				break
 			}
 		}
+		if r && len(l.tokens) > n && l.tokens[len(l.tokens)-1].Token == SemicolonToken {
+			l.ends = append(l.ends, l.pos)
+		}
 
 		if r {
 			continue
@@ -282,27 +358,84 @@ func (l *lexer) MatchArgTokenODBC() bool {
 	if len(l.tokens) < 1 {
 		return false
 	}
-	if l.tokens[len(l.tokens)-1].Token == SimpleQuoteToken || l.tokens[len(l.tokens)-1].Token == DoubleQuoteToken {
-		return false
-	}
-	i++
 	t := Token{
 		Token:  ArgToken,
 		Lexeme: "?",
 	}
+	// a marker cannot follow a literal, ? is then the JSON key exists operator
+	if l.tokens[len(l.tokens)-1].Token == SimpleQuoteToken || l.tokens[len(l.tokens)-1].Token == DoubleQuoteToken {
+		t.Token = KeyExistsToken
+	}
+	i++
 	l.tokens = append(l.tokens, t)
 	l.pos = i
 	return true
 }
 
+// MatchCastToken matches the :: type cast operator
+func (l *lexer) MatchCastToken() bool {
+	if l.pos+1 >= l.instructionLen || l.instruction[l.pos] != ':' || l.instruction[l.pos+1] != ':' {
+		return false
+	}
+
+	l.tokens = append(l.tokens, Token{Token: CastToken, Lexeme: "::"})
+	l.pos += 2
+	return true
+}
+
+// MatchArrayOperatorToken matches the @> (contains), <@ (is contained by) and && (overlaps) operators
+func (l *lexer) MatchArrayOperatorToken() bool {
+	if l.pos+1 >= l.instructionLen {
+		return false
+	}
+
+	var token int
+	switch string(l.instruction[l.pos : l.pos+2]) {
+	case "@>":
+		token = ContainsToken
+	case "<@":
+		token = ContainedByToken
+	case "&&":
+		token = OverlapToken
+	default:
+		return false
+	}
+
+	l.tokens = append(l.tokens, Token{Token: token, Lexeme: string(l.instruction[l.pos : l.pos+2])})
+	l.pos += 2
+	return true
+}
+
+// MatchJSONOperatorToken matches the ->, ->>, #> and #>> JSON operators
+func (l *lexer) MatchJSONOperatorToken() bool {
+	for _, op := range []Token{
+		{Token: JSONFieldTextToken, Lexeme: "->>"},
+		{Token: JSONFieldToken, Lexeme: "->"},
+		{Token: JSONPathTextToken, Lexeme: "#>>"},
+		{Token: JSONPathToken, Lexeme: "#>"},
+	} {
+		n := len(op.Lexeme)
+		if l.pos+n <= l.instructionLen && string(l.instruction[l.pos:l.pos+n]) == op.Lexeme {
+			l.tokens = append(l.tokens, op)
+			l.pos += n
+			return true
+		}
+	}
+	return false
+}
+
+// MatchNamedArgToken matches :name and @name parameter markers
 func (l *lexer) MatchNamedArgToken() bool {
 
 	i := l.pos
-	if l.instruction[i] != ':' {
+	if l.instruction[i] != ':' && l.instruction[i] != '@' {
 		return false
 	}
 	i++
-	for i < l.instructionLen && unicode.IsLetter(rune(l.instruction[i])) {
+	if i >= l.instructionLen || !unicode.IsLetter(rune(l.instruction[i])) {
+		return false
+	}
+	for i < l.instructionLen && (unicode.IsLetter(rune(l.instruction[i])) || unicode.IsDigit(rune(l.instruction[i])) || l.instruction[i] == '_') {
 		i++
 	}
 	if i > l.pos+1 {
@@ -341,6 +474,36 @@ func (l *lexer) MatchArgToken() bool {
 	return false
 }
 
+// MatchCommentToken matches -- line comments and /* block comments */, lexed as a space
+func (l *lexer) MatchCommentToken() bool {
+	if l.pos+1 >= l.instructionLen {
+		return false
+	}
+
+	i := l.pos
+	switch {
+	case l.instruction[i] == '-' && l.instruction[i+1] == '-':
+		for i < l.instructionLen && l.instruction[i] != '\n' {
+			i++
+		}
+	case l.instruction[i] == '/' && l.instruction[i+1] == '*':
+		i += 2
+		for i+1 < l.instructionLen && !(l.instruction[i] == '*' && l.instruction[i+1] == '/') {
+			i++
+		}
+		i += 2
+		if i > l.instructionLen {
+			i = l.instructionLen
+		}
+	default:
+		return false
+	}
+
+	l.tokens = append(l.tokens, Token{Token: SpaceToken, Lexeme: " "})
+	l.pos = i
+	return true
+}
+
 func (l *lexer) MatchSpaceToken() bool {
 
 	if unicode.IsSpace(rune(l.instruction[l.pos])) {
@@ -654,7 +817,7 @@ func (l *lexer) MatchSingle(char byte, token int) bool {
 
 func (l *lexer) Match(str []byte, token int) bool {
 
-	if l.pos+len(str)-1 > l.instructionLen {
+	if l.pos+len(str) > l.instructionLen {
 		return false
 	}
 
@@ -110,3 +110,55 @@ func Test_lexer_MatchNumberToken(t *testing.T) {
 		})
 	}
 }
+
+func TestLexerParameterMarkers(t *testing.T) {
+	query := `SELECT id FROM foo WHERE a = ? AND b = $2 AND c = :c_1 AND d = @d2`
+
+	lexer := lexer{}
+	tokens, err := lexer.lex([]byte(query))
+	if err != nil {
+		t.Fatalf("Cannot lex <%s> string", query)
+	}
+
+	var markers []Token
+	for _, tok := range tokens {
+		if tok.Token == ArgToken || tok.Token == NamedArgToken {
+			markers = append(markers, tok)
+		}
+	}
+
+	expected := []Token{
+		{Token: ArgToken, Lexeme: "?"},
+		{Token: ArgToken, Lexeme: "2"},
+		{Token: NamedArgToken, Lexeme: "c_1"},
+		{Token: NamedArgToken, Lexeme: "d2"},
+	}
+	if len(markers) != len(expected) {
+		t.Fatalf("expected %d markers, got %v", len(expected), markers)
+	}
+	for i := range expected {
+		if markers[i].Token != expected[i].Token || markers[i].Lexeme != expected[i].Lexeme {
+			t.Fatalf("expected marker %v, got %v", expected[i], markers[i])
+		}
+	}
+}
+
+func TestLexerKeywordPrefixAtEnd(t *testing.T) {
+	queries := []string{
+		`SELECT id FROM foo WHERE a = fals`,
+		`SELECT id FROM ord`,
+		`SELECT id FROM foo WHERE b = tru`,
+	}
+
+	for _, query := range queries {
+		lexer := lexer{}
+		tokens, err := lexer.lex([]byte(query))
+		if err != nil {
+			t.Fatalf("Cannot lex <%s> string: %s", query, err)
+		}
+		last := tokens[len(tokens)-1]
+		if last.Token != StringToken {
+			t.Fatalf("expected <%s> to end with a string token, got %v", query, last)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package parser
+
+import (
+	"fmt"
+)
+
+// parseTableOptions parses MySQL table options following a table definition
+//
+//	ENGINE=InnoDB AUTO_INCREMENT=10 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin
+//
+// AUTO_INCREMENT is the first value of the autoincrement attribute, other options are ignored.
+//
+// |-> "AUTO_INCREMENT" (AutoincrementToken)
+//
+//	|-> first value (NumberToken)
+func (p *parser) parseTableOptions(tableDecl *Decl) error {
+	for p.index < p.tokenLen && p.isNot(SemicolonToken) {
+		switch {
+		case p.is(CommaToken, DefaultToken):
+			p.index++
+			continue
+		case p.is(AutoincrementToken):
+			autoincDecl := NewDecl(p.cur())
+			p.index++
+			if p.index < p.tokenLen && p.is(EqualityToken) {
+				p.index++
+			}
+			if p.index >= p.tokenLen || !p.is(NumberToken) {
+				return fmt.Errorf("AUTO_INCREMENT table option requires a number")
+			}
+			autoincDecl.Add(NewDecl(p.cur()))
+			tableDecl.Add(autoincDecl)
+			p.index++
+			continue
+		case p.is(CollateToken), p.isWord("engine"), p.isWord("charset"), p.isWord("comment"), p.isWord("row_format"):
+			p.index++
+		case p.isWord("character"):
+			// CHARACTER SET
+			if _, err := p.isNext(SetToken); err != nil {
+				return err
+			}
+			p.index += 2
+		default:
+			return fmt.Errorf("Syntax error near %s, unknown table option", p.cur().Lexeme)
+		}
+
+		// option value, such as =InnoDB or 'a comment'
+		if p.index < p.tokenLen && p.is(EqualityToken) {
+			p.index++
+		}
+		if p.index >= p.tokenLen {
+			return fmt.Errorf("Unexpected end, table option requires a value")
+		}
+		if p.is(SimpleQuoteToken) {
+			p.index += 3
+			continue
+		}
+		if !p.is(StringToken, NumberToken) {
+			return p.syntaxError()
+		}
+		p.index++
+	}
+
+	return nil
+}
+
+// parseIndexDefinition parses a MySQL index definition of the table definition of nameTable
+//
+//	UNIQUE [KEY|INDEX] [name] (col)
+//	KEY|INDEX [name] (col1, col2)
+//
+// A unique key is returned as CONSTRAINT name UNIQUE (col), or UNIQUE (col) without name.
+// Other keys are returned as the AST of CREATE INDEX name ON table (col1, col2), named
+// after their first column without name.
+func (p *parser) parseIndexDefinition(nameTable *Decl) (*Decl, error) {
+	var uDecl *Decl
+	if p.is(UniqueToken) {
+		uDecl = NewDecl(p.cur())
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.is(KeyToken, IndexToken) {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+
+	var nameDecl *Decl
+	if !p.is(BracketOpeningToken) {
+		d, err := p.parseQuotedToken()
+		if err != nil {
+			return nil, err
+		}
+		nameDecl = d
+	}
+
+	if _, err := p.consumeToken(BracketOpeningToken); err != nil {
+		return nil, err
+	}
+	var cols []*Decl
+	for {
+		d, err := p.parseQuotedToken()
+		if err != nil {
+			return nil, err
+		}
+		cols = append(cols, d)
+		d, err = p.consumeToken(CommaToken, BracketClosingToken)
+		if err != nil {
+			return nil, err
+		}
+		if d.Token == BracketClosingToken {
+			break
+		}
+	}
+
+	if uDecl != nil {
+		uDecl.Decl = cols
+		if nameDecl == nil {
+			return uDecl, nil
+		}
+		cDecl := &Decl{Token: ConstraintToken, Lexeme: "constraint"}
+		cDecl.Add(nameDecl)
+		cDecl.Add(uDecl)
+		return cDecl, nil
+	}
+
+	if nameDecl == nil {
+		nameDecl = &Decl{Token: StringToken, Lexeme: cols[0].Lexeme}
+	}
+	indexDecl := &Decl{Token: IndexToken, Lexeme: "index"}
+	indexDecl.Add(nameDecl)
+	indexDecl.Add(&Decl{Token: TableToken, Lexeme: nameTable.Lexeme, Decl: nameTable.Decl})
+	for _, c := range cols {
+		indexDecl.Add(c)
+	}
+	return indexDecl, nil
+}
+
+// parseOnDuplicateKey parses a MySQL ON DUPLICATE KEY UPDATE clause into the
+// AST of ON CONFLICT DO UPDATE without conflict target
+//
+//	ON DUPLICATE KEY UPDATE col = value, col = VALUES(col), n = n + VALUES(n)
+//
+// VALUES(col) is the value col would have been inserted with, as excluded.col.
+func (p *parser) parseOnDuplicateKey() (*Decl, error) {
+	onDecl, err := p.consumeToken(OnToken)
+	if err != nil {
+		return nil, err
+	}
+	if !p.isWord("duplicate") {
+		return nil, p.syntaxError()
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if _, err := p.consumeToken(KeyToken); err != nil {
+		return nil, err
+	}
+
+	updateDecl, err := p.consumeToken(UpdateToken)
+	if err != nil {
+		return nil, err
+	}
+	conflictDecl := &Decl{Token: ConflictToken, Lexeme: "conflict"}
+	doDecl := &Decl{Token: DoToken, Lexeme: "do"}
+	setDecl := &Decl{Token: SetToken, Lexeme: "set"}
+	onDecl.Add(conflictDecl)
+	onDecl.Add(doDecl)
+	doDecl.Add(updateDecl)
+	updateDecl.Add(setDecl)
+
+	if err := p.parseConflictAssignments(setDecl); err != nil {
+		return nil, err
+	}
+
+	return onDecl, nil
+}
+
+// parseInsertedValue parses VALUES(col) of ON DUPLICATE KEY UPDATE clause,
+// returned as excluded.col
+func (p *parser) parseInsertedValue() (*Decl, error) {
+	if _, err := p.consumeToken(ValuesToken); err != nil {
+		return nil, err
+	}
+	if _, err := p.consumeToken(BracketOpeningToken); err != nil {
+		return nil, err
+	}
+	attrDecl, err := p.parseQuotedToken()
+	if err != nil {
+		return nil, err
+	}
+	if !p.is(BracketClosingToken) {
+		return nil, p.syntaxError()
+	}
+	p.next()
+
+	attrDecl.Add(&Decl{Token: StringToken, Lexeme: "excluded"})
+	return attrDecl, nil
+}
+
+// parseLastInsertID parses MySQL LAST_INSERT_ID() function.
+// The parser is left on the closing bracket.
+func (p *parser) parseLastInsertID() (*Decl, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if !p.is(BracketOpeningToken) {
+		return nil, p.syntaxError()
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if !p.is(BracketClosingToken) {
+		return nil, p.syntaxError()
+	}
+
+	return &Decl{Token: LastInsertIDToken, Lexeme: "last_insert_id()"}, nil
+}
@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestParserMySQLDialect(t *testing.T) {
+	queries := []string{
+		"CREATE TABLE `user` (`id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY, `active` TINYINT(1) NOT NULL DEFAULT 1, `created_at` DATETIME NULL) ENGINE=InnoDB AUTO_INCREMENT=10 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin",
+		"CREATE TABLE t (id INT) ENGINE = InnoDB, CHARACTER SET utf8 COMMENT 'a table';",
+		"INSERT INTO `user` (`name`, `hits`) VALUES ('foo', 1) ON DUPLICATE KEY UPDATE hits = VALUES(hits), name = 'bar'",
+		"INSERT INTO `user` (`name`, `hits`) VALUES ('foo', 1) ON DUPLICATE KEY UPDATE hits = hits + VALUES(hits)",
+		"SELECT * FROM user LIMIT 10, 20",
+		"SELECT * FROM user ORDER BY id LIMIT ?, ?",
+		"SELECT LAST_INSERT_ID()",
+		"CREATE TABLE t (id INT, email TEXT, team TEXT, PRIMARY KEY (id), UNIQUE KEY uniq_email (email), KEY idx_team (team)) ENGINE=InnoDB",
+		"CREATE TABLE t (id INT, email TEXT, team TEXT, UNIQUE INDEX (email), INDEX idx_team_email (team, email), UNIQUE (id))",
+	}
+
+	for _, q := range queries {
+		if _, err := ParseInstructionWithDialect(q, DialectMySQL); err != nil {
+			t.Fatalf("Cannot parse '%s' with mysql dialect: %s", q, err)
+		}
+	}
+
+	for _, q := range []string{queries[0], queries[3]} {
+		if _, err := ParseInstructionWithDialect(q, DialectPostgres); err == nil {
+			t.Fatalf("Expected error parsing '%s' with postgres dialect", q)
+		}
+	}
+
+	if _, err := ParseInstructionWithDialect("SELECT 1", "oracle"); err == nil {
+		t.Fatalf("Expected error with unknown dialect")
+	}
+}
+
+func TestParserMySQLLimitOffset(t *testing.T) {
+	instructions, err := ParseInstructionWithDialect("SELECT * FROM user LIMIT 10, 20", DialectMySQL)
+	if err != nil {
+		t.Fatalf("Cannot parse: %s", err)
+	}
+
+	limit, ok := instructions[0].Decls[0].Has(LimitToken)
+	if !ok || len(limit.Decl) != 1 || limit.Decl[0].Lexeme != "20" {
+		t.Fatalf("Expected LIMIT 20")
+	}
+	offset, ok := instructions[0].Decls[0].Has(OffsetToken)
+	if !ok || len(offset.Decl) != 1 || offset.Decl[0].Lexeme != "10" {
+		t.Fatalf("Expected OFFSET 10")
+	}
+}
+
+func TestParserUpdateWithoutWhere(t *testing.T) {
+	parse(`UPDATE account SET email = 'foo@bar.com'`, 1, t)
+	parse(`UPDATE account SET email = 'foo@bar.com', age = 2; SELECT * FROM account`, 2, t)
+	parse(`UPDATE account SET age = 2 RETURNING id`, 1, t)
+}
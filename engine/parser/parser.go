@@ -5,6 +5,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/proullon/ramsql/engine/log"
 )
@@ -15,6 +16,7 @@ type parser struct {
 	index    int
 	tokenLen int
 	tokens   []Token
+	dialect  string
 }
 
 // Decl structure is the node to statement declaration tree
@@ -57,6 +59,8 @@ func (d Decl) Has(t int) (*Decl, bool) {
 // Instruction define a valid SQL statement
 type Instruction struct {
 	Decls []*Decl
+	// Text is the statement as written, empty if it cannot be told apart from other statements of the query
+	Text string
 }
 
 // PrettyPrint prints instruction's declarations on console with indentation
@@ -102,7 +106,7 @@ func (p *parser) parse(tokens []Token) ([]Instruction, error) {
 		// Now,
 		// Create a logical tree of all tokens
 		// We start with first order query
-		// CREATE, SELECT, INSERT, UPDATE, DELETE, TRUNCATE, DROP, EXPLAIN
+		// CREATE, SELECT, INSERT, UPDATE, DELETE, TRUNCATE, DROP, ALTER, EXPLAIN, SET, SHOW, RESET, PRAGMA
 		switch tokens[p.index].Token {
 		case CreateToken:
 			i, err := p.parseCreate(tokens)
@@ -147,12 +151,43 @@ func (p *parser) parse(tokens []Token) ([]Instruction, error) {
 				return nil, err
 			}
 			p.i = append(p.i, *i)
+		case GenerateToken:
+			i, err := p.parseGenerate()
+			if err != nil {
+				return nil, err
+			}
+			p.i = append(p.i, *i)
 		case DropToken:
 			i, err := p.parseDrop(tokens)
 			if err != nil {
 				return nil, err
 			}
 			p.i = append(p.i, *i)
+		case SetToken:
+			i, err := p.parseSet()
+			if err != nil {
+				return nil, err
+			}
+			p.i = append(p.i, *i)
+		case StringToken:
+			var i *Instruction
+			var err error
+			switch {
+			case p.isWord("show"):
+				i, err = p.parseShow()
+			case p.isWord("reset"):
+				i, err = p.parseReset()
+			case p.isWord("alter"):
+				i, err = p.parseAlter()
+			case p.isWord("pragma") && p.dialect == DialectSQLite:
+				i, err = p.parsePragma()
+			default:
+				return nil, fmt.Errorf("Parsing error near <%s>", tokens[p.index].Lexeme)
+			}
+			if err != nil {
+				return nil, err
+			}
+			p.i = append(p.i, *i)
 		case ExplainToken:
 			break
 		case GrantToken:
@@ -194,7 +229,7 @@ func (p *parser) parseUpdate() (*Instruction, error) {
 
 	// should be a list of equality
 	gotClause := false
-	for p.tokens[p.index].Token != WhereToken {
+	for p.isNot(WhereToken, ReturningToken, SemicolonToken) {
 
 		if !p.hasNext() && gotClause {
 			break
@@ -211,20 +246,90 @@ func (p *parser) parseUpdate() (*Instruction, error) {
 		gotClause = true
 	}
 
-	err = p.parseWhere(updateDecl)
-	if err != nil {
+	if !p.is(WhereToken) {
+		// no WHERE clause, all rows are updated
+		addImplicitWhereAll(updateDecl)
+	} else if err = p.parseWhere(updateDecl); err != nil {
 		return nil, err
 	}
 
+	if p.is(ReturningToken) {
+		if err = p.parseReturning(updateDecl); err != nil {
+			return nil, err
+		}
+	}
+
 	return i, nil
 }
 
+// parseReturning parses a RETURNING clause, a list of
+// *
+// attribute
+// expression AS alias
+func (p *parser) parseReturning(decl *Decl) error {
+	retDecl, err := p.consumeToken(ReturningToken)
+	if err != nil {
+		return err
+	}
+	decl.Add(retDecl)
+
+	for {
+		var attrDecl *Decl
+		switch {
+		case p.is(StarToken):
+			attrDecl, err = p.consumeToken(StarToken)
+		case p.is(NumberToken):
+			attrDecl, err = p.consumeToken(NumberToken)
+			if err == nil {
+				attrDecl, err = p.parseExpression(attrDecl)
+			}
+		default:
+			attrDecl, err = p.parseAttribute()
+			if err == nil {
+				attrDecl, err = p.parseExpression(attrDecl)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		retDecl.Add(attrDecl)
+
+		if p.is(AsToken) {
+			asDecl, err := p.consumeToken(AsToken)
+			if err != nil {
+				return err
+			}
+			aliasDecl, err := p.consumeToken(StringToken)
+			if err != nil {
+				return err
+			}
+			asDecl.Add(aliasDecl)
+			attrDecl.Add(asDecl)
+		}
+
+		if !p.is(CommaToken) || !p.hasNext() {
+			return nil
+		}
+		p.next()
+	}
+}
+
 func (p *parser) parseType() (*Decl, error) {
 	typeDecl, err := p.consumeToken(FloatToken, DateToken, DecimalToken, NumberToken, StringToken)
 	if err != nil {
 		return nil, err
 	}
 
+	// PostgreSQL two words types, as written by ent or pg_dump
+	switch {
+	case strings.EqualFold(typeDecl.Lexeme, "character") && p.isWord("varying"):
+		typeDecl.Lexeme = "varchar"
+		p.index++
+	case strings.EqualFold(typeDecl.Lexeme, "double") && p.isWord("precision"):
+		typeDecl.Lexeme = "float"
+		p.index++
+	}
+
 	// Maybe a complex type
 	if p.is(BracketOpeningToken) {
 		_, err = p.consumeToken(BracketOpeningToken)
@@ -236,12 +341,48 @@ func (p *parser) parseType() (*Decl, error) {
 			return nil, err
 		}
 		typeDecl.Add(sizeDecl)
+		// DECIMAL(precision, scale)
+		if p.is(CommaToken) {
+			if _, err = p.consumeToken(CommaToken); err != nil {
+				return nil, err
+			}
+			scaleDecl, err := p.consumeToken(NumberToken)
+			if err != nil {
+				return nil, err
+			}
+			typeDecl.Add(scaleDecl)
+		}
 		_, err = p.consumeToken(BracketClosingToken)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	// PostgreSQL arrays, such as INT[] or VARCHAR(255)[]
+	if p.is(SquareBracketOpeningToken) {
+		arrayDecl, err := p.consumeToken(SquareBracketOpeningToken)
+		if err != nil {
+			return nil, err
+		}
+		// declared array size is ignored, as PostgreSQL does
+		if p.is(NumberToken) {
+			p.index++
+		}
+		if _, err = p.consumeToken(SquareBracketClosingToken); err != nil {
+			return nil, err
+		}
+		if p.is(SquareBracketOpeningToken) {
+			return nil, fmt.Errorf("multidimensional arrays are not supported")
+		}
+		typeDecl.Add(arrayDecl)
+	}
+
+	// MySQL booleans are TINYINT(1)
+	if p.dialect == DialectMySQL && strings.EqualFold(typeDecl.Lexeme, "tinyint") &&
+		len(typeDecl.Decl) == 1 && typeDecl.Decl[0].Lexeme == "1" {
+		typeDecl = &Decl{Token: StringToken, Lexeme: "boolean"}
+	}
+
 	return typeDecl, nil
 }
 
@@ -258,8 +399,13 @@ func (p *parser) parseOrderBy(selectDecl *Decl) error {
 	}
 
 	for {
-		// parse attribute now
-		attrDecl, err := p.parseAttribute()
+		// parse attribute now, or parameter marker
+		var attrDecl *Decl
+		if p.is(ArgToken, NamedArgToken) {
+			attrDecl, err = p.consumeToken(ArgToken, NamedArgToken)
+		} else {
+			attrDecl, err = p.parseAttribute()
+		}
 		if err != nil {
 			return err
 		}
@@ -285,14 +431,23 @@ func (p *parser) parseOrderBy(selectDecl *Decl) error {
 	return nil
 }
 
-// parseBuiltinFunc looks for COUNT,MAX,MIN
+// parseBuiltinFunc looks for COUNT, SUM, MIN, MAX, AVG and CURRENT_SCHEMA
+//
+//	|-> COUNT (CountToken) or SUM, MIN, MAX, AVG, ARRAY_AGG (AggregateToken)
+//	    |-> attribute
+//	    |-> DISTINCT (DistinctToken), optional
 func (p *parser) parseBuiltinFunc() (*Decl, error) {
 	var d *Decl
 	var err error
 
-	// COUNT(attribute)
-	if p.is(CountToken) {
-		d, err = p.consumeToken(CountToken)
+	// COUNT([DISTINCT] attribute)
+	if p.is(CountToken) || p.isAggregate() {
+		if p.is(CountToken) {
+			d, err = p.consumeToken(CountToken)
+		} else {
+			d = &Decl{Token: AggregateToken, Lexeme: strings.ToLower(p.cur().Lexeme)}
+			err = p.next()
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -301,12 +456,28 @@ func (p *parser) parseBuiltinFunc() (*Decl, error) {
 		if err != nil {
 			return nil, err
 		}
-		// Attribute
-		attr, err := p.parseAttribute()
+		var distinctDecl *Decl
+		if p.is(DistinctToken) {
+			distinctDecl, err = p.consumeToken(DistinctToken)
+			if err != nil {
+				return nil, err
+			}
+		}
+		// Attribute, COUNT(1) counts all rows as COUNT(*)
+		var attr *Decl
+		if d.Token == CountToken && p.is(NumberToken) {
+			_, err = p.consumeToken(NumberToken)
+			attr = &Decl{Token: StarToken, Lexeme: "*"}
+		} else {
+			attr, err = p.parseAttribute()
+		}
 		if err != nil {
 			return nil, err
 		}
 		d.Add(attr)
+		if distinctDecl != nil {
+			d.Add(distinctDecl)
+		}
 		// Bracket
 		_, err = p.consumeToken(BracketClosingToken)
 		if err != nil {
@@ -327,6 +498,128 @@ func (p *parser) parseBuiltinFunc() (*Decl, error) {
 	return d, nil
 }
 
+// isAggregate reports whether current token starts a SUM, MIN, MAX, AVG, ARRAY_AGG or JSONB_AGG call
+func (p *parser) isAggregate() bool {
+	if !p.isWord("sum") && !p.isWord("min") && !p.isWord("max") && !p.isWord("avg") && !p.isWord("array_agg") && !p.isWord("jsonb_agg") && !p.isWord("json_agg") {
+		return false
+	}
+	_, err := p.isNext(BracketOpeningToken)
+	return err == nil
+}
+
+// advisoryLockFuncs are the PostgreSQL advisory lock functions
+var advisoryLockFuncs = []string{
+	"pg_advisory_lock",
+	"pg_try_advisory_lock",
+	"pg_advisory_xact_lock",
+	"pg_try_advisory_xact_lock",
+	"pg_advisory_unlock",
+	"pg_advisory_unlock_all",
+}
+
+// isAdvisoryLock reports whether current token starts an advisory lock function call
+func (p *parser) isAdvisoryLock() bool {
+	for _, f := range advisoryLockFuncs {
+		if p.isWord(f) {
+			_, err := p.isNext(BracketOpeningToken)
+			return err == nil
+		}
+	}
+	return false
+}
+
+// parseAdvisoryLock parses advisory lock functions, with a bigint key or two integer keys.
+// The parser is left on the closing bracket.
+//
+//	|-> pg_advisory_lock (AdvisoryLockToken)
+//	    |-> key (ArgToken or NumberToken)
+//	    |-> key (ArgToken or NumberToken), optional
+func (p *parser) parseAdvisoryLock() (*Decl, error) {
+	d := &Decl{Token: AdvisoryLockToken, Lexeme: strings.ToLower(p.cur().Lexeme)}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if !p.is(BracketOpeningToken) {
+		return nil, p.syntaxError()
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	for !p.is(BracketClosingToken) {
+		if len(d.Decl) == 2 {
+			return nil, p.syntaxError()
+		}
+		keyDecl, err := p.consumeToken(ArgToken, NamedArgToken, NumberToken)
+		if err != nil {
+			return nil, err
+		}
+		d.Add(keyDecl)
+		if p.is(CommaToken) {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	n := len(d.Decl)
+	if (d.Lexeme == "pg_advisory_unlock_all") != (n == 0) {
+		return nil, fmt.Errorf("wrong number of arguments to %s", d.Lexeme)
+	}
+	return d, nil
+}
+
+// isSubquery reports whether current token opens a bracketed SELECT
+func (p *parser) isSubquery() bool {
+	if !p.is(BracketOpeningToken) {
+		return false
+	}
+	_, err := p.isNext(SelectToken)
+	return err == nil
+}
+
+// parseSubquery parses a SELECT enclosed in brackets, as in
+// IN (SELECT ...), EXISTS (SELECT ...) or JOIN (SELECT ...) AS alias
+func (p *parser) parseSubquery() (*Decl, error) {
+	if _, err := p.consumeToken(BracketOpeningToken); err != nil {
+		return nil, err
+	}
+
+	start, end, depth := p.index, -1, 0
+	for i := start; i < len(p.tokens) && end == -1; i++ {
+		switch p.tokens[i].Token {
+		case BracketOpeningToken:
+			depth++
+		case BracketClosingToken:
+			if depth == 0 {
+				end = i
+			}
+			depth--
+		}
+	}
+	if end == -1 {
+		return nil, fmt.Errorf("unmatched bracket in subquery")
+	}
+
+	tokens := p.tokens[start:end]
+	sub := &parser{
+		tokens:   tokens,
+		tokenLen: len(tokens),
+		dialect:  p.dialect,
+	}
+	i, err := sub.parseSelect(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	p.index = end
+	if _, err := p.consumeToken(BracketClosingToken); err != nil {
+		return nil, err
+	}
+
+	return i.Decls[0], nil
+}
+
 // parseTableName parse a table of the form
 // schema.table
 // "schema".table
@@ -549,6 +842,28 @@ func (p *parser) parseAttribution() (*Decl, error) {
 			return nil, err
 		}
 		attributeDecl.Add(nullDecl)
+	} else if p.isArrayConstructor() {
+		arrayDecl, err := p.parseArrayConstructor()
+		if err != nil {
+			return nil, err
+		}
+		attributeDecl.Add(arrayDecl)
+	} else if p.isJSONFunction() {
+		funcDecl, err := p.parseJSONFunction()
+		if err != nil {
+			return nil, err
+		}
+		attributeDecl.Add(funcDecl)
+	} else if p.isWord("coalesce") || p.isAttributeExpression() {
+		exprDecl, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		exprDecl, err = p.parseArithmetic(exprDecl)
+		if err != nil {
+			return nil, err
+		}
+		attributeDecl.Add(exprDecl)
 	} else {
 		valueDecl, err := p.parseValue()
 		if err != nil {
@@ -566,6 +881,16 @@ func (p *parser) parseIn() (*Decl, error) {
 		return nil, err
 	}
 
+	// IN (SELECT ...)
+	if p.isSubquery() {
+		subDecl, err := p.parseSubquery()
+		if err != nil {
+			return nil, err
+		}
+		inDecl.Add(subDecl)
+		return inDecl, nil
+	}
+
 	// bracket opening
 	_, err = p.consumeToken(BracketOpeningToken)
 	if err != nil {
@@ -746,6 +1071,100 @@ func (p *parser) parseExpression(leftDecl *Decl) (*Decl, error) {
 	return leftDecl, nil
 }
 
+// isAttributeExpression reports whether current token starts a qualified attribute,
+// as "users"."age", or an attribute followed by an arithmetic operator, as age + 1
+func (p *parser) isAttributeExpression() bool {
+	name, after := p.index, p.index+1
+	if p.is(DoubleQuoteToken, BacktickToken) {
+		name, after = p.index+1, p.index+3
+	}
+	if after >= len(p.tokens) || p.tokens[name].Token != StringToken {
+		return false
+	}
+
+	switch p.tokens[after].Token {
+	case PeriodToken, StarToken, PlusToken, MinusToken, DivideToken:
+		return true
+	}
+	return false
+}
+
+// parseOperand parses an operand of an arithmetic expression
+//
+//	|-> COALESCE (CoalesceToken)
+//	    |-> operand
+//	    |-> operand
+//	|-> attribute
+//	|-> 'literal' (SimpleQuoteToken)
+//	|-> parameter, number or NULL
+func (p *parser) parseOperand() (*Decl, error) {
+	switch {
+	case p.isWord("coalesce"):
+		coalesceDecl := &Decl{Token: CoalesceToken, Lexeme: "coalesce"}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if _, err := p.consumeToken(BracketOpeningToken); err != nil {
+			return nil, err
+		}
+		for {
+			operandDecl, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			operandDecl, err = p.parseArithmetic(operandDecl)
+			if err != nil {
+				return nil, err
+			}
+			coalesceDecl.Add(operandDecl)
+			if !p.is(CommaToken) {
+				break
+			}
+			if _, err := p.consumeToken(CommaToken); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := p.consumeToken(BracketClosingToken); err != nil {
+			return nil, err
+		}
+		return coalesceDecl, nil
+	case p.is(ArgToken, NamedArgToken, NumberToken, NullToken):
+		return p.consumeToken(ArgToken, NamedArgToken, NumberToken, NullToken)
+	case p.is(ValuesToken) && p.dialect == DialectMySQL:
+		return p.parseInsertedValue()
+	case p.is(SimpleQuoteToken):
+		valueDecl, err := p.parseStringLiteral()
+		if err != nil {
+			return nil, err
+		}
+		valueDecl.Token = SimpleQuoteToken
+		return valueDecl, nil
+	}
+
+	return p.parseAttribute()
+}
+
+// parseArithmetic adds an arithmetic operator and its right operand to leftDecl, if any
+func (p *parser) parseArithmetic(leftDecl *Decl) (*Decl, error) {
+	if !p.is(StarToken, PlusToken, MinusToken, DivideToken) {
+		return leftDecl, nil
+	}
+
+	operatorDecl, err := p.consumeToken(p.cur().Token)
+	if err != nil {
+		return nil, err
+	}
+	leftDecl.Add(operatorDecl)
+
+	rightDecl, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	leftDecl.Add(rightDecl)
+
+	return leftDecl, nil
+}
+
 func (p *parser) parseStringLiteral() (*Decl, error) {
 	singleQuoted := p.is(SimpleQuoteToken)
 	_, err := p.consumeToken(SimpleQuoteToken, DoubleQuoteToken)
@@ -768,14 +1187,23 @@ func (p *parser) parseStringLiteral() (*Decl, error) {
 
 // parseJoin parses the JOIN keywords and all its condition
 // JOIN user_addresses ON address.id=user_addresses.address_id
+// JOIN (SELECT ...) AS t1 ON address.id=t1.address_id
 func (p *parser) parseJoin() (*Decl, error) {
 	joinDecl, err := p.consumeToken(JoinToken)
 	if err != nil {
 		return nil, err
 	}
 
-	// TABLE NAME
-	tableDecl, err := p.parseAttribute()
+	// TABLE NAME, or derived table
+	var tableDecl *Decl
+	if p.isSubquery() {
+		tableDecl, err = p.parseSubquery()
+		if err == nil && !p.is(AsToken, StringToken) {
+			err = fmt.Errorf("subquery in JOIN must have an alias")
+		}
+	} else {
+		tableDecl, err = p.parseAttribute()
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -787,7 +1215,7 @@ func (p *parser) parseJoin() (*Decl, error) {
 		if err != nil {
 			return nil, err
 		}
-		aliasDecl, err := p.consumeToken(StringToken)
+		aliasDecl, err := p.parseQuotedToken()
 		if err != nil {
 			return nil, err
 		}
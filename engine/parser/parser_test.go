@@ -309,10 +309,37 @@ func TestIndex(t *testing.T) {
 	}
 }
 
+func TestAlter(t *testing.T) {
+	queries := []string{
+		`ALTER TABLE account ADD nickname VARCHAR(32) NOT NULL DEFAULT 'anonymous'`,
+		`ALTER TABLE "public"."account" ADD COLUMN IF NOT EXISTS "score" decimal`,
+		`ALTER TABLE account ADD CONSTRAINT fk_account_team FOREIGN KEY (team_id) REFERENCES team(id) ON DELETE CASCADE`,
+		`ALTER TABLE account ADD CONSTRAINT uni_account_login UNIQUE (login)`,
+		`ALTER TABLE IF EXISTS ONLY account DROP COLUMN IF EXISTS nickname CASCADE`,
+		`ALTER TABLE account DROP CONSTRAINT uni_account_login`,
+		`ALTER TABLE account RENAME COLUMN code TO reference`,
+		`ALTER TABLE account RENAME TO user_account`,
+		`ALTER TABLE "account" ALTER COLUMN "age" TYPE bigint USING "age"::bigint`,
+		`ALTER TABLE account ALTER age SET DATA TYPE bigint, ALTER name SET NOT NULL`,
+		`ALTER TABLE account ALTER COLUMN name DROP NOT NULL, ALTER COLUMN age DROP DEFAULT`,
+		`ALTER TABLE account ALTER COLUMN age SET DEFAULT 18`,
+		`ALTER INDEX IF EXISTS idx_account_code RENAME TO idx_code`,
+		`DROP INDEX IF EXISTS "idx_account_code" CASCADE`,
+	}
+
+	for _, q := range queries {
+		parse(q, 1, t)
+	}
+}
+
 func TestReturning(t *testing.T) {
 	queries := []string{
 		`INSERT INTO test (foo, bar) VALUES ('foo', 'bar') RETURNING id`,
 		`INSERT INTO test (foo, bar) VALUES ('foo', 'bar') RETURNING "id"`,
+		`UPDATE test SET foo = 'bar' WHERE id = 1 RETURNING *`,
+		`UPDATE test SET foo = 'bar' RETURNING id, price * 2 AS double, "foo"`,
+		`DELETE FROM test WHERE id = $1 RETURNING test.id AS deleted_id, 1`,
+		`DELETE FROM test RETURNING *`,
 	}
 
 	for _, q := range queries {
@@ -489,3 +516,153 @@ func parse(query string, instructionNumber int, t *testing.T) []Instruction {
 
 	return instructions
 }
+
+func TestParserGenerate(t *testing.T) {
+	parse(`GENERATE 100 ROWS FOR account`, 1, t)
+	parse(`GENERATE 100 ROWS FOR foo.account SEED 42;`, 1, t)
+	parse(`generate 10 rows for "account" seed 7; GENERATE 5 ROWS FOR user`, 2, t)
+
+	if _, err := ParseInstruction(`GENERATE ROWS FOR account`); err == nil {
+		t.Fatalf("expected error with missing row count")
+	}
+}
+
+func TestParserSession(t *testing.T) {
+	parse(`SET search_path TO foo, "$user", public`, 1, t)
+	parse(`SET LOCAL statement_timeout = '5s'; SHOW statement_timeout`, 2, t)
+	parse(`SET TIME ZONE 'Europe/Paris'; RESET TIME ZONE; RESET ALL`, 3, t)
+	parse(`set session application_name = default`, 1, t)
+
+	i := parse(`SET search_path TO foo, "$user", public`, 1, t)
+	name := i[0].Decls[0].Decl[0]
+	if name.Lexeme != "search_path" || len(name.Decl) != 3 || name.Decl[1].Lexeme != "$user" {
+		t.Fatalf("unexpected SET tree")
+	}
+
+	i = parse(`SHOW TIME ZONE`, 1, t)
+	if i[0].Decls[0].Token != ShowToken || i[0].Decls[0].Decl[0].Lexeme != "timezone" {
+		t.Fatalf("unexpected SHOW tree")
+	}
+
+	if _, err := ParseInstruction(`SET search_path`); err == nil {
+		t.Fatalf("expected error with missing value")
+	}
+	if _, err := ParseInstruction(`SHOW timezone extra`); err == nil {
+		t.Fatalf("expected error with trailing tokens")
+	}
+}
+
+func TestSubqueries(t *testing.T) {
+	queries := []string{
+		`SELECT "id" FROM "users" WHERE "users"."id" IN (SELECT "user_id" FROM "group_users" WHERE "group_id" = $1)`,
+		`SELECT "id" FROM "users" WHERE EXISTS (SELECT "pets"."owner_id" FROM "pets" WHERE "users"."id" = "pets"."owner_id")`,
+		`SELECT "id" FROM "users" WHERE NOT EXISTS (SELECT * FROM "pets") AND "age" > 1`,
+		`SELECT "users"."name" FROM "users" JOIN (SELECT "user_id" FROM "group_users") AS "t1" ON "users"."id" = "t1"."user_id"`,
+	}
+
+	for _, q := range queries {
+		parse(q, 1, t)
+	}
+
+	if _, err := ParseInstruction(`SELECT * FROM users JOIN (SELECT id FROM pets) ON users.id = pets.id`); err == nil {
+		t.Fatalf("expected error with missing subquery alias")
+	}
+}
+
+func TestAggregatesAndExpressions(t *testing.T) {
+	queries := []string{
+		`SELECT SUM("users"."age") FROM "users"`,
+		`SELECT min(age), max(age), avg(age) FROM users`,
+		`SELECT COUNT(DISTINCT "users"."id") FROM "users"`,
+		`SELECT * FROM users WHERE name ILIKE $1`,
+		`UPDATE "users" SET "age" = COALESCE("users"."age", 0) + $1 WHERE "id" = $2`,
+		"-- name: GetAuthor :one\nSELECT id, name FROM authors /* by id */ WHERE id = $1 LIMIT 1",
+	}
+
+	for _, q := range queries {
+		parse(q, 1, t)
+	}
+}
+
+func TestMigrationStatements(t *testing.T) {
+	queries := []string{
+		`SELECT pg_advisory_lock($1)`,
+		`SELECT pg_try_advisory_lock(1, 2)`,
+		`SELECT pg_advisory_unlock_all()`,
+		`SELECT COUNT(1) FROM information_schema.tables WHERE table_schema = $1 AND table_name = $2 LIMIT 1`,
+		`SELECT table_name FROM information_schema.tables WHERE table_schema=(SELECT current_schema()) AND table_type='BASE TABLE'`,
+		`TRUNCATE "public"."schema_migrations"`,
+		`TRUNCATE TABLE users, posts CASCADE`,
+	}
+
+	for _, q := range queries {
+		parse(q, 1, t)
+	}
+
+	if _, err := ParseInstruction(`SELECT pg_advisory_lock()`); err == nil {
+		t.Fatalf("expected error on advisory lock without key")
+	}
+	if _, err := ParseInstruction(`SELECT pg_advisory_lock(1, 2, 3)`); err == nil {
+		t.Fatalf("expected error on advisory lock with 3 keys")
+	}
+}
+
+func TestArrays(t *testing.T) {
+	queries := []string{
+		`CREATE TABLE posts (id BIGSERIAL PRIMARY KEY, tags TEXT[], scores INT[3])`,
+		`INSERT INTO posts (tags, scores) VALUES ('{go,sql}', ARRAY[1, 2])`,
+		`UPDATE posts SET tags = ARRAY['go'] WHERE id = 1`,
+		`SELECT * FROM posts WHERE id = ANY($1)`,
+		`SELECT * FROM posts WHERE "posts"."id" <> ALL('{1,2}')`,
+		`SELECT * FROM posts WHERE 'go' = ANY(tags) AND id = ANY(SELECT id FROM posts)`,
+		`SELECT * FROM posts WHERE tags @> ARRAY['go'] OR tags && $1 OR tags <@ '{go,sql}'`,
+		`SELECT array_length(tags, 1), array_agg(id) FROM posts`,
+		`SELECT * FROM unnest($1)`,
+		`SELECT t.x FROM unnest(ARRAY[1, 2]) AS t(x) WHERE t.x > 1`,
+	}
+
+	for _, q := range queries {
+		parse(q, 1, t)
+	}
+
+	if _, err := ParseInstruction(`CREATE TABLE m (x INT[][])`); err == nil {
+		t.Fatalf("expected error on multidimensional array")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	queries := []string{
+		`CREATE TABLE event (id BIGSERIAL PRIMARY KEY, payload JSONB, raw JSON)`,
+		`INSERT INTO event (payload) VALUES (jsonb_build_object('type', 'signup', 'n', 1))`,
+		`UPDATE event SET payload = jsonb_set(payload, '{user,plan}', '"pro"', false) WHERE id = 1`,
+		`SELECT payload->'user'->>'plan', payload#>'{tags,0}', payload#>>$1 FROM event`,
+		`SELECT * FROM event WHERE payload->>'type' = $1 AND e.payload->'user'->'id' IN (1, 2)`,
+		`SELECT * FROM event WHERE payload @> '{"type": "signup"}' OR payload ? 'tags' OR payload->'user' ? $1`,
+		`SELECT * FROM event WHERE payload->'user' IS NOT NULL`,
+		`SELECT jsonb_agg(payload), json_agg(id) FROM event`,
+		`SELECT value->>'name' FROM json_array_elements($1)`,
+		`SELECT t.e FROM jsonb_array_elements('[1, 2]') AS t(e)`,
+	}
+
+	for _, q := range queries {
+		parse(q, 1, t)
+	}
+}
+
+func TestStatementText(t *testing.T) {
+	query := `INSERT INTO account (email) VALUES ('a;b'); ; SELECT * FROM account;`
+
+	instructions, err := ParseInstruction(query)
+	if err != nil {
+		t.Fatalf("cannot parse: %s", err)
+	}
+	if len(instructions) != 2 {
+		t.Fatalf("expected 2 instructions, got %d", len(instructions))
+	}
+	if instructions[0].Text != `INSERT INTO account (email) VALUES ('a;b')` {
+		t.Fatalf("unexpected 1st statement text: %s", instructions[0].Text)
+	}
+	if instructions[1].Text != `SELECT * FROM account` {
+		t.Fatalf("unexpected 2nd statement text: %s", instructions[1].Text)
+	}
+}
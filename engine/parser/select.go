@@ -49,12 +49,24 @@ func (p *parser) parseSelect(tokens []Token) (*Instruction, error) {
 	for {
 		var needsNext bool = false
 		switch {
-		case p.is(CountToken):
+		case p.is(CountToken), p.isAggregate():
 			attrDecl, err := p.parseBuiltinFunc()
 			if err != nil {
 				return nil, err
 			}
 			selectDecl.Add(attrDecl)
+		case p.isWord("array_length"):
+			attrDecl, err := p.parseArrayLength()
+			if err != nil {
+				return nil, err
+			}
+			selectDecl.Add(attrDecl)
+		case p.isJSONFunction():
+			attrDecl, err := p.parseJSONFunction()
+			if err != nil {
+				return nil, err
+			}
+			selectDecl.Add(attrDecl)
 		case p.is(CurrentSchemaToken):
 			// Handle CURRENT_SCHEMA() function
 			attrDecl := NewDecl(p.cur())
@@ -65,6 +77,20 @@ func (p *parser) parseSelect(tokens []Token) (*Instruction, error) {
 			attrDecl := NewDecl(p.cur())
 			selectDecl.Add(attrDecl)
 			needsNext = true
+		case p.isAdvisoryLock():
+			attrDecl, err := p.parseAdvisoryLock()
+			if err != nil {
+				return nil, err
+			}
+			selectDecl.Add(attrDecl)
+			needsNext = true
+		case p.dialect == DialectMySQL && p.isWord("last_insert_id"):
+			attrDecl, err := p.parseLastInsertID()
+			if err != nil {
+				return nil, err
+			}
+			selectDecl.Add(attrDecl)
+			needsNext = true
 		case p.is(NumberToken):
 			// Handle literal numbers in SELECT clause (may be part of arithmetic expression)
 			attrDecl := NewDecl(p.cur())
@@ -105,11 +131,20 @@ func (p *parser) parseSelect(tokens []Token) (*Instruction, error) {
 			attrDecl := NewDecl(p.cur())
 			selectDecl.Add(attrDecl)
 			needsNext = true
+		case p.is(ArgToken, NamedArgToken):
+			// Handle parameter markers in SELECT clause
+			attrDecl := NewDecl(p.cur())
+			selectDecl.Add(attrDecl)
+			needsNext = true
 		default:
 			attrDecl, err := p.parseAttribute()
 			if err != nil {
 				return nil, err
 			}
+			attrDecl, err = p.parseJSONOperators(attrDecl)
+			if err != nil {
+				return nil, err
+			}
 
 			// Check if this attribute is part of an expression
 			attrDecl, err = p.parseExpression(attrDecl)
@@ -170,7 +205,14 @@ func (p *parser) parseSelect(tokens []Token) (*Instruction, error) {
 		if err = p.next(); err != nil {
 			return nil, fmt.Errorf("Unexpected end. Syntax error near %v\n", tokens[p.index])
 		}
-		tableNameDecl, err := p.parseTableName()
+		var tableNameDecl *Decl
+		if p.isUnnest() {
+			tableNameDecl, err = p.parseUnnest()
+		} else if p.isJSONArrayElements() {
+			tableNameDecl, err = p.parseJSONArrayElements()
+		} else {
+			tableNameDecl, err = p.parseTableName()
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -258,6 +300,19 @@ func (p *parser) parseSelect(tokens []Token) (*Instruction, error) {
 			if err != nil {
 				return nil, err
 			}
+			// MySQL LIMIT offset, count
+			if p.dialect == DialectMySQL && p.is(CommaToken) {
+				offsetDecl := &Decl{Token: OffsetToken, Lexeme: "offset"}
+				offsetDecl.Add(numDecl)
+				selectDecl.Add(offsetDecl)
+				if err := p.next(); err != nil {
+					return nil, err
+				}
+				numDecl, err = p.consumeToken(NumberToken, ArgToken, NamedArgToken)
+				if err != nil {
+					return nil, err
+				}
+			}
 			limitDecl.Add(numDecl)
 		case OffsetToken:
 			offsetDecl, err := p.consumeToken(OffsetToken)
@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseSet parses a session setting assignment
+//
+//	SET [ SESSION | LOCAL ] name { TO | = } { value [, ...] | DEFAULT }
+//	SET [ SESSION | LOCAL ] TIME ZONE { value | DEFAULT }
+//
+// |-> "SET" (SetToken)
+//
+//	|-> "LOCAL" (LocalToken), optional
+//	|-> name (StringToken)
+//	    |-> value (StringToken) or DEFAULT (DefaultToken)
+func (p *parser) parseSet() (*Instruction, error) {
+	i := &Instruction{}
+
+	setDecl, err := p.consumeToken(SetToken)
+	if err != nil {
+		return nil, err
+	}
+	i.Decls = append(i.Decls, setDecl)
+
+	if p.isWord("session") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	} else if p.isWord("local") {
+		setDecl.Add(&Decl{Token: LocalToken, Lexeme: "local"})
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+
+	nameDecl, timezone, err := p.parseSettingName()
+	if err != nil {
+		return nil, err
+	}
+	setDecl.Add(nameDecl)
+
+	if p.index >= p.tokenLen {
+		return nil, fmt.Errorf("SET %s requires a value", nameDecl.Lexeme)
+	}
+	if !timezone {
+		if !p.is(EqualityToken) && !p.isWord("to") {
+			return nil, fmt.Errorf("Syntax error near %s, expected TO or =", p.cur().Lexeme)
+		}
+		if err := p.next(); err != nil {
+			return nil, fmt.Errorf("SET %s requires a value", nameDecl.Lexeme)
+		}
+	}
+
+	if p.is(DefaultToken) {
+		nameDecl.Add(NewDecl(p.cur()))
+		p.index++
+		return i, nil
+	}
+
+	// values up to the end of the statement, quotes removed
+	for p.index < p.tokenLen && p.tokens[p.index].Token != SemicolonToken {
+		switch p.tokens[p.index].Token {
+		case SimpleQuoteToken, DoubleQuoteToken, CommaToken:
+		default:
+			nameDecl.Add(&Decl{Token: StringToken, Lexeme: p.tokens[p.index].Lexeme})
+		}
+		p.index++
+	}
+	if len(nameDecl.Decl) == 0 {
+		return nil, fmt.Errorf("SET %s requires a value", nameDecl.Lexeme)
+	}
+
+	return i, nil
+}
+
+// parseReset parses a session setting reset
+//
+//	RESET { name | TIME ZONE | ALL }
+//
+// |-> "RESET" (ResetToken)
+//
+//	|-> name (StringToken), "all" for every setting
+func (p *parser) parseReset() (*Instruction, error) {
+	return p.parseSettingStatement(ResetToken)
+}
+
+// parseShow parses a session setting read
+//
+//	SHOW { name | TIME ZONE | ALL }
+//
+// |-> "SHOW" (ShowToken)
+//
+//	|-> name (StringToken), "all" for every setting
+func (p *parser) parseShow() (*Instruction, error) {
+	return p.parseSettingStatement(ShowToken)
+}
+
+func (p *parser) parseSettingStatement(token int) (*Instruction, error) {
+	i := &Instruction{}
+
+	decl := &Decl{Token: token, Lexeme: strings.ToLower(p.cur().Lexeme)}
+	i.Decls = append(i.Decls, decl)
+	if err := p.next(); err != nil {
+		return nil, fmt.Errorf("%s requires a setting name", strings.ToUpper(decl.Lexeme))
+	}
+
+	nameDecl, _, err := p.parseSettingName()
+	if err != nil {
+		return nil, err
+	}
+	decl.Add(nameDecl)
+
+	if p.index < p.tokenLen && p.tokens[p.index].Token != SemicolonToken {
+		return nil, fmt.Errorf("Syntax error near %s", p.cur().Lexeme)
+	}
+
+	return i, nil
+}
+
+// parseSettingName parses a setting name, in lower case. TIME ZONE is the timezone setting.
+// The parser is moved past the name, up to the end of the statement.
+func (p *parser) parseSettingName() (*Decl, bool, error) {
+	if p.is(TimeToken) {
+		if !p.hasNext() || p.tokens[p.index+1].Token != ZoneToken {
+			return nil, false, fmt.Errorf("Syntax error near %s, expected TIME ZONE", p.cur().Lexeme)
+		}
+		p.index += 2
+		return &Decl{Token: StringToken, Lexeme: "timezone"}, true, nil
+	}
+
+	if !p.is(StringToken) {
+		return nil, false, fmt.Errorf("Syntax error near %s, expected a setting name", p.cur().Lexeme)
+	}
+	d := &Decl{Token: StringToken, Lexeme: strings.ToLower(p.cur().Lexeme)}
+	p.index++
+	return d, false, nil
+}
+
+// isWord returns true if current token is an identifier equal to word, case insensitive.
+// Session statements keywords are not reserved, so they can still name attributes.
+func (p *parser) isWord(word string) bool {
+	return p.index < p.tokenLen && p.is(StringToken) && strings.EqualFold(p.cur().Lexeme, word)
+}
@@ -0,0 +1,126 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseConflictResolution parses SQLite INSERT conflict resolution
+//
+//	INSERT OR { REPLACE | IGNORE | ABORT | FAIL | ROLLBACK } INTO ...
+//
+// OR IGNORE is returned as ON CONFLICT DO NOTHING, OR REPLACE as a ReplaceToken.
+// Other resolutions abort the statement, as a regular INSERT does, so nil is returned.
+//
+// |-> "REPLACE" (ReplaceToken)
+func (p *parser) parseConflictResolution() (*Decl, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	var decl *Decl
+	switch {
+	case p.isWord("replace"):
+		decl = &Decl{Token: ReplaceToken, Lexeme: "replace"}
+	case p.isWord("ignore"):
+		decl = &Decl{Token: OnToken, Lexeme: "on"}
+		conflictDecl := &Decl{Token: ConflictToken, Lexeme: "conflict"}
+		doDecl := &Decl{Token: DoToken, Lexeme: "do"}
+		decl.Add(conflictDecl)
+		decl.Add(doDecl)
+		doDecl.Add(&Decl{Token: NothingToken, Lexeme: "nothing"})
+	case p.isWord("abort"), p.isWord("fail"), p.isWord("rollback"):
+	default:
+		return nil, fmt.Errorf("Syntax error near %s, expected REPLACE, IGNORE, ABORT, FAIL or ROLLBACK", p.cur().Lexeme)
+	}
+
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	return decl, nil
+}
+
+// parsePragma parses a SQLite PRAGMA statement
+//
+//	PRAGMA [ schema. ] name [ = value | ( value ) ]
+//
+// The schema is ignored, pragmas apply to the whole engine.
+//
+// |-> "PRAGMA" (PragmaToken)
+//
+//	|-> name (StringToken), in lower case
+//	    |-> value (StringToken), optional
+func (p *parser) parsePragma() (*Instruction, error) {
+	i := &Instruction{}
+
+	pragmaDecl := &Decl{Token: PragmaToken, Lexeme: "pragma"}
+	i.Decls = append(i.Decls, pragmaDecl)
+	if err := p.next(); err != nil {
+		return nil, fmt.Errorf("PRAGMA requires a name")
+	}
+
+	if !p.is(StringToken) {
+		return nil, fmt.Errorf("Syntax error near %s, expected a pragma name", p.cur().Lexeme)
+	}
+	if p.hasNext() && p.tokens[p.index+1].Token == PeriodToken {
+		p.index += 2
+		if p.index >= p.tokenLen || !p.is(StringToken) {
+			return nil, fmt.Errorf("Syntax error, expected a pragma name")
+		}
+	}
+	nameDecl := &Decl{Token: StringToken, Lexeme: strings.ToLower(p.cur().Lexeme)}
+	pragmaDecl.Add(nameDecl)
+	p.index++
+
+	if p.index >= p.tokenLen || p.is(SemicolonToken) {
+		return i, nil
+	}
+
+	closing := false
+	switch {
+	case p.is(EqualityToken):
+	case p.is(BracketOpeningToken):
+		closing = true
+	default:
+		return nil, fmt.Errorf("Syntax error near %s, expected = or (", p.cur().Lexeme)
+	}
+	if err := p.next(); err != nil {
+		return nil, fmt.Errorf("PRAGMA %s requires a value", nameDecl.Lexeme)
+	}
+
+	// value, quotes removed
+	value := ""
+	if p.is(MinusToken) {
+		value = "-"
+		p.index++
+	}
+	quoted := p.index < p.tokenLen && p.is(SimpleQuoteToken, DoubleQuoteToken)
+	if quoted {
+		p.index++
+	}
+	if p.index >= p.tokenLen || p.is(BracketClosingToken, SemicolonToken) {
+		return nil, fmt.Errorf("PRAGMA %s requires a value", nameDecl.Lexeme)
+	}
+	value += p.cur().Lexeme
+	p.index++
+	if quoted {
+		if p.index >= p.tokenLen || !p.is(SimpleQuoteToken, DoubleQuoteToken) {
+			return nil, fmt.Errorf("Syntax error, unterminated PRAGMA %s value", nameDecl.Lexeme)
+		}
+		p.index++
+	}
+	nameDecl.Add(&Decl{Token: StringToken, Lexeme: value})
+
+	if closing {
+		if p.index >= p.tokenLen || !p.is(BracketClosingToken) {
+			return nil, fmt.Errorf("Syntax error, expected ) after PRAGMA %s value", nameDecl.Lexeme)
+		}
+		p.index++
+	}
+
+	if p.index < p.tokenLen && p.isNot(SemicolonToken) {
+		return nil, fmt.Errorf("Syntax error near %s", p.cur().Lexeme)
+	}
+
+	return i, nil
+}
@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestParserSQLiteDialect(t *testing.T) {
+	queries := []string{
+		"CREATE TABLE user (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, score REAL, avatar BLOB)",
+		"INSERT OR REPLACE INTO user (id, name) VALUES (1, 'foo')",
+		"INSERT OR IGNORE INTO user (name) VALUES ('foo') RETURNING id",
+		"INSERT OR ABORT INTO user (name) VALUES ('foo')",
+		"PRAGMA table_info(user)",
+		"PRAGMA main.table_info('user');",
+		"PRAGMA foreign_keys = ON",
+		"PRAGMA cache_size = -2000",
+		"PRAGMA journal_mode",
+		"CREATE TABLE pair (x, y UNIQUE, z DEFAULT 0, PRIMARY KEY (x, y))",
+	}
+
+	for _, q := range queries {
+		if _, err := ParseInstructionWithDialect(q, DialectSQLite); err != nil {
+			t.Fatalf("Cannot parse '%s' with sqlite dialect: %s", q, err)
+		}
+	}
+
+	for _, q := range []string{queries[1], queries[4], queries[9]} {
+		if _, err := ParseInstructionWithDialect(q, DialectPostgres); err == nil {
+			t.Fatalf("Expected error parsing '%s' with postgres dialect", q)
+		}
+	}
+
+	for _, q := range []string{"INSERT OR UPSERT INTO user (name) VALUES ('foo')", "PRAGMA table_info(user", "PRAGMA foreign_keys = ON OFF"} {
+		if _, err := ParseInstructionWithDialect(q, DialectSQLite); err == nil {
+			t.Fatalf("Expected error parsing '%s'", q)
+		}
+	}
+}
+
+func TestParserSQLiteInsertOr(t *testing.T) {
+	instructions, err := ParseInstructionWithDialect("INSERT OR IGNORE INTO user (name) VALUES ('foo')", DialectSQLite)
+	if err != nil {
+		t.Fatalf("Cannot parse: %s", err)
+	}
+	if _, ok := instructions[0].Decls[0].Has(NothingToken); !ok {
+		t.Fatalf("Expected OR IGNORE to be ON CONFLICT DO NOTHING")
+	}
+
+	instructions, err = ParseInstructionWithDialect("INSERT OR REPLACE INTO user (name) VALUES ('foo') ON CONFLICT DO NOTHING", DialectSQLite)
+	if err != nil {
+		t.Fatalf("Cannot parse: %s", err)
+	}
+	if _, ok := instructions[0].Decls[0].Has(ReplaceToken); ok {
+		t.Fatalf("Expected upsert clause to take precedence over OR REPLACE")
+	}
+}
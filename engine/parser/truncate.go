@@ -1,5 +1,13 @@
 package parser
 
+// parseTruncate parses a TRUNCATE statement
+//
+//	TRUNCATE [ TABLE ] name [, ...] [ CASCADE | RESTRICT ]
+//
+// |-> "TRUNCATE" (TruncateToken)
+//
+//	|-> name (StringToken)
+//	    |-> schema (SchemaToken), optional
 func (p *parser) parseTruncate() (*Instruction, error) {
 	i := &Instruction{}
 
@@ -10,12 +18,29 @@ func (p *parser) parseTruncate() (*Instruction, error) {
 	}
 	i.Decls = append(i.Decls, trDecl)
 
-	// Should be a table name
-	nameDecl, err := p.parseQuotedToken()
-	if err != nil {
-		return nil, err
+	if p.is(TableToken) {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Should be a list of table names
+	for {
+		nameDecl, err := p.parseTableName()
+		if err != nil {
+			return nil, err
+		}
+		trDecl.Add(nameDecl)
+
+		if !p.is(CommaToken) || !p.hasNext() {
+			break
+		}
+		p.next()
+	}
+
+	if p.is(CascadeToken, RestrictToken) {
+		p.index++
 	}
-	trDecl.Add(nameDecl)
 
 	return i, nil
 }
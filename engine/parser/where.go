@@ -23,7 +23,7 @@ func (p *parser) parseWhere(selectDecl *Decl) error {
 			break
 		}
 
-		if p.is(OrderToken, LimitToken, ForToken) {
+		if p.is(OrderToken, LimitToken, ForToken, ReturningToken) {
 			break
 		}
 
@@ -51,6 +51,11 @@ func (p *parser) parseWhere(selectDecl *Decl) error {
 func (p *parser) parseCondition() (*Decl, error) {
 	// Optionnaly, brackets
 
+	// value compared with array elements, as in 'go' = ANY(tags)
+	if p.isValueArrayComparison() {
+		return p.parseValueArrayComparison()
+	}
+
 	// We may have the WHERE 1 condition
 	if t := p.cur(); t.Token == NumberToken && t.Lexeme == "1" {
 		attributeDecl := NewDecl(t)
@@ -81,6 +86,11 @@ func (p *parser) parseCondition() (*Decl, error) {
 		return attributeDecl, nil
 	}
 
+	// [NOT] EXISTS (SELECT ...)
+	if p.is(ExistsToken) || (p.is(NotToken) && p.index+1 < p.tokenLen && p.tokens[p.index+1].Token == ExistsToken) {
+		return p.parseExists()
+	}
+
 	// do we have brackets ?
 	hasBracket := false
 	if p.is(BracketOpeningToken) {
@@ -123,6 +133,10 @@ func (p *parser) parseCondition() (*Decl, error) {
 		if err != nil {
 			return nil, err
 		}
+		firstAttr, err = p.parseJSONOperators(firstAttr)
+		if err != nil {
+			return nil, err
+		}
 
 		// Check if this is a tuple (comma follows) for tuple IN expressions
 		if p.is(CommaToken) {
@@ -206,8 +220,15 @@ func (p *parser) parseCondition() (*Decl, error) {
 		}
 
 		// Now check for comparison operators
+		if p.isWord("ilike") {
+			p.tokens[p.index].Token = IlikeToken
+		}
+		// ? following an operand is the JSON key exists operator
+		if p.is(ArgToken) && p.cur().Lexeme == "?" {
+			p.tokens[p.index].Token = KeyExistsToken
+		}
 		switch p.cur().Token {
-		case EqualityToken, DistinctnessToken, LeftDipleToken, RightDipleToken, LessOrEqualToken, GreaterOrEqualToken, LikeToken:
+		case EqualityToken, DistinctnessToken, LeftDipleToken, RightDipleToken, LessOrEqualToken, GreaterOrEqualToken, LikeToken, IlikeToken, ContainsToken, ContainedByToken, OverlapToken, KeyExistsToken:
 			decl, err := p.consumeToken(p.cur().Token)
 			if err != nil {
 				return nil, err
@@ -274,7 +295,7 @@ func (p *parser) parseCondition() (*Decl, error) {
 		}
 
 		// Value
-		valueDecl, err := p.parseValue()
+		valueDecl, err := p.parseConditionValue()
 		if err != nil {
 			return nil, err
 		}
@@ -294,6 +315,10 @@ func (p *parser) parseCondition() (*Decl, error) {
 	if err != nil {
 		return nil, err
 	}
+	attributeDecl, err = p.parseJSONOperators(attributeDecl)
+	if err != nil {
+		return nil, err
+	}
 
 	// Check for arithmetic operators first (*, +, -, /)
 	// This allows expressions like: WHERE price * quantity > 1000
@@ -321,8 +346,15 @@ func (p *parser) parseCondition() (*Decl, error) {
 	}
 
 	// Now check for comparison and special WHERE operators
+	if p.isWord("ilike") {
+		p.tokens[p.index].Token = IlikeToken
+	}
+	// ? following an operand is the JSON key exists operator
+	if p.is(ArgToken) && p.cur().Lexeme == "?" {
+		p.tokens[p.index].Token = KeyExistsToken
+	}
 	switch p.cur().Token {
-	case EqualityToken, DistinctnessToken, LeftDipleToken, RightDipleToken, LessOrEqualToken, GreaterOrEqualToken, LikeToken:
+	case EqualityToken, DistinctnessToken, LeftDipleToken, RightDipleToken, LessOrEqualToken, GreaterOrEqualToken, LikeToken, IlikeToken, ContainsToken, ContainedByToken, OverlapToken, KeyExistsToken:
 		decl, err := p.consumeToken(p.cur().Token)
 		if err != nil {
 			return nil, err
@@ -377,7 +409,7 @@ func (p *parser) parseCondition() (*Decl, error) {
 	}
 
 	// Value
-	valueDecl, err := p.parseValue()
+	valueDecl, err := p.parseConditionValue()
 	if err != nil {
 		return nil, err
 	}
@@ -386,6 +418,64 @@ func (p *parser) parseCondition() (*Decl, error) {
 	return attributeDecl, nil
 }
 
+// parseConditionValue parses the right operand of a comparison,
+// either a value, a qualified attribute like "users"."id", a scalar subquery,
+// an ARRAY[...] constructor or ANY(...) and ALL(...) array elements
+func (p *parser) parseConditionValue() (*Decl, error) {
+	switch {
+	case p.isSubquery():
+		return p.parseSubquery()
+	case p.isArrayComparison():
+		return p.parseArrayComparison()
+	case p.isArrayConstructor():
+		return p.parseArrayConstructor()
+	}
+
+	period := p.index + 1
+	if p.is(DoubleQuoteToken, BacktickToken) {
+		period = p.index + 3
+	}
+	if period < p.tokenLen && p.tokens[period].Token == PeriodToken {
+		return p.parseAttribute()
+	}
+	return p.parseValue()
+}
+
+// parseExists parses [NOT] EXISTS (SELECT ...)
+//
+//	 ExistsToken
+//	     |
+//	SelectToken
+func (p *parser) parseExists() (*Decl, error) {
+	var notDecl *Decl
+	if p.is(NotToken) {
+		d, err := p.consumeToken(NotToken)
+		if err != nil {
+			return nil, err
+		}
+		notDecl = d
+	}
+
+	existsDecl, err := p.consumeToken(ExistsToken)
+	if err != nil {
+		return nil, err
+	}
+	if !p.isSubquery() {
+		return nil, fmt.Errorf("expected subquery after EXISTS")
+	}
+	selectDecl, err := p.parseSubquery()
+	if err != nil {
+		return nil, err
+	}
+	existsDecl.Add(selectDecl)
+
+	if notDecl != nil {
+		notDecl.Add(existsDecl)
+		return notDecl, nil
+	}
+	return existsDecl, nil
+}
+
 func (p *parser) hasLogicalOperatorUntilClosingBracket() bool {
 	depth := 0
 	for i := p.index; i < len(p.tokens); i++ {
@@ -2,13 +2,22 @@ package main
 
 import (
 	"database/sql"
+	"flag"
 	"fmt"
+	"os"
 
 	"github.com/proullon/ramsql/cli"
 	_ "github.com/proullon/ramsql/driver"
+	"github.com/proullon/ramsql/engine/executor"
+	"github.com/proullon/ramsql/server"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		serve(os.Args[2:])
+		return
+	}
+
 	db, err := sql.Open("ramsql", "")
 	if err != nil {
 		fmt.Printf("Error : cannot open connection : %s\n", err)
@@ -16,3 +25,40 @@ func main() {
 	}
 	cli.Run(db)
 }
+
+// serve runs a PostgreSQL wire protocol server: ramsql serve --listen 127.0.0.1:5432
+//
+// The engine is named after --name, follows --dialect and is persisted to --file if set,
+// as the name, dialect and file options of a DataSourceName.
+func serve(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := fs.String("listen", "127.0.0.1:5432", "TCP address to listen on")
+	name := fs.String("name", "", "engine name, returned by current_database()")
+	dialect := fs.String("dialect", "", "SQL dialect: postgres, mysql or sqlite")
+	file := fs.String("file", "", "write-ahead log file, replayed on start")
+	fs.Parse(args)
+
+	e, err := executor.NewEngineWithName(*name)
+	if err != nil {
+		fmt.Printf("Error : cannot create engine : %s\n", err)
+		os.Exit(1)
+	}
+	defer e.Stop()
+
+	if err := e.SetDialect(*dialect); err != nil {
+		fmt.Printf("Error : %s\n", err)
+		os.Exit(1)
+	}
+	if *file != "" {
+		if err := e.Persist(*file, 0); err != nil {
+			fmt.Printf("Error : cannot persist engine : %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Printf("ramsql listening on %s\n", *listen)
+	if err := server.New(e).ListenAndServe(*listen); err != nil {
+		fmt.Printf("Error : %s\n", err)
+		os.Exit(1)
+	}
+}
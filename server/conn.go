@@ -0,0 +1,902 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/proullon/ramsql/engine/agnostic"
+	"github.com/proullon/ramsql/engine/executor"
+	"github.com/proullon/ramsql/engine/log"
+)
+
+// SQLSTATE codes of protocol errors
+const (
+	codeActiveTransaction   = "25001"
+	codeNoActiveTransaction = "25P01"
+	codeInFailedTransaction = "25P02"
+	codeInvalidStatement    = "26000"
+	codeInvalidPortal       = "34000"
+	codeSyntaxError         = "42601"
+	codeDuplicateStatement  = "42P05"
+	codeDuplicatePortal     = "42P03"
+	codeProtocolViolation   = "08P01"
+	codeFeatureNotSupported = "0A000"
+	codeInternalError       = "XX000"
+)
+
+// reportedParameters are sent to the client on startup, and again when they change
+var reportedParameters = map[string]string{
+	"application_name":            "application_name",
+	"TimeZone":                    "timezone",
+	"standard_conforming_strings": "standard_conforming_strings",
+}
+
+// staticParameters are sent to the client on startup
+var staticParameters = [][2]string{
+	{"server_version", "16.0"},
+	{"server_encoding", "UTF8"},
+	{"client_encoding", "UTF8"},
+	{"DateStyle", "ISO, MDY"},
+	{"IntervalStyle", "postgres"},
+	{"integer_datetimes", "on"},
+	{"is_superuser", "on"},
+}
+
+// statement is a prepared statement
+type statement struct {
+	query string
+	// control is the transaction control command of the statement, if any
+	control string
+	// p is nil for empty and transaction control statements
+	p *executor.Prepared
+	// params holds the type OID of each parameter
+	params []uint32
+	// cols holds the described result columns, nil until described
+	cols      []agnostic.ColumnType
+	described bool
+}
+
+// portal is a statement bound to its arguments
+type portal struct {
+	st      *statement
+	args    []executor.NamedValue
+	formats []int16
+	cols    []agnostic.ColumnType
+
+	// rows of a portal suspended by Execute
+	rows  *executor.Rows
+	count int64
+}
+
+// format returns the format of result column i
+func (p *portal) format(i int) int16 {
+	switch len(p.formats) {
+	case 0:
+		return formatText
+	case 1:
+		return p.formats[0]
+	}
+	if i < len(p.formats) {
+		return p.formats[i]
+	}
+	return formatText
+}
+
+// conn is a client connection
+type conn struct {
+	s  *Server
+	nc net.Conn
+	r  *bufio.Reader
+	w  *bufio.Writer
+
+	session *executor.Session
+	// tx is the current transaction, started by BEGIN if explicit, or by the
+	// current query or extended query sequence otherwise
+	tx       *executor.Tx
+	explicit bool
+	// failed is true once a statement of an explicit transaction failed
+	failed bool
+	// skip is true once an extended query message failed, until Sync
+	skip bool
+
+	statements map[string]*statement
+	portals    map[string]*portal
+	reported   map[string]string
+}
+
+func newConn(s *Server, nc net.Conn) *conn {
+	return &conn{
+		s:          s,
+		nc:         nc,
+		r:          bufio.NewReader(nc),
+		w:          bufio.NewWriter(nc),
+		session:    executor.NewSession(),
+		statements: make(map[string]*statement),
+		portals:    make(map[string]*portal),
+		reported:   make(map[string]string),
+	}
+}
+
+func (c *conn) serve() {
+	defer c.close()
+
+	if err := c.startup(); err != nil {
+		if !errors.Is(err, io.EOF) {
+			log.Debug("server: startup failed: %s", err)
+		}
+		return
+	}
+
+	for {
+		typ, body, err := c.readMessage()
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				log.Debug("server: cannot read message: %s", err)
+			}
+			return
+		}
+		if typ == msgTerminate {
+			return
+		}
+
+		if err := c.handle(typ, body); err != nil {
+			log.Debug("server: %s", err)
+			return
+		}
+	}
+}
+
+func (c *conn) close() {
+	c.endTx(false)
+	c.s.e.ReleaseAdvisoryLocks(c.session)
+	c.nc.Close()
+}
+
+// handle processes a frontend message. Returned errors close the connection.
+func (c *conn) handle(typ byte, body []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.fail(agnostic.NewError(codeInternalError, "%v", r))
+			if typ == msgQuery {
+				c.readyForQuery()
+			}
+			err = c.w.Flush()
+		}
+	}()
+
+	r := &reader{data: body}
+
+	// after an error, extended query messages are discarded until Sync
+	if c.skip && typ != msgSync && typ != msgQuery {
+		return nil
+	}
+
+	switch typ {
+	case msgQuery:
+		c.simpleQuery(r.string())
+		c.readyForQuery()
+		return c.w.Flush()
+	case msgParse:
+		err = c.parse(r)
+	case msgBind:
+		err = c.bind(r)
+	case msgDescribe:
+		err = c.describe(r)
+	case msgExecute:
+		err = c.execute(r)
+	case msgClose:
+		err = c.closeMessage(r)
+	case msgSync:
+		c.skip = false
+		if !c.explicit {
+			c.endTx(true)
+		}
+		c.readyForQuery()
+		return c.w.Flush()
+	case msgFlush:
+		return c.w.Flush()
+	default:
+		c.send(errorMessage(agnostic.NewError(codeProtocolViolation, "unsupported frontend message type %q", typ)))
+		return c.w.Flush()
+	}
+
+	if err == nil && r.err != nil {
+		err = agnostic.NewError(codeProtocolViolation, "%s", r.err)
+	}
+	if err != nil {
+		c.fail(err)
+		c.skip = true
+	}
+	return nil
+}
+
+// startup handles the startup sequence: SSL negotiation, startup parameters and authentication
+func (c *conn) startup() error {
+	for {
+		body, err := c.readStartup()
+		if err != nil {
+			return err
+		}
+
+		r := &reader{data: body}
+		switch code := r.int32(); code {
+		case sslRequest, gssEncRequest:
+			if _, err := c.nc.Write([]byte{'N'}); err != nil {
+				return err
+			}
+			continue
+		case cancelRequest:
+			return io.EOF
+		case protocolVersion3:
+			params := make(map[string]string)
+			for {
+				k := r.string()
+				if k == "" || r.err != nil {
+					break
+				}
+				params[k] = r.string()
+			}
+			if r.err != nil {
+				return r.err
+			}
+			return c.accept(params)
+		default:
+			c.send(errorMessage(agnostic.NewError(codeFeatureNotSupported, "unsupported frontend protocol %d.%d", code>>16, code&0xffff)))
+			c.w.Flush()
+			return fmt.Errorf("unsupported protocol %d", code)
+		}
+	}
+}
+
+// accept applies startup parameters and authenticates the client
+func (c *conn) accept(params map[string]string) error {
+	for k, v := range params {
+		switch k {
+		case "application_name", "search_path", "TimeZone", "timezone", "statement_timeout":
+			if err := c.session.Set(k, v); err != nil {
+				c.send(fatalMessage(err))
+				c.w.Flush()
+				return err
+			}
+		}
+	}
+
+	c.send(newMessage(msgAuthentication).int32(0))
+	for _, p := range staticParameters {
+		c.send(newMessage(msgParameterStatus).string(p[0]).string(p[1]))
+	}
+	if user, ok := params["user"]; ok {
+		c.send(newMessage(msgParameterStatus).string("session_authorization").string(user))
+	}
+	c.reportParameters()
+	c.send(newMessage(msgBackendKeyData).int32(c.s.pid.Add(1)).int32(rand.Int31()))
+	c.readyForQuery()
+	return c.w.Flush()
+}
+
+// simpleQuery runs statements of a Query message, in a single transaction unless they control it
+func (c *conn) simpleQuery(query string) {
+	// a Query message ends extended query messages
+	c.skip = false
+	delete(c.statements, "")
+	c.closePortal("")
+
+	stmts := split(query)
+	if len(stmts) == 0 {
+		c.send(newMessage(msgEmptyQueryResponse))
+		return
+	}
+
+	for _, q := range stmts {
+		st, err := c.prepare(q)
+		if err == nil {
+			p := &portal{st: st}
+			err = c.run(p, 0, true)
+			c.closePortalRows(p)
+		}
+		if err != nil {
+			c.fail(err)
+			break
+		}
+	}
+
+	if !c.explicit {
+		c.endTx(true)
+	}
+}
+
+// prepare parses a single statement
+func (c *conn) prepare(query string) (*statement, error) {
+	st := &statement{query: query}
+	if st.control = control(query); st.control != "" {
+		return st, nil
+	}
+	if query == "" {
+		return st, nil
+	}
+
+	p, err := c.s.e.Prepare(query)
+	if err != nil {
+		var e *agnostic.Error
+		if !errors.As(err, &e) {
+			err = agnostic.NewError(codeSyntaxError, "%s", err)
+		}
+		return nil, err
+	}
+	if p.NumInput() < 0 {
+		return nil, agnostic.NewError(codeSyntaxError, "cannot mix named and positional parameters")
+	}
+	st.p = p
+
+	st.params = make([]uint32, p.NumInput())
+	for i := range st.params {
+		st.params[i] = oidText
+		if param, ok := c.param(p, i); ok && param.Type.ScanType != nil {
+			st.params[i] = typeOID(param.Type)
+		}
+	}
+	return st, nil
+}
+
+// param returns the i-th parameter of p, positional or named
+func (c *conn) param(p *executor.Prepared, i int) (executor.Param, bool) {
+	if param, ok := p.Param("", i+1); ok {
+		return param, true
+	}
+	n := 0
+	seen := make(map[string]bool)
+	for _, param := range p.Params {
+		if param.Name == "" || seen[param.Name] {
+			continue
+		}
+		seen[param.Name] = true
+		if n == i {
+			return param, true
+		}
+		n++
+	}
+	return executor.Param{}, false
+}
+
+// parse handles a Parse message
+func (c *conn) parse(r *reader) error {
+	name := r.string()
+	query := r.string()
+	n := r.int16()
+	oids := make([]uint32, 0, n)
+	for i := int16(0); i < n; i++ {
+		oids = append(oids, uint32(r.int32()))
+	}
+	if r.err != nil {
+		return nil
+	}
+
+	if _, ok := c.statements[name]; ok && name != "" {
+		return agnostic.NewError(codeDuplicateStatement, "prepared statement \"%s\" already exists", name)
+	}
+
+	stmts := split(query)
+	if len(stmts) > 1 {
+		return agnostic.NewError(codeSyntaxError, "cannot insert multiple commands into a prepared statement")
+	}
+	q := ""
+	if len(stmts) == 1 {
+		q = stmts[0]
+	}
+	st, err := c.prepare(q)
+	if err != nil {
+		return err
+	}
+
+	// parameter types given by the client take precedence
+	for i, oid := range oids {
+		if oid == oidUnknown {
+			continue
+		}
+		if i >= len(st.params) {
+			return agnostic.NewError(codeProtocolViolation, "statement has %d parameters, %d types given", len(st.params), len(oids))
+		}
+		st.params[i] = oid
+	}
+
+	c.statements[name] = st
+	c.send(newMessage(msgParseComplete))
+	return nil
+}
+
+// bind handles a Bind message
+func (c *conn) bind(r *reader) error {
+	portalName := r.string()
+	name := r.string()
+
+	formats := make([]int16, r.int16())
+	for i := range formats {
+		formats[i] = r.int16()
+	}
+	values := make([][]byte, r.int16())
+	for i := range values {
+		values[i] = r.value()
+	}
+	resultFormats := make([]int16, r.int16())
+	for i := range resultFormats {
+		resultFormats[i] = r.int16()
+	}
+	if r.err != nil {
+		return nil
+	}
+
+	st, ok := c.statements[name]
+	if !ok {
+		return agnostic.NewError(codeInvalidStatement, "prepared statement \"%s\" does not exist", name)
+	}
+	if _, ok := c.portals[portalName]; ok && portalName != "" {
+		return agnostic.NewError(codeDuplicatePortal, "portal \"%s\" already exists", portalName)
+	}
+	if len(values) != len(st.params) {
+		return agnostic.NewError(codeProtocolViolation, "bind message supplies %d parameters, but prepared statement \"%s\" requires %d", len(values), name, len(st.params))
+	}
+
+	p := &portal{st: st, formats: resultFormats, cols: st.cols}
+	for i, b := range values {
+		format := int16(formatText)
+		switch {
+		case len(formats) == 1:
+			format = formats[0]
+		case i < len(formats):
+			format = formats[i]
+		}
+
+		v, err := decode(b, st.params[i], format)
+		if err != nil {
+			return agnostic.NewError("22P02", "%s", err)
+		}
+
+		param, _ := c.param(st.p, i)
+		p.args = append(p.args, executor.NamedValue{Name: param.Name, Ordinal: i + 1, Value: v})
+	}
+
+	c.closePortal(portalName)
+	c.portals[portalName] = p
+	c.send(newMessage(msgBindComplete))
+	return nil
+}
+
+// describe handles a Describe message
+func (c *conn) describe(r *reader) error {
+	kind := r.byte1()
+	name := r.string()
+	if r.err != nil {
+		return nil
+	}
+
+	switch kind {
+	case 'S':
+		st, ok := c.statements[name]
+		if !ok {
+			return agnostic.NewError(codeInvalidStatement, "prepared statement \"%s\" does not exist", name)
+		}
+		cols, err := c.describeStatement(st)
+		if err != nil {
+			return err
+		}
+		m := newMessage(msgParameterDescription).int16(int16(len(st.params)))
+		for _, oid := range st.params {
+			m.int32(int32(oid))
+		}
+		c.send(m)
+		c.sendRowDescription(&portal{st: st, cols: cols})
+	case 'P':
+		p, ok := c.portals[name]
+		if !ok {
+			return agnostic.NewError(codeInvalidPortal, "portal \"%s\" does not exist", name)
+		}
+		if p.cols == nil {
+			cols, err := c.describeStatement(p.st)
+			if err != nil {
+				return err
+			}
+			p.cols = cols
+		}
+		c.sendRowDescription(p)
+	default:
+		return agnostic.NewError(codeProtocolViolation, "invalid DESCRIBE message subtype %d", kind)
+	}
+	return nil
+}
+
+// describeStatement returns the columns returned by st, in the current transaction if any
+func (c *conn) describeStatement(st *statement) ([]agnostic.ColumnType, error) {
+	if st.described || st.p == nil || !st.p.ReturnsRows() {
+		return st.cols, nil
+	}
+	if c.failed {
+		return nil, errAborted()
+	}
+
+	tx := c.tx
+	if tx == nil {
+		var err error
+		tx, err = executor.NewTx(context.Background(), c.s.e, sql.TxOptions{})
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback()
+		tx.SetSession(c.session)
+	}
+
+	cols, err := tx.Describe(st.p)
+	if err != nil {
+		return nil, err
+	}
+	st.cols, st.described = cols, true
+	return cols, nil
+}
+
+// execute handles an Execute message
+func (c *conn) execute(r *reader) error {
+	name := r.string()
+	max := r.int32()
+	if r.err != nil {
+		return nil
+	}
+
+	p, ok := c.portals[name]
+	if !ok {
+		return agnostic.NewError(codeInvalidPortal, "portal \"%s\" does not exist", name)
+	}
+	return c.run(p, max, false)
+}
+
+// closeMessage handles a Close message
+func (c *conn) closeMessage(r *reader) error {
+	kind := r.byte1()
+	name := r.string()
+	if r.err != nil {
+		return nil
+	}
+
+	switch kind {
+	case 'S':
+		delete(c.statements, name)
+	case 'P':
+		c.closePortal(name)
+	default:
+		return agnostic.NewError(codeProtocolViolation, "invalid CLOSE message subtype %d", kind)
+	}
+	c.send(newMessage(msgCloseComplete))
+	return nil
+}
+
+// run executes portal p, sending up to max rows if max is positive.
+// The row description is sent first if describe is true, as simple queries do.
+func (c *conn) run(p *portal, max int32, describe bool) error {
+	st := p.st
+	if st.control != "" {
+		return c.control(st.control)
+	}
+	if c.failed {
+		return errAborted()
+	}
+	if st.p == nil {
+		c.send(newMessage(msgEmptyQueryResponse))
+		return nil
+	}
+
+	tx, err := c.begin()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	if !st.p.ReturnsRows() {
+		_, n, err := tx.ExecPrepared(ctx, st.p, p.args)
+		if err != nil {
+			return err
+		}
+		c.send(newMessage(msgCommandComplete).string(commandTag(st.p.Command(), n)))
+		return nil
+	}
+
+	if p.rows == nil {
+		rows, err := tx.QueryPrepared(ctx, st.p, p.args)
+		if err != nil {
+			return err
+		}
+		p.rows = rows
+		if describe || p.cols == nil {
+			p.cols = rows.Columns()
+		}
+		if describe {
+			c.sendRowDescription(p)
+		}
+	}
+
+	oids := make([]uint32, len(p.cols))
+	for i, col := range p.cols {
+		oids[i] = typeOID(col)
+	}
+	loc := c.location()
+
+	for sent := int32(0); max <= 0 || sent < max; sent++ {
+		tup, err := p.rows.Next()
+		if err != nil {
+			c.closePortalRows(p)
+			return err
+		}
+		if tup == nil {
+			err := p.rows.Close()
+			p.rows = nil
+			if err != nil {
+				return err
+			}
+			c.send(newMessage(msgCommandComplete).string(commandTag(st.p.Command(), p.count)))
+			return nil
+		}
+
+		values := tup.Values()
+		m := newMessage(msgDataRow).int16(int16(len(values)))
+		for i, v := range values {
+			oid := uint32(oidText)
+			if i < len(oids) {
+				oid = oids[i]
+			}
+			b, err := encode(v, oid, p.format(i), loc)
+			if err != nil {
+				c.closePortalRows(p)
+				return agnostic.NewError(codeInternalError, "%s", err)
+			}
+			m.value(b)
+		}
+		c.send(m)
+		p.count++
+	}
+
+	c.send(newMessage(msgPortalSuspended))
+	return nil
+}
+
+// control runs a transaction control command
+func (c *conn) control(cmd string) error {
+	switch cmd {
+	case cmdBegin:
+		if c.explicit {
+			c.notice(codeActiveTransaction, "there is already a transaction in progress")
+			break
+		}
+		if _, err := c.begin(); err != nil {
+			return err
+		}
+		c.explicit = true
+	case cmdCommit:
+		if !c.explicit {
+			c.notice(codeNoActiveTransaction, "there is no transaction in progress")
+			break
+		}
+		if c.failed {
+			c.endTx(false)
+			c.send(newMessage(msgCommandComplete).string(cmdRollback))
+			return nil
+		}
+		if err := c.endTx(true); err != nil {
+			return err
+		}
+	case cmdRollback:
+		if !c.explicit {
+			c.notice(codeNoActiveTransaction, "there is no transaction in progress")
+		}
+		c.endTx(false)
+	}
+
+	c.send(newMessage(msgCommandComplete).string(cmd))
+	return nil
+}
+
+// begin returns the current transaction, starting one if needed
+func (c *conn) begin() (*executor.Tx, error) {
+	if c.tx != nil {
+		return c.tx, nil
+	}
+
+	if err := c.s.e.ConnFault(); err != nil {
+		return nil, err
+	}
+	tx, err := executor.NewTx(context.Background(), c.s.e, sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	tx.SetSession(c.session)
+	c.tx = tx
+	return tx, nil
+}
+
+// endTx commits or rolls back the current transaction, closing its portals
+func (c *conn) endTx(commit bool) error {
+	for name := range c.portals {
+		c.closePortal(name)
+	}
+
+	tx := c.tx
+	c.tx, c.explicit, c.failed = nil, false, false
+	if tx == nil {
+		return nil
+	}
+	if !commit {
+		return tx.Rollback()
+	}
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	c.reportParameters()
+	return nil
+}
+
+// fail sends err to the client, and aborts the current transaction
+func (c *conn) fail(err error) {
+	c.send(errorMessage(err))
+
+	if c.explicit {
+		c.failed = true
+		return
+	}
+	c.endTx(false)
+}
+
+func (c *conn) closePortal(name string) {
+	if p, ok := c.portals[name]; ok {
+		c.closePortalRows(p)
+		delete(c.portals, name)
+	}
+}
+
+func (c *conn) closePortalRows(p *portal) {
+	if p.rows != nil {
+		p.rows.Close()
+		p.rows = nil
+	}
+}
+
+// location returns the session time zone
+func (c *conn) location() *time.Location {
+	tz, _ := c.session.Get("timezone")
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// reportParameters sends parameters changed since last reported
+func (c *conn) reportParameters() {
+	for name, setting := range reportedParameters {
+		v, err := c.session.Get(setting)
+		if err != nil || c.reported[name] == v {
+			continue
+		}
+		c.reported[name] = v
+		c.send(newMessage(msgParameterStatus).string(name).string(v))
+	}
+}
+
+func (c *conn) readyForQuery() {
+	status := byte('I')
+	switch {
+	case c.failed:
+		status = 'E'
+	case c.explicit:
+		status = 'T'
+	}
+	c.send(newMessage(msgReadyForQuery).byte1(status))
+}
+
+func (c *conn) notice(code string, msg string) {
+	c.send(newMessage(msgNoticeResponse).
+		byte1('S').string("WARNING").
+		byte1('V').string("WARNING").
+		byte1('C').string(code).
+		byte1('M').string(msg).
+		byte1(0))
+}
+
+func (c *conn) sendRowDescription(p *portal) {
+	if p.st.p == nil || !p.st.p.ReturnsRows() {
+		c.send(newMessage(msgNoData))
+		return
+	}
+
+	m := newMessage(msgRowDescription).int16(int16(len(p.cols)))
+	for i, col := range p.cols {
+		oid := typeOID(col)
+		m.string(col.Name).
+			int32(0).
+			int16(0).
+			int32(int32(oid)).
+			int16(typeSize(oid)).
+			int32(-1).
+			int16(p.format(i))
+	}
+	c.send(m)
+}
+
+// send buffers m, messages are written on Flush
+func (c *conn) send(m *message) {
+	var header [5]byte
+	header[0] = m.typ
+	binary.BigEndian.PutUint32(header[1:], uint32(len(m.data)+4))
+	c.w.Write(header[:])
+	c.w.Write(m.data)
+}
+
+func (c *conn) readStartup() ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return nil, err
+	}
+	return c.readBody(header[:])
+}
+
+func (c *conn) readMessage() (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	body, err := c.readBody(header[1:])
+	return header[0], body, err
+}
+
+func (c *conn) readBody(length []byte) ([]byte, error) {
+	n := int(binary.BigEndian.Uint32(length)) - 4
+	if n < 0 || n > maxMessageLen {
+		return nil, fmt.Errorf("invalid message length %d", n)
+	}
+	body := make([]byte, n)
+	_, err := io.ReadFull(c.r, body)
+	return body, err
+}
+
+func errAborted() error {
+	return agnostic.NewError(codeInFailedTransaction, "current transaction is aborted, commands ignored until end of transaction block")
+}
+
+// commandTag returns the tag of a command completed on n rows
+func commandTag(cmd string, n int64) string {
+	switch cmd {
+	case "INSERT":
+		return "INSERT 0 " + strconv.FormatInt(n, 10)
+	case "SELECT", "UPDATE", "DELETE":
+		return cmd + " " + strconv.FormatInt(n, 10)
+	}
+	return cmd
+}
+
+func errorMessage(err error) *message {
+	return responseMessage(msgErrorResponse, "ERROR", err)
+}
+
+func fatalMessage(err error) *message {
+	return responseMessage(msgErrorResponse, "FATAL", err)
+}
+
+func responseMessage(typ byte, severity string, err error) *message {
+	code, msg := codeInternalError, err.Error()
+	var e *agnostic.Error
+	if errors.As(err, &e) {
+		code, msg = e.Code, e.Message
+	}
+
+	return newMessage(typ).
+		byte1('S').string(severity).
+		byte1('V').string(severity).
+		byte1('C').string(code).
+		byte1('M').string(msg).
+		byte1(0)
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Frontend message types
+const (
+	msgBind      = 'B'
+	msgClose     = 'C'
+	msgDescribe  = 'D'
+	msgExecute   = 'E'
+	msgFlush     = 'H'
+	msgParse     = 'P'
+	msgQuery     = 'Q'
+	msgSync      = 'S'
+	msgTerminate = 'X'
+)
+
+// Backend message types
+const (
+	msgAuthentication       = 'R'
+	msgBackendKeyData       = 'K'
+	msgBindComplete         = '2'
+	msgCloseComplete        = '3'
+	msgCommandComplete      = 'C'
+	msgDataRow              = 'D'
+	msgEmptyQueryResponse   = 'I'
+	msgErrorResponse        = 'E'
+	msgNoData               = 'n'
+	msgNoticeResponse       = 'N'
+	msgParameterDescription = 't'
+	msgParameterStatus      = 'S'
+	msgParseComplete        = '1'
+	msgPortalSuspended      = 's'
+	msgReadyForQuery        = 'Z'
+	msgRowDescription       = 'T'
+)
+
+// Startup request codes
+const (
+	protocolVersion3 = 196608
+	cancelRequest    = 80877102
+	sslRequest       = 80877103
+	gssEncRequest    = 80877104
+)
+
+// maxMessageLen bounds the length of frontend messages
+const maxMessageLen = 64 << 20
+
+// message is a backend message being built
+type message struct {
+	typ  byte
+	data []byte
+}
+
+func newMessage(typ byte) *message {
+	return &message{typ: typ}
+}
+
+func (m *message) byte1(b byte) *message {
+	m.data = append(m.data, b)
+	return m
+}
+
+func (m *message) int16(v int16) *message {
+	m.data = binary.BigEndian.AppendUint16(m.data, uint16(v))
+	return m
+}
+
+func (m *message) int32(v int32) *message {
+	m.data = binary.BigEndian.AppendUint32(m.data, uint32(v))
+	return m
+}
+
+// string appends a null-terminated string
+func (m *message) string(s string) *message {
+	m.data = append(m.data, s...)
+	m.data = append(m.data, 0)
+	return m
+}
+
+// value appends a length-prefixed value, nil being NULL
+func (m *message) value(b []byte) *message {
+	if b == nil {
+		return m.int32(-1)
+	}
+	m.int32(int32(len(b)))
+	m.data = append(m.data, b...)
+	return m
+}
+
+// reader decodes a frontend message body. Reading past the end sets err.
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) short() {
+	if r.err == nil {
+		r.err = fmt.Errorf("invalid message format")
+	}
+	r.data = nil
+}
+
+func (r *reader) byte1() byte {
+	if len(r.data) < 1 {
+		r.short()
+		return 0
+	}
+	b := r.data[0]
+	r.data = r.data[1:]
+	return b
+}
+
+func (r *reader) int16() int16 {
+	if len(r.data) < 2 {
+		r.short()
+		return 0
+	}
+	v := int16(binary.BigEndian.Uint16(r.data))
+	r.data = r.data[2:]
+	return v
+}
+
+func (r *reader) int32() int32 {
+	if len(r.data) < 4 {
+		r.short()
+		return 0
+	}
+	v := int32(binary.BigEndian.Uint32(r.data))
+	r.data = r.data[4:]
+	return v
+}
+
+// string reads a null-terminated string
+func (r *reader) string() string {
+	i := bytes.IndexByte(r.data, 0)
+	if i < 0 {
+		r.short()
+		return ""
+	}
+	s := string(r.data[:i])
+	r.data = r.data[i+1:]
+	return s
+}
+
+// value reads a length-prefixed value, returning nil for NULL
+func (r *reader) value() []byte {
+	n := r.int32()
+	if n < 0 {
+		return nil
+	}
+	if int(n) > len(r.data) {
+		r.short()
+		return nil
+	}
+	v := r.data[:n:n]
+	r.data = r.data[n:]
+	return v
+}
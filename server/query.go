@@ -0,0 +1,124 @@
+package server
+
+import (
+	"strings"
+)
+
+// split returns statements of query, separated by semicolons outside of
+// literals, quoted identifiers and comments. Comments are removed, as the
+// parser does not handle them, and empty statements are dropped.
+func split(query string) []string {
+	var stmts []string
+	var b strings.Builder
+	empty := true
+
+	add := func() {
+		if !empty {
+			stmts = append(stmts, strings.TrimSpace(b.String()))
+		}
+		b.Reset()
+		empty = true
+	}
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == ';':
+			add()
+			continue
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			i += end
+			b.WriteByte(' ')
+			continue
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i - 3
+			}
+			i += end + 3
+			b.WriteByte(' ')
+			continue
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			b.WriteByte(c)
+			continue
+		}
+
+		// literals and quoted identifiers are copied as is
+		end := i
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			if n := strings.IndexByte(query[i+1:], c); n >= 0 {
+				end = i + n + 1
+			} else {
+				end = len(query) - 1
+			}
+		case c == '$':
+			// dollar quoted string, such as $$text$$ or $tag$text$tag$
+			if tag, ok := dollarTag(query[i:]); ok {
+				if n := strings.Index(query[i+len(tag):], tag); n >= 0 {
+					end = i + len(tag) + n + len(tag) - 1
+				} else {
+					end = len(query) - 1
+				}
+			}
+		}
+		b.WriteString(query[i : end+1])
+		i = end
+		empty = false
+	}
+	add()
+
+	return stmts
+}
+
+// dollarTag returns the opening tag of a dollar quoted string starting s, if any
+func dollarTag(s string) (string, bool) {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		if c == '$' {
+			return s[:i+1], true
+		}
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 1 && c >= '0' && c <= '9') {
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// Transaction control commands, handled by connections
+const (
+	cmdBegin    = "BEGIN"
+	cmdCommit   = "COMMIT"
+	cmdRollback = "ROLLBACK"
+)
+
+// control returns the transaction control command of stmt, if any:
+// BEGIN or START TRANSACTION, COMMIT or END, ROLLBACK or ABORT.
+func control(stmt string) string {
+	words := strings.Fields(strings.ToUpper(stmt))
+	if len(words) == 0 {
+		return ""
+	}
+
+	switch words[0] {
+	case "BEGIN":
+		return cmdBegin
+	case "START":
+		if len(words) > 1 && words[1] == "TRANSACTION" {
+			return cmdBegin
+		}
+	case "COMMIT", "END":
+		if len(words) == 1 || len(words) == 2 && (words[1] == "WORK" || words[1] == "TRANSACTION") {
+			return cmdCommit
+		}
+	case "ROLLBACK", "ABORT":
+		if len(words) == 1 || len(words) == 2 && (words[1] == "WORK" || words[1] == "TRANSACTION") {
+			return cmdRollback
+		}
+	}
+	return ""
+}
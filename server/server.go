@@ -0,0 +1,123 @@
+// Package server serves a RamSQL engine over the PostgreSQL v3 frontend/backend
+// protocol, so psql, pgx, psycopg or any PostgreSQL client can use it.
+//
+// Connections share the engine, each one having its own session settings.
+// Clients are not authenticated and TLS is not supported.
+package server
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/proullon/ramsql/engine/executor"
+	"github.com/proullon/ramsql/engine/log"
+)
+
+// ErrServerClosed is returned by Serve once Close is called.
+var ErrServerClosed = errors.New("server closed")
+
+// Server accepts PostgreSQL connections to an engine.
+type Server struct {
+	e *executor.Engine
+
+	ln     net.Listener
+	conns  map[*conn]struct{}
+	closed bool
+	pid    atomic.Int32
+
+	sync.Mutex
+}
+
+// New returns a Server for engine e.
+func New(e *executor.Engine) *Server {
+	return &Server{
+		e:     e,
+		conns: make(map[*conn]struct{}),
+	}
+}
+
+// ListenAndServe listens on TCP address addr, such as 127.0.0.1:5432, and serves connections.
+func (s *Server) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve accepts connections on ln until Close is called. It always returns a non-nil error.
+func (s *Server) Serve(ln net.Listener) error {
+	s.Lock()
+	if s.closed {
+		s.Unlock()
+		ln.Close()
+		return ErrServerClosed
+	}
+	s.ln = ln
+	s.Unlock()
+
+	for {
+		nc, err := ln.Accept()
+		if err != nil {
+			s.Lock()
+			closed := s.closed
+			s.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		c := newConn(s, nc)
+		s.Lock()
+		if s.closed {
+			s.Unlock()
+			nc.Close()
+			return ErrServerClosed
+		}
+		s.conns[c] = struct{}{}
+		s.Unlock()
+
+		go func() {
+			c.serve()
+			s.Lock()
+			delete(s.conns, c)
+			s.Unlock()
+		}()
+	}
+}
+
+// Addr returns the address the server listens on, or nil before Serve is called.
+func (s *Server) Addr() net.Addr {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.ln == nil {
+		return nil
+	}
+	return s.ln.Addr()
+}
+
+// Close stops listening and closes all connections, rolling back their transactions.
+func (s *Server) Close() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	var err error
+	if s.ln != nil {
+		err = s.ln.Close()
+	}
+	for c := range s.conns {
+		if cerr := c.nc.Close(); cerr != nil {
+			log.Debug("cannot close connection: %s", cerr)
+		}
+	}
+	return err
+}
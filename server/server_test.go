@@ -0,0 +1,435 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/proullon/ramsql/engine/executor"
+)
+
+func testServer(t *testing.T) string {
+	e, err := executor.NewEngine()
+	if err != nil {
+		t.Fatalf("executor.NewEngine: Error: %s\n", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: Error: %s\n", err)
+	}
+
+	s := New(e)
+	go s.Serve(ln)
+	t.Cleanup(func() {
+		s.Close()
+		e.Stop()
+	})
+
+	return "postgres://ramsql@" + ln.Addr().String() + "/ramsql?sslmode=disable"
+}
+
+func testConnect(t *testing.T, url string, mode pgx.QueryExecMode) *pgx.Conn {
+	config, err := pgx.ParseConfig(url)
+	if err != nil {
+		t.Fatalf("pgx.ParseConfig: Error: %s\n", err)
+	}
+	config.DefaultQueryExecMode = mode
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		t.Fatalf("pgx.Connect: Error: %s\n", err)
+	}
+	t.Cleanup(func() { conn.Close(context.Background()) })
+	return conn
+}
+
+func TestServerQuery(t *testing.T) {
+	url := testServer(t)
+
+	modes := map[string]pgx.QueryExecMode{
+		"extended": pgx.QueryExecModeCacheStatement,
+		"describe": pgx.QueryExecModeDescribeExec,
+		"simple":   pgx.QueryExecModeSimpleProtocol,
+	}
+	for name, mode := range modes {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			conn := testConnect(t, url, mode)
+			table := "account_" + name
+
+			_, err := conn.Exec(ctx, `CREATE TABLE `+table+` (id BIGSERIAL PRIMARY KEY, email TEXT, age INT, active BOOLEAN, created_at TIMESTAMP)`)
+			if err != nil {
+				t.Fatalf("CREATE TABLE: Error: %s\n", err)
+			}
+
+			created := time.Date(2024, time.March, 1, 12, 30, 0, 0, time.UTC)
+			tag, err := conn.Exec(ctx, `INSERT INTO `+table+` (email, age, active, created_at) VALUES ($1, $2, $3, $4)`, "foo@bar.com", 42, true, created)
+			if err != nil {
+				t.Fatalf("INSERT: Error: %s\n", err)
+			}
+			if !tag.Insert() || tag.RowsAffected() != 1 {
+				t.Fatalf("expected INSERT 0 1, got %s", tag)
+			}
+			_, err = conn.Exec(ctx, `INSERT INTO `+table+` (email, age, active, created_at) VALUES ($1, $2, $3, $4)`, "bar@bar.com", 7, false, nil)
+			if err != nil {
+				t.Fatalf("INSERT: Error: %s\n", err)
+			}
+
+			var id, age int64
+			var email string
+			var active bool
+			var at time.Time
+			err = conn.QueryRow(ctx, `SELECT id, email, age, active, created_at FROM `+table+` WHERE email = $1`, "foo@bar.com").Scan(&id, &email, &age, &active, &at)
+			if err != nil {
+				t.Fatalf("SELECT: Error: %s\n", err)
+			}
+			if id != 1 || email != "foo@bar.com" || age != 42 || !active || !at.Equal(created) {
+				t.Fatalf("unexpected row (%d, %s, %d, %t, %s)", id, email, age, active, at)
+			}
+
+			var missing *time.Time
+			err = conn.QueryRow(ctx, `SELECT created_at FROM `+table+` WHERE email = $1`, "bar@bar.com").Scan(&missing)
+			if err != nil {
+				t.Fatalf("SELECT: Error: %s\n", err)
+			}
+			if missing != nil {
+				t.Fatalf("expected NULL created_at, got %s", missing)
+			}
+
+			rows, err := conn.Query(ctx, `SELECT email FROM `+table+` ORDER BY id`)
+			if err != nil {
+				t.Fatalf("SELECT: Error: %s\n", err)
+			}
+			emails, err := pgx.CollectRows(rows, pgx.RowTo[string])
+			if err != nil {
+				t.Fatalf("CollectRows: Error: %s\n", err)
+			}
+			if len(emails) != 2 || emails[0] != "foo@bar.com" || emails[1] != "bar@bar.com" {
+				t.Fatalf("unexpected rows %v", emails)
+			}
+
+			tag, err = conn.Exec(ctx, `UPDATE `+table+` SET age = $1 WHERE email = $2`, 8, "bar@bar.com")
+			if err != nil {
+				t.Fatalf("UPDATE: Error: %s\n", err)
+			}
+			if !tag.Update() || tag.RowsAffected() != 1 {
+				t.Fatalf("expected UPDATE 1, got %s", tag)
+			}
+
+			err = conn.QueryRow(ctx, `DELETE FROM `+table+` WHERE email = $1 RETURNING age`, "bar@bar.com").Scan(&age)
+			if err != nil {
+				t.Fatalf("DELETE RETURNING: Error: %s\n", err)
+			}
+			if age != 8 {
+				t.Fatalf("expected age 8, got %d", age)
+			}
+		})
+	}
+}
+
+func TestServerArray(t *testing.T) {
+	url := testServer(t)
+
+	modes := map[string]pgx.QueryExecMode{
+		"extended": pgx.QueryExecModeCacheStatement,
+		"describe": pgx.QueryExecModeDescribeExec,
+		"simple":   pgx.QueryExecModeSimpleProtocol,
+	}
+	for name, mode := range modes {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			conn := testConnect(t, url, mode)
+			table := "post_" + name
+
+			_, err := conn.Exec(ctx, `CREATE TABLE `+table+` (id BIGSERIAL PRIMARY KEY, tags TEXT[], scores BIGINT[])`)
+			if err != nil {
+				t.Fatalf("CREATE TABLE: Error: %s\n", err)
+			}
+			for _, tags := range [][]string{{"go", "hello world"}, {"rust"}} {
+				_, err = conn.Exec(ctx, `INSERT INTO `+table+` (tags, scores) VALUES ($1, $2)`, tags, []int64{1, 2})
+				if err != nil {
+					t.Fatalf("INSERT: Error: %s\n", err)
+				}
+			}
+
+			var tags []string
+			var scores []int64
+			err = conn.QueryRow(ctx, `SELECT tags, scores FROM `+table+` WHERE id = ANY($1)`, []int64{1, 3}).Scan(&tags, &scores)
+			if err != nil {
+				t.Fatalf("SELECT: Error: %s\n", err)
+			}
+			if len(tags) != 2 || tags[1] != "hello world" || len(scores) != 2 || scores[1] != 2 {
+				t.Fatalf("unexpected row (%v, %v)", tags, scores)
+			}
+
+			var ids []int64
+			err = conn.QueryRow(ctx, `SELECT array_agg(id) FROM `+table+` WHERE tags && $1`, []string{"go", "rust"}).Scan(&ids)
+			if err != nil {
+				t.Fatalf("SELECT: Error: %s\n", err)
+			}
+			if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+				t.Fatalf("unexpected ids %v", ids)
+			}
+		})
+	}
+}
+
+func TestServerJSON(t *testing.T) {
+	url := testServer(t)
+
+	modes := map[string]pgx.QueryExecMode{
+		"extended": pgx.QueryExecModeCacheStatement,
+		"describe": pgx.QueryExecModeDescribeExec,
+		"simple":   pgx.QueryExecModeSimpleProtocol,
+	}
+	for name, mode := range modes {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			conn := testConnect(t, url, mode)
+			table := "event_" + name
+
+			_, err := conn.Exec(ctx, `CREATE TABLE `+table+` (id BIGSERIAL PRIMARY KEY, payload JSONB)`)
+			if err != nil {
+				t.Fatalf("CREATE TABLE: Error: %s\n", err)
+			}
+			for _, typ := range []string{"signup", "login"} {
+				payload := `{"type": "` + typ + `", "user": {"plan": "pro"}}`
+				_, err = conn.Exec(ctx, `INSERT INTO `+table+` (payload) VALUES ($1)`, payload)
+				if err != nil {
+					t.Fatalf("INSERT: Error: %s\n", err)
+				}
+			}
+
+			var payload map[string]any
+			var plan string
+			err = conn.QueryRow(ctx, `SELECT payload, payload->'user'->>'plan' FROM `+table+` WHERE payload->>'type' = $1`, "login").Scan(&payload, &plan)
+			if err != nil {
+				t.Fatalf("SELECT: Error: %s\n", err)
+			}
+			if payload["type"] != "login" || plan != "pro" {
+				t.Fatalf("unexpected row (%v, %s)", payload, plan)
+			}
+
+			var ids []int64
+			err = conn.QueryRow(ctx, `SELECT array_agg(id) FROM `+table+` WHERE payload @> $1`, `{"type": "signup"}`).Scan(&ids)
+			if err != nil {
+				t.Fatalf("SELECT: Error: %s\n", err)
+			}
+			if len(ids) != 1 || ids[0] != 1 {
+				t.Fatalf("unexpected ids %v", ids)
+			}
+		})
+	}
+}
+
+func TestServerTransaction(t *testing.T) {
+	url := testServer(t)
+	ctx := context.Background()
+	conn := testConnect(t, url, pgx.QueryExecModeCacheStatement)
+
+	_, err := conn.Exec(ctx, `CREATE TABLE item (id BIGSERIAL PRIMARY KEY, name TEXT)`)
+	if err != nil {
+		t.Fatalf("CREATE TABLE: Error: %s\n", err)
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin: Error: %s\n", err)
+	}
+	_, err = tx.Exec(ctx, `INSERT INTO item (name) VALUES ($1)`, "rolled back")
+	if err != nil {
+		t.Fatalf("INSERT: Error: %s\n", err)
+	}
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatalf("Rollback: Error: %s\n", err)
+	}
+
+	err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `INSERT INTO item (name) VALUES ($1)`, "committed")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("BeginFunc: Error: %s\n", err)
+	}
+
+	var count int64
+	if err := conn.QueryRow(ctx, `SELECT COUNT(*) FROM item`).Scan(&count); err != nil {
+		t.Fatalf("SELECT: Error: %s\n", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 item, got %d", count)
+	}
+
+	// statements of a failed transaction are rejected until it ends
+	tx, err = conn.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin: Error: %s\n", err)
+	}
+	if _, err := tx.Exec(ctx, `SELECT * FROM missing`); err == nil {
+		t.Fatalf("expected an error selecting from a missing table")
+	}
+	_, err = tx.Exec(ctx, `INSERT INTO item (name) VALUES ($1)`, "aborted")
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != codeInFailedTransaction {
+		t.Fatalf("expected error %s, got %v", codeInFailedTransaction, err)
+	}
+	if err := tx.Commit(ctx); !errors.Is(err, pgx.ErrTxCommitRollback) {
+		t.Fatalf("expected commit to roll back, got %v", err)
+	}
+	if conn.PgConn().TxStatus() != 'I' {
+		t.Fatalf("expected idle connection, got status %c", conn.PgConn().TxStatus())
+	}
+
+	// COMMIT without transaction is a warning
+	config, err := pgx.ParseConfig(url)
+	if err != nil {
+		t.Fatalf("pgx.ParseConfig: Error: %s\n", err)
+	}
+	var notices []*pgconn.Notice
+	config.OnNotice = func(_ *pgconn.PgConn, n *pgconn.Notice) {
+		notices = append(notices, n)
+	}
+	other, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		t.Fatalf("pgx.Connect: Error: %s\n", err)
+	}
+	defer other.Close(ctx)
+	for _, query := range []string{`COMMIT`, `BEGIN`, `BEGIN`, `ROLLBACK`} {
+		if _, err := other.Exec(ctx, query); err != nil {
+			t.Fatalf("%s: Error: %s\n", query, err)
+		}
+	}
+	if len(notices) != 2 || notices[0].Code != codeNoActiveTransaction || notices[1].Code != codeActiveTransaction {
+		t.Fatalf("expected notices %s and %s, got %v", codeNoActiveTransaction, codeActiveTransaction, notices)
+	}
+}
+
+func TestServerSimpleQuery(t *testing.T) {
+	url := testServer(t)
+	ctx := context.Background()
+	conn := testConnect(t, url, pgx.QueryExecModeSimpleProtocol)
+
+	results, err := conn.PgConn().Exec(ctx, `
+		CREATE TABLE note (id BIGSERIAL PRIMARY KEY, body TEXT); -- notes
+		INSERT INTO note (body) VALUES ('a;b'), ('c');
+		SELECT body FROM note ORDER BY id;
+	`).ReadAll()
+	if err != nil {
+		t.Fatalf("Exec: Error: %s\n", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if tag := results[1].CommandTag.String(); tag != "INSERT 0 2" {
+		t.Fatalf("expected INSERT 0 2, got %s", tag)
+	}
+	rows := results[2].Rows
+	if len(rows) != 2 || string(rows[0][0]) != "a;b" || string(rows[1][0]) != "c" {
+		t.Fatalf("unexpected rows %q", rows)
+	}
+
+	// statements of a query run in one transaction
+	_, err = conn.PgConn().Exec(ctx, `INSERT INTO note (body) VALUES ('d'); SELECT * FROM missing`).ReadAll()
+	if err == nil {
+		t.Fatalf("expected an error selecting from a missing table")
+	}
+	var count int64
+	if err := conn.QueryRow(ctx, `SELECT COUNT(*) FROM note`).Scan(&count); err != nil {
+		t.Fatalf("SELECT: Error: %s\n", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 notes, got %d", count)
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	url := testServer(t)
+	ctx := context.Background()
+	conn := testConnect(t, url, pgx.QueryExecModeCacheStatement)
+
+	_, err := conn.Exec(ctx, `CREATE TABLE person (id BIGSERIAL PRIMARY KEY, email TEXT UNIQUE)`)
+	if err != nil {
+		t.Fatalf("CREATE TABLE: Error: %s\n", err)
+	}
+	_, err = conn.Exec(ctx, `CREATE TABLE pet (id BIGSERIAL PRIMARY KEY, owner_id BIGINT NOT NULL, FOREIGN KEY (owner_id) REFERENCES person(id))`)
+	if err != nil {
+		t.Fatalf("CREATE TABLE: Error: %s\n", err)
+	}
+	_, err = conn.Exec(ctx, `INSERT INTO person (email) VALUES ($1)`, "foo@bar.com")
+	if err != nil {
+		t.Fatalf("INSERT: Error: %s\n", err)
+	}
+
+	tests := map[string]string{
+		`SELEC * FROM person`:                               codeSyntaxError,
+		`SHOW unknown_setting`:                              "42704",
+		`SET statement_timeout = 'soon'`:                    "22023",
+		`INSERT INTO person (email) VALUES ('foo@bar.com')`: "23505",
+		`INSERT INTO pet (owner_id) VALUES (42)`:            "23503",
+		`INSERT INTO pet (owner_id) VALUES (NULL)`:          "23502",
+		`SELECT * FROM missing`:                             "42P01",
+		`SELECT nope FROM person`:                           "42703",
+	}
+	for query, code := range tests {
+		_, err := conn.Exec(ctx, query)
+		var pgErr *pgconn.PgError
+		if !errors.As(err, &pgErr) {
+			t.Fatalf("%s: expected a PostgreSQL error, got %v", query, err)
+		}
+		if pgErr.Code != code {
+			t.Fatalf("%s: expected SQLSTATE %s, got %s (%s)", query, code, pgErr.Code, pgErr.Message)
+		}
+	}
+
+	// the connection is still usable
+	var n int64
+	if err := conn.QueryRow(ctx, `SELECT COUNT(*) FROM person`).Scan(&n); err != nil {
+		t.Fatalf("SELECT: Error: %s\n", err)
+	}
+}
+
+func TestServerSharedEngine(t *testing.T) {
+	url := testServer(t)
+	ctx := context.Background()
+	a := testConnect(t, url, pgx.QueryExecModeCacheStatement)
+	b := testConnect(t, url, pgx.QueryExecModeCacheStatement)
+
+	_, err := a.Exec(ctx, `CREATE TABLE shared (v TEXT)`)
+	if err != nil {
+		t.Fatalf("CREATE TABLE: Error: %s\n", err)
+	}
+	_, err = a.Exec(ctx, `INSERT INTO shared (v) VALUES ($1)`, "hello")
+	if err != nil {
+		t.Fatalf("INSERT: Error: %s\n", err)
+	}
+
+	var v string
+	if err := b.QueryRow(ctx, `SELECT v FROM shared`).Scan(&v); err != nil {
+		t.Fatalf("SELECT: Error: %s\n", err)
+	}
+	if v != "hello" {
+		t.Fatalf("expected hello, got %s", v)
+	}
+
+	// session settings are per connection
+	if _, err := a.Exec(ctx, `SET application_name = 'a'`); err != nil {
+		t.Fatalf("SET: Error: %s\n", err)
+	}
+	if err := b.QueryRow(ctx, `SHOW application_name`).Scan(&v); err != nil {
+		t.Fatalf("SHOW: Error: %s\n", err)
+	}
+	if v != "" {
+		t.Fatalf("expected empty application_name, got %q", v)
+	}
+	if got := a.PgConn().ParameterStatus("application_name"); got != "a" {
+		t.Fatalf("expected reported application_name a, got %q", got)
+	}
+}
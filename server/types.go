@@ -0,0 +1,548 @@
+package server
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/proullon/ramsql/engine/agnostic"
+)
+
+// Type OIDs, as defined in pg_type
+const (
+	oidUnknown     = 0
+	oidBool        = 16
+	oidBytea       = 17
+	oidInt8        = 20
+	oidInt2        = 21
+	oidInt4        = 23
+	oidText        = 25
+	oidJSON        = 114
+	oidFloat4      = 700
+	oidFloat8      = 701
+	oidVarchar     = 1043
+	oidDate        = 1082
+	oidTimestamp   = 1114
+	oidTimestamptz = 1184
+	oidNumeric     = 1700
+	oidUUID        = 2950
+	oidJSONB       = 3802
+
+	oidJSONArray        = 199
+	oidBoolArray        = 1000
+	oidByteaArray       = 1001
+	oidInt2Array        = 1005
+	oidInt4Array        = 1007
+	oidTextArray        = 1009
+	oidVarcharArray     = 1015
+	oidInt8Array        = 1016
+	oidFloat4Array      = 1021
+	oidFloat8Array      = 1022
+	oidTimestampArray   = 1115
+	oidDateArray        = 1182
+	oidTimestamptzArray = 1185
+	oidNumericArray     = 1231
+	oidUUIDArray        = 2951
+	oidJSONBArray       = 3807
+)
+
+// arrayElemOIDs maps array type OIDs to the OID of their elements
+var arrayElemOIDs = map[uint32]uint32{
+	oidJSONArray:        oidJSON,
+	oidBoolArray:        oidBool,
+	oidByteaArray:       oidBytea,
+	oidInt2Array:        oidInt2,
+	oidInt4Array:        oidInt4,
+	oidTextArray:        oidText,
+	oidVarcharArray:     oidVarchar,
+	oidInt8Array:        oidInt8,
+	oidFloat4Array:      oidFloat4,
+	oidFloat8Array:      oidFloat8,
+	oidTimestampArray:   oidTimestamp,
+	oidDateArray:        oidDate,
+	oidTimestamptzArray: oidTimestamptz,
+	oidNumericArray:     oidNumeric,
+	oidUUIDArray:        oidUUID,
+	oidJSONBArray:       oidJSONB,
+}
+
+// Format codes
+const (
+	formatText   = 0
+	formatBinary = 1
+)
+
+// postgresEpoch is the origin of binary dates and timestamps
+var postgresEpoch = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// typeOID returns the OID of the PostgreSQL type matching column type c.
+// Integers are int8 and decimals float8, as they are stored as int64 and float64.
+func typeOID(c agnostic.ColumnType) uint32 {
+	if agnostic.IsArrayType(c.TypeName) {
+		elem := agnostic.ColumnType{TypeName: agnostic.ArrayElemType(c.TypeName)}
+		if c.ScanType != nil && c.ScanType.Kind() == reflect.Slice {
+			elem.ScanType = c.ScanType.Elem()
+		}
+		return arrayOID(typeOID(elem))
+	}
+
+	name := c.TypeName
+	if i := strings.IndexByte(name, '('); i >= 0 {
+		name = name[:i]
+	}
+
+	switch strings.TrimSpace(name) {
+	case "BOOL", "BOOLEAN":
+		return oidBool
+	case "BYTEA":
+		return oidBytea
+	case "INT", "INTEGER", "BIGINT", "SMALLINT", "SERIAL", "BIGSERIAL":
+		return oidInt8
+	case "FLOAT", "DECIMAL", "NUMERIC", "REAL", "DOUBLE PRECISION":
+		return oidFloat8
+	case "VARCHAR", "CHARACTER VARYING", "CHAR":
+		return oidVarchar
+	case "JSON":
+		return oidJSON
+	case "JSONB":
+		return oidJSONB
+	case "UUID":
+		return oidUUID
+	case "DATE":
+		return oidDate
+	case "TIMESTAMP":
+		return oidTimestamp
+	case "TIMESTAMPTZ":
+		return oidTimestamptz
+	}
+
+	if c.ScanType == nil {
+		return oidText
+	}
+	switch c.ScanType.Kind() {
+	case reflect.Bool:
+		return oidBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return oidInt8
+	case reflect.Float32, reflect.Float64:
+		return oidFloat8
+	}
+	if c.ScanType == reflect.TypeOf(time.Time{}) {
+		return oidTimestamp
+	}
+	return oidText
+}
+
+// arrayOID returns the OID of arrays of elem elements
+func arrayOID(elem uint32) uint32 {
+	for oid, e := range arrayElemOIDs {
+		if e == elem {
+			return oid
+		}
+	}
+	return oidTextArray
+}
+
+// typeSize returns the length of values of type oid, -1 for variable length types
+func typeSize(oid uint32) int16 {
+	switch oid {
+	case oidBool:
+		return 1
+	case oidInt2:
+		return 2
+	case oidInt4, oidFloat4, oidDate:
+		return 4
+	case oidInt8, oidFloat8, oidTimestamp, oidTimestamptz:
+		return 8
+	case oidUUID:
+		return 16
+	}
+	return -1
+}
+
+// encode returns v as a value of type oid in given format, nil for NULL.
+// Timestamps with time zone are written in loc.
+func encode(v any, oid uint32, format int16, loc *time.Location) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if elem, ok := arrayElemOIDs[oid]; ok {
+		return encodeArray(v, elem, format, loc)
+	}
+	if format == formatBinary {
+		return encodeBinary(v, oid)
+	}
+
+	switch v := v.(type) {
+	case string:
+		return []byte(v), nil
+	case agnostic.JSON:
+		return []byte(v.Text()), nil
+	case []byte:
+		if oid == oidBytea {
+			return []byte(`\x` + hex.EncodeToString(v)), nil
+		}
+		return v, nil
+	case bool:
+		if v {
+			return []byte("t"), nil
+		}
+		return []byte("f"), nil
+	case float32:
+		return strconv.AppendFloat(nil, float64(v), 'g', -1, 32), nil
+	case float64:
+		return strconv.AppendFloat(nil, v, 'g', -1, 64), nil
+	case time.Time:
+		switch oid {
+		case oidDate:
+			return []byte(v.Format("2006-01-02")), nil
+		case oidTimestamptz:
+			v = v.In(loc)
+			layout := "2006-01-02 15:04:05.999999-07"
+			if _, offset := v.Zone(); offset%3600 != 0 {
+				layout += ":00"
+			}
+			return []byte(v.Format(layout)), nil
+		}
+		return []byte(v.Format("2006-01-02 15:04:05.999999")), nil
+	}
+
+	if i, ok := toInt64(v); ok {
+		return strconv.AppendInt(nil, i, 10), nil
+	}
+	return []byte(fmt.Sprint(v)), nil
+}
+
+// encodeArray returns array v, of elem elements, in given format.
+// Binary arrays have a header giving dimensions and element type, followed by
+// elements prefixed by their length.
+func encodeArray(v any, elem uint32, format int16, loc *time.Location) ([]byte, error) {
+	if format == formatText {
+		if s, ok := agnostic.FormatArray(v); ok {
+			return []byte(s), nil
+		}
+		return encode(v, oidText, format, loc)
+	}
+
+	values, ok := agnostic.ArrayValues(v)
+	if !ok {
+		return nil, fmt.Errorf("cannot encode %v (%T) as binary array", v, v)
+	}
+	var ndim uint32
+	if len(values) > 0 {
+		ndim = 1
+	}
+	b := binary.BigEndian.AppendUint32(nil, ndim)
+	b = binary.BigEndian.AppendUint32(b, 0)
+	b = binary.BigEndian.AppendUint32(b, elem)
+	if ndim > 0 {
+		b = binary.BigEndian.AppendUint32(b, uint32(len(values)))
+		b = binary.BigEndian.AppendUint32(b, 1)
+	}
+	for _, e := range values {
+		eb, err := encode(e, elem, formatBinary, loc)
+		if err != nil {
+			return nil, err
+		}
+		b = binary.BigEndian.AppendUint32(b, uint32(len(eb)))
+		b = append(b, eb...)
+	}
+	return b, nil
+}
+
+func encodeBinary(v any, oid uint32) ([]byte, error) {
+	switch oid {
+	case oidBool:
+		if b, ok := v.(bool); ok {
+			if b {
+				return []byte{1}, nil
+			}
+			return []byte{0}, nil
+		}
+	case oidInt2, oidInt4, oidInt8:
+		i, ok := toInt64(v)
+		if !ok {
+			break
+		}
+		switch oid {
+		case oidInt2:
+			return binary.BigEndian.AppendUint16(nil, uint16(i)), nil
+		case oidInt4:
+			return binary.BigEndian.AppendUint32(nil, uint32(i)), nil
+		}
+		return binary.BigEndian.AppendUint64(nil, uint64(i)), nil
+	case oidFloat4, oidFloat8:
+		f, ok := toFloat64(v)
+		if !ok {
+			break
+		}
+		if oid == oidFloat4 {
+			return binary.BigEndian.AppendUint32(nil, math.Float32bits(float32(f))), nil
+		}
+		return binary.BigEndian.AppendUint64(nil, math.Float64bits(f)), nil
+	case oidDate:
+		if t, ok := v.(time.Time); ok {
+			days := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Sub(postgresEpoch) / (24 * time.Hour)
+			return binary.BigEndian.AppendUint32(nil, uint32(int32(days))), nil
+		}
+	case oidTimestamp:
+		if t, ok := v.(time.Time); ok {
+			wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+			return binary.BigEndian.AppendUint64(nil, uint64(microseconds(wall))), nil
+		}
+	case oidTimestamptz:
+		if t, ok := v.(time.Time); ok {
+			return binary.BigEndian.AppendUint64(nil, uint64(microseconds(t))), nil
+		}
+	case oidUUID:
+		var s string
+		switch v := v.(type) {
+		case string:
+			s = v
+		case []byte:
+			s = string(v)
+		}
+		u, err := uuid.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input syntax for type uuid: \"%s\"", s)
+		}
+		return u[:], nil
+	case oidJSONB:
+		b, err := encode(v, oidText, formatText, time.UTC)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{1}, b...), nil
+	case oidBytea:
+		switch v := v.(type) {
+		case []byte:
+			return v, nil
+		case string:
+			return []byte(v), nil
+		}
+	default:
+		// binary format of text types is their text format
+		return encode(v, oid, formatText, time.UTC)
+	}
+
+	return nil, fmt.Errorf("cannot encode %v (%T) as binary type %d", v, v, oid)
+}
+
+// decode returns the value of a parameter of type oid in given format.
+func decode(b []byte, oid uint32, format int16) (any, error) {
+	if b == nil {
+		return nil, nil
+	}
+	if format == formatBinary {
+		return decodeBinary(b, oid)
+	}
+
+	s := string(b)
+	switch oid {
+	case oidBool:
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case "t", "true", "y", "yes", "on", "1":
+			return true, nil
+		case "f", "false", "n", "no", "off", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("invalid input syntax for type boolean: \"%s\"", s)
+	case oidInt2, oidInt4, oidInt8:
+		i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input syntax for type bigint: \"%s\"", s)
+		}
+		return i, nil
+	case oidFloat4, oidFloat8, oidNumeric:
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input syntax for type double precision: \"%s\"", s)
+		}
+		return f, nil
+	case oidBytea:
+		if strings.HasPrefix(s, `\x`) {
+			return hex.DecodeString(s[2:])
+		}
+		return b, nil
+	case oidDate, oidTimestamp, oidTimestamptz:
+		return parseTime(s)
+	}
+	return s, nil
+}
+
+// decodeBinaryArray returns the elements of a one dimension binary array
+func decodeBinaryArray(b []byte) (any, error) {
+	if len(b) < 12 {
+		return nil, fmt.Errorf("invalid binary array")
+	}
+	ndim, elem := binary.BigEndian.Uint32(b), binary.BigEndian.Uint32(b[8:])
+	b = b[12:]
+	switch {
+	case ndim == 0:
+		return agnostic.NewArray(nil), nil
+	case ndim > 1:
+		return nil, fmt.Errorf("multidimensional arrays are not supported")
+	case len(b) < 8:
+		return nil, fmt.Errorf("invalid binary array")
+	}
+	n := int(binary.BigEndian.Uint32(b))
+	b = b[8:]
+
+	values := make([]any, 0, n)
+	for i := 0; i < n; i++ {
+		if len(b) < 4 {
+			return nil, fmt.Errorf("invalid binary array")
+		}
+		size := int32(binary.BigEndian.Uint32(b))
+		b = b[4:]
+		if size < 0 {
+			return nil, fmt.Errorf("NULL array elements are not supported")
+		}
+		if len(b) < int(size) {
+			return nil, fmt.Errorf("invalid binary array")
+		}
+		v, err := decodeBinary(b[:size], elem)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		b = b[size:]
+	}
+	return agnostic.NewArray(values), nil
+}
+
+func decodeBinary(b []byte, oid uint32) (any, error) {
+	if _, ok := arrayElemOIDs[oid]; ok {
+		return decodeBinaryArray(b)
+	}
+
+	switch oid {
+	case oidBool:
+		if len(b) == 1 {
+			return b[0] != 0, nil
+		}
+	case oidInt2:
+		if len(b) == 2 {
+			return int64(int16(binary.BigEndian.Uint16(b))), nil
+		}
+	case oidInt4:
+		if len(b) == 4 {
+			return int64(int32(binary.BigEndian.Uint32(b))), nil
+		}
+	case oidInt8:
+		if len(b) == 8 {
+			return int64(binary.BigEndian.Uint64(b)), nil
+		}
+	case oidFloat4:
+		if len(b) == 4 {
+			return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+		}
+	case oidFloat8:
+		if len(b) == 8 {
+			return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+		}
+	case oidDate:
+		if len(b) == 4 {
+			days := int32(binary.BigEndian.Uint32(b))
+			return postgresEpoch.AddDate(0, 0, int(days)), nil
+		}
+	case oidTimestamp, oidTimestamptz:
+		if len(b) == 8 {
+			us := int64(binary.BigEndian.Uint64(b))
+			return postgresEpoch.Add(time.Duration(us) * time.Microsecond), nil
+		}
+	case oidUUID:
+		u, err := uuid.FromBytes(b)
+		if err != nil {
+			return nil, err
+		}
+		return u.String(), nil
+	case oidJSONB:
+		if len(b) > 0 && b[0] == 1 {
+			return string(b[1:]), nil
+		}
+	case oidBytea:
+		return append([]byte(nil), b...), nil
+	default:
+		return string(b), nil
+	}
+
+	return nil, fmt.Errorf("invalid binary value for type %d", oid)
+}
+
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999999999Z07:00:00",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+func parseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid input syntax for type timestamp: \"%s\"", s)
+}
+
+func microseconds(t time.Time) int64 {
+	return t.Sub(postgresEpoch).Microseconds()
+}
+
+func toInt64(v any) (int64, bool) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint:
+		return int64(v), true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		return int64(v), true
+	case string:
+		i, err := strconv.ParseInt(v, 10, 64)
+		return i, err == nil
+	}
+	return 0, false
+}
+
+func toFloat64(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	if i, ok := toInt64(v); ok {
+		return float64(i), true
+	}
+	return 0, false
+}